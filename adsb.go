@@ -0,0 +1,345 @@
+// adsb.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrADSBNoArrivalAirport = errors.New("Trace does not land at one of the scenario's arrival airports")
+	ErrADSBNotInFacility    = errors.New("Trace does not pass through the facility's airspace")
+	ErrADSBNoTraces         = errors.New("No usable ADS-B traces found")
+)
+
+// ADSBTrace is the position history of a single aircraft, as stored in the
+// trace files written by readsb and served by adsbexchange.
+type ADSBTrace struct {
+	ICAO         string
+	Registration string
+	Type         string
+	Callsign     string
+	Points       []ADSBTracePoint
+}
+
+type ADSBTracePoint struct {
+	Time     time.Time
+	Position Point2LL
+	Altitude int // barometric, in feet
+	OnGround bool
+	GS       float32
+	Track    float32
+}
+
+// ParseADSBTrace parses a readsb JSON trace file, which may be gzip
+// compressed. Each entry of the file's "trace" array has the form [seconds
+// after timestamp, lat, lon, altitude or "ground", ground speed, track,
+// flags, vertical rate, details or null, ...]; the callsign is taken from
+// the first details object that provides one.
+func ParseADSBTrace(b []byte) (*ADSBTrace, error) {
+	if len(b) >= 2 && b[0] == 0x1f && b[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		if b, err = io.ReadAll(zr); err != nil {
+			return nil, err
+		}
+	}
+
+	var raw struct {
+		ICAO         string  `json:"icao"`
+		Registration string  `json:"r"`
+		Type         string  `json:"t"`
+		Timestamp    float64 `json:"timestamp"`
+		Trace        [][]any `json:"trace"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+
+	t := &ADSBTrace{
+		ICAO:         strings.ToUpper(raw.ICAO),
+		Registration: raw.Registration,
+		Type:         raw.Type,
+	}
+	base := time.Unix(0, int64(raw.Timestamp*float64(time.Second))).UTC()
+
+	for i, entry := range raw.Trace {
+		if len(entry) < 6 {
+			return nil, fmt.Errorf("trace entry %d: expected at least 6 values, got %d", i, len(entry))
+		}
+		dt, okt := entry[0].(float64)
+		lat, oklat := entry[1].(float64)
+		lon, oklon := entry[2].(float64)
+		if !okt || !oklat || !oklon {
+			return nil, fmt.Errorf("trace entry %d: invalid time or position", i)
+		}
+
+		pt := ADSBTracePoint{
+			Time:     base.Add(time.Duration(dt * float64(time.Second))),
+			Position: Point2LL{float32(lon), float32(lat)},
+		}
+		switch alt := entry[3].(type) {
+		case float64:
+			pt.Altitude = int(alt)
+		case string:
+			if alt != "ground" {
+				return nil, fmt.Errorf("trace entry %d: %q: invalid altitude", i, alt)
+			}
+			pt.OnGround = true
+		default:
+			// No altitude was reported; there's not much we can do with
+			// the position alone.
+			continue
+		}
+		if gs, ok := entry[4].(float64); ok {
+			pt.GS = float32(gs)
+		}
+		if trk, ok := entry[5].(float64); ok {
+			pt.Track = float32(trk)
+		}
+
+		if t.Callsign == "" && len(entry) > 8 {
+			if details, ok := entry[8].(map[string]any); ok {
+				if flight, ok := details["flight"].(string); ok {
+					t.Callsign = strings.ToUpper(strings.TrimSpace(flight))
+				}
+			}
+		}
+
+		t.Points = append(t.Points, pt)
+	}
+
+	if t.Callsign == "" {
+		t.Callsign = Select(t.Registration != "", strings.ToUpper(t.Registration), t.ICAO)
+	}
+	t.Callsign = strings.ReplaceAll(t.Callsign, "-", "")
+
+	return t, nil
+}
+
+// adsbWaypointSpacing is the minimum distance in nm between the
+// successive waypoints generated from an ADS-B trace.
+const adsbWaypointSpacing = 3
+
+// CreateADSBArrival returns an arrival that flies the route flown in the
+// given trace, entering at the point where the trace first enters the
+// facility's airspace. Waypoints are left behind along the recorded
+// track, each with the altitude the aircraft was at there; the aircraft
+// leaves the recorded route 10nm from the airport so that it can be
+// vectored for the approach. The time at which the aircraft entered the
+// airspace is returned as well so that a set of traces can be launched
+// with their original relative timing.
+func (w *World) CreateADSBArrival(t *ADSBTrace) (*Aircraft, time.Time, error) {
+	if len(t.Points) < 2 {
+		return nil, time.Time{}, ErrADSBNotInFacility
+	}
+
+	// The arrival airport is the one where the trace ends, low and slow.
+	last := t.Points[len(t.Points)-1]
+	arrivalAirport, arrivalDist := "", float32(5)
+	for name := range w.ArrivalAirports {
+		if ap, ok := database.Airports[name]; ok {
+			if d := nmdistance2ll(ap.Location, last.Position); d < arrivalDist &&
+				(last.OnGround || last.Altitude < ap.Elevation+2000) {
+				arrivalAirport, arrivalDist = name, d
+			}
+		}
+	}
+	if arrivalAirport == "" {
+		return nil, time.Time{}, ErrADSBNoArrivalAirport
+	}
+	arrivalLocation := database.Airports[arrivalAirport].Location
+
+	// Take the departure airport to be the closest one to where the trace
+	// starts; it's only used for the flight plan.
+	departureAirport, departureDist := arrivalAirport, float32(0)
+	for name, ap := range database.Airports {
+		if d := nmdistance2ll(ap.Location, t.Points[0].Position); departureDist == 0 || d < departureDist {
+			departureAirport, departureDist = name, d
+		}
+	}
+
+	perf, ok := database.AircraftPerformance[t.Type]
+	if !ok {
+		return nil, time.Time{}, ErrUnknownAircraftType
+	}
+
+	// Generate waypoints from the point where the trace enters our
+	// airspace up until it gets close to the airport.
+	var wps []Waypoint
+	var entry *ADSBTracePoint
+	for i, pt := range t.Points {
+		if pt.OnGround {
+			continue
+		}
+		if entry == nil {
+			if nmdistance2ll(pt.Position, w.Center) > w.Range {
+				continue
+			}
+			entry = &t.Points[i]
+		}
+		if nmdistance2ll(pt.Position, arrivalLocation) < 10 {
+			break
+		}
+		if len(wps) > 0 && nmdistance2ll(pt.Position, wps[len(wps)-1].Location) < adsbWaypointSpacing {
+			continue
+		}
+
+		alt := float32(100 * ((pt.Altitude + 50) / 100))
+		wps = append(wps, Waypoint{
+			Fix:                 fmt.Sprintf("_ADSB%02d", len(wps)),
+			Location:            pt.Position,
+			AltitudeRestriction: &AltitudeRestriction{Range: [2]float32{alt, alt}},
+		})
+	}
+	if entry == nil || len(wps) < 2 {
+		return nil, time.Time{}, ErrADSBNotInFacility
+	}
+	// Don't offer the handoff the moment the aircraft appears.
+	wps[1].Handoff = true
+
+	// Associate the aircraft with the scenario arrival that starts
+	// closest to where it entered so that it gets that arrival's
+	// controllers, scratchpads, etc.
+	arrivalGroup, arrivalGroupIndex, closest := "", -1, float32(0)
+	for group, arrivals := range w.ArrivalGroups {
+		for i, ar := range arrivals {
+			if _, ok := ar.Airlines[arrivalAirport]; !ok || len(ar.Waypoints) == 0 {
+				continue
+			}
+			if d := nmdistance2ll(ar.Waypoints[0].Location, entry.Position); arrivalGroupIndex == -1 || d < closest {
+				arrivalGroup, arrivalGroupIndex, closest = group, i, d
+			}
+		}
+	}
+	if arrivalGroupIndex == -1 {
+		return nil, time.Time{}, ErrNoValidArrivalFound
+	}
+	arr := &w.ArrivalGroups[arrivalGroup][arrivalGroupIndex]
+
+	squawk := Squawk(rand.Intn(0o7000))
+	ac := &Aircraft{
+		Callsign:            t.Callsign,
+		AssignedSquawk:      squawk,
+		Squawk:              squawk,
+		Mode:                Charlie,
+		ArrivalGroup:        arrivalGroup,
+		ArrivalGroupIndex:   arrivalGroupIndex,
+		Scratchpad:          arr.Scratchpad,
+		SecondaryScratchpad: arr.SecondaryScratchpad,
+	}
+
+	acType := t.Type
+	if perf.WeightClass == "H" {
+		acType = "H/" + acType
+	}
+	if perf.WeightClass == "J" {
+		acType = "J/" + acType
+	}
+	ac.FlightPlan = NewFlightPlan(IFR, acType, departureAirport, arrivalAirport)
+	ac.FlightPlan.Route = "DCT"
+	for _, pt := range t.Points {
+		ac.FlightPlan.Altitude = max(ac.FlightPlan.Altitude, 1000*((pt.Altitude+500)/1000))
+	}
+
+	ac.TrackingController = arr.InitialController
+	ac.ControllingController = arr.InitialController
+	ac.WaypointHandoffController = w.PrimaryController
+	if len(w.MultiControllers) > 0 {
+		if ctrl := w.MultiControllers.GetArrivalController(arrivalGroup); ctrl != "" {
+			ac.WaypointHandoffController = ctrl
+		}
+	}
+
+	nav := makeNav(w, *ac.FlightPlan, perf, wps)
+	if nav == nil {
+		return nil, time.Time{}, fmt.Errorf("error initializing Nav")
+	}
+	nav.FlightState.Altitude = float32(entry.Altitude)
+	nav.FinalAltitude = max(nav.FinalAltitude, nav.FlightState.Altitude)
+	nav.FlightState.IAS = TASToIAS(entry.GS, nav.FlightState.Altitude)
+	nav.FlightState.GS = entry.GS
+	ac.Nav = *nav
+
+	return ac, entry.Time, nil
+}
+
+// ImportADSBTraces creates arrivals from the ADS-B trace files (.json or
+// .json.gz) in the given directory and schedules them to launch with the
+// same relative timing as the recorded traffic, starting now. Traces that
+// can't be used (overflights, departures, unknown aircraft types, ...)
+// are skipped. The number of scheduled aircraft is returned.
+func (w *World) ImportADSBTraces(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var launches []ScheduledLaunch
+	var entryTimes []time.Time
+	callsigns := make(map[string]interface{})
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !(strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".json.gz")) {
+			continue
+		}
+
+		fn := path.Join(dir, name)
+		b, err := os.ReadFile(fn)
+		if err != nil {
+			return 0, err
+		}
+		trace, err := ParseADSBTrace(b)
+		if err != nil {
+			lg.Warnf("%s: %v", fn, err)
+			continue
+		}
+
+		ac, t, err := w.CreateADSBArrival(trace)
+		if err != nil {
+			lg.Infof("%s: skipping: %v", fn, err)
+			continue
+		}
+		if _, ok := callsigns[ac.Callsign]; ok {
+			lg.Infof("%s: skipping: duplicate callsign %s", fn, ac.Callsign)
+			continue
+		} else if _, ok := w.Aircraft[ac.Callsign]; ok {
+			lg.Infof("%s: skipping: %s is already in the sim", fn, ac.Callsign)
+			continue
+		}
+		callsigns[ac.Callsign] = nil
+
+		launches = append(launches, ScheduledLaunch{Aircraft: *ac})
+		entryTimes = append(entryTimes, t)
+	}
+	if len(launches) == 0 {
+		return 0, ErrADSBNoTraces
+	}
+
+	start := entryTimes[0]
+	for _, t := range entryTimes {
+		if t.Before(start) {
+			start = t
+		}
+	}
+	for i := range launches {
+		launches[i].Delay = entryTimes[i].Sub(start)
+	}
+
+	w.ScheduleLaunches(launches)
+
+	return len(launches), nil
+}
@@ -0,0 +1,83 @@
+// adsb_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+)
+
+const testADSBTrace = `{"icao":"a1b2c3","r":"N123AB","t":"B738","timestamp":1700000000.5,
+"trace":[
+[0.0,40.5,-73.9,8000,250.1,45.2,0,-1024,{"type":"adsb_icao","flight":"AAL123  "},"adsb_icao",8050,null,null,null],
+[5.0,40.51,-73.89,null,250.0,45.0,0,-1024,null,"adsb_icao",null,null,null,null],
+[10.0,40.52,-73.88,7800,249.0,45.0,0,-1024,{"flight":"XXX999"},"adsb_icao",7850,null,null,null],
+[300.0,40.64,-73.78,"ground",20.0,130.0,0,null,null,"adsb_icao",null,null,null,null]
+]}`
+
+func TestParseADSBTrace(t *testing.T) {
+	check := func(b []byte) {
+		trace, err := ParseADSBTrace(b)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if trace.ICAO != "A1B2C3" || trace.Registration != "N123AB" || trace.Type != "B738" {
+			t.Errorf("got ICAO %q registration %q type %q", trace.ICAO, trace.Registration, trace.Type)
+		}
+		if trace.Callsign != "AAL123" {
+			t.Errorf("callsign: expected AAL123, got %q", trace.Callsign)
+		}
+
+		// The point without an altitude should be skipped.
+		if len(trace.Points) != 3 {
+			t.Fatalf("expected 3 points, got %d", len(trace.Points))
+		}
+
+		p := trace.Points[0]
+		if !p.Time.Equal(time.Unix(1700000000, 500000000)) {
+			t.Errorf("time: got %s", p.Time)
+		}
+		if p.Position != (Point2LL{-73.9, 40.5}) || p.Altitude != 8000 || p.OnGround {
+			t.Errorf("first point: got %+v", p)
+		}
+		if p.GS != 250.1 || p.Track != 45.2 {
+			t.Errorf("first point: got GS %f track %f", p.GS, p.Track)
+		}
+
+		if p := trace.Points[1]; !p.Time.Equal(time.Unix(1700000010, 500000000)) || p.Altitude != 7800 {
+			t.Errorf("second point: got %+v", p)
+		}
+		if p := trace.Points[2]; !p.OnGround {
+			t.Errorf("last point: expected on ground, got %+v", p)
+		}
+	}
+
+	check([]byte(testADSBTrace))
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(testADSBTrace))
+	zw.Close()
+	check(buf.Bytes())
+
+	// Fall back to the registration if there's no callsign.
+	trace, err := ParseADSBTrace([]byte(`{"icao":"abcdef","r":"G-ABCD","t":"C172","timestamp":0,
+"trace":[[0,51.5,-0.1,2000,100,90,0,0,null]]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if trace.Callsign != "GABCD" {
+		t.Errorf("callsign: expected GABCD, got %q", trace.Callsign)
+	}
+
+	if _, err := ParseADSBTrace([]byte(`{"trace":[[0,51.5,-0.1,"sky",100,90]]}`)); err == nil {
+		t.Errorf("expected error for invalid altitude")
+	}
+	if _, err := ParseADSBTrace([]byte(`{"trace":[[0,51.5]]}`)); err == nil {
+		t.Errorf("expected error for short trace entry")
+	}
+}
@@ -43,6 +43,7 @@ type Aircraft struct {
 
 	// Departure related state
 	Exit                       string
+	DepartureRunway            string
 	DepartureContactAltitude   float32
 	DepartureContactController string
 
@@ -52,9 +53,32 @@ type Aircraft struct {
 	ArrivalGroup      string
 	ArrivalGroupIndex int
 	GotContactTower   bool
+	ETAs              ArrivalETAs
+
+	// Trainers flying practice approaches: the number remaining,
+	// including the current one, and whether the last one will be a
+	// full stop rather than a low approach.
+	PracticeApproaches       int
+	PracticeApproachFullStop bool
 
 	// Who to try to hand off to at a waypoint with /ho
 	WaypointHandoffController string
+
+	// Background traffic stays with a neighboring facility's controller
+	// for its entire flight and is never handed off.
+	BackgroundTraffic bool
+
+	// VFR departures from satellite airports that will call up to
+	// request a transition through the TRACON's airspace.
+	RequestingTransition bool
+
+	// Emergency the pilot has declared, if any.
+	Emergency EmergencyType
+
+	// Instructions that the pilot didn't acknowledge (and so isn't
+	// following); they're cleared the next time the pilot reads back
+	// an instruction.
+	UnacknowledgedInstructions []string
 }
 
 type RedirectedHandoff struct {
@@ -156,13 +180,25 @@ func (ac *Aircraft) transmitResponse(r PilotResponse) []RadioTransmission {
 func (ac *Aircraft) Update(w *World, ep EventPoster, simlg *Logger) *Waypoint {
 	lg := simlg.With(slog.String("callsign", ac.Callsign))
 
+	if ac.Nav.Taxi != nil {
+		ac.updateTaxi(w, ep, lg)
+		return nil
+	}
+
 	passedWaypoint := ac.Nav.Update(w, lg)
 	if passedWaypoint != nil {
 		lg.Info("passed", slog.Any("waypoint", passedWaypoint))
 
 		if passedWaypoint.Delete {
-			lg.Info("deleting aircraft after landing")
-			w.DeleteAircraft(ac, nil)
+			if rt := ac.LowApproach(w, ep); rt != nil {
+				lg.Info("low approach", slog.Int("remaining", ac.PracticeApproaches))
+				PostRadioEvents(ac.Callsign, rt, ep)
+			} else if ac.startRollout(w, lg) {
+				lg.Info("rolling out after landing")
+			} else {
+				lg.Info("deleting aircraft after landing")
+				w.DeleteAircraft(ac, nil)
+			}
 		}
 	}
 
@@ -257,6 +293,49 @@ func (ac *Aircraft) DirectFix(fix string) []RadioTransmission {
 	return ac.transmitResponse(ac.Nav.DirectFix(strings.ToUpper(fix)))
 }
 
+func (ac *Aircraft) ResumeOwnNavigation() []RadioTransmission {
+	return ac.transmitResponse(ac.Nav.ResumeOwnNavigation())
+}
+
+func (ac *Aircraft) AmendRoute(waypoints []Waypoint) []RadioTransmission {
+	resp := ac.Nav.AmendRoute(waypoints)
+	if !resp.Unexpected && ac.FlightPlan != nil {
+		fixes := MapSlice(waypoints, func(wp Waypoint) string { return wp.Fix })
+		ac.FlightPlan.Route = amendedRoute(ac.FlightPlan.Route, fixes)
+	}
+	return ac.transmitResponse(resp)
+}
+
+// amendedRoute returns the filed route with the fixes of a route amendment
+// spliced into it. They replace the part of the route from the first of
+// them (or from its start, if the first isn't in the route) through the
+// last of them (or through its end, if the last isn't in it, since the
+// aircraft then doesn't rejoin its route).
+func amendedRoute(route string, fixes []string) string {
+	elements := strings.Fields(route)
+	index := func(fix string) int {
+		return slices.IndexFunc(elements, func(e string) bool {
+			e, _, _ = strings.Cut(e, "/")
+			return e == fix
+		})
+	}
+
+	start, end := index(fixes[0]), index(fixes[len(fixes)-1])
+	if end == -1 {
+		end = len(elements)
+	} else {
+		end++
+	}
+	if start == -1 || start >= end {
+		start = 0
+	}
+	return strings.Join(slices.Concat(elements[:start], fixes, elements[end:]), " ")
+}
+
+func (ac *Aircraft) Hold(h Hold, published bool, efc string) []RadioTransmission {
+	return ac.transmitResponse(ac.Nav.Hold(h, published, efc))
+}
+
 func (ac *Aircraft) DepartFixHeading(fix string, hdg int) []RadioTransmission {
 	resp := ac.Nav.DepartFixHeading(strings.ToUpper(fix), float32(hdg))
 	return ac.transmitResponse(resp)
@@ -345,10 +424,86 @@ func (ac *Aircraft) ClimbViaSID() []RadioTransmission {
 	return ac.transmitResponse(ac.Nav.ClimbViaSID())
 }
 
+// ClearedTECRoute clears the aircraft to its destination via the TEC route
+// from its departure airport.
+func (ac *Aircraft) ClearedTECRoute(w *World) []RadioTransmission {
+	var tec *TECRoute
+	if ap, ok := w.Airports[ac.FlightPlan.DepartureAirport]; ok {
+		tec = ap.TECRouteTo(ac.FlightPlan.ArrivalAirport, ac.AircraftPerformance().Engine.AircraftType)
+	}
+	if tec == nil {
+		return ac.transmitResponse(PilotResponse{
+			Message:    "unable. We don't have a TEC route to " + ac.FlightPlan.ArrivalAirport,
+			Unexpected: true,
+		})
+	}
+
+	ac.FlightPlan.Route = tec.Route
+	ac.FlightPlan.Altitude = tec.Altitude
+	return ac.transmitResponse(ac.Nav.ClearedRoute(tec.Waypoints, float32(tec.Altitude)))
+}
+
 func (ac *Aircraft) DescendViaSTAR() []RadioTransmission {
 	return ac.transmitResponse(ac.Nav.DescendViaSTAR())
 }
 
+// practiceApproachRequest returns the pilot's request for the remaining
+// practice approaches.
+func (ac *Aircraft) practiceApproachRequest() string {
+	n := ac.PracticeApproaches
+	req := Select(n == 1, "request one more practice approach", fmt.Sprintf("request %d practice approaches", n))
+	if ac.PracticeApproachFullStop {
+		return req + Select(n == 1, ", full stop", ", the last one a full stop")
+	}
+	return req + ", low approach only"
+}
+
+// LowApproach is called when an aircraft reaches the end of its approach.
+// If the aircraft is flying practice approaches and has more to go (or
+// isn't making a full stop), it flies the missed approach, the tower
+// hands it back to the approach controller, and the returned
+// transmission is its call to approach. Otherwise it returns nil and the
+// aircraft lands.
+func (ac *Aircraft) LowApproach(w *World, ep EventPoster) []RadioTransmission {
+	if ac.PracticeApproaches == 0 || (ac.PracticeApproaches == 1 && ac.PracticeApproachFullStop) {
+		return nil
+	}
+
+	ac.PracticeApproaches--
+	approach := ac.Nav.Approach.Assigned
+	ac.Nav.GoAround()
+	ac.GotContactTower = false
+
+	msg := "low approach complete, climbing to " + FormatAltitude(*ac.Nav.Altitude.Assigned)
+	if ac.PracticeApproaches > 0 {
+		msg += ", " + ac.practiceApproachRequest()
+		if approach != nil {
+			msg += ", " + Sample("we'd like the ", "requesting the ", "") + approach.FullName + " again"
+		}
+	} else {
+		msg += ", that was our last one, request vectors to depart the area"
+	}
+
+	// The tower coordinates with approach to send the aircraft back.
+	appr := Select(ac.ApproachController != "", ac.ApproachController, w.PrimaryController)
+	ac.ControllingController = appr
+	if ac.TrackingController != "" && ac.TrackingController != appr {
+		ac.HandoffTrackController = appr
+		ep.PostEvent(Event{
+			Type:           OfferedHandoffEvent,
+			Callsign:       ac.Callsign,
+			FromController: ac.TrackingController,
+			ToController:   appr,
+		})
+	}
+
+	return []RadioTransmission{RadioTransmission{
+		Controller: appr,
+		Message:    msg,
+		Type:       RadioTransmissionContact,
+	}}
+}
+
 func (ac *Aircraft) ContactTower(w *World) []RadioTransmission {
 	if ac.IsDeparture() {
 		return ac.readbackUnexpected("unable. This aircraft is a departure.")
@@ -423,7 +578,10 @@ func (ac *Aircraft) InitializeArrival(w *World, arrivalGroup string,
 		ac.FlightPlan.Route = "/. " + arr.STAR
 	}
 
-	if goAround {
+	if rand.Float32() < arr.PracticeApproachRate {
+		ac.PracticeApproaches = 2 + rand.Intn(3)
+		ac.PracticeApproachFullStop = rand.Intn(2) == 0
+	} else if goAround {
 		d := 0.1 + .6*rand.Float32()
 		ac.GoAroundDistance = &d
 	}
@@ -448,11 +606,7 @@ func (ac *Aircraft) InitializeDeparture(w *World, ap *Airport, departureAirport
 	wp = append(wp, dep.RouteWaypoints...)
 	wp = FilterSlice(wp, func(wp Waypoint) bool { return !wp.Location.IsZero() })
 
-	if exitRoute.SID != "" {
-		ac.FlightPlan.Route = exitRoute.SID + " " + dep.Route
-	} else {
-		ac.FlightPlan.Route = dep.Route
-	}
+	ac.FlightPlan.Route = exitRoute.FiledRoute(dep.Route)
 
 	perf, ok := database.AircraftPerformance[ac.FlightPlan.BaseType()]
 	if !ok {
@@ -466,6 +620,7 @@ func (ac *Aircraft) InitializeDeparture(w *World, ap *Airport, departureAirport
 	}
 	ac.SecondaryScratchpad = dep.SecondaryScratchpad
 	ac.Exit = dep.Exit
+	ac.DepartureRunway = runway
 
 	if dep.Altitude == 0 {
 		ac.FlightPlan.Altitude = PlausibleFinalAltitude(w, ac.FlightPlan, perf)
@@ -473,6 +628,14 @@ func (ac *Aircraft) InitializeDeparture(w *World, ap *Airport, departureAirport
 		ac.FlightPlan.Altitude = dep.Altitude
 	}
 
+	// Short-haul props to nearby airports file the TEC route if there is one.
+	if tec := ap.TECRouteTo(dep.Destination, perf.Engine.AircraftType); tec != nil {
+		ac.FlightPlan.Route = tec.Route
+		ac.FlightPlan.Altitude = tec.Altitude
+		wp = append(DuplicateSlice(exitRoute.Waypoints), tec.Waypoints...)
+		wp = FilterSlice(wp, func(wp Waypoint) bool { return !wp.Location.IsZero() })
+	}
+
 	nav := MakeDepartureNav(w, *ac.FlightPlan, perf, exitRoute.AssignedAltitude,
 		exitRoute.ClearedAltitude, wp)
 	if nav == nil {
@@ -501,17 +664,27 @@ func (ac *Aircraft) InitializeDeparture(w *World, ap *Airport, departureAirport
 		ac.DepartureContactController = ctrl
 	}
 
+	ac.startAtParkingSpot(w, departureAirport, runway)
+
 	ac.Nav.Check(lg)
 
 	return nil
 }
 
 func (ac *Aircraft) NavSummary() string {
-	return ac.Nav.Summary(*ac.FlightPlan)
+	s := ac.Nav.Summary(*ac.FlightPlan)
+	if eta := ac.ETAs.Summary(); eta != "" {
+		s += "\n" + eta
+	}
+	return s
 }
 
 func (ac *Aircraft) ContactMessage(reportingPoints []ReportingPoint) string {
-	return ac.Nav.ContactMessage(reportingPoints, ac.STAR)
+	msg := ac.Nav.ContactMessage(reportingPoints, ac.STAR)
+	if ac.PracticeApproaches > 0 {
+		msg += ", " + ac.practiceApproachRequest()
+	}
+	return msg
 }
 
 func (ac *Aircraft) DepartOnCourse() {
@@ -561,6 +734,36 @@ func (ac *Aircraft) GS() float32 {
 	return ac.Nav.FlightState.GS
 }
 
+// ModeSParameters holds the parameters that Mode S transponders and ADS-B
+// downlink along with the aircraft's position and altitude.
+type ModeSParameters struct {
+	Heading          int // magnetic
+	IAS              int
+	SelectedAltitude int // 0 if no altitude has been selected
+}
+
+// DownlinkedParameters returns the parameters the aircraft is currently
+// reporting. ok is false if its transponder isn't operating.
+func (ac *Aircraft) DownlinkedParameters() (p ModeSParameters, ok bool) {
+	if ac.Mode == Standby {
+		return
+	}
+
+	p.Heading = int(ac.Heading() + 0.5)
+	if p.Heading <= 0 {
+		p.Heading += 360
+	}
+	p.IAS = int(ac.IAS() + 0.5)
+	// The pilot dials the assigned altitude into the autopilot's altitude
+	// selector, or the cleared altitude before one has been assigned.
+	if alt := ac.Nav.Altitude.Assigned; alt != nil {
+		p.SelectedAltitude = int(*alt)
+	} else if alt := ac.Nav.Altitude.Cleared; alt != nil {
+		p.SelectedAltitude = int(*alt)
+	}
+	return p, true
+}
+
 func (ac *Aircraft) OnApproach(checkAltitude bool) bool {
 	return ac.Nav.OnApproach(checkAltitude)
 }
@@ -0,0 +1,77 @@
+// aircraft_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+)
+
+func TestDownlinkedParameters(t *testing.T) {
+	alt := float32(11000)
+	ac := &Aircraft{
+		Mode: Charlie,
+		Nav: Nav{
+			FlightState: FlightState{Heading: 0.2, IAS: 249.6},
+			Altitude:    NavAltitude{Cleared: &alt},
+		},
+	}
+
+	p, ok := ac.DownlinkedParameters()
+	if !ok {
+		t.Fatalf("no parameters downlinked in mode C")
+	}
+	if p != (ModeSParameters{Heading: 360, IAS: 250, SelectedAltitude: 11000}) {
+		t.Errorf("got %+v", p)
+	}
+	if s := formatModeSParameters(&p); s != " H360 S110 I250" {
+		t.Errorf("got datablock field %q", s)
+	}
+
+	// An assigned altitude takes precedence over the cleared one.
+	assigned := float32(5000)
+	ac.Nav.Altitude.Assigned = &assigned
+	if p, _ := ac.DownlinkedParameters(); p.SelectedAltitude != 5000 {
+		t.Errorf("got selected altitude %d, expected 5000", p.SelectedAltitude)
+	}
+
+	ac.Nav.Altitude = NavAltitude{}
+	p, _ = ac.DownlinkedParameters()
+	if p.SelectedAltitude != 0 {
+		t.Errorf("got selected altitude %d without a cleared altitude", p.SelectedAltitude)
+	}
+	if s := formatModeSParameters(&p); s != " H360 I250" {
+		t.Errorf("without selected altitude: got datablock field %q", s)
+	}
+
+	ac.Mode = Standby
+	if _, ok := ac.DownlinkedParameters(); ok {
+		t.Errorf("parameters downlinked with the transponder in standby")
+	}
+	if s := formatModeSParameters(nil); s != "" {
+		t.Errorf("got %q for no parameters", s)
+	}
+}
+
+func TestAmendedRoute(t *testing.T) {
+	const filed = "MERIT J60 PSB/N0450F350 J146 GIJ BENKY5"
+	for _, test := range []struct {
+		fixes    []string
+		expected string
+	}{
+		// Direct to a fix later in the route: the filed route is unchanged.
+		{[]string{"GIJ"}, "MERIT J60 PSB/N0450F350 J146 GIJ BENKY5"},
+		// Shortcut between two fixes in the route
+		{[]string{"MERIT", "SLT", "PSB"}, "MERIT SLT PSB J146 GIJ BENKY5"},
+		// Off the route, rejoining it
+		{[]string{"XXX", "PSB"}, "XXX PSB J146 GIJ BENKY5"},
+		// A new route
+		{[]string{"XXX", "YYY"}, "XXX YYY"},
+		{[]string{"PSB", "YYY"}, "MERIT J60 PSB YYY"},
+	} {
+		if r := amendedRoute(filed, test.fixes); r != test.expected {
+			t.Errorf("%v: got %q, expected %q", test.fixes, r, test.expected)
+		}
+	}
+}
@@ -6,6 +6,7 @@ package main
 
 import (
 	"fmt"
+	"path/filepath"
 	"slices"
 	"strings"
 	"unicode"
@@ -24,8 +25,15 @@ type Airport struct {
 	// controller has the initial track.
 	DepartureController string `json:"departure_controller"`
 
+	// Optional: runway -> the altitude that departures from it are
+	// cleared to when their departure route doesn't specify one.
+	InitialAltitudes map[string]int `json:"initial_altitudes"`
+
 	ExitCategories map[string]string `json:"exit_categories"`
 
+	// Tower enroute control routes to nearby airports.
+	TECRoutes []TECRoute `json:"tec_routes"`
+
 	// runway -> (exit -> route)
 	DepartureRoutes map[string]map[string]ExitRoute `json:"departure_routes"`
 
@@ -34,6 +42,52 @@ type Airport struct {
 
 	ATPAVolumes           map[string]*ATPAVolume `json:"atpa_volumes"`
 	OmitArrivalScratchpad bool                   `json:"omit_arrival_scratchpad"`
+
+	// Optional surface detail that is drawn on the scope at short ranges.
+	Diagram *AirportDiagram `json:"diagram,omitempty"`
+
+	// Optional: where the tower cab is, for the tower view. The airport's
+	// location is used if it isn't specified.
+	TowerLocation Point2LL `json:"tower_location"`
+
+	// Optional: fixes that arrivals are metered at. Arrivals' ETAs at the
+	// first of these on their route are shown in the arrival lists.
+	MeterFixes []string `json:"meter_fixes"`
+
+	// Optional: chart files for the airport's procedures, keyed by the
+	// approach's name in "approaches" or the SID or STAR's name. See
+	// ChartPane.
+	Charts map[string]string `json:"charts"`
+
+	// Optional: the largest tailwind component in knots that the
+	// airport's runways may be used with. DefaultTailwindLimit is used if
+	// it isn't specified.
+	TailwindLimit int `json:"tailwind_limit"`
+}
+
+// AirportDiagram stores taxiways and pads for an airport; runways come
+// from the FAA database and so are not specified here.
+type AirportDiagram struct {
+	Taxiways []AirportTaxiway `json:"taxiways"`
+	Pads     []AirportPad     `json:"pads"`
+	// Parking spots for ground movement.
+	Spots []AirportSpot `json:"spots"`
+}
+
+type AirportTaxiway struct {
+	Name  string     `json:"name"`
+	Width float32    `json:"width"` // feet
+	Path  []Point2LL `json:"path"`
+}
+
+type AirportPad struct {
+	Name    string     `json:"name"`
+	Outline []Point2LL `json:"outline"`
+}
+
+type AirportSpot struct {
+	Name     string   `json:"name"`
+	Location Point2LL `json:"location"`
 }
 
 type ConvergingRunways struct {
@@ -44,6 +98,24 @@ type ConvergingRunways struct {
 	LeaderDirectionStrings [2]string                   `json:"leader_directions"`
 	LeaderDirections       [2]CardinalOrdinalDirection // not in JSON, set during deserialize
 	RunwayIntersection     Point2LL                    // not in JSON, set during deserialize
+
+	// Optional qualification regions for this pair's runways that are
+	// used instead of the airport's "approach_regions" so that pairs that
+	// share a runway can qualify aircraft differently.
+	ApproachRegions map[string]*ApproachRegion `json:"approach_regions,omitempty"`
+}
+
+// ConvergingRunwayRegions returns the approach regions for the given
+// pair's runways; those given with the pair take precedence over the
+// airport's.
+func (ap *Airport) ConvergingRunwayRegions(pair ConvergingRunways) [2]*ApproachRegion {
+	var r [2]*ApproachRegion
+	for i, rwy := range pair.Runways {
+		if r[i] = pair.ApproachRegions[rwy]; r[i] == nil {
+			r[i] = ap.ApproachRegions[rwy]
+		}
+	}
+	return r
 }
 
 type ApproachRegion struct {
@@ -249,6 +321,23 @@ func (ap *Airport) PostDeserialize(icao string, sg *ScenarioGroup, e *ErrorLogge
 		e.ErrorString("Must specify \"location\" for airport")
 	}
 
+	for _, fix := range ap.MeterFixes {
+		if _, ok := sg.locate(fix); !ok {
+			e.ErrorString("meter fix \"%s\" not found", fix)
+		}
+	}
+
+	for name, fn := range ap.Charts {
+		if !slices.Contains(chartExtensions, strings.ToLower(filepath.Ext(fn))) {
+			e.ErrorString("chart \"%s\": \"%s\" must be one of the file types %s", name, fn,
+				strings.Join(chartExtensions, ", "))
+		}
+	}
+
+	if ap.TailwindLimit < 0 {
+		e.ErrorString("\"tailwind_limit\" cannot be negative")
+	}
+
 	for name, appr := range ap.Approaches {
 		e.Push("Approach " + name)
 
@@ -408,6 +497,14 @@ func (ap *Airport) PostDeserialize(icao string, sg *ScenarioGroup, e *ErrorLogge
 	}
 	ap.DepartureRoutes = splitDepartureRoutes
 
+	for rwy, alt := range ap.InitialAltitudes {
+		if _, ok := ap.DepartureRoutes[rwy]; !ok {
+			e.ErrorString("runway \"%s\" in \"initial_altitudes\" has no \"departure_routes\"", rwy)
+		} else if alt < 1000 {
+			e.ErrorString("initial altitude %d for runway \"%s\" is too low", alt, rwy)
+		}
+	}
+
 	// Make sure if departures are initially controlled by a virtual
 	// controller, all routes have a valid handoff controller (and the
 	// converse).
@@ -426,6 +523,15 @@ func (ap *Airport) PostDeserialize(icao string, sg *ScenarioGroup, e *ErrorLogge
 				e.ErrorString("\"handoff_controller\" specified but won't be used since airport has no \"departure_controller\"")
 			}
 
+			if route.Transition != "" && route.SID == "" {
+				e.ErrorString("\"transition\" specified without \"sid\"")
+			}
+
+			if alt, ok := ap.InitialAltitudes[rwy]; ok && route.AssignedAltitude == 0 && route.ClearedAltitude == 0 {
+				// Use the runway's initial altitude.
+				route.ClearedAltitude = alt
+				routes[exit] = route
+			}
 			if route.AssignedAltitude == 0 && route.ClearedAltitude == 0 {
 				e.ErrorString("must specify either \"assigned_altitude\" or \"cleared_altitude\"")
 			} else if route.AssignedAltitude != 0 && route.ClearedAltitude != 0 {
@@ -506,6 +612,32 @@ func (ap *Airport) PostDeserialize(icao string, sg *ScenarioGroup, e *ErrorLogge
 		e.Pop()
 	}
 
+	for i := range ap.TECRoutes {
+		tec := &ap.TECRoutes[i]
+		e.Push("TEC route to " + tec.Destination)
+
+		if _, ok := database.Airports[tec.Destination]; !ok {
+			e.ErrorString("\"destination\" airport \"%s\" not found in database", tec.Destination)
+		}
+		if tec.Altitude <= 0 || tec.Altitude > 18000 {
+			e.ErrorString("invalid \"altitude\" %d", tec.Altitude)
+		}
+		if len(tec.AircraftTypes) == 0 {
+			tec.AircraftTypes = []string{"P", "T"}
+		}
+
+		for _, fix := range strings.Fields(tec.Route) {
+			wp := []Waypoint{Waypoint{Fix: fix}}
+			sg.InitializeWaypointLocations(wp, e)
+			tec.Waypoints = append(tec.Waypoints, wp...)
+		}
+		if len(tec.Waypoints) == 0 {
+			e.ErrorString("must specify \"route\"")
+		}
+
+		e.Pop()
+	}
+
 	for rwy, def := range ap.ApproachRegions {
 		e.Push(rwy + " region")
 		def.Runway = rwy
@@ -532,8 +664,16 @@ func (ap *Airport) PostDeserialize(icao string, sg *ScenarioGroup, e *ErrorLogge
 			}
 		}
 
+		for rwy, def := range pair.ApproachRegions {
+			def.Runway = rwy
+			if rwy != pair.Runways[0] && rwy != pair.Runways[1] {
+				e.ErrorString("approach region for runway \"%s\" that isn't in the pair", rwy)
+			}
+		}
+		regions := ap.ConvergingRunwayRegions(pair)
+
 		// Find the runway intersection point
-		reg0, reg1 := ap.ApproachRegions[pair.Runways[0]], ap.ApproachRegions[pair.Runways[1]]
+		reg0, reg1 := regions[0], regions[1]
 		if reg0 != nil && reg1 != nil {
 			// If either is nil, we'll flag the error below, so it's fine to ignore that here.
 			r0n := reg0.NearPoint(sg.NmPerLongitude, sg.MagneticVariation)
@@ -560,7 +700,7 @@ func (ap *Airport) PostDeserialize(icao string, sg *ScenarioGroup, e *ErrorLogge
 				e.Error(err)
 			}
 
-			if _, ok := ap.ApproachRegions[rwy]; !ok {
+			if regions[j] == nil {
 				e.ErrorString("runway not defined in \"approach_regions\"")
 			}
 			e.Pop()
@@ -626,10 +766,130 @@ func (ap *Airport) PostDeserialize(icao string, sg *ScenarioGroup, e *ErrorLogge
 
 		e.Pop()
 	}
+
+	if ap.Diagram != nil {
+		for i, twy := range ap.Diagram.Taxiways {
+			e.Push("Taxiway " + twy.Name)
+			if len(twy.Path) < 2 {
+				e.ErrorString("must specify at least two points in \"path\"")
+			}
+			if twy.Width == 0 {
+				ap.Diagram.Taxiways[i].Width = 75
+			}
+			e.Pop()
+		}
+		for _, pad := range ap.Diagram.Pads {
+			e.Push("Pad " + pad.Name)
+			if len(pad.Outline) < 3 {
+				e.ErrorString("must specify at least three points in \"outline\"")
+			}
+			e.Pop()
+		}
+		for _, spot := range ap.Diagram.Spots {
+			e.Push("Spot " + spot.Name)
+			if spot.Name == "" {
+				e.ErrorString("must specify \"name\"")
+			}
+			if spot.Location.IsZero() {
+				e.ErrorString("must specify \"location\"")
+			}
+			e.Pop()
+		}
+	}
+}
+
+// RunwayGeometry describes a runway's pavement for drawing it and for
+// determining whether aircraft are on it.
+type RunwayGeometry struct {
+	Id    string        // e.g., "4L/22R"
+	Ends  [2][2]float32 // thresholds, in nm coordinates
+	Width float32       // feet
+}
+
+// AirportRunwayGeometry returns the geometry of each of the airport's
+// runways from the FAA database, each one only once.
+func AirportRunwayGeometry(icao string, nmPerLongitude, magneticVariation float32) []RunwayGeometry {
+	fap, ok := database.Airports[icao]
+	if !ok {
+		return nil
+	}
+
+	var runways []RunwayGeometry
+	for _, rwy := range fap.Runways {
+		g := RunwayGeometry{
+			Id:    rwy.Id,
+			Width: float32(Select(rwy.Width != 0, rwy.Width, 150)),
+		}
+		g.Ends[0] = ll2nm(rwy.Threshold, nmPerLongitude)
+		if opp, ok := LookupOppositeRunway(icao, rwy.Id); ok {
+			if opp.Id < rwy.Id {
+				continue
+			}
+			g.Id += "/" + opp.Id
+			g.Ends[1] = ll2nm(opp.Threshold, nmPerLongitude)
+		} else {
+			hdg := radians(rwy.Heading - magneticVariation)
+			v := [2]float32{sin(hdg), cos(hdg)}
+			g.Ends[1] = add2f(g.Ends[0], scale2f(v, float32(rwy.Length)*FeetToNauticalMiles))
+		}
+		runways = append(runways, g)
+	}
+	return runways
+}
+
+// GenerateDiagramCommands generates draw commands for the airport's
+// surface: runways are drawn as filled quads with their actual widths,
+// taxiways as filled strips along their paths, and pads as outlines.
+// Positions are in lat-long, so the caller should load the lat-long
+// viewing matrices before calling the resulting command buffer.
+func (ap *Airport) GenerateDiagramCommands(icao string, nmPerLongitude, magneticVariation float32,
+	runwayColor, taxiwayColor RGB, cb *CommandBuffer) {
+	trid := GetTrianglesDrawBuilder()
+	defer ReturnTrianglesDrawBuilder(trid)
+
+	// Adds a quad of the given width (in feet) along the segment p0-p1,
+	// which is specified in nm coordinates.
+	addStrip := func(p0, p1 [2]float32, width float32) {
+		v := sub2f(p1, p0)
+		if length2f(v) == 0 {
+			return
+		}
+		v = normalize2f(v)
+		vperp := scale2f([2]float32{-v[1], v[0]}, width/2*FeetToNauticalMiles)
+		trid.AddQuad(nm2ll(add2f(p0, vperp), nmPerLongitude), nm2ll(add2f(p1, vperp), nmPerLongitude),
+			nm2ll(sub2f(p1, vperp), nmPerLongitude), nm2ll(sub2f(p0, vperp), nmPerLongitude))
+	}
+
+	for _, rwy := range AirportRunwayGeometry(icao, nmPerLongitude, magneticVariation) {
+		addStrip(rwy.Ends[0], rwy.Ends[1], rwy.Width)
+	}
+	cb.SetRGB(runwayColor)
+	trid.GenerateCommands(cb)
+
+	if ap.Diagram == nil {
+		return
+	}
+
+	trid.Reset()
+	for _, twy := range ap.Diagram.Taxiways {
+		for i := 0; i+1 < len(twy.Path); i++ {
+			addStrip(ll2nm(twy.Path[i], nmPerLongitude), ll2nm(twy.Path[i+1], nmPerLongitude), twy.Width)
+		}
+	}
+	cb.SetRGB(taxiwayColor)
+	trid.GenerateCommands(cb)
+
+	ld := GetLinesDrawBuilder()
+	defer ReturnLinesDrawBuilder(ld)
+	for _, pad := range ap.Diagram.Pads {
+		ld.AddLineLoop(MapSlice(pad.Outline, func(p Point2LL) [2]float32 { return p }))
+	}
+	ld.GenerateCommands(cb)
 }
 
 type ExitRoute struct {
 	SID              string        `json:"sid"`
+	Transition       string        `json:"transition"` // optional, SID transition filed by departures
 	AssignedAltitude int           `json:"assigned_altitude"`
 	ClearedAltitude  int           `json:"cleared_altitude"`
 	Waypoints        WaypointArray `json:"waypoints"`
@@ -638,6 +898,47 @@ type ExitRoute struct {
 	HandoffController string `json:"handoff_controller"`
 }
 
+// FiledRoute returns the route filed by a departure flying the exit route,
+// given the rest of its route after the SID.
+func (er ExitRoute) FiledRoute(route string) string {
+	if er.SID == "" {
+		return route
+	}
+
+	sid := er.SID
+	if er.Transition != "" {
+		sid += "." + er.Transition
+		// The transition fix is given by the SID, so don't repeat it.
+		if f := strings.Fields(route); len(f) > 0 && f[0] == er.Transition {
+			route = strings.Join(f[1:], " ")
+		}
+	}
+	return strings.TrimSpace(sid + " " + route)
+}
+
+// TECRoute is a tower enroute control (TEC) route: a low-altitude IFR
+// route to a nearby airport that stays within approach control airspace.
+// Departures to the destination that have one of the given engine types
+// file it, and it can be assigned to others with the "CTEC" command.
+type TECRoute struct {
+	Destination   string        `json:"destination"`
+	Route         string        `json:"route"`
+	Altitude      int           `json:"altitude"`
+	AircraftTypes []string      `json:"aircraft_types"` // engine types; default "P" and "T"
+	Waypoints     WaypointArray // not specified in user JSON
+}
+
+// TECRouteTo returns the TEC route from the airport to the given
+// destination for aircraft with the given engine type, if there is one.
+func (ap *Airport) TECRouteTo(destination, engineType string) *TECRoute {
+	for i, tec := range ap.TECRoutes {
+		if tec.Destination == destination && slices.Contains(tec.AircraftTypes, engineType) {
+			return &ap.TECRoutes[i]
+		}
+	}
+	return nil
+}
+
 type Departure struct {
 	Exit string `json:"exit"`
 
@@ -0,0 +1,27 @@
+// airport_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+)
+
+func TestExitRouteFiledRoute(t *testing.T) {
+	for _, test := range []struct {
+		exit     ExitRoute
+		route    string
+		expected string
+	}{
+		{ExitRoute{}, "WAVEY EMJAY J174", "WAVEY EMJAY J174"},
+		{ExitRoute{SID: "JFK5"}, "WAVEY EMJAY J174", "JFK5 WAVEY EMJAY J174"},
+		{ExitRoute{SID: "DEEZZ5", Transition: "CANDR"}, "J60 PSB", "DEEZZ5.CANDR J60 PSB"},
+		{ExitRoute{SID: "DEEZZ5", Transition: "CANDR"}, "CANDR J60 PSB", "DEEZZ5.CANDR J60 PSB"},
+		{ExitRoute{SID: "DEEZZ5", Transition: "CANDR"}, "", "DEEZZ5.CANDR"},
+	} {
+		if r := test.exit.FiledRoute(test.route); r != test.expected {
+			t.Errorf("%+v %q: got %q, expected %q", test.exit, test.route, r, test.expected)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+// alerts.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// AlertSeverity orders alerts in the alert list and determines how their
+// sounds are played: warnings sound continuously until they are
+// acknowledged, cautions sound once when they first occur, and
+// advisories are silent.
+type AlertSeverity int
+
+const (
+	AlertAdvisory AlertSeverity = iota
+	AlertCaution
+	AlertWarning
+)
+
+func (s AlertSeverity) String() string {
+	return []string{"ADVISORY", "CAUTION", "WARNING"}[s]
+}
+
+func (s AlertSeverity) Color() RGB {
+	return []RGB{{0.3, 0.8, 0.9}, {1, 0.8, 0.2}, {1, 0.25, 0.25}}[s]
+}
+
+// noAlertAudio is used for alerts that have no sound.
+const noAlertAudio AudioType = -1
+
+// Alert is a condition that the controller should be made aware of. The
+// features that detect them report the current ones to the AlertManager
+// every frame; an alert is identified across frames by its Id.
+type Alert struct {
+	Id        string
+	Severity  AlertSeverity
+	Text      string
+	Callsigns []string
+	Audio     AudioType
+	// The alert's sound stops at this time even if the alert hasn't been
+	// acknowledged; zero if it plays until it is.
+	SoundEnd time.Time
+	// Alerts are only shown once they have been active for this long.
+	Delay time.Duration
+	// Set if the alert has been acknowledged elsewhere, e.g. by a
+	// scope command.
+	Acknowledged bool
+
+	// Optional callbacks for when the controller acknowledges or
+	// inhibits the alert in the alert list; otherwise, the AlertManager
+	// keeps track of it. Inhibited alerts aren't shown or sounded until
+	// their condition clears.
+	OnAcknowledge func()
+	OnInhibit     func()
+}
+
+// activeAlert is an alert along with the AlertManager's state for it.
+type activeAlert struct {
+	Alert
+	since        time.Time
+	acknowledged bool
+	inhibited    bool
+	sounded      bool
+}
+
+// AlertManager collects the active alerts, keeps them in priority order,
+// and plays their sounds.
+type AlertManager struct {
+	alerts []*activeAlert
+	show   bool
+}
+
+func NewAlertManager() *AlertManager {
+	return &AlertManager{}
+}
+
+func (w *World) AlertManager() *AlertManager {
+	if w.alertManager == nil {
+		w.alertManager = NewAlertManager()
+	}
+	return w.alertManager
+}
+
+// update takes the currently-active alerts, updates the alert list, and
+// returns the sounds that should be playing continuously and the ones
+// that should be played once.
+func (am *AlertManager) update(alerts []Alert, now time.Time) (continuous, once []AudioType) {
+	var updated []*activeAlert
+	for _, a := range alerts {
+		idx := slices.IndexFunc(am.alerts, func(aa *activeAlert) bool { return aa.Id == a.Id })
+		if idx == -1 {
+			updated = append(updated, &activeAlert{Alert: a, since: now})
+		} else {
+			aa := am.alerts[idx]
+			aa.Alert = a
+			updated = append(updated, aa)
+		}
+	}
+	// Alerts that aren't reported any more have cleared.
+	am.alerts = updated
+
+	slices.SortStableFunc(am.alerts, func(a, b *activeAlert) int {
+		if a.Severity != b.Severity {
+			return int(b.Severity) - int(a.Severity)
+		}
+		return a.since.Compare(b.since)
+	})
+
+	for _, a := range am.alerts {
+		if !a.visible(now) || a.Audio == noAlertAudio {
+			continue
+		}
+		switch a.Severity {
+		case AlertWarning:
+			if !a.isAcknowledged() && (a.SoundEnd.IsZero() || now.Before(a.SoundEnd)) &&
+				!slices.Contains(continuous, a.Audio) {
+				continuous = append(continuous, a.Audio)
+			}
+		case AlertCaution:
+			if !a.sounded && !a.isAcknowledged() {
+				once = append(once, a.Audio)
+			}
+		}
+		a.sounded = true
+	}
+	return
+}
+
+// Update takes the alerts that are currently active and plays their
+// sounds according to their severity.
+func (am *AlertManager) Update(alerts []Alert, now time.Time) {
+	continuous, once := am.update(alerts, now)
+
+	// All of the other sounds are stopped, not just ones started here, so
+	// that ones started by another World's AlertManager (e.g., before the
+	// user switched tabs) don't keep playing.
+	for e := AudioType(0); e < AudioNumTypes; e++ {
+		if slices.Contains(continuous, e) {
+			globalConfig.Audio.StartPlayContinuous(e)
+		} else {
+			globalConfig.Audio.StopPlayContinuous(e)
+		}
+	}
+	for _, e := range once {
+		globalConfig.Audio.PlayOnce(e)
+	}
+}
+
+func (a *activeAlert) visible(now time.Time) bool {
+	return !a.inhibited && now.Sub(a.since) >= a.Delay
+}
+
+func (a *activeAlert) isAcknowledged() bool {
+	return a.acknowledged || a.Acknowledged
+}
+
+func (a *activeAlert) acknowledge() {
+	a.acknowledged = true
+	if a.OnAcknowledge != nil {
+		a.OnAcknowledge()
+	}
+}
+
+func (a *activeAlert) inhibit() {
+	a.inhibited = true
+	if a.OnInhibit != nil {
+		a.OnInhibit()
+	}
+}
+
+// Visible returns the alerts that should be shown, in priority order.
+func (am *AlertManager) Visible(now time.Time) []Alert {
+	var alerts []Alert
+	for _, a := range am.alerts {
+		if a.visible(now) {
+			alerts = append(alerts, a.Alert)
+		}
+	}
+	return alerts
+}
+
+// Unacknowledged returns the number of visible alerts of at least the
+// given severity that haven't been acknowledged.
+func (am *AlertManager) Unacknowledged(severity AlertSeverity, now time.Time) int {
+	n := 0
+	for _, a := range am.alerts {
+		if a.visible(now) && a.Severity >= severity && !a.isAcknowledged() {
+			n++
+		}
+	}
+	return n
+}
+
+func (am *AlertManager) ToggleShow() {
+	am.show = !am.show
+}
+
+// DrawWindow draws the alert list, if it's open.
+func (am *AlertManager) DrawWindow(eventStream *EventStream) {
+	if !am.show {
+		return
+	}
+
+	now := time.Now()
+	imgui.BeginV("Alerts", &am.show, imgui.WindowFlagsAlwaysAutoResize)
+
+	n := 0
+	for _, a := range am.alerts {
+		if !a.visible(now) {
+			continue
+		}
+		n++
+
+		imgui.PushID(a.Id)
+		c := a.Severity.Color()
+		if a.isAcknowledged() {
+			c = c.Scale(0.6)
+		}
+		imgui.PushStyleColor(imgui.StyleColorText, imgui.Vec4{c.R, c.G, c.B, 1})
+		imgui.Text(fmt.Sprintf("%-8s %s %s", a.Severity, a.since.Format("15:04:05"), a.Text))
+		imgui.PopStyleColor()
+
+		if len(a.Callsigns) > 0 {
+			imgui.SameLine()
+			if imgui.Button("Select") {
+				eventStream.Post(Event{Type: SelectedAircraftEvent, Callsign: a.Callsigns[0], Selected: true})
+			}
+		}
+		if !a.isAcknowledged() {
+			imgui.SameLine()
+			if imgui.Button("Ack") {
+				a.acknowledge()
+			}
+		}
+		imgui.SameLine()
+		if imgui.Button("Inhibit") {
+			a.inhibit()
+		}
+		imgui.PopID()
+	}
+	if n == 0 {
+		imgui.Text("No active alerts")
+	}
+
+	imgui.End()
+}
+
+///////////////////////////////////////////////////////////////////////////
+// STARS alerts
+
+const (
+	// Inbound handoffs are alerted if they haven't been accepted after
+	// this long and outbound ones if they haven't been accepted after
+	// twice as long.
+	handoffAlertTimeout = 60 * time.Second
+	// Aircraft more than this many feet from their assigned altitude and
+	// moving away from it are alerted.
+	altitudeDeviationLimit = 300
+)
+
+// collectAlerts returns the alerts for the aircraft on the scope and the
+// controller's position.
+func (sp *STARSPane) collectAlerts(ctx *PaneContext, aircraft []*Aircraft) []Alert {
+	ps := sp.CurrentPreferenceSet
+	w := ctx.world
+	var alerts []Alert
+
+	if !ps.DisableCAWarnings {
+		for _, ca := range sp.CAAircraft {
+			if sp.Aircraft[ca.Callsigns[0]].DisableCAWarnings || sp.Aircraft[ca.Callsigns[1]].DisableCAWarnings {
+				continue
+			}
+			callsigns := ca.Callsigns
+			alerts = append(alerts, Alert{
+				Id:           "CA " + strings.Join(callsigns[:], " "),
+				Severity:     AlertWarning,
+				Text:         "CONFLICT ALERT " + callsigns[0] + " " + callsigns[1],
+				Callsigns:    callsigns[:],
+				Audio:        AudioConflictAlert,
+				SoundEnd:     ca.SoundEnd,
+				Acknowledged: ca.Acknowledged,
+				OnAcknowledge: func() {
+					if i := slices.IndexFunc(sp.CAAircraft, func(c CAAircraft) bool { return c.Callsigns == callsigns }); i != -1 {
+						sp.CAAircraft[i].Acknowledged = true
+					}
+				},
+			})
+		}
+	}
+
+	for _, ac := range aircraft {
+		state := sp.Aircraft[ac.Callsign]
+
+		if !ps.DisableMSAW && state.MSAW && !state.InhibitMSAW && !state.DisableMSAW {
+			alerts = append(alerts, Alert{
+				Id:            "MSAW " + ac.Callsign,
+				Severity:      AlertWarning,
+				Text:          "LOW ALTITUDE " + ac.Callsign,
+				Callsigns:     []string{ac.Callsign},
+				Audio:         AudioMinimumSafeAltitudeWarning,
+				SoundEnd:      state.MSAWSoundEnd,
+				Acknowledged:  state.MSAWAcknowledged,
+				OnAcknowledge: func() { state.MSAWAcknowledged = true },
+				OnInhibit:     func() { state.InhibitMSAW = true },
+			})
+		}
+
+		if ok, code := SquawkIsSPC(ac.Squawk); ok && (code == "EM" || code == "RF" || code == "HJ") {
+			alerts = append(alerts, Alert{
+				Id:        code + " " + ac.Callsign,
+				Severity:  AlertCaution,
+				Text:      code + " " + ac.Callsign + " SQUAWKING " + ac.Squawk.String(),
+				Callsigns: []string{ac.Callsign},
+				Audio:     AudioEmergencySquawk,
+			})
+		}
+
+		if ac.HandoffTrackController == w.Callsign {
+			alerts = append(alerts, Alert{
+				Id:        "HO IN " + ac.Callsign,
+				Severity:  AlertCaution,
+				Text:      "HANDOFF " + ac.Callsign + " NOT ACCEPTED",
+				Callsigns: []string{ac.Callsign},
+				Audio:     AudioInboundHandoff,
+				Delay:     handoffAlertTimeout,
+			})
+		} else if ac.TrackingController == w.Callsign && ac.HandoffTrackController != "" {
+			to := ac.HandoffTrackController
+			if ctrl := w.GetControllerByCallsign(to); ctrl != nil {
+				to = ctrl.SectorId
+			}
+			alerts = append(alerts, Alert{
+				Id:        "HO OUT " + ac.Callsign,
+				Severity:  AlertAdvisory,
+				Text:      "HANDOFF " + ac.Callsign + " TO " + to + " NOT ACCEPTED",
+				Callsigns: []string{ac.Callsign},
+				Audio:     noAlertAudio,
+				Delay:     2 * handoffAlertTimeout,
+			})
+		}
+
+		if ac.ControllingController == w.Callsign && ac.Nav.Altitude.Assigned != nil {
+			assigned := int(*ac.Nav.Altitude.Assigned)
+			if altitudeDeviation(assigned, state.TrackAltitude(), state.TrackAltitudeRate()) {
+				alerts = append(alerts, Alert{
+					Id:        "ALT " + ac.Callsign,
+					Severity:  AlertCaution,
+					Text:      fmt.Sprintf("ALTITUDE DEVIATION %s %03d ASSIGNED %03d", ac.Callsign, (state.TrackAltitude()+50)/100, (assigned+50)/100),
+					Callsigns: []string{ac.Callsign},
+					Audio:     noAlertAudio,
+					// Aircraft take a moment to stop climbing or
+					// descending after a new altitude is assigned.
+					Delay: 10 * time.Second,
+				})
+			}
+		}
+	}
+
+	for f := SystemFailureType(0); f < NumSystemFailureTypes; f++ {
+		if w.HasSystemFailure(f) {
+			alerts = append(alerts, Alert{
+				Id:       "FAIL " + f.String(),
+				Severity: AlertCaution,
+				Text:     f.SSAText(),
+				Audio:    noAlertAudio,
+			})
+		}
+	}
+	for _, p := range w.PIREPs {
+		if p.Urgent() {
+			alerts = append(alerts, Alert{
+				Id:       p.String(),
+				Severity: AlertCaution,
+				Text:     p.String(),
+				Audio:    noAlertAudio,
+			})
+		}
+	}
+	for _, ro := range w.RadarOutages {
+		text := Select(ro.Site != "", "RDR "+ro.Site+" OUT", "RDR OUT "+ro.Fix)
+		alerts = append(alerts, Alert{
+			Id:       text,
+			Severity: AlertAdvisory,
+			Text:     text,
+			Audio:    noAlertAudio,
+		})
+	}
+
+	return alerts
+}
+
+// altitudeDeviation indicates whether an aircraft at the given altitude
+// and vertical speed (feet per second) is deviating from its assigned
+// altitude: it's well away from it and moving farther away.
+func altitudeDeviation(assigned, altitude int, rate float32) bool {
+	d := altitude - assigned
+	return abs(d) > altitudeDeviationLimit && float32(d)*rate > 0
+}
@@ -0,0 +1,95 @@
+// alerts_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAlertManager(t *testing.T) {
+	am := NewAlertManager()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	ids := func() []string {
+		var ids []string
+		for _, a := range am.Visible(now) {
+			ids = append(ids, a.Id)
+		}
+		return ids
+	}
+
+	ca := Alert{Id: "CA", Severity: AlertWarning, Audio: AudioConflictAlert, SoundEnd: now.Add(10 * time.Second)}
+	ho := Alert{Id: "HO", Severity: AlertCaution, Audio: AudioInboundHandoff, Delay: time.Minute}
+	rdr := Alert{Id: "RDR", Severity: AlertAdvisory, Audio: noAlertAudio}
+	em := Alert{Id: "EM", Severity: AlertCaution, Audio: AudioEmergencySquawk}
+
+	continuous, once := am.update([]Alert{rdr, ho, ca}, now)
+	if !slices.Equal(continuous, []AudioType{AudioConflictAlert}) || len(once) != 0 {
+		t.Errorf("got continuous %v once %v, expected only the conflict alert", continuous, once)
+	}
+	// The handoff alert isn't shown until its delay has passed.
+	if got := ids(); !slices.Equal(got, []string{"CA", "RDR"}) {
+		t.Errorf("got visible alerts %v, expected [CA RDR]", got)
+	}
+
+	// Cautions sound once; warnings stop sounding at SoundEnd.
+	now = now.Add(time.Minute)
+	continuous, once = am.update([]Alert{rdr, ho, ca, em}, now)
+	if len(continuous) != 0 || !slices.Equal(once, []AudioType{AudioInboundHandoff, AudioEmergencySquawk}) {
+		t.Errorf("got continuous %v once %v", continuous, once)
+	}
+	if got := ids(); !slices.Equal(got, []string{"CA", "HO", "EM", "RDR"}) {
+		t.Errorf("got visible alerts %v, expected [CA HO EM RDR]", got)
+	}
+	if n := am.Unacknowledged(AlertCaution, now); n != 3 {
+		t.Errorf("got %d unacknowledged alerts, expected 3", n)
+	}
+
+	now = now.Add(time.Second)
+	if continuous, once = am.update([]Alert{rdr, ho, ca, em}, now); len(continuous) != 0 || len(once) != 0 {
+		t.Errorf("got continuous %v once %v, expected no sounds", continuous, once)
+	}
+
+	// Acknowledged warnings are silent; inhibited alerts are hidden until
+	// their condition clears.
+	ca.SoundEnd = time.Time{}
+	acked := false
+	ca.OnAcknowledge = func() { acked = true }
+	am.update([]Alert{ca, em}, now)
+	am.alerts[0].acknowledge()
+	am.alerts[1].inhibit()
+	if continuous, _ = am.update([]Alert{ca, em}, now); len(continuous) != 0 || !acked {
+		t.Errorf("got continuous %v after acknowledging", continuous)
+	}
+	if got := ids(); !slices.Equal(got, []string{"CA"}) {
+		t.Errorf("got visible alerts %v, expected [CA]", got)
+	}
+	am.update(nil, now)
+	if _, once = am.update([]Alert{em}, now); !slices.Equal(once, []AudioType{AudioEmergencySquawk}) {
+		t.Errorf("got once %v for a new emergency alert", once)
+	}
+}
+
+func TestAltitudeDeviation(t *testing.T) {
+	for _, tc := range []struct {
+		assigned, altitude int
+		rate               float32
+		deviation          bool
+	}{
+		{5000, 5000, 0, false},
+		{5000, 5200, 10, false},
+		{5000, 5400, 10, true},
+		{5000, 5400, -10, false},
+		{5000, 4500, -20, true},
+		{5000, 4500, 0, false},
+		{5000, 3000, 25, false},
+	} {
+		if d := altitudeDeviation(tc.assigned, tc.altitude, tc.rate); d != tc.deviation {
+			t.Errorf("altitudeDeviation(%d, %d, %f) = %v, expected %v", tc.assigned, tc.altitude, tc.rate, d, tc.deviation)
+		}
+	}
+}
@@ -163,19 +163,24 @@ func ParseARINC424(file []byte) (map[string]FAAAirport, map[string]Navaid, map[s
 				}
 
 				name := strings.TrimSpace(string(line[93:123]))
+				// VOR frequencies are given in units of 10kHz and NDB
+				// frequencies in units of 0.1kHz.
+				freq := Frequency(parseInt(line[22:27]) * Select(subsectionCode == ' ', 10, 100))
 				if !empty(line[32:51]) {
 					navaids[id] = Navaid{
-						Id:       id,
-						Type:     Select(subsectionCode == ' ', "VOR", "NDB"),
-						Name:     name,
-						Location: parseLatLong(line[32:41], line[41:51]),
+						Id:        id,
+						Type:      Select(subsectionCode == ' ', "VOR", "NDB"),
+						Name:      name,
+						Location:  parseLatLong(line[32:41], line[41:51]),
+						Frequency: freq,
 					}
 				} else {
 					navaids[id] = Navaid{
-						Id:       id,
-						Type:     "DME",
-						Name:     name,
-						Location: parseLatLong(line[55:64], line[64:74]),
+						Id:        id,
+						Type:      "DME",
+						Name:      name,
+						Location:  parseLatLong(line[55:64], line[64:74]),
+						Frequency: freq,
 					}
 				}
 			}
@@ -287,6 +292,8 @@ func ParseARINC424(file []byte) (map[string]FAAAirport, map[string]Navaid, map[s
 					Heading:   float32(parseInt(line[27:31])) / 10,
 					Threshold: parseLatLong(line[32:41], line[41:51]),
 					Elevation: parseInt(line[66:71]),
+					Length:    parseInt(line[22:27]),
+					Width:     parseInt(line[77:80]),
 				})
 				airports[icao] = ap
 			}
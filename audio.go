@@ -10,6 +10,7 @@ import "C"
 
 import (
 	"C"
+	"math"
 	"sync"
 	"unsafe"
 
@@ -32,6 +33,11 @@ const (
 	AudioInboundHandoff
 	AudioCommandError
 	AudioHandoffAccepted
+	AudioArrivalCheckIn
+	AudioDepartureCheckIn
+	AudioSatelliteRequest
+	AudioPointOut
+	AudioFrequencyMessage
 	AudioNumTypes
 )
 
@@ -44,20 +50,116 @@ func (ae AudioType) String() string {
 		"Inbound Handoff",
 		"Command Error",
 		"Handoff Accepted",
+		"Arrival Check-in",
+		"Departure Check-in",
+		"Satellite Airport Request",
+		"Point Out",
+		"Frequency Message",
 	}[ae]
 }
 
+// The check-in cues tell the controller that an aircraft has called
+// without their having to read the messages; each can be set to one of
+// the sounds in audioCueSounds.
+var audioCheckInCues = []AudioType{AudioArrivalCheckIn, AudioDepartureCheckIn, AudioSatelliteRequest}
+
+// audioTone is a sine tone at the given frequency in Hz; a zero frequency
+// gives silence.
+type audioTone struct {
+	Frequency float32
+	Duration  float32 // seconds
+}
+
+// audioCueSounds are synthesized so that they are distinct from each
+// other and from the alerts.
+var audioCueSounds = map[string][]audioTone{
+	"Two-tone chime":  {{659, 0.15}, {523, 0.3}},
+	"Rising chime":    {{523, 0.12}, {659, 0.12}, {784, 0.25}},
+	"Triple beep":     {{988, 0.08}, {0, 0.06}, {988, 0.08}, {0, 0.06}, {988, 0.08}},
+	"Low tone":        {{330, 0.4}},
+	"Falling chime":   {{784, 0.12}, {659, 0.12}, {523, 0.25}},
+	"Double low beep": {{440, 0.1}, {0, 0.08}, {440, 0.1}},
+}
+
+var defaultAudioCueSounds = map[AudioType]string{
+	AudioArrivalCheckIn:   "Two-tone chime",
+	AudioDepartureCheckIn: "Rising chime",
+	AudioSatelliteRequest: "Triple beep",
+}
+
+// checkInCue returns the cue to play when the given aircraft first
+// contacts the controller: VFRs from satellite airports are calling to
+// request a transition, departures have just become airborne, and
+// everything else is entering the airspace.
+func checkInCue(w *World, callsign string) (AudioType, bool) {
+	ac, ok := w.Aircraft[callsign]
+	if !ok || ac.FlightPlan == nil {
+		return 0, false
+	}
+
+	fp := ac.FlightPlan
+	if _, ok := w.SatelliteTraffic[fp.DepartureAirport]; ok && fp.Rules == VFR {
+		return AudioSatelliteRequest, true
+	} else if ac.IsDeparture() {
+		return AudioDepartureCheckIn, true
+	}
+	return AudioArrivalCheckIn, true
+}
+
+// synthesizeTones returns 16-bit PCM samples for the given tones. Each
+// is faded in and out so that there aren't clicks between them.
+func synthesizeTones(tones []audioTone) []byte {
+	var pcm []byte
+	const fade = 0.01 // seconds
+	for _, t := range tones {
+		n := int(t.Duration * AudioSampleRate)
+		for i := 0; i < n; i++ {
+			ts := float32(i) / AudioSampleRate
+			gain := min(1, min(ts/fade, (t.Duration-ts)/fade))
+			v := int16(8000 * gain * float32(math.Sin(2*math.Pi*float64(t.Frequency*ts))))
+			pcm = append(pcm, byte(v&0xff), byte((v>>8)&0xff))
+		}
+	}
+	return pcm
+}
+
 type AudioEngine struct {
 	AudioEnabled  bool
 	EffectEnabled [AudioNumTypes]bool
+	SpeechEnabled bool
+	// Names of the sounds in audioCueSounds used for the check-in cues.
+	CueSounds map[AudioType]string
+	// Settings for the alerts in audioCustomAlerts
+	Alerts map[AudioType]AudioAlertSettings
+
+	VoiceCommandsEnabled bool
+	PushToTalkKey        int    // GLFW key code; see PushToTalkKeys
+	WhisperModel         string // path to the whisper.cpp model file
 
 	effects [AudioNumTypes]AudioEffect
 
+	// The built-in and current sounds for the alerts in
+	// audioCustomAlerts; the effects add silence after them according to
+	// their repeat intervals.
+	builtinSounds [AudioNumTypes][]byte
+	alertSounds   [AudioNumTypes][]byte
+	alertErrors   map[AudioType]string
+
+	// Synthesized pilot transmissions waiting to be played; the first
+	// one is currently playing, starting at speechOffset.
+	speech         [][]byte
+	speechOffset   int
+	speechRequests chan speechRequest
+	speechBackend  string
+
+	recordingDevice sdl.AudioDeviceID
+
 	mu sync.Mutex
 }
 
 type AudioEffect struct {
 	pcm            []byte
+	gain           float32
 	playOnceCount  int
 	playContinuous bool
 	playOffset     int
@@ -68,6 +170,21 @@ func (a *AudioEngine) SetDefaults() {
 	for i := 0; i < AudioNumTypes; i++ {
 		a.EffectEnabled[i] = true
 	}
+	a.SpeechEnabled = true
+	a.CueSounds = DuplicateMap(defaultAudioCueSounds)
+	a.Alerts = defaultAudioAlertSettings()
+	// Every pilot transmission would sound it, so it's off by default.
+	a.EffectEnabled[AudioFrequencyMessage] = false
+	a.PushToTalkKey = PushToTalkKeys["Insert"]
+}
+
+// setCueSound sets the sound used for the given check-in cue.
+func (a *AudioEngine) setCueSound(e AudioType, name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.CueSounds[e] = name
+	a.effects[e] = AudioEffect{pcm: synthesizeTones(audioCueSounds[name]), gain: 1}
 }
 
 func (a *AudioEngine) PlayOnce(e AudioType) {
@@ -111,6 +228,20 @@ func audioCallback(user unsafe.Pointer, ptr *C.uint8, size C.int) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
+	// Play the current pilot transmission, if any, and duck the sound
+	// effects while it's playing.
+	effectGain := float32(1)
+	for i := 0; i < n/2 && len(a.speech) > 0; i++ {
+		s := a.speech[0]
+		accum[i] += int(int16(s[a.speechOffset]) | int16(s[a.speechOffset+1])<<8)
+		a.speechOffset += 2
+		if a.speechOffset == len(s) {
+			a.speech = a.speech[1:]
+			a.speechOffset = 0
+		}
+		effectGain = speechDuckGain
+	}
+
 	for i := range a.effects {
 		e := &a.effects[i]
 		buf := make([]byte, n)
@@ -129,7 +260,7 @@ func audioCallback(user unsafe.Pointer, ptr *C.uint8, size C.int) {
 		}
 
 		for i := 0; i < len(buf)/2; i++ {
-			accum[i] += int(int16(buf[2*i])|int16(buf[2*i+1])<<8) / 2
+			accum[i] += int(effectGain*e.gain*float32(int16(buf[2*i])|int16(buf[2*i+1])<<8)) / 2
 		}
 	}
 
@@ -152,7 +283,7 @@ func (a *AudioEngine) loadMP3(filename string) AudioEffect {
 		lg.Errorf("expected 1 channel, got %d", dec.Channels)
 	}
 
-	return AudioEffect{pcm: pcm}
+	return AudioEffect{pcm: pcm, gain: 1}
 }
 
 func (a *AudioEngine) Activate() error {
@@ -176,23 +307,165 @@ func (a *AudioEngine) Activate() error {
 	a.effects[AudioCommandError] = a.loadMP3("426888__thisusernameis__beep4.mp3")
 	a.effects[AudioHandoffAccepted] = a.loadMP3("321104__nsstudios__blip2.mp3")
 
+	if a.CueSounds == nil {
+		// The check-in cues were added after the other effects, so they
+		// are enabled by default in configs from before then.
+		a.CueSounds = make(map[AudioType]string)
+		for _, e := range audioCheckInCues {
+			a.EffectEnabled[e] = a.AudioEnabled
+		}
+	}
+	for _, e := range audioCheckInCues {
+		name, ok := a.CueSounds[e]
+		if _, valid := audioCueSounds[name]; !ok || !valid {
+			name = defaultAudioCueSounds[e]
+		}
+		a.setCueSound(e, name)
+	}
+
+	if a.Alerts == nil {
+		// Alert settings were added along with the point out and
+		// frequency message alerts; point outs are enabled by default in
+		// configs from before then.
+		a.Alerts = defaultAudioAlertSettings()
+		a.EffectEnabled[AudioPointOut] = a.AudioEnabled
+	}
+	a.alertErrors = make(map[AudioType]string)
+	for _, e := range audioCustomAlerts {
+		if tones, ok := audioAlertTones[e]; ok {
+			a.builtinSounds[e] = synthesizeTones(tones)
+		} else {
+			a.builtinSounds[e] = a.effects[e].pcm
+		}
+		if _, ok := a.Alerts[e]; !ok {
+			a.Alerts[e] = AudioAlertSettings{Volume: 1}
+		}
+		if err := a.setAlertSound(e); err != nil {
+			lg.Errorf("%s: %v", e, err)
+			a.alertErrors[e] = err.Error()
+		}
+	}
+
+	if a.speechBackend = speechBackend(); a.speechBackend == "" {
+		lg.Warnf("Audio: %v; pilot transmissions will not be spoken", ErrNoSpeechBackend)
+	} else {
+		lg.Infof("Audio: using %s for text-to-speech", a.speechBackend)
+		a.speechRequests = make(chan speechRequest, 8)
+		go a.speechWorker(a.speechBackend)
+	}
+
 	lg.Info("Finished initializing audio")
 	return nil
 }
 
+// StartRecording starts recording from the default audio input device.
+func (a *AudioEngine) StartRecording() error {
+	spec := sdl.AudioSpec{
+		Freq:     VoiceRecordingSampleRate,
+		Format:   sdl.AUDIO_S16SYS,
+		Channels: 1,
+		Samples:  1024,
+	}
+	dev, err := sdl.OpenAudioDevice("", true, &spec, nil, 0)
+	if err != nil {
+		return err
+	}
+	a.recordingDevice = dev
+	sdl.PauseAudioDevice(dev, false)
+	return nil
+}
+
+// StopRecording stops recording and returns the samples that were
+// recorded since StartRecording was called.
+func (a *AudioEngine) StopRecording() []int16 {
+	if a.recordingDevice == 0 {
+		return nil
+	}
+	defer func() {
+		sdl.CloseAudioDevice(a.recordingDevice)
+		a.recordingDevice = 0
+	}()
+
+	buf := make([]byte, sdl.GetQueuedAudioSize(a.recordingDevice))
+	// SDL_DequeueAudio returns the number of bytes dequeued, which
+	// go-sdl2 takes to be an error code, so the returned error is
+	// meaningless.
+	_ = sdl.DequeueAudio(a.recordingDevice, buf)
+
+	pcm := make([]int16, len(buf)/2)
+	for i := range pcm {
+		pcm[i] = int16(buf[2*i]) | int16(buf[2*i+1])<<8
+	}
+	return pcm
+}
+
 func (a *AudioEngine) DrawUI() {
 	imgui.Checkbox("Enable Sound Effects", &a.AudioEnabled)
 	imgui.Separator()
 
 	uiStartDisable(!a.AudioEnabled)
 	// Not all of the ones available in the engine are used, so only offer these up:
-	for _, i := range []AudioType{AudioConflictAlert, AudioInboundHandoff, AudioHandoffAccepted, AudioCommandError} {
+	for _, i := range []AudioType{AudioHandoffAccepted, AudioCommandError} {
 		if imgui.Checkbox(AudioType(i).String(), &a.EffectEnabled[i]) && a.EffectEnabled[i] {
-			n := Select(i == AudioConflictAlert, 5, 1)
-			for j := 0; j < n; j++ {
-				a.PlayOnce(i)
+			a.PlayOnce(i)
+		}
+	}
+
+	imgui.Separator()
+	imgui.Text("Alerts:")
+	a.drawAlertsUI()
+
+	imgui.Separator()
+	imgui.Text("Check-in cues:")
+	for _, e := range audioCheckInCues {
+		imgui.PushID(e.String())
+		if imgui.Checkbox(e.String(), &a.EffectEnabled[e]) && a.EffectEnabled[e] {
+			a.PlayOnce(e)
+		}
+		imgui.SameLine()
+		imgui.SetNextItemWidth(200)
+		if imgui.BeginCombo("##sound", a.CueSounds[e]) {
+			for _, name := range SortedMapKeys(audioCueSounds) {
+				if imgui.SelectableV(name, name == a.CueSounds[e], 0, imgui.Vec2{}) {
+					a.setCueSound(e, name)
+					a.PlayOnce(e)
+				}
+			}
+			imgui.EndCombo()
+		}
+		imgui.PopID()
+	}
+
+	imgui.Separator()
+	uiStartDisable(a.speechBackend == "")
+	imgui.Checkbox("Speak Pilot Transmissions", &a.SpeechEnabled)
+	uiEndDisable(a.speechBackend == "")
+	if a.speechBackend == "" {
+		imgui.SameLine()
+		imgui.Text("(no text-to-speech program found)")
+	}
+
+	imgui.Separator()
+	imgui.Checkbox("Voice Commands", &a.VoiceCommandsEnabled)
+	uiStartDisable(!a.VoiceCommandsEnabled)
+	current := ""
+	for k, key := range PushToTalkKeys {
+		if key == a.PushToTalkKey {
+			current = k
+		}
+	}
+	if imgui.BeginCombo("Push-to-talk key", current) {
+		for _, k := range SortedMapKeys(PushToTalkKeys) {
+			if imgui.SelectableV(k, k == current, 0, imgui.Vec2{}) {
+				a.PushToTalkKey = PushToTalkKeys[k]
 			}
 		}
+		imgui.EndCombo()
+	}
+	imgui.InputText("Whisper model file", &a.WhisperModel)
+	if speechRecognizer() == "" {
+		imgui.Text("(whisper.cpp not found; it must be installed for voice commands)")
 	}
+	uiEndDisable(!a.VoiceCommandsEnabled)
 	uiEndDisable(!a.AudioEnabled)
 }
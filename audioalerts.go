@@ -0,0 +1,227 @@
+// audioalerts.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+var ErrNoOggDecoder = errors.New("oggdec or ffmpeg must be installed to play .ogg files")
+
+// AudioAlertSettings are the user's settings for one of the alerts in
+// audioCustomAlerts.
+type AudioAlertSettings struct {
+	// File is a .wav or .ogg file to play instead of the built-in sound.
+	File   string
+	Volume float32 // [0,1]
+	// RepeatInterval is the time in seconds from the start of one
+	// repetition of the alert to the start of the next while it sounds
+	// repeatedly. Repetitions are back to back if it's shorter than the
+	// sound.
+	RepeatInterval float32
+}
+
+// audioCustomAlerts are the alerts whose sounds, volumes, and repeat
+// intervals may be set by the user.
+var audioCustomAlerts = []AudioType{AudioConflictAlert, AudioMinimumSafeAltitudeWarning, AudioInboundHandoff,
+	AudioPointOut, AudioFrequencyMessage}
+
+// audioAlertTones are the built-in sounds for the alerts that don't have a
+// recorded one.
+var audioAlertTones = map[AudioType][]audioTone{
+	AudioPointOut:         {{880, 0.1}, {0, 0.05}, {880, 0.1}, {0, 0.05}, {660, 0.2}},
+	AudioFrequencyMessage: {{1200, 0.04}},
+}
+
+func defaultAudioAlertSettings() map[AudioType]AudioAlertSettings {
+	m := make(map[AudioType]AudioAlertSettings)
+	for _, e := range audioCustomAlerts {
+		m[e] = AudioAlertSettings{Volume: 1}
+	}
+	return m
+}
+
+// loadAudioFile returns the samples in the given .wav or .ogg file as
+// 16-bit PCM at AudioSampleRate, mixed down to mono.
+func loadAudioFile(filename string) ([]byte, error) {
+	var b []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".wav":
+		b, err = os.ReadFile(filename)
+	case ".ogg":
+		b, err = convertOggToWAV(filename)
+	default:
+		return nil, fmt.Errorf("%s: only .wav and .ogg files are supported", filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pcm, rate, err := decodeWAV(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return pcmBytes(resamplePCM(pcm, rate, AudioSampleRate)), nil
+}
+
+// convertOggToWAV decodes the given Ogg Vorbis file using oggdec or
+// ffmpeg, whichever is available, and returns the resulting WAV file.
+func convertOggToWAV(filename string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "vice-audio")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "alert.wav")
+
+	var cmd *exec.Cmd
+	if _, err := exec.LookPath("oggdec"); err == nil {
+		cmd = exec.Command("oggdec", "--quiet", "--bits", "16", "--output", fn, filename)
+	} else if _, err := exec.LookPath("ffmpeg"); err == nil {
+		cmd = exec.Command("ffmpeg", "-loglevel", "error", "-i", filename, "-ac", "1",
+			"-ar", strconv.Itoa(AudioSampleRate), "-c:a", "pcm_s16le", fn)
+	} else {
+		return nil, ErrNoOggDecoder
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%s: %v: %s", filepath.Base(cmd.Path), err, string(out))
+	}
+	return os.ReadFile(fn)
+}
+
+// pcmBytes returns the given samples as the little-endian bytes that the
+// audio callback plays.
+func pcmBytes(pcm []int16) []byte {
+	b := make([]byte, 2*len(pcm))
+	for i, s := range pcm {
+		binary.LittleEndian.PutUint16(b[2*i:], uint16(s))
+	}
+	return b
+}
+
+// alertPCM returns the samples to play for an alert with the given sound,
+// with silence added after it so that repetitions start repeatInterval
+// seconds apart.
+func alertPCM(sound []byte, repeatInterval float32) []byte {
+	n := 2 * int(repeatInterval*AudioSampleRate)
+	if n <= len(sound) {
+		return sound
+	}
+	return append(slices.Clone(sound), make([]byte, n-len(sound))...)
+}
+
+// setAlertSound loads the sound for the given alert: the user's file if
+// one has been set and otherwise the built-in sound. The built-in sound
+// is used if the file can't be loaded, in which case the error is
+// returned.
+func (a *AudioEngine) setAlertSound(e AudioType) error {
+	var err error
+	sound := a.builtinSounds[e]
+	if fn := a.Alerts[e].File; fn != "" {
+		var pcm []byte
+		if pcm, err = loadAudioFile(fn); err == nil {
+			sound = pcm
+		}
+	}
+
+	a.mu.Lock()
+	a.alertSounds[e] = sound
+	a.mu.Unlock()
+
+	a.applyAlertSettings(e)
+	return err
+}
+
+// applyAlertSettings updates the effect for the given alert after its
+// volume or repeat interval has changed.
+func (a *AudioEngine) applyAlertSettings(e AudioType) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s := a.Alerts[e]
+	a.effects[e].pcm = alertPCM(a.alertSounds[e], s.RepeatInterval)
+	a.effects[e].gain = s.Volume
+	a.effects[e].playOffset = 0
+}
+
+// drawAlertsUI draws the table of the user-customizable alerts in the
+// audio settings.
+func (a *AudioEngine) drawAlertsUI() {
+	flags := imgui.TableFlagsBordersV | imgui.TableFlagsBordersOuterH | imgui.TableFlagsRowBg
+	if !imgui.BeginTableV("alerts", 4, flags, imgui.Vec2{}, 0) {
+		return
+	}
+	imgui.TableSetupColumn("Alert")
+	imgui.TableSetupColumn("Volume")
+	imgui.TableSetupColumn("Repeat interval")
+	imgui.TableSetupColumn("Sound file (.wav or .ogg)")
+	imgui.TableHeadersRow()
+
+	for _, e := range audioCustomAlerts {
+		imgui.PushID(e.String())
+		s := a.Alerts[e]
+
+		imgui.TableNextRow()
+		imgui.TableNextColumn()
+		if imgui.Checkbox(e.String(), &a.EffectEnabled[e]) && a.EffectEnabled[e] {
+			a.PlayOnce(e)
+		}
+
+		imgui.TableNextColumn()
+		imgui.SetNextItemWidth(120)
+		changed := imgui.SliderFloatV("##volume", &s.Volume, 0, 1, "%.2f", 0)
+		if imgui.IsItemDeactivatedAfterEdit() {
+			a.PlayOnce(e)
+		}
+
+		imgui.TableNextColumn()
+		imgui.SetNextItemWidth(120)
+		changed = imgui.SliderFloatV("##repeat", &s.RepeatInterval, 0, 10, "%.1f s", 0) || changed
+
+		imgui.TableNextColumn()
+		imgui.SetNextItemWidth(250)
+		imgui.InputText("##file", &s.File)
+		imgui.SameLine()
+		load := imgui.Button("Load")
+		imgui.SameLine()
+		if imgui.Button("Default") {
+			s.File = ""
+			load = true
+		}
+
+		if changed || load {
+			a.Alerts[e] = s
+			a.applyAlertSettings(e)
+		}
+		if load {
+			if err := a.setAlertSound(e); err != nil {
+				a.alertErrors[e] = err.Error()
+			} else {
+				delete(a.alertErrors, e)
+				a.PlayOnce(e)
+			}
+		}
+		if msg, ok := a.alertErrors[e]; ok {
+			imgui.PushStyleColor(imgui.StyleColorText, imgui.Vec4{1, .5, .5, 1})
+			imgui.Text(msg)
+			imgui.PopStyleColor()
+		}
+
+		imgui.PopID()
+	}
+	imgui.EndTable()
+}
@@ -0,0 +1,65 @@
+// audioalerts_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadAudioFile(t *testing.T) {
+	dir := t.TempDir()
+
+	// A WAV at twice the sample rate is resampled.
+	pcm := make([]int16, 2*AudioSampleRate/10)
+	for i := range pcm {
+		pcm[i] = int16(i)
+	}
+	fn := filepath.Join(dir, "alert.WAV")
+	if err := os.WriteFile(fn, encodeWAV(pcm, 2*AudioSampleRate), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := loadAudioFile(fn); err != nil {
+		t.Errorf("%s: unexpected error %v", fn, err)
+	} else if len(b) != 2*AudioSampleRate/10 {
+		t.Errorf("got %d bytes, expected %d", len(b), 2*AudioSampleRate/10)
+	} else if b[2] != 2 || b[3] != 0 {
+		t.Errorf("expected second sample to be 2, got bytes %v", b[2:4])
+	}
+
+	fn = filepath.Join(dir, "alert.mp3")
+	if err := os.WriteFile(fn, []byte("ID3"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadAudioFile(fn); err == nil {
+		t.Errorf("%s: expected error for unsupported file type", fn)
+	}
+	if _, err := loadAudioFile(filepath.Join(dir, "missing.wav")); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+}
+
+func TestAlertPCM(t *testing.T) {
+	sound := []byte{1, 2, 3, 4}
+
+	if b := alertPCM(sound, 0); len(b) != len(sound) {
+		t.Errorf("got %d bytes with no repeat interval, expected %d", len(b), len(sound))
+	}
+
+	b := alertPCM(sound, 0.5)
+	if len(b) != AudioSampleRate {
+		t.Errorf("got %d bytes, expected %d", len(b), AudioSampleRate)
+	}
+	for i, v := range b {
+		if (i < len(sound) && v != sound[i]) || (i >= len(sound) && v != 0) {
+			t.Errorf("unexpected byte %d at offset %d", v, i)
+			break
+		}
+	}
+	if sound[3] != 4 || len(sound) != 4 {
+		t.Errorf("sound was modified")
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/klauspost/compress/zstd"
@@ -68,6 +69,10 @@ type Arrival struct {
 	SecondaryScratchpad string  `json:"secondary_scratchpad"`
 	Description         string  `json:"description"`
 
+	// Probability that an arrival is a trainer that requests a series of
+	// practice approaches.
+	PracticeApproachRate float32 `json:"practice_approach_rate"`
+
 	// Airport -> arrival airlines
 	Airlines map[string][]ArrivalAirline `json:"airlines"`
 }
@@ -152,6 +157,8 @@ type Runway struct {
 	Heading   float32
 	Threshold Point2LL
 	Elevation int
+	Length    int // feet
+	Width     int // feet
 }
 
 type METAR struct {
@@ -225,6 +232,73 @@ type ATIS struct {
 	AppDep   string
 	Code     string
 	Contents string
+	Issued   time.Time
+}
+
+// NextATISCode returns the ATIS letter that follows the given one.
+func NextATISCode(code string) string {
+	if len(code) != 1 || code[0] < 'A' || code[0] > 'Z' {
+		return "A"
+	}
+	return string(rune('A' + (code[0]-'A'+1)%26))
+}
+
+// MakeATISContents returns the text of an ATIS broadcast for the given
+// airport. The METAR may be nil, in which case weather isn't reported.
+func MakeATISContents(airport, code string, issued time.Time, metar *METAR,
+	approaches, arrivalRunways, departureRunways []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s INFORMATION %s %sZ.", airport, code, issued.UTC().Format("1504"))
+
+	if metar != nil {
+		if metar.Wind != "" {
+			sb.WriteString(" " + atisWind(metar.Wind) + ".")
+		}
+		if metar.Weather != "" {
+			sb.WriteString(" " + metar.Weather + ".")
+		}
+		if alt := strings.TrimPrefix(metar.Altimeter, "A"); alt != "" {
+			sb.WriteString(" ALTIMETER " + alt + ".")
+		}
+	}
+
+	if len(approaches) > 0 {
+		sb.WriteString(" " + strings.ToUpper(strings.Join(approaches, ", ")) + " IN USE.")
+	}
+	if len(arrivalRunways) > 0 {
+		sb.WriteString(" LANDING RUNWAY " + strings.Join(arrivalRunways, ", ") + ".")
+	}
+	if len(departureRunways) > 0 {
+		sb.WriteString(" DEPARTING RUNWAY " + strings.Join(departureRunways, ", ") + ".")
+	}
+
+	fmt.Fprintf(&sb, " ADVISE ON INITIAL CONTACT YOU HAVE INFORMATION %s.", code)
+	return sb.String()
+}
+
+// atisWind returns the ATIS phrasing of a METAR wind report, e.g.,
+// "31012G20KT" -> "WIND 310 AT 12 GUST 20".
+func atisWind(wind string) string {
+	w := strings.TrimSuffix(wind, "KT")
+	if len(w) < 5 {
+		return "WIND " + wind
+	}
+
+	dir := w[:3]
+	spd, gust, _ := strings.Cut(w[3:], "G")
+	s, err := strconv.Atoi(spd)
+	if err != nil {
+		return "WIND " + wind
+	}
+	if s == 0 {
+		return "WIND CALM"
+	}
+
+	str := "WIND " + Select(dir == "VRB", "VARIABLE", dir) + " AT " + strconv.Itoa(s)
+	if g, err := strconv.Atoi(gust); err == nil {
+		str += " GUST " + strconv.Itoa(g)
+	}
+	return str
 }
 
 // Frequencies are scaled by 1000 and then stored in integers.
@@ -308,6 +382,38 @@ func ParseSquawk(s string) (Squawk, error) {
 	return Squawk(sq), nil
 }
 
+// ParseFixRadialDistance parses a location given in FAA fix-radial-distance
+// notation, e.g., "JFK180010" for 10nm from JFK on the 180 (magnetic)
+// radial. It returns the fix, the radial, and the distance in nm; an
+// error is returned if the string isn't in that format.
+func ParseFixRadialDistance(s string) (fix string, radial int, dist int, err error) {
+	// At least two characters for the fix, followed by three digits each
+	// for the radial and distance.
+	n := len(s)
+	if n < 8 || n > 11 || !isAllNumbers(s[n-6:]) ||
+		strings.ContainsFunc(s[:n-6], func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		return "", 0, 0, fmt.Errorf("%s: invalid fix-radial-distance", s)
+	}
+
+	fix = s[:n-6]
+	radial, _ = strconv.Atoi(s[n-6 : n-3])
+	dist, _ = strconv.Atoi(s[n-3:])
+	if radial > 360 {
+		return "", 0, 0, fmt.Errorf("%s: invalid radial", s)
+	}
+	return
+}
+
+// FormatFixRadialDistance returns the FAA fix-radial-distance string for
+// the given fix, radial, and distance in nm, e.g. "JFK180010". Per
+// convention, a radial of 0 is given as 360.
+func FormatFixRadialDistance(fix string, radial int, dist int) string {
+	if radial == 0 {
+		radial = 360
+	}
+	return fmt.Sprintf("%s%03d%03d", fix, radial, dist)
+}
+
 // Special purpose code: beacon codes are squawked in various unusual situations.
 type SPC struct {
 	Squawk Squawk
@@ -343,6 +449,7 @@ type RadarTrack struct {
 	Altitude    int
 	Groundspeed int
 	Time        time.Time
+	ModeS       *ModeSParameters // nil if the aircraft isn't downlinking them
 }
 
 func FormatAltitude(falt float32) string {
@@ -376,10 +483,11 @@ func (t TransponderMode) String() string {
 }
 
 type Navaid struct {
-	Id       string
-	Type     string
-	Name     string
-	Location Point2LL
+	Id        string
+	Type      string
+	Name      string
+	Location  Point2LL
+	Frequency Frequency // MHz for VORs and DMEs, kHz for NDBs
 }
 
 type Fix struct {
@@ -633,7 +741,7 @@ type Wind struct {
 
 type WindModel interface {
 	GetWindVector(p Point2LL, alt float32) Point2LL
-	AverageWindVector() [2]float32
+	AverageWindVector(p Point2LL, alt float32) [2]float32
 }
 
 ///////////////////////////////////////////////////////////////////////////
@@ -1231,6 +1339,30 @@ type RadarSite struct {
 	SecondaryRange int32   `json:"secondary_range"`
 	SlopeAngle     float32 `json:"slope_angle"`
 	SilenceAngle   float32 `json:"silence_angle"`
+	RotationPeriod float32 `json:"rotation_period"` // seconds; 0 -> terminal radar default
+}
+
+// Default time for a full rotation of a terminal radar's antenna.
+const DefaultRadarRotationPeriod = 4.8
+
+// SweepPasses indicates whether the radar's antenna, rotating once every
+// period, points toward p at some time in [t0,t1).
+func (rs *RadarSite) SweepPasses(p Point2LL, nmPerLongitude float32, period time.Duration, t0, t1 time.Time) bool {
+	if t1.Sub(t0) >= period {
+		return true
+	}
+
+	// Antenna azimuth at time t, in [0,360).
+	azimuth := func(t time.Time) float32 {
+		return 360 * float32(t.UnixNano()%int64(period)) / float32(period)
+	}
+	a0, a1 := azimuth(t0), azimuth(t1)
+	bearing := headingp2ll(rs.Position, p, nmPerLongitude, 0)
+	if a0 <= a1 {
+		return bearing >= a0 && bearing < a1
+	}
+	// Wrapped around through north
+	return bearing >= a0 || bearing < a1
 }
 
 func (rs *RadarSite) CheckVisibility(w *World, p Point2LL, altitude int) (primary, secondary bool, distance float32) {
@@ -1381,6 +1513,9 @@ func (d StaticDatabase) LookupWaypoint(f string) (Point2LL, bool) {
 type AircraftPerformance struct {
 	Name string `json:"name"`
 	ICAO string `json:"icao"`
+	// Helicopters have no minimum speed and can hover, take off, and
+	// land vertically.
+	Helicopter bool `json:"helicopter"`
 	// engines, weight class, category
 	WeightClass string  `json:"weightClass"`
 	Ceiling     float32 `json:"ceiling"`
@@ -1728,6 +1863,92 @@ func (m *MVA) Inside(p [2]float32) bool {
 	return true
 }
 
+// LabelPosition returns a point inside the MVA's area at which to draw
+// its altitude label. The center of its bounds is used if that is inside;
+// otherwise (e.g., for concave or ring-shaped areas), the inside point on
+// a grid over the bounds that is closest to the center is used.
+func (m *MVA) LabelPosition() [2]float32 {
+	e := Extent2DFromPoints(m.ExteriorRing)
+	center := e.Center()
+	if m.Inside(center) {
+		return center
+	}
+
+	const n = 16
+	best, bestDist := center, float32(1e30)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			p := e.Lerp([2]float32{(float32(i) + 0.5) / n, (float32(j) + 0.5) / n})
+			if d := distance2f(p, center); d < bestDist && m.Inside(p) {
+				best, bestDist = p, d
+			}
+		}
+	}
+	return best
+}
+
+// ParseMinimumAltitudeGeoJSON parses minimum vectoring or instrument
+// altitude areas from a GeoJSON FeatureCollection. Each feature must have
+// Polygon or MultiPolygon geometry and an "altitude" property that gives
+// the minimum altitude in feet.
+func ParseMinimumAltitudeGeoJSON(b []byte) ([]MVA, error) {
+	var fc struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Geometry struct {
+				Type        string          `json:"type"`
+				Coordinates json.RawMessage `json:"coordinates"`
+			} `json:"geometry"`
+			Properties struct {
+				Altitude *int `json:"altitude"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(b, &fc); err != nil {
+		return nil, err
+	}
+	if fc.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("%s: expected \"FeatureCollection\"", fc.Type)
+	}
+
+	var mvas []MVA
+	for i, f := range fc.Features {
+		if f.Properties.Altitude == nil {
+			return nil, fmt.Errorf("feature %d: no \"altitude\" property", i)
+		}
+
+		// GeoJSON coordinates are (longitude, latitude), which matches
+		// Point2LL.
+		var polygons [][][][2]float32
+		switch f.Geometry.Type {
+		case "Polygon":
+			var poly [][][2]float32
+			if err := json.Unmarshal(f.Geometry.Coordinates, &poly); err != nil {
+				return nil, fmt.Errorf("feature %d: %w", i, err)
+			}
+			polygons = append(polygons, poly)
+		case "MultiPolygon":
+			if err := json.Unmarshal(f.Geometry.Coordinates, &polygons); err != nil {
+				return nil, fmt.Errorf("feature %d: %w", i, err)
+			}
+		default:
+			return nil, fmt.Errorf("feature %d: unsupported geometry type \"%s\"", i, f.Geometry.Type)
+		}
+
+		for _, poly := range polygons {
+			if len(poly) == 0 || len(poly[0]) < 3 {
+				return nil, fmt.Errorf("feature %d: polygon must have at least 3 vertices", i)
+			}
+			mvas = append(mvas, MVA{
+				MinimumLimit:  *f.Properties.Altitude,
+				ExteriorRing:  poly[0],
+				InteriorRings: poly[1:],
+			})
+		}
+	}
+	return mvas, nil
+}
+
 type MVALinearRing struct {
 	PosList string `xml:"posList"`
 }
@@ -2273,6 +2494,10 @@ func (ar *Arrival) PostDeserialize(sg *ScenarioGroup, e *ErrorLogger) {
 		e.ErrorString("must specify \"initial_speed\"")
 	}
 
+	if ar.PracticeApproachRate < 0 || ar.PracticeApproachRate > 1 {
+		e.ErrorString("\"practice_approach_rate\" must be between 0 and 1")
+	}
+
 	if ar.InitialController == "" {
 		e.ErrorString("\"initial_controller\" missing")
 	} else if _, ok := sg.ControlPositions[ar.InitialController]; !ok {
@@ -6,6 +6,7 @@ package main
 
 import (
 	"testing"
+	"time"
 )
 
 func TestFrequencyFormat(t *testing.T) {
@@ -63,3 +64,146 @@ func TestParseAltitudeRestriction(t *testing.T) {
 		}
 	}
 }
+
+func TestParseFixRadialDistance(t *testing.T) {
+	type testcase struct {
+		s            string
+		fix          string
+		radial, dist int
+	}
+	for _, test := range []testcase{
+		testcase{s: "JFK180010", fix: "JFK", radial: 180, dist: 10},
+		testcase{s: "CAMRN045125", fix: "CAMRN", radial: 45, dist: 125},
+		testcase{s: "KJFK360001", fix: "KJFK", radial: 360, dist: 1},
+	} {
+		fix, radial, dist, err := ParseFixRadialDistance(test.s)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.s, err)
+		} else if fix != test.fix || radial != test.radial || dist != test.dist {
+			t.Errorf("%s: got %s/%d/%d, expected %s/%d/%d", test.s, fix, radial, dist,
+				test.fix, test.radial, test.dist)
+		}
+	}
+
+	if frd := FormatFixRadialDistance("JFK", 0, 5); frd != "JFK360005" {
+		t.Errorf("got %s for FormatFixRadialDistance, expected JFK360005", frd)
+	}
+
+	for _, s := range []string{"JFK", "JFK18001", "J180010", "JFK-80010", "JFK400010", "JF.180010", "ABCDEF180010"} {
+		if _, _, _, err := ParseFixRadialDistance(s); err == nil {
+			t.Errorf("%s: expected error for invalid fix-radial-distance", s)
+		}
+	}
+}
+
+func TestMVALabelPosition(t *testing.T) {
+	// U-shaped area where the center of the bounds is outside.
+	mva := MVA{ExteriorRing: [][2]float32{{0, 0}, {3, 0}, {3, 3}, {2, 3}, {2, 1}, {1, 1}, {1, 3}, {0, 3}}}
+	if p := mva.LabelPosition(); !mva.Inside(p) {
+		t.Errorf("label position %v is outside of the MVA", p)
+	}
+
+	square := MVA{ExteriorRing: [][2]float32{{0, 0}, {2, 0}, {2, 2}, {0, 2}}}
+	if p := square.LabelPosition(); p != [2]float32{1, 1} {
+		t.Errorf("got label position %v for square; expected its center", p)
+	}
+}
+
+func TestParseMinimumAltitudeGeoJSON(t *testing.T) {
+	geojson := `{
+  "type": "FeatureCollection",
+  "features": [
+    { "type": "Feature", "properties": { "altitude": 2000 },
+      "geometry": { "type": "Polygon",
+        "coordinates": [ [ [-74, 40], [-73, 40], [-73, 41], [-74, 41], [-74, 40] ] ] } },
+    { "type": "Feature", "properties": { "altitude": 3500 },
+      "geometry": { "type": "MultiPolygon",
+        "coordinates": [ [ [ [-75, 40], [-74, 40], [-74, 41] ] ],
+                         [ [ [-76, 40], [-75, 40], [-75, 41] ] ] ] } }
+  ]
+}`
+	mvas, err := ParseMinimumAltitudeGeoJSON([]byte(geojson))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mvas) != 3 {
+		t.Fatalf("got %d MVAs, expected 3", len(mvas))
+	}
+	if mvas[0].MinimumLimit != 2000 || mvas[1].MinimumLimit != 3500 || mvas[2].MinimumLimit != 3500 {
+		t.Errorf("unexpected altitudes %d, %d, %d", mvas[0].MinimumLimit, mvas[1].MinimumLimit,
+			mvas[2].MinimumLimit)
+	}
+	if !mvas[0].Inside([2]float32{-73.5, 40.5}) {
+		t.Errorf("expected point to be inside the first MVA")
+	}
+	if mvas[0].Inside([2]float32{-72.5, 40.5}) {
+		t.Errorf("expected point to be outside the first MVA")
+	}
+
+	if _, err := ParseMinimumAltitudeGeoJSON([]byte(`{"type": "FeatureCollection", "features": [
+        { "properties": {}, "geometry": { "type": "Polygon", "coordinates": [] } } ]}`)); err == nil {
+		t.Errorf("expected error for missing altitude")
+	}
+}
+
+func TestATIS(t *testing.T) {
+	for _, test := range []struct{ wind, expected string }{
+		{"31012KT", "WIND 310 AT 12"},
+		{"31012G20KT", "WIND 310 AT 12 GUST 20"},
+		{"VRB03KT", "WIND VARIABLE AT 3"},
+		{"00000KT", "WIND CALM"},
+		{"bogus", "WIND bogus"},
+	} {
+		if w := atisWind(test.wind); w != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.wind, test.expected, w)
+		}
+	}
+
+	for _, test := range [][2]string{{"A", "B"}, {"M", "N"}, {"Z", "A"}, {"", "A"}} {
+		if c := NextATISCode(test[0]); c != test[1] {
+			t.Errorf("%q: expected next code %q, got %q", test[0], test[1], c)
+		}
+	}
+
+	issued := time.Date(2024, 3, 1, 18, 51, 0, 0, time.UTC)
+	metar := &METAR{AirportICAO: "KJFK", Wind: "31012G20KT", Altimeter: "A2992"}
+	contents := MakeATISContents("KJFK", "K", issued, metar, []string{"ILS Runway 31R"},
+		[]string{"31R"}, []string{"31L"})
+	expected := "KJFK INFORMATION K 1851Z. WIND 310 AT 12 GUST 20. ALTIMETER 2992. ILS RUNWAY 31R IN USE. " +
+		"LANDING RUNWAY 31R. DEPARTING RUNWAY 31L. ADVISE ON INITIAL CONTACT YOU HAVE INFORMATION K."
+	if contents != expected {
+		t.Errorf("expected %q, got %q", expected, contents)
+	}
+
+	if contents := MakeATISContents("KJFK", "A", issued, nil, nil, nil, nil); contents !=
+		"KJFK INFORMATION A 1851Z. ADVISE ON INITIAL CONTACT YOU HAVE INFORMATION A." {
+		t.Errorf("got %q for empty ATIS", contents)
+	}
+}
+
+func TestRadarSweep(t *testing.T) {
+	rs := RadarSite{Position: Point2LL{-73, 40}}
+	nmPerLongitude := float32(46)
+	period := 4 * time.Second
+	north, east := Point2LL{-73, 40.5}, Point2LL{-72.5, 40}
+
+	// The antenna points north at multiples of the period and east one
+	// quarter of the way through.
+	t0 := time.Unix(1000, 0)
+	for _, test := range []struct {
+		p        Point2LL
+		from, to time.Duration
+		expected bool
+	}{
+		{p: north, from: 0, to: 500 * time.Millisecond, expected: true},
+		{p: north, from: 500 * time.Millisecond, to: 3 * time.Second, expected: false},
+		{p: north, from: 3500 * time.Millisecond, to: 4500 * time.Millisecond, expected: true}, // wraps
+		{p: east, from: 0, to: 500 * time.Millisecond, expected: false},
+		{p: east, from: 500 * time.Millisecond, to: 1500 * time.Millisecond, expected: true},
+		{p: east, from: 2 * time.Second, to: 7 * time.Second, expected: true}, // a full rotation
+	} {
+		if got := rs.SweepPasses(test.p, nmPerLongitude, period, t0.Add(test.from), t0.Add(test.to)); got != test.expected {
+			t.Errorf("%v from %s to %s: expected %v, got %v", test.p, test.from, test.to, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// background.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// BackgroundTraffic specifies aircraft that operate entirely within
+// neighboring facilities' airspace. They are tracked by a neighboring
+// controller and shown with limited datablocks, but they are never handed
+// off and can't be given instructions; they provide realistic clutter on
+// the scope and quick-look practice without adding any work.
+type BackgroundTraffic struct {
+	// Randomly generated overflights that cross the scope above the
+	// facility's airspace. If the controller isn't specified, the first
+	// ARTCC controller in the scenario's "controllers" is used.
+	OverflightRate       int    `json:"overflight_rate"` // per hour
+	OverflightController string `json:"overflight_controller"`
+
+	Routes []BackgroundTrafficRoute `json:"routes"`
+}
+
+// BackgroundTrafficRoute is a scripted route for background traffic,
+// e.g., arrivals to a neighboring facility's airport. Aircraft start at
+// the given altitude; altitude restrictions at the waypoints can be used
+// to have them descend along the route.
+type BackgroundTrafficRoute struct {
+	Waypoints   WaypointArray    `json:"waypoints"`
+	Altitude    float32          `json:"altitude"`
+	Speed       float32          `json:"speed"`
+	Rate        int              `json:"rate"` // per hour
+	Controller  string           `json:"controller"`
+	Destination string           `json:"destination"`
+	Airlines    []ArrivalAirline `json:"airlines"`
+}
+
+func (bt *BackgroundTraffic) PostDeserialize(sg *ScenarioGroup, s *Scenario, e *ErrorLogger) {
+	e.Push("\"background_traffic\"")
+	defer e.Pop()
+
+	addController := func(ctrl string) {
+		if _, ok := sg.ControlPositions[ctrl]; !ok {
+			e.ErrorString("controller \"%s\" not defined in the scenario group's \"control_positions\"", ctrl)
+		} else if !slices.Contains(s.VirtualControllers, ctrl) {
+			s.VirtualControllers = append(s.VirtualControllers, ctrl)
+		}
+	}
+
+	if bt.OverflightRate < 0 {
+		e.ErrorString("\"overflight_rate\" must be positive")
+	} else if bt.OverflightRate > 0 {
+		if bt.OverflightController == "" {
+			if idx := slices.IndexFunc(s.VirtualControllers, func(ctrl string) bool {
+				c, ok := sg.ControlPositions[ctrl]
+				return ok && c.ERAMFacility
+			}); idx != -1 {
+				bt.OverflightController = s.VirtualControllers[idx]
+			} else {
+				e.ErrorString("no \"overflight_controller\" specified and no ARTCC controller in \"controllers\"")
+			}
+		}
+		if bt.OverflightController != "" {
+			addController(bt.OverflightController)
+		}
+	}
+
+	for i := range bt.Routes {
+		r := &bt.Routes[i]
+		e.Push(fmt.Sprintf("Route %d", i))
+
+		if len(r.Waypoints) < 2 {
+			e.ErrorString("must provide at least two \"waypoints\"")
+		} else {
+			sg.InitializeWaypointLocations(r.Waypoints, e)
+			r.Waypoints.checkBasics(e)
+		}
+		if r.Altitude <= 0 || r.Altitude > 60000 {
+			e.ErrorString("invalid \"altitude\" %.0f", r.Altitude)
+		}
+		if r.Speed < 0 {
+			e.ErrorString("invalid \"speed\" %.0f", r.Speed)
+		}
+		if r.Rate < 0 {
+			e.ErrorString("\"rate\" must be positive")
+		}
+		if r.Controller == "" {
+			e.ErrorString("must specify \"controller\"")
+		} else {
+			addController(r.Controller)
+		}
+		if _, ok := database.Airports[r.Destination]; !ok {
+			e.ErrorString("\"destination\" airport \"%s\" not found in database", r.Destination)
+		}
+
+		if len(r.Airlines) == 0 {
+			e.ErrorString("must provide at least one entry in \"airlines\"")
+		}
+		for _, al := range r.Airlines {
+			if _, ok := database.Airlines[al.ICAO]; !ok {
+				e.ErrorString("airline \"%s\" not found in database", al.ICAO)
+			}
+			if _, ok := database.Airports[al.Airport]; !ok {
+				e.ErrorString("airport \"%s\" not found in database", al.Airport)
+			}
+		}
+
+		e.Pop()
+	}
+}
+
+// initializeBackgroundAircraft sets up the aircraft's flight plan and
+// navigation for flying the given waypoints, starting at the given
+// altitude.
+func (w *World) initializeBackgroundAircraft(ac *Aircraft, acType, departure, destination string,
+	wps []Waypoint, alt, speed float32, controller string) error {
+	ac.FlightPlan = NewFlightPlan(IFR, acType, departure, destination)
+	ac.FlightPlan.Route = "DCT"
+	ac.TrackingController = controller
+	ac.ControllingController = controller
+	ac.BackgroundTraffic = true
+
+	perf, ok := database.AircraftPerformance[ac.FlightPlan.BaseType()]
+	if !ok {
+		return ErrUnknownAircraftType
+	}
+	if perf.Ceiling > 0 {
+		alt = min(alt, perf.Ceiling)
+	}
+	ac.FlightPlan.Altitude = int(alt)
+
+	nav := makeNav(w, *ac.FlightPlan, perf, wps)
+	if nav == nil {
+		return fmt.Errorf("error initializing Nav")
+	}
+	nav.FlightState.Altitude = alt
+	if speed != 0 {
+		nav.Speed.Assigned = &speed
+		nav.FlightState.IAS = speed
+	} else {
+		nav.FlightState.IAS = min(MaxIAS, TASToIAS(perf.Speed.CruiseTAS, alt))
+	}
+	nav.FlightState.GS = nav.FlightState.IAS
+	ac.Nav = *nav
+
+	return nil
+}
+
+// CreateBackgroundRouteAircraft returns an aircraft that flies the given
+// background traffic route.
+func (w *World) CreateBackgroundRouteAircraft(r *BackgroundTrafficRoute) (*Aircraft, error) {
+	airline := SampleSlice(r.Airlines)
+	ac, acType := w.sampleAircraft(airline.ICAO, airline.Fleet)
+	if ac == nil {
+		return nil, fmt.Errorf("unable to sample a valid aircraft")
+	}
+
+	if err := w.initializeBackgroundAircraft(ac, acType, airline.Airport, r.Destination, r.Waypoints,
+		r.Altitude, r.Speed, r.Controller); err != nil {
+		return nil, err
+	}
+	return ac, nil
+}
+
+// CreateOverflight returns a high-altitude aircraft that crosses the scope
+// on a random straight-line track. Its airline and endpoints are drawn
+// from the scenario's arrivals.
+func (w *World) CreateOverflight() (*Aircraft, error) {
+	var airlines []ArrivalAirline
+	for _, arrivals := range w.ArrivalGroups {
+		for _, arr := range arrivals {
+			for _, al := range arr.Airlines {
+				airlines = append(airlines, al...)
+			}
+		}
+	}
+	if len(airlines) == 0 {
+		return nil, fmt.Errorf("no arrival airlines to use for overflights")
+	}
+
+	airline := SampleSlice(airlines)
+	ac, acType := w.sampleAircraft(airline.ICAO, airline.Fleet)
+	if ac == nil {
+		return nil, fmt.Errorf("unable to sample a valid aircraft")
+	}
+	destination := SampleSlice(airlines).Airport
+
+	// Enter just outside the scope's range and leave on the other side,
+	// with a final waypoint well beyond so that the aircraft doesn't turn
+	// back toward its destination before it is culled.
+	center := ll2nm(w.Center, w.NmPerLongitude)
+	point := func(theta, r float32) Point2LL {
+		return nm2ll(add2f(center, scale2f([2]float32{sin(theta), cos(theta)}, r)), w.NmPerLongitude)
+	}
+	entry := 2 * math.Pi * rand.Float32()
+	exit := entry + math.Pi + (rand.Float32()-0.5)*math.Pi/2
+	wps := []Waypoint{
+		{Fix: "_OVFL_ENTRY", Location: point(entry, 1.1*w.Range)},
+		{Fix: "_OVFL_EXIT", Location: point(exit, 1.1*w.Range)},
+		{Fix: "_OVFL_BEYOND", Location: point(exit, 3*w.Range)},
+	}
+
+	// Odd flight levels eastbound, even westbound.
+	hdg := headingp2ll(wps[0].Location, wps[1].Location, w.NmPerLongitude, w.MagneticVariation)
+	fl := 24 + 2*rand.Intn(9)
+	if hdg < 180 {
+		fl++
+	}
+
+	if err := w.initializeBackgroundAircraft(ac, acType, airline.Airport, destination, wps,
+		float32(1000*fl), 0, w.BackgroundTraffic.OverflightController); err != nil {
+		return nil, err
+	}
+	alt := ac.Nav.FlightState.Altitude
+	ac.Nav.Altitude.Assigned = &alt
+	return ac, nil
+}
+
+// backgroundTrafficDone indicates whether the given background aircraft
+// is finished: it is either leaving the scope or has reached its
+// destination airport.
+func (w *World) backgroundTrafficDone(ac *Aircraft) bool {
+	p := ac.Position()
+	if nmdistance2ll(p, ac.Nav.FlightState.ArrivalAirportLocation) < 2 {
+		return true
+	}
+	if nmdistance2ll(p, w.Center) > 1.2*w.Range {
+		toCenter := headingp2ll(p, w.Center, w.NmPerLongitude, w.MagneticVariation)
+		return headingDifference(ac.Nav.FlightState.Heading, toCenter) > 90
+	}
+	return false
+}
+
+// spawnBackgroundTraffic launches background traffic according to the
+// rates specified for the overflights and each of the routes. s.mu must
+// be held.
+func (s *Sim) spawnBackgroundTraffic() {
+	bt := &s.World.BackgroundTraffic
+	now := s.SimTime
+
+	launch := func(i int, rate int, create func() (*Aircraft, error)) {
+		if !now.After(s.NextBackgroundSpawn[i]) {
+			return
+		}
+		if ac, err := create(); err != nil {
+			s.lg.Errorf("background traffic: %v", err)
+		} else {
+			s.launchAircraftNoLock(*ac)
+		}
+		s.NextBackgroundSpawn[i] = now.Add(randomWait(rate, false))
+	}
+
+	for i := range bt.Routes {
+		launch(i, bt.Routes[i].Rate, func() (*Aircraft, error) {
+			return s.World.CreateBackgroundRouteAircraft(&bt.Routes[i])
+		})
+	}
+	launch(len(bt.Routes), bt.OverflightRate, s.World.CreateOverflight)
+}
+
+// initialBackgroundSpawnTimes returns the initial spawn times for each of
+// the background traffic routes, followed by the time for overflights.
+func initialBackgroundSpawnTimes(bt BackgroundTraffic, randomSpawn func(rate int) time.Time) []time.Time {
+	var t []time.Time
+	for _, r := range bt.Routes {
+		t = append(t, randomSpawn(r.Rate))
+	}
+	return append(t, randomSpawn(bt.OverflightRate))
+}
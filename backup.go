@@ -0,0 +1,160 @@
+// backup.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// Copies of the configuration file and of scenario files written by the
+// scenario editor are saved in the backups directory next to the config
+// file before they are overwritten so that hand-tuned setups can be
+// recovered after a crash, a bad edit, or an upgrade that changed them.
+// Each file's backups are in their own subdirectory, named by the time
+// they were made.
+
+// maxBackups is the number of backups kept for each file.
+const maxBackups = 20
+
+const backupTimeFormat = "20060102-150405"
+
+type Backup struct {
+	Path string
+	Time time.Time
+}
+
+func backupRootDir() string {
+	return path.Join(path.Dir(configFilePath()), "backups")
+}
+
+// backupDir returns the directory under root that holds the backups of
+// the given file. The name includes a hash of the file's full path so
+// that files with the same name in different directories are kept
+// separate.
+func backupDir(root, filename string) string {
+	if abs, err := filepath.Abs(filename); err == nil {
+		filename = abs
+	}
+	base := filepath.Base(filename)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	return path.Join(root, fmt.Sprintf("%s-%08x", base, crc32.ChecksumIEEE([]byte(filename))))
+}
+
+// BackupFile saves a copy of the file's current contents unless it
+// doesn't exist or it's unchanged since the most recent backup.
+func BackupFile(filename string) error {
+	return backupFile(backupRootDir(), filename, time.Now())
+}
+
+func backupFile(root, filename string, now time.Time) error {
+	contents, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	backups, err := listBackups(root, filename)
+	if err != nil {
+		return err
+	}
+	if len(backups) > 0 {
+		if prev, err := os.ReadFile(backups[0].Path); err == nil && bytes.Equal(prev, contents) {
+			return nil
+		}
+	}
+
+	dir := backupDir(root, filename)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	fn := path.Join(dir, now.Format(backupTimeFormat)+filepath.Ext(filename))
+	if err := os.WriteFile(fn, contents, 0o600); err != nil {
+		return err
+	}
+	lg.Infof("%s: backed up to %s", filename, fn)
+
+	// Remove the oldest ones beyond the limit, including the one we just
+	// made in the list.
+	backups = slices.DeleteFunc(backups, func(b Backup) bool { return b.Path == fn })
+	backups = append([]Backup{{Path: fn, Time: now}}, backups...)
+	for _, b := range backups[min(len(backups), maxBackups):] {
+		if err := os.Remove(b.Path); err != nil {
+			lg.Warnf("%s: %v", b.Path, err)
+		}
+	}
+	return nil
+}
+
+// ListBackups returns the backups of the given file, most recent first.
+func ListBackups(filename string) ([]Backup, error) {
+	return listBackups(backupRootDir(), filename)
+}
+
+func listBackups(root, filename string) ([]Backup, error) {
+	entries, err := os.ReadDir(backupDir(root, filename))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var backups []Backup
+	for _, e := range entries {
+		name := e.Name()
+		if t, err := time.ParseInLocation(backupTimeFormat, strings.TrimSuffix(name, filepath.Ext(name)),
+			time.Local); err == nil && !e.IsDir() {
+			backups = append(backups, Backup{Path: path.Join(backupDir(root, filename), name), Time: t})
+		}
+	}
+	slices.SortFunc(backups, func(a, b Backup) int { return b.Time.Compare(a.Time) })
+	return backups, nil
+}
+
+// RestoreBackup replaces the file with the backup's contents after
+// backing up its current contents.
+func RestoreBackup(b Backup, filename string) error {
+	contents, err := os.ReadFile(b.Path)
+	if err != nil {
+		return err
+	}
+	if err := BackupFile(filename); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, contents, 0o644)
+}
+
+// drawBackupsUI lists the backups of the given file, calling restore when
+// one of them is selected to be restored.
+func drawBackupsUI(filename string, restore func(Backup)) {
+	backups, err := ListBackups(filename)
+	if err != nil {
+		imgui.Text(err.Error())
+		return
+	} else if len(backups) == 0 {
+		imgui.Text("No backups of " + filename)
+		return
+	}
+
+	for i, b := range backups {
+		imgui.Text(b.Time.Format("2006-01-02 15:04:05"))
+		imgui.SameLine()
+		if imgui.Button(fmt.Sprintf("Restore##backup%d", i)) {
+			restore(b)
+		}
+	}
+}
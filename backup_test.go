@@ -0,0 +1,63 @@
+// backup_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestBackupFile(t *testing.T) {
+	dir := t.TempDir()
+	root, fn := path.Join(dir, "backups"), path.Join(dir, "config.json")
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.Local)
+
+	// Nothing to back up yet.
+	if err := backupFile(root, fn, start); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b, _ := listBackups(root, fn); len(b) != 0 {
+		t.Errorf("got %d backups of a nonexistent file", len(b))
+	}
+
+	for i := 0; i < maxBackups+5; i++ {
+		if err := os.WriteFile(fn, []byte{byte(i)}, 0o600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		now := start.Add(time.Duration(i) * time.Minute)
+		if err := backupFile(root, fn, now); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// Unchanged contents aren't backed up again.
+		if err := backupFile(root, fn, now.Add(time.Second)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	backups, err := listBackups(root, fn)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(backups) != maxBackups {
+		t.Fatalf("got %d backups, expected %d", len(backups), maxBackups)
+	}
+	for i, b := range backups {
+		expected := start.Add(time.Duration(maxBackups+4-i) * time.Minute)
+		if !b.Time.Equal(expected) {
+			t.Errorf("backup %d: got time %s, expected %s", i, b.Time, expected)
+		}
+		if contents, err := os.ReadFile(b.Path); err != nil || len(contents) != 1 || int(contents[0]) != maxBackups+4-i {
+			t.Errorf("backup %d: got contents %v (err %v)", i, contents, err)
+		}
+	}
+
+	// A file with the same name elsewhere has its own backups.
+	other := path.Join(dir, "other", "config.json")
+	if b, _ := listBackups(root, other); len(b) != 0 {
+		t.Errorf("got %d backups of %s", len(b), other)
+	}
+}
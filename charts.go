@@ -0,0 +1,313 @@
+// charts.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mmp/imgui-go/v4"
+	"github.com/pkg/browser"
+)
+
+// File types that charts may be given as. Images are shown in the
+// ChartPane; PDFs are opened in the system's viewer.
+var chartExtensions = []string{".png", ".jpg", ".jpeg", ".pdf"}
+
+// ChartPane shows approach plates and SID and STAR charts so that
+// procedures can be referenced without leaving vice. The charts are given
+// by the scenario's airports, keyed by procedure; since they aren't
+// distributed with vice, relative filenames are found in the "charts"
+// directory next to the config file. If FollowSelected is set, selecting
+// an aircraft brings up the chart for the approach it is expecting (or its
+// STAR or SID).
+type ChartPane struct {
+	Enabled        bool
+	Airport        string
+	Procedure      string
+	FollowSelected bool
+	FontSize       int
+	font           *Font
+
+	zoom   float32    // 1 fits the chart to the pane
+	offset [2]float32 // of the chart's center from the pane's, in pixels
+
+	filename string // the chart that was last loaded
+	texId    uint32
+	size     [2]float32 // image resolution
+	err      error
+
+	charts      map[string]map[string]string // the scenario's, for the UI
+	renderer    Renderer
+	eventStream *EventStream
+	events      *EventsSubscription
+}
+
+func NewChartPane() *ChartPane {
+	return &ChartPane{FollowSelected: true, FontSize: 12}
+}
+
+func (cp *ChartPane) Name() string { return "Charts" }
+
+func (cp *ChartPane) Activate(w *World, r Renderer, eventStream *EventStream) {
+	if cp.FontSize == 0 {
+		cp.FontSize = 12
+	}
+	if cp.font = GetFont(FontIdentifier{Name: "Flight Strip Printer", Size: cp.FontSize}); cp.font == nil {
+		cp.font = GetDefaultFont()
+	}
+	cp.renderer = r
+	cp.eventStream = eventStream
+
+	// Images are loaded when the pane is next drawn, but PDFs aren't
+	// opened again until a different chart is selected.
+	cp.filename = ""
+	if w != nil {
+		if ap, ok := w.Airports[cp.Airport]; ok {
+			if fn := ap.Charts[cp.Procedure]; strings.ToLower(filepath.Ext(fn)) == ".pdf" {
+				cp.filename = fn
+			}
+		}
+	}
+}
+
+func (cp *ChartPane) Deactivate() {
+	cp.freeTexture()
+	cp.unsubscribe()
+}
+
+func (cp *ChartPane) ResetWorld(w *World) {}
+
+func (cp *ChartPane) CanTakeKeyboardFocus() bool { return false }
+
+// Hidden implements the PaneHider interface; the ChartPane is only shown
+// when it is enabled.
+func (cp *ChartPane) Hidden() bool { return !cp.Enabled }
+
+func (cp *ChartPane) DrawUI() {
+	if imgui.Checkbox("Show charts", &cp.Enabled) && !cp.Enabled {
+		// Selections aren't followed while the pane isn't drawn.
+		cp.unsubscribe()
+	}
+
+	uiStartDisable(!cp.Enabled)
+	if len(cp.charts) == 0 {
+		imgui.Text("The scenario doesn't have any charts.")
+	} else {
+		if imgui.BeginComboV("Airport##charts", cp.Airport, imgui.ComboFlagsHeightLarge) {
+			for _, icao := range SortedMapKeys(cp.charts) {
+				if imgui.SelectableV(icao, icao == cp.Airport, 0, imgui.Vec2{}) {
+					cp.Airport = icao
+					cp.Procedure = ""
+				}
+			}
+			imgui.EndCombo()
+		}
+		if imgui.BeginComboV("Procedure##charts", cp.Procedure, imgui.ComboFlagsHeightLarge) {
+			for _, proc := range SortedMapKeys(cp.charts[cp.Airport]) {
+				if imgui.SelectableV(proc, proc == cp.Procedure, 0, imgui.Vec2{}) {
+					cp.Procedure = proc
+				}
+			}
+			imgui.EndCombo()
+		}
+	}
+	imgui.Checkbox("Show the chart for the selected aircraft's procedure", &cp.FollowSelected)
+	id := FontIdentifier{Name: cp.font.id.Name, Size: cp.FontSize}
+	if newFont, changed := DrawFontSizeSelector(&id); changed {
+		cp.FontSize = newFont.size
+		cp.font = newFont
+	}
+	uiEndDisable(!cp.Enabled)
+}
+
+func (cp *ChartPane) unsubscribe() {
+	if cp.events != nil {
+		cp.events.Unsubscribe()
+		cp.events = nil
+	}
+}
+
+// chartForAircraft returns the airport and procedure of the chart that is
+// most useful for the given aircraft: for arrivals, the approach that it
+// is expecting or otherwise its STAR, and for departures, its SID.
+func chartForAircraft(w *World, ac *Aircraft) (string, string, bool) {
+	fp := ac.FlightPlan
+	if fp == nil {
+		return "", "", false
+	}
+	haveChart := func(icao, proc string) bool {
+		ap, ok := w.Airports[icao]
+		if !ok || proc == "" {
+			return false
+		}
+		_, ok = ap.Charts[proc]
+		return ok
+	}
+
+	if ac.IsDeparture() {
+		// Departure routes start with the SID, if there is one.
+		if f := strings.Fields(fp.Route); len(f) > 0 && haveChart(fp.DepartureAirport, f[0]) {
+			return fp.DepartureAirport, f[0], true
+		}
+		return "", "", false
+	}
+	for _, proc := range []string{ac.Nav.Approach.AssignedId, ac.STAR} {
+		if haveChart(fp.ArrivalAirport, proc) {
+			return fp.ArrivalAirport, proc, true
+		}
+	}
+	return "", "", false
+}
+
+// chartPath returns the path to the given chart file.
+func chartPath(fn string) string {
+	if filepath.IsAbs(fn) {
+		return fn
+	}
+	return filepath.Join(filepath.Dir(configFilePath()), "charts", filepath.FromSlash(fn))
+}
+
+func (cp *ChartPane) freeTexture() {
+	if cp.texId != 0 {
+		cp.renderer.DestroyTexture(cp.texId)
+		cp.texId = 0
+	}
+}
+
+// load loads the given chart, replacing the current one. PDFs are opened
+// in the system's viewer rather than being loaded.
+func (cp *ChartPane) load(fn string) {
+	cp.freeTexture()
+	cp.filename, cp.err = fn, nil
+	cp.zoom, cp.offset = 1, [2]float32{}
+	if fn == "" {
+		return
+	}
+
+	path := chartPath(fn)
+	if strings.ToLower(filepath.Ext(path)) == ".pdf" {
+		if _, err := os.Stat(path); err != nil {
+			cp.err = err
+		} else {
+			cp.err = browser.OpenFile(path)
+		}
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		cp.err = err
+		return
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		cp.err = fmt.Errorf("%s: %w", path, err)
+		return
+	}
+	cp.texId = cp.renderer.CreateTextureFromImage(img, false)
+	b := img.Bounds()
+	cp.size = [2]float32{float32(b.Dx()), float32(b.Dy())}
+}
+
+func (cp *ChartPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	cb.ClearRGB(RGB{0.2, 0.2, 0.2})
+
+	w := ctx.world
+	cp.charts = make(map[string]map[string]string)
+	for icao, ap := range w.Airports {
+		if len(ap.Charts) > 0 {
+			cp.charts[icao] = ap.Charts
+		}
+	}
+
+	if cp.events == nil {
+		cp.events = cp.eventStream.Subscribe()
+	}
+	for _, event := range cp.events.Get() {
+		if event.Type == SelectedAircraftEvent && event.Selected && cp.FollowSelected {
+			if ac, ok := w.Aircraft[event.Callsign]; ok {
+				if icao, proc, ok := chartForAircraft(w, ac); ok {
+					cp.Airport, cp.Procedure = icao, proc
+				}
+			}
+		}
+	}
+
+	if fn := cp.charts[cp.Airport][cp.Procedure]; fn != cp.filename {
+		cp.load(fn)
+	}
+	cp.processMouse(ctx)
+
+	ctx.SetWindowCoordinateMatrices(cb)
+	width, height := ctx.paneExtent.Width(), ctx.paneExtent.Height()
+
+	if cp.texId != 0 {
+		// Fit the chart to the pane and then apply the zoom and pan. The
+		// first row of the image is its top.
+		s := cp.zoom * min(width/cp.size[0], height/cp.size[1])
+		c := add2f([2]float32{width / 2, height / 2}, cp.offset)
+		hw, hh := s*cp.size[0]/2, s*cp.size[1]/2
+
+		tb := GetTexturedTrianglesDrawBuilder()
+		defer ReturnTexturedTrianglesDrawBuilder(tb)
+		tb.AddQuad([2]float32{c[0] - hw, c[1] - hh}, [2]float32{c[0] + hw, c[1] - hh},
+			[2]float32{c[0] + hw, c[1] + hh}, [2]float32{c[0] - hw, c[1] + hh},
+			[2]float32{0, 1}, [2]float32{1, 1}, [2]float32{1, 0}, [2]float32{0, 0})
+
+		cb.SetRGB(RGB{1, 1, 1})
+		cb.EnableTexture(cp.texId)
+		tb.GenerateCommands(cb)
+		cb.DisableTexture()
+	}
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+	style := TextStyle{Font: cp.font, Color: RGB{0.9, 0.9, 0.9}}
+	var msg string
+	switch {
+	case cp.filename == "":
+		msg = "No chart selected"
+	case cp.err != nil:
+		msg = cp.err.Error()
+		style.Color = RGB{1, 0.5, 0.5}
+	case cp.texId == 0:
+		msg = cp.Airport + " " + cp.Procedure + ": opened " + filepath.Base(cp.filename) + " in the PDF viewer"
+	default:
+		msg = cp.Airport + " " + cp.Procedure
+	}
+	lineHeight := float32(cp.font.size)
+	td.AddText(msg, [2]float32{lineHeight / 2, height - lineHeight/2}, style)
+	td.GenerateCommands(cb)
+}
+
+// processMouse handles dragging the chart with the primary button and
+// zooming with the scroll wheel; double-clicking resets the view.
+func (cp *ChartPane) processMouse(ctx *PaneContext) {
+	mouse := ctx.mouse
+	if mouse == nil || cp.texId == 0 {
+		return
+	}
+
+	if mouse.DoubleClicked[MouseButtonPrimary] {
+		cp.zoom, cp.offset = 1, [2]float32{}
+	} else if mouse.Dragging[MouseButtonPrimary] {
+		cp.offset = add2f(cp.offset, mouse.DragDelta)
+	}
+	if mouse.Wheel[1] != 0 {
+		// Zoom about the mouse position.
+		zoom := clamp(cp.zoom*pow(1.1, mouse.Wheel[1]), 0.5, 10)
+		center := [2]float32{ctx.paneExtent.Width() / 2, ctx.paneExtent.Height() / 2}
+		c := add2f(center, cp.offset)
+		c = sub2f(mouse.Pos, scale2f(sub2f(mouse.Pos, c), zoom/cp.zoom))
+		cp.offset = sub2f(c, center)
+		cp.zoom = zoom
+	}
+}
@@ -0,0 +1,48 @@
+// charts_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+)
+
+func TestChartForAircraft(t *testing.T) {
+	w := &World{
+		Airports: map[string]*Airport{
+			"KJFK": {Charts: map[string]string{"I13L": "KJFK/ils13l.png", "CAMRN4": "KJFK/camrn4.pdf",
+				"JFK5": "KJFK/jfk5.png"}},
+			"KLGA": {},
+		},
+	}
+
+	arrival := &Aircraft{
+		FlightPlan: &FlightPlan{ArrivalAirport: "KJFK"},
+		STAR:       "CAMRN4",
+	}
+	expect := func(ac *Aircraft, airport, proc string) {
+		t.Helper()
+		ap, p, ok := chartForAircraft(w, ac)
+		if ok != (proc != "") || ap != airport || p != proc {
+			t.Errorf("got %q %q %v, expected %q %q", ap, p, ok, airport, proc)
+		}
+	}
+
+	// The STAR until an approach is expected.
+	expect(arrival, "KJFK", "CAMRN4")
+	arrival.Nav.Approach.AssignedId = "I13L"
+	expect(arrival, "KJFK", "I13L")
+	// No chart for this approach, so back to the STAR.
+	arrival.Nav.Approach.AssignedId = "I4R"
+	expect(arrival, "KJFK", "CAMRN4")
+
+	// Departures get their SID.
+	departure := &Aircraft{FlightPlan: &FlightPlan{DepartureAirport: "KJFK", Route: "JFK5 GREKI"}}
+	departure.Nav.FlightState.IsDeparture = true
+	expect(departure, "KJFK", "JFK5")
+
+	departure.FlightPlan.DepartureAirport = "KLGA"
+	expect(departure, "", "")
+	expect(&Aircraft{}, "", "")
+}
@@ -0,0 +1,370 @@
+// companion.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The companion is a small web page served by the sim server that shows a
+// controller's flight strips, arrival and departure lists, and timers so
+// that they can be displayed on a tablet next to the scope. The page
+// polls /companion/state to stay in sync with the running sim. Requests
+// must carry the controller's token (see World.CompanionURL), so the page
+// only shows the state of the position that the token was issued to.
+
+// CompanionState is the snapshot of a sim that the companion page shows.
+// Timers on the page are based on SimTime so that they follow pauses and
+// the sim rate.
+type CompanionState struct {
+	SimTime    time.Time
+	Paused     bool
+	Controller string
+	Strips     []CompanionStrip
+	Arrivals   []CompanionListEntry
+	Departures []CompanionListEntry
+}
+
+type CompanionStrip struct {
+	Callsign            string
+	AircraftType        string
+	Rules               string
+	Squawk              string
+	TempAltitude        int
+	Altitude            int
+	DepartureAirport    string
+	ArrivalAirport      string
+	AlternateAirport    string
+	Scratchpad          string
+	SecondaryScratchpad string
+	Route               string
+	Remarks             string
+	Annotations         []string
+	HandoffOffered      bool // a handoff to the controller is pending
+}
+
+type CompanionListEntry struct {
+	Callsign     string
+	AircraftType string
+	Airport      string
+	Runway       string  // departures only
+	Status       string  // departures only
+	Distance     float32 // arrivals: nm to the airport
+	MeterFix     string  // arrivals: the meter fix and ETAs from Aircraft.ETAs
+	MeterFixETA  string
+	FAFETA       string
+	ETA          string // arrivals: at the runway (or the airport)
+	eta          time.Time
+}
+
+// maxCompanionDepartureDistance is how far from their departure airport
+// airborne departures are still shown in the departure list.
+const maxCompanionDepartureDistance = 25
+
+// makeCompanionState returns the companion state for the given
+// controller at the given sim time.
+func makeCompanionState(w *World, releases []DepartureRelease, controller string, simTime time.Time) CompanionState {
+	cs := CompanionState{SimTime: simTime}
+
+	for _, callsign := range SortedMapKeys(w.Aircraft) {
+		ac := w.Aircraft[callsign]
+		if ac.FlightPlan == nil {
+			continue
+		}
+		fp := ac.FlightPlan
+
+		if controller != "" && (ac.TrackingController == controller || ac.ControllingController == controller ||
+			ac.HandoffTrackController == controller) {
+			cs.Strips = append(cs.Strips, CompanionStrip{
+				Callsign:            ac.Callsign,
+				AircraftType:        fp.AircraftType,
+				Rules:               fp.Rules.String(),
+				Squawk:              ac.AssignedSquawk.String(),
+				TempAltitude:        ac.TempAltitude,
+				Altitude:            fp.Altitude,
+				DepartureAirport:    fp.DepartureAirport,
+				ArrivalAirport:      fp.ArrivalAirport,
+				AlternateAirport:    fp.AlternateAirport,
+				Scratchpad:          ac.Scratchpad,
+				SecondaryScratchpad: ac.SecondaryScratchpad,
+				Route:               fp.Route,
+				Remarks:             fp.Remarks,
+				Annotations:         slices.DeleteFunc(slices.Clone(ac.Strip.Annotations[:]), func(s string) bool { return s == "" }),
+				HandoffOffered:      ac.HandoffTrackController == controller,
+			})
+		}
+
+		if !ac.Nav.IsAirborne() {
+			continue
+		}
+		if ac.IsDeparture() {
+			if ap, ok := w.DepartureAirports[fp.DepartureAirport]; ok && ap != nil {
+				if d := nmdistance2ll(ac.Position(), ap.Location); d < maxCompanionDepartureDistance {
+					cs.Departures = append(cs.Departures, CompanionListEntry{
+						Callsign:     ac.Callsign,
+						AircraftType: fp.AircraftType,
+						Airport:      fp.DepartureAirport,
+						Status:       "Airborne",
+						Distance:     d,
+					})
+				}
+			}
+		} else if ap, ok := w.ArrivalAirports[fp.ArrivalAirport]; ok && ap != nil {
+			e := CompanionListEntry{
+				Callsign:     ac.Callsign,
+				AircraftType: fp.AircraftType,
+				Airport:      fp.ArrivalAirport,
+				Distance:     nmdistance2ll(ac.Position(), ap.Location),
+			}
+			format := func(t time.Time) string {
+				return Select(t.IsZero(), "", t.UTC().Format("1504"))
+			}
+			if etas := ac.ETAs; !etas.RunwayTime.IsZero() {
+				e.MeterFix, e.MeterFixETA = etas.MeterFix, format(etas.MeterFixTime)
+				e.FAFETA, e.ETA, e.eta = format(etas.FAFTime), format(etas.RunwayTime), etas.RunwayTime
+			}
+			cs.Arrivals = append(cs.Arrivals, e)
+		}
+	}
+
+	// Arrivals are listed in the order they're expected at the runway;
+	// those without an ETA go at the end.
+	sort.SliceStable(cs.Arrivals, func(i, j int) bool {
+		a, b := cs.Arrivals[i], cs.Arrivals[j]
+		if a.eta.IsZero() != b.eta.IsZero() {
+			return b.eta.IsZero()
+		} else if !a.eta.Equal(b.eta) {
+			return a.eta.Before(b.eta)
+		}
+		return a.Distance < b.Distance
+	})
+	sort.SliceStable(cs.Departures, func(i, j int) bool { return cs.Departures[i].Distance < cs.Departures[j].Distance })
+
+	// Departures waiting on the ground go before the airborne ones.
+	var ground []CompanionListEntry
+	for _, rel := range releases {
+		status := "Awaiting release"
+		if rel.Held {
+			status = "Held"
+		}
+		ac := rel.Aircraft
+		e := CompanionListEntry{Callsign: ac.Callsign, Runway: rel.Runway, Status: status}
+		if ac.FlightPlan != nil {
+			e.AircraftType = ac.FlightPlan.AircraftType
+			e.Airport = ac.FlightPlan.DepartureAirport
+		}
+		ground = append(ground, e)
+	}
+	cs.Departures = append(ground, cs.Departures...)
+
+	return cs
+}
+
+// CompanionState returns the view of the sim for the companion page of
+// the controller with the given token.
+func (s *Sim) CompanionState(token string) (CompanionState, error) {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	sc, ok := s.controllers[token]
+	if !ok {
+		return CompanionState{}, ErrInvalidControllerToken
+	} else if sc.coach || sc.Callsign == "Observer" || sc.Callsign == "Spectator" {
+		// Only controllers get the live state; spectators are limited to
+		// the delayed updates.
+		return CompanionState{}, ErrInvalidControllerToken
+	}
+
+	cs := makeCompanionState(s.World, s.DepartureReleases, sc.Callsign, s.SimTime)
+	cs.Paused = s.Paused
+	cs.Controller = sc.Callsign
+	return cs, nil
+}
+
+// CompanionURL returns the address of the companion page for the user's
+// position. It includes the controller token in the URL's fragment so
+// that the page can authenticate its requests.
+func (w *World) CompanionURL() string {
+	host := "localhost"
+	if localServer == nil || w.simProxy.Client != localServer.RPCClient {
+		host, _, _ = strings.Cut(*serverAddress, ":")
+	}
+	return "http://" + host + ":6502/companion#" + url.PathEscape(w.simProxy.ControllerToken)
+}
+
+// companionStateHandler serves the companion state. The controller token
+// is given in the Authorization header rather than the URL so that it
+// doesn't end up in logs.
+func companionStateHandler(w http.ResponseWriter, r *http.Request, sm *SimManager) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		http.Error(w, ErrInvalidControllerToken.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	sim, ok := sm.ControllerTokenToSim(token)
+	if !ok {
+		http.Error(w, ErrInvalidControllerToken.Error(), http.StatusForbidden)
+		return
+	}
+	cs, err := sim.CompanionState(token)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(cs); err != nil {
+		lg.Errorf("%s: %v", r.URL.String(), err)
+	}
+}
+
+func companionPageHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write([]byte(companionPage)); err != nil {
+		lg.Errorf("%s: %v", r.URL.String(), err)
+	}
+}
+
+const companionPage = `<!DOCTYPE html>
+<html>
+<head>
+<title>vice companion</title>
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<style>
+body { background: #111; color: #ddd; font-family: monospace; font-size: 15px; margin: 8px; }
+h2 { font-size: 15px; color: #8cf; margin: 12px 0 4px 0; }
+select, input, button { font-family: monospace; font-size: 15px; background: #222; color: #ddd; border: 1px solid #555; }
+table { border-collapse: collapse; width: 100%; }
+td, th { padding: 2px 6px; text-align: left; }
+tr:nth-child(even) { background: #1a1a1a; }
+.strip { display: grid; grid-template-columns: 8em 6em 1fr 8em; border: 1px solid #555; margin: 3px 0; background: #e8e4d0; color: #000; }
+.strip > div { padding: 3px 5px; border-right: 1px solid #999; white-space: pre-wrap; }
+.offered { background: #f4e39a; }
+.timer { display: inline-block; border: 1px solid #555; padding: 4px 8px; margin: 2px; }
+#status { color: #f88; }
+</style>
+</head>
+<body>
+<div>
+<span id="position"></span> <span id="clock"></span> <span id="status"></span>
+</div>
+
+<h2>Flight Strips</h2>
+<div id="strips"></div>
+
+<h2>Arrivals</h2>
+<table id="arrivals"></table>
+
+<h2>Departures</h2>
+<table id="departures"></table>
+
+<h2>Timers</h2>
+<div>
+<input id="timerLabel" size="10" placeholder="label">
+<input id="timerMinutes" size="3" placeholder="min">
+<button onclick="addTimer()">Add</button>
+</div>
+<div id="timers"></div>
+
+<script>
+let state = null;
+let timers = JSON.parse(localStorage.getItem("viceTimers") || "[]");
+
+function el(id) { return document.getElementById(id); }
+function esc(s) { return String(s ?? "").replace(/[&<>"]/g, c => ({"&":"&amp;","<":"&lt;",">":"&gt;",'"':"&quot;"})[c]); }
+function simMs() { return state ? Date.parse(state.SimTime) : 0; }
+function fmt(ms) {
+  const s = Math.max(0, Math.round(Math.abs(ms) / 1000));
+  return (ms < 0 ? "-" : "") + Math.floor(s / 60) + ":" + String(s % 60).padStart(2, "0");
+}
+
+// The controller token is passed in the URL's fragment, which isn't sent
+// to the server; it is kept in session storage and then removed from the
+// address bar.
+if (location.hash.length > 1) {
+  sessionStorage.setItem("viceCompanionToken", decodeURIComponent(location.hash.substr(1)));
+  history.replaceState(null, "", location.pathname);
+}
+const token = sessionStorage.getItem("viceCompanionToken") || "";
+
+function draw() {
+  el("position").textContent = state.Controller;
+  el("clock").textContent = new Date(simMs()).toISOString().substr(11, 8) + "Z" + (state.Paused ? " PAUSED" : "");
+  el("strips").innerHTML = (state.Strips || []).map(s =>
+    "<div class='strip" + (s.HandoffOffered ? " offered" : "") + "'>" +
+    "<div>" + esc(s.Callsign) + "\n" + esc(s.AircraftType) + "\n" + esc(s.Rules) + "</div>" +
+    "<div>" + esc(s.Squawk) + "\n" + (s.TempAltitude || "") + "\n" + s.Altitude + "</div>" +
+    "<div>" + esc([s.DepartureAirport, s.ArrivalAirport, s.AlternateAirport].join(" ")) + "  " +
+    esc(s.Scratchpad) + " " + esc(s.SecondaryScratchpad) + "\n" + esc(s.Route) + "\n" + esc(s.Remarks) + "</div>" +
+    "<div>" + esc((s.Annotations || []).join(" ")) + "</div></div>").join("");
+  el("arrivals").innerHTML = "<tr><th>Callsign</th><th>Type</th><th>Airport</th><th>Dist</th><th>Meter</th><th>FAF</th><th>ETA</th></tr>" +
+    (state.Arrivals || []).map(a => "<tr><td>" + esc(a.Callsign) + "</td><td>" + esc(a.AircraftType) +
+      "</td><td>" + esc(a.Airport) + "</td><td>" + a.Distance.toFixed(1) + "</td><td>" +
+      esc(a.MeterFix ? a.MeterFix + " " + a.MeterFixETA : "") + "</td><td>" + esc(a.FAFETA) +
+      "</td><td>" + esc(a.ETA) + "</td></tr>").join("");
+  el("departures").innerHTML = "<tr><th>Callsign</th><th>Type</th><th>Airport</th><th>Runway</th><th>Status</th></tr>" +
+    (state.Departures || []).map(d => "<tr><td>" + esc(d.Callsign) + "</td><td>" + esc(d.AircraftType) +
+      "</td><td>" + esc(d.Airport) + "</td><td>" + esc(d.Runway) + "</td><td>" + esc(d.Status) + "</td></tr>").join("");
+  drawTimers();
+}
+
+function drawTimers() {
+  const now = simMs();
+  el("timers").innerHTML = timers.map((t, i) => {
+    const ms = t.end ? t.end - now : now - t.start;
+    return "<span class='timer'>" + esc(t.label) + " " + fmt(ms) +
+      " <button onclick='removeTimer(" + i + ")'>x</button></span>";
+  }).join("");
+}
+
+function addTimer() {
+  if (!state) return;
+  const min = parseFloat(el("timerMinutes").value);
+  const t = {label: el("timerLabel").value, start: simMs()};
+  if (min > 0) t.end = t.start + min * 60000;
+  timers.push(t);
+  localStorage.setItem("viceTimers", JSON.stringify(timers));
+  drawTimers();
+}
+
+function removeTimer(i) {
+  timers.splice(i, 1);
+  localStorage.setItem("viceTimers", JSON.stringify(timers));
+  drawTimers();
+}
+
+async function poll() {
+  if (!token) {
+    el("status").textContent = "Open this page using the link from the vice Settings window";
+    return;
+  }
+  try {
+    const resp = await fetch("/companion/state", {headers: {"Authorization": "Bearer " + token}});
+    if (!resp.ok) {
+      el("status").textContent = await resp.text();
+    } else {
+      state = await resp.json();
+      el("status").textContent = "";
+      draw();
+    }
+  } catch (e) {
+    el("status").textContent = "Unable to reach the vice server";
+  }
+  setTimeout(poll, 1000);
+}
+
+poll();
+</script>
+</body>
+</html>
+`
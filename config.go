@@ -33,7 +33,9 @@ import (
 // 21: STARS DCB drawing changes, so system list positions changed
 // 22: draw points using triangles, remove some CommandBuffer commands
 // 23: video map format update
-const CurrentConfigVersion = 23
+// 24: STARS airport diagrams
+// 25: STARS ownership colors
+const CurrentConfigVersion = 25
 
 // Slightly convoluted, but the full GlobalConfig definition is split into
 // the part with the Sim and the rest of it.  In this way, we can first
@@ -56,20 +58,30 @@ type GlobalConfigNoSim struct {
 	LastServer            string
 	LastTRACON            string
 	UIFontSize            int
+	UIScale               float32
 
 	Audio AudioEngine
 
 	DisplayRoot *DisplayNode
 
+	// Named layouts that can be switched between, e.g. for different
+	// positions.
+	DisplayProfiles map[string]*DisplayProfile
+
 	AskedDiscordOptIn        bool
 	InhibitDiscordActivity   AtomicBool
 	NotifiedNewCommandSyntax bool
 	StartInFullScreen        bool
+	ExportDebriefOnSignOff   bool
 
 	Callsign string
 
 	highlightedLocation        Point2LL
 	highlightedLocationEndTime time.Time
+
+	// Set when a backup of the config file has been restored so that it
+	// isn't overwritten before vice is restarted.
+	restoredBackup bool
 }
 
 type GlobalConfigSim struct {
@@ -99,14 +111,30 @@ func (gc *GlobalConfig) Encode(w io.Writer) error {
 }
 
 func (c *GlobalConfig) Save() error {
-	lg.Infof("Saving config to: %s", configFilePath())
-	f, err := os.Create(configFilePath())
+	fn := configFilePath()
+	if c.restoredBackup {
+		lg.Infof("Not saving config to %s since a backup was restored", fn)
+		return nil
+	}
+	if err := BackupFile(fn); err != nil {
+		lg.Errorf("%s: unable to back up config file: %v", fn, err)
+	}
+
+	// Write to a temporary file and then rename it so that a crash while
+	// saving doesn't leave a truncated config file.
+	lg.Infof("Saving config to: %s", fn)
+	f, err := os.Create(fn + ".tmp")
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-
-	return c.Encode(f)
+	if err := c.Encode(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(fn+".tmp", fn)
 }
 
 func (gc *GlobalConfig) SaveIfChanged(renderer Renderer, platform Platform, w *World, saveSim bool) bool {
@@ -164,6 +192,11 @@ func LoadOrMakeDefaultConfig() {
 	fn := configFilePath()
 	lg.Infof("Loading config from: %s", fn)
 
+	// Save a copy before it's upgraded or, if it's corrupt, replaced.
+	if err := BackupFile(fn); err != nil {
+		lg.Errorf("%s: unable to back up config file: %v", fn, err)
+	}
+
 	SetDefaultConfig()
 	if config, err := os.ReadFile(fn); err == nil {
 		r := bytes.NewReader(config)
@@ -210,6 +243,12 @@ func LoadOrMakeDefaultConfig() {
 	if globalConfig.UIFontSize == 0 {
 		globalConfig.UIFontSize = 16
 	}
+	if globalConfig.UIScale == 0 {
+		globalConfig.UIScale = 1
+	}
+	if globalConfig.Audio.PushToTalkKey == 0 {
+		globalConfig.Audio.PushToTalkKey = PushToTalkKeys["Insert"]
+	}
 	globalConfig.Version = CurrentConfigVersion
 
 	if err := globalConfig.Audio.Activate(); err != nil {
@@ -280,5 +319,98 @@ func (gc *GlobalConfig) Activate(w *World, r Renderer, eventStream *EventStream)
 		}
 	}
 
+	// Similarly, add a NonRadarPane alongside the STARS scope; only one of
+	// the two is shown at a time.
+	haveNonRadar := false
+	var stars *STARSPane
+	gc.DisplayRoot.VisitPanes(func(p Pane) {
+		switch pane := p.(type) {
+		case *NonRadarPane:
+			haveNonRadar = true
+		case *STARSPane:
+			// If there are multiple scopes, the other panes go with the
+			// first one.
+			if stars == nil {
+				stars = pane
+			}
+		}
+	})
+	if !haveNonRadar && stars != nil {
+		node := gc.DisplayRoot.NodeForPane(stars)
+		*node = DisplayNode{
+			SplitLine: SplitLine{
+				Pos:  0.5,
+				Axis: SplitAxisX,
+			},
+			Children: [2]*DisplayNode{
+				&DisplayNode{Pane: stars},
+				&DisplayNode{Pane: NewNonRadarPane()},
+			},
+		}
+	}
+
+	// And a SurfacePane, which is shown next to the scope when enabled.
+	haveSurface := false
+	gc.DisplayRoot.VisitPanes(func(p Pane) {
+		if _, ok := p.(*SurfacePane); ok {
+			haveSurface = true
+		}
+	})
+	if !haveSurface && stars != nil {
+		node := gc.DisplayRoot.NodeForPane(stars)
+		*node = DisplayNode{
+			SplitLine: SplitLine{
+				Pos:  0.5,
+				Axis: SplitAxisX,
+			},
+			Children: [2]*DisplayNode{
+				&DisplayNode{Pane: stars},
+				&DisplayNode{Pane: NewSurfacePane()},
+			},
+		}
+	}
+
+	// Similarly for the TowerPane.
+	haveTower := false
+	gc.DisplayRoot.VisitPanes(func(p Pane) {
+		if _, ok := p.(*TowerPane); ok {
+			haveTower = true
+		}
+	})
+	if !haveTower && stars != nil {
+		node := gc.DisplayRoot.NodeForPane(stars)
+		*node = DisplayNode{
+			SplitLine: SplitLine{
+				Pos:  0.5,
+				Axis: SplitAxisX,
+			},
+			Children: [2]*DisplayNode{
+				&DisplayNode{Pane: stars},
+				&DisplayNode{Pane: NewTowerPane()},
+			},
+		}
+	}
+
+	// And the ChartPane.
+	haveCharts := false
+	gc.DisplayRoot.VisitPanes(func(p Pane) {
+		if _, ok := p.(*ChartPane); ok {
+			haveCharts = true
+		}
+	})
+	if !haveCharts && stars != nil {
+		node := gc.DisplayRoot.NodeForPane(stars)
+		*node = DisplayNode{
+			SplitLine: SplitLine{
+				Pos:  0.6,
+				Axis: SplitAxisX,
+			},
+			Children: [2]*DisplayNode{
+				&DisplayNode{Pane: stars},
+				&DisplayNode{Pane: NewChartPane()},
+			},
+		}
+	}
+
 	gc.DisplayRoot.VisitPanes(func(p Pane) { p.Activate(w, r, eventStream) })
 }
@@ -0,0 +1,295 @@
+// controlmap.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+type STARSControlAction int
+
+const (
+	STARSControlRange STARSControlAction = iota
+	STARSControlBrightness
+	STARSControlSimRate
+	STARSControlPreset
+)
+
+var starsControlActions = map[string]STARSControlAction{
+	"range":      STARSControlRange,
+	"brightness": STARSControlBrightness,
+	"sim_rate":   STARSControlSimRate,
+	"preset":     STARSControlPreset,
+}
+
+// starsBrightnessControl gives the preference set brightness that is
+// adjusted by a "brightness" binding along with its limits, which match
+// the corresponding BRITE DCB spinner.
+type starsBrightnessControl struct {
+	b        func(ps *STARSPreferenceSet) *STARSBrightness
+	min      STARSBrightness
+	allowOff bool
+}
+
+var starsBrightnessControls = map[string]starsBrightnessControl{
+	"DCB": {func(ps *STARSPreferenceSet) *STARSBrightness { return &ps.Brightness.DCB }, 25, false},
+	"BKC": {func(ps *STARSPreferenceSet) *STARSBrightness { return &ps.Brightness.BackgroundContrast }, 0, false},
+	"MPA": {func(ps *STARSPreferenceSet) *STARSBrightness { return &ps.Brightness.VideoGroupA }, 5, false},
+	"MPB": {func(ps *STARSPreferenceSet) *STARSBrightness { return &ps.Brightness.VideoGroupB }, 5, false},
+	"FDB": {func(ps *STARSPreferenceSet) *STARSBrightness { return &ps.Brightness.FullDatablocks }, 5, true},
+	"LST": {func(ps *STARSPreferenceSet) *STARSBrightness { return &ps.Brightness.Lists }, 25, false},
+	"POS": {func(ps *STARSPreferenceSet) *STARSBrightness { return &ps.Brightness.Positions }, 5, true},
+	"LDB": {func(ps *STARSPreferenceSet) *STARSBrightness { return &ps.Brightness.LimitedDatablocks }, 5, true},
+	"OTH": {func(ps *STARSPreferenceSet) *STARSBrightness { return &ps.Brightness.OtherTracks }, 5, true},
+	"TLS": {func(ps *STARSPreferenceSet) *STARSBrightness { return &ps.Brightness.Lines }, 5, true},
+	"RR":  {func(ps *STARSPreferenceSet) *STARSBrightness { return &ps.Brightness.RangeRings }, 5, true},
+	"CMP": {func(ps *STARSPreferenceSet) *STARSBrightness { return &ps.Brightness.Compass }, 5, true},
+	"BCN": {func(ps *STARSPreferenceSet) *STARSBrightness { return &ps.Brightness.BeaconSymbols }, 5, true},
+	"PRI": {func(ps *STARSPreferenceSet) *STARSBrightness { return &ps.Brightness.PrimarySymbols }, 5, true},
+	"HST": {func(ps *STARSPreferenceSet) *STARSBrightness { return &ps.Brightness.History }, 5, true},
+	"WX":  {func(ps *STARSPreferenceSet) *STARSBrightness { return &ps.Brightness.Weather }, 5, true},
+	"WXC": {func(ps *STARSPreferenceSet) *STARSBrightness { return &ps.Brightness.WxContrast }, 5, false},
+}
+
+// STARSControlBinding binds an axis or a button of an external input
+// device to one of the scope's controls.
+type STARSControlBinding struct {
+	Device string // substring of the device's name; the first device if empty
+	Axis   int    // -1 if a button is bound
+	Button int    // -1 if an axis is bound
+	Action STARSControlAction
+	Target string // BRITE control for STARSControlBrightness
+	// Value is the amount to step the control by for buttons; for
+	// STARSControlPreset it's the preference set number.
+	Value float32
+}
+
+// STARSControlMap holds the bindings of hardware controls--rotary
+// encoders, sliders, and buttons on control surfaces and game controllers
+// that the OS exposes as joysticks--to scope controls. It's loaded from a
+// JSON file in the user's configuration directory, e.g.:
+//
+//	[
+//	  { "axis": 0, "action": "range" },
+//	  { "device": "Xbox", "axis": 1, "action": "brightness", "target": "FDB" },
+//	  { "button": 4, "action": "sim_rate", "value": -1 },
+//	  { "button": 6, "action": "preset", "value": 2 }
+//	]
+//
+// Axes set the control's value from their position while buttons step
+// the control by the given value (or recall the given preference set).
+// MIDI control surfaces and devices like the Stream Deck can be used via
+// their vendors' joystick or keyboard emulation modes.
+type STARSControlMap struct {
+	Bindings []STARSControlBinding
+
+	// Axes are only applied when they move so that the controls can
+	// still be adjusted from the DCB; buttons are applied when pressed.
+	lastAxis    []*float32
+	lastPressed []bool
+}
+
+func starsControlMapPath() string {
+	return path.Join(path.Dir(configFilePath()), "stars-controls.json")
+}
+
+// LoadSTARSControlMap returns the user's hardware control bindings; there
+// are none if the file doesn't exist.
+func LoadSTARSControlMap() (*STARSControlMap, error) {
+	contents, err := os.ReadFile(starsControlMapPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return &STARSControlMap{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return parseSTARSControlMap(contents)
+}
+
+func parseSTARSControlMap(contents []byte) (*STARSControlMap, error) {
+	var entries []struct {
+		Device string   `json:"device"`
+		Axis   *int     `json:"axis"`
+		Button *int     `json:"button"`
+		Action string   `json:"action"`
+		Target string   `json:"target"`
+		Value  *float32 `json:"value"`
+	}
+	if err := json.Unmarshal(contents, &entries); err != nil {
+		return nil, err
+	}
+
+	cm := &STARSControlMap{}
+	for i, e := range entries {
+		b := STARSControlBinding{Device: e.Device, Axis: -1, Button: -1, Target: strings.ToUpper(e.Target), Value: 1}
+
+		if (e.Axis == nil) == (e.Button == nil) {
+			return nil, fmt.Errorf("binding %d: must specify exactly one of \"axis\" and \"button\"", i+1)
+		} else if e.Axis != nil {
+			if *e.Axis < 0 {
+				return nil, fmt.Errorf("binding %d: invalid axis %d", i+1, *e.Axis)
+			} else if e.Value != nil {
+				return nil, fmt.Errorf("binding %d: \"value\" can only be given for buttons", i+1)
+			}
+			b.Axis = *e.Axis
+		} else {
+			if *e.Button < 0 {
+				return nil, fmt.Errorf("binding %d: invalid button %d", i+1, *e.Button)
+			}
+			b.Button = *e.Button
+			if e.Value != nil {
+				b.Value = *e.Value
+			}
+		}
+
+		var ok bool
+		if b.Action, ok = starsControlActions[strings.ToLower(e.Action)]; !ok {
+			return nil, fmt.Errorf("binding %d: unknown action \"%s\"", i+1, e.Action)
+		}
+		switch b.Action {
+		case STARSControlBrightness:
+			if _, ok := starsBrightnessControls[b.Target]; !ok {
+				return nil, fmt.Errorf("binding %d: unknown brightness \"target\" \"%s\"", i+1, e.Target)
+			}
+		case STARSControlPreset:
+			if b.Button == -1 {
+				return nil, fmt.Errorf("binding %d: presets can only be bound to buttons", i+1)
+			} else if b.Value != float32(int(b.Value)) || b.Value < 1 || b.Value > NumSTARSPreferenceSets {
+				return nil, fmt.Errorf("binding %d: preset \"value\" must be between 1 and %d", i+1,
+					NumSTARSPreferenceSets)
+			}
+		}
+
+		cm.Bindings = append(cm.Bindings, b)
+	}
+
+	cm.lastAxis = make([]*float32, len(cm.Bindings))
+	cm.lastPressed = make([]bool, len(cm.Bindings))
+	return cm, nil
+}
+
+// findDevice returns the binding's device from the given ones.
+func (b STARSControlBinding) findDevice(joysticks []Joystick) (Joystick, bool) {
+	for _, j := range joysticks {
+		if strings.Contains(strings.ToLower(j.Name), strings.ToLower(b.Device)) {
+			return j, true
+		}
+	}
+	return Joystick{}, false
+}
+
+// processControlMap applies the user's hardware control bindings.
+func (sp *STARSPane) processControlMap(ctx *PaneContext) {
+	cm := sp.controlMap
+	if cm == nil || len(cm.Bindings) == 0 {
+		return
+	}
+
+	joysticks := ctx.platform.Joysticks()
+	ps := &sp.CurrentPreferenceSet
+	for i, b := range cm.Bindings {
+		joy, ok := b.findDevice(joysticks)
+		if !ok {
+			continue
+		}
+
+		if b.Axis != -1 {
+			if b.Axis >= len(joy.Axes) {
+				continue
+			}
+			v := joy.Axes[b.Axis]
+			last := cm.lastAxis[i]
+			cm.lastAxis[i] = &v
+			if last == nil || abs(v-*last) < 0.01 {
+				continue
+			}
+
+			t := clamp((v+1)/2, 0, 1)
+			switch b.Action {
+			case STARSControlRange:
+				// Exponential so that there's finer control at short ranges.
+				ps.Range = float32(int(6 * pow(256./6., t)))
+			case STARSControlBrightness:
+				bc := starsBrightnessControls[b.Target]
+				br := STARSBrightness(lerp(t, float32(bc.min), 100))
+				if t == 0 && bc.allowOff {
+					br = 0
+				}
+				*bc.b(ps) = br
+			case STARSControlSimRate:
+				if rate := float32(int(2*lerp(t, 1, 20))) / 2; rate != ctx.world.SimRate {
+					ctx.world.SetSimRate(rate)
+				}
+			}
+		} else {
+			pressed := b.Button < len(joy.Buttons) && joy.Buttons[b.Button]
+			wasPressed := cm.lastPressed[i]
+			cm.lastPressed[i] = pressed
+			if !pressed || wasPressed {
+				continue
+			}
+
+			switch b.Action {
+			case STARSControlRange:
+				MakeRadarRangeSpinner(&ps.Range).MouseWheel(int(b.Value))
+			case STARSControlBrightness:
+				bc := starsBrightnessControls[b.Target]
+				MakeBrightnessSpinner(b.Target, bc.b(ps), bc.min, bc.allowOff).MouseWheel(int(b.Value))
+			case STARSControlSimRate:
+				ctx.world.SetSimRate(clamp(ctx.world.SimRate+b.Value, 1, 20))
+			case STARSControlPreset:
+				if idx := int(b.Value) - 1; idx < len(sp.PreferenceSets) {
+					sp.recallPreferenceSet(ctx, idx)
+				}
+			}
+		}
+	}
+}
+
+func (sp *STARSPane) loadControlMap() {
+	if cm, err := LoadSTARSControlMap(); err != nil {
+		lg.Errorf("%s: %v", starsControlMapPath(), err)
+		ShowErrorDialog("Error loading STARS hardware controls %s: %v", starsControlMapPath(), err)
+		sp.controlMap = &STARSControlMap{}
+	} else {
+		sp.controlMap = cm
+	}
+}
+
+// drawControlMapUI shows the bindings file and the connected devices'
+// inputs, which is helpful for figuring out which axis and button numbers
+// to bind.
+func (sp *STARSPane) drawControlMapUI() {
+	n := 0
+	if sp.controlMap != nil {
+		n = len(sp.controlMap.Bindings)
+	}
+	imgui.Text(fmt.Sprintf("Hardware control bindings: %s (%d bindings)", starsControlMapPath(), n))
+	imgui.SameLine()
+	if imgui.Button("Reload##controls") {
+		sp.loadControlMap()
+	}
+
+	for _, j := range platform.Joysticks() {
+		var axes, buttons []string
+		for i, a := range j.Axes {
+			axes = append(axes, fmt.Sprintf("%d:%.2f", i, a))
+		}
+		for i, b := range j.Buttons {
+			if b {
+				buttons = append(buttons, fmt.Sprintf("%d", i))
+			}
+		}
+		imgui.Text(fmt.Sprintf("  %s: axes %s, buttons pressed: %s", j.Name, strings.Join(axes, " "),
+			strings.Join(buttons, " ")))
+	}
+}
@@ -0,0 +1,52 @@
+// controlmap_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+)
+
+func TestParseSTARSControlMap(t *testing.T) {
+	cm, err := parseSTARSControlMap([]byte(`[
+  { "axis": 0, "action": "range" },
+  { "device": "Xbox", "axis": 1, "action": "Brightness", "target": "fdb" },
+  { "button": 4, "action": "sim_rate", "value": -1 },
+  { "button": 6, "action": "preset", "value": 2 }
+]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []STARSControlBinding{
+		{Axis: 0, Button: -1, Action: STARSControlRange, Value: 1},
+		{Device: "Xbox", Axis: 1, Button: -1, Action: STARSControlBrightness, Target: "FDB", Value: 1},
+		{Axis: -1, Button: 4, Action: STARSControlSimRate, Value: -1},
+		{Axis: -1, Button: 6, Action: STARSControlPreset, Value: 2},
+	}
+	if len(cm.Bindings) != len(expected) {
+		t.Fatalf("got %d bindings, expected %d", len(cm.Bindings), len(expected))
+	}
+	for i, b := range expected {
+		if cm.Bindings[i] != b {
+			t.Errorf("binding %d: got %+v, expected %+v", i, cm.Bindings[i], b)
+		}
+	}
+
+	for _, bad := range []string{
+		`[ { "action": "range" } ]`,
+		`[ { "axis": 0, "button": 1, "action": "range" } ]`,
+		`[ { "axis": -1, "action": "range" } ]`,
+		`[ { "axis": 0, "action": "zoom" } ]`,
+		`[ { "axis": 0, "action": "range", "value": 2 } ]`,
+		`[ { "axis": 0, "action": "brightness", "target": "XYZ" } ]`,
+		`[ { "axis": 0, "action": "preset" } ]`,
+		`[ { "button": 0, "action": "preset", "value": 33 } ]`,
+		`[ { "button": 0, "action": "preset", "value": 1.5 } ]`,
+		`{ "range": 0 }`,
+	} {
+		if _, err := parseSTARSControlMap([]byte(bad)); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
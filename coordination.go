@@ -0,0 +1,244 @@
+// coordination.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// coordinationTypes are the kinds of coordination messages that may be
+// sent; the type is the first word of the message. APREQs and ride
+// requests may be approved or declined by the receiving controller.
+var coordinationTypes = []string{"APREQ", "RIDE", "SPACING", "INFO"}
+
+// formatCoordinationMessage returns the text of a coordination message of
+// the given type about the given aircraft, which may be empty.
+func formatCoordinationMessage(typ, callsign, text string) string {
+	return strings.Join(slices.DeleteFunc([]string{typ, callsign, strings.TrimSpace(text)},
+		func(s string) bool { return s == "" }), " ")
+}
+
+// coordinationNeedsReply indicates whether the receiver of the message is
+// expected to approve or decline it.
+func coordinationNeedsReply(msg string) bool {
+	typ, _, _ := strings.Cut(msg, " ")
+	return typ == "APREQ" || typ == "RIDE"
+}
+
+// SendCoordinationMessage sends a text message from the controller with
+// the given token to another signed-in controller. The message is posted
+// as a CoordinationMessageEvent; if it is about an aircraft, callsign
+// should be given so that the aircraft can be selected by the receiver.
+func (s *Sim) SendCoordinationMessage(token, toController, callsign, message string) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	sc, ok := s.controllers[token]
+	if !ok {
+		return ErrInvalidControllerToken
+	}
+	if HasSystemFailure(s.SystemFailures, FailureLandlines, sc.Callsign, toController) {
+		return ErrLandlinesFailure
+	}
+	if to := s.World.GetControllerByCallsign(toController); to == nil || !to.IsHuman || to.Callsign == sc.Callsign {
+		return ErrNoController
+	}
+	if callsign != "" {
+		if _, ok := s.World.Aircraft[callsign]; !ok {
+			return ErrNoAircraftForCallsign
+		}
+	}
+	if strings.TrimSpace(message) == "" {
+		return ErrInvalidCommandSyntax
+	}
+
+	s.eventStream.Post(Event{
+		Type:           CoordinationMessageEvent,
+		Callsign:       callsign,
+		FromController: sc.Callsign,
+		ToController:   toController,
+		Message:        message,
+	})
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////
+// CoordinationWindow
+
+// CoordinationWindow shows the coordination messages that we have sent
+// and received and lets us compose new ones. It is opened automatically
+// when a message arrives so that it isn't missed.
+type CoordinationWindow struct {
+	show     bool
+	to       string
+	typ      string
+	callsign string
+	text     string
+
+	log    []coordinationLogEntry
+	events *EventsSubscription
+}
+
+type coordinationLogEntry struct {
+	time     string
+	from, to string
+	callsign string
+	message  string
+	replied  bool
+}
+
+func (w *World) ToggleShowCoordinationWindow() {
+	if w.coordinationWindow == nil {
+		w.coordinationWindow = &CoordinationWindow{typ: coordinationTypes[0]}
+	}
+	w.coordinationWindow.show = !w.coordinationWindow.show
+}
+
+// SendCoordinationMessage sends a message to the given controller.
+func (w *World) SendCoordinationMessage(to, callsign, message string, success func(any), err func(error)) {
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.SendCoordinationMessage(to, callsign, message),
+			IssueTime: time.Now(),
+			OnSuccess: success,
+			OnErr:     err,
+		})
+}
+
+// send sends a message and adds it to the log once the server has
+// accepted it.
+func (cw *CoordinationWindow) send(w *World, to, callsign, msg string) {
+	w.SendCoordinationMessage(to, callsign, msg,
+		func(any) {
+			cw.log = append(cw.log, coordinationLogEntry{
+				time:     w.CurrentTime().UTC().Format("1504"),
+				from:     w.Callsign,
+				to:       to,
+				callsign: callsign,
+				message:  msg,
+			})
+		},
+		func(err error) {
+			cw.log = append(cw.log, coordinationLogEntry{from: w.Callsign, to: to, message: err.Error()})
+		})
+}
+
+// DrawCoordinationWindow collects incoming coordination messages and draws
+// the coordination window if it's open. It's called every frame so that
+// messages arriving while the window is closed aren't lost.
+func (w *World) DrawCoordinationWindow(eventStream *EventStream) {
+	if w.coordinationWindow == nil {
+		w.coordinationWindow = &CoordinationWindow{typ: coordinationTypes[0]}
+	}
+	cw := w.coordinationWindow
+
+	if cw.events == nil {
+		cw.events = eventStream.Subscribe()
+	}
+	for _, event := range cw.events.Get() {
+		if event.Type == CoordinationMessageEvent && event.ToController == w.Callsign {
+			cw.log = append(cw.log, coordinationLogEntry{
+				time:     w.CurrentTime().UTC().Format("1504"),
+				from:     event.FromController,
+				to:       event.ToController,
+				callsign: event.Callsign,
+				message:  event.Message,
+			})
+			cw.show = true
+		}
+	}
+
+	if !cw.show {
+		return
+	}
+
+	var controllers []string
+	for _, callsign := range SortedMapKeys(w.Controllers) {
+		if ctrl := w.Controllers[callsign]; ctrl.IsHuman && callsign != w.Callsign {
+			controllers = append(controllers, callsign)
+		}
+	}
+	if !slices.Contains(controllers, cw.to) {
+		cw.to = ""
+	}
+
+	imgui.BeginV("Coordination", &cw.show, imgui.WindowFlagsAlwaysAutoResize)
+
+	if len(controllers) == 0 {
+		imgui.Text("No other controllers are signed in")
+	}
+	if imgui.BeginComboV("To", cw.to, 0) {
+		for _, callsign := range controllers {
+			if imgui.SelectableV(callsign, callsign == cw.to, 0, imgui.Vec2{}) {
+				cw.to = callsign
+			}
+		}
+		imgui.EndCombo()
+	}
+	if imgui.BeginComboV("Type", cw.typ, 0) {
+		for _, typ := range coordinationTypes {
+			if imgui.SelectableV(typ, typ == cw.typ, 0, imgui.Vec2{}) {
+				cw.typ = typ
+			}
+		}
+		imgui.EndCombo()
+	}
+	imgui.InputTextV("Aircraft", &cw.callsign, imgui.InputTextFlagsCharsUppercase, nil)
+	imgui.InputText("Message", &cw.text)
+
+	callsign := strings.TrimSpace(cw.callsign)
+	if callsign != "" {
+		if ac := w.GetAircraft(callsign, true /*abbreviated*/); ac != nil {
+			callsign = ac.Callsign
+		}
+	}
+	msg := formatCoordinationMessage(cw.typ, callsign, cw.text)
+
+	disable := cw.to == "" || (callsign == "" && strings.TrimSpace(cw.text) == "")
+	uiStartDisable(disable)
+	if imgui.Button("Send") {
+		cw.send(w, cw.to, callsign, msg)
+		cw.callsign, cw.text = "", ""
+	}
+	uiEndDisable(disable)
+
+	if len(cw.log) > 0 {
+		imgui.Separator()
+		start := max(0, len(cw.log)-15)
+		for i := start; i < len(cw.log); i++ {
+			entry := &cw.log[i]
+			incoming := entry.from != w.Callsign
+			line := entry.time + " " + Select(incoming, entry.from+": ", "-> "+entry.to+": ") + entry.message
+			if incoming {
+				imgui.PushStyleColor(imgui.StyleColorText, imgui.Vec4{0.3, 0.8, 0.9, 1})
+			}
+			imgui.Text(line)
+			if incoming {
+				imgui.PopStyleColor()
+			}
+
+			if incoming && !entry.replied && coordinationNeedsReply(entry.message) {
+				imgui.SameLine()
+				imgui.PushID(line)
+				if imgui.Button("Approve") {
+					cw.send(w, entry.from, entry.callsign, formatCoordinationMessage("APPROVED", "", entry.message))
+					entry.replied = true
+				}
+				imgui.SameLine()
+				if imgui.Button("Unable") {
+					cw.send(w, entry.from, entry.callsign, formatCoordinationMessage("UNABLE", "", entry.message))
+					entry.replied = true
+				}
+				imgui.PopID()
+			}
+		}
+	}
+
+	imgui.End()
+}
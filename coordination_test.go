@@ -0,0 +1,31 @@
+// coordination_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+)
+
+func TestCoordinationMessages(t *testing.T) {
+	for _, test := range []struct {
+		typ, callsign, text string
+		expected            string
+		reply               bool
+	}{
+		{"APREQ", "AAL123", "climb to 12,000", "APREQ AAL123 climb to 12,000", true},
+		{"RIDE", "N123AB", "  ", "RIDE N123AB", true},
+		{"SPACING", "", "10 in trail to LGA", "SPACING 10 in trail to LGA", false},
+		{"INFO", "", "going to 31 shortly", "INFO going to 31 shortly", false},
+		{"APPROVED", "", "APREQ AAL123 climb to 12,000", "APPROVED APREQ AAL123 climb to 12,000", false},
+	} {
+		msg := formatCoordinationMessage(test.typ, test.callsign, test.text)
+		if msg != test.expected {
+			t.Errorf("got %q, expected %q", msg, test.expected)
+		}
+		if r := coordinationNeedsReply(msg); r != test.reply {
+			t.Errorf("%q: got needs reply %v, expected %v", msg, r, test.reply)
+		}
+	}
+}
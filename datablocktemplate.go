@@ -0,0 +1,228 @@
+// datablocktemplate.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// STARSDatablockTemplate gives user-specified formats for the three lines
+// of full datablocks that follow the alerts line. Each is a string in which
+// fields given in braces, e.g. "{callsign}", are replaced with the
+// aircraft's values; a width can be given after a colon, e.g.
+// "{scratchpad:3}", in which case the value is padded or truncated to that
+// many characters. Lines that are empty are formatted as usual; the
+// {standard} field gives the usual contents of the line, so that fields
+// can be added to it.
+type STARSDatablockTemplate struct {
+	Enabled bool
+	Lines   [3]string
+}
+
+// datablockTemplateFields lists the fields that may be used in templates
+// along with their descriptions.
+var datablockTemplateFields = [][2]string{
+	{"callsign", "Callsign"},
+	{"altitude", "Altitude in hundreds of feet, or CST if the track is coasting"},
+	{"speed", "Groundspeed in tens of knots"},
+	{"scratchpad", "Primary scratchpad"},
+	{"scratchpad2", "Secondary scratchpad"},
+	{"destination", "Destination airport"},
+	{"exit", "Departure exit fix"},
+	{"type", "Aircraft type"},
+	{"category", "Wake turbulence category"},
+	{"squawk", "Beacon code"},
+	{"temp_altitude", "Temporary altitude in hundreds of feet"},
+	{"requested_altitude", "Requested altitude in hundreds of feet"},
+	{"heading", "Mode S heading"},
+	{"selected_altitude", "Mode S selected altitude in hundreds of feet"},
+	{"ias", "Mode S indicated airspeed"},
+	{"modes", "Mode S heading, selected altitude, and IAS with a leading space, e.g. \" H270 S110 I250\"; empty if not downlinked"},
+	{"standard", "The standard contents of the line"},
+	{"route", "PDR or PAR if the filed route doesn't conform to the preferential route"},
+}
+
+var reDatablockTemplateField = regexp.MustCompile(`\{([a-z_0-9]+)(:([0-9]+))?\}`)
+
+// formatDatablockTemplate returns the given template with its fields
+// replaced with their values. An error is returned if the template uses a
+// field that isn't known; it is left as is in the result.
+func formatDatablockTemplate(tmpl string, fields map[string]string) (string, error) {
+	var err error
+	s := reDatablockTemplateField.ReplaceAllStringFunc(tmpl, func(f string) string {
+		m := reDatablockTemplateField.FindStringSubmatch(f)
+		v, ok := fields[m[1]]
+		if !ok {
+			err = fmt.Errorf("%s: unknown datablock field", m[1])
+			return f
+		}
+		if m[3] != "" {
+			if n, aerr := strconv.Atoi(m[3]); aerr == nil {
+				v = fmt.Sprintf("%-*s", n, v)[:n]
+			}
+		}
+		return v
+	})
+	return s, err
+}
+
+// datablockTemplateFieldValues returns the values of the template fields
+// for the given aircraft.
+func (sp *STARSPane) datablockTemplateFieldValues(ctx *PaneContext, ac *Aircraft, state *STARSAircraftState) map[string]string {
+	fp := ac.FlightPlan
+	if fp == nil {
+		fp = &FlightPlan{}
+	}
+	hundreds := func(alt int) string {
+		if alt == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%03d", (alt+50)/100)
+	}
+
+	alt := hundreds(state.TrackAltitude())
+	if state.Coasting(ctx.world.CurrentTime()) {
+		alt = "CST"
+	}
+	dest := fp.ArrivalAirport
+	if len(dest) == 4 {
+		dest = dest[1:] // drop the leading K
+	}
+	fields := map[string]string{
+		"callsign":           ac.Callsign,
+		"altitude":           alt,
+		"speed":              fmt.Sprintf("%02d", (state.TrackGroundspeed()+5)/10),
+		"scratchpad":         ac.Scratchpad,
+		"scratchpad2":        ac.SecondaryScratchpad,
+		"destination":        dest,
+		"exit":               ac.Exit,
+		"type":               fp.BaseType(),
+		"category":           getCwtCategory(ac),
+		"squawk":             ac.Squawk.String(),
+		"temp_altitude":      hundreds(ac.TempAltitude),
+		"requested_altitude": hundreds(fp.Altitude),
+		"heading":            "",
+		"selected_altitude":  "",
+		"ias":                "",
+		"route":              "",
+		"modes":              formatModeSParameters(state.track.ModeS),
+		"standard":           "",
+	}
+	if pr, ok := ctx.world.RouteConformance(ac); !ok {
+		fields["route"] = pr.Type
+	}
+	if p := state.track.ModeS; p != nil {
+		fields["heading"] = fmt.Sprintf("%03d", p.Heading)
+		fields["selected_altitude"] = hundreds(p.SelectedAltitude)
+		fields["ias"] = fmt.Sprintf("%03d", p.IAS)
+	}
+	return fields
+}
+
+// datablockTemplatePreviewFields are the values used to preview templates
+// in the settings UI.
+var datablockTemplatePreviewFields = map[string]string{
+	"callsign":           "AAL123",
+	"altitude":           "110",
+	"speed":              "25",
+	"scratchpad":         "CAM",
+	"scratchpad2":        "R4",
+	"destination":        "JFK",
+	"exit":               "WAVEY",
+	"type":               "B738",
+	"category":           "D",
+	"squawk":             "4312",
+	"temp_altitude":      "070",
+	"requested_altitude": "350",
+	"heading":            "270",
+	"selected_altitude":  "070",
+	"ias":                "250",
+	"route":              "PDR",
+	"modes":              " H270 S070 I250",
+	"standard":           "",
+}
+
+// datablockTemplatePreviewStandard gives the standard contents of each
+// line for the preview.
+var datablockTemplatePreviewStandard = [3]string{"AAL123", "110 25", "JFK    A070"}
+
+// formatModeSParameters returns the Mode S datablock field for the given
+// downlinked parameters: heading, selected altitude (if there is one), and
+// indicated airspeed, e.g., " H270 S110 I210".
+func formatModeSParameters(p *ModeSParameters) string {
+	if p == nil {
+		return ""
+	}
+	s := fmt.Sprintf(" H%03d", p.Heading)
+	if p.SelectedAltitude != 0 {
+		s += fmt.Sprintf(" S%03d", (p.SelectedAltitude+50)/100)
+	}
+	return s + fmt.Sprintf(" I%03d", p.IAS)
+}
+
+// applyDatablockTemplate formats the given line of the datablock using the
+// template, with the line's current contents as the {standard} field. If
+// the result still starts with them, the line's colors are kept.
+func applyDatablockTemplate(line *STARSDatablockLine, tmpl string, fields map[string]string) {
+	fields["standard"] = line.Text
+	text, _ := formatDatablockTemplate(tmpl, fields)
+	if !strings.HasPrefix(text, line.Text) {
+		line.Colors = nil
+	}
+	line.Text = text
+}
+
+// DrawUI draws the settings UI for editing the template, with a preview of
+// the resulting datablock.
+func (t *STARSDatablockTemplate) DrawUI() {
+	imgui.Checkbox("Use custom full datablock format", &t.Enabled)
+	uiStartDisable(!t.Enabled)
+
+	for i := range t.Lines {
+		imgui.InputText(fmt.Sprintf("Line %d", i+1), &t.Lines[i])
+	}
+	if imgui.Button("Standard") {
+		t.Lines = [3]string{}
+	}
+	imgui.SameLine()
+	if imgui.Button("Mode S") {
+		t.Lines = [3]string{"", "", "{standard}{modes}"}
+	}
+	imgui.SameLine()
+	if imgui.Button("Departure exit") {
+		t.Lines = [3]string{"", "{altitude} {exit:5} {speed}", ""}
+	}
+
+	imgui.Text("Preview (empty lines use the standard format):")
+	for i, line := range t.Lines {
+		if line == "" {
+			line = "{standard}"
+		}
+		fields := DuplicateMap(datablockTemplatePreviewFields)
+		fields["standard"] = datablockTemplatePreviewStandard[i]
+		s, err := formatDatablockTemplate(line, fields)
+		if err != nil {
+			imgui.PushStyleColor(imgui.StyleColorText, imgui.Vec4{1, .5, .5, 1})
+			imgui.Text("  " + err.Error())
+			imgui.PopStyleColor()
+		} else {
+			imgui.Text("  " + s)
+		}
+	}
+
+	if imgui.TreeNode("Fields") {
+		for _, f := range datablockTemplateFields {
+			imgui.Text(fmt.Sprintf("{%s}: %s", f[0], f[1]))
+		}
+		imgui.TreePop()
+	}
+
+	uiEndDisable(!t.Enabled)
+}
@@ -0,0 +1,72 @@
+// datablocktemplate_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+)
+
+func TestFormatDatablockTemplate(t *testing.T) {
+	fields := map[string]string{
+		"callsign":    "AAL123",
+		"altitude":    "110",
+		"speed":       "25",
+		"exit":        "WAVEY",
+		"destination": "",
+	}
+
+	for _, test := range []struct {
+		tmpl, expected string
+		err            bool
+	}{
+		{tmpl: "{callsign}", expected: "AAL123"},
+		{tmpl: "{altitude} {speed}", expected: "110 25"},
+		{tmpl: "{exit:3}/{destination:3}|", expected: "WAV/   |"},
+		{tmpl: "{speed:4}{altitude}", expected: "25  110"},
+		{tmpl: "no fields", expected: "no fields"},
+		{tmpl: "{altitude} {bogus}", expected: "110 {bogus}", err: true},
+	} {
+		s, err := formatDatablockTemplate(test.tmpl, fields)
+		if s != test.expected {
+			t.Errorf("%q: got %q, expected %q", test.tmpl, s, test.expected)
+		}
+		if (err != nil) != test.err {
+			t.Errorf("%q: got error %v", test.tmpl, err)
+		}
+	}
+
+	// Every field should have a preview value.
+	for _, f := range datablockTemplateFields {
+		if _, ok := datablockTemplatePreviewFields[f[0]]; !ok {
+			t.Errorf("%s: no preview value", f[0])
+		}
+	}
+}
+
+func TestApplyDatablockTemplate(t *testing.T) {
+	colors := []STARSDatablockFieldColors{STARSDatablockFieldColors{Start: 0, End: 3}}
+	fields := map[string]string{"exit": "WAVEY", "modes": ""}
+
+	// Adding to the standard line keeps its colors.
+	line := STARSDatablockLine{Text: "JFK    A070", Colors: colors}
+	applyDatablockTemplate(&line, "{standard} {exit}", fields)
+	if line.Text != "JFK    A070 WAVEY" || len(line.Colors) != 1 {
+		t.Errorf("got %+v", line)
+	}
+
+	// An empty field adds nothing.
+	line = STARSDatablockLine{Text: "JFK    A070", Colors: colors}
+	applyDatablockTemplate(&line, "{standard}{modes}", fields)
+	if line.Text != "JFK    A070" || len(line.Colors) != 1 {
+		t.Errorf("got %+v", line)
+	}
+
+	// Replacing it drops them.
+	line = STARSDatablockLine{Text: "JFK    A070", Colors: colors}
+	applyDatablockTemplate(&line, "{exit}", fields)
+	if line.Text != "WAVEY" || line.Colors != nil {
+		t.Errorf("got %+v", line)
+	}
+}
@@ -0,0 +1,240 @@
+// datalink.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// DatalinkUplink is a clearance sent to an aircraft via CPDLC. Rather than
+// being read back over the radio, the pilot acknowledges it after a delay
+// and then the commands are executed just as if they had been issued by
+// voice.
+type DatalinkUplink struct {
+	ControllerToken string
+	Callsign        string
+	Commands        string
+	AckTime         time.Time // sim time
+}
+
+// DatalinkEquipped indicates whether the aircraft can receive datalink
+// clearances; we assume that all jets (and only jets) are equipped.
+func (ac *Aircraft) DatalinkEquipped() bool {
+	perf := ac.AircraftPerformance()
+	return ac.FlightPlan != nil && perf.Engine.AircraftType == "J" && !perf.Helicopter
+}
+
+// UplinkClearance queues the given commands to be sent to the aircraft via
+// datalink. The commands aren't validated until the pilot acknowledges
+// them; any errors are reported back as an UNABLE response.
+func (s *Sim) UplinkClearance(token, callsign, commands string) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	if strings.TrimSpace(commands) == "" {
+		return ErrInvalidCommandSyntax
+	}
+
+	return s.dispatchCommand(token, callsign,
+		func(ctrl *Controller, ac *Aircraft) error {
+			if ac.ControllingController != ctrl.Callsign {
+				return ErrOtherControllerHasTrack
+			}
+			if !ac.DatalinkEquipped() {
+				return ErrNotDatalinkEquipped
+			}
+			return nil
+		},
+		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			s.datalinkUplinks = append(s.datalinkUplinks, DatalinkUplink{
+				ControllerToken: token,
+				Callsign:        callsign,
+				Commands:        commands,
+				AckTime:         s.SimTime.Add(time.Duration(15+rand.Intn(30)) * time.Second),
+			})
+			return nil
+		})
+}
+
+// processDatalinkUplinks runs the commands for the uplinked clearances that
+// pilots are ready to acknowledge and posts their responses. s.mu must be
+// held.
+func (s *Sim) processDatalinkUplinks() {
+	var due []DatalinkUplink
+	s.datalinkUplinks = slices.DeleteFunc(s.datalinkUplinks, func(ul DatalinkUplink) bool {
+		if s.SimTime.After(ul.AckTime) {
+			due = append(due, ul)
+			return true
+		}
+		return false
+	})
+
+	if s.datalinkExecuting == nil {
+		s.datalinkExecuting = make(map[string]bool)
+	}
+
+	for _, ul := range due {
+		sc, ok := s.controllers[ul.ControllerToken]
+		if !ok {
+			// The controller has signed off.
+			continue
+		}
+		if _, ok := s.World.Aircraft[ul.Callsign]; !ok {
+			// The aircraft has landed or left the airspace.
+			continue
+		}
+
+		s.datalinkExecuting[ul.Callsign] = true
+		result := s.runAircraftCommands(ul.ControllerToken, ul.Callsign, ul.Commands)
+		delete(s.datalinkExecuting, ul.Callsign)
+
+		msg := "WILCO " + ul.Commands
+		if result.ErrorMessage != "" {
+			msg = "UNABLE " + result.RemainingInput
+		}
+		s.eventStream.Post(Event{
+			Type:         DatalinkMessageEvent,
+			Callsign:     ul.Callsign,
+			ToController: sc.Callsign,
+			Message:      msg,
+		})
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////
+// DatalinkWindow
+
+// DatalinkWindow is the client-side CPDLC message composer. It builds
+// uplinks from the standard altitude, route, speed, and frequency change
+// message elements and shows the pilots' WILCO/UNABLE downlinks.
+type DatalinkWindow struct {
+	show     bool
+	callsign string
+
+	climb, direct, speed, contact bool
+	altitude, kts                 int32
+	fix                           string
+
+	log    []string
+	events *EventsSubscription
+}
+
+func (w *World) ToggleShowDatalinkWindow() {
+	if w.datalinkWindow == nil {
+		w.datalinkWindow = &DatalinkWindow{altitude: 100, kts: 250}
+	}
+	w.datalinkWindow.show = !w.datalinkWindow.show
+}
+
+// commands returns the aircraft commands for the currently-selected
+// message elements.
+func (dw *DatalinkWindow) commands() []string {
+	var cmds []string
+	if dw.climb {
+		cmds = append(cmds, fmt.Sprintf("C%d", dw.altitude))
+	}
+	if dw.direct && dw.fix != "" {
+		cmds = append(cmds, "D"+strings.ToUpper(dw.fix))
+	}
+	if dw.speed {
+		cmds = append(cmds, fmt.Sprintf("S%d", dw.kts))
+	}
+	if dw.contact {
+		cmds = append(cmds, "FC")
+	}
+	return cmds
+}
+
+// DrawDatalinkWindow draws the CPDLC message composer, if it's open.
+func (w *World) DrawDatalinkWindow(eventStream *EventStream) {
+	dw := w.datalinkWindow
+	if dw == nil {
+		return
+	}
+	if !dw.show {
+		if dw.events != nil {
+			dw.events.Unsubscribe()
+			dw.events = nil
+		}
+		return
+	}
+
+	if dw.events == nil {
+		dw.events = eventStream.Subscribe()
+	}
+	for _, event := range dw.events.Get() {
+		if event.Type == DatalinkMessageEvent && event.ToController == w.Callsign {
+			dw.log = append(dw.log, w.CurrentTime().UTC().Format("1504")+" "+event.Callsign+" "+event.Message)
+		}
+	}
+
+	var equipped []string
+	for callsign, ac := range w.Aircraft {
+		if ac.ControllingController == w.Callsign && ac.DatalinkEquipped() {
+			equipped = append(equipped, callsign)
+		}
+	}
+	slices.Sort(equipped)
+	if !slices.Contains(equipped, dw.callsign) {
+		dw.callsign = ""
+	}
+
+	imgui.BeginV("CPDLC", &dw.show, imgui.WindowFlagsAlwaysAutoResize)
+
+	if imgui.BeginComboV("Aircraft", dw.callsign, 0) {
+		for _, callsign := range equipped {
+			if imgui.SelectableV(callsign, callsign == dw.callsign, 0, imgui.Vec2{}) {
+				dw.callsign = callsign
+			}
+		}
+		imgui.EndCombo()
+	}
+	if len(equipped) == 0 {
+		imgui.Text("No datalink-equipped aircraft under our control")
+	}
+
+	imgui.Checkbox("Climb/descend and maintain", &dw.climb)
+	imgui.SameLine()
+	imgui.SliderIntV("##altitude", &dw.altitude, 10, 450, "FL%03d", 0)
+	imgui.Checkbox("Proceed direct", &dw.direct)
+	imgui.SameLine()
+	imgui.InputTextV("##fix", &dw.fix, imgui.InputTextFlagsCharsUppercase, nil)
+	imgui.Checkbox("Maintain speed", &dw.speed)
+	imgui.SameLine()
+	imgui.SliderIntV("##speed", &dw.kts, 150, 350, "%d knots", 0)
+	imgui.Checkbox("Contact next frequency", &dw.contact)
+
+	cmds := dw.commands()
+	imgui.Separator()
+	imgui.Text("Uplink: " + strings.Join(cmds, " "))
+
+	uiStartDisable(dw.callsign == "" || len(cmds) == 0)
+	if imgui.Button("Send") {
+		callsign, msg := dw.callsign, strings.Join(cmds, " ")
+		w.UplinkClearance(callsign, msg,
+			func(any) {
+				dw.log = append(dw.log, w.CurrentTime().UTC().Format("1504")+" "+callsign+" SENT "+msg)
+			},
+			func(err error) {
+				dw.log = append(dw.log, callsign+": "+err.Error())
+			})
+		dw.climb, dw.direct, dw.speed, dw.contact = false, false, false, false
+	}
+	uiEndDisable(dw.callsign == "" || len(cmds) == 0)
+
+	if len(dw.log) > 0 {
+		imgui.Separator()
+		for _, l := range dw.log[max(0, len(dw.log)-10):] {
+			imgui.Text(l)
+		}
+	}
+
+	imgui.End()
+}
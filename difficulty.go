@@ -0,0 +1,222 @@
+// difficulty.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// Difficulty presets bundle the launch configuration's realism settings
+// so that they can be set with a single selection when a sim is created;
+// the individual settings can still be changed afterward.
+type Difficulty int
+
+const (
+	DifficultyBasic Difficulty = iota
+	DifficultyRealistic
+	DifficultyExpert
+	NumDifficulties
+)
+
+func (d Difficulty) String() string {
+	return []string{"Basic", "Realistic", "Expert"}[d]
+}
+
+func ParseDifficulty(s string) (Difficulty, bool) {
+	for d := range NumDifficulties {
+		if strings.EqualFold(s, d.String()) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// DifficultySettings are the LaunchConfig settings that are set by a
+// difficulty preset.
+type DifficultySettings struct {
+	PilotRealism        PilotRealism
+	ReadbackErrorRate   float32
+	PilotErrorRate      float32
+	ResponseDelay       float32
+	FrequencyCongestion float32
+	GoAroundRate        float32
+	EmergencyRate       float32
+	FailureRate         float32
+}
+
+var difficultyPresets = [NumDifficulties]DifficultySettings{
+	// The defaults from MakeLaunchConfig.
+	DifficultyBasic: {
+		PilotRealism: PilotRealismStandard,
+		GoAroundRate: 0.05,
+	},
+	DifficultyRealistic: {
+		PilotRealism:        PilotRealismTypical,
+		ReadbackErrorRate:   0.03,
+		PilotErrorRate:      0.01,
+		ResponseDelay:       4,
+		FrequencyCongestion: 0.03,
+		GoAroundRate:        0.05,
+		EmergencyRate:       0.5,
+		FailureRate:         0.25,
+	},
+	DifficultyExpert: {
+		PilotRealism:        PilotRealismChallenging,
+		ReadbackErrorRate:   0.08,
+		PilotErrorRate:      0.03,
+		ResponseDelay:       8,
+		FrequencyCongestion: 0.08,
+		GoAroundRate:        0.1,
+		EmergencyRate:       1.5,
+		FailureRate:         1,
+	},
+}
+
+func (lc *LaunchConfig) difficultySettings() DifficultySettings {
+	return DifficultySettings{
+		PilotRealism:        lc.PilotRealism,
+		ReadbackErrorRate:   lc.ReadbackErrorRate,
+		PilotErrorRate:      lc.PilotErrorRate,
+		ResponseDelay:       lc.ResponseDelay,
+		FrequencyCongestion: lc.FrequencyCongestion,
+		GoAroundRate:        lc.GoAroundRate,
+		EmergencyRate:       lc.EmergencyRate,
+		FailureRate:         lc.FailureRate,
+	}
+}
+
+func (lc *LaunchConfig) ApplyDifficulty(d Difficulty) {
+	p := difficultyPresets[d]
+	lc.PilotRealism = p.PilotRealism
+	lc.ReadbackErrorRate = p.ReadbackErrorRate
+	lc.PilotErrorRate = p.PilotErrorRate
+	lc.ResponseDelay = p.ResponseDelay
+	lc.FrequencyCongestion = p.FrequencyCongestion
+	lc.GoAroundRate = p.GoAroundRate
+	lc.EmergencyRate = p.EmergencyRate
+	lc.FailureRate = p.FailureRate
+}
+
+// Difficulty returns the preset that matches the launch configuration's
+// current settings; false is returned if they have been customized.
+func (lc *LaunchConfig) Difficulty() (Difficulty, bool) {
+	s := lc.difficultySettings()
+	for d, p := range difficultyPresets {
+		if s == p {
+			return Difficulty(d), true
+		}
+	}
+	return 0, false
+}
+
+func (lc *LaunchConfig) DrawDifficultyUI() (changed bool) {
+	current := "Custom"
+	if d, ok := lc.Difficulty(); ok {
+		current = d.String()
+	}
+	if imgui.BeginComboV("Difficulty", current, 0) {
+		for d := range NumDifficulties {
+			if imgui.SelectableV(d.String(), d.String() == current, 0, imgui.Vec2{}) {
+				lc.ApplyDifficulty(d)
+				changed = true
+			}
+		}
+		imgui.EndCombo()
+	}
+	return
+}
+
+// valueInstruction is a controller instruction with a single numeric
+// value that a pilot may mishear or misfly: an altitude ('C' or 'D', in
+// hundreds of feet), heading ('H', 'L', or 'R'), or speed ('S').
+type valueInstruction struct {
+	Command byte
+	Value   int
+}
+
+func parseValueInstruction(command string) (valueInstruction, bool) {
+	if len(command) < 2 || !isAllNumbers(command[1:]) || !strings.ContainsRune("CDHLRS", rune(command[0])) {
+		return valueInstruction{}, false
+	}
+	v, err := strconv.Atoi(command[1:])
+	if err != nil {
+		return valueInstruction{}, false
+	}
+	return valueInstruction{Command: command[0], Value: v}, true
+}
+
+func (vi valueInstruction) IsHeading() bool {
+	return vi.Command == 'H' || vi.Command == 'L' || vi.Command == 'R'
+}
+
+func (vi valueInstruction) String() string {
+	if vi.IsHeading() {
+		return fmt.Sprintf("%c%03d", vi.Command, vi.Value)
+	}
+	return fmt.Sprintf("%c%d", vi.Command, vi.Value)
+}
+
+// misheard returns the instruction with a value that a pilot might
+// plausibly hear instead: an altitude 1,000' off or a heading or speed 20
+// off.
+func (vi valueInstruction) misheard() valueInstruction {
+	switch vi.Command {
+	case 'C', 'D':
+		if vi.Value <= 10 {
+			vi.Value += 10
+		} else {
+			vi.Value += Sample(-10, 10)
+		}
+	case 'S':
+		if vi.Value <= 20 {
+			vi.Value += 20
+		} else {
+			vi.Value += Sample(-20, 20)
+		}
+	default:
+		vi.Value = (vi.Value+Sample(-20, 20)+359)%360 + 1
+	}
+	return vi
+}
+
+// pilotCompliance models pilots flying something other than what they
+// read back and taking a while to start turning after being given a
+// heading. s.mu must be held.
+func (s *Sim) pilotCompliance(callsign string, vi valueInstruction, lc *LaunchConfig) {
+	ac, ok := s.World.Aircraft[callsign]
+	if !ok {
+		return
+	}
+
+	if rand.Float32() < lc.PilotErrorRate {
+		wrong := vi.misheard()
+		s.lg.Info("pilot error", slog.String("callsign", callsign), slog.String("instruction", vi.String()),
+			slog.String("flying", wrong.String()))
+		switch wrong.Command {
+		case 'C', 'D':
+			ac.Nav.AssignAltitude(float32(100*wrong.Value), false)
+		case 'S':
+			ac.Nav.AssignSpeed(float32(wrong.Value), false)
+		case 'H':
+			ac.Nav.AssignHeading(float32(wrong.Value), TurnClosest)
+		case 'L':
+			ac.Nav.AssignHeading(float32(wrong.Value), TurnLeft)
+		case 'R':
+			ac.Nav.AssignHeading(float32(wrong.Value), TurnRight)
+		}
+	}
+
+	if vi.IsHeading() && lc.ResponseDelay > 0 {
+		if dh := ac.Nav.DeferredHeading; dh != nil {
+			dh.Time = dh.Time.Add(time.Duration(rand.Float32() * lc.ResponseDelay * float32(time.Second)))
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// difficulty_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+)
+
+func TestDifficultyPresets(t *testing.T) {
+	lc := MakeLaunchConfig(nil, nil)
+	if d, ok := lc.Difficulty(); !ok || d != DifficultyBasic {
+		t.Errorf("default launch config: got difficulty %s (%v), expected Basic", d, ok)
+	}
+
+	for d := range NumDifficulties {
+		lc.ApplyDifficulty(d)
+		if got, ok := lc.Difficulty(); !ok || got != d {
+			t.Errorf("%s: got difficulty %s (%v) after applying it", d, got, ok)
+		}
+		if pd, ok := ParseDifficulty(d.String()); !ok || pd != d {
+			t.Errorf("%s: parsed as %s (%v)", d, pd, ok)
+		}
+	}
+
+	// Changing one of the settings makes it custom.
+	lc.ReadbackErrorRate += 0.01
+	if d, ok := lc.Difficulty(); ok {
+		t.Errorf("got difficulty %s after customizing the settings", d)
+	}
+}
+
+func TestValueInstructions(t *testing.T) {
+	for _, cmd := range []string{"CAC", "C", "DCAMRN", "L10D", "SMIN", "H", "A50", "ED"} {
+		if vi, ok := parseValueInstruction(cmd); ok {
+			t.Errorf("%s: unexpectedly parsed as %+v", cmd, vi)
+		}
+	}
+
+	for _, test := range []struct {
+		cmd   string
+		delta int
+	}{{"D50", 10}, {"C110", 10}, {"H090", 20}, {"L005", 20}, {"R350", 20}, {"S210", 20}, {"D5", 10}} {
+		vi, ok := parseValueInstruction(test.cmd)
+		if !ok {
+			t.Errorf("%s: didn't parse", test.cmd)
+			continue
+		}
+		if vi.String() != test.cmd && test.cmd != "D5" {
+			t.Errorf("%s: got %s back", test.cmd, vi.String())
+		}
+		for range 20 {
+			m := vi.misheard()
+			d := abs(m.Value - vi.Value)
+			if vi.IsHeading() {
+				d = min(d, 360-d)
+				if m.Value < 1 || m.Value > 360 {
+					t.Errorf("%s: misheard heading %d out of range", test.cmd, m.Value)
+				}
+			}
+			if d != test.delta || m.Value <= 0 || m.Command != vi.Command {
+				t.Errorf("%s: misheard as %s", test.cmd, m.String())
+			}
+		}
+	}
+}
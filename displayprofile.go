@@ -0,0 +1,223 @@
+// displayprofile.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+var ErrDisplayProfileVersion = errors.New("Display profile was saved by a newer version of vice")
+
+// DisplayProfile is a named snapshot of the entire window layout along with
+// the settings of all of its panes: the scopes' ranges, centers, colors,
+// and filters, the flight strip options, and so forth. Different positions
+// (e.g., a departure and an approach position) can then each have their
+// own layout.
+type DisplayProfile struct {
+	Name        string
+	Version     int // CurrentConfigVersion when the profile was saved
+	DisplayRoot json.RawMessage
+}
+
+// MakeDisplayProfile captures the given layout as a profile with the given
+// name.
+func MakeDisplayProfile(name string, root *DisplayNode) (*DisplayProfile, error) {
+	b, err := json.Marshal(root)
+	if err != nil {
+		return nil, err
+	}
+	return &DisplayProfile{Name: name, Version: CurrentConfigVersion, DisplayRoot: b}, nil
+}
+
+// Root returns a new DisplayNode hierarchy for the profile's layout, with
+// its panes upgraded if the profile was saved by an older version of vice.
+func (dp *DisplayProfile) Root() (*DisplayNode, error) {
+	if dp.Version > CurrentConfigVersion {
+		return nil, ErrDisplayProfileVersion
+	}
+
+	var root DisplayNode
+	if err := json.Unmarshal(dp.DisplayRoot, &root); err != nil {
+		return nil, err
+	}
+	if dp.Version < CurrentConfigVersion {
+		root.VisitPanes(func(p Pane) {
+			if up, ok := p.(PaneUpgrader); ok {
+				up.Upgrade(dp.Version, CurrentConfigVersion)
+			}
+		})
+	}
+	return &root, nil
+}
+
+// Export writes the profile to a JSON file in the given directory,
+// returning the file's name.
+func (dp *DisplayProfile) Export(dir string) (string, error) {
+	fn := path.Join(dir, displayProfileFilename(dp.Name))
+
+	b, err := json.MarshalIndent(dp, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return fn, os.WriteFile(fn, b, 0o600)
+}
+
+// displayProfileFilename returns a filename for a profile with the given
+// name, replacing characters that aren't safe in filenames.
+func displayProfileFilename(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name) + ".json"
+}
+
+// ImportDisplayProfile reads a profile that was written by Export.
+func ImportDisplayProfile(filename string) (*DisplayProfile, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var dp DisplayProfile
+	if err := json.Unmarshal(b, &dp); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if dp.Name == "" {
+		dp.Name = strings.TrimSuffix(path.Base(filename), path.Ext(filename))
+	}
+	// Make sure that the layout is usable before accepting it.
+	if _, err := dp.Root(); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return &dp, nil
+}
+
+// SaveDisplayProfile saves the current layout as a profile with the given
+// name, replacing any existing one with that name.
+func (gc *GlobalConfig) SaveDisplayProfile(name string) error {
+	dp, err := MakeDisplayProfile(name, gc.DisplayRoot)
+	if err != nil {
+		return err
+	}
+	if gc.DisplayProfiles == nil {
+		gc.DisplayProfiles = make(map[string]*DisplayProfile)
+	}
+	gc.DisplayProfiles[name] = dp
+	return nil
+}
+
+// LoadDisplayProfile replaces the current layout with the one from the
+// named profile.
+func (gc *GlobalConfig) LoadDisplayProfile(name string, w *World, r Renderer, eventStream *EventStream) error {
+	dp, ok := gc.DisplayProfiles[name]
+	if !ok {
+		return fmt.Errorf("%s: no such display profile", name)
+	}
+	root, err := dp.Root()
+	if err != nil {
+		return err
+	}
+
+	gc.DisplayRoot.VisitPanes(func(p Pane) { p.Deactivate() })
+	gc.DisplayRoot = root
+	// Activate() takes care of adding any panes that are missing from
+	// older profiles.
+	gc.Activate(w, r, eventStream)
+	return nil
+}
+
+// DrawDisplayProfilesUI draws the settings window's controls for saving,
+// loading, and importing and exporting display profiles.
+func (gc *GlobalConfig) DrawDisplayProfilesUI(w *World, r Renderer, eventStream *EventStream) {
+	status := func(msg string) {
+		eventStream.Post(Event{Type: StatusMessageEvent, Message: msg})
+	}
+
+	if len(gc.DisplayProfiles) > 0 {
+		flags := imgui.TableFlagsBordersV | imgui.TableFlagsBordersOuterH | imgui.TableFlagsRowBg | imgui.TableFlagsSizingStretchProp
+		if imgui.BeginTableV("profiles", 2, flags, imgui.Vec2{}, 0) {
+			imgui.TableSetupColumn("Profile")
+			imgui.TableSetupColumn("")
+			imgui.TableHeadersRow()
+
+			for _, name := range SortedMapKeys(gc.DisplayProfiles) {
+				imgui.PushID(name)
+				imgui.TableNextRow()
+				imgui.TableNextColumn()
+				imgui.Text(name)
+
+				imgui.TableNextColumn()
+				if imgui.Button("Load") {
+					if err := gc.LoadDisplayProfile(name, w, r, eventStream); err != nil {
+						ShowErrorDialog("Unable to load display profile: %v", err)
+					} else {
+						status("Loaded display profile " + name)
+					}
+				}
+				imgui.SameLine()
+				if imgui.Button("Update") {
+					if err := gc.SaveDisplayProfile(name); err != nil {
+						ShowErrorDialog("Unable to save display profile: %v", err)
+					} else {
+						status("Saved the current layout to display profile " + name)
+					}
+				}
+				imgui.SameLine()
+				if imgui.Button("Export...") {
+					dp := gc.DisplayProfiles[name]
+					ui.profileDialog = NewDirectorySelectDialogBox("Export Display Profile...", "", func(dir string) {
+						if fn, err := dp.Export(dir); err != nil {
+							ShowErrorDialog("Unable to export display profile: %v", err)
+						} else {
+							status("Exported display profile to " + fn)
+						}
+					})
+					ui.profileDialog.Activate()
+				}
+				imgui.SameLine()
+				if imgui.Button(FontAwesomeIconTrash) {
+					delete(gc.DisplayProfiles, name)
+				}
+				imgui.PopID()
+			}
+			imgui.EndTable()
+		}
+	}
+
+	imgui.InputTextV("##profilename", &ui.profileName, imgui.InputTextFlagsCharsUppercase, nil)
+	imgui.SameLine()
+	if imgui.Button("Save current layout") && ui.profileName != "" {
+		if err := gc.SaveDisplayProfile(ui.profileName); err != nil {
+			ShowErrorDialog("Unable to save display profile: %v", err)
+		} else {
+			status("Saved display profile " + ui.profileName)
+			ui.profileName = ""
+		}
+	}
+
+	if imgui.Button("Import...") {
+		ui.profileDialog = NewFileSelectDialogBox("Import Display Profile...", []string{".json"}, "", func(fn string) {
+			if dp, err := ImportDisplayProfile(fn); err != nil {
+				ShowErrorDialog("Unable to import display profile: %v", err)
+			} else {
+				if gc.DisplayProfiles == nil {
+					gc.DisplayProfiles = make(map[string]*DisplayProfile)
+				}
+				gc.DisplayProfiles[dp.Name] = dp
+				status("Imported display profile " + dp.Name)
+			}
+		})
+		ui.profileDialog.Activate()
+	}
+}
@@ -0,0 +1,49 @@
+// displayprofile_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"path"
+	"testing"
+)
+
+func TestDisplayProfileExportImport(t *testing.T) {
+	root := &DisplayNode{SplitLine: SplitLine{Pos: 0.25, Axis: SplitAxisX},
+		Children: [2]*DisplayNode{{}, {}}}
+	dp, err := MakeDisplayProfile("JFK_APP/2", root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	fn, err := dp.Export(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fn != path.Join(dir, "JFK_APP_2.json") {
+		t.Errorf("exported to %s, expected JFK_APP_2.json", fn)
+	}
+
+	imp, err := ImportDisplayProfile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imp.Name != "JFK_APP/2" || imp.Version != CurrentConfigVersion {
+		t.Errorf("imported %q version %d", imp.Name, imp.Version)
+	}
+	r, err := imp.Root()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.SplitLine != root.SplitLine || r.Children[0] == nil || r.Children[1] == nil {
+		t.Errorf("imported layout %+v doesn't match %+v", r, root)
+	}
+
+	imp.Version = CurrentConfigVersion + 1
+	if _, err := imp.Root(); !errors.Is(err, ErrDisplayProfileVersion) {
+		t.Errorf("expected version error for a profile from a newer version, got %v", err)
+	}
+}
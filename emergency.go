@@ -0,0 +1,222 @@
+// emergency.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+type EmergencyType int
+
+const (
+	EmergencyNone EmergencyType = iota
+	EmergencyEngineFailure
+	EmergencyHydraulicFailure
+	EmergencyMedical
+	EmergencyNORDO        // lost communications, still squawking the assigned code
+	EmergencyRadioFailure // lost communications, squawking 7600
+	EmergencyHijack
+	NumEmergencyTypes
+)
+
+func (e EmergencyType) String() string {
+	return []string{"None", "Engine failure", "Hydraulic failure", "Medical", "NORDO",
+		"Radio failure (7600)", "Hijack (7500)"}[e]
+}
+
+// RespondsToCommands indicates whether the pilot will acknowledge and
+// follow ATC instructions given the emergency.
+func (e EmergencyType) RespondsToCommands() bool {
+	return e != EmergencyNORDO && e != EmergencyRadioFailure && e != EmergencyHijack
+}
+
+// Single-engine service ceiling that we assume for all multi-engine
+// aircraft after an engine failure.
+const engineOutCeiling = 16000
+
+// TriggerEmergency has the given aircraft declare the specified emergency
+// (or a random one if EmergencyNone is given). Only the launch controller
+// may trigger emergencies.
+func (s *Sim) TriggerEmergency(token, callsign string, kind EmergencyType) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	if ctrl, ok := s.controllers[token]; !ok {
+		return ErrInvalidControllerToken
+	} else if ctrl.Callsign != s.LaunchConfig.Controller {
+		return ErrNotLaunchController
+	} else if ac, ok := s.World.Aircraft[callsign]; !ok {
+		return ErrNoAircraftForCallsign
+	} else if ac.Emergency != EmergencyNone {
+		return ErrAlreadyDeclaredEmergency
+	} else if !ac.Nav.IsAirborne() {
+		return ErrNotAirborne
+	} else {
+		if kind == EmergencyNone {
+			kind = EmergencyType(1 + rand.Intn(int(NumEmergencyTypes)-1))
+		}
+		s.declareEmergency(ac, kind)
+		return nil
+	}
+}
+
+// triggerRandomEmergency occasionally has an aircraft that a human is
+// controlling declare an emergency, at the rate given in the launch
+// config. s.mu must be held.
+func (s *Sim) triggerRandomEmergency() {
+	if rand.Float32() >= s.LaunchConfig.EmergencyRate/3600 {
+		return
+	}
+
+	callsigns := FilterSlice(SortedMapKeys(s.World.Aircraft), func(callsign string) bool {
+		ac := s.World.Aircraft[callsign]
+		return ac.FlightPlan != nil && ac.Emergency == EmergencyNone && !ac.BackgroundTraffic &&
+			s.controllerIsSignedIn(ac.ControllingController) && ac.Nav.IsAirborne()
+	})
+	if len(callsigns) == 0 {
+		return
+	}
+
+	ac := s.World.Aircraft[callsigns[rand.Intn(len(callsigns))]]
+	s.declareEmergency(ac, EmergencyType(1+rand.Intn(int(NumEmergencyTypes)-1)))
+}
+
+// declareEmergency updates the aircraft's transponder and performance
+// for the emergency and has the pilot make the corresponding request to
+// the controller, if the radio still works. s.mu must be held.
+func (s *Sim) declareEmergency(ac *Aircraft, kind EmergencyType) {
+	ac.Emergency = kind
+	s.lg.Info("emergency", slog.String("callsign", ac.Callsign), slog.String("type", kind.String()))
+
+	// Divert back to the departure airport if we're still close to it.
+	airport := ac.FlightPlan.ArrivalAirport
+	if ap := s.World.GetAirport(ac.FlightPlan.DepartureAirport); ac.IsDeparture() && ap != nil &&
+		nmdistance2ll(ac.Position(), ap.Location) < 50 {
+		airport = ac.FlightPlan.DepartureAirport
+	}
+
+	var msg string
+	switch kind {
+	case EmergencyEngineFailure:
+		ac.Squawk = Squawk(0o7700)
+		ac.Nav.Perf.Ceiling = min(ac.Nav.Perf.Ceiling, engineOutCeiling)
+		ac.Nav.Perf.Rate.Climb /= 2
+
+		alt := min(float32(1000*int(ac.Nav.FlightState.Altitude/1000)), ac.Nav.Perf.Ceiling)
+		if !ac.Nav.Approach.Cleared {
+			ac.Nav.AssignAltitude(alt, false)
+		}
+		msg = fmt.Sprintf("mayday, mayday, mayday, we've lost an engine, we're able to maintain %s, "+
+			"request vectors to %s", FormatAltitude(alt), airport)
+
+	case EmergencyHydraulicFailure:
+		ac.Squawk = Squawk(0o7700)
+		// Without flaps and spoilers, the aircraft lands faster and
+		// can't slow down as quickly.
+		ac.Nav.Perf.Speed.Landing += 20
+		ac.Nav.Perf.Rate.Decelerate /= 2
+		msg = fmt.Sprintf("mayday, mayday, mayday, we've lost hydraulics, requesting a long final at %s "+
+			"and the equipment standing by", airport)
+
+	case EmergencyMedical:
+		msg = fmt.Sprintf("pan-pan, pan-pan, pan-pan, we have a medical emergency on board, "+
+			"request priority handling to %s", airport)
+
+	case EmergencyNORDO:
+		// Nothing changes but the pilot stops responding.
+
+	case EmergencyRadioFailure:
+		ac.Squawk = Squawk(0o7600)
+		// Departures climb to their filed altitude, as they would
+		// after the expected further clearance.
+		if ac.IsDeparture() {
+			if alt := min(float32(ac.FlightPlan.Altitude), ac.Nav.Perf.Ceiling); alt > ac.Nav.FlightState.Altitude {
+				ac.Nav.AssignAltitude(alt, false)
+			}
+		}
+
+	case EmergencyHijack:
+		ac.Squawk = Squawk(0o7500)
+		hdg := float32(10 * (1 + rand.Intn(36)))
+		ac.Nav.AssignHeading(hdg, TurnClosest)
+	}
+
+	if msg != "" {
+		PostRadioEvents(ac.Callsign, []RadioTransmission{RadioTransmission{
+			Controller: ac.ControllingController,
+			Message:    msg,
+			Type:       RadioTransmissionUnexpected,
+		}}, s)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Client side
+
+func (w *World) TriggerEmergency(callsign string, kind EmergencyType, success func(any), err func(error)) {
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.TriggerEmergency(callsign, kind),
+			IssueTime: time.Now(),
+			OnSuccess: success,
+			OnErr:     err,
+		})
+}
+
+func (lc *LaunchConfig) DrawEmergencyUI() (changed bool) {
+	imgui.Text("Emergencies")
+	changed = imgui.SliderFloatV("Random emergencies (per hour)", &lc.EmergencyRate, 0, 10, "%.1f", 0) || changed
+	return
+}
+
+// drawEmergencyTrigger draws the launch control UI for having a
+// particular aircraft declare an emergency.
+func (lc *LaunchControlWindow) drawEmergencyTrigger(eventStream *EventStream) {
+	var callsigns []string
+	for callsign, ac := range lc.w.Aircraft {
+		if ac.FlightPlan != nil && ac.Emergency == EmergencyNone && ac.Nav.IsAirborne() {
+			callsigns = append(callsigns, callsign)
+		}
+	}
+	slices.Sort(callsigns)
+	if !slices.Contains(callsigns, lc.emergencyCallsign) {
+		lc.emergencyCallsign = ""
+	}
+
+	imgui.SetNextItemWidth(150)
+	if imgui.BeginComboV("##emergencyCallsign", lc.emergencyCallsign, 0) {
+		for _, callsign := range callsigns {
+			if imgui.SelectableV(callsign, callsign == lc.emergencyCallsign, 0, imgui.Vec2{}) {
+				lc.emergencyCallsign = callsign
+			}
+		}
+		imgui.EndCombo()
+	}
+	imgui.SameLine()
+	imgui.SetNextItemWidth(200)
+	if imgui.BeginComboV("##emergencyType", Select(lc.emergencyType == EmergencyNone, "Random", lc.emergencyType.String()), 0) {
+		for e := EmergencyNone; e < NumEmergencyTypes; e++ {
+			if imgui.SelectableV(Select(e == EmergencyNone, "Random", e.String()), e == lc.emergencyType, 0, imgui.Vec2{}) {
+				lc.emergencyType = e
+			}
+		}
+		imgui.EndCombo()
+	}
+	imgui.SameLine()
+	uiStartDisable(lc.emergencyCallsign == "")
+	if imgui.Button("Declare emergency") {
+		lc.w.TriggerEmergency(lc.emergencyCallsign, lc.emergencyType, nil,
+			func(err error) {
+				eventStream.Post(Event{Type: StatusMessageEvent, Message: err.Error()})
+			})
+		lc.emergencyCallsign = ""
+	}
+	uiEndDisable(lc.emergencyCallsign == "")
+}
@@ -0,0 +1,96 @@
+// enroutedatablock.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// formatEnRouteDatablocks returns ERAM-style full datablocks for the
+// aircraft, which are used in place of the terminal format when the
+// EnRouteDatablocks preference is set:
+//
+//	AAL123 PO
+//	350+231
+//	452 WAVEY
+//
+// Line 2 is the altitude: the flight plan's assigned altitude followed by
+// "C" if the aircraft is at it or otherwise "+" or "-" if it is climbing
+// or descending to it and then its reported altitude. An interim
+// (temporary) altitude takes the place of the assigned one and is
+// followed by "T" and the reported altitude if the aircraft isn't at it.
+// Line 3 starts with the computed groundspeed in knots and then rotates
+// between the handoff sector, the exit fix of departures, the
+// destination, and the aircraft type. field1 and field8 are line 1's
+// callsign and point out fields from the terminal format.
+func (sp *STARSPane) formatEnRouteDatablocks(ctx *PaneContext, ac *Aircraft, state *STARSAircraftState,
+	baseDB STARSDatablock, field1 string, field8 []string) []STARSDatablock {
+	fp := ac.FlightPlan
+
+	reported := fmt.Sprintf("%03d", (state.TrackAltitude()+50)/100)
+	if state.Coasting(ctx.world.CurrentTime()) {
+		reported = "CST"
+	}
+	alt := enRouteAltitude(fp.Altitude, ac.TempAltitude, state.TrackAltitude(), reported)
+
+	gs := fmt.Sprintf("%03d", state.TrackGroundspeed())
+	if state.Ident() {
+		// Rotation stops while the aircraft is identing.
+		gs += " ID"
+	}
+
+	var rotate []string
+	if ac.HandoffTrackController != "" {
+		if ctrl := ctx.world.GetControllerByCallsign(ac.HandoffTrackController); ctrl != nil {
+			rotate = append(rotate, "H"+ctrl.SectorId)
+		}
+	}
+	if !state.Ident() {
+		if ac.Exit != "" {
+			rotate = append(rotate, ac.Exit)
+		}
+		if fp.ArrivalAirport != "" {
+			rotate = append(rotate, fp.ArrivalAirport)
+		}
+		rotate = append(rotate, fp.BaseType())
+	}
+	if len(rotate) == 0 {
+		rotate = []string{""}
+	}
+
+	var dbs []STARSDatablock
+	n := lcm(len(rotate), len(field8))
+	for i := 0; i < n; i++ {
+		db := baseDB.Duplicate()
+		db.Lines[1].Text = field1 + field8[i%len(field8)]
+		db.Lines[2].Text = alt
+		db.Lines[3].Text = strings.TrimSpace(gs + " " + rotate[i%len(rotate)])
+		dbs = append(dbs, db)
+	}
+	return dbs
+}
+
+// enRouteAltitude returns the ERAM-style altitude field given the
+// assigned and interim altitudes and the track's altitude, all in feet,
+// and the reported altitude as it is to be shown.
+func enRouteAltitude(assigned, interim, track int, reported string) string {
+	if interim != 0 {
+		s := fmt.Sprintf("%03dT", (interim+50)/100)
+		if abs(track-interim) < 200 && reported != "CST" {
+			return s
+		}
+		return s + reported
+	} else if assigned != 0 {
+		s := fmt.Sprintf("%03d", (assigned+50)/100)
+		if abs(track-assigned) < 200 && reported != "CST" {
+			return s + "C"
+		} else if track < assigned {
+			return s + "+" + reported
+		}
+		return s + "-" + reported
+	}
+	return reported
+}
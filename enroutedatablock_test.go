@@ -0,0 +1,28 @@
+// enroutedatablock_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+)
+
+func TestEnRouteAltitude(t *testing.T) {
+	for _, test := range []struct {
+		assigned, interim, track int
+		reported, expected       string
+	}{
+		{assigned: 35000, track: 35050, reported: "351", expected: "350C"},
+		{assigned: 35000, track: 23100, reported: "231", expected: "350+231"},
+		{assigned: 11000, track: 23100, reported: "231", expected: "110-231"},
+		{assigned: 35000, interim: 9000, track: 9000, reported: "090", expected: "090T"},
+		{assigned: 35000, interim: 9000, track: 5200, reported: "052", expected: "090T052"},
+		{assigned: 35000, track: 35000, reported: "CST", expected: "350-CST"},
+		{track: 5200, reported: "052", expected: "052"},
+	} {
+		if s := enRouteAltitude(test.assigned, test.interim, test.track, test.reported); s != test.expected {
+			t.Errorf("%+v: got %q", test, s)
+		}
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 // Aviation-related
 var (
+	ErrAlreadyDeclaredEmergency     = errors.New("Aircraft has already declared an emergency")
 	ErrClearedForUnexpectedApproach = errors.New("Cleared for unexpected approach")
 	ErrFixNotInRoute                = errors.New("Fix not in aircraft's route")
 	ErrInvalidAltitude              = errors.New("Altitude above aircraft's ceiling")
@@ -24,36 +25,52 @@ var (
 	ErrInvalidHeading               = errors.New("Invalid heading")
 	ErrNoAircraftForCallsign        = errors.New("No aircraft exists with specified callsign")
 	ErrNoController                 = errors.New("No controller with that callsign")
+	ErrNoAircraftForRandomEvent     = errors.New("No aircraft available for the event")
 	ErrNotLaunchController          = errors.New("Not signed in as the launch controller")
 	ErrNoFlightPlan                 = errors.New("No flight plan has been filed for aircraft")
+	ErrNoReleaseRequest             = errors.New("No release request for aircraft")
 	ErrNoValidArrivalFound          = errors.New("Unable to find a valid arrival")
 	ErrNoValidDepartureFound        = errors.New("Unable to find a valid departure")
 	ErrNotBeingHandedOffToMe        = errors.New("Aircraft not being handed off to current controller")
 	ErrNotPointedOutToMe            = errors.New("Aircraft not being pointed out to current controller")
 	ErrNotClearedForApproach        = errors.New("Aircraft has not been cleared for an approach")
+	ErrNotAirborne                  = errors.New("Aircraft is not airborne")
+	ErrNotDatalinkEquipped          = errors.New("Aircraft is not datalink equipped")
 	ErrNotFlyingRoute               = errors.New("Aircraft is not currently flying its assigned route")
 	ErrOtherControllerHasTrack      = errors.New("Another controller is already tracking the aircraft")
+	ErrRandomEventNotEnabled        = errors.New("Event is not enabled in the scenario")
 	ErrUnableCommand                = errors.New("Unable")
 	ErrUnknownAircraftType          = errors.New("Unknown aircraft type")
 	ErrUnknownAirport               = errors.New("Unknown airport")
 	ErrUnknownApproach              = errors.New("Unknown approach")
+	ErrUnknownFix                   = errors.New("Unknown fix")
+	ErrUnknownRadarSite             = errors.New("Unknown radar site")
 	ErrUnknownRunway                = errors.New("Unknown runway")
 )
 
 // Sim/server-related
 var (
+	ErrAutomationFailure         = errors.New("Handoff automation is out of service")
 	ErrControllerAlreadySignedIn = errors.New("Controller with that callsign already signed in")
+	ErrCoachHasControl           = errors.New("The coach has taken control of this position")
 	ErrDuplicateSimName          = errors.New("A sim with that name already exists")
+	ErrIncompatibleSessionExport = errors.New("Session was exported by an incompatible version of vice")
 	ErrInvalidControllerToken    = errors.New("Invalid controller token")
 	ErrNoNamedSim                = errors.New("No Sim with that name")
 	ErrNoSimForControllerToken   = errors.New("No Sim running for controller token")
+	ErrNotPrimaryController      = errors.New("Only the primary controller can do that")
 	ErrRPCTimeout                = errors.New("RPC call timed out")
 	ErrRPCVersionMismatch        = errors.New("Client and server RPC versions don't match")
 	ErrRestoringSavedState       = errors.New("Errors during state restoration")
+	ErrUnknownWeatherPreset      = errors.New("Unknown weather preset")
+	ErrUnknownScenario           = errors.New("Scenario is no longer defined in the scenario group")
+	ErrUnknownScenarioGroup      = errors.New("Unable to find the scenario group's file")
 	ErrInvalidPassword           = errors.New("Invalid password")
+	ErrLandlinesFailure          = errors.New("Landlines are out of service")
 )
 
 var errorStringToError = map[string]error{
+	ErrAlreadyDeclaredEmergency.Error():     ErrAlreadyDeclaredEmergency,
 	ErrClearedForUnexpectedApproach.Error(): ErrClearedForUnexpectedApproach,
 	ErrFixNotInRoute.Error():                ErrFixNotInRoute,
 	ErrInvalidAltitude.Error():              ErrInvalidAltitude,
@@ -64,27 +81,42 @@ var errorStringToError = map[string]error{
 	ErrInvalidHeading.Error():               ErrInvalidHeading,
 	ErrNoAircraftForCallsign.Error():        ErrNoAircraftForCallsign,
 	ErrNoController.Error():                 ErrNoController,
+	ErrNoAircraftForRandomEvent.Error():     ErrNoAircraftForRandomEvent,
 	ErrNoFlightPlan.Error():                 ErrNoFlightPlan,
+	ErrNoReleaseRequest.Error():             ErrNoReleaseRequest,
 	ErrNoValidDepartureFound.Error():        ErrNoValidDepartureFound,
 	ErrNotBeingHandedOffToMe.Error():        ErrNotBeingHandedOffToMe,
 	ErrNotPointedOutToMe.Error():            ErrNotPointedOutToMe,
 	ErrNotClearedForApproach.Error():        ErrNotClearedForApproach,
+	ErrNotAirborne.Error():                  ErrNotAirborne,
+	ErrNotDatalinkEquipped.Error():          ErrNotDatalinkEquipped,
 	ErrNotFlyingRoute.Error():               ErrNotFlyingRoute,
 	ErrOtherControllerHasTrack.Error():      ErrOtherControllerHasTrack,
+	ErrRandomEventNotEnabled.Error():        ErrRandomEventNotEnabled,
 	ErrUnableCommand.Error():                ErrUnableCommand,
 	ErrUnknownAircraftType.Error():          ErrUnknownAircraftType,
 	ErrUnknownAirport.Error():               ErrUnknownAirport,
 	ErrUnknownApproach.Error():              ErrUnknownApproach,
+	ErrUnknownFix.Error():                   ErrUnknownFix,
+	ErrUnknownRadarSite.Error():             ErrUnknownRadarSite,
 	ErrUnknownRunway.Error():                ErrUnknownRunway,
+	ErrAutomationFailure.Error():            ErrAutomationFailure,
 	ErrControllerAlreadySignedIn.Error():    ErrControllerAlreadySignedIn,
+	ErrCoachHasControl.Error():              ErrCoachHasControl,
 	ErrDuplicateSimName.Error():             ErrDuplicateSimName,
+	ErrIncompatibleSessionExport.Error():    ErrIncompatibleSessionExport,
 	ErrInvalidControllerToken.Error():       ErrInvalidControllerToken,
 	ErrNoNamedSim.Error():                   ErrNoNamedSim,
 	ErrNoSimForControllerToken.Error():      ErrNoSimForControllerToken,
+	ErrNotPrimaryController.Error():         ErrNotPrimaryController,
 	ErrRPCTimeout.Error():                   ErrRPCTimeout,
 	ErrRPCVersionMismatch.Error():           ErrRPCVersionMismatch,
 	ErrRestoringSavedState.Error():          ErrRestoringSavedState,
+	ErrUnknownWeatherPreset.Error():         ErrUnknownWeatherPreset,
+	ErrUnknownScenario.Error():              ErrUnknownScenario,
+	ErrUnknownScenarioGroup.Error():         ErrUnknownScenarioGroup,
 	ErrInvalidPassword.Error():              ErrInvalidPassword,
+	ErrLandlinesFailure.Error():             ErrLandlinesFailure,
 }
 
 func TryDecodeError(e error) error {
@@ -126,12 +158,15 @@ var (
 	ErrSTARSIllegalText       = NewSTARSError("ILL TEXT")
 	ErrSTARSIllegalTrack      = NewSTARSError("ILL TRK")
 	ErrSTARSIllegalValue      = NewSTARSError("ILL VALUE")
+	ErrSTARSNoAutomation      = NewSTARSError("NO AUTO")
 	ErrSTARSNoFlight          = NewSTARSError("NO FLIGHT")
 	ErrSTARSRangeLimit        = NewSTARSError("RANGE LIMIT")
 )
 
 var starsErrorRemap = map[error]*STARSError{
+	ErrAutomationFailure:            ErrSTARSNoAutomation,
 	ErrClearedForUnexpectedApproach: ErrSTARSIllegalValue,
+	ErrCoachHasControl:              ErrSTARSIllegalFunction,
 	ErrFixNotInRoute:                ErrSTARSIllegalFix,
 	ErrInvalidAltitude:              ErrSTARSIllegalValue,
 	ErrInvalidApproach:              ErrSTARSIllegalValue,
@@ -151,6 +186,7 @@ var starsErrorRemap = map[error]*STARSError{
 	ErrUnknownAircraftType:          ErrSTARSIllegalParam,
 	ErrUnknownAirport:               ErrSTARSIllegalAirport,
 	ErrUnknownApproach:              ErrSTARSIllegalValue,
+	ErrUnknownFix:                   ErrSTARSIllegalFix,
 	ErrUnknownRunway:                ErrSTARSIllegalValue,
 }
 
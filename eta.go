@@ -0,0 +1,183 @@
+// eta.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// ArrivalETAs holds an arrival's estimated times at its meter fix, the
+// final approach fix, and the runway. They're computed by the sim along
+// the aircraft's route, accounting for its speed and the winds, and
+// refreshed every second so that everything that shows an arrival time
+// uses the same estimate. Times are zero if the aircraft has already
+// passed the point or it isn't known.
+type ArrivalETAs struct {
+	MeterFix     string
+	MeterFixTime time.Time
+	FAF          string
+	FAFTime      time.Time
+	Runway       string // empty if no approach is assigned; RunwayTime is then for the airport
+	RunwayTime   time.Time
+}
+
+// etaPoint is a point along an arrival's route for computing ETAs.
+type etaPoint struct {
+	Waypoint
+	ias float32 // 0 if unchanged from the previous point
+}
+
+// groundspeedAlong returns the groundspeed along the given course for an
+// aircraft with the given true airspeed and wind vector, both in knots.
+func groundspeedAlong(tas float32, wind [2]float32, course [2]float32) float32 {
+	if course[0] == 0 && course[1] == 0 {
+		return tas
+	}
+	u := normalize2f(course)
+	along := wind[0]*u[0] + wind[1]*u[1]
+	cross := wind[0]*u[1] - wind[1]*u[0]
+	if cross*cross >= tas*tas {
+		// The wind is stronger than the aircraft can correct for; this
+		// shouldn't happen in practice.
+		return max(along, 1)
+	}
+	return max(along+sqrt(tas*tas-cross*cross), 1)
+}
+
+// etaRoute returns the points along the aircraft's route to the runway:
+// its remaining waypoints followed by the rest of its assigned approach,
+// if it hasn't joined it yet. The runway threshold is returned
+// separately since it's not always part of the approach's waypoints.
+func (ac *Aircraft) etaRoute(w *World) ([]etaPoint, Point2LL, string) {
+	nav := &ac.Nav
+	var route []etaPoint
+	for _, wp := range nav.Waypoints {
+		route = append(route, etaPoint{Waypoint: wp, ias: float32(wp.Speed)})
+	}
+
+	appr := nav.Approach.Assigned
+	if appr == nil || len(appr.Waypoints) == 0 {
+		if ap := w.GetAirport(ac.FlightPlan.ArrivalAirport); ap != nil {
+			return route, ap.Location, ""
+		}
+		return route, Point2LL{}, ""
+	}
+
+	if !slices.ContainsFunc(route, func(p etaPoint) bool { return p.FAF }) {
+		// Join the approach at the last route fix that's on it, if there
+		// is one.
+		awps := appr.Waypoints[0]
+		if n := len(route); n > 0 {
+			for _, wps := range appr.Waypoints {
+				if i := slices.IndexFunc(wps, func(wp Waypoint) bool { return wp.Fix == route[n-1].Fix }); i != -1 {
+					awps = wps[i+1:]
+					break
+				}
+			}
+		}
+		for _, wp := range awps {
+			route = append(route, etaPoint{Waypoint: wp, ias: float32(wp.Speed)})
+		}
+	}
+
+	// Slow to the landing speed after the FAF.
+	if i := slices.IndexFunc(route, func(p etaPoint) bool { return p.FAF }); i != -1 && i+1 < len(route) {
+		route[i+1].ias = nav.Perf.Speed.Landing
+	}
+
+	threshold := appr.Line()[1]
+	if rwy, ok := LookupRunway(ac.FlightPlan.ArrivalAirport, appr.Runway); ok {
+		threshold = rwy.Threshold
+	}
+	return route, threshold, appr.Runway
+}
+
+// computeETAs returns the aircraft's ETAs at the points along its route
+// that are used for metering and sequencing.
+func (ac *Aircraft) computeETAs(w *World, meterFixes []string, now time.Time) ArrivalETAs {
+	nav := &ac.Nav
+	route, threshold, runway := ac.etaRoute(w)
+	etas := ArrivalETAs{Runway: runway}
+
+	p, alt, ias := nav.FlightState.Position, nav.FlightState.Altitude, nav.FlightState.IAS
+	if spd := nav.Speed.Assigned; spd != nil {
+		ias = *spd
+	}
+	var elapsed float32 // seconds
+
+	// fly accumulates the time to fly from p to q.
+	fly := func(q Point2LL, nextAlt float32) {
+		course := sub2f(ll2nm(q, nav.FlightState.NmPerLongitude), ll2nm(p, nav.FlightState.NmPerLongitude))
+		if d := length2f(course); d > 0 {
+			legAlt := (alt + nextAlt) / 2
+			tas := min(IASToTAS(ias, legAlt), nav.Perf.Speed.CruiseTAS)
+			wind := w.AverageWindVector(mid2ll(p, q), legAlt)
+			elapsed += 3600 * d / groundspeedAlong(tas, wind, course)
+		}
+		p, alt = q, nextAlt
+	}
+	at := func() time.Time {
+		return now.Add(time.Duration(elapsed * float32(time.Second)))
+	}
+
+	for _, pt := range route {
+		nextAlt := alt
+		if ar := pt.AltitudeRestriction; ar != nil {
+			nextAlt = ar.TargetAltitude(alt)
+		}
+		fly(pt.Location, nextAlt)
+		if pt.ias != 0 {
+			ias = pt.ias
+		}
+
+		if etas.MeterFix == "" && slices.Contains(meterFixes, pt.Fix) {
+			etas.MeterFix, etas.MeterFixTime = pt.Fix, at()
+		}
+		if pt.FAF && etas.FAF == "" {
+			etas.FAF, etas.FAFTime = pt.Fix, at()
+		}
+	}
+	if !threshold.IsZero() {
+		fly(threshold, alt)
+		etas.RunwayTime = at()
+	}
+
+	return etas
+}
+
+// updateETAs refreshes the aircraft's ETAs if it's an airborne arrival.
+func (ac *Aircraft) updateETAs(w *World, now time.Time) {
+	if ac.FlightPlan == nil || ac.IsDeparture() || !ac.Nav.IsAirborne() || ac.Nav.Taxi != nil {
+		ac.ETAs = ArrivalETAs{}
+		return
+	}
+
+	var meterFixes []string
+	if ap := w.GetAirport(ac.FlightPlan.ArrivalAirport); ap != nil {
+		meterFixes = ap.MeterFixes
+	}
+	ac.ETAs = ac.computeETAs(w, meterFixes, now)
+}
+
+// Summary returns the ETAs formatted for the aircraft's information
+// display.
+func (e ArrivalETAs) Summary() string {
+	var s []string
+	if !e.MeterFixTime.IsZero() {
+		s = append(s, e.MeterFix+" "+e.MeterFixTime.UTC().Format("1504:05"))
+	}
+	if !e.FAFTime.IsZero() {
+		s = append(s, e.FAF+" "+e.FAFTime.UTC().Format("1504:05"))
+	}
+	if !e.RunwayTime.IsZero() {
+		s = append(s, Select(e.Runway != "", "runway "+e.Runway, "airport")+" "+e.RunwayTime.UTC().Format("1504:05"))
+	}
+	if len(s) == 0 {
+		return ""
+	}
+	return "ETA " + strings.Join(s, ", ")
+}
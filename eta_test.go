@@ -0,0 +1,75 @@
+// eta_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGroundspeedAlong(t *testing.T) {
+	for _, test := range []struct {
+		wind     [2]float32
+		course   [2]float32
+		expected float32
+	}{
+		{wind: [2]float32{0, 0}, course: [2]float32{1, 0}, expected: 200},
+		{wind: [2]float32{30, 0}, course: [2]float32{1, 0}, expected: 230},   // tailwind
+		{wind: [2]float32{30, 0}, course: [2]float32{-2, 0}, expected: 170},  // headwind
+		{wind: [2]float32{0, 120}, course: [2]float32{1, 0}, expected: 160},  // crosswind: 3-4-5
+		{wind: [2]float32{0, -120}, course: [2]float32{0, 0}, expected: 200}, // no course
+	} {
+		if gs := groundspeedAlong(200, test.wind, test.course); abs(gs-test.expected) > 1e-3 {
+			t.Errorf("wind %v course %v: got %f, expected %f", test.wind, test.course, gs, test.expected)
+		}
+	}
+}
+
+func TestComputeETAs(t *testing.T) {
+	// With 60 nm per degree of longitude, the fixes are 60 nm apart.
+	w := &World{
+		Airports: map[string]*Airport{"KXXX": {Location: Point2LL{2, 0}}},
+		Weather: WeatherModel{
+			WindsAloft: []WindLayer{{Altitude: 1000, Direction: 270, Speed: 40}},
+		},
+	}
+	ac := &Aircraft{
+		FlightPlan: &FlightPlan{ArrivalAirport: "KXXX"},
+		Nav: Nav{
+			FlightState: FlightState{
+				Position:       Point2LL{0, 0},
+				NmPerLongitude: 60,
+				IAS:            200,
+				Altitude:       2000,
+			},
+			Waypoints: []Waypoint{
+				{Fix: "METER", Location: Point2LL{1, 0}},
+			},
+		},
+	}
+	ac.Nav.Perf.Speed.CruiseTAS = 500
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	etas := ac.computeETAs(w, []string{"METER"}, now)
+
+	gs := IASToTAS(200, 2000) + 40 // tailwind
+	expected := now.Add(time.Duration(60 / gs * float32(time.Hour)))
+	if etas.MeterFix != "METER" || etas.MeterFixTime.Sub(expected).Abs() > time.Second {
+		t.Errorf("meter fix: got %s at %s, expected METER at %s", etas.MeterFix, etas.MeterFixTime, expected)
+	}
+	expected = now.Add(time.Duration(120 / gs * float32(time.Hour)))
+	if etas.Runway != "" || etas.RunwayTime.Sub(expected).Abs() > time.Second {
+		t.Errorf("airport: got %s, expected %s", etas.RunwayTime, expected)
+	}
+	if !etas.FAFTime.IsZero() {
+		t.Errorf("got FAF ETA %s without an approach", etas.FAFTime)
+	}
+
+	// Once it's passed, the meter fix doesn't have an ETA.
+	ac.Nav.Waypoints = nil
+	if etas := ac.computeETAs(w, []string{"METER"}, now); etas.MeterFix != "" || etas.RunwayTime.IsZero() {
+		t.Errorf("after passing the meter fix: got %+v", etas)
+	}
+}
@@ -199,6 +199,12 @@ const (
 	HandoffControllEvent
 	SetGlobalLeaderLineEvent
 	TrackClickedEvent
+	DatalinkMessageEvent
+	OperationalErrorEvent
+	SelectedAircraftEvent    // local: keeps multiple scopes' selections in sync
+	HighlightedAircraftEvent // local: coach highlights
+	CoordinationMessageEvent
+	DepartureRollEvent // Message is the airport and runway, e.g. "KJFK 31L"
 	NumEventTypes
 )
 
@@ -207,7 +213,8 @@ func (t EventType) String() string {
 		"OfferedHandoff", "AcceptedHandoff", "AcceptedRedirectedHandoffEvent", "CanceledHandoff", "RejectedHandoff",
 		"RadioTransmission", "StatusMessage", "ServerBroadcastMessage", "GlobalMessage",
 		"AcknowledgedPointOut", "RejectedPointOut", "Ident", "HandoffControll",
-		"SetGlobalLeaderLine", "TrackClicked"}[t]
+		"SetGlobalLeaderLine", "TrackClicked", "DatalinkMessage",
+		"OperationalError", "SelectedAircraft", "HighlightedAircraft", "CoordinationMessage", "DepartureRoll"}[t]
 }
 
 type Event struct {
@@ -218,6 +225,7 @@ type Event struct {
 	Message               string
 	RadioTransmissionType RadioTransmissionType     // For radio transmissions only
 	LeaderLineDirection   *CardinalOrdinalDirection // SetGlobalLeaderLineEvent
+	Selected              bool                      // SelectedAircraftEvent, HighlightedAircraftEvent
 }
 
 func (e *Event) String() string {
@@ -0,0 +1,199 @@
+// failures.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// SystemFailureType enumerates the ATC equipment failures that may be
+// injected so that controllers can practice falling back to manual
+// procedures.
+type SystemFailureType int
+
+const (
+	// Automated handoffs and point outs to and from the position fail;
+	// tracks must be dropped and initiated after verbal coordination.
+	FailureHandoffAutomation SystemFailureType = iota
+	// The position can't send coordination messages.
+	FailureLandlines
+	// The radar feed to the position's scope stops updating, so all of
+	// its tracks coast.
+	FailureRadarFeed
+	NumSystemFailureTypes
+)
+
+func (f SystemFailureType) String() string {
+	return []string{"Handoff automation", "Landlines", "Radar feed"}[f]
+}
+
+// SSAText returns the indication of the failure that is shown in the
+// STARS SSA.
+func (f SystemFailureType) SSAText() string {
+	return []string{"HO AUTO FAIL", "LL FAIL", "RDR FEED FAIL"}[f]
+}
+
+type SystemFailure struct {
+	Type       SystemFailureType
+	Controller string
+	EndTime    time.Time // sim time
+}
+
+// HasSystemFailure indicates whether the given failure is in effect for
+// any of the given controller positions.
+func HasSystemFailure(failures []SystemFailure, f SystemFailureType, controllers ...string) bool {
+	return slices.ContainsFunc(failures, func(sf SystemFailure) bool {
+		return sf.Type == f && slices.Contains(controllers, sf.Controller)
+	})
+}
+
+// TriggerSystemFailure injects the failure at the given controller
+// position; it lasts for the given number of minutes. Only the launch
+// controller may trigger failures.
+func (s *Sim) TriggerSystemFailure(token string, f SystemFailure, minutes int) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	if ctrl, ok := s.controllers[token]; !ok {
+		return ErrInvalidControllerToken
+	} else if ctrl.Callsign != s.LaunchConfig.Controller {
+		return ErrNotLaunchController
+	} else if !s.controllerIsSignedIn(f.Controller) {
+		return ErrNoController
+	} else {
+		f.EndTime = s.SimTime.Add(time.Duration(minutes) * time.Minute)
+		s.startSystemFailure(f)
+		return nil
+	}
+}
+
+func (s *Sim) startSystemFailure(f SystemFailure) {
+	// Replace any existing failure of the same type at the position.
+	s.SystemFailures = slices.DeleteFunc(s.SystemFailures, func(sf SystemFailure) bool {
+		return sf.Type == f.Type && sf.Controller == f.Controller
+	})
+	s.SystemFailures = append(s.SystemFailures, f)
+
+	s.lg.Info("system failure", slog.String("controller", f.Controller), slog.String("type", f.Type.String()),
+		slog.Time("end", f.EndTime))
+	s.eventStream.Post(Event{
+		Type:    StatusMessageEvent,
+		Message: fmt.Sprintf("%s: %s failure", f.Controller, f.Type),
+	})
+}
+
+// updateSystemFailures ends the failures whose time is up and, at the
+// rate given in the launch config, starts new ones at positions that
+// humans are working. s.mu must be held.
+func (s *Sim) updateSystemFailures() {
+	s.SystemFailures = slices.DeleteFunc(s.SystemFailures, func(f SystemFailure) bool {
+		if s.SimTime.Before(f.EndTime) {
+			return false
+		}
+		s.eventStream.Post(Event{
+			Type:    StatusMessageEvent,
+			Message: fmt.Sprintf("%s: %s restored", f.Controller, f.Type),
+		})
+		return true
+	})
+
+	if rand.Float32() >= s.LaunchConfig.FailureRate/3600 {
+		return
+	}
+
+	var positions []string
+	for _, sc := range s.controllers {
+		if !sc.coach && sc.Callsign != "Observer" && sc.Callsign != "Spectator" && !slices.Contains(positions, sc.Callsign) {
+			positions = append(positions, sc.Callsign)
+		}
+	}
+	if len(positions) == 0 {
+		return
+	}
+	slices.Sort(positions)
+
+	s.startSystemFailure(SystemFailure{
+		Type:       SystemFailureType(rand.Intn(int(NumSystemFailureTypes))),
+		Controller: positions[rand.Intn(len(positions))],
+		EndTime:    s.SimTime.Add(time.Duration(2+rand.Intn(9)) * time.Minute),
+	})
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Client side
+
+func (w *World) TriggerSystemFailure(f SystemFailure, minutes int, success func(any), err func(error)) {
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.TriggerSystemFailure(f, minutes),
+			IssueTime: time.Now(),
+			OnSuccess: success,
+			OnErr:     err,
+		})
+}
+
+// HasSystemFailure indicates whether the given failure is in effect at
+// the user's position.
+func (w *World) HasSystemFailure(f SystemFailureType) bool {
+	return HasSystemFailure(w.SystemFailures, f, w.Callsign)
+}
+
+func (lc *LaunchConfig) DrawFailureUI() (changed bool) {
+	imgui.Text("Equipment failures")
+	changed = imgui.SliderFloatV("Random failures (per hour)", &lc.FailureRate, 0, 6, "%.1f", 0) || changed
+	return
+}
+
+// drawFailureTrigger draws the launch control UI for injecting a
+// failure at a particular position.
+func (lc *LaunchControlWindow) drawFailureTrigger(eventStream *EventStream) {
+	var positions []string
+	for callsign, ctrl := range lc.w.Controllers {
+		if ctrl.IsHuman {
+			positions = append(positions, callsign)
+		}
+	}
+	slices.Sort(positions)
+	if !slices.Contains(positions, lc.failure.Controller) {
+		lc.failure.Controller = ""
+	}
+
+	imgui.SetNextItemWidth(150)
+	if imgui.BeginComboV("##failureController", lc.failure.Controller, 0) {
+		for _, callsign := range positions {
+			if imgui.SelectableV(callsign, callsign == lc.failure.Controller, 0, imgui.Vec2{}) {
+				lc.failure.Controller = callsign
+			}
+		}
+		imgui.EndCombo()
+	}
+	imgui.SameLine()
+	imgui.SetNextItemWidth(200)
+	if imgui.BeginComboV("##failureType", lc.failure.Type.String(), 0) {
+		for f := SystemFailureType(0); f < NumSystemFailureTypes; f++ {
+			if imgui.SelectableV(f.String(), f == lc.failure.Type, 0, imgui.Vec2{}) {
+				lc.failure.Type = f
+			}
+		}
+		imgui.EndCombo()
+	}
+	imgui.SameLine()
+	imgui.SetNextItemWidth(100)
+	imgui.SliderIntV("##failureMinutes", &lc.failureMinutes, 1, 30, "%d min", 0)
+	imgui.SameLine()
+	uiStartDisable(lc.failure.Controller == "")
+	if imgui.Button("Fail") {
+		lc.w.TriggerSystemFailure(lc.failure, int(lc.failureMinutes), nil,
+			func(err error) {
+				eventStream.Post(Event{Type: StatusMessageEvent, Message: err.Error()})
+			})
+	}
+	uiEndDisable(lc.failure.Controller == "")
+}
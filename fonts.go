@@ -34,25 +34,35 @@ var (
 	FontAwesomeIconArrowRight          = faUsedIcons["ArrowRight"]
 	FontAwesomeIconArrowUp             = faUsedIcons["ArrowUp"]
 	FontAwesomeIconBook                = faUsedIcons["Book"]
+	FontAwesomeIconBroadcastTower      = faUsedIcons["BroadcastTower"]
 	FontAwesomeIconBug                 = faUsedIcons["Bug"]
 	FontAwesomeIconCaretDown           = faUsedIcons["CaretDown"]
 	FontAwesomeIconCaretRight          = faUsedIcons["CaretRight"]
 	FontAwesomeIconCheckSquare         = faUsedIcons["CheckSquare"]
+	FontAwesomeIconCloud               = faUsedIcons["Cloud"]
 	FontAwesomeIconCog                 = faUsedIcons["Cog"]
+	FontAwesomeIconComments            = faUsedIcons["Comments"]
 	FontAwesomeIconCompressAlt         = faUsedIcons["CompressAlt"]
 	FontAwesomeIconCopyright           = faUsedIcons["Copyright"]
 	FontAwesomeIconDiscord             = faBrandsUsedIcons["Discord"]
+	FontAwesomeIconDotCircle           = faUsedIcons["DotCircle"]
+	FontAwesomeIconEdit                = faUsedIcons["Edit"]
+	FontAwesomeIconEnvelope            = faUsedIcons["Envelope"]
 	FontAwesomeIconExclamationTriangle = faUsedIcons["ExclamationTriangle"]
 	FontAwesomeIconExpandAlt           = faUsedIcons["ExpandAlt"]
 	FontAwesomeIconFile                = faUsedIcons["File"]
+	FontAwesomeIconFileImport          = faUsedIcons["FileImport"]
+	FontAwesomeIconFilm                = faUsedIcons["Film"]
 	FontAwesomeIconFolder              = faUsedIcons["Folder"]
 	FontAwesomeIconGithub              = faBrandsUsedIcons["Github"]
 	FontAwesomeIconHandPointLeft       = faUsedIcons["HandPointLeft"]
 	FontAwesomeIconHome                = faUsedIcons["Home"]
 	FontAwesomeIconInfoCircle          = faUsedIcons["InfoCircle"]
 	FontAwesomeIconKeyboard            = faUsedIcons["Keyboard"]
+	FontAwesomeIconLayerGroup          = faUsedIcons["LayerGroup"]
 	FontAwesomeIconLevelUpAlt          = faUsedIcons["LevelUpAlt"]
 	FontAwesomeIconLock                = faUsedIcons["Lock"]
+	FontAwesomeIconMicrophone          = faUsedIcons["Microphone"]
 	FontAwesomeIconMouse               = faUsedIcons["Mouse"]
 	FontAwesomeIconPauseCircle         = faUsedIcons["PauseCircle"]
 	FontAwesomeIconPlayCircle          = faUsedIcons["PlayCircle"]
@@ -60,12 +70,18 @@ var (
 	FontAwesomeIconPlaneDeparture      = faUsedIcons["PlaneDeparture"]
 	FontAwesomeIconRedo                = faUsedIcons["Redo"]
 	FontAwesomeIconSquare              = faUsedIcons["Square"]
+	FontAwesomeIconStopCircle          = faUsedIcons["StopCircle"]
+	FontAwesomeIconTrafficLight        = faUsedIcons["TrafficLight"]
+	FontAwesomeIconChartBar            = faUsedIcons["ChartBar"]
 	FontAwesomeIconTrash               = faUsedIcons["Trash"]
 )
 
 var (
 	// All of the available fonts.
 	fonts map[FontIdentifier]*Font
+	// The factor that the imgui fonts were scaled by when they were
+	// rasterized; see fontScale().
+	fontsScale float32
 
 	// This and the following faBrandsUsedIcons map are what drives
 	// determining which icons are copied into regular fonts; see
@@ -76,23 +92,33 @@ var (
 		"ArrowRight":          FontAwesomeString("ArrowRight"),
 		"ArrowUp":             FontAwesomeString("ArrowUp"),
 		"Book":                FontAwesomeString("Book"),
+		"BroadcastTower":      FontAwesomeString("BroadcastTower"),
 		"Bug":                 FontAwesomeString("Bug"),
 		"CaretDown":           FontAwesomeString("CaretDown"),
 		"CaretRight":          FontAwesomeString("CaretRight"),
 		"CheckSquare":         FontAwesomeString("CheckSquare"),
+		"Comments":            FontAwesomeString("Comments"),
 		"CompressAlt":         FontAwesomeString("CompressAlt"),
+		"Cloud":               FontAwesomeString("Cloud"),
 		"Cog":                 FontAwesomeString("Cog"),
 		"Copyright":           FontAwesomeString("Copyright"),
+		"DotCircle":           FontAwesomeString("DotCircle"),
+		"Edit":                FontAwesomeString("Edit"),
+		"Envelope":            FontAwesomeString("Envelope"),
 		"ExclamationTriangle": FontAwesomeString("ExclamationTriangle"),
 		"ExpandAlt":           FontAwesomeString("ExpandAlt"),
 		"File":                FontAwesomeString("File"),
+		"FileImport":          FontAwesomeString("FileImport"),
+		"Film":                FontAwesomeString("Film"),
 		"Folder":              FontAwesomeString("Folder"),
 		"HandPointLeft":       FontAwesomeString("HandPointLeft"),
 		"Home":                FontAwesomeString("Home"),
 		"InfoCircle":          FontAwesomeString("InfoCircle"),
 		"Keyboard":            FontAwesomeString("Keyboard"),
+		"LayerGroup":          FontAwesomeString("LayerGroup"),
 		"LevelUpAlt":          FontAwesomeString("LevelUpAlt"),
 		"Lock":                FontAwesomeString("Lock"),
+		"Microphone":          FontAwesomeString("Microphone"),
 		"Mouse":               FontAwesomeString("Mouse"),
 		"PauseCircle":         FontAwesomeString("PauseCircle"),
 		"PlayCircle":          FontAwesomeString("PlayCircle"),
@@ -100,6 +126,9 @@ var (
 		"PlaneDeparture":      FontAwesomeString("PlaneDeparture"),
 		"Redo":                FontAwesomeString("Redo"),
 		"Square":              FontAwesomeString("Square"),
+		"StopCircle":          FontAwesomeString("StopCircle"),
+		"TrafficLight":        FontAwesomeString("TrafficLight"),
+		"ChartBar":            FontAwesomeString("ChartBar"),
 		"Trash":               FontAwesomeString("Trash"),
 	}
 	faBrandsUsedIcons map[string]string = map[string]string{
@@ -204,9 +233,21 @@ func ptrToUint16Slice(p unsafe.Pointer) []uint16 {
 	return (*[unrealisticLargePointer / 2]uint16)(p)[:]
 }
 
+// fontScale returns the factor by which font sizes should be scaled for a
+// display with the given WindowScale.
+func fontScale(windowScale float32) float32 {
+	if runtime.GOOS == "windows" {
+		// Fix font sizes to account for Windows using 96dpi but everyone
+		// else using 72...
+		return max(windowScale, 96./72.)
+	}
+	return max(windowScale, 1)
+}
+
 func fontsInit(r Renderer, platform Platform) {
 	lg.Info("Starting to initialize fonts")
 	fonts = make(map[FontIdentifier]*Font)
+	fontsScale = fontScale(platform.WindowScale())
 	io := imgui.CurrentIO()
 
 	// Given a map that specifies the icons used in an icon font, returns
@@ -243,17 +284,7 @@ func fontsInit(r Renderer, platform Platform) {
 	add := func(filename string, mono bool, name string) {
 		ttf := LoadResource("fonts/" + filename)
 		for _, size := range []int{6, 7, 8, 9, 10, 11, 12, 13, 14, 16, 18, 20, 22, 24, 28} {
-			sp := float32(size)
-			if runtime.GOOS == "windows" {
-				if dpis := platform.DPIScale(); dpis > 1 {
-					sp *= platform.DPIScale()
-				} else {
-					// Fix font sizes to account for Windows using 96dpi but
-					// everyone else using 72...
-					sp *= 96. / 72.
-				}
-				sp = float32(int(sp + 0.5))
-			}
+			sp := float32(int(float32(size)*fontsScale + 0.5))
 
 			ifont := io.Fonts().AddFontFromMemoryTTFV(ttf, sp, imgui.DefaultFontConfig, imgui.EmptyGlyphRanges)
 
@@ -413,11 +444,11 @@ func initializeSTARSFonts(r Renderer) {
 	// We'll extract the font bitmaps into an atlas image; assume 1k x 1k for starters.
 	res := 1024
 
-	// Windows high DPI displays are different than Macs in that they
-	// expose the actual pixel count.  So we need to scale the font atlas
-	// accordingly. Here we just double up pixels since we want to maintain
-	// the realistic chunkiness of the original fonts.
-	doublePixels := runtime.GOOS == "windows" && platform.DPIScale() > 1.5
+	// Windows and Linux high DPI displays are different than Macs in that
+	// they expose the actual pixel count.  So we need to scale the font
+	// atlas accordingly. Here we just double up pixels since we want to
+	// maintain the realistic chunkiness of the original fonts.
+	doublePixels := platform.WindowScale() > 1.5
 
 	if doublePixels {
 		res *= 2
@@ -0,0 +1,204 @@
+// helicopter.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// HelicopterRoute specifies a route flown by helicopters: they lift off
+// and hover-taxi out of the departure heliport (or airport), fly direct
+// to each of the waypoints, if any, and then slow to a hover and land at
+// the arrival heliport.
+type HelicopterRoute struct {
+	Departure string        `json:"departure"`
+	Arrival   string        `json:"arrival"`
+	Waypoints WaypointArray `json:"waypoints"`
+	Altitude  float32       `json:"altitude"`
+	Rate      int           `json:"rate"` // per hour
+	// Aircraft types to use; if not specified, any of the helicopters in
+	// the performance database may be used.
+	Types []string `json:"types"`
+	// If specified, the helicopters are IFR and tracked by this (virtual)
+	// controller; otherwise they are untracked VFRs.
+	Controller string `json:"controller"`
+}
+
+const (
+	helicopterHoverTaxiSpeed    = 15   // knots
+	helicopterHoverHeight       = 25   // feet AGL
+	helicopterHoverTaxiDistance = 0.1  // nm
+	helicopterLandingDistance   = 1.5  // nm; start of the final descent
+	helicopterDescentGradient   = 400. // feet per nm on final
+)
+
+func (hr *HelicopterRoute) PostDeserialize(sg *ScenarioGroup, s *Scenario, e *ErrorLogger) {
+	if _, ok := database.Airports[hr.Departure]; !ok {
+		e.ErrorString("\"departure\" heliport \"%s\" not found in database", hr.Departure)
+	}
+	if _, ok := database.Airports[hr.Arrival]; !ok {
+		e.ErrorString("\"arrival\" heliport \"%s\" not found in database", hr.Arrival)
+	}
+	if len(hr.Waypoints) > 0 {
+		sg.InitializeWaypointLocations(hr.Waypoints, e)
+		hr.Waypoints.checkBasics(e)
+	}
+
+	if hr.Altitude <= 0 || hr.Altitude > 20000 {
+		e.ErrorString("invalid \"altitude\" %.0f", hr.Altitude)
+	}
+	if hr.Rate < 0 {
+		e.ErrorString("\"rate\" must be positive")
+	}
+	for _, t := range hr.Types {
+		if perf, ok := database.AircraftPerformance[t]; !ok {
+			e.ErrorString("aircraft type \"%s\" not found in performance database", t)
+		} else if !perf.Helicopter {
+			e.ErrorString("aircraft type \"%s\" is not a helicopter", t)
+		}
+	}
+
+	if hr.Controller != "" {
+		if _, ok := sg.ControlPositions[hr.Controller]; !ok {
+			e.ErrorString("controller \"%s\" not defined in the scenario group's \"control_positions\"", hr.Controller)
+		} else if !slices.Contains(s.VirtualControllers, hr.Controller) {
+			s.VirtualControllers = append(s.VirtualControllers, hr.Controller)
+		}
+	}
+}
+
+// helicopterTypes returns the ICAO codes of all of the helicopters in the
+// performance database.
+func helicopterTypes() []string {
+	var types []string
+	for icao, perf := range database.AircraftPerformance {
+		if perf.Helicopter {
+			types = append(types, icao)
+		}
+	}
+	slices.Sort(types)
+	return types
+}
+
+// CreateHelicopter returns a helicopter on the ground at the route's
+// departure heliport, ready to lift off.
+func (w *World) CreateHelicopter(hr *HelicopterRoute) (*Aircraft, error) {
+	ac, acType, err := w.sampleGAAircraft(Select(len(hr.Types) > 0, hr.Types, helicopterTypes()))
+	if err != nil {
+		return nil, err
+	}
+	perf := database.AircraftPerformance[acType]
+
+	rules := FlightRules(VFR)
+	if hr.Controller != "" {
+		rules = IFR
+		ac.AssignedSquawk = Squawk(rand.Intn(0o7000))
+		ac.Squawk = ac.AssignedSquawk
+		ac.TrackingController = hr.Controller
+		ac.ControllingController = hr.Controller
+		ac.WaypointHandoffController = w.PrimaryController
+	}
+	ac.FlightPlan = NewFlightPlan(rules, acType, hr.Departure, hr.Arrival)
+	ac.FlightPlan.Altitude = int(hr.Altitude)
+	ac.FlightPlan.Route = "DCT"
+
+	// The arrival heliport is included explicitly so that makeNav has a
+	// second waypoint to compute the initial heading with even if the
+	// route is direct.
+	dep, arr := database.Airports[hr.Departure], database.Airports[hr.Arrival]
+	wps := []Waypoint{{Fix: hr.Departure, Location: dep.Location}}
+	wps = append(wps, hr.Waypoints...)
+	wps = append(wps, Waypoint{Fix: hr.Arrival, Location: arr.Location})
+	nav := makeNav(w, *ac.FlightPlan, perf, wps)
+	if nav == nil {
+		return nil, fmt.Errorf("error initializing Nav")
+	}
+	// makeNav adds the arrival heliport as well, so drop its copy. Then
+	// the helicopter lands and disappears there.
+	nav.Waypoints = nav.Waypoints[:len(nav.Waypoints)-1]
+	nav.Waypoints[len(nav.Waypoints)-1].Delete = true
+
+	nav.FlightState.Altitude = float32(dep.Elevation)
+	alt := hr.Altitude
+	nav.Altitude.Assigned = &alt
+	ac.Nav = *nav
+
+	return ac, nil
+}
+
+// helicopterHoverTaxiing indicates whether the helicopter is still
+// hover-taxiing out of its departure heliport.
+func (nav *Nav) helicopterHoverTaxiing() bool {
+	fs := &nav.FlightState
+	return nmdistance2ll(fs.Position, fs.DepartureAirportLocation) < helicopterHoverTaxiDistance &&
+		fs.Altitude-fs.DepartureAirportElevation < 100
+}
+
+// helicopterLandingDistance returns the distance to the arrival heliport
+// if the helicopter is flying to it and is close enough that it should
+// be slowing and descending to land.
+func (nav *Nav) helicopterLandingDistance() (float32, bool) {
+	if len(nav.Waypoints) != 1 || nav.Heading.Assigned != nil {
+		return 0, false
+	}
+	d := nmdistance2ll(nav.FlightState.Position, nav.FlightState.ArrivalAirportLocation)
+	return d, d < helicopterLandingDistance
+}
+
+// helicopterTargetSpeed returns the speed for a helicopter that is
+// hover-taxiing or landing; the bool return value is false otherwise.
+func (nav *Nav) helicopterTargetSpeed() (float32, bool) {
+	if nav.helicopterHoverTaxiing() {
+		return helicopterHoverTaxiSpeed, true
+	}
+	if d, ok := nav.helicopterLandingDistance(); ok {
+		// Slow steadily down to a hover over the landing spot.
+		return max(5, min(nav.FlightState.IAS, 60*d)), true
+	}
+	return 0, false
+}
+
+// helicopterTargetAltitude is the counterpart of helicopterTargetSpeed
+// for altitude.
+func (nav *Nav) helicopterTargetAltitude() (float32, bool) {
+	fs := &nav.FlightState
+	if nav.helicopterHoverTaxiing() {
+		return fs.DepartureAirportElevation + helicopterHoverHeight, true
+	}
+	if d, ok := nav.helicopterLandingDistance(); ok {
+		return min(fs.Altitude, fs.ArrivalAirportElevation+helicopterDescentGradient*d), true
+	}
+	return 0, false
+}
+
+// spawnHelicopters launches helicopters along each of the scenario's
+// helicopter routes according to their rates. s.mu must be held.
+func (s *Sim) spawnHelicopters() {
+	now := s.SimTime
+	for i := range s.World.HelicopterRoutes {
+		hr := &s.World.HelicopterRoutes[i]
+		if !now.After(s.NextHelicopterSpawn[i]) {
+			continue
+		}
+		if ac, err := s.World.CreateHelicopter(hr); err != nil {
+			s.lg.Errorf("%s-%s: CreateHelicopter error: %v", hr.Departure, hr.Arrival, err)
+		} else {
+			s.launchAircraftNoLock(*ac)
+		}
+		s.NextHelicopterSpawn[i] = now.Add(randomWait(hr.Rate, false))
+	}
+}
+
+// initialHelicopterSpawnTimes returns the initial spawn times for each of
+// the helicopter routes.
+func initialHelicopterSpawnTimes(routes []HelicopterRoute, randomSpawn func(rate int) time.Time) []time.Time {
+	var t []time.Time
+	for _, hr := range routes {
+		t = append(t, randomSpawn(hr.Rate))
+	}
+	return t
+}
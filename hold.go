@@ -0,0 +1,269 @@
+// hold.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Hold describes a holding pattern at a fix. Published holds are
+// specified in the scenario group's "holds"; controllers may also
+// issue holds at any fix that can be located.
+type Hold struct {
+	Fix           string   `json:"fix"`
+	InboundCourse float32  `json:"inbound_course"`
+	LeftTurns     bool     `json:"left_turns,omitempty"`
+	LegMinutes    float32  `json:"leg_minutes,omitempty"`
+	LegLength     float32  `json:"leg_nm,omitempty"` // if specified, takes precedence over LegMinutes
+	Location      Point2LL `json:"-"`
+}
+
+func (h *Hold) PostDeserialize(sg *ScenarioGroup, e *ErrorLogger) {
+	h.Fix = strings.ToUpper(h.Fix)
+	if h.Fix == "" {
+		e.ErrorString("\"fix\" must be specified for hold")
+		return
+	}
+
+	e.Push("Hold " + h.Fix)
+	defer e.Pop()
+
+	if loc, ok := sg.locate(h.Fix); !ok {
+		e.ErrorString("unknown fix")
+	} else {
+		h.Location = loc
+	}
+	if h.InboundCourse <= 0 || h.InboundCourse > 360 {
+		e.ErrorString("\"inbound_course\" %.0f must be between 1 and 360", h.InboundCourse)
+	}
+	if h.LegMinutes < 0 || h.LegLength < 0 {
+		e.ErrorString("leg length must not be negative")
+	}
+	if h.LegMinutes != 0 && h.LegLength != 0 {
+		e.ErrorString("only one of \"leg_minutes\" and \"leg_nm\" may be specified")
+	}
+}
+
+func (h Hold) TurnMethod() TurnMethod {
+	return TurnMethod(Select(h.LeftTurns, TurnLeft, TurnRight))
+}
+
+func (h Hold) OutboundCourse() float32 {
+	return OppositeHeading(h.InboundCourse)
+}
+
+// Direction returns the direction of the holding pattern from the fix
+// (e.g., "northeast"), as it is given in holding instructions.
+func (h Hold) Direction() string {
+	return strings.ToLower(compass(h.OutboundCourse()))
+}
+
+// SelectEntry returns the entry to the hold for an aircraft that is
+// approaching the fix on the given heading. The entries are the same as
+// for racetrack procedure turns.
+func (h Hold) SelectEntry(aircraftFixHeading float32) RacetrackPTEntry {
+	pt := ProcedureTurn{RightTurns: !h.LeftTurns}
+	return pt.SelectRacetrackEntry(h.InboundCourse, aircraftFixHeading)
+}
+
+// LegDistance returns the length of the hold's legs in nm for an aircraft
+// at the given altitude and groundspeed. Absent a specified length,
+// legs are one minute at or below 14,000' and 1.5 minutes above.
+func (h Hold) LegDistance(altitude, gs float32) float32 {
+	if h.LegLength != 0 {
+		return h.LegLength
+	}
+	minutes := h.LegMinutes
+	if minutes == 0 {
+		minutes = float32(Select(altitude > 14000, 1.5, 1.))
+	}
+	return minutes * gs / 60
+}
+
+// Legs returns a description of the hold's legs for readbacks, or an
+// empty string if they weren't specified.
+func (h Hold) Legs() string {
+	format := func(v float32) string { return strconv.FormatFloat(float64(v), 'f', -1, 32) }
+	if h.LegLength != 0 {
+		return format(h.LegLength) + " mile legs"
+	} else if h.LegMinutes != 0 {
+		return format(h.LegMinutes) + " minute legs"
+	}
+	return ""
+}
+
+// Instructions returns the holding instructions as they would be read
+// back by a pilot.
+func (h Hold) Instructions(published bool) string {
+	s := "hold " + h.Direction() + " of " + FixReadback(h.Fix)
+	if published {
+		return s + " as published"
+	}
+	s += fmt.Sprintf(", %03d inbound, %s turns", int(h.InboundCourse), Select(h.LeftTurns, "left", "right"))
+	if legs := h.Legs(); legs != "" {
+		s += ", " + legs
+	}
+	return s
+}
+
+// maxHoldingSpeed returns the maximum indicated airspeed in the hold at
+// the given altitude.
+func maxHoldingSpeed(altitude float32) float32 {
+	if altitude <= 6000 {
+		return 200
+	} else if altitude <= 14000 {
+		return 230
+	}
+	return 265
+}
+
+// Racetrack returns the outline of the holding pattern in lat-long
+// coordinates for an aircraft flying it at the given altitude and
+// groundspeed with standard rate turns, starting at the fix.
+func (h Hold) Racetrack(altitude, gs, nmPerLongitude, magneticVariation float32) [][2]float32 {
+	// Work in nm coordinates: inbound is the direction of flight on the
+	// inbound leg and side points toward the holding side of it.
+	hdg := radians(h.InboundCourse - magneticVariation)
+	inbound := [2]float32{sin(hdg), cos(hdg)}
+	side := [2]float32{inbound[1], -inbound[0]}
+	if h.LeftTurns {
+		side = scale2f(side, -1)
+	}
+
+	// A standard rate turn takes a minute to turn 180 degrees.
+	r := gs / (60 * math.Pi)
+	fix := ll2nm(h.Location, nmPerLongitude)
+	start := sub2f(fix, scale2f(inbound, h.LegDistance(altitude, gs)))
+
+	var pts [][2]float32
+	add := func(p [2]float32) { pts = append(pts, nm2ll(p, nmPerLongitude)) }
+
+	const nsegs = 16
+	// The turn to the outbound leg at the fix.
+	c := add2f(fix, scale2f(side, r))
+	for i := 0; i <= nsegs; i++ {
+		theta := float32(i) / nsegs * math.Pi
+		add(add2f(c, add2f(scale2f(side, -r*cos(theta)), scale2f(inbound, r*sin(theta)))))
+	}
+	// The turn back to the inbound leg at the end of the outbound leg.
+	c = add2f(start, scale2f(side, r))
+	for i := 0; i <= nsegs; i++ {
+		theta := float32(i) / nsegs * math.Pi
+		add(add2f(c, sub2f(scale2f(side, r*cos(theta)), scale2f(inbound, r*sin(theta)))))
+	}
+
+	return pts
+}
+
+// HoldClearance is a holding instruction issued by a controller. Values
+// that are zero weren't specified; they come from the published hold at
+// the fix if there is one and otherwise take default values.
+type HoldClearance struct {
+	Fix           string
+	InboundCourse float32
+	Turns         TurnMethod
+	LegMinutes    float32
+	LegLength     float32
+	EFC           string // expect further clearance time, HHMM
+}
+
+// parseHoldCommand parses a hold command of the form
+// HOLD/fix[/inbound course][/L or /R][/legs M or NM][/EFChhmm].
+func parseHoldCommand(command string) (HoldClearance, error) {
+	fields := strings.Split(command, "/")
+	if len(fields) < 2 || fields[0] != "HOLD" || fields[1] == "" {
+		return HoldClearance{}, ErrInvalidCommandSyntax
+	}
+
+	hc := HoldClearance{Fix: fields[1]}
+	for _, f := range fields[2:] {
+		switch {
+		case f == "L" || f == "R":
+			hc.Turns = TurnMethod(Select(f == "L", TurnLeft, TurnRight))
+
+		case len(f) == 7 && strings.HasPrefix(f, "EFC"):
+			hh, herr := strconv.Atoi(f[3:5])
+			mm, merr := strconv.Atoi(f[5:])
+			if herr != nil || merr != nil || hh > 23 || mm > 59 {
+				return HoldClearance{}, ErrInvalidCommandSyntax
+			}
+			hc.EFC = f[3:]
+
+		case strings.HasSuffix(f, "NM"):
+			if v, err := strconv.ParseFloat(f[:len(f)-2], 32); err != nil || v <= 0 {
+				return HoldClearance{}, ErrInvalidCommandSyntax
+			} else {
+				hc.LegLength = float32(v)
+			}
+
+		case strings.HasSuffix(f, "M"):
+			if v, err := strconv.ParseFloat(f[:len(f)-1], 32); err != nil || v <= 0 {
+				return HoldClearance{}, ErrInvalidCommandSyntax
+			} else {
+				hc.LegMinutes = float32(v)
+			}
+
+		case len(f) == 3:
+			if crs, err := strconv.Atoi(f); err != nil {
+				return HoldClearance{}, ErrInvalidCommandSyntax
+			} else if crs <= 0 || crs > 360 {
+				return HoldClearance{}, ErrInvalidHeading
+			} else {
+				hc.InboundCourse = float32(crs)
+			}
+
+		default:
+			return HoldClearance{}, ErrInvalidCommandSyntax
+		}
+	}
+	return hc, nil
+}
+
+// Resolve returns the hold that the aircraft at the given position has
+// been cleared to fly. The second return value indicates whether it is
+// the published hold at the fix, unmodified.
+func (hc HoldClearance) Resolve(published []Hold, location, position Point2LL, nmPerLongitude,
+	magneticVariation float32) (Hold, bool) {
+	var h Hold
+	isPublished := false
+	for _, ph := range published {
+		if ph.Fix == hc.Fix {
+			h, isPublished = ph, true
+			break
+		}
+	}
+	if !isPublished {
+		// Absent other instructions, hold on the aircraft's present
+		// course to the fix with standard (right) turns.
+		crs := headingp2ll(position, location, nmPerLongitude, magneticVariation)
+		crs = float32(int(crs + 0.5))
+		h = Hold{
+			Fix:           hc.Fix,
+			InboundCourse: Select(crs == 0, float32(360), crs),
+			Location:      location,
+		}
+	}
+
+	if hc.InboundCourse != 0 {
+		h.InboundCourse = hc.InboundCourse
+		isPublished = false
+	}
+	if hc.Turns != TurnClosest {
+		h.LeftTurns = hc.Turns == TurnLeft
+		isPublished = false
+	}
+	if hc.LegLength != 0 {
+		h.LegLength, h.LegMinutes = hc.LegLength, 0
+		isPublished = false
+	} else if hc.LegMinutes != 0 {
+		h.LegLength, h.LegMinutes = 0, hc.LegMinutes
+		isPublished = false
+	}
+
+	return h, isPublished
+}
@@ -0,0 +1,141 @@
+// hold_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+)
+
+func TestParseHoldCommand(t *testing.T) {
+	hc, err := parseHoldCommand("HOLD/MERIT")
+	if err != nil || hc != (HoldClearance{Fix: "MERIT"}) {
+		t.Errorf("got %+v, %v", hc, err)
+	}
+
+	hc, err = parseHoldCommand("HOLD/MERIT/270/L/1.5M/EFC1530")
+	expected := HoldClearance{Fix: "MERIT", InboundCourse: 270, Turns: TurnLeft, LegMinutes: 1.5, EFC: "1530"}
+	if err != nil || hc != expected {
+		t.Errorf("got %+v, %v; expected %+v", hc, err, expected)
+	}
+
+	hc, err = parseHoldCommand("HOLD/JFK/R/10NM")
+	if err != nil || hc.Turns != TurnRight || hc.LegLength != 10 || hc.LegMinutes != 0 {
+		t.Errorf("got %+v, %v", hc, err)
+	}
+
+	for _, cmd := range []string{"HOLD", "HOLD/", "HOLD/MERIT/X", "HOLD/MERIT/EFC2561", "HOLD/MERIT/0M",
+		"HOLD/MERIT/ABC"} {
+		if _, err := parseHoldCommand(cmd); err != ErrInvalidCommandSyntax {
+			t.Errorf("%s: got error %v, expected invalid syntax", cmd, err)
+		}
+	}
+	if _, err := parseHoldCommand("HOLD/MERIT/400"); err != ErrInvalidHeading {
+		t.Errorf("got error %v for invalid inbound course", err)
+	}
+}
+
+func TestResolveHold(t *testing.T) {
+	// FixReadback looks up navaids in the database.
+	saved := database
+	database = &StaticDatabase{}
+	defer func() { database = saved }()
+
+	published := []Hold{Hold{Fix: "MERIT", InboundCourse: 270, LeftTurns: true, LegLength: 5}}
+
+	// Published, unmodified.
+	h, pub := HoldClearance{Fix: "MERIT"}.Resolve(published, Point2LL{}, Point2LL{1, 0}, 45, 0)
+	if !pub || h != published[0] {
+		t.Errorf("got %+v published %v", h, pub)
+	}
+	if s := h.Instructions(pub); s != "hold east of MERIT as published" {
+		t.Errorf("got instructions %q", s)
+	}
+
+	// Published, with different legs.
+	h, pub = HoldClearance{Fix: "MERIT", LegMinutes: 1}.Resolve(published, Point2LL{}, Point2LL{1, 0}, 45, 0)
+	if pub || h.LegLength != 0 || h.LegMinutes != 1 || !h.LeftTurns {
+		t.Errorf("got %+v published %v", h, pub)
+	}
+	if s := h.Instructions(pub); s != "hold east of MERIT, 270 inbound, left turns, 1 minute legs" {
+		t.Errorf("got instructions %q", s)
+	}
+
+	// Not published: hold on the present course to the fix, right turns.
+	h, pub = HoldClearance{Fix: "DPK"}.Resolve(published, Point2LL{0, 0}, Point2LL{0, -0.5}, 45, 0)
+	if pub || h.InboundCourse != 360 || h.LeftTurns || h.Fix != "DPK" {
+		t.Errorf("got %+v published %v", h, pub)
+	}
+}
+
+func TestHoldEntry(t *testing.T) {
+	right := Hold{InboundCourse: 360}
+	left := Hold{InboundCourse: 360, LeftTurns: true}
+
+	for _, test := range []struct {
+		hold    Hold
+		heading float32 // aircraft heading to the fix
+		entry   RacetrackPTEntry
+	}{
+		{right, 360, DirectEntryShortTurn},
+		{right, 90, DirectEntryShortTurn},
+		{right, 200, ParallelEntry},
+		{right, 150, TeardropEntry},
+		{left, 360, DirectEntryLongTurn},
+		{left, 160, ParallelEntry},
+		{left, 210, TeardropEntry},
+		{left, 270, DirectEntryShortTurn},
+	} {
+		if e := test.hold.SelectEntry(test.heading); e != test.entry {
+			t.Errorf("left %v heading %.0f: got %s entry, expected %s", test.hold.LeftTurns, test.heading, e, test.entry)
+		}
+	}
+}
+
+func TestHoldRacetrack(t *testing.T) {
+	const nmPerLongitude = 45
+	h := Hold{Fix: "FIX", InboundCourse: 360, LegMinutes: 1, Location: Point2LL{-73, 40}}
+
+	// At 120 knots, legs are 2nm and the turn radius is 2/pi nm.
+	pts := h.Racetrack(5000, 120, nmPerLongitude, 0)
+	if nmdistance2ll(pts[0], h.Location) > 0.01 {
+		t.Errorf("racetrack starts at %v, not the fix", pts[0])
+	}
+	fix := ll2nm(h.Location, nmPerLongitude)
+	var east, south float32
+	for _, p := range pts {
+		v := sub2f(ll2nm(p, nmPerLongitude), fix)
+		if v[0] < -0.01 {
+			t.Errorf("right turn hold has a point %v west of the inbound course", v)
+		}
+		east, south = max(east, v[0]), min(south, v[1])
+	}
+	if abs(east-4/3.14159) > 0.01 {
+		t.Errorf("hold width %.3f, expected %.3f", east, 4/3.14159)
+	}
+	// The outbound leg is 2nm and the turn extends past it by the turn
+	// radius.
+	if abs(south+2+2/3.14159) > 0.01 {
+		t.Errorf("hold extends %.3f south of the fix, expected %.3f", -south, 2+2/3.14159)
+	}
+
+	h.LeftTurns = true
+	for _, p := range h.Racetrack(5000, 120, nmPerLongitude, 0) {
+		if v := sub2f(ll2nm(p, nmPerLongitude), fix); v[0] > 0.01 {
+			t.Errorf("left turn hold has a point %v east of the inbound course", v)
+		}
+	}
+
+	if d := h.LegDistance(16000, 240); d != 4 {
+		t.Errorf("got leg length %.1f, expected 4", d)
+	}
+	h.LegMinutes = 0
+	if d := h.LegDistance(16000, 240); d != 6 {
+		t.Errorf("got default leg length %.1f above 14,000, expected 6", d)
+	}
+	h.LegLength = 5
+	if d := h.LegDistance(16000, 240); d != 5 {
+		t.Errorf("got leg length %.1f, expected 5", d)
+	}
+}
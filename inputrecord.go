@@ -0,0 +1,237 @@
+// inputrecord.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// InputRecordingVersion should be incremented whenever the input
+// recording format changes in a way that makes older recordings
+// unreadable.
+const InputRecordingVersion = 1
+
+var ErrInputRecordingVersionMismatch = errors.New("Input recording was made by an incompatible version of vice")
+
+// InputRecording stores the mouse and keyboard input that the Panes
+// received, starting from a snapshot of a local Sim and the window
+// layout, so that UI bugs can be reproduced by replaying the input
+// against the same state. Recordings are stored as zstd-compressed JSON.
+type InputRecording struct {
+	Version     int
+	DisplaySize [2]float32
+	DisplayRoot json.RawMessage
+	Sim         *Sim
+	Frames      []InputFrame
+}
+
+// InputFrame records the input that was delivered to the Panes in a single
+// frame. Panes are identified by their index in the order that
+// VisitPanesWithBounds visits them; the mouse position is in the
+// coordinates of the pane that received it.
+type InputFrame struct {
+	Time      time.Duration // since the start of the recording
+	MousePane int           // -1 if no pane had the mouse
+	Mouse     MouseState
+	FocusPane int // -1 if no pane had the keyboard focus
+	Keyboard  *KeyboardState
+}
+
+// held returns the input that persists from the frame until the next one
+// is recorded: the mouse position, buttons held down, and so forth, but
+// not one-time events like clicks and key presses.
+func (f InputFrame) held() InputFrame {
+	f.Mouse.Clicked = [MouseButtonCount]bool{}
+	f.Mouse.Released = [MouseButtonCount]bool{}
+	f.Mouse.DoubleClicked = [MouseButtonCount]bool{}
+	f.Mouse.Wheel = [2]float32{}
+	f.Keyboard = nil
+	return f
+}
+
+// sameInput returns true if the two frames deliver the same input,
+// ignoring when they happened.
+func (f InputFrame) sameInput(g InputFrame) bool {
+	if f.MousePane != g.MousePane || f.Mouse != g.Mouse || f.FocusPane != g.FocusPane {
+		return false
+	}
+	empty := func(k *KeyboardState) bool { return k == nil || (k.Input == "" && len(k.Pressed) == 0) }
+	return empty(f.Keyboard) && empty(g.Keyboard)
+}
+
+// inputRecordingDirectory returns the directory where input recordings
+// are saved.
+func inputRecordingDirectory() string {
+	dir := path.Join(path.Dir(configFilePath()), "input")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		lg.Errorf("%s: unable to make directory for input recordings: %v", dir, err)
+	}
+	return dir
+}
+
+///////////////////////////////////////////////////////////////////////////
+// InputRecorder
+
+// InputRecorder accumulates the input that the Panes receive; the
+// recording is written out when it is stopped.
+type InputRecorder struct {
+	Filename string
+
+	rec   InputRecording
+	start time.Time
+	last  InputFrame
+}
+
+// NewInputRecorder starts recording input against the current state of
+// the given World, which must be running a local Sim.
+func NewInputRecorder(w *World, displaySize [2]float32) (*InputRecorder, error) {
+	if w == nil || w.simProxy == nil || localServer == nil || w.simProxy.Client != localServer.RPCClient {
+		return nil, errors.New("Input can only be recorded with a local simulation")
+	}
+
+	sim, err := w.GetSerializeSim()
+	if err != nil {
+		return nil, err
+	}
+	sim.PreSave()
+
+	root, err := json.Marshal(globalConfig.DisplayRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	ir := &InputRecorder{
+		Filename: path.Join(inputRecordingDirectory(), "vice-"+time.Now().Format("2006-01-02-150405")+".input"),
+		rec: InputRecording{
+			Version:     InputRecordingVersion,
+			DisplaySize: displaySize,
+			DisplayRoot: root,
+			Sim:         sim,
+		},
+		start: time.Now(),
+		last:  InputFrame{MousePane: -1, FocusPane: -1},
+	}
+
+	lg.Infof("%s: started recording input", ir.Filename)
+	return ir, nil
+}
+
+// Record adds the input for a frame to the recording if it differs from
+// what was held over from the last recorded frame.
+func (ir *InputRecorder) Record(f InputFrame) {
+	if f.sameInput(ir.last.held()) {
+		return
+	}
+	if k := f.Keyboard; k != nil && k.Input == "" && len(k.Pressed) == 0 {
+		f.Keyboard = nil
+	}
+	f.Time = time.Since(ir.start)
+	ir.rec.Frames = append(ir.rec.Frames, f)
+	ir.last = f
+}
+
+// Close writes the recording to disk.
+func (ir *InputRecorder) Close() error {
+	f, err := os.Create(ir.Filename)
+	if err != nil {
+		return err
+	}
+	zw, err := zstd.NewWriter(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = json.NewEncoder(zw).Encode(ir.rec)
+	if zerr := zw.Close(); err == nil {
+		err = zerr
+	}
+	if ferr := f.Close(); err == nil {
+		err = ferr
+	}
+
+	lg.Infof("%s: finished recording input: %d frames over %s", ir.Filename, len(ir.rec.Frames),
+		time.Since(ir.start))
+	return err
+}
+
+///////////////////////////////////////////////////////////////////////////
+// InputPlayer
+
+// InputPlayer feeds the input from a recording to the Panes in place of
+// the user's, one recorded frame at a time once its time has come.
+type InputPlayer struct {
+	Filename string
+	Frames   []InputFrame
+
+	start   time.Time
+	next    int
+	current InputFrame
+}
+
+// LoadInputRecording reads an input recording, returning the Sim and
+// window layout it was made against along with an InputPlayer for its
+// input. The Sim still needs to have PostLoad called on it.
+func LoadInputRecording(filename string) (*Sim, *DisplayNode, *InputPlayer, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer f.Close()
+
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer zr.Close()
+
+	var rec InputRecording
+	if err := json.NewDecoder(zr).Decode(&rec); err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if rec.Version != InputRecordingVersion {
+		return nil, nil, nil, ErrInputRecordingVersionMismatch
+	}
+	if rec.Sim == nil {
+		return nil, nil, nil, fmt.Errorf("%s: no simulation in recording", filename)
+	}
+
+	var root DisplayNode
+	if err := json.Unmarshal(rec.DisplayRoot, &root); err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	lg.Infof("%s: loaded %d frames of input recorded with a %v display", filename, len(rec.Frames), rec.DisplaySize)
+
+	return rec.Sim, &root, &InputPlayer{
+		Filename: filename,
+		Frames:   rec.Frames,
+		current:  InputFrame{MousePane: -1, FocusPane: -1},
+	}, nil
+}
+
+// Next returns the input for the current frame and whether playback has
+// finished. Playback starts the first time it is called.
+func (ip *InputPlayer) Next() (InputFrame, bool) {
+	if ip.start.IsZero() {
+		ip.start = time.Now()
+	}
+	if ip.next == len(ip.Frames) {
+		return InputFrame{}, true
+	}
+	if time.Since(ip.start) >= ip.Frames[ip.next].Time {
+		ip.current = ip.Frames[ip.next]
+		ip.next++
+		return ip.current, false
+	}
+	return ip.current.held(), false
+}
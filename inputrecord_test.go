@@ -0,0 +1,61 @@
+// inputrecord_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+)
+
+func TestInputRecordPlayback(t *testing.T) {
+	ir := &InputRecorder{last: InputFrame{MousePane: -1, FocusPane: -1}}
+
+	hover := InputFrame{MousePane: 1, FocusPane: 0, Mouse: MouseState{Pos: [2]float32{10, 20}}}
+	click := hover
+	click.Mouse.Clicked[MouseButtonPrimary] = true
+	click.Mouse.Down[MouseButtonPrimary] = true
+	key := click.held()
+	key.Keyboard = &KeyboardState{Input: "A", Pressed: map[Key]interface{}{}}
+
+	for _, f := range []InputFrame{
+		hover,
+		hover, // unchanged
+		click,
+		click.held(), // still holding the button down
+		key,
+		{MousePane: 1, FocusPane: 0, Mouse: hover.Mouse, Keyboard: &KeyboardState{Pressed: map[Key]interface{}{}}},
+	} {
+		ir.Record(f)
+	}
+
+	if len(ir.rec.Frames) != 4 {
+		t.Fatalf("expected 4 recorded frames, got %d: %+v", len(ir.rec.Frames), ir.rec.Frames)
+	}
+	if ir.rec.Frames[3].Keyboard != nil {
+		t.Errorf("empty keyboard state wasn't dropped: %+v", ir.rec.Frames[3].Keyboard)
+	}
+
+	// With all of the frames due, each is played back in turn.
+	ip := &InputPlayer{Frames: ir.rec.Frames}
+	for i := range ip.Frames {
+		ip.Frames[i].Time = 0
+	}
+	for i, expected := range []InputFrame{hover, click, key, hover} {
+		f, done := ip.Next()
+		if done || f.Mouse != expected.Mouse || f.MousePane != expected.MousePane || (f.Keyboard == nil) != (expected.Keyboard == nil) {
+			t.Errorf("frame %d: got %+v done %v, expected %+v", i, f, done, expected)
+		}
+	}
+	if _, done := ip.Next(); !done {
+		t.Errorf("expected playback to be done")
+	}
+
+	// Until the next frame is due, the held input from the last one is
+	// repeated without its clicks.
+	ip = &InputPlayer{Frames: []InputFrame{click, {Time: 1 << 60}}}
+	ip.Next()
+	if f, done := ip.Next(); done || f.Mouse.Clicked[MouseButtonPrimary] || !f.Mouse.Down[MouseButtonPrimary] {
+		t.Errorf("got %+v done %v, expected the button to be held down", f, done)
+	}
+}
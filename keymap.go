@@ -0,0 +1,119 @@
+// keymap.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// STARSKeyBinding is the action for a function key in the STARS scope: it
+// may enter a command mode, insert text in the preview area, or both
+// (e.g., multifunc followed by a particular function's letter).
+type STARSKeyBinding struct {
+	Mode CommandMode
+	Text string
+}
+
+// STARSKeymap maps function keys pressed without modifiers to the actions
+// to take in place of the default ones. It's loaded from a JSON file in
+// the user's configuration directory so that the layout of a particular
+// facility's keyboard can be matched, e.g.:
+//
+//	{
+//	  "F2": { "mode": "INITIATE CONTROL" },
+//	  "F12": { "mode": "MULTIFUNC", "text": "D" }
+//	}
+//
+// Keys that aren't in the keymap keep their default bindings.
+type STARSKeymap map[Key]STARSKeyBinding
+
+var starsKeymapModes = map[string]CommandMode{
+	"INITIATE CONTROL":  CommandModeInitiateControl,
+	"TERMINATE CONTROL": CommandModeTerminateControl,
+	"HANDOFF":           CommandModeHandOff,
+	"VFR PLAN":          CommandModeVFRPlan,
+	"MULTIFUNC":         CommandModeMultiFunc,
+	"FLIGHT DATA":       CommandModeFlightData,
+	"CA":                CommandModeCollisionAlert,
+	"MIN":               CommandModeMin,
+}
+
+func starsKeymapPath() string {
+	return path.Join(path.Dir(configFilePath()), "stars-keymap.json")
+}
+
+// LoadSTARSKeymap returns the user's keymap; it is empty if the keymap
+// file doesn't exist.
+func LoadSTARSKeymap() (STARSKeymap, error) {
+	contents, err := os.ReadFile(starsKeymapPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return STARSKeymap{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return parseSTARSKeymap(contents)
+}
+
+func parseSTARSKeymap(contents []byte) (STARSKeymap, error) {
+	var entries map[string]struct {
+		Mode string `json:"mode"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(contents, &entries); err != nil {
+		return nil, err
+	}
+
+	km := make(STARSKeymap)
+	for _, name := range SortedMapKeys(entries) {
+		e := entries[name]
+
+		var n int
+		if _, err := fmt.Sscanf(strings.ToUpper(name), "F%d", &n); err != nil || n < 1 || n > 12 {
+			return nil, fmt.Errorf("%s: only function keys F1-F12 can be bound", name)
+		}
+
+		b := STARSKeyBinding{Text: strings.ToUpper(e.Text)}
+		if e.Mode != "" {
+			var ok bool
+			if b.Mode, ok = starsKeymapModes[strings.ToUpper(e.Mode)]; !ok {
+				return nil, fmt.Errorf("%s: unknown command mode \"%s\"", name, e.Mode)
+			}
+		} else if b.Text == "" {
+			return nil, fmt.Errorf("%s: must specify \"mode\" and/or \"text\"", name)
+		}
+		km[Key(int(KeyF1)+n-1)] = b
+	}
+	return km, nil
+}
+
+// applyKeyBinding performs the action for a key from the user's keymap.
+func (sp *STARSPane) applyKeyBinding(b STARSKeyBinding) {
+	if b.Mode != CommandModeNone {
+		sp.resetInputState()
+		sp.commandMode = b.Mode
+	}
+	if sp.commandMode == CommandModeMultiFunc && sp.multiFuncPrefix == "" && len(b.Text) > 0 {
+		sp.multiFuncPrefix = b.Text[:1]
+		sp.previewAreaInput += b.Text[1:]
+	} else {
+		sp.previewAreaInput += b.Text
+	}
+}
+
+func (sp *STARSPane) loadKeymap() {
+	if km, err := LoadSTARSKeymap(); err != nil {
+		lg.Errorf("%s: %v", starsKeymapPath(), err)
+		ShowErrorDialog("Error loading STARS keymap %s: %v", starsKeymapPath(), err)
+		sp.keymap = STARSKeymap{}
+	} else {
+		sp.keymap = km
+	}
+}
@@ -0,0 +1,45 @@
+// keymap_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+)
+
+func TestParseSTARSKeymap(t *testing.T) {
+	km, err := parseSTARSKeymap([]byte(`{
+  "F2": { "mode": "initiate control" },
+  "f12": { "mode": "MULTIFUNC", "text": "d" },
+  "F10": { "text": "QP J " }
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := STARSKeymap{
+		KeyF2:  {Mode: CommandModeInitiateControl},
+		KeyF12: {Mode: CommandModeMultiFunc, Text: "D"},
+		KeyF10: {Text: "QP J "},
+	}
+	if len(km) != len(expected) {
+		t.Errorf("got %d bindings, expected %d", len(km), len(expected))
+	}
+	for k, b := range expected {
+		if km[k] != b {
+			t.Errorf("key %d: got %+v, expected %+v", k, km[k], b)
+		}
+	}
+
+	for _, bad := range []string{
+		`{ "F13": { "mode": "MIN" } }`,
+		`{ "A": { "mode": "MIN" } }`,
+		`{ "F1": { "mode": "TAKEOFF" } }`,
+		`{ "F1": {} }`,
+		`[ "F1" ]`,
+	} {
+		if _, err := parseSTARSKeymap([]byte(bad)); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
@@ -47,11 +47,12 @@ var (
 	resourcesFS  fs.StatFS
 
 	// client only
-	newWorldChan chan *World
-	localServer  *SimServer
-	remoteServer *SimServer
-	airportWind  map[string]Wind
-	windRequest  map[string]chan getweather.MetarData
+	newWorldChan    chan *World
+	newWorldTabChan chan *World // opened alongside the current world
+	localServer     *SimServer
+	remoteServer    *SimServer
+	airportWind     map[string]Wind
+	windRequest     map[string]chan getweather.MetarData
 
 	//go:embed resources/version.txt
 	buildVersion string
@@ -71,6 +72,7 @@ var (
 	resetSim          = flag.Bool("resetsim", false, "discard the saved simulation and do not try to resume it")
 	showRoutes        = flag.String("routes", "", "display the STARS, SIDs, and approaches known for the given airport")
 	listMaps          = flag.String("listmaps", "", "path to a video map file to list maps of (e.g., resources/videomaps/ZNY-videomaps.gob.zst)")
+	replayInput       = flag.String("replayinput", "", "filename of an input recording to play back")
 )
 
 func init() {
@@ -277,11 +279,36 @@ func main() {
 		fontsInit(renderer, platform)
 
 		newWorldChan = make(chan *World, 2)
+		newWorldTabChan = make(chan *World, 2)
 		var world *World
 
 		localServer = <-localSimServerChan
 
-		if globalConfig.Sim != nil && !*resetSim {
+		var inputPlayer *InputPlayer
+		if *replayInput != "" {
+			// Start from the state the input was recorded against rather
+			// than the saved Sim.
+			sim, root, ip, err := LoadInputRecording(*replayInput)
+			if err == nil {
+				err = sim.PostLoad(mapLibrary)
+			}
+			var result NewSimResult
+			if err == nil {
+				err = localServer.Call("SimManager.Add", sim, &result)
+			}
+			if err != nil {
+				lg.Errorf("%s: unable to play back input: %v", *replayInput, err)
+			} else {
+				world = result.World
+				world.simProxy = &SimProxy{
+					ControllerToken: result.ControllerToken,
+					Client:          localServer.RPCClient,
+				}
+				worldTabs.Add(world)
+				globalConfig.DisplayRoot = root
+				inputPlayer = ip
+			}
+		} else if globalConfig.Sim != nil && !*resetSim {
 			if err := globalConfig.Sim.PostLoad(mapLibrary); err != nil {
 				lg.Errorf("Error in Sim PostLoad: %v", err)
 			} else {
@@ -295,15 +322,18 @@ func main() {
 						Client:          localServer.RPCClient,
 					}
 					world.ToggleShowScenarioInfoWindow()
+					worldTabs.Add(world)
 				}
 			}
 		}
 
 		wmInit()
+		wm.inputPlayer = inputPlayer
 
 		uiInit(renderer, platform, eventStream)
 
-		globalConfig.Activate(world, renderer, eventStream)
+		globalConfig.Activate(world, renderer, worldTabs.Events(eventStream))
+		worldTabs.SetPanes()
 
 		if world == nil {
 			uiShowConnectDialog(false)
@@ -331,21 +361,17 @@ func main() {
 		for {
 			select {
 			case nw := <-newWorldChan:
-				if world != nil {
-					world.Disconnect()
-				}
-				world = nw
+				worldTabs.ReplaceActive(nw)
 				simStartTime = time.Now()
-
-				if world == nil {
-					uiShowConnectDialog(false)
-				} else if world != nil {
-					world.ToggleShowScenarioInfoWindow()
-					globalConfig.DisplayRoot.VisitPanes(func(p Pane) {
-						p.ResetWorld(world)
-					})
+				if nw != nil {
+					nw.ToggleShowScenarioInfoWindow()
 				}
 
+			case nw := <-newWorldTabChan:
+				worldTabs.Add(nw)
+				simStartTime = time.Now()
+				nw.ToggleShowScenarioInfoWindow()
+
 			case remoteServerConn := <-remoteSimServerChan:
 				if err := remoteServerConn.err; err != nil {
 					lg.Warn("Unable to connect to remote server", slog.Any("error", err))
@@ -369,6 +395,18 @@ func main() {
 			default:
 			}
 
+			// Switch the panes over if the active world has changed,
+			// either from a new connection or from the user choosing
+			// another tab.
+			if w := worldTabs.Active(); w != world {
+				world = w
+				if world == nil {
+					uiShowConnectDialog(false)
+				} else {
+					worldTabs.Show(renderer)
+				}
+			}
+
 			if world == nil {
 				platform.SetWindowTitle("vice: [disconnected]")
 				SetDiscordStatus(discordStatus{start: simStartTime})
@@ -403,10 +441,11 @@ func main() {
 			// Let the world update its state based on messages from the
 			// network; a synopsis of changes to aircraft is then passed along
 			// to the window panes.
+			worldEvents := worldTabs.Events(eventStream)
 			if world != nil {
-				world.GetUpdates(eventStream,
+				world.GetUpdates(worldEvents,
 					func(err error) {
-						eventStream.Post(Event{
+						worldEvents.Post(Event{
 							Type:    StatusMessageEvent,
 							Message: "Error getting update from server: " + err.Error(),
 						})
@@ -416,19 +455,22 @@ func main() {
 							}), true)
 
 							remoteServer = nil
+							worldTabs.Drop()
 							world = nil
-
-							uiShowConnectDialog(false)
+							if worldTabs.Active() == nil {
+								uiShowConnectDialog(false)
+							}
 						}
 					})
 			}
+			worldTabs.UpdateBackground(worldEvents, eventStream)
 
 			platform.NewFrame()
 			imgui.NewFrame()
 
 			// Generate and render vice draw lists
 			if world != nil {
-				wmDrawPanes(platform, renderer, world, &stats)
+				wmDrawPanes(platform, renderer, world, worldEvents, &stats)
 			} else {
 				commandBuffer := GetCommandBuffer()
 				commandBuffer.ClearRGB(RGB{})
@@ -439,7 +481,7 @@ func main() {
 			timeMarker(&stats.drawPanes)
 
 			// Draw the user interface
-			drawUI(platform, renderer, world, eventStream, &stats)
+			drawUI(platform, renderer, world, worldEvents, &stats)
 			timeMarker(&stats.drawImgui)
 
 			// Wait for vsync
@@ -453,12 +495,16 @@ func main() {
 
 			if platform.ShouldStop() && len(ui.activeModalDialogs) == 0 {
 				// Do this while we're still running the event loop.
+				if wm.inputRecorder != nil {
+					if err := wm.inputRecorder.Close(); err != nil {
+						lg.Errorf("%s: %v", wm.inputRecorder.Filename, err)
+					}
+				}
+
 				saveSim := world != nil && world.simProxy.Client == localServer.RPCClient
 				globalConfig.SaveIfChanged(renderer, platform, world, saveSim)
 
-				if world != nil {
-					world.Disconnect()
-				}
+				worldTabs.DisconnectAll()
 				break
 			}
 		}
@@ -318,6 +318,17 @@ func RayRayMinimumDistance(p0, d0, p1, d1 [2]float32) float32 {
 	return t
 }
 
+// RayRayClosureRate takes two rays p0+d0*t and p1+d1*t and returns the
+// rate at which the distance between the two points is decreasing at
+// t=0; the returned value is positive if they are converging.
+func RayRayClosureRate(p0, d0, p1, d1 [2]float32) float32 {
+	v := sub2f(p1, p0)
+	if length2f(v) == 0 {
+		return 0
+	}
+	return -dot(sub2f(d1, d0), normalize2f(v))
+}
+
 // SignedPointLineDistance returns the signed distance from the point p to
 // the infinite line defined by (p0, p1) where points to the right of the
 // line have negative distances.
@@ -819,6 +830,26 @@ func normalize2f(a [2]float32) [2]float32 {
 	return scale2f(a, 1/l)
 }
 
+func add3f(a, b [3]float32) [3]float32 {
+	return [3]float32{a[0] + b[0], a[1] + b[1], a[2] + b[2]}
+}
+
+func sub3f(a, b [3]float32) [3]float32 {
+	return [3]float32{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+func scale3f(a [3]float32, s float32) [3]float32 {
+	return [3]float32{s * a[0], s * a[1], s * a[2]}
+}
+
+func dot3f(a, b [3]float32) float32 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func lerp3f(x float32, a, b [3]float32) [3]float32 {
+	return add3f(scale3f(a, 1-x), scale3f(b, x))
+}
+
 // rotator2f returns a function that rotates points by the specified angle
 // (given in degrees).
 func rotator2f(angle float32) func([2]float32) [2]float32 {
@@ -288,6 +288,25 @@ func TestPointSegmentDistance(t *testing.T) {
 	}
 }
 
+func TestRayRayClosureRate(t *testing.T) {
+	type cr struct {
+		p0, d0, p1, d1 [2]float32
+		rate           float32
+	}
+
+	for _, c := range []cr{
+		cr{p0: [2]float32{0, 0}, d0: [2]float32{1, 0}, p1: [2]float32{10, 0}, d1: [2]float32{-1, 0}, rate: 2},
+		cr{p0: [2]float32{0, 0}, d0: [2]float32{-1, 0}, p1: [2]float32{10, 0}, d1: [2]float32{1, 0}, rate: -2},
+		cr{p0: [2]float32{0, 0}, d0: [2]float32{0, 1}, p1: [2]float32{10, 0}, d1: [2]float32{0, 1}, rate: 0},
+		cr{p0: [2]float32{0, 0}, d0: [2]float32{0, 5}, p1: [2]float32{0, 10}, d1: [2]float32{3, 2}, rate: 3},
+		cr{p0: [2]float32{4, 4}, d0: [2]float32{1, 1}, p1: [2]float32{4, 4}, d1: [2]float32{0, 0}, rate: 0},
+	} {
+		if r := RayRayClosureRate(c.p0, c.d0, c.p1, c.d1); abs(r-c.rate) > 1e-5 {
+			t.Errorf("RayRayClosureRate(%v, %v, %v, %v) -> %f, expected %f", c.p0, c.d0, c.p1, c.d1, r, c.rate)
+		}
+	}
+}
+
 func TestPermutationElement(t *testing.T) {
 	for _, n := range []int{8, 31, 10523} {
 		for _, h := range []uint32{0, 0xff, 0xfeedface} {
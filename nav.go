@@ -32,8 +32,24 @@ type Nav struct {
 
 	FinalAltitude float32
 	Waypoints     []Waypoint
+
+	// Set when the aircraft is moving on the ground, from pushback until
+	// takeoff or from landing until parking.
+	Taxi *NavTaxi
+
+	// Set for aircraft without RNAV (GPS) equipment, which can only
+	// proceed direct to navaids and to nearby fixes.
+	NonRNAV bool
 }
 
+// Aircraft without RNAV refuse direct-to clearances to fixes other than
+// navaids that are farther away than this, in nm.
+const nonRNAVDirectRange = 25
+
+// nonRNAVPistonFraction is the fraction of piston aircraft that aren't
+// RNAV equipped; all turbine aircraft are.
+const nonRNAVPistonFraction = 0.3
+
 // DeferredHeading stores a heading assignment from the controller and the
 // time at which to start executing it; this time is set to be a few
 // seconds after the controller issues it in order to model the delay
@@ -113,6 +129,7 @@ type NavHeading struct {
 	JoiningArc   bool
 	RacetrackPT  *FlyRacetrackPT
 	Standard45PT *FlyStandard45PT
+	Hold         *FlyHold
 }
 
 type NavApproach struct {
@@ -193,6 +210,7 @@ func makeNav(w *World, fp FlightPlan, perf AircraftPerformance, wp []Waypoint) *
 		FinalAltitude:  float32(fp.Altitude),
 		Waypoints:      DuplicateSlice(wp),
 		FixAssignments: make(map[string]NavFixAssignment),
+		NonRNAV:        perf.Engine.AircraftType == "P" && rand.Float32() < nonRNAVPistonFraction,
 	}
 
 	nav.FlightState = FlightState{
@@ -346,6 +364,9 @@ func (nav *Nav) Summary(fp FlightPlan) string {
 	} else {
 		lines = append(lines, "Arrival to "+fp.ArrivalAirport)
 	}
+	if nav.Taxi != nil {
+		lines = append(lines, nav.Taxi.Summary())
+	}
 
 	if nav.Altitude.Assigned != nil {
 		if abs(nav.FlightState.Altitude-*nav.Altitude.Assigned) < 100 {
@@ -406,8 +427,13 @@ func (nav *Nav) Summary(fp FlightPlan) string {
 				int(nav.FlightState.Heading), int(*nav.Heading.Assigned)))
 		}
 	}
+	if fh := nav.Heading.Hold; fh != nil {
+		lines = append(lines, fh.Summary())
+	}
 	if dh := nav.DeferredHeading; dh != nil {
-		if dh.Heading.Assigned == nil && len(nav.Waypoints) > 0 {
+		if dh.Heading.Hold != nil {
+			lines = append(lines, "Will shortly proceed to "+dh.Heading.Hold.Hold.Fix+" to hold")
+		} else if dh.Heading.Assigned == nil && len(nav.Waypoints) > 0 {
 			lines = append(lines, fmt.Sprintf("Will shortly go direct %s", nav.Waypoints[0].Fix))
 		} else if dh.Heading.Assigned != nil {
 			lines = append(lines, fmt.Sprintf("Will shortly start flying heading %03d", int(*dh.Heading.Assigned)))
@@ -647,8 +673,11 @@ func (nav *Nav) updateAltitude(lg *Logger) {
 		if nav.FlightState.Altitude < 10000 {
 			// Have a slower baseline rate of descent on approach
 			descent = min(descent, 2000)
-			// And reduce it based on airspeed as well
-			descent *= min(nav.FlightState.IAS/250, 1)
+			// And reduce it based on airspeed as well (but helicopters
+			// can descend at low speeds.)
+			if !nav.Perf.Helicopter {
+				descent *= min(nav.FlightState.IAS/250, 1)
+			}
 		}
 		climb = min(climb, targetRate)
 		descent = min(descent, targetRate)
@@ -774,7 +803,7 @@ func (nav *Nav) Update(wind WindModel, lg *Logger) *Waypoint {
 	// Don't refer to DeferredHeading here; assume that if the pilot hasn't
 	// punched in a new heading assignment, we should update waypoints or
 	// not as per the old assignment.
-	if nav.Heading.Assigned == nil {
+	if nav.Heading.Assigned == nil && nav.Heading.Hold == nil {
 		return nav.updateWaypoints(wind, lg)
 	}
 
@@ -804,6 +833,9 @@ func (nav *Nav) TargetHeading(wind WindModel, lg *Logger) (heading float32, turn
 	if nav.Heading.Standard45PT != nil {
 		return nav.Heading.Standard45PT.GetHeading(nav, wind, lg)
 	}
+	if nav.Heading.Hold != nil {
+		return nav.Heading.Hold.GetHeading(nav, wind, lg)
+	}
 
 	if nav.Heading.Assigned != nil {
 		heading = *nav.Heading.Assigned
@@ -855,7 +887,7 @@ func (nav *Nav) TargetHeading(wind WindModel, lg *Logger) (heading float32, turn
 
 		if nav.IsAirborne() {
 			// model where we'll actually end up, given the wind
-			vp := add2f(v, wind.AverageWindVector())
+			vp := add2f(v, wind.AverageWindVector(nav.FlightState.Position, nav.FlightState.Altitude))
 
 			// Find the deflection angle of how much the wind pushes us off course.
 			vn, vpn := normalize2f(v), normalize2f(vp)
@@ -992,6 +1024,13 @@ func (nav *Nav) TargetAltitude(lg *Logger) (alt, rate float32) {
 		}
 	}
 
+	if nav.Perf.Helicopter {
+		if alt, ok := nav.helicopterTargetAltitude(); ok {
+			lg.Debugf("alt: helicopter hover-taxi or landing %.0f", alt)
+			return alt, MaximumRate
+		}
+	}
+
 	if nav.FlightState.IsDeparture {
 		// Accel is given in "per 2 seconds...", want to return per minute..
 		maxClimb := nav.Perf.Rate.Climb
@@ -1248,6 +1287,13 @@ func (nav *Nav) TargetSpeed(lg *Logger) (float32, float32) {
 		nav.Speed = NavSpeed{}
 	}
 
+	if nav.Perf.Helicopter {
+		if spd, ok := nav.helicopterTargetSpeed(); ok {
+			lg.Debugf("speed: helicopter hover-taxi or landing %.0f", spd)
+			return spd, MaximumRate
+		}
+	}
+
 	if nav.Speed.MaintainSlowestPractical {
 		lg.Debug("speed: slowest practical")
 		return nav.v2() + 5, MaximumRate
@@ -1287,6 +1333,13 @@ func (nav *Nav) TargetSpeed(lg *Logger) (float32, float32) {
 		return *nav.Speed.Assigned, MaximumRate
 	}
 
+	if fh := nav.Heading.Hold; fh != nil && fh.State != HoldStateApproaching {
+		ias, rate := nav.targetAltitudeIAS()
+		ias = min(ias, maxHoldingSpeed(nav.FlightState.Altitude))
+		lg.Debugf("speed: holding at %.0f", ias)
+		return ias, rate
+	}
+
 	if wp, speed, eta := nav.getUpcomingSpeedRestrictionWaypoint(); nav.Heading.Assigned == nil && wp != nil {
 		lg.Debugf("speed: %.0f to cross %s in %.0fs", speed, wp.Fix, eta)
 		if eta < 5 { // includes unknown ETA case
@@ -1916,7 +1969,42 @@ func (nav *Nav) directFix(fix string) bool {
 	return false
 }
 
+// canProceedDirect indicates whether the aircraft is able to navigate
+// direct to the given waypoint from its present position: RNAV aircraft
+// always are, but others can only go direct to navaids and nearby fixes.
+func (nav *Nav) canProceedDirect(wp Waypoint) bool {
+	if !nav.NonRNAV {
+		return true
+	}
+	if database != nil {
+		if _, ok := database.Navaids[wp.Fix]; ok {
+			return true
+		}
+	}
+	return nmdistance2ll(nav.FlightState.Position, wp.Location) <= nonRNAVDirectRange
+}
+
+// findFix returns the waypoint for the given fix if it's in the route or
+// the assigned approach.
+func (nav *Nav) findFix(fix string) (Waypoint, bool) {
+	if idx := slices.IndexFunc(nav.Waypoints, func(wp Waypoint) bool { return wp.Fix == fix }); idx != -1 {
+		return nav.Waypoints[idx], true
+	}
+	if ap := nav.Approach.Assigned; ap != nil {
+		for _, route := range ap.Waypoints {
+			if idx := slices.IndexFunc(route, func(wp Waypoint) bool { return wp.Fix == fix }); idx != -1 {
+				return route[idx], true
+			}
+		}
+	}
+	return Waypoint{}, false
+}
+
 func (nav *Nav) DirectFix(fix string) PilotResponse {
+	if wp, ok := nav.findFix(fix); ok && !nav.canProceedDirect(wp) {
+		return PilotResponse{Message: "unable direct " + FixReadback(fix) + ", we're not RNAV equipped", Unexpected: true}
+	}
+
 	if nav.directFix(fix) {
 		nav.EnqueueHeading(NavHeading{})
 		nav.Approach.NoPT = false
@@ -1928,6 +2016,84 @@ func (nav *Nav) DirectFix(fix string) PilotResponse {
 	}
 }
 
+// AmendRoute has the aircraft proceed direct to the first of the given
+// waypoints and then fly the rest of them. If the last one is in its
+// current route, it continues on its route after it.
+func (nav *Nav) AmendRoute(waypoints []Waypoint) PilotResponse {
+	if !nav.canProceedDirect(waypoints[0]) {
+		return PilotResponse{Message: "unable direct " + FixReadback(waypoints[0].Fix) + ", we're not RNAV equipped",
+			Unexpected: true}
+	}
+
+	route := slices.Clone(waypoints)
+	last := waypoints[len(waypoints)-1].Fix
+	idx := slices.IndexFunc(nav.Waypoints, func(wp Waypoint) bool { return wp.Fix == last })
+	if idx != -1 {
+		route = append(route, nav.Waypoints[idx+1:]...)
+	}
+	nav.Waypoints = route
+	nav.EnqueueHeading(NavHeading{})
+	nav.Approach.NoPT = false
+	nav.Approach.InterceptState = NotIntercepting
+
+	var fixes []string
+	for _, wp := range waypoints {
+		fixes = append(fixes, FixReadback(wp.Fix))
+	}
+	msg := "direct " + strings.Join(fixes, " then ")
+	if idx != -1 {
+		msg += ", then as filed"
+	}
+	return PilotResponse{Message: msg}
+}
+
+// rejoinIndex returns the index of the first waypoint in the route that
+// is ahead of the aircraft: the one after the closest waypoint if the
+// aircraft has already passed the closest one and otherwise the closest
+// one.
+func (nav *Nav) rejoinIndex() int {
+	p := nav.FlightState.Position
+	closest, dist := 0, nmdistance2ll(p, nav.Waypoints[0].Location)
+	for i, wp := range nav.Waypoints[1:] {
+		if d := nmdistance2ll(p, wp.Location); d < dist {
+			closest, dist = i+1, d
+		}
+	}
+	if closest+1 < len(nav.Waypoints) {
+		next := nav.Waypoints[closest+1].Location
+		if nmdistance2ll(p, next) < nmdistance2ll(nav.Waypoints[closest].Location, next) {
+			return closest + 1
+		}
+	}
+	return closest
+}
+
+// ResumeOwnNavigation has the aircraft rejoin its route from its present
+// position by proceeding direct to the first fix in the route that is
+// ahead of it. Aircraft without RNAV proceed to the first one ahead that
+// they can navigate direct to; if there isn't one, they need vectors.
+func (nav *Nav) ResumeOwnNavigation() PilotResponse {
+	if len(nav.Waypoints) == 0 {
+		return PilotResponse{Message: "unable. We don't have a route to rejoin", Unexpected: true}
+	}
+
+	idx := nav.rejoinIndex()
+	for idx < len(nav.Waypoints) && !nav.canProceedDirect(nav.Waypoints[idx]) {
+		idx++
+	}
+	if idx == len(nav.Waypoints) {
+		return PilotResponse{Message: "unable, we're not RNAV equipped. We'll need vectors to rejoin our route",
+			Unexpected: true}
+	}
+
+	nav.Waypoints = nav.Waypoints[idx:]
+	nav.EnqueueHeading(NavHeading{})
+	nav.Approach.NoPT = false
+	nav.Approach.InterceptState = NotIntercepting
+
+	return PilotResponse{Message: "resuming own navigation, direct " + FixReadback(nav.Waypoints[0].Fix)}
+}
+
 func (nav *Nav) DepartFixDirect(fixa string, fixb string) PilotResponse {
 	fa, fb := nav.fixPairInRoute(fixa, fixb)
 	if fa == nil {
@@ -1987,6 +2153,52 @@ func (nav *Nav) CrossFixAt(fix string, ar *AltitudeRestriction, speed int) Pilot
 	return PilotResponse{Message: response}
 }
 
+// UnableCrossingRestriction checks the controller-assigned crossing
+// restrictions for the fixes in the aircraft's route and returns the
+// first one that the aircraft cannot meet given its current altitude,
+// speed, and performance, if any. The aircraft's best climb, descent,
+// and speed change rates are assumed, so a restriction that is reported
+// here won't be met even if the pilot expedites.
+func (nav *Nav) UnableCrossingRestriction() (string, bool) {
+	if nav.FlightState.GS <= 0 {
+		return "", false
+	}
+
+	// Distance along the route so far.
+	dist := float32(0)
+	p := nav.FlightState.Position
+	for _, wp := range nav.Waypoints {
+		dist += nmdistance2ll(p, wp.Location)
+		p = wp.Location
+
+		nfa, ok := nav.FixAssignments[wp.Fix]
+		if !ok {
+			continue
+		}
+
+		eta := dist / nav.FlightState.GS * 3600 // seconds
+
+		if ar := nfa.Arrive.Altitude; ar != nil {
+			alt := nav.FlightState.Altitude
+			delta := ar.TargetAltitude(alt) - alt
+			rate := Select(delta > 0, nav.Perf.Rate.Climb, nav.Perf.Rate.Descent) / 60 // ft/s
+			if abs(delta) > rate*eta {
+				return wp.Fix, true
+			}
+		}
+
+		if spd := nfa.Arrive.Speed; spd != nil {
+			delta := *spd - nav.FlightState.IAS
+			// Rates are given in knots per 2 seconds.
+			rate := Select(delta > 0, nav.Perf.Rate.Accelerate, nav.Perf.Rate.Decelerate) / 2
+			if abs(delta) > rate*eta {
+				return wp.Fix, true
+			}
+		}
+	}
+	return "", false
+}
+
 func (nav *Nav) getApproach(airport string, id string, w *World) (*Approach, error) {
 	if id == "" {
 		return nil, ErrInvalidApproach
@@ -2012,6 +2224,11 @@ func (nav *Nav) ExpectApproach(airport string, id string, arr *Arrival, w *World
 		return PilotResponse{Message: "unable. We don't know the " + id + " approach.", Unexpected: true}
 	}
 
+	if !w.Weather.ApproachAvailable(ap.Type) {
+		return PilotResponse{Message: "unable. The weather is below minimums for the " + ap.FullName + " approach.",
+			Unexpected: true}
+	}
+
 	if id == nav.Approach.AssignedId && nav.Approach.Assigned != nil {
 		return PilotResponse{Message: "you already told us to expect the " + ap.FullName + " approach."}
 	}
@@ -2270,6 +2487,10 @@ func (nav *Nav) clearedApproach(airport string, id string, straightIn bool, arr
 		return PilotResponse{Message: "unable. We were told to expect the " + ap.FullName + " approach...", Unexpected: true},
 			ErrClearedForUnexpectedApproach
 	}
+	if !w.Weather.ApproachAvailable(ap.Type) {
+		return PilotResponse{Message: "unable. The weather is below minimums for the " + ap.FullName + " approach.",
+			Unexpected: true}, ErrUnableCommand
+	}
 
 	if resp, err := nav.prepareForApproach(straightIn); err != nil {
 		return resp, err
@@ -2318,6 +2539,21 @@ func (nav *Nav) ClimbViaSID() PilotResponse {
 	return PilotResponse{Message: "climb via the SID"}
 }
 
+// ClearedRoute replaces the aircraft's route with the given waypoints to
+// its arrival airport and assigns it the given altitude.
+func (nav *Nav) ClearedRoute(wps []Waypoint, alt float32) PilotResponse {
+	nav.Waypoints = append(DuplicateSlice(wps), nav.FlightState.ArrivalAirport)
+	nav.FixAssignments = make(map[string]NavFixAssignment)
+	nav.FinalAltitude = alt
+	nav.Altitude = NavAltitude{Assigned: &alt}
+	nav.Speed = NavSpeed{}
+	nav.EnqueueHeading(NavHeading{})
+	nav.Approach.InterceptState = NotIntercepting
+
+	return PilotResponse{Message: "cleared to " + nav.FlightState.ArrivalAirport.Fix + " via the TEC route, " +
+		"maintain " + FormatAltitude(alt)}
+}
+
 func (nav *Nav) DescendViaSTAR() PilotResponse {
 	if nav.FlightState.IsDeparture {
 		return PilotResponse{Message: "unable. We're not an arrival", Unexpected: true}
@@ -2715,3 +2951,191 @@ func (fp *FlyStandard45PT) GetHeading(nav *Nav, wind WindModel, lg *Logger) (flo
 		return nav.FlightState.Heading, TurnClosest, StandardTurnRate
 	}
 }
+
+///////////////////////////////////////////////////////////////////////////
+// Holds
+
+type FlyHold struct {
+	Hold        Hold
+	Published   bool
+	Entry       RacetrackPTEntry
+	EFC         string
+	OutboundLeg float32 // nm
+	State       int
+}
+
+const (
+	HoldStateApproaching   = iota
+	HoldStateEntryTurn     // parallel and teardrop entries only
+	HoldStateEntryOutbound // parallel and teardrop entries only
+	HoldStateEntryReturn   // parallel and teardrop entries only
+	HoldStateEntryInbound  // parallel entry only
+	HoldStateTurningOutbound
+	HoldStateFlyingOutbound
+	HoldStateTurningInbound
+	HoldStateFlyingInbound
+)
+
+func (nav *Nav) Hold(h Hold, published bool, efc string) PilotResponse {
+	if !nav.IsAirborne() {
+		return PilotResponse{Message: "unable. We're not airborne", Unexpected: true}
+	}
+
+	acFixHeading := headingp2ll(nav.FlightState.Position, h.Location, nav.FlightState.NmPerLongitude,
+		nav.FlightState.MagneticVariation)
+	nav.EnqueueHeading(NavHeading{Hold: &FlyHold{
+		Hold:      h,
+		Published: published,
+		Entry:     h.SelectEntry(acFixHeading),
+		EFC:       efc,
+		State:     HoldStateApproaching,
+	}})
+	nav.Approach.InterceptState = NotIntercepting
+
+	msg := h.Instructions(published)
+	if efc != "" {
+		msg += ", expect further clearance " + efc
+	}
+	return PilotResponse{Message: msg}
+}
+
+func (fh *FlyHold) Summary() string {
+	s := "Hold " + fh.Hold.Direction() + " of " + fh.Hold.Fix
+	if fh.Published {
+		s += " as published"
+	} else {
+		s += fmt.Sprintf(", %03d inbound, %s turns", int(fh.Hold.InboundCourse),
+			Select(fh.Hold.LeftTurns, "left", "right"))
+		if legs := fh.Hold.Legs(); legs != "" {
+			s += ", " + legs
+		}
+	}
+	if fh.State == HoldStateApproaching {
+		s += ", " + fh.Entry.String() + " entry"
+	}
+	if fh.EFC != "" {
+		s += ", EFC " + fh.EFC
+	}
+	return s
+}
+
+// entryOutboundHeading returns the heading flown on the outbound leg of
+// a parallel or teardrop entry.
+func (fh *FlyHold) entryOutboundHeading() float32 {
+	hdg := fh.Hold.OutboundCourse()
+	if fh.Entry == TeardropEntry {
+		// Offset by 30 degrees toward the holding side.
+		hdg += float32(Select(fh.Hold.LeftTurns, 30, -30))
+	}
+	return NormalizeHeading(hdg)
+}
+
+func (fh *FlyHold) GetHeading(nav *Nav, wind WindModel, lg *Logger) (float32, TurnMethod, float32) {
+	h := fh.Hold
+	turn := h.TurnMethod()
+	fixHeading := headingp2ll(nav.FlightState.Position, h.Location, nav.FlightState.NmPerLongitude,
+		nav.FlightState.MagneticVariation)
+	atFix := func() bool {
+		eta := nmdistance2ll(nav.FlightState.Position, h.Location) / nav.FlightState.GS * 3600 // in seconds
+		return eta < 2
+	}
+
+	switch fh.State {
+	case HoldStateApproaching:
+		if atFix() {
+			fh.OutboundLeg = h.LegDistance(nav.FlightState.Altitude, nav.FlightState.GS)
+			if fh.Entry == ParallelEntry || fh.Entry == TeardropEntry {
+				fh.State = HoldStateEntryTurn
+			} else {
+				fh.State = HoldStateTurningOutbound
+			}
+			lg.Debugf("hold: at %s, %s entry", h.Fix, fh.Entry)
+		}
+		return fixHeading, TurnClosest, StandardTurnRate
+
+	case HoldStateEntryTurn:
+		hdg := fh.entryOutboundHeading()
+		if headingDifference(nav.FlightState.Heading, hdg) < 1 {
+			fh.State = HoldStateEntryOutbound
+		}
+		if fh.Entry == ParallelEntry {
+			// The parallel entry turns away from the holding side.
+			return hdg, TurnMethod(Select(h.LeftTurns, TurnRight, TurnLeft)), StandardTurnRate
+		}
+		return hdg, TurnClosest, StandardTurnRate
+
+	case HoldStateEntryOutbound:
+		d := nmdistance2ll(nav.FlightState.Position, h.Location)
+		if fh.Entry == TeardropEntry {
+			if d > 0.5 && nav.shouldTurnToIntercept(h.Location, h.InboundCourse, turn, wind, lg) {
+				fh.State = HoldStateEntryReturn
+			}
+		} else if d > fh.OutboundLeg {
+			fh.State = HoldStateEntryReturn
+		}
+		return fh.entryOutboundHeading(), TurnClosest, StandardTurnRate
+
+	case HoldStateEntryReturn:
+		if fh.Entry == TeardropEntry {
+			if headingDifference(nav.FlightState.Heading, h.InboundCourse) < 1 {
+				fh.State = HoldStateFlyingInbound
+			}
+			return h.InboundCourse, turn, StandardTurnRate
+		}
+
+		// Parallel: turn back through more than 180 degrees to a heading
+		// that intercepts the inbound course from the holding side.
+		hdg := NormalizeHeading(h.InboundCourse + float32(Select(h.LeftTurns, 30, -30)))
+		if headingDifference(nav.FlightState.Heading, hdg) < 1 {
+			fh.State = HoldStateEntryInbound
+		}
+		return hdg, TurnMethod(Select(h.LeftTurns, TurnRight, TurnLeft)), StandardTurnRate
+
+	case HoldStateEntryInbound:
+		if nav.shouldTurnToIntercept(h.Location, h.InboundCourse, turn, wind, lg) {
+			fh.State = HoldStateFlyingInbound
+		}
+		return NormalizeHeading(h.InboundCourse + float32(Select(h.LeftTurns, 30, -30))), TurnClosest,
+			StandardTurnRate
+
+	case HoldStateTurningOutbound:
+		hdg := h.OutboundCourse()
+		if headingDifference(nav.FlightState.Heading, hdg) < 1 {
+			fh.State = HoldStateFlyingOutbound
+			fh.OutboundLeg = h.LegDistance(nav.FlightState.Altitude, nav.FlightState.GS)
+		}
+		return hdg, turn, StandardTurnRate
+
+	case HoldStateFlyingOutbound:
+		// Legs are measured from abeam the fix.
+		hdg := radians(h.OutboundCourse() - nav.FlightState.MagneticVariation)
+		v := sub2f(ll2nm(nav.FlightState.Position, nav.FlightState.NmPerLongitude),
+			ll2nm(h.Location, nav.FlightState.NmPerLongitude))
+		if dot(v, [2]float32{sin(hdg), cos(hdg)}) > fh.OutboundLeg {
+			fh.State = HoldStateTurningInbound
+		}
+		return h.OutboundCourse(), TurnClosest, StandardTurnRate
+
+	case HoldStateTurningInbound:
+		if headingDifference(nav.FlightState.Heading, h.InboundCourse) < 1 {
+			fh.State = HoldStateFlyingInbound
+		}
+		return h.InboundCourse, turn, StandardTurnRate
+
+	case HoldStateFlyingInbound:
+		if atFix() {
+			if nav.Approach.Cleared {
+				// Leave the hold at the fix for the approach.
+				lg.Debugf("hold: leaving the hold at %s for the approach", h.Fix)
+				nav.Heading = NavHeading{}
+			} else {
+				fh.State = HoldStateTurningOutbound
+			}
+		}
+		return fixHeading, TurnClosest, StandardTurnRate
+
+	default:
+		lg.Errorf("unhandled hold state: %d", fh.State)
+		return nav.FlightState.Heading, TurnClosest, StandardTurnRate
+	}
+}
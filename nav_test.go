@@ -0,0 +1,131 @@
+// nav_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+)
+
+func TestRNAVNavigation(t *testing.T) {
+	// FixReadback looks up navaids in the database.
+	saved := database
+	database = &StaticDatabase{Navaids: map[string]Navaid{"BBB": {Id: "BBB", Name: "BBB"}}}
+	defer func() { database = saved }()
+
+	// A degree of longitude at the equator is 60 nm, so the fixes are 30
+	// nm apart.
+	route := []Waypoint{
+		{Fix: "AAA", Location: Point2LL{0.5, 0}},
+		{Fix: "BBB", Location: Point2LL{1, 0}},
+		{Fix: "CCC", Location: Point2LL{1.5, 0}},
+		{Fix: "DDD", Location: Point2LL{2, 0}},
+	}
+	makeNav := func(nonRNAV bool, pos Point2LL) *Nav {
+		return &Nav{
+			FlightState: FlightState{Position: pos, NmPerLongitude: 60},
+			Waypoints:   DuplicateSlice(route),
+			NonRNAV:     nonRNAV,
+		}
+	}
+
+	// Direct to distant fixes
+	for _, test := range []struct {
+		nonRNAV bool
+		fix     string
+		unable  bool
+	}{
+		{nonRNAV: false, fix: "DDD"},
+		{nonRNAV: true, fix: "AAA"},               // 18 nm
+		{nonRNAV: true, fix: "BBB"},               // 48 nm, but a VOR
+		{nonRNAV: true, fix: "DDD", unable: true}, // 108 nm
+	} {
+		nav := makeNav(test.nonRNAV, Point2LL{0.2, 0})
+		resp := nav.DirectFix(test.fix)
+		if resp.Unexpected != test.unable {
+			t.Errorf("non-RNAV %v direct %s: got response %q", test.nonRNAV, test.fix, resp.Message)
+		}
+		if !test.unable && nav.Waypoints[0].Fix != test.fix {
+			t.Errorf("non-RNAV %v direct %s: route starts at %s", test.nonRNAV, test.fix, nav.Waypoints[0].Fix)
+		}
+		if test.unable && len(nav.Waypoints) != len(route) {
+			t.Errorf("non-RNAV %v direct %s: route changed after unable", test.nonRNAV, test.fix)
+		}
+	}
+
+	// Resume own navigation
+	for _, test := range []struct {
+		nonRNAV bool
+		pos     Point2LL
+		fix     string
+	}{
+		// Abeam AAA and closer to BBB than AAA is, so AAA has been passed.
+		{nonRNAV: false, pos: Point2LL{0.6, 0.1}, fix: "BBB"},
+		// Short of AAA
+		{nonRNAV: false, pos: Point2LL{0.3, 0.1}, fix: "AAA"},
+		{nonRNAV: true, pos: Point2LL{0.3, 0.1}, fix: "AAA"},
+		// Just past BBB; CCC is 30 nm away but DDD is too far.
+		{nonRNAV: false, pos: Point2LL{1.15, -0.3}, fix: "CCC"},
+		{nonRNAV: true, pos: Point2LL{1.15, -0.3}, fix: ""},
+		// AAA is out of range but the VOR after it can be flown to.
+		{nonRNAV: false, pos: Point2LL{0.7, 0.5}, fix: "AAA"},
+		{nonRNAV: true, pos: Point2LL{0.7, 0.5}, fix: "BBB"},
+	} {
+		nav := makeNav(test.nonRNAV, test.pos)
+		resp := nav.ResumeOwnNavigation()
+		if test.fix == "" {
+			if !resp.Unexpected {
+				t.Errorf("non-RNAV %v at %v: expected unable, got %q", test.nonRNAV, test.pos, resp.Message)
+			}
+		} else if resp.Unexpected || nav.Waypoints[0].Fix != test.fix {
+			t.Errorf("non-RNAV %v at %v: got %q, route starting at %s; expected %s", test.nonRNAV, test.pos,
+				resp.Message, nav.Waypoints[0].Fix, test.fix)
+		}
+	}
+}
+
+func TestAmendRoute(t *testing.T) {
+	saved := database
+	database = &StaticDatabase{}
+	defer func() { database = saved }()
+
+	route := []Waypoint{
+		{Fix: "AAA", Location: Point2LL{0.5, 0}},
+		{Fix: "BBB", Location: Point2LL{1, 0}},
+		{Fix: "CCC", Location: Point2LL{1.5, 0}},
+	}
+	fixes := func(wps []Waypoint) string {
+		var s string
+		for _, wp := range wps {
+			s += wp.Fix + " "
+		}
+		return s
+	}
+
+	// Rejoining the route
+	nav := &Nav{FlightState: FlightState{Position: Point2LL{0.2, 0}, NmPerLongitude: 60},
+		Waypoints: DuplicateSlice(route)}
+	resp := nav.AmendRoute([]Waypoint{{Fix: "XXX", Location: Point2LL{0.7, 0.2}}, {Fix: "BBB", Location: Point2LL{1, 0}}})
+	if resp.Unexpected || resp.Message != "direct XXX then BBB, then as filed" {
+		t.Errorf("got response %q", resp.Message)
+	}
+	if f := fixes(nav.Waypoints); f != "XXX BBB CCC " {
+		t.Errorf("got route %s", f)
+	}
+
+	// A new route
+	resp = nav.AmendRoute([]Waypoint{{Fix: "YYY", Location: Point2LL{0.7, 0.2}}})
+	if resp.Unexpected || resp.Message != "direct YYY" || fixes(nav.Waypoints) != "YYY " {
+		t.Errorf("got response %q, route %s", resp.Message, fixes(nav.Waypoints))
+	}
+
+	// Non-RNAV aircraft can't go direct to distant fixes.
+	nav = &Nav{FlightState: FlightState{Position: Point2LL{0.2, 0}, NmPerLongitude: 60},
+		Waypoints: DuplicateSlice(route), NonRNAV: true}
+	if resp := nav.AmendRoute([]Waypoint{{Fix: "ZZZ", Location: Point2LL{3, 0}}}); !resp.Unexpected {
+		t.Errorf("non-RNAV got %q, expected unable", resp.Message)
+	} else if fixes(nav.Waypoints) != "AAA BBB CCC " {
+		t.Errorf("non-RNAV route changed to %s after unable", fixes(nav.Waypoints))
+	}
+}
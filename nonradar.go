@@ -0,0 +1,306 @@
+// nonradar.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// NonRadarPane is an alternative to the STARS scope for practicing
+// procedural (non-radar) control, as is done in oceanic airspace or when
+// the radar is out of service. Aircraft are only known from their
+// position reports, which they make as they pass each fix along their
+// route, and separation is based on the estimates they give for the next
+// fix.
+type NonRadarPane struct {
+	// When enabled, the NonRadarPane is drawn in place of the STARS
+	// scope.
+	Enabled  bool
+	FontSize int
+	font     *Font
+
+	// Minimum longitudinal separation in minutes between aircraft at the
+	// same fix and altitude, before adjustments for the Mach number
+	// technique.
+	MinimumSeparation int
+
+	reports   map[string]*PositionReport
+	nextFix   map[string]string // callsign -> next fix when last seen
+	scrollbar *ScrollBar
+}
+
+// PositionReport records what a pilot reports when passing a fix.
+type PositionReport struct {
+	Fix          string // empty for the aircraft's initial report
+	Time         time.Time
+	Altitude     int
+	Mach         float32
+	Groundspeed  int
+	NextFix      string
+	NextEstimate time.Time
+}
+
+// NonRadarConflict describes two aircraft whose estimates at the same fix
+// don't provide the required separation.
+type NonRadarConflict struct {
+	Lead, Trail string // callsigns
+	Fix         string
+	Separation  time.Duration
+	Required    time.Duration
+	// Closing is set if the trailing aircraft is faster and so the
+	// separation will decrease after the fix.
+	Closing bool
+}
+
+func NewNonRadarPane() *NonRadarPane {
+	return &NonRadarPane{FontSize: 12, MinimumSeparation: 10}
+}
+
+func (nrp *NonRadarPane) Name() string { return "Non-Radar" }
+
+func (nrp *NonRadarPane) Activate(w *World, r Renderer, eventStream *EventStream) {
+	if nrp.FontSize == 0 {
+		nrp.FontSize = 12
+	}
+	if nrp.MinimumSeparation == 0 {
+		nrp.MinimumSeparation = 10
+	}
+	if nrp.font = GetFont(FontIdentifier{Name: "Flight Strip Printer", Size: nrp.FontSize}); nrp.font == nil {
+		nrp.font = GetDefaultFont()
+	}
+	if nrp.scrollbar == nil {
+		nrp.scrollbar = NewVerticalScrollBar(4, false)
+	}
+	nrp.reports = make(map[string]*PositionReport)
+	nrp.nextFix = make(map[string]string)
+}
+
+func (nrp *NonRadarPane) Deactivate() {}
+
+func (nrp *NonRadarPane) ResetWorld(w *World) {
+	nrp.reports = make(map[string]*PositionReport)
+	nrp.nextFix = make(map[string]string)
+}
+
+func (nrp *NonRadarPane) CanTakeKeyboardFocus() bool { return false }
+
+// Hidden implements the PaneHider interface; the NonRadarPane is only
+// shown when it is enabled.
+func (nrp *NonRadarPane) Hidden() bool { return !nrp.Enabled }
+
+func (nrp *NonRadarPane) DrawUI() {
+	imgui.Checkbox("Use non-radar display in place of STARS", &nrp.Enabled)
+
+	uiStartDisable(!nrp.Enabled)
+	sep := int32(nrp.MinimumSeparation)
+	imgui.SliderIntV("Minimum separation at a fix (minutes)", &sep, 3, 20, "%d", 0)
+	nrp.MinimumSeparation = int(sep)
+	id := FontIdentifier{Name: nrp.font.id.Name, Size: nrp.FontSize}
+	if newFont, changed := DrawFontSizeSelector(&id); changed {
+		nrp.FontSize = newFont.size
+		nrp.font = newFont
+	}
+	uiEndDisable(!nrp.Enabled)
+}
+
+// nonRadarModeEnabled indicates whether the NonRadarPane is being shown in
+// place of the STARS scope.
+func nonRadarModeEnabled() bool {
+	enabled := false
+	if globalConfig.DisplayRoot != nil {
+		globalConfig.DisplayRoot.VisitPanes(func(p Pane) {
+			if nrp, ok := p.(*NonRadarPane); ok {
+				enabled = nrp.Enabled
+			}
+		})
+	}
+	return enabled
+}
+
+// speedOfSound returns the speed of sound in knots at the given altitude
+// in the standard atmosphere.
+func speedOfSound(altitude float32) float32 {
+	// Temperature in Kelvin; it's constant above the tropopause.
+	t := max(288.15-0.0019812*altitude, 216.65)
+	return 38.967854 * sqrt(t)
+}
+
+func machNumber(tas, altitude float32) float32 {
+	return tas / speedOfSound(altitude)
+}
+
+// machTechniqueMinimum returns the required longitudinal separation at a
+// fix given the aircraft's Mach numbers: if the leading aircraft is faster
+// by at least Mach 0.02, the minimum is reduced by a minute for each
+// additional 0.01 of difference, down to 5 minutes.
+func machTechniqueMinimum(leadMach, trailMach float32, minimum int) time.Duration {
+	if d := int(math.Round(float64(100 * (leadMach - trailMach)))); d >= 2 {
+		minimum = max(min(5, minimum), minimum-(d-1))
+	}
+	return time.Duration(minimum) * time.Minute
+}
+
+// nonRadarConflicts returns all of the pairs of aircraft that will reach
+// the same fix within 1,000' of each other and without the required
+// separation between their estimates.
+func nonRadarConflicts(reports map[string]*PositionReport, minimum int) []NonRadarConflict {
+	var conflicts []NonRadarConflict
+	callsigns := SortedMapKeys(reports)
+	for i, ca := range callsigns {
+		for _, cb := range callsigns[i+1:] {
+			a, b := reports[ca], reports[cb]
+			if a.NextFix == "" || a.NextFix != b.NextFix || abs(a.Altitude-b.Altitude) >= 1000 {
+				continue
+			}
+
+			lead, trail := ca, cb
+			if b.NextEstimate.Before(a.NextEstimate) {
+				lead, trail = cb, ca
+			}
+			rl, rt := reports[lead], reports[trail]
+			sep := rt.NextEstimate.Sub(rl.NextEstimate)
+			req := machTechniqueMinimum(rl.Mach, rt.Mach, minimum)
+			closing := rt.Mach > rl.Mach
+
+			if sep < req || closing {
+				conflicts = append(conflicts, NonRadarConflict{
+					Lead:       lead,
+					Trail:      trail,
+					Fix:        rl.NextFix,
+					Separation: sep,
+					Required:   req,
+					Closing:    closing,
+				})
+			}
+		}
+	}
+	return conflicts
+}
+
+// makePositionReport returns the position report the aircraft makes now.
+func makePositionReport(w *World, ac *Aircraft, fix string) *PositionReport {
+	fs := &ac.Nav.FlightState
+	now := w.CurrentTime()
+	r := &PositionReport{
+		Fix:         fix,
+		Time:        now,
+		Altitude:    int(ac.Altitude()),
+		Mach:        machNumber(IASToTAS(fs.IAS, fs.Altitude), fs.Altitude),
+		Groundspeed: int(fs.GS),
+	}
+	if len(ac.Nav.Waypoints) > 0 && fs.GS > 0 {
+		wp := ac.Nav.Waypoints[0]
+		r.NextFix = wp.Fix
+		hours := nmdistance2ll(ac.Position(), wp.Location) / fs.GS
+		r.NextEstimate = now.Add(time.Duration(hours * float32(time.Hour)))
+	}
+	return r
+}
+
+// updateReports records new position reports from the aircraft that are
+// ours or are being handed off to us. An aircraft reports when its next
+// fix changes, i.e., when it has passed a fix (or been cleared direct to
+// another one).
+func (nrp *NonRadarPane) updateReports(w *World) {
+	for callsign := range nrp.reports {
+		if ac, ok := w.Aircraft[callsign]; !ok ||
+			(ac.TrackingController != w.Callsign && ac.HandoffTrackController != w.Callsign) {
+			delete(nrp.reports, callsign)
+			delete(nrp.nextFix, callsign)
+		}
+	}
+
+	for callsign, ac := range w.Aircraft {
+		if ac.FlightPlan == nil ||
+			(ac.TrackingController != w.Callsign && ac.HandoffTrackController != w.Callsign) {
+			continue
+		}
+
+		next := ""
+		if len(ac.Nav.Waypoints) > 0 {
+			next = ac.Nav.Waypoints[0].Fix
+		}
+
+		if prev, ok := nrp.nextFix[callsign]; !ok {
+			nrp.reports[callsign] = makePositionReport(w, ac, "")
+		} else if prev != next {
+			nrp.reports[callsign] = makePositionReport(w, ac, prev)
+		}
+		nrp.nextFix[callsign] = next
+	}
+}
+
+func (nrp *NonRadarPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	w := ctx.world
+	nrp.updateReports(w)
+	conflicts := nonRadarConflicts(nrp.reports, nrp.MinimumSeparation)
+
+	bx, _ := nrp.font.BoundText("X", 0)
+	fw, lineHeight := float32(bx), float32(nrp.font.size+2)
+	style := TextStyle{Font: nrp.font, Color: UITextColor}
+	headerStyle := TextStyle{Font: nrp.font, Color: UITextHighlightColor}
+	alertStyle := TextStyle{Font: nrp.font, Color: UIErrorColor}
+
+	hhmm := func(t time.Time) string {
+		if t.IsZero() {
+			return "----"
+		}
+		return t.UTC().Format("1504")
+	}
+
+	type line struct {
+		text  string
+		style TextStyle
+	}
+	lines := []line{{fmt.Sprintf("%-8s %-5s %-6s %-4s %-6s %-5s %-6s %-4s %s", "CALLSIGN", "TYPE", "FIX", "TIME",
+		"ALT", "MACH", "NEXT", "EST", "ROUTE"), headerStyle}}
+	for _, callsign := range SortedMapKeys(nrp.reports) {
+		r := nrp.reports[callsign]
+		ac := w.Aircraft[callsign]
+		inConflict := slices.ContainsFunc(conflicts, func(c NonRadarConflict) bool {
+			return c.Lead == callsign || c.Trail == callsign
+		})
+		text := fmt.Sprintf("%-8s %-5s %-6s %-4s %-6s %-5s %-6s %-4s %s", callsign, ac.FlightPlan.BaseType(),
+			Select(r.Fix == "", "-", r.Fix), hhmm(r.Time), strconv.Itoa(r.Altitude/100),
+			fmt.Sprintf("%.2f", r.Mach)[1:], Select(r.NextFix == "", "-", r.NextFix),
+			hhmm(r.NextEstimate), ac.FlightPlan.Route)
+		lines = append(lines, line{text, Select(inConflict, alertStyle, style)})
+	}
+
+	lines = append(lines, line{}, line{"SEPARATION", headerStyle})
+	if len(conflicts) == 0 {
+		lines = append(lines, line{"No conflicts", style})
+	}
+	for _, c := range conflicts {
+		text := fmt.Sprintf("%s / %s at %s: %d min, %d required", c.Lead, c.Trail, c.Fix,
+			int(c.Separation.Minutes()), int(c.Required.Minutes()))
+		if c.Closing {
+			text += ", trailing aircraft faster"
+		}
+		lines = append(lines, line{text, alertStyle})
+	}
+
+	visibleLines := int(ctx.paneExtent.Height() / lineHeight)
+	nrp.scrollbar.Update(len(lines), visibleLines, ctx)
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	y := ctx.paneExtent.Height() - lineHeight/2
+	for _, l := range lines[min(nrp.scrollbar.Offset(), len(lines)):] {
+		td.AddText(l.text, [2]float32{fw, y}, l.style)
+		y -= lineHeight
+	}
+
+	ctx.SetWindowCoordinateMatrices(cb)
+	nrp.scrollbar.Draw(ctx, cb)
+	td.GenerateCommands(cb)
+}
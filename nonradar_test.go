@@ -0,0 +1,68 @@
+// nonradar_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMachTechnique(t *testing.T) {
+	for _, test := range []struct {
+		lead, trail float32
+		expected    time.Duration
+	}{
+		{0.80, 0.80, 10 * time.Minute},
+		{0.80, 0.82, 10 * time.Minute},
+		{0.81, 0.80, 10 * time.Minute},
+		{0.82, 0.80, 9 * time.Minute},
+		{0.84, 0.80, 7 * time.Minute},
+		{0.86, 0.80, 5 * time.Minute},
+		{0.90, 0.78, 5 * time.Minute},
+	} {
+		if m := machTechniqueMinimum(test.lead, test.trail, 10); m != test.expected {
+			t.Errorf("lead M%.2f trail M%.2f: got %s, expected %s", test.lead, test.trail, m, test.expected)
+		}
+	}
+
+	if m := machNumber(480, 35000); m < 0.82 || m > 0.85 {
+		t.Errorf("480 knots TAS at FL350: got Mach %.3f, expected ~0.84", m)
+	}
+}
+
+func TestNonRadarConflicts(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	report := func(alt int, mach float32, fix string, eta int) *PositionReport {
+		return &PositionReport{Altitude: alt, Mach: mach, NextFix: fix, NextEstimate: t0.Add(time.Duration(eta) * time.Minute)}
+	}
+
+	reports := map[string]*PositionReport{
+		"AAL1": report(35000, 0.80, "BOBBY", 0),
+		"AAL2": report(35000, 0.80, "BOBBY", 8),  // too close behind AAL1
+		"AAL3": report(37000, 0.80, "BOBBY", 1),  // different altitude
+		"AAL4": report(35000, 0.80, "CINDY", 0),  // different fix
+		"AAL5": report(37000, 0.84, "BOBBY", 15), // faster than AAL3
+	}
+	conflicts := nonRadarConflicts(reports, 10)
+	if len(conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got %+v", conflicts)
+	}
+
+	c := conflicts[0]
+	if c.Lead != "AAL1" || c.Trail != "AAL2" || c.Fix != "BOBBY" || c.Separation != 8*time.Minute ||
+		c.Required != 10*time.Minute || c.Closing {
+		t.Errorf("unexpected conflict %+v", c)
+	}
+	c = conflicts[1]
+	if c.Lead != "AAL3" || c.Trail != "AAL5" || !c.Closing {
+		t.Errorf("unexpected conflict %+v", c)
+	}
+
+	// With the leader faster, less separation is required.
+	reports["AAL1"].Mach = 0.84
+	if conflicts := nonRadarConflicts(reports, 10); len(conflicts) != 1 || conflicts[0].Trail != "AAL5" {
+		t.Errorf("expected only the AAL3/AAL5 conflict, got %+v", conflicts)
+	}
+}
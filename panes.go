@@ -33,6 +33,12 @@ type PaneUIDrawer interface {
 	DrawUI()
 }
 
+// PaneHider is implemented by Panes that may be hidden; when one is
+// hidden, its sibling in the display hierarchy takes its space.
+type PaneHider interface {
+	Hidden() bool
+}
+
 type PaneUpgrader interface {
 	Upgrade(prev, current int)
 }
@@ -238,6 +244,18 @@ func unmarshalPane(paneType string, data []byte) (Pane, error) {
 	case "*main.MessagesPane":
 		return unmarshalPaneHelper[*MessagesPane](data)
 
+	case "*main.NonRadarPane":
+		return unmarshalPaneHelper[*NonRadarPane](data)
+
+	case "*main.SurfacePane":
+		return unmarshalPaneHelper[*SurfacePane](data)
+
+	case "*main.TowerPane":
+		return unmarshalPaneHelper[*TowerPane](data)
+
+	case "*main.ChartPane":
+		return unmarshalPaneHelper[*ChartPane](data)
+
 	case "*main.STARSPane":
 		return unmarshalPaneHelper[*STARSPane](data)
 
@@ -389,7 +407,7 @@ func (fsp *FlightStripPane) processEvents(w *World) {
 	for _, event := range fsp.events.Get() {
 		switch event.Type {
 		case PushedFlightStripEvent:
-			if ac, ok := w.Aircraft[event.Callsign]; ok && fsp.AddPushed {
+			if ac, ok := w.Aircraft[event.Callsign]; ok && fsp.AddPushed && event.ToController == w.Callsign {
 				possiblyAdd(ac)
 			}
 
@@ -441,6 +459,28 @@ func (fsp *FlightStripPane) processEvents(w *World) {
 	}
 }
 
+// pushStrip sends the aircraft's flight strip to the controller it is
+// being handed off to or, if the handoff has already been accepted, the
+// controller now tracking it.
+func (fsp *FlightStripPane) pushStrip(w *World, callsign string) {
+	ac := w.GetAircraft(callsign, false)
+	if ac == nil {
+		return
+	}
+
+	to := ac.HandoffTrackController
+	if to == "" && ac.TrackingController != w.Callsign {
+		to = ac.TrackingController
+	}
+	if ctrl := w.GetControllerByCallsign(to); ctrl == nil || !ctrl.IsHuman {
+		lg.Infof("%s: no controller to push flight strip to", callsign)
+		return
+	}
+
+	w.PushFlightStrip(callsign, to, nil,
+		func(err error) { lg.Errorf("%s: push flight strip to %s: %v", callsign, to, err) })
+}
+
 func (fsp *FlightStripPane) Name() string { return "Flight Strips" }
 
 func (fsp *FlightStripPane) DrawUI() {
@@ -489,7 +529,7 @@ func (fsp *FlightStripPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 	// column widths
 	width0 := 10 * fw
 	width1 := 6 * fw
-	width2 := 5 * fw
+	width2 := 9 * fw // room for both scratchpads
 	widthAnn := 5 * fw
 
 	widthCenter := ctx.paneExtent.Width() - width0 - width1 - width2 - 3*widthAnn
@@ -542,12 +582,14 @@ func (fsp *FlightStripPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 		qb := GetColoredTrianglesDrawBuilder()
 		defer ReturnColoredTrianglesDrawBuilder(qb)
 		bgColor := func() RGB {
-			/*if fsp.isDeparture(ac) {
-				return ctx.cs.DepartureStrip
+			if ac.IsDeparture() {
+				return RGB{.85, .9, .95}
+			} else if fp != nil && ctx.world.ArrivalAirports[fp.ArrivalAirport] == nil {
+				// overflight
+				return RGB{.85, .93, .85}
 			} else {
-				return ctx.cs.ArrivalStrip
-			}*/
-			return RGB{.9, .9, .85}
+				return RGB{.95, .93, .8}
+			}
 		}()
 		y0, y1 := y+1+vpad-stripHeight, y+1+vpad
 		qb.AddQuad([2]float32{0, y0}, [2]float32{drawWidth, y0}, [2]float32{drawWidth, y1}, [2]float32{0, y1}, bgColor)
@@ -558,7 +600,12 @@ func (fsp *FlightStripPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 		// First column; 3 entries
 		td.AddText(callsign, [2]float32{x, y}, style)
 		if fp != nil {
-			td.AddText(fp.AircraftType, [2]float32{x, y - fh*3/2}, style)
+			actype := fp.AircraftType
+			if ac.Nav.NonRNAV {
+				// Equipment suffix for aircraft without RNAV
+				actype += "/A"
+			}
+			td.AddText(actype, [2]float32{x, y - fh*3/2}, style)
 			td.AddText(fp.Rules.String(), [2]float32{x, y - fh*3}, style)
 		}
 		ld.AddLine([2]float32{width0, y}, [2]float32{width0, y - stripHeight})
@@ -581,7 +628,7 @@ func (fsp *FlightStripPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 			td.AddText(fp.ArrivalAirport, [2]float32{x, y - fh}, style)
 			td.AddText(fp.AlternateAirport, [2]float32{x, y - 2*fh}, style)
 		}
-		td.AddText(ac.Scratchpad, [2]float32{x, y - 3*fh}, style)
+		td.AddText(strings.TrimSpace(ac.Scratchpad+" "+ac.SecondaryScratchpad), [2]float32{x, y - 3*fh}, style)
 		ld.AddLine([2]float32{width0 + width1 + width2, y},
 			[2]float32{width0 + width1 + width2, y - stripHeight})
 
@@ -589,6 +636,14 @@ func (fsp *FlightStripPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 		x += width2
 		if fp != nil {
 			cols := int(widthCenter / fw)
+			routeStyle := style
+			remarks := fp.Remarks
+			if pr, ok := ctx.world.RouteConformance(ac); !ok {
+				// The filed route doesn't conform to the preferential
+				// route; show it in red, followed by the expected route.
+				routeStyle.Color = RGB{.7, .1, .1}
+				remarks = strings.TrimSpace(pr.Type + " " + pr.Route + " " + remarks)
+			}
 			// Line-wrap the route to fit the box and break it into lines.
 			route, _ := wrapText(fp.Route, cols, 2 /* indent */, true)
 			text := strings.Split(route, "\n")
@@ -597,7 +652,7 @@ func (fsp *FlightStripPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 				text = append(text, "")
 			}
 			// Similarly for the remarks
-			remarks, _ := wrapText(fp.Remarks, cols, 2 /* indent */, true)
+			remarks, _ = wrapText(remarks, cols, 2 /* indent */, true)
 			text = append(text, strings.Split(remarks, "\n")...)
 			// Limit to the first four lines so we don't spill over.
 			if len(text) > 4 {
@@ -611,7 +666,7 @@ func (fsp *FlightStripPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 					text[i] = text[i][:cols]
 				}
 			}
-			td.AddText(strings.Join(text, "\n"), [2]float32{x, y}, style)
+			td.AddText(strings.Join(text, "\n"), [2]float32{x, y}, routeStyle)
 		}
 
 		// Annotations
@@ -634,6 +689,12 @@ func (fsp *FlightStripPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 					strip.Annotations[fsp.selectedAnnotation] = strip.Annotations[fsp.selectedAnnotation][:3]
 					fsp.annotationCursorPos = min(fsp.annotationCursorPos, len(strip.Annotations[fsp.selectedAnnotation]))
 				}
+				if editResult != TextEditReturnNone {
+					// Send it along right away so the edit isn't undone
+					// by the next update from the server.
+					ctx.world.AnnotateFlightStrip(callsign, strip.Annotations, nil,
+						func(err error) { lg.Errorf("%s: annotate flight strip: %v", callsign, err) })
+				}
 			} else {
 				td.AddText(ann, [2]float32{xp, yp}, style)
 			}
@@ -673,8 +734,15 @@ func (fsp *FlightStripPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 		y += stripHeight
 	}
 
-	// Handle selection, deletion, and reordering
+	// Handle selection, deletion, reordering, and pushing strips
 	if ctx.mouse != nil {
+		if ctx.mouse.Clicked[MouseButtonSecondary] && ctx.mouse.Pos[0] <= drawWidth {
+			stripIndex := int(ctx.mouse.Pos[1]/stripHeight) + scrollOffset
+			if stripIndex < len(fsp.strips) {
+				fsp.pushStrip(ctx.world, fsp.strips[stripIndex])
+			}
+		}
+
 		// Ignore clicks if the mouse is over the scrollbar (and it's being drawn)
 		if ctx.mouse.Clicked[MouseButtonPrimary] && ctx.mouse.Pos[0] <= drawWidth {
 			// from the bottom
@@ -747,28 +815,26 @@ func (fsp *FlightStripPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 		}
 	}
 	// Take focus if the user clicks in the annotations
-	/*
-		if ctx.mouse != nil && ctx.mouse.Clicked[MouseButtonPrimary] {
-			annotationStartX := drawWidth - 3*widthAnn
-			if xp := ctx.mouse.Pos[0]; xp >= annotationStartX && xp < drawWidth {
-				stripIndex := int(ctx.mouse.Pos[1]/stripHeight) + scrollOffset
-				if stripIndex < len(fsp.strips) {
-					wmTakeKeyboardFocus(fsp, true)
-					fsp.selectedStrip = stripIndex
-
-					// Figure out which annotation was selected
-					xa := int(ctx.mouse.Pos[0]-annotationStartX) / int(widthAnn)
-					ya := 2 - (int(ctx.mouse.Pos[1])%int(stripHeight))/(int(stripHeight)/3)
-					xa, ya = clamp(xa, 0, 2), clamp(ya, 0, 2) // just in case
-					fsp.selectedAnnotation = 3*ya + xa
-
-					callsign := fsp.strips[fsp.selectedStrip]
-					strip := ctx.world.GetFlightStrip(callsign)
-					fsp.annotationCursorPos = len(strip.annotations[fsp.selectedAnnotation])
-				}
+	if ctx.mouse != nil && ctx.mouse.Clicked[MouseButtonPrimary] {
+		annotationStartX := drawWidth - 3*widthAnn
+		if xp := ctx.mouse.Pos[0]; xp >= annotationStartX && xp < drawWidth {
+			stripIndex := int(ctx.mouse.Pos[1]/stripHeight) + scrollOffset
+			if stripIndex < len(fsp.strips) {
+				wmTakeKeyboardFocus(fsp, true)
+				fsp.selectedStrip = stripIndex
+
+				// Figure out which annotation was selected
+				xa := int(ctx.mouse.Pos[0]-annotationStartX) / int(widthAnn)
+				ya := 2 - (int(ctx.mouse.Pos[1])%int(stripHeight))/(int(stripHeight)/3)
+				xa, ya = clamp(xa, 0, 2), clamp(ya, 0, 2) // just in case
+				fsp.selectedAnnotation = 3*ya + xa
+
+				callsign := fsp.strips[fsp.selectedStrip]
+				strip := ctx.world.GetFlightStrip(callsign)
+				fsp.annotationCursorPos = len(strip.Annotations[fsp.selectedAnnotation])
 			}
 		}
-	*/
+	}
 	fsp.scrollbar.Draw(ctx, cb)
 
 	cb.SetRGB(UIControlColor)
@@ -788,6 +854,7 @@ type Message struct {
 	system   bool
 	error    bool
 	global   bool
+	datalink bool
 }
 
 type CLIInput struct {
@@ -802,6 +869,10 @@ type MessagesPane struct {
 	events         *EventsSubscription
 	messages       []Message
 
+	// If set, aircraft that haven't acknowledged instructions we've
+	// issued are listed above the command prompt.
+	FlagUnacknowledged bool
+
 	// Command-input-related
 	input         CLIInput
 	history       []CLIInput
@@ -843,6 +914,7 @@ func (mp *MessagesPane) DrawUI() {
 	if newFont, changed := DrawFontPicker(&mp.FontIdentifier, "Font"); changed {
 		mp.font = newFont
 	}
+	imgui.Checkbox("Flag instructions that haven't been read back", &mp.FlagUnacknowledged)
 }
 
 func (mp *MessagesPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
@@ -853,7 +925,12 @@ func (mp *MessagesPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 	}
 	mp.processKeyboard(ctx)
 
-	nLines := len(mp.messages) + 1 /* prompt */
+	var unacked []string
+	if mp.FlagUnacknowledged {
+		unacked = mp.unacknowledged(ctx.world)
+	}
+
+	nLines := len(mp.messages) + 1 /* prompt */ + len(unacked)
 	lineHeight := float32(mp.font.size + 1)
 	visibleLines := int(ctx.paneExtent.Height() / lineHeight)
 	mp.scrollbar.Update(nLines, visibleLines, ctx)
@@ -895,6 +972,11 @@ func (mp *MessagesPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 	}
 	y += lineHeight
 
+	for _, u := range unacked {
+		td.AddText(u, [2]float32{indent, y}, TextStyle{Font: mp.font, Color: RGB{1, .5, 0}})
+		y += lineHeight
+	}
+
 	for i := scrollOffset; i < min(len(mp.messages), visibleLines+scrollOffset+1); i++ {
 		// TODO? wrap text
 		msg := mp.messages[len(mp.messages)-1-i]
@@ -1003,15 +1085,34 @@ func (msg *Message) Color() RGB {
 		return RGB{.9, .1, .1}
 	case msg.global:
 		return RGB{0.012, 0.78, 0.016}
+	case msg.datalink:
+		return RGB{0.3, 0.8, 0.9}
 	default:
 		return RGB{1, 1, 1}
 	}
 }
 
+// unacknowledged returns a line of text for each of our aircraft that has
+// outstanding instructions that the pilot hasn't read back.
+func (mp *MessagesPane) unacknowledged(w *World) []string {
+	var lines []string
+	for _, callsign := range SortedMapKeys(w.Aircraft) {
+		ac := w.Aircraft[callsign]
+		if ac.ControllingController == w.Callsign && len(ac.UnacknowledgedInstructions) > 0 {
+			lines = append(lines, "NO READBACK "+callsign+": "+strings.Join(ac.UnacknowledgedInstructions, " / "))
+		}
+	}
+	return lines
+}
+
 func (mp *MessagesPane) runCommands(w *World) {
 	mp.input.cmd = strings.TrimSpace(mp.input.cmd)
 
 	if mp.input.cmd[0] == '/' {
+		if w.HasSystemFailure(FailureLandlines) {
+			mp.messages = append(mp.messages, Message{contents: ErrLandlinesFailure.Error(), error: true})
+			return
+		}
 		w.SendGlobalMessage(GlobalMessage{
 			FromController: w.Callsign,
 			Message:        w.Callsign + ": " + mp.input.cmd[1:],
@@ -1028,7 +1129,14 @@ func (mp *MessagesPane) runCommands(w *World) {
 	mp.input = CLIInput{}
 
 	if ok {
-		if ac := w.GetAircraft(callsign, true /*abbreviated*/); ac != nil {
+		if ac := w.GetAircraft(callsign, true /*abbreviated*/); ac == nil {
+			mp.messages = append(mp.messages, Message{contents: callsign + ": no such aircraft", error: true})
+		} else if uplink, isUplink := strings.CutPrefix(cmd, "UL "); isUplink {
+			// Send the rest of the commands via datalink
+			w.UplinkClearance(ac.Callsign, uplink, nil, func(err error) {
+				mp.messages = append(mp.messages, Message{contents: ac.Callsign + ": " + err.Error(), error: true})
+			})
+		} else {
 			w.RunAircraftCommands(ac.Callsign, cmd, func(errorString string, remainingCommands string) {
 				if errorString != "" {
 					mp.messages = append(mp.messages, Message{contents: errorString, error: true})
@@ -1038,8 +1146,6 @@ func (mp *MessagesPane) runCommands(w *World) {
 					mp.input.cursor = len(mp.input.cmd)
 				}
 			})
-		} else {
-			mp.messages = append(mp.messages, Message{contents: callsign + ": no such aircraft", error: true})
 		}
 	} else {
 		mp.messages = append(mp.messages, Message{contents: "invalid command: " + callsign, error: true})
@@ -1109,14 +1215,19 @@ func (mp *MessagesPane) processEvents(w *World) {
 				fullName = strings.ReplaceAll(fullName, "approach", "departure")
 			}
 			msg = Message{contents: fullName + ", " + radioCallsign + ", " + response}
+			if cue, ok := checkInCue(w, callsign); ok {
+				globalConfig.Audio.PlayOnce(cue)
+			}
 		} else {
 			if len(response) > 0 {
 				response = strings.ToUpper(response[:1]) + response[1:]
 			}
 			msg = Message{contents: response + ". " + radioCallsign, error: unexpectedTransmission}
+			globalConfig.Audio.PlayOnce(AudioFrequencyMessage)
 		}
 		lg.Debug("radio_transmission", slog.String("callsign", callsign), slog.Any("message", msg))
 		mp.messages = append(mp.messages, msg)
+		globalConfig.Audio.Speak(callsign, msg.contents, w.LaunchConfig.PilotRealism)
 	}
 
 	for _, event := range mp.events.Get() {
@@ -1152,6 +1263,22 @@ func (mp *MessagesPane) processEvents(w *World) {
 					})
 			}
 
+		case DatalinkMessageEvent:
+			if event.ToController == w.Callsign {
+				mp.messages = append(mp.messages, Message{
+					contents: "CPDLC " + event.Callsign + ": " + event.Message,
+					datalink: true,
+				})
+			}
+
+		case OperationalErrorEvent:
+			if event.ToController == w.Callsign {
+				mp.messages = append(mp.messages, Message{
+					contents: "OPERATIONAL ERROR " + event.Message,
+					error:    true,
+				})
+			}
+
 		case TrackClickedEvent:
 			if cmd := strings.TrimSpace(mp.input.cmd); cmd != "" {
 				mp.input.cmd = event.Callsign + " " + cmd
@@ -0,0 +1,190 @@
+// pilotrealism.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"strings"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// PilotRealism controls how much pilots' transmissions vary from textbook
+// phraseology so that controllers can practice getting the information
+// they need from imperfect check-ins and readbacks.
+type PilotRealism int
+
+const (
+	// Standard phraseology for every transmission.
+	PilotRealismStandard PilotRealism = iota
+	// Some pilots are wordy or terse, hesitate, or use non-standard
+	// phrasing; a few have accents.
+	PilotRealismTypical
+	// More of the above, plus check-ins that leave out information and
+	// readbacks that are just "roger".
+	PilotRealismChallenging
+	NumPilotRealismLevels
+)
+
+func (r PilotRealism) String() string {
+	return []string{"Standard", "Typical", "Challenging"}[r]
+}
+
+// pilotRealismParams gives the probabilities of each of the variations
+// for a PilotRealism level.
+type pilotRealismParams struct {
+	Hesitation  float32 // "uh"
+	Terse       float32 // check in with just the altitude
+	Verbose     float32 // greetings and "with you"
+	NonStandard float32 // e.g., "out of 5,000 for 8,000"
+	Omit        float32 // leave out the assigned altitude or speed
+	RogerOnly   float32 // "roger" in place of a readback
+	Accent      float32
+}
+
+var pilotRealismParameters = [NumPilotRealismLevels]pilotRealismParams{
+	PilotRealismStandard: {},
+	PilotRealismTypical: {
+		Hesitation:  0.15,
+		Terse:       0.1,
+		Verbose:     0.15,
+		NonStandard: 0.25,
+		Accent:      0.15,
+	},
+	PilotRealismChallenging: {
+		Hesitation:  0.35,
+		Terse:       0.2,
+		Verbose:     0.25,
+		NonStandard: 0.5,
+		Omit:        0.2,
+		RogerOnly:   0.05,
+		Accent:      0.4,
+	},
+}
+
+func (lc *LaunchConfig) DrawPilotRealismUI() (changed bool) {
+	imgui.Text("Pilots")
+	if imgui.BeginComboV("Pilot realism", lc.PilotRealism.String(), 0) {
+		for r := range NumPilotRealismLevels {
+			if imgui.SelectableV(r.String(), r == lc.PilotRealism, 0, imgui.Vec2{}) {
+				lc.PilotRealism = r
+				changed = true
+			}
+		}
+		imgui.EndCombo()
+	}
+	changed = imgui.SliderFloatV("Readback errors (probability)", &lc.ReadbackErrorRate, 0, 0.2, "%.02f", 0) || changed
+	changed = imgui.SliderFloatV("Pilot errors (probability)", &lc.PilotErrorRate, 0, 0.2, "%.02f", 0) || changed
+	changed = imgui.SliderFloatV("Additional turn delay (seconds)", &lc.ResponseDelay, 0, 15, "%.0f", 0) || changed
+	changed = imgui.SliderFloatV("Frequency congestion (probability)", &lc.FrequencyCongestion, 0, 0.2, "%.02f", 0) || changed
+	return
+}
+
+// varyTransmissions returns the given pilot transmissions, adjusted
+// according to the launch configuration's pilot realism level.
+func (s *Sim) varyTransmissions(rts []RadioTransmission) []RadioTransmission {
+	if s.LaunchConfig.PilotRealism == PilotRealismStandard {
+		return rts
+	}
+
+	p := pilotRealismParameters[s.LaunchConfig.PilotRealism]
+	varied := DuplicateSlice(rts)
+	for i, rt := range varied {
+		switch rt.Type {
+		case RadioTransmissionContact:
+			varied[i].Message = varyCheckIn(rt.Message, p)
+		case RadioTransmissionReadback:
+			varied[i].Message = varyReadback(rt.Message, p)
+		}
+	}
+	return varied
+}
+
+// varyCheckIn rewrites a check-in message, which is a comma-separated
+// list of clauses like those generated by Nav.ContactMessage.
+func varyCheckIn(msg string, p pilotRealismParams) string {
+	clauses := strings.Split(msg, ", ")
+
+	if rand.Float32() < p.Terse {
+		// Just the altitude.
+		if alt := FilterSlice(clauses, func(c string) bool { return strings.HasPrefix(c, "at ") }); len(alt) > 0 {
+			clauses = alt
+		}
+	}
+
+	for i, c := range clauses {
+		if rand.Float32() < p.Omit {
+			c = omitAssigned(c)
+		}
+		if rand.Float32() < p.NonStandard {
+			c = nonStandardPhrasing(c)
+		}
+		clauses[i] = c
+	}
+	if c := FilterSlice(clauses, func(c string) bool { return c != "" }); len(c) > 0 {
+		clauses = c
+	}
+
+	if len(clauses) > 0 && rand.Float32() < p.Hesitation {
+		i := rand.Intn(len(clauses))
+		clauses[i] = "uh, " + clauses[i]
+	}
+	if rand.Float32() < p.Verbose {
+		clauses = append([]string{Sample("good day", "hello", "hi there", "good evening")}, clauses...)
+		if len(clauses) > 1 && strings.HasPrefix(clauses[1], "at ") {
+			clauses[1] = "with you " + clauses[1]
+		}
+	}
+
+	return strings.Join(clauses, ", ")
+}
+
+// omitAssigned drops the assigned altitude or speed from a check-in
+// clause so that the controller has to ask for it.
+func omitAssigned(c string) string {
+	if strings.HasPrefix(c, "at ") && strings.HasSuffix(c, " assigned") {
+		if alt, _, ok := strings.Cut(c, " for "); ok {
+			return alt
+		}
+	} else if strings.HasPrefix(c, "assigned ") && strings.HasSuffix(c, " knots") {
+		return ""
+	}
+	return c
+}
+
+// nonStandardPhrasing returns an alternative way of saying the given
+// check-in clause that pilots commonly use.
+func nonStandardPhrasing(c string) string {
+	switch {
+	case strings.HasPrefix(c, "at ") && strings.HasSuffix(c, " assigned"):
+		// "at 5,000 for 8,000 assigned"
+		if alt, assigned, ok := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(c, "at "), " assigned"), " for "); ok {
+			return Sample("out of "+alt+" for "+assigned, "leaving "+alt+" for "+assigned, alt+" for "+assigned)
+		}
+	case strings.HasPrefix(c, "at ") && strings.Contains(c, " climbing "):
+		// "at 2,000 climbing 5,000"
+		if alt, cleared, ok := strings.Cut(strings.TrimPrefix(c, "at "), " climbing "); ok {
+			return Sample("out of "+alt+" for "+cleared, "climbing through "+alt+" for "+cleared)
+		}
+	case strings.HasPrefix(c, "at ") && !strings.Contains(c, " for "):
+		alt := strings.TrimPrefix(c, "at ")
+		return Sample("level "+alt, "maintaining "+alt, alt)
+	case strings.HasPrefix(c, "on a ") && strings.HasSuffix(c, " heading"):
+		return "heading " + strings.TrimSuffix(strings.TrimPrefix(c, "on a "), " heading")
+	case strings.HasPrefix(c, "assigned ") && strings.HasSuffix(c, " knots"):
+		spd := strings.TrimSuffix(strings.TrimPrefix(c, "assigned "), " knots")
+		return Sample(spd+" on the speed", "speed "+spd)
+	}
+	return c
+}
+
+func varyReadback(msg string, p pilotRealismParams) string {
+	if rand.Float32() < p.RogerOnly {
+		return Sample("roger", "wilco", "copy that")
+	}
+	if rand.Float32() < p.Hesitation {
+		return "uh, " + msg
+	}
+	return msg
+}
@@ -0,0 +1,57 @@
+// pilotrealism_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestVaryCheckIn(t *testing.T) {
+	const msg = "5 miles north of CAMRN, at 9,000 for 7,000 assigned, assigned 250 knots"
+
+	if v := varyCheckIn(msg, pilotRealismParameters[PilotRealismStandard]); v != msg {
+		t.Errorf("standard realism: got %q, expected %q", v, msg)
+	}
+	if v := varyCheckIn(msg, pilotRealismParams{Terse: 1}); v != "at 9,000 for 7,000 assigned" {
+		t.Errorf("terse: got %q", v)
+	}
+	if v := varyCheckIn(msg, pilotRealismParams{Omit: 1}); v != "5 miles north of CAMRN, at 9,000" {
+		t.Errorf("omit: got %q", v)
+	}
+	if v := varyCheckIn(msg, pilotRealismParams{NonStandard: 1}); !slices.Contains([]string{
+		"5 miles north of CAMRN, out of 9,000 for 7,000, speed 250",
+		"5 miles north of CAMRN, out of 9,000 for 7,000, 250 on the speed",
+		"5 miles north of CAMRN, leaving 9,000 for 7,000, speed 250",
+		"5 miles north of CAMRN, leaving 9,000 for 7,000, 250 on the speed",
+		"5 miles north of CAMRN, 9,000 for 7,000, speed 250",
+		"5 miles north of CAMRN, 9,000 for 7,000, 250 on the speed",
+	}, v) {
+		t.Errorf("non-standard: got %q", v)
+	}
+	if v := varyCheckIn("at 8,000", pilotRealismParams{NonStandard: 1, Verbose: 1}); !slices.Contains([]string{
+		"good day, level 8,000", "hello, level 8,000", "hi there, level 8,000", "good evening, level 8,000",
+		"good day, maintaining 8,000", "hello, maintaining 8,000", "hi there, maintaining 8,000",
+		"good evening, maintaining 8,000", "good day, 8,000", "hello, 8,000", "hi there, 8,000", "good evening, 8,000",
+	}, v) {
+		t.Errorf("verbose: got %q", v)
+	}
+	if v := varyCheckIn("at 8,000", pilotRealismParams{Verbose: 1}); v[len(v)-len("with you at 8,000"):] != "with you at 8,000" {
+		t.Errorf("verbose: got %q", v)
+	}
+	// Terse check-ins with no altitude are left alone.
+	if v := varyCheckIn("passing CAMRN", pilotRealismParams{Terse: 1, Omit: 1}); v != "passing CAMRN" {
+		t.Errorf("got %q, expected \"passing CAMRN\"", v)
+	}
+}
+
+func TestVaryReadback(t *testing.T) {
+	if v := varyReadback("descend and maintain 5,000", pilotRealismParams{RogerOnly: 1}); !slices.Contains([]string{"roger", "wilco", "copy that"}, v) {
+		t.Errorf("got %q", v)
+	}
+	if v := varyReadback("descend and maintain 5,000", pilotRealismParams{Hesitation: 1}); v != "uh, descend and maintain 5,000" {
+		t.Errorf("got %q", v)
+	}
+}
@@ -0,0 +1,357 @@
+// pirep.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// PIREP is a pilot report of the turbulence or icing encountered by an
+// aircraft; the same conditions are expected within Radius nm of where it
+// was reported between Floor and Ceiling. Reports of no turbulence are
+// ride reports.
+type PIREP struct {
+	Type           PIREPType
+	Intensity      PIREPIntensity
+	Location       Point2LL
+	Radius         float32 // nm
+	Floor, Ceiling int     // feet MSL
+	Altitude       int     // of the reporting aircraft
+	// Location relative to a nearby airport, e.g., "JFK270015" for 15nm
+	// on JFK's 270 radial.
+	Reference    string
+	AircraftType string
+	Time         time.Time // sim time
+
+	// Callsigns of the aircraft that have asked for a different altitude
+	// to get out of the conditions.
+	requested []string
+}
+
+type PIREPType int
+
+const (
+	PIREPTurbulence PIREPType = iota
+	PIREPIcing
+)
+
+type PIREPIntensity int
+
+const (
+	PIREPNone PIREPIntensity = iota // smooth ride or no icing
+	PIREPLight
+	PIREPModerate
+	PIREPSevere
+)
+
+func (i PIREPIntensity) String() string {
+	return []string{"NEG", "LGT", "MOD", "SEV"}[i]
+}
+
+const (
+	// PIREPs are removed after this long.
+	pirepLifetime = 30 * time.Minute
+	// Reports are generated at this rate, per hour across all aircraft,
+	// if the weather preset doesn't specify one.
+	defaultPIREPRate = 3
+	// Probability per second that an aircraft in conditions that its
+	// pilots don't like asks for a different altitude.
+	pirepRequestProbability = 1. / 45
+)
+
+// Urgent indicates whether the PIREP should be disseminated as an urgent
+// (UUA) report.
+func (p PIREP) Urgent() bool {
+	return p.Intensity == PIREPSevere
+}
+
+// Unacceptable indicates whether pilots will refuse to climb or descend
+// into the conditions in the PIREP.
+func (p PIREP) Unacceptable() bool {
+	return p.Intensity >= Select(p.Type == PIREPIcing, PIREPModerate, PIREPSevere)
+}
+
+// Uncomfortable indicates whether pilots in the conditions in the PIREP
+// will ask for a different altitude.
+func (p PIREP) Uncomfortable() bool {
+	return p.Intensity >= Select(p.Type == PIREPIcing, PIREPLight, PIREPModerate)
+}
+
+// Affects indicates whether an aircraft at the given position and altitude
+// is in the conditions in the PIREP.
+func (p PIREP) Affects(pos Point2LL, alt int) bool {
+	return alt >= p.Floor && alt <= p.Ceiling && nmdistance2ll(pos, p.Location) <= p.Radius
+}
+
+// Conditions returns a description of the conditions as a pilot would
+// report them.
+func (p PIREP) Conditions() string {
+	intensity := []string{"", "light", "moderate", "severe"}[p.Intensity]
+	if p.Type == PIREPIcing {
+		return intensity + " icing"
+	} else if p.Intensity == PIREPNone {
+		return "a smooth ride"
+	} else if p.Intensity == PIREPSevere {
+		return "severe turbulence"
+	}
+	return intensity + " chop"
+}
+
+// String returns the PIREP in the standard format, e.g.,
+// "UA /OV JFK270015 /TM 1520 /FL080 /TP B738 /TB MOD 070-090".
+func (p PIREP) String() string {
+	report := Select(p.Type == PIREPIcing, "/IC ", "/TB ") + p.Intensity.String()
+	if p.Intensity != PIREPNone {
+		report += fmt.Sprintf(" %03d-%03d", p.Floor/100, p.Ceiling/100)
+	}
+	return fmt.Sprintf("%s /OV %s /TM %s /FL%03d /TP %s %s", Select(p.Urgent(), "UUA", "UA"),
+		p.Reference, p.Time.UTC().Format("1504"), p.Altitude/100, p.AircraftType, report)
+}
+
+// pirepConditions returns the type and intensity of the conditions that
+// an aircraft at the given height above the ground reports in the given
+// weather. u0 and u1 should be uniformly distributed in [0,1).
+func pirepConditions(p WeatherPreset, agl int, u0, u1 float32) (PIREPType, PIREPIntensity) {
+	if base, top := p.cloudLayer(); base != 0 && agl >= base && agl <= top && agl < 20000 && u0 < 0.4 {
+		// Freezing precipitation and convective clouds make for worse
+		// icing.
+		heavy := strings.Contains(p.Weather, "FZ") || strings.Contains(p.Weather, "TS")
+		return PIREPIcing, Select(u1 < Select(heavy, float32(0.6), 0.2), PIREPModerate, PIREPLight)
+	}
+
+	// Probabilities of severe, moderate, and light turbulence.
+	prob := [3]float32{0, 0.1, 0.4}
+	if strings.Contains(p.Weather, "TS") || strings.Contains(p.Sky, "CB") {
+		prob = [3]float32{0.15, 0.45, 0.3}
+	} else if p.Gust > 0 && agl < 10000 {
+		// Mechanical turbulence down low
+		prob = [3]float32{0, 0.4, 0.4}
+	}
+	switch {
+	case u1 < prob[0]:
+		return PIREPTurbulence, PIREPSevere
+	case u1 < prob[0]+prob[1]:
+		return PIREPTurbulence, PIREPModerate
+	case u1 < prob[0]+prob[1]+prob[2]:
+		return PIREPTurbulence, PIREPLight
+	default:
+		return PIREPTurbulence, PIREPNone
+	}
+}
+
+// cloudLayer returns the base of the lowest broken or overcast layer in
+// the preset's sky condition and the top of the clouds, which is taken to
+// be 3,000' above the highest layer, in feet AGL. Both are zero if there
+// is no ceiling.
+func (p WeatherPreset) cloudLayer() (base, top int) {
+	base = p.Ceiling()
+	if base == 0 {
+		return 0, 0
+	}
+	for _, layer := range strings.Fields(p.Sky) {
+		if _, h, ok := parseSkyLayer(layer); ok {
+			top = max(top, h+3000)
+		}
+	}
+	return
+}
+
+// pirepAltitudes returns the altitude band of a PIREP from an aircraft at
+// the given altitude: 2,000' above and below it, rounded out to thousands
+// of feet.
+func pirepAltitudes(alt int) (floor, ceiling int) {
+	floor = max(1000, (alt-2000)/1000*1000)
+	return floor, (alt + 2999) / 1000 * 1000
+}
+
+// pirepRequestAltitude returns the altitude that an aircraft asks for to
+// get out of the conditions in the PIREP: departures ask to go above them
+// unless that would take them above their filed altitude and other
+// aircraft ask to go below them unless that would take them too low.
+func pirepRequestAltitude(p PIREP, departure bool, filed int) int {
+	below, above := p.Floor-1000, p.Ceiling+1000
+	if departure && (filed == 0 || above <= filed) {
+		return above
+	}
+	if below >= 3000 {
+		return below
+	}
+	return above
+}
+
+// updatePIREPs removes old PIREPs, occasionally adds a new one, and has
+// aircraft that are in unpleasant conditions ask to get out of them. s.mu
+// must be held.
+func (s *Sim) updatePIREPs() {
+	s.PIREPs = slices.DeleteFunc(s.PIREPs, func(p PIREP) bool {
+		return s.SimTime.Sub(p.Time) > pirepLifetime
+	})
+
+	rate := float32(defaultPIREPRate)
+	preset, ok := s.World.WeatherPresets[s.World.WeatherPreset]
+	if ok {
+		rate = preset.PIREPRate
+	}
+	if rand.Float32() < rate/3600 {
+		s.generatePIREP(preset)
+	}
+
+	for i := range s.PIREPs {
+		p := &s.PIREPs[i]
+		if !p.Uncomfortable() {
+			continue
+		}
+		for _, callsign := range s.humanAircraft(func(ac *Aircraft) bool {
+			return !slices.Contains(p.requested, ac.Callsign) && !ac.Nav.Approach.Cleared &&
+				p.Affects(ac.Position(), int(ac.Altitude()))
+		}) {
+			if rand.Float32() >= pirepRequestProbability {
+				continue
+			}
+
+			ac := s.World.Aircraft[callsign]
+			p.requested = append(p.requested, callsign)
+			alt := pirepRequestAltitude(*p, ac.IsDeparture(), ac.FlightPlan.Altitude)
+			PostRadioEvents(callsign, []RadioTransmission{RadioTransmission{
+				Controller: ac.ControllingController,
+				Message: fmt.Sprintf("we're getting %s at %s, request %s", p.Conditions(),
+					FormatAltitude(ac.Altitude()), FormatAltitude(float32(alt))),
+				Type: RadioTransmissionUnexpected,
+			}}, s)
+			s.lg.Info("PIREP altitude request", slog.String("callsign", callsign), slog.Int("altitude", alt))
+		}
+	}
+}
+
+// generatePIREP adds a report from a randomly-chosen airborne aircraft.
+// s.mu must be held.
+func (s *Sim) generatePIREP(preset WeatherPreset) {
+	callsigns := FilterSlice(SortedMapKeys(s.World.Aircraft), func(callsign string) bool {
+		ac := s.World.Aircraft[callsign]
+		return ac.FlightPlan != nil && ac.Nav.IsAirborne() && ac.Altitude() > 2000
+	})
+	if len(callsigns) == 0 {
+		return
+	}
+	ac := s.World.Aircraft[callsigns[rand.Intn(len(callsigns))]]
+
+	elevation := 0
+	if ap, ok := database.Airports[s.World.PrimaryAirport]; ok {
+		elevation = ap.Elevation
+	}
+	alt := int(ac.Altitude())
+	typ, intensity := pirepConditions(preset, alt-elevation, rand.Float32(), rand.Float32())
+	floor, ceiling := pirepAltitudes(alt)
+
+	p := PIREP{
+		Type:         typ,
+		Intensity:    intensity,
+		Location:     ac.Position(),
+		Radius:       float32(10 + rand.Intn(11)),
+		Floor:        floor,
+		Ceiling:      ceiling,
+		Altitude:     alt,
+		Reference:    s.World.pirepReference(ac.Position()),
+		AircraftType: ac.FlightPlan.TypeWithoutSuffix(),
+		Time:         s.SimTime,
+		// The reporting aircraft already knows what it's in for.
+		requested: []string{ac.Callsign},
+	}
+	s.PIREPs = append(s.PIREPs, p)
+	s.lg.Info("PIREP", slog.String("callsign", ac.Callsign), slog.String("report", p.String()))
+
+	if s.controllerIsSignedIn(ac.ControllingController) {
+		PostRadioEvents(ac.Callsign, []RadioTransmission{RadioTransmission{
+			Controller: ac.ControllingController,
+			Message:    fmt.Sprintf("ride report, %s at %s", p.Conditions(), FormatAltitude(float32(alt))),
+			Type:       RadioTransmissionType(Select(p.Urgent(), RadioTransmissionUnexpected, RadioTransmissionContact)),
+		}}, s)
+	}
+}
+
+// unacceptablePIREP returns a PIREP of conditions that an aircraft at the
+// given position wouldn't climb or descend to the given altitude into,
+// or nil if there isn't one. s.mu must be held.
+func (s *Sim) unacceptablePIREP(ac *Aircraft, alt int) *PIREP {
+	for i, p := range s.PIREPs {
+		if p.Unacceptable() && p.Affects(ac.Position(), alt) && !p.Affects(ac.Position(), int(ac.Altitude())) {
+			return &s.PIREPs[i]
+		}
+	}
+	return nil
+}
+
+// pirepReference returns the location of the given point relative to the
+// nearest airport.
+func (w *World) pirepReference(p Point2LL) string {
+	var id string
+	var loc Point2LL
+	for _, name := range SortedMapKeys(w.AllAirports()) {
+		ap := w.AllAirports()[name]
+		if id == "" || nmdistance2ll(p, ap.Location) < nmdistance2ll(p, loc) {
+			id, loc = name, ap.Location
+		}
+	}
+	if id == "" {
+		return p.DMSString()
+	}
+	if len(id) == 4 && id[0] == 'K' {
+		id = id[1:]
+	}
+	hdg := int(headingp2ll(loc, p, w.NmPerLongitude, w.MagneticVariation) + 0.5)
+	if hdg <= 0 {
+		hdg += 360
+	}
+	return fmt.Sprintf("%s%03d%03d", id, hdg, int(nmdistance2ll(loc, p)+0.5))
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Client side
+
+func (w *World) ToggleShowPIREPWindow() {
+	w.showPIREPs = !w.showPIREPs
+}
+
+// DrawPIREPWindow draws the list of current PIREPs, most recent first. It
+// is opened automatically when an urgent PIREP is received.
+func (w *World) DrawPIREPWindow() {
+	urgent := 0
+	for _, p := range w.PIREPs {
+		if p.Urgent() {
+			urgent++
+		}
+	}
+	if urgent > w.lastUrgentPIREPCount {
+		w.showPIREPs = true
+	}
+	w.lastUrgentPIREPCount = urgent
+
+	if !w.showPIREPs {
+		return
+	}
+
+	imgui.BeginV("PIREPs", &w.showPIREPs, imgui.WindowFlagsAlwaysAutoResize)
+	if len(w.PIREPs) == 0 {
+		imgui.Text("No PIREPs have been received.")
+	}
+	for i := len(w.PIREPs) - 1; i >= 0; i-- {
+		p := w.PIREPs[i]
+		age := w.CurrentTime().Sub(p.Time)
+		if p.Urgent() {
+			imgui.PushStyleColor(imgui.StyleColorText, imgui.Vec4{1, .5, .5, 1})
+		}
+		imgui.Text(fmt.Sprintf("%s (%d min ago)", p.String(), int(age.Minutes())))
+		if p.Urgent() {
+			imgui.PopStyleColor()
+		}
+	}
+	imgui.End()
+}
@@ -0,0 +1,111 @@
+// pirep_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPIREPString(t *testing.T) {
+	p := PIREP{
+		Type:         PIREPTurbulence,
+		Intensity:    PIREPModerate,
+		Floor:        7000,
+		Ceiling:      9000,
+		Altitude:     8000,
+		Reference:    "JFK270015",
+		AircraftType: "B738",
+		Time:         time.Date(2024, 3, 1, 15, 20, 0, 0, time.UTC),
+	}
+	if s := p.String(); s != "UA /OV JFK270015 /TM 1520 /FL080 /TP B738 /TB MOD 070-090" {
+		t.Errorf("got %q", s)
+	}
+
+	p.Intensity = PIREPSevere
+	if s := p.String(); s != "UUA /OV JFK270015 /TM 1520 /FL080 /TP B738 /TB SEV 070-090" {
+		t.Errorf("got %q", s)
+	}
+
+	p.Intensity = PIREPNone
+	if s := p.String(); s != "UA /OV JFK270015 /TM 1520 /FL080 /TP B738 /TB NEG" {
+		t.Errorf("got %q", s)
+	}
+
+	p.Type, p.Intensity = PIREPIcing, PIREPLight
+	if s := p.String(); s != "UA /OV JFK270015 /TM 1520 /FL080 /TP B738 /IC LGT 070-090" {
+		t.Errorf("got %q", s)
+	}
+}
+
+func TestPIREPPilotResponses(t *testing.T) {
+	for _, test := range []struct {
+		typ                         PIREPType
+		intensity                   PIREPIntensity
+		uncomfortable, unacceptable bool
+	}{
+		{PIREPTurbulence, PIREPNone, false, false},
+		{PIREPTurbulence, PIREPLight, false, false},
+		{PIREPTurbulence, PIREPModerate, true, false},
+		{PIREPTurbulence, PIREPSevere, true, true},
+		{PIREPIcing, PIREPLight, true, false},
+		{PIREPIcing, PIREPModerate, true, true},
+	} {
+		p := PIREP{Type: test.typ, Intensity: test.intensity}
+		if p.Uncomfortable() != test.uncomfortable || p.Unacceptable() != test.unacceptable {
+			t.Errorf("%s: got uncomfortable %v unacceptable %v, expected %v %v", p.Conditions(),
+				p.Uncomfortable(), p.Unacceptable(), test.uncomfortable, test.unacceptable)
+		}
+	}
+}
+
+func TestPIREPAltitudes(t *testing.T) {
+	for _, test := range []struct {
+		alt, floor, ceiling int
+	}{
+		{8000, 6000, 10000},
+		{8400, 6000, 11000},
+		{2500, 1000, 5000},
+	} {
+		if f, c := pirepAltitudes(test.alt); f != test.floor || c != test.ceiling {
+			t.Errorf("%d: got %d-%d, expected %d-%d", test.alt, f, c, test.floor, test.ceiling)
+		}
+	}
+
+	p := PIREP{Floor: 6000, Ceiling: 10000}
+	if alt := pirepRequestAltitude(p, true, 23000); alt != 11000 {
+		t.Errorf("departure requested %d, expected 11000", alt)
+	}
+	if alt := pirepRequestAltitude(p, true, 10000); alt != 5000 {
+		t.Errorf("departure filed at 10,000 requested %d, expected 5000", alt)
+	}
+	if alt := pirepRequestAltitude(p, false, 0); alt != 5000 {
+		t.Errorf("arrival requested %d, expected 5000", alt)
+	}
+	p = PIREP{Floor: 1000, Ceiling: 5000}
+	if alt := pirepRequestAltitude(p, false, 0); alt != 6000 {
+		t.Errorf("arrival requested %d, expected 6000", alt)
+	}
+}
+
+func TestPIREPConditions(t *testing.T) {
+	ifr := WeatherPreset{Sky: "OVC010", Weather: "-RA"}
+	if typ, intensity := pirepConditions(ifr, 3000, 0.1, 0.5); typ != PIREPIcing || intensity != PIREPLight {
+		t.Errorf("in clouds: got %d %s, expected light icing", typ, intensity)
+	}
+	// Above the tops, only turbulence is reported.
+	if typ, _ := pirepConditions(ifr, 6000, 0.1, 0.5); typ != PIREPTurbulence {
+		t.Errorf("above clouds: got %d, expected turbulence", typ)
+	}
+
+	vfr := WeatherPreset{Sky: "FEW250"}
+	if _, intensity := pirepConditions(vfr, 8000, 0.1, 0.99); intensity != PIREPNone {
+		t.Errorf("clear: got %s, expected smooth ride", intensity)
+	}
+	convective := WeatherPreset{Sky: "BKN030CB", Weather: "TSRA"}
+	if _, intensity := pirepConditions(convective, 12000, 0.9, 0.1); intensity != PIREPSevere {
+		t.Errorf("convective: got %s, expected severe turbulence", intensity)
+	}
+}
@@ -65,8 +65,27 @@ type Platform interface {
 	StartCaptureMouse(e Extent2D)
 	// Disable mouse capture.
 	EndCaptureMouse()
-	// Scaling factor to account for Retina-style displays
+	// DPIScale returns the number of framebuffer pixels per unit in
+	// window coordinates after accounting for the display's DPI, e.g. 2
+	// for Retina-style displays.
 	DPIScale() float32
+	// WindowScale returns the factor by which sizes in window coordinates
+	// should be scaled so that things have the same physical size
+	// regardless of the display's DPI. It is 1 on systems where window
+	// coordinates are already scaled (e.g., macOS) and otherwise follows
+	// the monitor the window is on, so it may change if the window is
+	// moved to another monitor.
+	WindowScale() float32
+	// Joysticks returns the state of the connected joysticks, game
+	// controllers, and other devices that are exposed as joysticks.
+	Joysticks() []Joystick
+}
+
+// Joystick is the state of a joystick-like input device.
+type Joystick struct {
+	Name    string
+	Axes    []float32 // in [-1, 1]
+	Buttons []bool    // pressed or not
 }
 
 ///////////////////////////////////////////////////////////////////////////
@@ -165,12 +184,36 @@ func NewGLFWPlatform(io imgui.IO, windowSize [2]int, windowPosition [2]int, mult
 }
 
 func (g *GLFWPlatform) DPIScale() float32 {
-	if runtime.GOOS == "windows" {
-		sx, sy := g.window.GetContentScale()
-		return float32(int((sx + sy) / 2))
-	} else {
-		return g.FramebufferSize()[0] / g.DisplaySize()[0]
+	if d := g.DisplaySize(); d[0] > 0 {
+		return g.FramebufferSize()[0] / d[0] * g.WindowScale()
+	}
+	return g.WindowScale()
+}
+
+func (g *GLFWPlatform) WindowScale() float32 {
+	if fb, d := g.FramebufferSize(), g.DisplaySize(); d[0] > 0 && fb[0] > d[0] {
+		// The OS already scales window coordinates.
+		return 1
+	}
+	if sx, sy := g.window.GetContentScale(); sx > 0 && sy > 0 {
+		return (sx + sy) / 2
+	}
+	return 1
+}
+
+func (g *GLFWPlatform) Joysticks() []Joystick {
+	var js []Joystick
+	for joy := glfw.Joystick1; joy <= glfw.JoystickLast; joy++ {
+		if !joy.Present() {
+			continue
+		}
+		j := Joystick{Name: joy.GetName(), Axes: joy.GetAxes()}
+		for _, b := range joy.GetButtons() {
+			j.Buttons = append(j.Buttons, b == glfw.Press)
+		}
+		js = append(js, j)
 	}
+	return js
 }
 
 func (g *GLFWPlatform) EnableVSync(sync bool) {
@@ -0,0 +1,139 @@
+// prefroute.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// PreferentialRoute is a preferential departure route (PDR) or
+// preferential arrival route (PAR). IFR aircraft departing from (for
+// PDRs) or arriving at (for PARs) the route's airport are expected to
+// have filed routes that start or end with it, respectively.
+type PreferentialRoute struct {
+	Type      string `json:"type"` // "PDR" or "PAR"
+	Departure string `json:"departure,omitempty"`
+	Arrival   string `json:"arrival,omitempty"`
+	Route     string `json:"route"`
+	// If non-zero, the route only applies to aircraft that have filed for
+	// altitudes in this range.
+	Altitudes [2]int `json:"altitudes,omitempty"`
+}
+
+func (pr *PreferentialRoute) PostDeserialize(e *ErrorLogger) {
+	pr.Type = strings.ToUpper(pr.Type)
+	pr.Departure = strings.ToUpper(pr.Departure)
+	pr.Arrival = strings.ToUpper(pr.Arrival)
+
+	e.Push(pr.Type + " " + pr.Departure + "-" + pr.Arrival)
+	defer e.Pop()
+
+	checkAirport := func(ap string) {
+		if _, ok := database.Airports[ap]; !ok {
+			e.ErrorString("airport \"%s\" unknown", ap)
+		}
+	}
+	switch pr.Type {
+	case "PDR":
+		if pr.Departure == "" {
+			e.ErrorString("\"departure\" must be specified for PDR")
+		} else {
+			checkAirport(pr.Departure)
+		}
+		if pr.Arrival != "" {
+			checkAirport(pr.Arrival)
+		}
+
+	case "PAR":
+		if pr.Arrival == "" {
+			e.ErrorString("\"arrival\" must be specified for PAR")
+		} else {
+			checkAirport(pr.Arrival)
+		}
+		if pr.Departure != "" {
+			checkAirport(pr.Departure)
+		}
+
+	default:
+		e.ErrorString("\"type\" must be \"PDR\" or \"PAR\"")
+	}
+
+	if len(routeElements(pr.Route, pr.Departure, pr.Arrival)) == 0 {
+		e.ErrorString("\"route\" must be specified")
+	}
+	if pr.Altitudes[1] != 0 && pr.Altitudes[0] > pr.Altitudes[1] {
+		e.ErrorString("\"altitudes\" range %d-%d is invalid", pr.Altitudes[0], pr.Altitudes[1])
+	}
+}
+
+// Applies returns whether the aircraft with the given flight plan is
+// expected to fly the route.
+func (pr *PreferentialRoute) Applies(fp *FlightPlan) bool {
+	if pr.Departure != "" && fp.DepartureAirport != pr.Departure {
+		return false
+	}
+	if pr.Arrival != "" && fp.ArrivalAirport != pr.Arrival {
+		return false
+	}
+	return (pr.Altitudes[0] == 0 || fp.Altitude >= pr.Altitudes[0]) &&
+		(pr.Altitudes[1] == 0 || fp.Altitude <= pr.Altitudes[1])
+}
+
+// Conforms returns whether the flight plan's route starts with the route,
+// for PDRs, or ends with it, for PARs.
+func (pr *PreferentialRoute) Conforms(fp *FlightPlan) bool {
+	filed := routeElements(fp.Route, fp.DepartureAirport, fp.ArrivalAirport)
+	route := routeElements(pr.Route, fp.DepartureAirport, fp.ArrivalAirport)
+	if len(route) > len(filed) {
+		return false
+	}
+	if pr.Type == "PDR" {
+		return slices.Equal(filed[:len(route)], route)
+	}
+	return slices.Equal(filed[len(filed)-len(route):], route)
+}
+
+// routeElements returns the fixes, airways, and procedures in the given
+// route, leaving out the departure and arrival airports, DCTs, and any
+// speed and altitude changes.
+func routeElements(route string, departure, arrival string) []string {
+	var elements []string
+	for _, f := range strings.Fields(strings.ReplaceAll(strings.ToUpper(route), ".", " ")) {
+		f, _, _ = strings.Cut(f, "/")
+		if f != "" && f != "DCT" && f != departure && f != arrival {
+			elements = append(elements, f)
+		}
+	}
+	return elements
+}
+
+// checkRouteConformance checks the flight plan against the given
+// preferential routes. If any of them apply to it, it must conform to
+// at least one of them; otherwise, the first of them that applies is
+// returned along with false.
+func checkRouteConformance(fp *FlightPlan, routes []PreferentialRoute) (*PreferentialRoute, bool) {
+	if fp == nil || fp.Rules != IFR {
+		return nil, true
+	}
+
+	var applies *PreferentialRoute
+	for i := range routes {
+		if pr := &routes[i]; pr.Applies(fp) {
+			if pr.Conforms(fp) {
+				return pr, true
+			} else if applies == nil {
+				applies = pr
+			}
+		}
+	}
+	return applies, applies == nil
+}
+
+// RouteConformance returns the preferential route that the aircraft's
+// filed route doesn't conform to, if any.
+func (w *World) RouteConformance(ac *Aircraft) (*PreferentialRoute, bool) {
+	return checkRouteConformance(ac.FlightPlan, w.PreferentialRoutes)
+}
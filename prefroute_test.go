@@ -0,0 +1,63 @@
+// prefroute_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRouteElements(t *testing.T) {
+	got := routeElements("KJFK DCT MERIT.J60 PSB/N0450F350 DCT KORD", "KJFK", "KORD")
+	if expected := []string{"MERIT", "J60", "PSB"}; !slices.Equal(got, expected) {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+}
+
+func TestRouteConformance(t *testing.T) {
+	routes := []PreferentialRoute{
+		{Type: "PDR", Departure: "KJFK", Arrival: "KORD", Route: "MERIT J60 PSB", Altitudes: [2]int{18000, 0}},
+		{Type: "PDR", Departure: "KJFK", Arrival: "KORD", Route: "MERIT ROBER", Altitudes: [2]int{0, 17000}},
+		{Type: "PAR", Arrival: "KJFK", Route: "CAMRN4"},
+	}
+
+	for _, test := range []struct {
+		fp         FlightPlan
+		conforming bool
+		route      int // index of the route expected to be returned; -1 for none
+	}{
+		// Conforming PDR, high and low.
+		{FlightPlan{Rules: IFR, DepartureAirport: "KJFK", ArrivalAirport: "KORD", Altitude: 35000,
+			Route: "MERIT J60 PSB J146 GIJ"}, true, 0},
+		{FlightPlan{Rules: IFR, DepartureAirport: "KJFK", ArrivalAirport: "KORD", Altitude: 12000,
+			Route: "KJFK DCT MERIT ROBER DCT KORD"}, true, 1},
+		// The route for the other altitude doesn't count.
+		{FlightPlan{Rules: IFR, DepartureAirport: "KJFK", ArrivalAirport: "KORD", Altitude: 12000,
+			Route: "MERIT J60 PSB"}, false, 1},
+		// Doesn't start with the PDR.
+		{FlightPlan{Rules: IFR, DepartureAirport: "KJFK", ArrivalAirport: "KORD", Altitude: 35000,
+			Route: "GAYEL J95 PSB"}, false, 0},
+		// No PDR to other destinations.
+		{FlightPlan{Rules: IFR, DepartureAirport: "KJFK", ArrivalAirport: "KBOS", Altitude: 35000,
+			Route: "GAYEL J95"}, true, -1},
+		// PARs must be at the end of the route.
+		{FlightPlan{Rules: IFR, DepartureAirport: "KDCA", ArrivalAirport: "KJFK", Altitude: 21000,
+			Route: "SWANN V268 CAMRN4 KJFK"}, true, 2},
+		{FlightPlan{Rules: IFR, DepartureAirport: "KDCA", ArrivalAirport: "KJFK", Altitude: 21000,
+			Route: "CAMRN4 SWANN"}, false, 2},
+		// VFR aircraft aren't checked.
+		{FlightPlan{Rules: VFR, DepartureAirport: "KDCA", ArrivalAirport: "KJFK", Route: "DCT"}, true, -1},
+	} {
+		pr, ok := checkRouteConformance(&test.fp, routes)
+		if ok != test.conforming {
+			t.Errorf("%s %s-%s %d: got conforming %v, expected %v", test.fp.Route, test.fp.DepartureAirport,
+				test.fp.ArrivalAirport, test.fp.Altitude, ok, test.conforming)
+		}
+		if (test.route == -1 && pr != nil) || (test.route != -1 && pr != &routes[test.route]) {
+			t.Errorf("%s %s-%s %d: got route %+v, expected %d", test.fp.Route, test.fp.DepartureAirport,
+				test.fp.ArrivalAirport, test.fp.Altitude, pr, test.route)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// radaroutage.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// RadarOutage takes either a radar site or all radar coverage in an area
+// out of service for a while. Tracks that aren't seen by another site
+// coast and are then dropped, so controllers have to use position reports
+// and non-radar separation until service is restored.
+type RadarOutage struct {
+	// Exactly one of Site or Fix is set. Area outages cover everything
+	// within Radius nm of Fix.
+	Site    string
+	Fix     string
+	Center  Point2LL // set from Fix by the Sim
+	Radius  float32
+	EndTime time.Time // sim time
+}
+
+func (ro RadarOutage) String() string {
+	if ro.Site != "" {
+		return "radar site " + ro.Site
+	}
+	return fmt.Sprintf("radar coverage within %.0fnm of %s", ro.Radius, ro.Fix)
+}
+
+// TriggerRadarOutage starts the given outage, which lasts for the given
+// number of minutes. Only the launch controller may trigger outages.
+func (s *Sim) TriggerRadarOutage(token string, ro RadarOutage, minutes int) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	if ctrl, ok := s.controllers[token]; !ok {
+		return ErrInvalidControllerToken
+	} else if ctrl.Callsign != s.LaunchConfig.Controller {
+		return ErrNotLaunchController
+	}
+
+	if ro.Site != "" {
+		if _, ok := s.World.RadarSites[ro.Site]; !ok {
+			return ErrUnknownRadarSite
+		}
+	} else {
+		ro.Fix = strings.ToUpper(ro.Fix)
+		var ok bool
+		if ro.Center, ok = s.World.Locate(ro.Fix); !ok || ro.Radius <= 0 {
+			return ErrInvalidCommandSyntax
+		}
+	}
+	ro.EndTime = s.SimTime.Add(time.Duration(minutes) * time.Minute)
+
+	s.RadarOutages = append(s.RadarOutages, ro)
+	s.lg.Info("radar outage", slog.String("outage", ro.String()), slog.Time("end", ro.EndTime))
+	s.eventStream.Post(Event{
+		Type:    StatusMessageEvent,
+		Message: ro.String() + " is out of service",
+	})
+	return nil
+}
+
+// updateRadarOutages restores service for the outages whose time is up.
+// s.mu must be held.
+func (s *Sim) updateRadarOutages() {
+	s.RadarOutages = slices.DeleteFunc(s.RadarOutages, func(ro RadarOutage) bool {
+		if s.SimTime.Before(ro.EndTime) {
+			return false
+		}
+		s.eventStream.Post(Event{
+			Type:    StatusMessageEvent,
+			Message: ro.String() + " has been restored",
+		})
+		return true
+	})
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Client side
+
+func (w *World) TriggerRadarOutage(ro RadarOutage, minutes int, success func(any), err func(error)) {
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.TriggerRadarOutage(ro, minutes),
+			IssueTime: time.Now(),
+			OnSuccess: success,
+			OnErr:     err,
+		})
+}
+
+// RadarSiteInService indicates whether the radar site with the given
+// identifier is currently working.
+func (w *World) RadarSiteInService(id string) bool {
+	return !slices.ContainsFunc(w.RadarOutages, func(ro RadarOutage) bool { return ro.Site == id })
+}
+
+// InRadarOutageArea indicates whether the given point is in an area where
+// there's no radar coverage.
+func (w *World) InRadarOutageArea(p Point2LL) bool {
+	return slices.ContainsFunc(w.RadarOutages, func(ro RadarOutage) bool {
+		return ro.Site == "" && nmdistance2ll(p, ro.Center) < ro.Radius
+	})
+}
+
+// InRadarCoverage indicates whether an aircraft at the given position and
+// altitude can be tracked: it's not in an area with a radar outage nor in
+// one of the facility's coverage gaps (e.g., low altitudes behind
+// terrain).
+func (w *World) InRadarCoverage(p Point2LL, alt int) bool {
+	if w.InRadarOutageArea(p) {
+		return false
+	}
+	return !slices.ContainsFunc(w.STARSFacilityAdaptation.RadarCoverageGaps,
+		func(vol AirspaceVolume) bool { return vol.Inside(p, alt) })
+}
+
+// drawRadarOutageTrigger draws the launch control UI for taking a radar
+// site or area out of service.
+func (lc *LaunchControlWindow) drawRadarOutageTrigger(eventStream *EventStream) {
+	const area = "Area"
+	label := Select(lc.radarOutage.Site == "", area, lc.radarOutage.Site)
+
+	imgui.SetNextItemWidth(150)
+	if imgui.BeginComboV("##outageSite", label, 0) {
+		if imgui.SelectableV(area, lc.radarOutage.Site == "", 0, imgui.Vec2{}) {
+			lc.radarOutage.Site = ""
+		}
+		for _, id := range SortedMapKeys(lc.w.RadarSites) {
+			if imgui.SelectableV(id, id == lc.radarOutage.Site, 0, imgui.Vec2{}) {
+				lc.radarOutage.Site = id
+			}
+		}
+		imgui.EndCombo()
+	}
+	if lc.radarOutage.Site == "" {
+		imgui.SameLine()
+		imgui.SetNextItemWidth(100)
+		imgui.InputTextV("Fix##outageFix", &lc.radarOutage.Fix, imgui.InputTextFlagsCharsUppercase, nil)
+		imgui.SameLine()
+		imgui.SetNextItemWidth(100)
+		imgui.SliderFloatV("##outageRadius", &lc.radarOutage.Radius, 5, 60, "%.0f nm", 0)
+	}
+	imgui.SameLine()
+	imgui.SetNextItemWidth(100)
+	imgui.SliderIntV("##outageMinutes", &lc.radarOutageMinutes, 1, 30, "%d min", 0)
+	imgui.SameLine()
+	disable := lc.radarOutage.Site == "" && lc.radarOutage.Fix == ""
+	uiStartDisable(disable)
+	if imgui.Button("Radar outage") {
+		lc.w.TriggerRadarOutage(lc.radarOutage, int(lc.radarOutageMinutes), nil,
+			func(err error) {
+				eventStream.Post(Event{Type: StatusMessageEvent, Message: err.Error()})
+			})
+	}
+	uiEndDisable(disable)
+}
@@ -0,0 +1,60 @@
+// radaroutage_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInRadarCoverage(t *testing.T) {
+	w := &World{
+		RadarOutages: []RadarOutage{{Center: Point2LL{1, 0}, Radius: 5}},
+	}
+	w.STARSFacilityAdaptation.RadarCoverageGaps = []AirspaceVolume{
+		{Type: AirspaceVolumeCircle, Center: Point2LL{0, 0}, Radius: 10, Floor: 0, Ceiling: 3000},
+	}
+
+	for _, test := range []struct {
+		p        Point2LL
+		alt      int
+		expected bool
+	}{
+		{p: Point2LL{0, 0}, alt: 2000, expected: false},  // in the gap
+		{p: Point2LL{0, 0}, alt: 5000, expected: true},   // above it
+		{p: Point2LL{0, 1}, alt: 2000, expected: true},   // outside of it
+		{p: Point2LL{1, 0}, alt: 20000, expected: false}, // radar outage
+		{p: Point2LL{-1, 0}, alt: 20000, expected: true}, // clear
+	} {
+		if c := w.InRadarCoverage(test.p, test.alt); c != test.expected {
+			t.Errorf("%v at %d: got coverage %v, expected %v", test.p, test.alt, c, test.expected)
+		}
+	}
+}
+
+func TestTrackCoasting(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	var state STARSAircraftState
+	if state.Coasting(now) || state.LostTrack(now) {
+		t.Errorf("aircraft without a track shouldn't coast or be lost")
+	}
+
+	state.track = RadarTrack{Position: Point2LL{1, 1}, Time: now}
+	for _, test := range []struct {
+		elapsed        time.Duration
+		coasting, lost bool
+	}{
+		{elapsed: 5 * time.Second},
+		{elapsed: 20 * time.Second, coasting: true},
+		{elapsed: 45 * time.Second, lost: true},
+	} {
+		t1 := now.Add(test.elapsed)
+		if c, l := state.Coasting(t1), state.LostTrack(t1); c != test.coasting || l != test.lost {
+			t.Errorf("after %s: got coasting %v lost %v, expected %v %v", test.elapsed, c, l,
+				test.coasting, test.lost)
+		}
+	}
+}
@@ -10,12 +10,17 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
+	_ "image/jpeg"
 	"image/png"
 	"log/slog"
 	"math"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -30,18 +35,53 @@ type WeatherRadar struct {
 
 	// Radar images are fetched and processed in a separate goroutine;
 	// updated radar center locations are sent from the main thread via
-	// reqChan and command buffers to draw each of the 6 weather levels are
-	// returned by cbChan.
-	reqChan chan Point2LL
-	cbChan  chan [NumWxLevels]CommandBuffer
+	// reqChan and frames with the command buffers to draw each of the 6
+	// weather levels are returned by frameChan.
+	reqChan   chan Point2LL
+	frameChan chan WeatherFrame
 
 	// Texture id for each wx level's image.
 	texId [NumWxLevels]uint32
-	wxCb  [NumWxLevels]CommandBuffer
+
+	// The most recent frames, oldest first, and the index of the one that
+	// was drawn most recently.
+	frames    []WeatherFrame
+	displayed int
+	animating bool
+}
+
+// WeatherFrame is a single processed radar image.
+type WeatherFrame struct {
+	Time time.Time // when it was fetched
+	cb   [NumWxLevels]CommandBuffer
 }
 
+// NumWxLevels is the number of NWS precipitation intensity levels.
 const NumWxLevels = 6
 
+// wxLevelDBZ gives the minimum reflectivity in dBZ for each of the NWS
+// intensity levels: light, moderate, heavy, very heavy, intense, and
+// extreme.
+var wxLevelDBZ = [NumWxLevels]float32{18, 30, 41, 46, 50, 57}
+
+// Range of reflectivities covered by the NOAA color map.
+const (
+	wxColorMapMinDBZ = -30
+	wxColorMapMaxDBZ = 75
+)
+
+// WxMaxFrames is the number of recent frames that are kept for
+// animation; frames fetched less than WxMinFrameInterval after the
+// previous one (e.g., after the scope has been recentered) replace it.
+const WxMaxFrames = 8
+const WxMinFrameInterval = 90 * time.Second
+
+// When the weather is animated, each frame is shown for WxAnimationDwell
+// and the most recent one is held for wxAnimationHold times as long
+// before the loop restarts.
+const WxAnimationDwell = 500 * time.Millisecond
+const wxAnimationHold = 4
+
 // Block size in pixels of the quads in the converted radar image used for
 // display.
 const WxBlockRes = 4
@@ -62,14 +102,16 @@ func (w *WeatherRadar) Activate(center Point2LL, r Renderer) {
 
 	w.reqChan = make(chan Point2LL, 1000) // lots of buffering
 	w.reqChan <- center
-	w.cbChan = make(chan [NumWxLevels]CommandBuffer, 8)
+	w.frameChan = make(chan WeatherFrame, 8)
 
 	if w.texId[0] == 0 {
 		// Create a small texture for each weather level
 		img := image.NewRGBA(image.Rectangle{Max: image.Point{X: WxBlockRes, Y: WxBlockRes}})
 
 		for i := 0; i < NumWxLevels; i++ {
-			// RGBs from STARS Manual, B-5
+			// RGBs from STARS Manual, B-5: levels 1-3 are dark gray-blue
+			// and 4-6 are dark mustard, with no stipple, a light
+			// stipple, and a dense stipple, respectively.
 			baseColor := Select(i < 3, color.RGBA{R: 37, G: 77, B: 77, A: 255},
 				color.RGBA{R: 100, G: 100, B: 51, A: 255})
 			stipple := i % 3
@@ -100,7 +142,7 @@ func (w *WeatherRadar) Activate(center Point2LL, r Renderer) {
 		}
 	}
 
-	go fetchWeather(w.reqChan, w.cbChan)
+	go fetchWeather(w.reqChan, w.frameChan)
 }
 
 // Deactivate causes the WeatherRadar to stop fetching weather updates.
@@ -269,9 +311,9 @@ func invertRadarReflectivity(rgb [3]byte) float32 {
 
 // fetchWeather runs asynchronously in a goroutine, receiving requests from
 // reqChan, fetching corresponding radar images from the NOAA, and sending
-// the results back on cbChan.  New images are also automatically
+// the results back on frameChan.  New images are also automatically
 // fetched periodically, with a wait time specified by the delay parameter.
-func fetchWeather(reqChan chan Point2LL, cbChan chan [NumWxLevels]CommandBuffer) {
+func fetchWeather(reqChan chan Point2LL, frameChan chan WeatherFrame) {
 	// NOAA posts new maps every 2 minutes, so fetch a new map at minimum
 	// every 100s to stay current.
 	fetchRate := 100 * time.Second
@@ -291,7 +333,7 @@ func fetchWeather(reqChan chan Point2LL, cbChan chan [NumWxLevels]CommandBuffer)
 				}
 			} else {
 				// The channel is closed; wrap up.
-				close(cbChan)
+				close(frameChan)
 				return
 			}
 		case <-time.After(fetchRate):
@@ -345,12 +387,24 @@ func fetchWeather(reqChan chan Point2LL, cbChan chan [NumWxLevels]CommandBuffer)
 		}
 
 		// Send the command buffers back to the main thread.
-		cbChan <- makeWeatherCommandBuffers(img, rb)
+		frameChan <- WeatherFrame{Time: time.Now(), cb: makeWeatherCommandBuffers(img, rb)}
 
 		lg.Info("finish weather fetch")
 	}
 }
 
+// weatherLevel returns the NWS intensity level in [0,6] for the given
+// position in the NOAA reflectivity color map, where 0 means no
+// precipitation.
+func weatherLevel(refl float32) int {
+	dbz := lerp(refl, wxColorMapMinDBZ, wxColorMapMaxDBZ)
+	level := 0
+	for level < NumWxLevels && dbz >= wxLevelDBZ[level] {
+		level++
+	}
+	return level
+}
+
 func makeWeatherCommandBuffers(img image.Image, rb Extent2D) [NumWxLevels]CommandBuffer {
 	// Convert the Image returned by png.Decode to a simple 8-bit RGBA image.
 	rgba := image.NewRGBA(img.Bounds())
@@ -376,9 +430,7 @@ func makeWeatherCommandBuffers(img image.Image, rb Extent2D) [NumWxLevels]Comman
 				}
 			}
 
-			// levels from [0,6].
-			level := int(min(avg*7/(WxBlockRes*WxBlockRes), 6))
-			levels[x+y*nbx] = level
+			levels[x+y*nbx] = weatherLevel(avg / (WxBlockRes * WxBlockRes))
 		}
 	}
 
@@ -434,32 +486,228 @@ func makeWeatherCommandBuffers(img image.Image, rb Extent2D) [NumWxLevels]Comman
 	return cb
 }
 
+// addWeatherFrame adds a newly-fetched frame to frames, returning the
+// updated frames.
+func addWeatherFrame(frames []WeatherFrame, f WeatherFrame) []WeatherFrame {
+	if n := len(frames); n > 0 && f.Time.Sub(frames[n-1].Time) < WxMinFrameInterval {
+		frames[n-1] = f
+		return frames
+	}
+	frames = append(frames, f)
+	if len(frames) > WxMaxFrames {
+		frames = frames[len(frames)-WxMaxFrames:]
+	}
+	return frames
+}
+
+// weatherAnimationFrame returns the index of the frame to show at time t
+// when animating n frames.
+func weatherAnimationFrame(n int, t time.Duration) int {
+	if n <= 1 {
+		return n - 1
+	}
+	step := int(t/WxAnimationDwell) % (n - 1 + wxAnimationHold)
+	return min(step, n-1)
+}
+
 // Draw draws the current weather radar image, if available. (If none is yet
-// available, it returns rather than stalling waiting for it).
+// available, it returns rather than stalling waiting for it). If animate
+// is set, it loops through the recent images instead.
 func (w *WeatherRadar) Draw(ctx *PaneContext, intensity float32, contrast float32,
-	active [NumWxLevels]bool, transforms ScopeTransformations, cb *CommandBuffer) {
-	select {
-	case w.wxCb = <-w.cbChan:
-		// got updated command buffers, yaay.  Note that we always go ahead
-		// and drain the cbChan, even if if the WeatherRadar is inactive.
+	active [NumWxLevels]bool, animate bool, transforms ScopeTransformations, cb *CommandBuffer) {
+	// Note that we always go ahead and drain the frameChan, even if if
+	// the WeatherRadar is inactive.
+	for len(w.frameChan) > 0 {
+		if f, ok := <-w.frameChan; ok {
+			w.frames = addWeatherFrame(w.frames, f)
+		}
+	}
 
-	default:
-		// no message
+	if !w.active || len(w.frames) == 0 {
+		return
 	}
 
-	if w.active {
-		transforms.LoadLatLongViewingMatrices(cb)
-		cb.SetRGBA(RGBA{1, 1, 1, intensity})
-		cb.Blend()
-		for i, wcb := range w.wxCb {
-			if active[i] {
-				cb.EnableTexture(w.texId[i])
-				cb.Call(wcb)
-				cb.DisableTexture()
-			}
+	w.animating = animate && len(w.frames) > 1
+	w.displayed = len(w.frames) - 1
+	if w.animating {
+		w.displayed = weatherAnimationFrame(len(w.frames), time.Duration(time.Now().UnixNano()))
+	}
+
+	transforms.LoadLatLongViewingMatrices(cb)
+	cb.SetRGBA(RGBA{1, 1, 1, intensity})
+	cb.Blend()
+	for i, wcb := range w.frames[w.displayed].cb {
+		if active[i] {
+			cb.EnableTexture(w.texId[i])
+			cb.Call(wcb)
+			cb.DisableTexture()
+		}
+	}
+	cb.DisableBlend()
+}
+
+// StatusText returns a summary of the most recently drawn frame for the
+// SSA: the time it was fetched and its age in minutes and, if the weather
+// is being animated, its position in the loop. It is empty if no weather
+// is being displayed.
+func (w *WeatherRadar) StatusText(now time.Time) string {
+	if !w.active || w.displayed >= len(w.frames) {
+		return ""
+	}
+	f := w.frames[w.displayed]
+	s := fmt.Sprintf("WX %s %dM", f.Time.UTC().Format("1504"), int(now.Sub(f.Time).Minutes()))
+	if w.animating {
+		s += fmt.Sprintf(" LOOP %d/%d", w.displayed+1, len(w.frames))
+	}
+	return s
+}
+
+///////////////////////////////////////////////////////////////////////////
+// RasterUnderlay
+
+// RasterUnderlay is a georeferenced image--a VFR sectional chart, an
+// OpenStreetMap tile, etc.--that is drawn underneath everything else on a
+// radar scope.
+type RasterUnderlay struct {
+	Filename string
+	// SW and NE give the latitude-longitude of the image's corners, in any
+	// format understood by ParseLatLong. They may be left empty for
+	// OpenStreetMap tiles stored using the usual .../z/x/y.png layout, in
+	// which case the bounds are computed from the tile indices.
+	SW, NE     string
+	Visible    bool
+	Brightness float32 // [0,1]
+
+	// Set when the image is (re)loaded.
+	texId  uint32
+	bounds [2]Point2LL
+	err    error
+	loaded bool
+}
+
+// Reload causes the underlay's image to be loaded again the next time
+// it is drawn, e.g., after its filename or bounds have changed.
+func (u *RasterUnderlay) Reload() {
+	u.loaded = false
+}
+
+// Error returns the error, if any, from the most recent attempt to load
+// the underlay.
+func (u *RasterUnderlay) Error() error {
+	return u.err
+}
+
+func (u *RasterUnderlay) load(r Renderer) error {
+	sw, ne, err := u.getBounds()
+	if err != nil {
+		return err
+	}
+	if sw[0] >= ne[0] || sw[1] >= ne[1] {
+		return fmt.Errorf("%s, %s: SW corner must be south and west of the NE corner", u.SW, u.NE)
+	}
+
+	f, err := os.Open(u.Filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", u.Filename, err)
+	}
+
+	if u.texId == 0 {
+		u.texId = r.CreateTextureFromImage(img, false)
+	} else {
+		r.UpdateTextureFromImage(u.texId, img, false)
+	}
+	u.bounds = [2]Point2LL{sw, ne}
+
+	return nil
+}
+
+func (u *RasterUnderlay) getBounds() (sw, ne Point2LL, err error) {
+	if u.SW == "" && u.NE == "" {
+		// Try to interpret the path as an OpenStreetMap tile.
+		f := strings.Split(filepath.ToSlash(filepath.Clean(u.Filename)), "/")
+		if len(f) < 3 {
+			return Point2LL{}, Point2LL{}, fmt.Errorf("%s: bounds must be specified for images that aren't OpenStreetMap tiles",
+				u.Filename)
+		}
+		f = f[len(f)-3:]
+		zs, xs, ys := f[0], f[1], strings.TrimSuffix(f[2], filepath.Ext(f[2]))
+
+		z, zerr := strconv.Atoi(zs)
+		x, xerr := strconv.Atoi(xs)
+		y, yerr := strconv.Atoi(ys)
+		if zerr != nil || xerr != nil || yerr != nil {
+			return Point2LL{}, Point2LL{}, fmt.Errorf("%s: bounds must be specified for images that aren't OpenStreetMap tiles",
+				u.Filename)
+		}
+		sw, ne = OSMTileBounds(z, x, y)
+		return
+	}
+
+	if sw, err = ParseLatLong([]byte(u.SW)); err != nil {
+		return
+	}
+	ne, err = ParseLatLong([]byte(u.NE))
+	return
+}
+
+// Draw draws the underlay, loading its image first if necessary.
+func (u *RasterUnderlay) Draw(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
+	if !u.loaded {
+		u.loaded = true
+		if u.err = u.load(ctx.renderer); u.err != nil {
+			lg.Errorf("%s: unable to load underlay: %v", u.Filename, u.err)
 		}
-		cb.DisableBlend()
 	}
+	if !u.Visible || u.err != nil || u.texId == 0 {
+		return
+	}
+
+	tb := GetTexturedTrianglesDrawBuilder()
+	defer ReturnTexturedTrianglesDrawBuilder(tb)
+
+	// The first row of the image is its northern edge.
+	sw, ne := u.bounds[0], u.bounds[1]
+	tb.AddQuad([2]float32{sw[0], sw[1]}, [2]float32{ne[0], sw[1]}, [2]float32{ne[0], ne[1]}, [2]float32{sw[0], ne[1]},
+		[2]float32{0, 1}, [2]float32{1, 1}, [2]float32{1, 0}, [2]float32{0, 0})
+
+	transforms.LoadLatLongViewingMatrices(cb)
+	cb.SetRGBA(RGBA{1, 1, 1, u.Brightness})
+	cb.Blend()
+	cb.EnableTexture(u.texId)
+	tb.GenerateCommands(cb)
+	cb.DisableTexture()
+	cb.DisableBlend()
+}
+
+// Free releases the underlay's texture, if it has one.
+func (u *RasterUnderlay) Free(r Renderer) {
+	if u.texId != 0 {
+		r.DestroyTexture(u.texId)
+		u.texId = 0
+	}
+	u.loaded = false
+}
+
+// OSMTileBounds returns the latitude-longitude of the south-west and
+// north-east corners of the given OpenStreetMap (web Mercator) tile.
+func OSMTileBounds(z, x, y int) (sw, ne Point2LL) {
+	n := math.Exp2(float64(z))
+	long := func(x int) float32 {
+		return float32(float64(x)/n*360 - 180)
+	}
+	lat := func(y int) float32 {
+		return float32(math.Atan(math.Sinh(math.Pi*(1-2*float64(y)/n))) * 180 / math.Pi)
+	}
+	// Tile y indices increase going south.
+	sw = Point2LL{long(x), lat(y + 1)}
+	ne = Point2LL{long(x + 1), lat(y)}
+	return
 }
 
 ///////////////////////////////////////////////////////////////////////////
@@ -697,7 +945,9 @@ func DrawHighlighted(ctx *PaneContext, transforms ScopeTransformations, cb *Comm
 // DrawMinimumSeparationLine estimates the time at which the given two
 // aircraft will be the closest together and then draws lines indicating
 // where they will be at that point and also text indicating their
-// estimated separation then.
+// estimated separation then, the time until then, and their current
+// closure rate. The direction vectors d0 and d1 should give the
+// aircrafts' motion over one minute.
 func DrawMinimumSeparationLine(p0ll, d0ll, p1ll, d1ll Point2LL, nmPerLongitude float32, color RGB, backgroundColor RGB,
 	font *Font, ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
 	p0, d0 := ll2nm(p0ll, nmPerLongitude), ll2nm(d0ll, nmPerLongitude)
@@ -758,7 +1008,12 @@ func DrawMinimumSeparationLine(p0ll, d0ll, p1ll, d1ll Point2LL, nmPerLongitude f
 	text := fmt.Sprintf("%.2f nm", nmdistance2ll(p0tmin, p1tmin))
 	if tmin < 0 {
 		text = "NO XING\n" + text
+	} else {
+		sec := int(60*tmin + 0.5)
+		text += fmt.Sprintf("\n%d:%02d", sec/60, sec%60)
 	}
+	// d0 and d1 are per minute, so scale to get knots.
+	text += fmt.Sprintf("\n%+d KT", int(60*RayRayClosureRate(p0, d0, p1, d1)))
 	td.AddTextCentered(text, pText, style)
 
 	// Add the corresponding drawing commands to the CommandBuffer.
@@ -0,0 +1,66 @@
+// radartools_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWeatherLevel(t *testing.T) {
+	refl := func(dbz float32) float32 {
+		return (dbz - wxColorMapMinDBZ) / (wxColorMapMaxDBZ - wxColorMapMinDBZ)
+	}
+	for _, test := range []struct {
+		dbz   float32
+		level int
+	}{
+		{-30, 0}, {10, 0}, {18.5, 1}, {29, 1}, {30.5, 2}, {40, 2}, {41.5, 3},
+		{46.5, 4}, {49, 4}, {50.5, 5}, {56, 5}, {57.5, 6}, {75, 6},
+	} {
+		if l := weatherLevel(refl(test.dbz)); l != test.level {
+			t.Errorf("%.1f dBZ: got level %d, expected %d", test.dbz, l, test.level)
+		}
+	}
+}
+
+func TestAddWeatherFrame(t *testing.T) {
+	start := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+	var frames []WeatherFrame
+	for i := 0; i < WxMaxFrames+3; i++ {
+		frames = addWeatherFrame(frames, WeatherFrame{Time: start.Add(time.Duration(i) * 2 * time.Minute)})
+	}
+	if len(frames) != WxMaxFrames {
+		t.Fatalf("got %d frames, expected %d", len(frames), WxMaxFrames)
+	}
+	if !frames[0].Time.Equal(start.Add(6 * time.Minute)) {
+		t.Errorf("oldest frame is from %s, expected %s", frames[0].Time, start.Add(6*time.Minute))
+	}
+
+	// A frame fetched soon after the last one replaces it.
+	last := frames[len(frames)-1].Time
+	frames = addWeatherFrame(frames, WeatherFrame{Time: last.Add(20 * time.Second)})
+	if len(frames) != WxMaxFrames || !frames[len(frames)-1].Time.Equal(last.Add(20*time.Second)) {
+		t.Errorf("recent frame wasn't replaced")
+	}
+}
+
+func TestWeatherAnimationFrame(t *testing.T) {
+	if f := weatherAnimationFrame(1, 7*time.Second); f != 0 {
+		t.Errorf("got frame %d for a single frame, expected 0", f)
+	}
+
+	var got []int
+	for i := 0; i < 2*(3+wxAnimationHold); i++ {
+		got = append(got, weatherAnimationFrame(4, time.Duration(i)*WxAnimationDwell))
+	}
+	expected := []int{0, 1, 2, 3, 3, 3, 3, 0, 1, 2, 3, 3, 3, 3}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Errorf("got frames %v, expected %v", got, expected)
+			break
+		}
+	}
+}
@@ -0,0 +1,394 @@
+// randomevents.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// RandomEventType enumerates the miscellaneous events that scenarios may
+// enable to add some variety to a session.
+type RandomEventType int
+
+const (
+	// Bird activity near the airport causes arrivals on final to go
+	// around.
+	RandomEventBirds RandomEventType = iota
+	// A pilot reports a drone on final and the final is closed for a few
+	// minutes.
+	RandomEventDrone
+	// A pilot reports being illuminated by a laser.
+	RandomEventLaser
+	// A parachute jumping area goes active.
+	RandomEventParachute
+	NumRandomEventTypes
+)
+
+func (e RandomEventType) String() string {
+	return []string{"Bird activity", "Drone sighting", "Laser strike", "Parachute jumping"}[e]
+}
+
+// ScenarioRandomEvents specifies which of the random events may happen in
+// a scenario.
+type ScenarioRandomEvents struct {
+	Rate           float32         `json:"rate"`   // default number per hour
+	Birds          []string        `json:"birds"`  // airports with bird activity
+	Drones         []string        `json:"drones"` // airports whose finals may have drones
+	Lasers         bool            `json:"lasers"`
+	ParachuteAreas []ParachuteArea `json:"parachute_areas"`
+}
+
+type ParachuteArea struct {
+	Name     string   `json:"name"`
+	Location Point2LL `json:"location"`
+	Radius   float32  `json:"radius"`  // nm
+	Ceiling  int      `json:"ceiling"` // feet MSL
+}
+
+// RandomEvent is a random event that is in progress.
+type RandomEvent struct {
+	Type    RandomEventType
+	Airport string
+	Runway  string // the runway whose final is closed for drones
+	Area    string // the parachute area
+	EndTime time.Time
+	// Aircraft that the event has already affected so that each is only
+	// affected (or, for parachute areas, counted as an incursion) once.
+	Callsigns []string
+}
+
+const (
+	birdGoAroundProbability = 0.25
+	droneReportRange        = 15 // nm from the airport
+)
+
+func (re *ScenarioRandomEvents) PostDeserialize(sg *ScenarioGroup, e *ErrorLogger) {
+	if re.Rate < 0 {
+		e.ErrorString("\"rate\" must be positive")
+	}
+	for _, ap := range re.Birds {
+		if _, ok := sg.Airports[ap]; !ok {
+			e.ErrorString("\"birds\" airport \"%s\" not found in the scenario group's \"airports\"", ap)
+		}
+	}
+	for _, ap := range re.Drones {
+		if _, ok := sg.Airports[ap]; !ok {
+			e.ErrorString("\"drones\" airport \"%s\" not found in the scenario group's \"airports\"", ap)
+		}
+	}
+	for i, area := range re.ParachuteAreas {
+		e.Push(fmt.Sprintf("\"parachute_areas\" %d", i))
+		if area.Name == "" {
+			e.ErrorString("\"name\" must be specified")
+		}
+		if area.Location.IsZero() {
+			e.ErrorString("\"location\" must be specified")
+		}
+		if area.Radius <= 0 {
+			e.ErrorString("\"radius\" must be positive")
+		}
+		if area.Ceiling <= 0 {
+			e.ErrorString("\"ceiling\" must be positive")
+		}
+		e.Pop()
+	}
+}
+
+// Types returns the random events that are enabled.
+func (re *ScenarioRandomEvents) Types() []RandomEventType {
+	var types []RandomEventType
+	if len(re.Birds) > 0 {
+		types = append(types, RandomEventBirds)
+	}
+	if len(re.Drones) > 0 {
+		types = append(types, RandomEventDrone)
+	}
+	if re.Lasers {
+		types = append(types, RandomEventLaser)
+	}
+	if len(re.ParachuteAreas) > 0 {
+		types = append(types, RandomEventParachute)
+	}
+	return types
+}
+
+// TriggerRandomEvent starts the given random event. Only the launch
+// controller may trigger random events.
+func (s *Sim) TriggerRandomEvent(token string, kind RandomEventType) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	if ctrl, ok := s.controllers[token]; !ok {
+		return ErrInvalidControllerToken
+	} else if ctrl.Callsign != s.LaunchConfig.Controller {
+		return ErrNotLaunchController
+	} else if !slices.Contains(s.World.RandomEvents.Types(), kind) {
+		return ErrRandomEventNotEnabled
+	} else {
+		return s.startRandomEvent(kind)
+	}
+}
+
+// humanAircraft returns the callsigns of the airborne aircraft that a
+// human controller is working that satisfy the given predicate. s.mu must
+// be held.
+func (s *Sim) humanAircraft(pred func(*Aircraft) bool) []string {
+	return FilterSlice(SortedMapKeys(s.World.Aircraft), func(callsign string) bool {
+		ac := s.World.Aircraft[callsign]
+		return ac.FlightPlan != nil && !ac.BackgroundTraffic && ac.Nav.IsAirborne() &&
+			s.controllerIsSignedIn(ac.ControllingController) && pred(ac)
+	})
+}
+
+// startRandomEvent starts the given random event. s.mu must be held.
+func (s *Sim) startRandomEvent(kind RandomEventType) error {
+	re := &s.World.RandomEvents
+	ev := RandomEvent{Type: kind}
+	var pilot *Aircraft
+	var report, status string
+
+	switch kind {
+	case RandomEventBirds:
+		ev.Airport = re.Birds[rand.Intn(len(re.Birds))]
+		ev.EndTime = s.SimTime.Add(time.Duration(10+rand.Intn(11)) * time.Minute)
+		// An arrival on final reports the birds and goes around.
+		callsigns := s.humanAircraft(func(ac *Aircraft) bool {
+			d, err := ac.Nav.distanceToEndOfApproach()
+			return ac.FlightPlan.ArrivalAirport == ev.Airport && ac.Nav.Approach.Cleared &&
+				ac.GoAroundDistance == nil && err == nil && d > 2 && d < 12
+		})
+		if len(callsigns) > 0 {
+			pilot = s.World.Aircraft[callsigns[rand.Intn(len(callsigns))]]
+			s.birdGoAround(pilot)
+			ev.Callsigns = append(ev.Callsigns, pilot.Callsign)
+			report = "we've got a big flock of birds ahead of us on the final, we may have to go around"
+		}
+		status = fmt.Sprintf("%s: bird activity reported in the vicinity of the airport", ev.Airport)
+
+	case RandomEventDrone:
+		ev.Airport = re.Drones[rand.Intn(len(re.Drones))]
+		var runways []string
+		for _, rwy := range s.World.ArrivalRunways {
+			if rwy.Airport == ev.Airport && !slices.Contains(runways, rwy.Runway) {
+				runways = append(runways, rwy.Runway)
+			}
+		}
+		if len(runways) == 0 {
+			return ErrNoAircraftForRandomEvent
+		}
+		ev.Runway = runways[rand.Intn(len(runways))]
+		ev.EndTime = s.SimTime.Add(time.Duration(5+rand.Intn(6)) * time.Minute)
+
+		if ap := s.World.GetAirport(ev.Airport); ap != nil {
+			callsigns := s.humanAircraft(func(ac *Aircraft) bool {
+				return ac.FlightPlan.ArrivalAirport == ev.Airport &&
+					nmdistance2ll(ac.Position(), ap.Location) < droneReportRange
+			})
+			if len(callsigns) > 0 {
+				pilot = s.World.Aircraft[callsigns[rand.Intn(len(callsigns))]]
+				alt := 100 * (5 + rand.Intn(20))
+				report = fmt.Sprintf("we just passed a drone at about %d feet on the final for runway %s",
+					alt, ev.Runway)
+			}
+		}
+		status = fmt.Sprintf("%s: runway %s final closed for drone activity until %s", ev.Airport, ev.Runway,
+			ev.EndTime.UTC().Format("1504Z"))
+
+	case RandomEventLaser:
+		callsigns := s.humanAircraft(func(ac *Aircraft) bool { return ac.Nav.FlightState.Altitude < 15000 })
+		if len(callsigns) == 0 {
+			return ErrNoAircraftForRandomEvent
+		}
+		pilot = s.World.Aircraft[callsigns[rand.Intn(len(callsigns))]]
+		ev.EndTime = s.SimTime
+		report = fmt.Sprintf("we just got hit by a green laser, it came from about %d miles %s of us",
+			1+rand.Intn(5), compass(float32(rand.Intn(360))))
+
+	case RandomEventParachute:
+		area := re.ParachuteAreas[rand.Intn(len(re.ParachuteAreas))]
+		ev.Area = area.Name
+		ev.EndTime = s.SimTime.Add(time.Duration(15+rand.Intn(16)) * time.Minute)
+		status = fmt.Sprintf("Parachute jumping active at %s within %.0f miles, surface to %s, until %s",
+			area.Name, area.Radius, FormatAltitude(float32(area.Ceiling)), ev.EndTime.UTC().Format("1504Z"))
+	}
+
+	s.lg.Info("random event", slog.String("type", kind.String()), slog.String("airport", ev.Airport),
+		slog.String("runway", ev.Runway), slog.String("area", ev.Area))
+
+	if pilot != nil && report != "" {
+		PostRadioEvents(pilot.Callsign, []RadioTransmission{RadioTransmission{
+			Controller: pilot.ControllingController,
+			Message:    report,
+			Type:       RadioTransmissionUnexpected,
+		}}, s)
+	}
+	if status != "" {
+		s.eventStream.Post(Event{Type: StatusMessageEvent, Message: status})
+	}
+	if ev.EndTime.After(s.SimTime) {
+		s.ActiveRandomEvents = append(s.ActiveRandomEvents, ev)
+	}
+	return nil
+}
+
+// birdGoAround has the aircraft go around partway down the final. s.mu
+// must be held.
+func (s *Sim) birdGoAround(ac *Aircraft) {
+	if d, err := ac.Nav.distanceToEndOfApproach(); err == nil {
+		gd := d * (0.1 + 0.5*rand.Float32())
+		ac.GoAroundDistance = &gd
+	}
+}
+
+// updateRandomEvents ends the random events whose time is up, applies the
+// effects of the ongoing ones, and, at the rate given in the launch
+// config, starts new ones. s.mu must be held.
+func (s *Sim) updateRandomEvents() {
+	s.ActiveRandomEvents = slices.DeleteFunc(s.ActiveRandomEvents, func(ev RandomEvent) bool {
+		if s.SimTime.Before(ev.EndTime) {
+			return false
+		}
+		switch ev.Type {
+		case RandomEventDrone:
+			s.eventStream.Post(Event{
+				Type:    StatusMessageEvent,
+				Message: fmt.Sprintf("%s: runway %s final reopened", ev.Airport, ev.Runway),
+			})
+		case RandomEventParachute:
+			s.eventStream.Post(Event{
+				Type:    StatusMessageEvent,
+				Message: fmt.Sprintf("Parachute jumping at %s complete", ev.Area),
+			})
+		}
+		return true
+	})
+
+	for i := range s.ActiveRandomEvents {
+		ev := &s.ActiveRandomEvents[i]
+		switch ev.Type {
+		case RandomEventBirds:
+			// Some of the arrivals that are cleared for an approach
+			// while the birds are around go around.
+			for _, callsign := range s.humanAircraft(func(ac *Aircraft) bool {
+				return ac.FlightPlan.ArrivalAirport == ev.Airport && ac.Nav.Approach.Cleared &&
+					!slices.Contains(ev.Callsigns, ac.Callsign)
+			}) {
+				ac := s.World.Aircraft[callsign]
+				ev.Callsigns = append(ev.Callsigns, callsign)
+				if ac.GoAroundDistance == nil && rand.Float32() < birdGoAroundProbability {
+					s.birdGoAround(ac)
+				}
+			}
+
+		case RandomEventParachute:
+			idx := slices.IndexFunc(s.World.RandomEvents.ParachuteAreas,
+				func(a ParachuteArea) bool { return a.Name == ev.Area })
+			if idx == -1 {
+				break
+			}
+			area := s.World.RandomEvents.ParachuteAreas[idx]
+			for _, callsign := range s.humanAircraft(func(ac *Aircraft) bool {
+				return !slices.Contains(ev.Callsigns, ac.Callsign) &&
+					ac.Nav.FlightState.Altitude <= float32(area.Ceiling) &&
+					nmdistance2ll(ac.Position(), area.Location) < area.Radius
+			}) {
+				ac := s.World.Aircraft[callsign]
+				ev.Callsigns = append(ev.Callsigns, callsign)
+				s.sessionStats(ac.ControllingController).JumpAreaIncursions++
+				s.lg.Info("jump area incursion", slog.String("callsign", callsign), slog.String("area", area.Name))
+				s.eventStream.Post(Event{
+					Type:    StatusMessageEvent,
+					Message: fmt.Sprintf("%s entered the active %s jump area", callsign, area.Name),
+				})
+			}
+		}
+	}
+
+	types := s.World.RandomEvents.Types()
+	if len(types) == 0 || rand.Float32() >= s.LaunchConfig.RandomEventRate/3600 {
+		return
+	}
+	// Don't worry if there's no aircraft for the event this time.
+	_ = s.startRandomEvent(types[rand.Intn(len(types))])
+}
+
+// checkClosedFinal records an approach clearance to a runway whose final
+// is closed for drone activity. s.mu must be held.
+func (s *Sim) checkClosedFinal(ctrl *Controller, ac *Aircraft) {
+	if !ac.Nav.Approach.Cleared || ac.Nav.Approach.Assigned == nil || ac.FlightPlan == nil {
+		return
+	}
+	if slices.ContainsFunc(s.ActiveRandomEvents, func(ev RandomEvent) bool {
+		return ev.Type == RandomEventDrone && ev.Airport == ac.FlightPlan.ArrivalAirport &&
+			ev.Runway == ac.Nav.Approach.Assigned.Runway
+	}) {
+		s.sessionStats(ctrl.Callsign).ClosedFinalApproaches++
+		s.lg.Info("approach to closed final", slog.String("callsign", ac.Callsign),
+			slog.String("runway", ac.Nav.Approach.Assigned.Runway))
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Client side
+
+func (w *World) TriggerRandomEvent(kind RandomEventType, success func(any), err func(error)) {
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.TriggerRandomEvent(kind),
+			IssueTime: time.Now(),
+			OnSuccess: success,
+			OnErr:     err,
+		})
+}
+
+func (lc *LaunchConfig) DrawRandomEventUI() (changed bool) {
+	imgui.Text("Random events")
+	changed = imgui.SliderFloatV("Random events (per hour)", &lc.RandomEventRate, 0, 10, "%.1f", 0) || changed
+	return
+}
+
+// drawRandomEventTrigger draws the launch control UI for starting one of
+// the scenario's random events.
+func (lc *LaunchControlWindow) drawRandomEventTrigger(eventStream *EventStream) {
+	types := lc.w.RandomEvents.Types()
+	if len(types) == 0 {
+		return
+	}
+	if !slices.Contains(types, lc.randomEventType) {
+		lc.randomEventType = types[0]
+	}
+
+	imgui.SetNextItemWidth(200)
+	if imgui.BeginComboV("##randomEventType", lc.randomEventType.String(), 0) {
+		for _, t := range types {
+			if imgui.SelectableV(t.String(), t == lc.randomEventType, 0, imgui.Vec2{}) {
+				lc.randomEventType = t
+			}
+		}
+		imgui.EndCombo()
+	}
+	imgui.SameLine()
+	if imgui.Button("Start event") {
+		lc.w.TriggerRandomEvent(lc.randomEventType, nil,
+			func(err error) {
+				eventStream.Post(Event{Type: StatusMessageEvent, Message: err.Error()})
+			})
+	}
+
+	for _, ev := range lc.w.ActiveRandomEvents {
+		where := ev.Airport
+		if ev.Runway != "" {
+			where += " " + ev.Runway
+		} else if ev.Area != "" {
+			where = ev.Area
+		}
+		imgui.Text(fmt.Sprintf("%s: %s until %s", ev.Type, where, ev.EndTime.UTC().Format("1504Z")))
+	}
+}
@@ -0,0 +1,199 @@
+// release.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// DepartureRelease is an IFR departure that the tower is holding on the
+// ground until the TRACON releases it. When LaunchConfig.RequireReleases
+// is set, the Sim doesn't launch departures when they are spawned but
+// instead adds a release request; the aircraft is launched when the
+// departure controller releases it.
+type DepartureRelease struct {
+	Aircraft    Aircraft
+	Runway      string
+	Controller  string // who may release it
+	RequestTime time.Time
+	Held        bool // the controller has told the tower to hold it
+}
+
+// requestRelease adds a release request for the given departure. s.mu
+// must be held.
+func (s *Sim) requestRelease(ac *Aircraft, runway string) {
+	rel := DepartureRelease{
+		Aircraft:    *ac,
+		Runway:      runway,
+		Controller:  s.World.DepartureController(ac),
+		RequestTime: s.SimTime,
+	}
+	s.DepartureReleases = append(s.DepartureReleases, rel)
+
+	s.lg.Info("release requested", slog.String("callsign", ac.Callsign),
+		slog.String("controller", rel.Controller))
+	s.eventStream.Post(Event{
+		Type: StatusMessageEvent,
+		Message: fmt.Sprintf("%s tower requests release for %s, runway %s", ac.FlightPlan.DepartureAirport,
+			ac.Callsign, runway),
+	})
+}
+
+// releaseIndex returns the index of the release request for the given
+// callsign after checking that the controller may release or hold it.
+// s.mu must be held.
+func (s *Sim) releaseIndex(token, callsign string) (int, error) {
+	sc, ok := s.controllers[token]
+	if !ok {
+		return -1, ErrInvalidControllerToken
+	}
+	idx := slices.IndexFunc(s.DepartureReleases, func(r DepartureRelease) bool {
+		return r.Aircraft.Callsign == callsign
+	})
+	if idx == -1 {
+		return -1, ErrNoReleaseRequest
+	}
+	if ctrl := s.DepartureReleases[idx].Controller; sc.Callsign != ctrl && sc.Callsign != s.World.PrimaryController {
+		return -1, ErrOtherControllerHasTrack
+	}
+	return idx, nil
+}
+
+// ReleaseDeparture releases the departure, which then takes off.
+func (s *Sim) ReleaseDeparture(token, callsign string) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	idx, err := s.releaseIndex(token, callsign)
+	if err != nil {
+		return err
+	}
+	rel := s.DepartureReleases[idx]
+	s.DepartureReleases = slices.Delete(s.DepartureReleases, idx, idx+1)
+
+	s.lg.Info("departure released", slog.String("callsign", callsign),
+		slog.Duration("delay", s.SimTime.Sub(rel.RequestTime)))
+	s.sessionStats(rel.Controller).addDelay(s.SimTime.Sub(rel.RequestTime))
+	s.launchAircraftNoLock(rel.Aircraft)
+	return nil
+}
+
+// HoldDeparture tells the tower to hold the departure on the ground; it
+// remains in the list of release requests until it is released.
+func (s *Sim) HoldDeparture(token, callsign string) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	idx, err := s.releaseIndex(token, callsign)
+	if err != nil {
+		return err
+	}
+	s.DepartureReleases[idx].Held = true
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Client side
+
+func (w *World) ReleaseDeparture(callsign string, success func(any), err func(error)) {
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.ReleaseDeparture(callsign),
+			IssueTime: time.Now(),
+			OnSuccess: success,
+			OnErr:     err,
+		})
+}
+
+func (w *World) HoldDeparture(callsign string, success func(any), err func(error)) {
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.HoldDeparture(callsign),
+			IssueTime: time.Now(),
+			OnSuccess: success,
+			OnErr:     err,
+		})
+}
+
+func (w *World) ToggleShowReleasesWindow() {
+	w.showReleases = !w.showReleases
+}
+
+// DrawReleasesWindow shows the departures that are waiting for release and
+// allows the controller to release or hold them. It's opened
+// automatically when there is a new request for the current controller.
+func (w *World) DrawReleasesWindow(eventStream *EventStream) {
+	var ours []DepartureRelease
+	for _, rel := range w.DepartureReleases {
+		if rel.Controller == w.Callsign || w.Callsign == w.PrimaryController {
+			ours = append(ours, rel)
+		}
+	}
+	if len(ours) > w.lastReleaseCount {
+		w.showReleases = true
+	}
+	w.lastReleaseCount = len(ours)
+
+	if !w.showReleases {
+		return
+	}
+
+	imgui.BeginV("Departure Releases", &w.showReleases, imgui.WindowFlagsAlwaysAutoResize)
+	if len(ours) == 0 {
+		imgui.Text("No departures are awaiting release.")
+	}
+
+	flags := imgui.TableFlagsBordersV | imgui.TableFlagsBordersOuterH | imgui.TableFlagsRowBg |
+		imgui.TableFlagsSizingStretchProp
+	if len(ours) > 0 && imgui.BeginTableV("releases", 6, flags, imgui.Vec2{}, 0) {
+		imgui.TableSetupColumn("Callsign")
+		imgui.TableSetupColumn("Type")
+		imgui.TableSetupColumn("Airport")
+		imgui.TableSetupColumn("Runway")
+		imgui.TableSetupColumn("Waiting")
+		imgui.TableSetupColumn("")
+		imgui.TableHeadersRow()
+
+		onErr := func(err error) {
+			eventStream.Post(Event{Type: StatusMessageEvent, Message: err.Error()})
+		}
+
+		for _, rel := range ours {
+			ac := &rel.Aircraft
+			imgui.PushID(ac.Callsign)
+			imgui.TableNextRow()
+			imgui.TableNextColumn()
+			imgui.Text(ac.Callsign)
+			imgui.TableNextColumn()
+			imgui.Text(ac.FlightPlan.TypeWithoutSuffix())
+			imgui.TableNextColumn()
+			imgui.Text(ac.FlightPlan.DepartureAirport)
+			imgui.TableNextColumn()
+			imgui.Text(rel.Runway)
+			imgui.TableNextColumn()
+			waiting := w.CurrentTime().Sub(rel.RequestTime)
+			imgui.Text(fmt.Sprintf("%d:%02d", int(waiting.Minutes()), int(waiting.Seconds())%60) +
+				Select(rel.Held, " (held)", ""))
+			imgui.TableNextColumn()
+			if imgui.Button("Release") {
+				w.ReleaseDeparture(ac.Callsign, nil, onErr)
+			}
+			if !rel.Held {
+				imgui.SameLine()
+				if imgui.Button("Hold") {
+					w.HoldDeparture(ac.Callsign, nil, onErr)
+				}
+			}
+			imgui.PopID()
+		}
+		imgui.EndTable()
+	}
+	imgui.End()
+}
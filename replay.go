@@ -0,0 +1,323 @@
+// replay.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mmp/imgui-go/v4"
+)
+
+// ReplayFileVersion should be incremented whenever the replay file format
+// changes in a way that makes older replays unreadable.
+const ReplayFileVersion = 1
+
+var ErrReplayVersionMismatch = errors.New("Replay was recorded by an incompatible version of vice")
+
+// Replays are stored as a zstd-compressed gob stream: a ReplayHeader
+// followed by a SimWorldUpdate for each update that the client received
+// from the Sim.
+type ReplayHeader struct {
+	Version int
+	World   *World
+}
+
+// replayDirectory returns the directory where recorded replays are saved.
+func replayDirectory() string {
+	dir := path.Join(path.Dir(configFilePath()), "replays")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		lg.Errorf("%s: unable to make directory for replays: %v", dir, err)
+	}
+	return dir
+}
+
+///////////////////////////////////////////////////////////////////////////
+// ReplayRecorder
+
+// ReplayRecorder writes the world updates received from the Sim to a
+// replay file as they arrive.
+type ReplayRecorder struct {
+	Filename string
+
+	f        *os.File
+	zw       *zstd.Encoder
+	enc      *gob.Encoder
+	lastTime time.Time
+}
+
+func NewReplayRecorder(w *World) (*ReplayRecorder, error) {
+	fn := path.Join(replayDirectory(), "vice-"+time.Now().Format("2006-01-02-150405")+".replay")
+
+	f, err := os.Create(fn)
+	if err != nil {
+		return nil, err
+	}
+	zw, err := zstd.NewWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	rr := &ReplayRecorder{Filename: fn, f: f, zw: zw, enc: gob.NewEncoder(zw)}
+
+	// The aircraft will come along with the first update; no need to
+	// store them in the header as well.
+	hw := *w
+	hw.Aircraft = nil
+	if err := rr.enc.Encode(ReplayHeader{Version: ReplayFileVersion, World: &hw}); err != nil {
+		rr.Close()
+		return nil, err
+	}
+
+	lg.Infof("%s: started recording replay", fn)
+	return rr, nil
+}
+
+// Record adds the given update to the replay. Updates where the sim is
+// paused and nothing happened are skipped.
+func (rr *ReplayRecorder) Record(wu *SimWorldUpdate) error {
+	if wu.Time.Equal(rr.lastTime) && len(wu.Events) == 0 {
+		return nil
+	}
+	rr.lastTime = wu.Time
+	return rr.enc.Encode(wu)
+}
+
+func (rr *ReplayRecorder) Close() error {
+	lg.Infof("%s: finished recording replay", rr.Filename)
+	err := rr.zw.Close()
+	if ferr := rr.f.Close(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+///////////////////////////////////////////////////////////////////////////
+// ReplayPlayer
+
+// ReplayPlayer plays back a recorded replay. It stands in for a Sim: it is
+// served over RPC as "Sim" so that the World and the radar scopes run
+// unchanged, while only supporting the subset of the Sim's methods that
+// make sense for playback (pausing and changing the rate). Anything else,
+// e.g. trying to issue a control instruction, returns an error.
+type ReplayPlayer struct {
+	Header ReplayHeader
+	Frames []SimWorldUpdate
+
+	mu        sync.Mutex
+	playTime  time.Time // w.r.t. sim time in the recording
+	lastWall  time.Time
+	rate      float32
+	paused    bool
+	lastFrame int // index of the last frame returned by GetWorldUpdate
+}
+
+func LoadReplay(filename string) (*ReplayPlayer, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	dec := gob.NewDecoder(zr)
+	rp := &ReplayPlayer{rate: 1, lastFrame: -1}
+	if err := dec.Decode(&rp.Header); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if rp.Header.Version != ReplayFileVersion {
+		return nil, ErrReplayVersionMismatch
+	}
+
+	for {
+		var wu SimWorldUpdate
+		if err := dec.Decode(&wu); err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			// An unexpected EOF is most likely due to vice exiting
+			// without closing the recording; keep what we have.
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		rp.Frames = append(rp.Frames, wu)
+	}
+	if len(rp.Frames) == 0 {
+		return nil, fmt.Errorf("%s: replay is empty", filename)
+	}
+
+	rp.playTime = rp.Frames[0].Time
+	rp.lastWall = time.Now()
+
+	return rp, nil
+}
+
+// Connect returns a World for the replay whose SimProxy talks to the
+// ReplayPlayer.
+func (rp *ReplayPlayer) Connect() (*World, error) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("Sim", rp); err != nil {
+		return nil, err
+	}
+	sc, cc := net.Pipe()
+	go server.ServeConn(sc)
+
+	w := NewWorld()
+	w.Assign(rp.Header.World)
+	w.simProxy = &SimProxy{
+		ControllerToken: "replay",
+		Client:          &RPCClient{rpc.NewClient(cc)},
+	}
+	w.replay = rp
+
+	return w, nil
+}
+
+func (rp *ReplayPlayer) StartTime() time.Time { return rp.Frames[0].Time }
+func (rp *ReplayPlayer) EndTime() time.Time   { return rp.Frames[len(rp.Frames)-1].Time }
+
+// advance updates the playback time according to the elapsed wallclock
+// time since the last call. rp.mu must be held.
+func (rp *ReplayPlayer) advance() {
+	now := time.Now()
+	if !rp.paused {
+		d := time.Duration(float64(now.Sub(rp.lastWall)) * float64(rp.rate))
+		rp.playTime = rp.playTime.Add(d)
+		if end := rp.EndTime(); rp.playTime.After(end) {
+			rp.playTime = end
+			rp.paused = true
+		}
+	}
+	rp.lastWall = now
+}
+
+// frameIndex returns the index of the last frame at or before t. rp.mu
+// must be held.
+func (rp *ReplayPlayer) frameIndex(t time.Time) int {
+	i := sort.Search(len(rp.Frames), func(i int) bool { return rp.Frames[i].Time.After(t) })
+	return max(0, i-1)
+}
+
+// Seek moves playback to the given time. Events (radio transmissions,
+// etc.) between the previous playback time and the new one are skipped.
+func (rp *ReplayPlayer) Seek(t time.Time) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	if t.Before(rp.StartTime()) {
+		t = rp.StartTime()
+	} else if t.After(rp.EndTime()) {
+		t = rp.EndTime()
+	}
+	rp.playTime = t
+	rp.lastWall = time.Now()
+	rp.lastFrame = rp.frameIndex(t)
+}
+
+func (rp *ReplayPlayer) CurrentTime() time.Time {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.advance()
+	return rp.playTime
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Sim RPC methods
+
+func (rp *ReplayPlayer) GetWorldUpdate(token string, update *SimWorldUpdate) error {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	rp.advance()
+
+	idx := rp.frameIndex(rp.playTime)
+	*update = rp.Frames[idx]
+	update.SimIsPaused = rp.paused
+	update.SimRate = rp.rate
+
+	// Deliver all of the events since the last update.
+	update.Events = nil
+	for i := rp.lastFrame + 1; i <= idx; i++ {
+		update.Events = append(update.Events, rp.Frames[i].Events...)
+	}
+	rp.lastFrame = idx
+
+	return nil
+}
+
+func (rp *ReplayPlayer) TogglePause(token string, _ *struct{}) error {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	rp.advance()
+	if rp.paused && !rp.playTime.Before(rp.EndTime()) {
+		// Restart from the beginning if we're at the end.
+		rp.playTime = rp.StartTime()
+		rp.lastFrame = -1
+	}
+	rp.paused = !rp.paused
+	return nil
+}
+
+func (rp *ReplayPlayer) SetSimRate(r *SetSimRateArgs, _ *struct{}) error {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	rp.advance()
+	rp.rate = r.Rate
+	return nil
+}
+
+func (rp *ReplayPlayer) SignOff(token string, _ *struct{}) error {
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////
+// UI
+
+// DrawUI draws the replay's transport controls: a scrub bar and the
+// current playback time.
+func (rp *ReplayPlayer) DrawUI(w *World) {
+	imgui.BeginV("Replay", nil, imgui.WindowFlagsAlwaysAutoResize)
+
+	start := rp.StartTime()
+	t := rp.CurrentTime()
+	elapsed := float32(t.Sub(start).Seconds())
+	length := float32(rp.EndTime().Sub(start).Seconds())
+
+	if imgui.Button(Select(w.SimIsPaused, FontAwesomeIconPlayCircle, FontAwesomeIconPauseCircle)) {
+		w.ToggleSimPause()
+	}
+	imgui.SameLine()
+	imgui.PushItemWidth(400)
+	if imgui.SliderFloatV("##scrub", &elapsed, 0, length, "", 0) {
+		rp.Seek(start.Add(time.Duration(elapsed * float32(time.Second))))
+	}
+	imgui.PopItemWidth()
+	imgui.SameLine()
+	imgui.Text(fmt.Sprintf("%s (%s / %s)", t.UTC().Format("15:04:05"),
+		time.Duration(elapsed)*time.Second, time.Duration(length)*time.Second))
+
+	rate := w.GetSimRate()
+	if imgui.SliderFloatV("Playback speed", &rate, 1, 20, "%.1f", 0) {
+		w.SetSimRate(rate)
+	}
+
+	imgui.End()
+}
@@ -0,0 +1,328 @@
+// runwayconfig.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// DefaultTailwindLimit is the largest tailwind component in knots that
+// runways are used with at airports that don't specify their own limit.
+const DefaultTailwindLimit = 5
+
+// RunwayWind gives the components of the wind for a runway.
+type RunwayWind struct {
+	Airport       string
+	Runway        string
+	Headwind      float32 // negative for a tailwind
+	Crosswind     float32 // positive if the wind is from the right
+	TailwindLimit int
+}
+
+func (rw RunwayWind) ExceedsTailwindLimit() bool {
+	return -rw.Headwind > float32(rw.TailwindLimit)
+}
+
+func (rw RunwayWind) String() string {
+	s := rw.Airport + " " + rw.Runway + ": "
+	if rw.Headwind < 0 {
+		s += fmt.Sprintf("%.0f kt tailwind", -rw.Headwind)
+	} else {
+		s += fmt.Sprintf("%.0f kt headwind", rw.Headwind)
+	}
+	return s + fmt.Sprintf(", %.0f kt crosswind", abs(rw.Crosswind))
+}
+
+// windComponents returns the headwind and crosswind components of the
+// given wind for a runway with the given magnetic heading. Gusts are
+// included so that the limits aren't exceeded when they come through.
+func windComponents(wind Wind, heading, magneticVariation float32) (headwind, crosswind float32) {
+	if wind.Direction == -1 {
+		// Variable winds are light and don't favor any runway.
+		return 0, 0
+	}
+	spd := float32(max(wind.Speed, wind.Gust))
+	// Winds are reported relative to true north.
+	d := radians(float32(wind.Direction) + magneticVariation - heading)
+	return spd * cos(d), spd * sin(d)
+}
+
+// runwayHeading returns the magnetic heading of the given runway, falling
+// back to the one given by its number if it's not in the database.
+func runwayHeading(icao, rwy string) (float32, bool) {
+	if r, ok := LookupRunway(icao, rwy); ok {
+		return r.Heading, true
+	}
+	n, err := strconv.Atoi(strings.TrimRight(cleanRunway(rwy), "LRC"))
+	if err != nil || n < 1 || n > 36 {
+		return 0, false
+	}
+	return float32(10 * n), true
+}
+
+// runwayWinds returns the wind components for each of the given arrival
+// and departure runways, using the wind at the runway's airport.
+func runwayWinds(arrivals []ScenarioGroupArrivalRunway, departures []ScenarioGroupDepartureRunway,
+	wind func(icao string) Wind, magneticVariation float32, tailwindLimit func(icao string) int) []RunwayWind {
+	var winds []RunwayWind
+	add := func(icao, rwy string) {
+		rwy = cleanRunway(rwy)
+		if slices.ContainsFunc(winds, func(rw RunwayWind) bool { return rw.Airport == icao && rw.Runway == rwy }) {
+			return
+		}
+		if hdg, ok := runwayHeading(icao, rwy); ok {
+			hw, xw := windComponents(wind(icao), hdg, magneticVariation)
+			winds = append(winds, RunwayWind{
+				Airport:       icao,
+				Runway:        rwy,
+				Headwind:      hw,
+				Crosswind:     xw,
+				TailwindLimit: tailwindLimit(icao),
+			})
+		}
+	}
+	for _, rwy := range arrivals {
+		add(rwy.Airport, rwy.Runway)
+	}
+	for _, rwy := range departures {
+		add(rwy.Airport, rwy.Runway)
+	}
+
+	slices.SortFunc(winds, func(a, b RunwayWind) int {
+		if a.Airport != b.Airport {
+			return strings.Compare(a.Airport, b.Airport)
+		}
+		return strings.Compare(a.Runway, b.Runway)
+	})
+	return winds
+}
+
+// worstHeadwind returns the smallest headwind component (i.e., the
+// largest tailwind) of the given runways.
+func worstHeadwind(winds []RunwayWind) float32 {
+	hw := winds[0].Headwind
+	for _, rw := range winds[1:] {
+		hw = min(hw, rw.Headwind)
+	}
+	return hw
+}
+
+// suggestRunwayConfig returns the name of the runway configuration in
+// configs that best suits the wind: the one where the runway with the
+// least headwind has the most. The current configuration is kept unless
+// another one is better by more than a knot so that suggestions don't
+// flip back and forth in a crosswind.
+func suggestRunwayConfig(current string, configs map[string][]RunwayWind) string {
+	if len(configs[current]) == 0 {
+		return current
+	}
+
+	best, bestHeadwind := current, worstHeadwind(configs[current])
+	for _, name := range SortedMapKeys(configs) {
+		if len(configs[name]) == 0 {
+			continue
+		}
+		if hw := worstHeadwind(configs[name]); hw > bestHeadwind+1 {
+			best, bestHeadwind = name, hw
+		}
+	}
+	return best
+}
+
+// scenarioRunwayWinds returns the wind components for the runways of each
+// of the scenarios that are for the same controller position as the given
+// one; these are the runway configurations that may be changed between.
+func scenarioRunwayWinds(configs map[string]*SimScenarioConfiguration, scenario string,
+	wind func(icao string) Wind) map[string][]RunwayWind {
+	cur, ok := configs[scenario]
+	if !ok {
+		return nil
+	}
+
+	winds := make(map[string][]RunwayWind)
+	for name, sc := range configs {
+		if sc.SelectedController != cur.SelectedController {
+			continue
+		}
+		limit := func(icao string) int {
+			if l, ok := sc.TailwindLimits[icao]; ok {
+				return l
+			}
+			return DefaultTailwindLimit
+		}
+		winds[name] = runwayWinds(sc.ArrivalRunways, sc.DepartureRunways, wind, sc.MagneticVariation, limit)
+	}
+	return winds
+}
+
+// airportWind returns the wind at the given airport according to its
+// METAR or the scenario's wind if there isn't one.
+func (w *World) airportWind(icao string) Wind {
+	if m, ok := w.METAR[icao]; ok {
+		if wind, err := ParseMETARWind(m.Wind); err == nil {
+			return wind
+		}
+	}
+	return w.Wind
+}
+
+// RunwayWinds returns the wind components for the sim's active arrival and
+// departure runways.
+func (w *World) RunwayWinds() []RunwayWind {
+	limit := func(icao string) int {
+		if ap, ok := w.Airports[icao]; ok && ap.TailwindLimit > 0 {
+			return ap.TailwindLimit
+		}
+		return DefaultTailwindLimit
+	}
+	return runwayWinds(w.ArrivalRunways, w.DepartureRunways, w.airportWind, w.MagneticVariation, limit)
+}
+
+// scenarioConfigs returns the name of the scenario group that the sim's
+// scenario is from along with the configurations of its scenarios.
+func (w *World) scenarioConfigs() (string, map[string]*SimScenarioConfiguration) {
+	if localServer == nil {
+		return "", nil
+	}
+	groups := localServer.configs[w.TRACON]
+	for _, group := range SortedMapKeys(groups) {
+		if _, ok := groups[group].ScenarioConfigs[w.SimDescription]; ok {
+			return group, groups[group].ScenarioConfigs
+		}
+	}
+	return "", nil
+}
+
+// SuggestRunwayConfig returns the scenario group and the name of the
+// scenario in it whose runways best suit the current wind, along with
+// their wind components. The sim's current scenario is returned if it's
+// the best one or if the scenario group isn't available.
+func (w *World) SuggestRunwayConfig() (string, string, []RunwayWind) {
+	group, configs := w.scenarioConfigs()
+	winds := scenarioRunwayWinds(configs, w.SimDescription, w.airportWind)
+	if winds == nil {
+		return "", w.SimDescription, w.RunwayWinds()
+	}
+	// The active departure runways may have been changed from the
+	// scenario's.
+	winds[w.SimDescription] = w.RunwayWinds()
+	name := suggestRunwayConfig(w.SimDescription, winds)
+	return group, name, winds[name]
+}
+
+// CheckRunwayWinds alerts the controller when the wind at an airport
+// exceeds the tailwind limit of one of the active runways, suggesting a
+// runway configuration that suits it better. Each set of runways is only
+// alerted once, so that the controller isn't asked again until the wind
+// changes.
+func (w *World) CheckRunwayWinds() {
+	// Only the primary controller can change the configuration.
+	if w.replay != nil || w.IsCoach || w.Callsign != w.PrimaryController {
+		return
+	}
+
+	var exceeded []RunwayWind
+	for _, rw := range w.RunwayWinds() {
+		if rw.ExceedsTailwindLimit() {
+			exceeded = append(exceeded, rw)
+		}
+	}
+	alert := strings.Join(MapSlice(exceeded, func(rw RunwayWind) string { return rw.Airport + rw.Runway }), ",")
+	if alert == w.tailwindAlert {
+		return
+	}
+	w.tailwindAlert = alert
+
+	if len(exceeded) > 0 {
+		client := &RunwayConfigModalClient{world: w, exceeded: exceeded}
+		client.group, client.suggested, client.suggestedWinds = w.SuggestRunwayConfig()
+		uiShowModalDialog(NewModalDialogBox(client), false)
+	}
+}
+
+// RunwayConfigModalClient tells the controller that the wind exceeds the
+// tailwind limits of the active runways and offers to start a new sim
+// with the scenario that best suits the wind.
+type RunwayConfigModalClient struct {
+	world          *World
+	exceeded       []RunwayWind
+	group          string
+	suggested      string
+	suggestedWinds []RunwayWind
+}
+
+func (rc *RunwayConfigModalClient) Title() string { return "Runway Configuration" }
+
+func (rc *RunwayConfigModalClient) Opening() {}
+
+func (rc *RunwayConfigModalClient) Buttons() []ModalDialogButton {
+	b := []ModalDialogButton{{text: "Dismiss"}}
+	if rc.suggested != rc.world.SimDescription {
+		b = append(b, ModalDialogButton{
+			text: "Change configuration...",
+			action: func() bool {
+				uiShowModalDialog(NewModalDialogBox(&ConnectModalClient{
+					allowCancel: true,
+					tracon:      rc.world.TRACON,
+					group:       rc.group,
+					scenario:    rc.suggested,
+				}), false)
+				return true
+			},
+		})
+	}
+	return b
+}
+
+func (rc *RunwayConfigModalClient) Draw() int {
+	imgui.Text("The wind exceeds the tailwind limit for:")
+	for _, rw := range rc.exceeded {
+		imgui.Text("    " + rw.String() + fmt.Sprintf(" (limit %d kt)", rw.TailwindLimit))
+	}
+	imgui.Text("")
+	if rc.suggested == rc.world.SimDescription {
+		imgui.Text("None of the scenario's other runway configurations suit the wind better.")
+	} else {
+		imgui.Text("The \"" + rc.suggested + "\" configuration suits the wind better:")
+		for _, rw := range rc.suggestedWinds {
+			imgui.Text("    " + rw.String())
+		}
+	}
+	return -1
+}
+
+// drawRunwayWinds draws a table of the given runways' wind components,
+// highlighting the ones that exceed their tailwind limit.
+func drawRunwayWinds(id string, winds []RunwayWind) {
+	flags := imgui.TableFlagsBordersV | imgui.TableFlagsBordersOuterH | imgui.TableFlagsRowBg |
+		imgui.TableFlagsSizingStretchProp
+	if imgui.BeginTableV(id, 3, flags, imgui.Vec2{}, 0) {
+		imgui.TableSetupColumn("Runway")
+		imgui.TableSetupColumn("Headwind")
+		imgui.TableSetupColumn("Crosswind")
+		imgui.TableHeadersRow()
+		for _, rw := range winds {
+			imgui.TableNextRow()
+			imgui.TableNextColumn()
+			imgui.Text(rw.Airport + " " + rw.Runway)
+			imgui.TableNextColumn()
+			if rw.ExceedsTailwindLimit() {
+				imgui.PushStyleColor(imgui.StyleColorText, imgui.Vec4{1, .5, .5, 1})
+			}
+			imgui.Text(fmt.Sprintf("%.0f kt", rw.Headwind))
+			if rw.ExceedsTailwindLimit() {
+				imgui.PopStyleColor()
+			}
+			imgui.TableNextColumn()
+			imgui.Text(fmt.Sprintf("%.0f kt %s", abs(rw.Crosswind), Select(rw.Crosswind < 0, "L", "R")))
+		}
+		imgui.EndTable()
+	}
+}
@@ -0,0 +1,62 @@
+// runwayconfig_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+)
+
+func TestWindComponents(t *testing.T) {
+	for _, test := range []struct {
+		wind                Wind
+		heading, magVar     float32
+		headwind, crosswind float32
+	}{
+		{wind: Wind{Direction: 310, Speed: 10}, heading: 310, headwind: 10},
+		{wind: Wind{Direction: 130, Speed: 10}, heading: 310, headwind: -10},
+		{wind: Wind{Direction: 40, Speed: 10}, heading: 310, crosswind: 10},
+		{wind: Wind{Direction: 220, Speed: 10, Gust: 20}, heading: 310, crosswind: -20},
+		// Winds are true, runway headings magnetic.
+		{wind: Wind{Direction: 300, Speed: 10}, heading: 310, magVar: 10, headwind: 10},
+		{wind: Wind{Direction: -1, Speed: 3}, heading: 310},
+	} {
+		hw, xw := windComponents(test.wind, test.heading, test.magVar)
+		if abs(hw-test.headwind) > 0.01 || abs(xw-test.crosswind) > 0.01 {
+			t.Errorf("%+v on %.0f: got headwind %f crosswind %f, expected %f %f", test.wind, test.heading,
+				hw, xw, test.headwind, test.crosswind)
+		}
+	}
+}
+
+func TestSuggestRunwayConfig(t *testing.T) {
+	rwy := func(headwind float32) RunwayWind {
+		return RunwayWind{Headwind: headwind, TailwindLimit: DefaultTailwindLimit}
+	}
+	configs := map[string][]RunwayWind{
+		"North": {rwy(-8), rwy(-2)},
+		"South": {rwy(8), rwy(2)},
+		"West":  {rwy(5), rwy(-1)},
+		"None":  nil,
+	}
+
+	if !configs["North"][0].ExceedsTailwindLimit() || configs["North"][1].ExceedsTailwindLimit() {
+		t.Errorf("tailwind limit not applied as expected")
+	}
+	if s := suggestRunwayConfig("North", configs); s != "South" {
+		t.Errorf("got %q, expected South", s)
+	}
+	if s := suggestRunwayConfig("South", configs); s != "South" {
+		t.Errorf("got %q, expected to stay with South", s)
+	}
+	if s := suggestRunwayConfig("None", configs); s != "None" {
+		t.Errorf("got %q for a configuration without runways", s)
+	}
+
+	// Small improvements don't change the suggestion.
+	configs["South"] = []RunwayWind{rwy(-0.5)}
+	if s := suggestRunwayConfig("West", configs); s != "West" {
+		t.Errorf("got %q, expected to stay with West", s)
+	}
+}
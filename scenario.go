@@ -37,11 +37,20 @@ type ScenarioGroup struct {
 	ReportingPointStrings []string         `json:"reporting_points"`
 	ReportingPoints       []ReportingPoint // not in JSON
 
+	Holds []Hold `json:"holds"`
+
+	PreferentialRoutes []PreferentialRoute `json:"preferential_routes"`
+
 	NmPerLatitude           float32 // Always 60
 	NmPerLongitude          float32 // Derived from Center
 	MagneticVariation       float32
 	MagneticAdjustment      float32                 `json:"magnetic_adjustment"`
 	STARSFacilityAdaptation STARSFacilityAdaptation `json:"stars_config"`
+
+	// Where the scenario group was loaded from so that it can be reloaded
+	// while a sim is running.
+	sourceFS   fs.FS
+	sourcePath string
 }
 
 type AirspaceAwareness struct {
@@ -60,11 +69,41 @@ type STARSFacilityAdaptation struct {
 	ControllerConfigs   map[string]STARSControllerConfig `json:"controller_configs"`
 	InhibitCAVolumes    []AirspaceVolume                 `json:"inhibit_ca_volumes"`
 	RadarSites          map[string]*RadarSite            `json:"radar_sites"`
+	RadarCoverageGaps   []AirspaceVolume                 `json:"radar_coverage_gaps"`
 	Center              Point2LL                         `json:"-"`
 	CenterString        string                           `json:"center"`
 	Range               float32                          `json:"range"`
 	Scratchpads         map[string]string                `json:"scratchpads"`
 	VideoMapFile        string                           `json:"video_map_file"`
+	MSAWFile            string                           `json:"msaw_file"`
+	MSAWAreas           []MSAWArea                       `json:"msaw_areas"`
+	MSAWVolumes         []MVA                            `json:"-"` // from MSAWFile and MSAWAreas
+	Timeshare           STARSTimeshare                   `json:"timeshare"`
+}
+
+// STARSTimeshare specifies how the fields of full datablocks that share a
+// position are rotated. Fields 3 and 5 cycle through the listed items,
+// spending Period seconds on each.
+type STARSTimeshare struct {
+	Period float32 `json:"period"`
+	// Items for field 3: "altitude", "scratchpad", "secondary_scratchpad",
+	// and "destination". The destination airport is only shown if neither
+	// scratchpad has been entered. Items that are empty are skipped.
+	Field3 []string `json:"field3"`
+	// Items for field 5: "speed" (which includes the aircraft's category),
+	// "type", and "requested_altitude".
+	Field5 []string `json:"field5"`
+	// If set, fields 3 and 5 stop rotating and just show altitude and
+	// speed while a handoff is in progress.
+	HoldDuringHandoff bool `json:"hold_during_handoff"`
+}
+
+// MSAWArea is a scenario-specified polygon with a minimum safe altitude
+// that is used for MSAW in addition to the FAA's MVAs.
+type MSAWArea struct {
+	Name           string   `json:"name"`
+	Altitude       int      `json:"altitude"`
+	PolygonStrings []string `json:"polygon"`
 }
 
 type STARSControllerConfig struct {
@@ -74,6 +113,10 @@ type STARSControllerConfig struct {
 	Center        Point2LL `json:"-"`
 	CenterString  string   `json:"center"`
 	Range         float32  `json:"range"`
+	// Initial [low, high] altitude filters in feet; the full range is
+	// shown if they're not specified.
+	UnassociatedAltitudeFilter [2]int `json:"unassociated_altitude_filter"`
+	AssociatedAltitudeFilter   [2]int `json:"associated_altitude_filter"`
 }
 
 type Airspace struct {
@@ -89,15 +132,33 @@ type ControllerAirspaceVolume struct {
 }
 
 type Scenario struct {
-	SoloController      string                `json:"solo_controller"`
-	SplitConfigurations SplitConfigurationSet `json:"multi_controllers"`
-	DefaultSplit        string                `json:"default_split"`
-	Wind                Wind                  `json:"wind"`
-	VirtualControllers  []string              `json:"controllers"`
+	SoloController      string                   `json:"solo_controller"`
+	SplitConfigurations SplitConfigurationSet    `json:"multi_controllers"`
+	DefaultSplit        string                   `json:"default_split"`
+	Wind                Wind                     `json:"wind"`
+	WindsAloft          []WindLayer              `json:"winds_aloft,omitempty"`
+	METAR               []string                 `json:"metar,omitempty"` // used instead of generated METARs
+	WeatherPresets      map[string]WeatherPreset `json:"weather_presets,omitempty"`
+	VirtualControllers  []string                 `json:"controllers"`
 
 	// Map from arrival group name to map from airport name to default rate...
 	ArrivalGroupDefaultRates map[string]map[string]int `json:"arrivals"`
 
+	BackgroundTraffic BackgroundTraffic `json:"background_traffic"`
+
+	// Map from satellite airport to its VFR activity
+	SatelliteTraffic map[string]*SatelliteAirportTraffic `json:"satellite_traffic"`
+
+	HelicopterRoutes []HelicopterRoute `json:"helicopter_routes"`
+
+	RandomEvents ScenarioRandomEvents `json:"random_events"`
+
+	// Simulate taxiing at airports that have a diagram with spots.
+	GroundMovement bool `json:"ground_movement"`
+
+	// Optional difficulty preset that is selected by default.
+	Difficulty string `json:"difficulty"`
+
 	ApproachAirspace       []ControllerAirspaceVolume `json:"approach_airspace_volumes"`  // not in JSON
 	DepartureAirspace      []ControllerAirspaceVolume `json:"departure_airspace_volumes"` // not in JSON
 	ApproachAirspaceNames  []string                   `json:"approach_airspace"`
@@ -112,6 +173,16 @@ type Scenario struct {
 	DefaultMaps  []string `json:"default_maps"`
 }
 
+// AllWeatherPresets returns the weather presets available in the
+// scenario: the default ones along with those it defines.
+func (s *Scenario) AllWeatherPresets() map[string]WeatherPreset {
+	presets := DuplicateMap(DefaultWeatherPresets)
+	for name, p := range s.WeatherPresets {
+		presets[name] = p
+	}
+	return presets
+}
+
 // split -> config
 type SplitConfigurationSet map[string]SplitConfiguration
 
@@ -155,6 +226,30 @@ func (s *Scenario) PostDeserialize(sg *ScenarioGroup, e *ErrorLogger) {
 		}
 	}
 
+	for _, str := range s.METAR {
+		if m, err := ParseMETAR(str); err != nil {
+			e.ErrorString("%s: %v", str, err)
+		} else if _, err := ParseMETARWind(m.Wind); err != nil {
+			e.ErrorString("%s: %v", str, err)
+		} else if _, err := ParseAltimeter(m.Altimeter); err != nil {
+			e.ErrorString("%s: %v", str, err)
+		} else if _, ok := sg.Airports[m.AirportICAO]; !ok {
+			if _, ok := database.Airports[m.AirportICAO]; !ok {
+				e.ErrorString("%s: unknown airport in METAR", m.AirportICAO)
+			}
+		}
+	}
+	for _, name := range SortedMapKeys(s.WeatherPresets) {
+		e.Push("Weather preset " + name)
+		s.WeatherPresets[name].Check(e)
+		e.Pop()
+	}
+	for _, l := range s.WindsAloft {
+		if l.Direction < 0 || l.Direction > 360 || l.Speed < 0 {
+			e.ErrorString("%d: invalid winds aloft %03d%02d", l.Altitude, l.Direction, l.Speed)
+		}
+	}
+
 	sort.Slice(s.DepartureRunways, func(i, j int) bool {
 		if s.DepartureRunways[i].Airport != s.DepartureRunways[j].Airport {
 			return s.DepartureRunways[i].Airport < s.DepartureRunways[j].Airport
@@ -537,6 +632,37 @@ func (s *Scenario) PostDeserialize(sg *ScenarioGroup, e *ErrorLogger) {
 		e.Pop()
 	}
 
+	s.BackgroundTraffic.PostDeserialize(sg, s, e)
+
+	if len(s.SatelliteTraffic) > 0 {
+		e.Push("\"satellite_traffic\"")
+		for _, icao := range SortedMapKeys(s.SatelliteTraffic) {
+			s.SatelliteTraffic[icao].PostDeserialize(icao, sg, e)
+		}
+		e.Pop()
+	}
+
+	for i := range s.HelicopterRoutes {
+		e.Push(fmt.Sprintf("\"helicopter_routes\" %d", i))
+		s.HelicopterRoutes[i].PostDeserialize(sg, s, e)
+		e.Pop()
+	}
+
+	e.Push("\"random_events\"")
+	s.RandomEvents.PostDeserialize(sg, e)
+	e.Pop()
+
+	if s.GroundMovement && !slices.ContainsFunc(SortedMapKeys(sg.Airports), func(icao string) bool {
+		d := sg.Airports[icao].Diagram
+		return d != nil && len(d.Taxiways) > 0 && len(d.Spots) > 0
+	}) {
+		e.ErrorString("\"ground_movement\" requires an airport with a \"diagram\" that has \"taxiways\" and \"spots\"")
+	}
+
+	if _, ok := ParseDifficulty(s.Difficulty); s.Difficulty != "" && !ok {
+		e.ErrorString("\"difficulty\": \"%s\" is not one of \"basic\", \"realistic\", or \"expert\"", s.Difficulty)
+	}
+
 	for _, ctrl := range s.VirtualControllers {
 		if _, ok := sg.ControlPositions[ctrl]; !ok {
 			e.ErrorString("controller \"%s\" unknown", ctrl)
@@ -785,6 +911,13 @@ func (sg *ScenarioGroup) PostDeserialize(e *ErrorLogger, simConfigurations map[s
 		}
 	}
 
+	for i := range sg.Holds {
+		sg.Holds[i].PostDeserialize(sg, e)
+	}
+	for i := range sg.PreferentialRoutes {
+		sg.PreferentialRoutes[i].PostDeserialize(e)
+	}
+
 	// Do after airports!
 	if len(sg.Scenarios) == 0 {
 		e.ErrorString("No \"scenarios\" specified")
@@ -812,7 +945,7 @@ func (s *STARSFacilityAdaptation) PostDeserialize(e *ErrorLogger, sg *ScenarioGr
 
 		for ctrl, config := range s.ControllerConfigs {
 			if pos, ok := sg.locate(config.CenterString); !ok {
-				e.ErrorString("unknown location \"%s\" specified for \"center\"", s.CenterString)
+				e.ErrorString("unknown location \"%s\" specified for \"center\"", config.CenterString)
 			} else {
 				config.Center = pos
 				s.ControllerConfigs[ctrl] = config
@@ -830,6 +963,11 @@ func (s *STARSFacilityAdaptation) PostDeserialize(e *ErrorLogger, sg *ScenarioGr
 						"\"controller_maps\"", name, ctrl)
 				}
 			}
+			for _, f := range [][2]int{config.UnassociatedAltitudeFilter, config.AssociatedAltitudeFilter} {
+				if f[0] < 0 || f[1] < 0 || f[0] > f[1] {
+					e.ErrorString("invalid altitude filter [%d, %d] for \"%s\"", f[0], f[1], ctrl)
+				}
+			}
 			// Make sure all of the control positions are included in at least
 			// one of the scenarios.  As with VideoMapNames, don't try to
 			// validate the map names yet.
@@ -867,6 +1005,49 @@ func (s *STARSFacilityAdaptation) PostDeserialize(e *ErrorLogger, sg *ScenarioGr
 		s.Range = 50
 	}
 
+	s.MSAWVolumes = nil
+	if s.MSAWFile != "" {
+		if b, err := fs.ReadFile(resourcesFS, s.MSAWFile); err != nil {
+			e.Error(err)
+		} else if mvas, err := ParseMinimumAltitudeGeoJSON(b); err != nil {
+			e.ErrorString("%s: %v", s.MSAWFile, err)
+		} else {
+			s.MSAWVolumes = mvas
+		}
+	}
+	for _, area := range s.MSAWAreas {
+		e.Push("MSAW area " + area.Name)
+		if area.Altitude <= 0 {
+			e.ErrorString("must specify a positive \"altitude\"")
+		}
+		if len(area.PolygonStrings) < 3 {
+			e.ErrorString("\"polygon\" must have at least 3 vertices")
+		}
+		var ring [][2]float32
+		for _, str := range area.PolygonStrings {
+			if p, ok := sg.locate(str); !ok {
+				e.ErrorString("unknown location \"%s\" in \"polygon\"", str)
+			} else {
+				ring = append(ring, p)
+			}
+		}
+		s.MSAWVolumes = append(s.MSAWVolumes, MVA{MinimumLimit: area.Altitude, ExteriorRing: ring})
+		e.Pop()
+	}
+
+	for i, vol := range s.RadarCoverageGaps {
+		e.Push(fmt.Sprintf("Radar coverage gap %d", i))
+		if vol.Ceiling <= vol.Floor {
+			e.ErrorString("\"ceiling\" %d must be above \"floor\" %d", vol.Ceiling, vol.Floor)
+		}
+		if vol.Type == AirspaceVolumePolygon && len(vol.Vertices) < 3 {
+			e.ErrorString("polygon needs at least three \"vertices\"")
+		} else if vol.Type == AirspaceVolumeCircle && vol.Radius <= 0 {
+			e.ErrorString("circle must have a positive \"radius\"")
+		}
+		e.Pop()
+	}
+
 	for name, rs := range s.RadarSites {
 		e.Push("Radar site " + name)
 		if p, ok := sg.locate(rs.PositionString); rs.PositionString == "" || !ok {
@@ -880,12 +1061,54 @@ func (s *STARSFacilityAdaptation) PostDeserialize(e *ErrorLogger, sg *ScenarioGr
 		if rs.Elevation == 0 {
 			e.ErrorString("radar site is missing \"elevation\"")
 		}
+		if rs.RotationPeriod < 0 || rs.RotationPeriod > 30 {
+			e.ErrorString("radar site \"rotation_period\" %.1f should be between 0 and 30 seconds", rs.RotationPeriod)
+		}
 		e.Pop()
 	}
 
+	s.Timeshare.PostDeserialize(e)
+
 	e.Pop() // stars_config
 }
 
+// CyclePeriod returns how long each of the timeshared items is displayed.
+func (t STARSTimeshare) CyclePeriod() time.Duration {
+	if t.Period <= 0 {
+		return 2 * time.Second
+	}
+	return time.Duration(t.Period * float32(time.Second))
+}
+
+func (t *STARSTimeshare) PostDeserialize(e *ErrorLogger) {
+	e.Push("timeshare")
+	defer e.Pop()
+
+	if t.Period == 0 {
+		t.Period = 2
+	} else if t.Period < 0.5 || t.Period > 10 {
+		e.ErrorString("\"period\" %.1f should be between 0.5 and 10 seconds", t.Period)
+	}
+
+	check := func(name string, items []string, first string, allowed []string) []string {
+		if len(items) == 0 {
+			return allowed
+		}
+		for _, item := range items {
+			if !slices.Contains(allowed, item) {
+				e.ErrorString("\"%s\": unknown item \"%s\". Options: %s", name, item, strings.Join(allowed, ", "))
+			}
+		}
+		if !slices.Contains(items, first) {
+			e.ErrorString("\"%s\": must include \"%s\"", name, first)
+		}
+		return items
+	}
+	t.Field3 = check("field3", t.Field3, "altitude",
+		[]string{"altitude", "scratchpad", "secondary_scratchpad", "destination"})
+	t.Field5 = check("field5", t.Field5, "speed", []string{"speed", "type", "requested_altitude"})
+}
+
 func (s *STARSFacilityAdaptation) PreSave() {
 	// Slim down STARSFacilityAdaptation before it is saved by discarding
 	// the video maps, which we can restore at load time through the
@@ -941,9 +1164,23 @@ func initializeSimConfigurations(sg *ScenarioGroup,
 			LaunchConfig: MakeLaunchConfig(scenario.DepartureRunways,
 				scenario.ArrivalGroupDefaultRates),
 			Wind:             scenario.Wind,
+			WeatherPresets:   SortedMapKeys(scenario.AllWeatherPresets()),
 			DepartureRunways: scenario.DepartureRunways,
 			ArrivalRunways:   scenario.ArrivalRunways,
 			PrimaryAirport:   sg.PrimaryAirport,
+			HaveRandomEvents: len(scenario.RandomEvents.Types()) > 0,
+
+			MagneticVariation: sg.MagneticVariation,
+			TailwindLimits:    make(map[string]int),
+		}
+		for icao, ap := range sg.Airports {
+			if ap.TailwindLimit > 0 {
+				sc.TailwindLimits[icao] = ap.TailwindLimit
+			}
+		}
+		sc.LaunchConfig.RandomEventRate = scenario.RandomEvents.Rate
+		if d, ok := ParseDifficulty(scenario.Difficulty); ok {
+			sc.LaunchConfig.ApplyDifficulty(d)
 		}
 
 		if multiController {
@@ -1197,6 +1434,14 @@ func loadScenarioGroup(filesystem fs.FS, path string, e *ErrorLogger) *ScenarioG
 		return nil
 	}
 
+	s := parseScenarioGroup(contents, e)
+	if s != nil {
+		s.sourceFS, s.sourcePath = filesystem, path
+	}
+	return s
+}
+
+func parseScenarioGroup(contents []byte, e *ErrorLogger) *ScenarioGroup {
 	CheckJSONVsSchema[ScenarioGroup](contents, e)
 	if e.HaveErrors() {
 		return nil
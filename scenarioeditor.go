@@ -0,0 +1,1306 @@
+// scenarioeditor.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/iancoleman/orderedmap"
+	"github.com/mmp/imgui-go/v4"
+)
+
+// ScenarioEditor is a window for writing scenario group files. It edits
+// the controller positions, the arrival and departure routes, and each
+// scenario's runway configuration and spawn rates; routes can be built by
+// clicking on fixes on the STARS scope and new fixes and airspace volumes
+// can be defined by clicking points on it. The file is edited as generic JSON
+// so that the parts of it that the editor doesn't know about are written
+// back as they were, in the same order.
+type ScenarioEditor struct {
+	show     bool
+	filename string
+	doc      *orderedmap.OrderedMap
+
+	scenario     string // selected scenario
+	arrivalGroup string // selected arrival group
+	airport      string // selected airport for departure routes
+	newName      string // for adding positions, groups, scenarios, ...
+
+	// When non-nil, clicks on the scope append the closest fix to this
+	// route.
+	capture *scenarioEditorCapture
+
+	// When non-nil, this CRDA qualification region is drawn on the scope
+	// and its extent can be adjusted by dragging its handles.
+	region *scenarioEditorRegion
+
+	// When non-nil, clicks on the scope define new fixes or airspace
+	// volumes.
+	canvas *scenarioEditorCanvas
+
+	openDialog *FileSelectDialogBox
+	problems   []string // from the last validation
+	message    string
+}
+
+type scenarioEditorCapture struct {
+	label string
+	route *orderedmap.OrderedMap // object with the route's "waypoints"
+}
+
+type scenarioEditorRegion struct {
+	label  string
+	obj    *orderedmap.OrderedMap // the region's JSON
+	handle int                    // index of the handle being dragged or -1
+}
+
+// scenarioEditorCanvas holds the state for defining fixes and airspace
+// volumes by clicking on the scope, which saves looking up and typing
+// their coordinates.
+type scenarioEditorCanvas struct {
+	mode   int
+	points []Point2LL // for the volume being defined
+
+	fixPrefix      string
+	volumeName     string
+	volumeTarget   int // index into canvasVolumeTargets
+	floor, ceiling int32
+}
+
+const (
+	canvasModeFix = iota
+	canvasModePolygon
+	canvasModeCircle
+)
+
+// canvasVolumeTargets are the arrays in "stars_config" that airspace
+// volumes can be added to.
+var canvasVolumeTargets = []string{"inhibit_ca_volumes", "radar_coverage_gaps"}
+
+func (w *World) ToggleShowScenarioEditor() {
+	if w.scenarioEditor == nil {
+		w.scenarioEditor = &ScenarioEditor{}
+		w.scenarioEditor.newDocument(w)
+	}
+	w.scenarioEditor.show = !w.scenarioEditor.show
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Generic JSON
+
+func newJSONObject() *orderedmap.OrderedMap {
+	o := orderedmap.New()
+	o.SetEscapeHTML(false)
+	return o
+}
+
+// jsonPointerize replaces the objects in JSON that was decoded using an
+// OrderedMap with pointers so that they can be modified in place.
+func jsonPointerize(v any) any {
+	switch v := v.(type) {
+	case orderedmap.OrderedMap:
+		return jsonPointerize(&v)
+	case *orderedmap.OrderedMap:
+		for _, k := range v.Keys() {
+			c, _ := v.Get(k)
+			v.Set(k, jsonPointerize(c))
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = jsonPointerize(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// jsonObject returns the object with the given key, adding an empty one
+// if there isn't one.
+func jsonObject(m *orderedmap.OrderedMap, key string) *orderedmap.OrderedMap {
+	if v, ok := m.Get(key); ok {
+		if o, ok := v.(*orderedmap.OrderedMap); ok {
+			return o
+		}
+	}
+	o := newJSONObject()
+	m.Set(key, o)
+	return o
+}
+
+func jsonArray(m *orderedmap.OrderedMap, key string) []any {
+	v, _ := m.Get(key)
+	a, _ := v.([]any)
+	return a
+}
+
+func jsonString(m *orderedmap.OrderedMap, key string) string {
+	v, _ := m.Get(key)
+	switch v := v.(type) {
+	case string:
+		return v
+	case []any:
+		// Waypoints may be given as an array of strings.
+		var s []string
+		for _, e := range v {
+			if str, ok := e.(string); ok {
+				s = append(s, str)
+			}
+		}
+		return strings.Join(s, " ")
+	default:
+		return ""
+	}
+}
+
+func jsonInt(m *orderedmap.OrderedMap, key string) int {
+	v, _ := m.Get(key)
+	f, _ := v.(float64)
+	return int(f)
+}
+
+func jsonFloat(m *orderedmap.OrderedMap, key string) float32 {
+	v, _ := m.Get(key)
+	f, _ := v.(float64)
+	return float32(f)
+}
+
+// jsonCopy returns a deep copy of the given object.
+func jsonCopy(m *orderedmap.OrderedMap) *orderedmap.OrderedMap {
+	c := newJSONObject()
+	if b, err := json.Marshal(m); err == nil {
+		if err := json.Unmarshal(b, c); err == nil {
+			return jsonPointerize(c).(*orderedmap.OrderedMap)
+		}
+	}
+	return c
+}
+
+func jsonInputText(label string, m *orderedmap.OrderedMap, key string, flags imgui.InputTextFlags) {
+	s := jsonString(m, key)
+	if imgui.InputTextV(label, &s, flags, nil) {
+		m.Set(key, s)
+	}
+}
+
+func jsonInputFloat(label string, m *orderedmap.OrderedMap, key string) {
+	v := jsonFloat(m, key)
+	if imgui.DragFloatV(label, &v, 0.1, 0, 0, "%.1f", 0) {
+		m.Set(key, float64(v))
+	}
+}
+
+func jsonInputInt(label string, m *orderedmap.OrderedMap, key string) {
+	v := int32(jsonInt(m, key))
+	if imgui.InputIntV(label, &v, 0, 0, 0) {
+		m.Set(key, float64(v))
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Files
+
+// newDocument starts a new scenario group for the current TRACON.
+func (se *ScenarioEditor) newDocument(w *World) {
+	doc := newJSONObject()
+	doc.Set("tracon", w.TRACON)
+	doc.Set("name", "")
+	doc.Set("primary_airport", w.PrimaryAirport)
+	doc.Set("airports", newJSONObject())
+	doc.Set("fixes", newJSONObject())
+	doc.Set("control_positions", newJSONObject())
+	doc.Set("arrival_groups", newJSONObject())
+	doc.Set("scenarios", newJSONObject())
+	doc.Set("default_scenario", "")
+
+	fa := newJSONObject()
+	fa.Set("center", w.Center.DMSString())
+	fa.Set("range", float64(w.Range))
+	fa.Set("video_map_file", w.STARSFacilityAdaptation.VideoMapFile)
+	doc.Set("stars_config", fa)
+
+	*se = ScenarioEditor{show: se.show, doc: doc}
+}
+
+func (se *ScenarioEditor) open(filename string) {
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		ShowErrorDialog("%s: %v", filename, err)
+		return
+	}
+	doc := newJSONObject()
+	if err := json.Unmarshal(contents, doc); err != nil {
+		ShowErrorDialog("%s: %v", filename, err)
+		return
+	}
+	*se = ScenarioEditor{show: se.show, filename: filename, doc: jsonPointerize(doc).(*orderedmap.OrderedMap)}
+}
+
+// validate returns the scenario group's JSON along with any errors that
+// would prevent it from being loaded.
+func (se *ScenarioEditor) validate() ([]byte, []string) {
+	contents, err := json.MarshalIndent(se.doc, "", "  ")
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+
+	var e ErrorLogger
+	if sg := parseScenarioGroup(contents, &e); sg != nil {
+		// The sim configurations are only needed at startup.
+		sg.PostDeserialize(&e, make(map[string]map[string]*SimConfiguration))
+	}
+	return contents, e.errors
+}
+
+// save writes the scenario group if it's valid or if force is set.
+func (se *ScenarioEditor) save(force bool) {
+	contents, problems := se.validate()
+	se.problems = problems
+	if contents == nil || (len(problems) > 0 && !force) {
+		se.message = ""
+		return
+	}
+
+	if err := BackupFile(se.filename); err != nil {
+		ShowErrorDialog("%s: unable to make backup: %v", se.filename, err)
+		return
+	}
+	if err := os.WriteFile(se.filename, contents, 0o644); err != nil {
+		ShowErrorDialog("%s: %v", se.filename, err)
+	} else {
+		se.message = "Saved " + se.filename
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Scope interaction
+
+// Capturing indicates whether clicks on the scope should be used to build
+// a route or to define fixes and volumes.
+func (se *ScenarioEditor) Capturing() bool {
+	return se != nil && se.show && (se.capture != nil || se.canvas != nil)
+}
+
+// ScopeClicked appends the fix closest to the clicked point to the route
+// being built, as long as it's within the given distance in nm. If fixes
+// or volumes are being defined, the point is used for them instead.
+func (se *ScenarioEditor) ScopeClicked(w *World, p Point2LL, tolerance float32) {
+	if se.canvas != nil {
+		se.canvasClicked(p)
+		return
+	}
+
+	closest, dist := "", tolerance
+	check := func(name string, loc Point2LL) {
+		if d := nmdistance2ll(p, loc); d < dist {
+			closest, dist = name, d
+		}
+	}
+	for name, loc := range se.documentFixes() {
+		check(name, loc)
+	}
+	for name, loc := range w.Fixes {
+		check(name, loc)
+	}
+	for name, nav := range database.Navaids {
+		check(name, nav.Location)
+	}
+	for name, fix := range database.Fixes {
+		check(name, fix.Location)
+	}
+
+	if closest == "" {
+		se.message = "No fix found near " + p.DMSString()
+		return
+	}
+	wps := strings.Fields(jsonString(se.capture.route, "waypoints"))
+	se.capture.route.Set("waypoints", strings.Join(append(wps, closest), " "))
+	se.message = "Added " + closest + " to " + se.capture.label
+}
+
+// DrawScenarioEditorRoute draws the route that is being built by clicking
+// on the scope.
+func (w *World) DrawScenarioEditorRoute(transforms ScopeTransformations, color RGB, cb *CommandBuffer) {
+	if !w.scenarioEditor.Capturing() {
+		return
+	}
+
+	ld := GetLinesDrawBuilder()
+	defer ReturnLinesDrawBuilder(ld)
+
+	var prev *Point2LL
+	for _, wp := range strings.Fields(jsonString(w.scenarioEditor.capture.route, "waypoints")) {
+		// Ignore altitude and speed restrictions and the like.
+		fix, _, _ := strings.Cut(wp, "/")
+		if p, ok := w.Locate(fix); ok {
+			if prev != nil {
+				ld.AddLine(*prev, p)
+			}
+			ld.AddLatLongCircle(p, w.NmPerLongitude, 0.5, 16)
+			prev = &p
+		}
+	}
+
+	cb.LineWidth(2)
+	cb.SetRGB(color)
+	transforms.LoadLatLongViewingMatrices(cb)
+	ld.GenerateCommands(cb)
+}
+
+// EditingRegion indicates whether a CRDA qualification region is being
+// edited on the scope.
+func (se *ScenarioEditor) EditingRegion() bool {
+	return se != nil && se.show && se.region != nil
+}
+
+func decodeApproachRegion(obj *orderedmap.OrderedMap) (*ApproachRegion, error) {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var ar ApproachRegion
+	if err := json.Unmarshal(b, &ar); err != nil {
+		return nil, err
+	}
+	return &ar, nil
+}
+
+// approachRegionHandles returns the points, in nm coordinates, that are
+// dragged to adjust a qualification region: the centers of its near and
+// far edges and one end of each of them.
+func approachRegionHandles(ar *ApproachRegion, nmPerLongitude, magneticVariation float32) [4][2]float32 {
+	pNear := ar.NearPoint(nmPerLongitude, magneticVariation)
+	pFar := ar.FarPoint(nmPerLongitude, magneticVariation)
+	v := normalize2f(sub2f(pFar, pNear))
+	vperp := [2]float32{-v[1], v[0]}
+	return [4][2]float32{pNear, pFar, add2f(pNear, scale2f(vperp, ar.NearHalfWidth)),
+		add2f(pFar, scale2f(vperp, ar.FarHalfWidth))}
+}
+
+// adjustApproachRegion updates the region's lateral extent so that the
+// given handle (as returned by approachRegionHandles) is at the point p,
+// given in nm coordinates. The reference line is left unchanged.
+func adjustApproachRegion(ar *ApproachRegion, handle int, p [2]float32, nmPerLongitude, magneticVariation float32) {
+	p0 := ar.referenceLinePoint(0, nmPerLongitude, magneticVariation)
+	v := normalize2f(sub2f(ar.referenceLinePoint(1, nmPerLongitude, magneticVariation), p0))
+	vperp := [2]float32{-v[1], v[0]}
+	d := sub2f(p, p0)
+	along, across := dot(d, v), abs(dot(d, vperp))
+
+	round := func(f float32) float32 { return float32(int(10*f+0.5)) / 10 }
+	switch handle {
+	case 0:
+		far := ar.NearDistance + ar.RegionLength
+		ar.NearDistance = round(clamp(along, 0, far-0.5))
+		ar.RegionLength = far - ar.NearDistance
+	case 1:
+		ar.RegionLength = round(max(along-ar.NearDistance, 0.5))
+	case 2:
+		ar.NearHalfWidth = round(max(across, 0.1))
+	case 3:
+		ar.FarHalfWidth = round(max(across, 0.1))
+	}
+}
+
+// RegionMouse handles dragging the handles of the region being edited;
+// it returns true if it used the mouse event.
+func (se *ScenarioEditor) RegionMouse(w *World, mouse *MouseState, transforms ScopeTransformations) bool {
+	r := se.region
+	ar, err := decodeApproachRegion(r.obj)
+	if err != nil {
+		return false
+	}
+
+	if mouse.Clicked[MouseButtonPrimary] {
+		// Handles within 10 pixels of the click can be grabbed.
+		r.handle = -1
+		for i, h := range approachRegionHandles(ar, w.NmPerLongitude, w.MagneticVariation) {
+			if distance2f(transforms.WindowFromLatLongP(nm2ll(h, w.NmPerLongitude)), mouse.Pos) < 10 {
+				r.handle = i
+			}
+		}
+		return r.handle != -1
+	}
+	if r.handle == -1 {
+		return false
+	} else if !mouse.Down[MouseButtonPrimary] {
+		r.handle = -1
+		return false
+	}
+
+	p := ll2nm(transforms.LatLongFromWindowP(mouse.Pos), w.NmPerLongitude)
+	adjustApproachRegion(ar, r.handle, p, w.NmPerLongitude, w.MagneticVariation)
+	r.obj.Set("near_distance", float64(ar.NearDistance))
+	r.obj.Set("region_length", float64(ar.RegionLength))
+	r.obj.Set("near_half_width", float64(ar.NearHalfWidth))
+	r.obj.Set("far_half_width", float64(ar.FarHalfWidth))
+	return true
+}
+
+// DrawScenarioEditorRegion draws the qualification region that is being
+// edited along with its reference line and handles.
+func (w *World) DrawScenarioEditorRegion(transforms ScopeTransformations, color RGB, cb *CommandBuffer) {
+	if !w.scenarioEditor.EditingRegion() {
+		return
+	}
+	ar, err := decodeApproachRegion(w.scenarioEditor.region.obj)
+	if err != nil {
+		return
+	}
+
+	ld := GetLinesDrawBuilder()
+	defer ReturnLinesDrawBuilder(ld)
+
+	line, quad := ar.GetLateralGeometry(w.NmPerLongitude, w.MagneticVariation)
+	ld.AddLine(line[0], line[1])
+	ld.AddLineLoop([][2]float32{quad[0], quad[1], quad[2], quad[3]})
+	cb.LineWidth(1)
+	cb.SetRGB(color)
+	transforms.LoadLatLongViewingMatrices(cb)
+	ld.GenerateCommands(cb)
+
+	ld.Reset()
+	for _, h := range approachRegionHandles(ar, w.NmPerLongitude, w.MagneticVariation) {
+		ld.AddCircle(transforms.WindowFromLatLongP(nm2ll(h, w.NmPerLongitude)), 5, 12)
+	}
+	cb.LineWidth(2)
+	transforms.LoadWindowViewingMatrices(cb)
+	ld.GenerateCommands(cb)
+}
+
+// documentFixes returns the locations of the fixes that the scenario group
+// defines.
+func (se *ScenarioEditor) documentFixes() map[string]Point2LL {
+	fixes := make(map[string]Point2LL)
+	f := jsonObject(se.doc, "fixes")
+	for _, name := range f.Keys() {
+		if p, err := ParseLatLong([]byte(jsonString(f, name))); err == nil {
+			fixes[name] = p
+		}
+	}
+	return fixes
+}
+
+// nextFixName returns the first name of the form prefix01, prefix02, ...
+// that the scenario group doesn't already use for a fix.
+func (se *ScenarioEditor) nextFixName(prefix string) string {
+	fixes := jsonObject(se.doc, "fixes")
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("%s%02d", prefix, i)
+		if _, ok := fixes.Get(name); !ok {
+			return name
+		}
+	}
+}
+
+func (se *ScenarioEditor) canvasClicked(p Point2LL) {
+	c := se.canvas
+	switch c.mode {
+	case canvasModeFix:
+		name := se.nextFixName(Select(c.fixPrefix != "", c.fixPrefix, "WP"))
+		jsonObject(se.doc, "fixes").Set(name, p.DMSString())
+		se.message = "Added fix " + name + " at " + p.DMSString()
+	case canvasModePolygon:
+		c.points = append(c.points, p)
+	case canvasModeCircle:
+		// The first click gives the center and the second the radius.
+		if len(c.points) == 2 {
+			c.points = nil
+		}
+		c.points = append(c.points, p)
+	}
+}
+
+// canvasVolume returns the JSON for the airspace volume defined by the
+// clicked points or nil if there aren't enough of them.
+func (se *ScenarioEditor) canvasVolume() *orderedmap.OrderedMap {
+	c := se.canvas
+	vol := newJSONObject()
+	vol.Set("name", c.volumeName)
+	switch c.mode {
+	case canvasModePolygon:
+		if len(c.points) < 3 {
+			return nil
+		}
+		vol.Set("type", "polygon")
+		var vertices []any
+		for _, p := range c.points {
+			vertices = append(vertices, p.DMSString())
+		}
+		vol.Set("vertices", vertices)
+	case canvasModeCircle:
+		if len(c.points) < 2 {
+			return nil
+		}
+		vol.Set("type", "circle")
+		vol.Set("center", c.points[0].DMSString())
+		r := nmdistance2ll(c.points[0], c.points[1])
+		vol.Set("radius", float64(int(10*r+0.5))/10)
+	default:
+		return nil
+	}
+	vol.Set("floor", float64(c.floor))
+	vol.Set("ceiling", float64(c.ceiling))
+	return vol
+}
+
+// DrawScenarioEditorCanvas draws the fixes that the scenario group
+// defines and the volume that is being defined while points are being
+// clicked on the scope.
+func (w *World) DrawScenarioEditorCanvas(transforms ScopeTransformations, color RGB, cb *CommandBuffer) {
+	se := w.scenarioEditor
+	if !se.Capturing() || se.canvas == nil {
+		return
+	}
+
+	ld := GetLinesDrawBuilder()
+	defer ReturnLinesDrawBuilder(ld)
+
+	for _, p := range se.documentFixes() {
+		ld.AddLatLongCircle(p, w.NmPerLongitude, 0.25, 8)
+	}
+
+	pts := se.canvas.points
+	for _, p := range pts {
+		ld.AddLatLongCircle(p, w.NmPerLongitude, 0.5, 16)
+	}
+	switch se.canvas.mode {
+	case canvasModePolygon:
+		if len(pts) > 2 {
+			var loop [][2]float32
+			for _, p := range pts {
+				loop = append(loop, p)
+			}
+			ld.AddLineLoop(loop)
+		} else if len(pts) == 2 {
+			ld.AddLine(pts[0], pts[1])
+		}
+	case canvasModeCircle:
+		if len(pts) == 2 {
+			ld.AddLatLongCircle(pts[0], w.NmPerLongitude, nmdistance2ll(pts[0], pts[1]), 64)
+		}
+	}
+
+	cb.LineWidth(2)
+	cb.SetRGB(color)
+	transforms.LoadLatLongViewingMatrices(cb)
+	ld.GenerateCommands(cb)
+}
+
+///////////////////////////////////////////////////////////////////////////
+// UI
+
+func (w *World) DrawScenarioEditor() {
+	se := w.scenarioEditor
+	if se == nil || !se.show {
+		return
+	}
+
+	imgui.BeginV("Scenario Editor", &se.show, imgui.WindowFlagsAlwaysAutoResize)
+
+	if imgui.Button("New") {
+		se.newDocument(w)
+	}
+	imgui.SameLine()
+	if imgui.Button("Open...") {
+		if se.openDialog == nil {
+			se.openDialog = NewFileSelectDialogBox("Open Scenario Group...", []string{".json"}, se.filename,
+				func(fn string) { se.open(fn) })
+		}
+		se.openDialog.Activate()
+	}
+	imgui.SameLine()
+	imgui.SetNextItemWidth(400)
+	imgui.InputTextV("##filename", &se.filename, 0, nil)
+	imgui.SameLine()
+	uiStartDisable(se.filename == "")
+	if imgui.Button("Save") {
+		se.save(false)
+	}
+	uiEndDisable(se.filename == "")
+
+	jsonInputText("TRACON", se.doc, "tracon", imgui.InputTextFlagsCharsUppercase)
+	jsonInputText("Name", se.doc, "name", 0)
+	jsonInputText("Primary airport", se.doc, "primary_airport", imgui.InputTextFlagsCharsUppercase)
+
+	if imgui.CollapsingHeader("Controller positions") {
+		se.drawControllers()
+	}
+	if imgui.CollapsingHeader("Arrival routes") {
+		se.drawArrivalRoutes()
+	}
+	if imgui.CollapsingHeader("Departure routes") {
+		se.drawDepartureRoutes()
+	}
+	if imgui.CollapsingHeader("Scenarios") {
+		se.drawScenarios()
+	}
+	if imgui.CollapsingHeader("CRDA regions") {
+		se.drawCRDARegions()
+	}
+	if imgui.CollapsingHeader("Fixes and volumes") {
+		se.drawCanvas()
+	}
+	if se.filename != "" && imgui.CollapsingHeader("Backups") {
+		drawBackupsUI(se.filename, func(b Backup) {
+			if err := RestoreBackup(b, se.filename); err != nil {
+				ShowErrorDialog("%s: unable to restore backup: %v", se.filename, err)
+			} else {
+				se.open(se.filename)
+				se.message = "Restored the backup from " + b.Time.Format("2006-01-02 15:04:05")
+			}
+		})
+	}
+
+	if se.region != nil {
+		imgui.Separator()
+		imgui.Text("Drag the handles on the scope to adjust " + se.region.label)
+		imgui.SameLine()
+		if imgui.Button("Done##region") {
+			se.region = nil
+		}
+	}
+	if se.capture != nil {
+		imgui.Separator()
+		imgui.Text("Click on fixes on the scope to add them to " + se.capture.label)
+		imgui.SameLine()
+		if imgui.Button("Done") {
+			se.capture = nil
+		}
+	}
+	if se.message != "" {
+		imgui.Text(se.message)
+	}
+	if len(se.problems) > 0 {
+		imgui.Separator()
+		imgui.PushStyleColor(imgui.StyleColorText, imgui.Vec4{1, .5, .5, 1})
+		imgui.Text("The scenario group has errors and wasn't saved:")
+		for _, p := range se.problems {
+			imgui.Text(p)
+		}
+		imgui.PopStyleColor()
+		if imgui.Button("Save anyway") {
+			se.save(true)
+		}
+	}
+
+	imgui.End()
+
+	if se.openDialog != nil {
+		se.openDialog.Draw()
+	}
+	if !se.show {
+		se.capture = nil
+		se.canvas = nil
+	}
+}
+
+// drawCanvas draws the UI for defining fixes and airspace volumes by
+// clicking on the scope.
+func (se *ScenarioEditor) drawCanvas() {
+	active := se.canvas != nil
+	if imgui.Checkbox("Define by clicking on the scope", &active) {
+		if active {
+			se.canvas = &scenarioEditorCanvas{fixPrefix: "WP", ceiling: 3000}
+			se.capture, se.region = nil, nil
+		} else {
+			se.canvas = nil
+		}
+	}
+	c := se.canvas
+	if c == nil {
+		return
+	}
+
+	for i, label := range []string{"Fixes", "Polygon volume", "Circle volume"} {
+		if i > 0 {
+			imgui.SameLine()
+		}
+		if imgui.RadioButtonInt(label, &c.mode, i) {
+			c.points = nil
+		}
+	}
+
+	if c.mode == canvasModeFix {
+		imgui.SetNextItemWidth(100)
+		imgui.InputTextV("Name prefix", &c.fixPrefix, imgui.InputTextFlagsCharsUppercase, nil)
+		imgui.Text("Each click adds a fix named " + se.nextFixName(Select(c.fixPrefix != "", c.fixPrefix, "WP")) +
+			", ... that can be used in routes, approaches, and as spawn points")
+		return
+	}
+
+	imgui.SetNextItemWidth(150)
+	imgui.InputTextV("Name", &c.volumeName, 0, nil)
+	imgui.SetNextItemWidth(100)
+	imgui.InputIntV("Floor", &c.floor, 100, 1000, 0)
+	imgui.SameLine()
+	imgui.SetNextItemWidth(100)
+	imgui.InputIntV("Ceiling", &c.ceiling, 100, 1000, 0)
+	imgui.SetNextItemWidth(200)
+	if imgui.BeginComboV("Add to", canvasVolumeTargets[c.volumeTarget], 0) {
+		for i, target := range canvasVolumeTargets {
+			if imgui.SelectableV(target, i == c.volumeTarget, 0, imgui.Vec2{}) {
+				c.volumeTarget = i
+			}
+		}
+		imgui.EndCombo()
+	}
+
+	if c.mode == canvasModePolygon {
+		imgui.Text(fmt.Sprintf("Click on the scope to add vertices (%d so far)", len(c.points)))
+	} else {
+		imgui.Text("Click on the scope for the center and then a point on the edge")
+	}
+
+	vol := se.canvasVolume()
+	uiStartDisable(vol == nil || c.ceiling <= c.floor)
+	if imgui.Button("Add volume") {
+		fa := jsonObject(se.doc, "stars_config")
+		target := canvasVolumeTargets[c.volumeTarget]
+		fa.Set(target, append(jsonArray(fa, target), vol))
+		c.points = nil
+		se.message = "Added volume to " + target
+	}
+	uiEndDisable(vol == nil || c.ceiling <= c.floor)
+	imgui.SameLine()
+	if imgui.Button("Clear points") {
+		c.points = nil
+	}
+}
+
+// drawAddItem draws the UI for adding a new named item to the given
+// object.
+func (se *ScenarioEditor) drawAddItem(label string, m *orderedmap.OrderedMap, uppercase bool, value func() any) string {
+	imgui.SetNextItemWidth(150)
+	imgui.InputTextV("##new"+label, &se.newName, Select(uppercase, imgui.InputTextFlagsCharsUppercase, 0), nil)
+	imgui.SameLine()
+	name := strings.TrimSpace(se.newName)
+	_, exists := m.Get(name)
+	uiStartDisable(name == "" || exists)
+	added := imgui.Button("Add " + label)
+	uiEndDisable(name == "" || exists)
+	if added {
+		m.Set(name, value())
+		se.newName = ""
+		return name
+	}
+	return ""
+}
+
+func (se *ScenarioEditor) drawControllers() {
+	positions := jsonObject(se.doc, "control_positions")
+	flags := imgui.TableFlagsBordersV | imgui.TableFlagsBordersOuterH | imgui.TableFlagsRowBg
+	if imgui.BeginTableV("positions", 7, flags, imgui.Vec2{}, 0) {
+		for _, col := range []string{"Callsign", "Name", "Frequency", "Sector", "Scope", "Facility", ""} {
+			imgui.TableSetupColumn(col)
+		}
+		imgui.TableHeadersRow()
+
+		for _, callsign := range positions.Keys() {
+			ctrl := jsonObject(positions, callsign)
+			imgui.PushID(callsign)
+			imgui.TableNextRow()
+			imgui.TableNextColumn()
+			imgui.Text(callsign)
+			imgui.TableNextColumn()
+			imgui.SetNextItemWidth(150)
+			jsonInputText("##name", ctrl, "full_name", 0)
+			imgui.TableNextColumn()
+			imgui.SetNextItemWidth(80)
+			jsonInputInt("##freq", ctrl, "frequency")
+			imgui.TableNextColumn()
+			imgui.SetNextItemWidth(50)
+			jsonInputText("##sector", ctrl, "sector_id", imgui.InputTextFlagsCharsUppercase)
+			imgui.TableNextColumn()
+			imgui.SetNextItemWidth(30)
+			jsonInputText("##scope", ctrl, "scope_char", imgui.InputTextFlagsCharsUppercase)
+			imgui.TableNextColumn()
+			imgui.SetNextItemWidth(30)
+			jsonInputText("##facility", ctrl, "facility_id", imgui.InputTextFlagsCharsUppercase)
+			imgui.TableNextColumn()
+			if imgui.Button(FontAwesomeIconTrash) {
+				positions.Delete(callsign)
+			}
+			imgui.PopID()
+		}
+		imgui.EndTable()
+	}
+
+	se.drawAddItem("position", positions, true, func() any {
+		ctrl := newJSONObject()
+		for _, key := range []string{"full_name", "frequency", "sector_id", "scope_char", "facility_id"} {
+			ctrl.Set(key, Select[any](key == "frequency", float64(118000), ""))
+		}
+		return ctrl
+	})
+}
+
+// drawRoute draws the UI for editing a route's waypoints, either by typing
+// them or by clicking on the scope.
+func (se *ScenarioEditor) drawRoute(label string, route *orderedmap.OrderedMap) {
+	imgui.SetNextItemWidth(400)
+	jsonInputText("##waypoints", route, "waypoints", imgui.InputTextFlagsCharsUppercase)
+	imgui.SameLine()
+	capturing := se.capture != nil && se.capture.route == route
+	if imgui.Button(Select(capturing, "Done", "Pick on scope")) {
+		if capturing {
+			se.capture = nil
+		} else {
+			se.capture = &scenarioEditorCapture{label: label, route: route}
+			se.region, se.canvas = nil, nil
+		}
+	}
+}
+
+// drawNameCombo draws a combo box for selecting one of the object's items.
+func drawNameCombo(label string, m *orderedmap.OrderedMap, selected *string) {
+	if _, ok := m.Get(*selected); !ok {
+		*selected = ""
+		if keys := m.Keys(); len(keys) > 0 {
+			*selected = keys[0]
+		}
+	}
+	imgui.SetNextItemWidth(200)
+	if imgui.BeginComboV(label, *selected, 0) {
+		for _, name := range m.Keys() {
+			if imgui.SelectableV(name, name == *selected, 0, imgui.Vec2{}) {
+				*selected = name
+			}
+		}
+		imgui.EndCombo()
+	}
+}
+
+func (se *ScenarioEditor) drawArrivalRoutes() {
+	groups := jsonObject(se.doc, "arrival_groups")
+	drawNameCombo("Arrival group", groups, &se.arrivalGroup)
+	imgui.SameLine()
+	if name := se.drawAddItem("group", groups, true, func() any { return []any{} }); name != "" {
+		se.arrivalGroup = name
+	}
+	if se.arrivalGroup == "" {
+		return
+	}
+
+	arrivals := jsonArray(groups, se.arrivalGroup)
+	for i := 0; i < len(arrivals); i++ {
+		arr, ok := arrivals[i].(*orderedmap.OrderedMap)
+		if !ok {
+			continue
+		}
+		imgui.PushIDInt(i)
+		imgui.Separator()
+		label := fmt.Sprintf("%s arrival %d", se.arrivalGroup, i+1)
+		imgui.Text(fmt.Sprintf("Arrival %d", i+1))
+		imgui.SameLine()
+		if imgui.Button(FontAwesomeIconTrash) {
+			arrivals = slices.Delete(arrivals, i, i+1)
+			groups.Set(se.arrivalGroup, arrivals)
+			se.capture = nil
+			imgui.PopID()
+			break
+		}
+		se.drawRoute(label, arr)
+
+		imgui.SetNextItemWidth(100)
+		jsonInputInt("Initial altitude", arr, "initial_altitude")
+		imgui.SameLine()
+		imgui.SetNextItemWidth(100)
+		jsonInputInt("Initial speed", arr, "initial_speed")
+		imgui.SameLine()
+		imgui.SetNextItemWidth(100)
+		jsonInputText("Initial controller", arr, "initial_controller", imgui.InputTextFlagsCharsUppercase)
+
+		// Airlines, per destination airport, as space-separated ICAO
+		// codes. Existing entries are kept as is so that their fleets
+		// aren't lost.
+		airlines := jsonObject(arr, "airlines")
+		for _, ap := range airlines.Keys() {
+			var icaos []string
+			for _, al := range jsonArray(airlines, ap) {
+				if al, ok := al.(*orderedmap.OrderedMap); ok {
+					icaos = append(icaos, jsonString(al, "icao"))
+				}
+			}
+			s := strings.Join(icaos, " ")
+			imgui.SetNextItemWidth(300)
+			if imgui.InputTextV("Airlines to "+ap, &s, imgui.InputTextFlagsCharsUppercase, nil) {
+				var updated []any
+				for _, icao := range strings.Fields(s) {
+					idx := slices.IndexFunc(jsonArray(airlines, ap), func(al any) bool {
+						o, ok := al.(*orderedmap.OrderedMap)
+						return ok && jsonString(o, "icao") == icao
+					})
+					if idx != -1 {
+						updated = append(updated, jsonArray(airlines, ap)[idx])
+					} else {
+						al := newJSONObject()
+						al.Set("icao", icao)
+						updated = append(updated, al)
+					}
+				}
+				airlines.Set(ap, updated)
+			}
+			imgui.SameLine()
+			if imgui.Button(FontAwesomeIconTrash + "##" + ap) {
+				airlines.Delete(ap)
+			}
+		}
+		se.drawAddItem("destination", airlines, true, func() any { return []any{} })
+
+		imgui.PopID()
+	}
+
+	imgui.Separator()
+	if imgui.Button("Add arrival") {
+		arr := newJSONObject()
+		arr.Set("waypoints", "")
+		arr.Set("initial_altitude", float64(10000))
+		arr.Set("initial_speed", float64(250))
+		arr.Set("initial_controller", "")
+		arr.Set("airlines", newJSONObject())
+		groups.Set(se.arrivalGroup, append(arrivals, arr))
+	}
+}
+
+func (se *ScenarioEditor) drawDepartureRoutes() {
+	airports := jsonObject(se.doc, "airports")
+	drawNameCombo("Airport", airports, &se.airport)
+	imgui.SameLine()
+	if name := se.drawAddItem("airport", airports, true, func() any { return newJSONObject() }); name != "" {
+		se.airport = name
+	}
+	if se.airport == "" {
+		return
+	}
+
+	routes := jsonObject(jsonObject(airports, se.airport), "departure_routes")
+	for _, rwy := range routes.Keys() {
+		exits := jsonObject(routes, rwy)
+		imgui.PushID(rwy)
+		imgui.Separator()
+		imgui.Text("Runway " + rwy)
+		imgui.SameLine()
+		if imgui.Button(FontAwesomeIconTrash) {
+			routes.Delete(rwy)
+			se.capture = nil
+			imgui.PopID()
+			continue
+		}
+
+		for _, exit := range exits.Keys() {
+			route := jsonObject(exits, exit)
+			imgui.PushID(exit)
+			imgui.Text(exit)
+			imgui.SameLine()
+			se.drawRoute(se.airport+" runway "+rwy+" "+exit+" departures", route)
+			imgui.SameLine()
+			imgui.SetNextItemWidth(100)
+			jsonInputInt("Altitude", route, "cleared_altitude")
+			imgui.SameLine()
+			if imgui.Button(FontAwesomeIconTrash) {
+				exits.Delete(exit)
+				se.capture = nil
+			}
+			imgui.PopID()
+		}
+		se.drawAddItem("exit", exits, true, func() any {
+			route := newJSONObject()
+			route.Set("waypoints", "")
+			route.Set("cleared_altitude", float64(5000))
+			return route
+		})
+		imgui.PopID()
+	}
+	imgui.Separator()
+	se.drawAddItem("runway", routes, true, func() any { return newJSONObject() })
+}
+
+func (se *ScenarioEditor) drawScenarios() {
+	scenarios := jsonObject(se.doc, "scenarios")
+	drawNameCombo("Scenario", scenarios, &se.scenario)
+	imgui.SameLine()
+	if name := se.drawAddItem("scenario", scenarios, false, func() any {
+		sc := newJSONObject()
+		sc.Set("solo_controller", "")
+		sc.Set("controllers", []any{})
+		sc.Set("departure_runways", []any{})
+		sc.Set("arrival_runways", []any{})
+		sc.Set("arrivals", newJSONObject())
+		return sc
+	}); name != "" {
+		se.scenario = name
+	}
+	if se.scenario == "" {
+		return
+	}
+	sc := jsonObject(scenarios, se.scenario)
+
+	isDefault := jsonString(se.doc, "default_scenario") == se.scenario
+	if imgui.Checkbox("Default scenario", &isDefault) && isDefault {
+		se.doc.Set("default_scenario", se.scenario)
+	}
+
+	// The solo controller is chosen from the positions.
+	positions := jsonObject(se.doc, "control_positions")
+	solo := jsonString(sc, "solo_controller")
+	imgui.SetNextItemWidth(200)
+	if imgui.BeginComboV("Solo controller", solo, 0) {
+		for _, callsign := range positions.Keys() {
+			if imgui.SelectableV(callsign, callsign == solo, 0, imgui.Vec2{}) {
+				sc.Set("solo_controller", callsign)
+			}
+		}
+		imgui.EndCombo()
+	}
+
+	var virtual []string
+	for _, c := range jsonArray(sc, "controllers") {
+		if c, ok := c.(string); ok {
+			virtual = append(virtual, c)
+		}
+	}
+	s := strings.Join(virtual, " ")
+	imgui.SetNextItemWidth(400)
+	if imgui.InputTextV("Virtual controllers", &s, imgui.InputTextFlagsCharsUppercase, nil) {
+		var cs []any
+		for _, c := range strings.Fields(s) {
+			cs = append(cs, c)
+		}
+		sc.Set("controllers", cs)
+	}
+
+	// Runway configuration and departure rates
+	imgui.Text("Departure runways")
+	deps := jsonArray(sc, "departure_runways")
+	for i := 0; i < len(deps); i++ {
+		rwy, ok := deps[i].(*orderedmap.OrderedMap)
+		if !ok {
+			continue
+		}
+		imgui.PushIDInt(i)
+		imgui.SetNextItemWidth(60)
+		jsonInputText("##airport", rwy, "airport", imgui.InputTextFlagsCharsUppercase)
+		imgui.SameLine()
+		imgui.SetNextItemWidth(40)
+		jsonInputText("##runway", rwy, "runway", imgui.InputTextFlagsCharsUppercase)
+		imgui.SameLine()
+		imgui.SetNextItemWidth(100)
+		jsonInputText("##category", rwy, "category", 0)
+		imgui.SameLine()
+		imgui.SetNextItemWidth(60)
+		jsonInputInt("per hour##rate", rwy, "rate")
+		imgui.SameLine()
+		if imgui.Button(FontAwesomeIconTrash) {
+			deps = slices.Delete(deps, i, i+1)
+			sc.Set("departure_runways", deps)
+			imgui.PopID()
+			break
+		}
+		imgui.PopID()
+	}
+	if imgui.Button("Add departure runway") {
+		rwy := newJSONObject()
+		rwy.Set("airport", jsonString(se.doc, "primary_airport"))
+		rwy.Set("runway", "")
+		rwy.Set("rate", float64(30))
+		sc.Set("departure_runways", append(deps, rwy))
+	}
+
+	imgui.Text("Arrival runways")
+	arrs := jsonArray(sc, "arrival_runways")
+	for i := 0; i < len(arrs); i++ {
+		rwy, ok := arrs[i].(*orderedmap.OrderedMap)
+		if !ok {
+			continue
+		}
+		imgui.PushIDInt(1000 + i)
+		imgui.SetNextItemWidth(60)
+		jsonInputText("##airport", rwy, "airport", imgui.InputTextFlagsCharsUppercase)
+		imgui.SameLine()
+		imgui.SetNextItemWidth(40)
+		jsonInputText("##runway", rwy, "runway", imgui.InputTextFlagsCharsUppercase)
+		imgui.SameLine()
+		if imgui.Button(FontAwesomeIconTrash) {
+			arrs = slices.Delete(arrs, i, i+1)
+			sc.Set("arrival_runways", arrs)
+			imgui.PopID()
+			break
+		}
+		imgui.PopID()
+	}
+	if imgui.Button("Add arrival runway") {
+		rwy := newJSONObject()
+		rwy.Set("airport", jsonString(se.doc, "primary_airport"))
+		rwy.Set("runway", "")
+		sc.Set("arrival_runways", append(arrs, rwy))
+	}
+
+	// Arrival rates: one for each destination airport of each of the
+	// arrival groups; a zero rate removes it from the scenario.
+	imgui.Text("Arrival rates")
+	rates := jsonObject(sc, "arrivals")
+	groups := jsonObject(se.doc, "arrival_groups")
+	for _, group := range groups.Keys() {
+		var airports []string
+		for _, arr := range jsonArray(groups, group) {
+			if arr, ok := arr.(*orderedmap.OrderedMap); ok {
+				for _, ap := range jsonObject(arr, "airlines").Keys() {
+					if !slices.Contains(airports, ap) {
+						airports = append(airports, ap)
+					}
+				}
+			}
+		}
+
+		for _, ap := range airports {
+			groupRates := jsonObject(rates, group)
+			r := int32(jsonInt(groupRates, ap))
+			imgui.SetNextItemWidth(60)
+			if imgui.InputIntV(group+" to "+ap+" per hour", &r, 0, 0, 0) {
+				if r > 0 {
+					groupRates.Set(ap, float64(r))
+				} else {
+					groupRates.Delete(ap)
+				}
+			}
+			if len(groupRates.Keys()) == 0 {
+				rates.Delete(group)
+			}
+		}
+	}
+}
+
+// drawCRDARegions draws the UI for editing the selected airport's CRDA
+// qualification regions, both the airport's and those that converging
+// runway pairs use instead of them.
+func (se *ScenarioEditor) drawCRDARegions() {
+	airports := jsonObject(se.doc, "airports")
+	drawNameCombo("Airport##crda", airports, &se.airport)
+	if se.airport == "" {
+		return
+	}
+	ap := jsonObject(airports, se.airport)
+
+	regions := jsonObject(ap, "approach_regions")
+	for _, rwy := range regions.Keys() {
+		imgui.PushID(rwy)
+		imgui.Text("Runway " + rwy)
+		imgui.SameLine()
+		se.drawRegionButtons(se.airport+" runway "+rwy+" region", regions, rwy)
+		imgui.PopID()
+	}
+	se.drawAddItem("region", regions, true, func() any {
+		// A starting point that is then adjusted on the scope.
+		ar := newJSONObject()
+		ar.Set("reference_point", se.airport)
+		for _, kv := range []struct {
+			key string
+			v   float64
+		}{{"reference_heading", 0}, {"reference_length", 20}, {"reference_altitude", 0},
+			{"heading_tolerance", 90}, {"near_distance", 1}, {"near_half_width", 0.5},
+			{"far_half_width", 2}, {"region_length", 15}, {"descent_distance", 10},
+			{"descent_altitude", 3000}, {"above_altitude_tolerance", 500},
+			{"below_altitude_tolerance", 500}} {
+			ar.Set(kv.key, kv.v)
+		}
+		return ar
+	})
+
+	for i, p := range jsonArray(ap, "converging_runways") {
+		pair, ok := p.(*orderedmap.OrderedMap)
+		if !ok {
+			continue
+		}
+		var rwys []string
+		for _, r := range jsonArray(pair, "runways") {
+			if r, ok := r.(string); ok {
+				rwys = append(rwys, r)
+			}
+		}
+
+		imgui.PushID(fmt.Sprintf("pair%d", i))
+		imgui.Separator()
+		imgui.Text("Converging runways " + strings.Join(rwys, "/"))
+		for _, rwy := range rwys {
+			imgui.PushID(rwy)
+			label := se.airport + " " + strings.Join(rwys, "/") + " runway " + rwy + " region"
+			// Only add "approach_regions" to the pair if it gets one.
+			var pairRegions *orderedmap.OrderedMap
+			custom := false
+			if _, ok := pair.Get("approach_regions"); ok {
+				pairRegions = jsonObject(pair, "approach_regions")
+				_, custom = pairRegions.Get(rwy)
+			}
+
+			if custom {
+				imgui.Text("  Runway " + rwy + ": own region")
+				imgui.SameLine()
+				se.drawRegionButtons(label, pairRegions, rwy)
+				if len(pairRegions.Keys()) == 0 {
+					pair.Delete("approach_regions")
+				}
+			} else {
+				imgui.Text("  Runway " + rwy + ": airport's region")
+				imgui.SameLine()
+				if imgui.Button("Customize for pair") {
+					ar := newJSONObject()
+					if _, ok := regions.Get(rwy); ok {
+						ar = jsonCopy(jsonObject(regions, rwy))
+					}
+					jsonObject(pair, "approach_regions").Set(rwy, ar)
+					se.capture, se.canvas = nil, nil
+					se.region = &scenarioEditorRegion{label: label, obj: ar, handle: -1}
+				}
+			}
+			imgui.PopID()
+		}
+		imgui.PopID()
+	}
+
+	if r := se.region; r != nil {
+		imgui.Separator()
+		imgui.Text("Editing " + r.label)
+		imgui.SetNextItemWidth(200)
+		jsonInputText("Reference point", r.obj, "reference_point", imgui.InputTextFlagsCharsUppercase)
+		for _, f := range []struct{ label, key string }{
+			{"Reference heading", "reference_heading"}, {"Reference length (nm)", "reference_length"},
+			{"Heading tolerance", "heading_tolerance"}, {"Near distance (nm)", "near_distance"},
+			{"Region length (nm)", "region_length"}, {"Near half width (nm)", "near_half_width"},
+			{"Far half width (nm)", "far_half_width"}, {"Descent point distance (nm)", "descent_distance"},
+			{"Descent point altitude", "descent_altitude"},
+		} {
+			imgui.SetNextItemWidth(200)
+			jsonInputFloat(f.label, r.obj, f.key)
+		}
+	}
+}
+
+// drawRegionButtons draws the buttons for editing or deleting the region
+// with the given key.
+func (se *ScenarioEditor) drawRegionButtons(label string, regions *orderedmap.OrderedMap, key string) {
+	obj := jsonObject(regions, key)
+	editing := se.region != nil && se.region.obj == obj
+	if imgui.Button(Select(editing, "Done", "Edit on scope")) {
+		if editing {
+			se.region = nil
+		} else {
+			se.capture, se.canvas = nil, nil
+			se.region = &scenarioEditorRegion{label: label, obj: obj, handle: -1}
+		}
+	}
+	imgui.SameLine()
+	if imgui.Button(FontAwesomeIconTrash) {
+		regions.Delete(key)
+		if editing {
+			se.region = nil
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// scenarioeditor_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+func TestScenarioEditorJSON(t *testing.T) {
+	doc := newJSONObject()
+	if err := json.Unmarshal([]byte(`{"tracon":"N90","arrival_groups":{"CAMRN":[{"waypoints":"CAMRN","initial_altitude":11000,"airlines":{"KJFK":[{"icao":"JBU","fleet":"short"}]}}]},"zzz":[1,2]}`), doc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc = jsonPointerize(doc).(*orderedmap.OrderedMap)
+
+	// Modify the nested objects in place.
+	arr := jsonArray(jsonObject(doc, "arrival_groups"), "CAMRN")[0].(*orderedmap.OrderedMap)
+	if s := jsonString(arr, "waypoints"); s != "CAMRN" {
+		t.Errorf("got waypoints %q, expected \"CAMRN\"", s)
+	}
+	if alt := jsonInt(arr, "initial_altitude"); alt != 11000 {
+		t.Errorf("got initial altitude %d, expected 11000", alt)
+	}
+	arr.Set("waypoints", "CAMRN KINGS")
+	jsonObject(arr, "airlines").Set("KLGA", []any{})
+	jsonObject(doc, "control_positions").Set("JFK_APP", newJSONObject())
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"tracon":"N90","arrival_groups":{"CAMRN":[{"waypoints":"CAMRN KINGS","initial_altitude":11000,"airlines":{"KJFK":[{"icao":"JBU","fleet":"short"}],"KLGA":[]}}]},"zzz":[1,2],"control_positions":{"JFK_APP":{}}}`
+	if string(b) != expected {
+		t.Errorf("got %s, expected %s", string(b), expected)
+	}
+}
+
+func TestAdjustApproachRegion(t *testing.T) {
+	// The reference line runs north from the reference point.
+	ar := &ApproachRegion{
+		ReferenceLineHeading: 180,
+		ReferenceLineLength:  20,
+		ReferencePoint:       Point2LL{-73, 40},
+		NearDistance:         1,
+		RegionLength:         10,
+		NearHalfWidth:        0.5,
+		FarHalfWidth:         2,
+	}
+	const nmPerLongitude, magneticVariation = 46, 0
+	p0 := ll2nm(ar.ReferencePoint, nmPerLongitude)
+
+	expect := func(what string, got, expected float32) {
+		if abs(got-expected) > 0.001 {
+			t.Errorf("%s: got %f, expected %f", what, got, expected)
+		}
+	}
+
+	h := approachRegionHandles(ar, nmPerLongitude, magneticVariation)
+	expect("near handle", distance2f(h[0], p0), 1)
+	expect("far handle", distance2f(h[1], p0), 11)
+	expect("near width handle", distance2f(h[2], h[0]), 0.5)
+	expect("far width handle", distance2f(h[3], h[1]), 2)
+
+	// Moving the far edge changes the length but not the near edge.
+	adjustApproachRegion(ar, 1, add2f(p0, [2]float32{0.3, 15}), nmPerLongitude, magneticVariation)
+	expect("near distance", ar.NearDistance, 1)
+	expect("region length", ar.RegionLength, 14)
+
+	// Moving the near edge keeps the far edge in place.
+	adjustApproachRegion(ar, 0, add2f(p0, [2]float32{0, 3}), nmPerLongitude, magneticVariation)
+	expect("near distance", ar.NearDistance, 3)
+	expect("region length", ar.RegionLength, 12)
+
+	// Widths are the distance from the reference line on either side.
+	adjustApproachRegion(ar, 2, add2f(p0, [2]float32{-1.5, 3}), nmPerLongitude, magneticVariation)
+	expect("near half width", ar.NearHalfWidth, 1.5)
+	adjustApproachRegion(ar, 3, add2f(p0, [2]float32{4, 12}), nmPerLongitude, magneticVariation)
+	expect("far half width", ar.FarHalfWidth, 4)
+}
+
+func TestScenarioEditorCanvas(t *testing.T) {
+	se := &ScenarioEditor{doc: newJSONObject()}
+	se.canvas = &scenarioEditorCanvas{fixPrefix: "WP", floor: 0, ceiling: 3000}
+
+	// Fixes get the first unused name.
+	jsonObject(se.doc, "fixes").Set("WP01", "N040.00.00.000,W073.00.00.000")
+	se.canvasClicked(Point2LL{-73.5, 40.5})
+	fixes := se.documentFixes()
+	if p, ok := fixes["WP02"]; !ok || len(fixes) != 2 || abs(p[0]+73.5) > 1e-3 || abs(p[1]-40.5) > 1e-3 {
+		t.Errorf("got fixes %v", fixes)
+	}
+
+	// Polygons need at least three vertices.
+	se.canvas.mode = canvasModePolygon
+	se.canvasClicked(Point2LL{-73, 40})
+	se.canvasClicked(Point2LL{-73, 41})
+	if vol := se.canvasVolume(); vol != nil {
+		t.Errorf("got volume with two vertices")
+	}
+	se.canvasClicked(Point2LL{-74, 41})
+	if vol := se.canvasVolume(); vol == nil || jsonString(vol, "type") != "polygon" || len(jsonArray(vol, "vertices")) != 3 ||
+		jsonInt(vol, "ceiling") != 3000 {
+		t.Errorf("got polygon volume %+v", vol)
+	}
+
+	// Circles are given by their center and a point on the edge; a third
+	// click starts over.
+	se.canvas.mode, se.canvas.points = canvasModeCircle, nil
+	se.canvasClicked(Point2LL{-73, 40})
+	se.canvasClicked(Point2LL{-73, 40.1})
+	vol := se.canvasVolume()
+	if vol == nil || jsonString(vol, "center") != "N040.00.00.000,W073.00.00.000" || jsonFloat(vol, "radius") != 6 {
+		t.Errorf("got circle volume %+v", vol)
+	}
+	se.canvasClicked(Point2LL{-74, 41})
+	if len(se.canvas.points) != 1 || se.canvasVolume() != nil {
+		t.Errorf("third click didn't start a new circle")
+	}
+}
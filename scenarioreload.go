@@ -0,0 +1,276 @@
+// scenarioreload.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// ScenarioReload holds the parts of a reloaded scenario group that are
+// used to spawn aircraft along with a summary of what changed.
+type ScenarioReload struct {
+	Changes          []string
+	LaunchConfig     LaunchConfig
+	Airports         map[string]*Airport
+	Fixes            map[string]Point2LL
+	ArrivalGroups    map[string][]Arrival
+	DepartureRunways []ScenarioGroupDepartureRunway
+	ArrivalRunways   []ScenarioGroupArrivalRunway
+}
+
+// rereadScenarioGroup loads the given scenario group from the file that
+// it was originally loaded from and validates it. The new definition is
+// only used by the sims that reload it; sims that are started later use
+// the definition that was loaded at startup.
+func (sm *SimManager) rereadScenarioGroup(tracon, name string) (*ScenarioGroup, error) {
+	sm.mu.Lock(sm.lg)
+	sg, ok := sm.scenarioGroups[tracon][name]
+	sm.mu.Unlock(sm.lg)
+	if !ok || sg.sourceFS == nil {
+		return nil, ErrUnknownScenarioGroup
+	}
+
+	var e ErrorLogger
+	if sg = loadScenarioGroup(sg.sourceFS, sg.sourcePath, &e); sg != nil {
+		if sg.TRACON != tracon || sg.Name != name {
+			e.ErrorString("scenario group is now %s / %s; it must remain %s / %s to be reloaded",
+				sg.TRACON, sg.Name, tracon, name)
+		} else {
+			// The sim configurations are only needed at startup.
+			sg.PostDeserialize(&e, make(map[string]map[string]*SimConfiguration))
+		}
+	}
+	if e.HaveErrors() {
+		return nil, errors.New(e.String())
+	}
+	return sg, nil
+}
+
+// ReloadScenarioGroup switches to the given new definition of the sim's
+// scenario group for aircraft spawned from here on; aircraft that are
+// already flying are unaffected. Only the launch controller may reload
+// the scenario group.
+func (s *Sim) ReloadScenarioGroup(token string, sg *ScenarioGroup) (ScenarioReload, error) {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	if ctrl, ok := s.controllers[token]; !ok {
+		return ScenarioReload{}, ErrInvalidControllerToken
+	} else if ctrl.Callsign != s.LaunchConfig.Controller {
+		return ScenarioReload{}, ErrNotLaunchController
+	}
+
+	sc, ok := sg.Scenarios[s.Scenario]
+	if !ok {
+		return ScenarioReload{}, ErrUnknownScenario
+	}
+	var prev *Scenario
+	if s.scenarioGroup != nil {
+		prev = s.scenarioGroup.Scenarios[s.Scenario]
+	}
+
+	r := ScenarioReload{
+		Changes: diffScenarioRoutes(s.World.Airports, sg.Airports, s.World.ArrivalGroups,
+			sg.ArrivalGroups),
+		Airports:         sg.Airports,
+		Fixes:            sg.Fixes,
+		ArrivalGroups:    sg.ArrivalGroups,
+		DepartureRunways: sc.DepartureRunways,
+		ArrivalRunways:   sc.ArrivalRunways,
+	}
+	lc, rateChanges := reloadLaunchRates(s.LaunchConfig, prev, sc)
+	r.Changes = append(r.Changes, rateChanges...)
+
+	s.scenarioGroup = sg
+	s.updateLaunchConfig(lc)
+	r.LaunchConfig = s.LaunchConfig
+	s.World.applyScenarioReload(r)
+
+	s.lg.Info("reloaded scenario group", slog.String("group", sg.Name), slog.Any("changes", r.Changes))
+	s.eventStream.Post(Event{
+		Type:    StatusMessageEvent,
+		Message: fmt.Sprintf("Scenario group %s reloaded: %d changes", sg.Name, len(r.Changes)),
+	})
+	return r, nil
+}
+
+// diffScenarioRoutes returns descriptions of the differences in the
+// departures and arrival routes between two versions of a scenario group.
+func diffScenarioRoutes(oldAirports, newAirports map[string]*Airport,
+	oldArrivals, newArrivals map[string][]Arrival) []string {
+	var changes []string
+	for _, name := range SortedMapKeys(newAirports) {
+		ap, oldap := newAirports[name], oldAirports[name]
+		if oldap == nil {
+			changes = append(changes, name+": airport added")
+		} else if !reflect.DeepEqual(oldap.Departures, ap.Departures) ||
+			!reflect.DeepEqual(oldap.DepartureRoutes, ap.DepartureRoutes) {
+			changes = append(changes, name+": departures changed")
+		}
+	}
+	for _, name := range SortedMapKeys(oldAirports) {
+		if _, ok := newAirports[name]; !ok {
+			changes = append(changes, name+": airport removed")
+		}
+	}
+
+	for _, group := range SortedMapKeys(newArrivals) {
+		if oldArr, ok := oldArrivals[group]; !ok {
+			changes = append(changes, group+": arrival group added")
+		} else if !reflect.DeepEqual(oldArr, newArrivals[group]) {
+			changes = append(changes, group+": arrival routes changed")
+		}
+	}
+	for _, group := range SortedMapKeys(oldArrivals) {
+		if _, ok := newArrivals[group]; !ok {
+			changes = append(changes, group+": arrival group removed")
+		}
+	}
+
+	return changes
+}
+
+// reloadLaunchRates returns the launch configuration to use after the
+// scenario is reloaded, where prev is the scenario's previous definition
+// (or nil if it's unknown) and sc is its new one. Spawn rates that were
+// changed in the scenario file replace the current rates while those that
+// weren't keep any adjustments made in the launch control window. Entries
+// that are no longer in the scenario are removed.
+func reloadLaunchRates(cur LaunchConfig, prev, sc *Scenario) (LaunchConfig, []string) {
+	var old LaunchConfig
+	if prev != nil {
+		old = MakeLaunchConfig(prev.DepartureRunways, prev.ArrivalGroupDefaultRates)
+	}
+	defaults := MakeLaunchConfig(sc.DepartureRunways, sc.ArrivalGroupDefaultRates)
+
+	var changes []string
+	rate := func(name string, r, oldDefault, current int, isOld, isCurrent bool) int {
+		if isCurrent && isOld && r == oldDefault {
+			return current
+		} else if !isCurrent {
+			changes = append(changes, fmt.Sprintf("%s: added at %d/hour", name, r))
+		} else if r != current {
+			changes = append(changes, fmt.Sprintf("%s: rate %d -> %d/hour", name, current, r))
+		}
+		return r
+	}
+
+	lc := cur
+	lc.DepartureRates = make(map[string]map[string]map[string]int)
+	for ap, rwyRates := range defaults.DepartureRates {
+		lc.DepartureRates[ap] = make(map[string]map[string]int)
+		for rwy, categoryRates := range rwyRates {
+			lc.DepartureRates[ap][rwy] = make(map[string]int)
+			for category, r := range categoryRates {
+				oldDefault, isOld := old.DepartureRates[ap][rwy][category]
+				current, isCurrent := cur.DepartureRates[ap][rwy][category]
+				lc.DepartureRates[ap][rwy][category] = rate(departureRateName(ap, rwy, category),
+					r, oldDefault, current, isOld, isCurrent)
+			}
+		}
+	}
+	for ap, rwyRates := range cur.DepartureRates {
+		for rwy, categoryRates := range rwyRates {
+			for category := range categoryRates {
+				if _, ok := lc.DepartureRates[ap][rwy][category]; !ok {
+					changes = append(changes, departureRateName(ap, rwy, category)+": removed")
+				}
+			}
+		}
+	}
+
+	lc.ArrivalGroupRates = make(map[string]map[string]int)
+	for group, airportRates := range defaults.ArrivalGroupRates {
+		lc.ArrivalGroupRates[group] = make(map[string]int)
+		for ap, r := range airportRates {
+			oldDefault, isOld := old.ArrivalGroupRates[group][ap]
+			current, isCurrent := cur.ArrivalGroupRates[group][ap]
+			lc.ArrivalGroupRates[group][ap] = rate(group+" arrivals to "+ap, r, oldDefault, current,
+				isOld, isCurrent)
+		}
+	}
+	for group, airportRates := range cur.ArrivalGroupRates {
+		for ap := range airportRates {
+			if _, ok := lc.ArrivalGroupRates[group][ap]; !ok {
+				changes = append(changes, group+" arrivals to "+ap+": removed")
+			}
+		}
+	}
+
+	slices.Sort(changes)
+	return lc, changes
+}
+
+func departureRateName(airport, runway, category string) string {
+	if category == "" {
+		return airport + "/" + runway + " departures"
+	}
+	return airport + "/" + runway + " " + category + " departures"
+}
+
+// applyScenarioReload updates the World's definitions that are used to
+// spawn aircraft.
+func (w *World) applyScenarioReload(r ScenarioReload) {
+	w.LaunchConfig = r.LaunchConfig
+	w.Airports = r.Airports
+	w.Fixes = r.Fixes
+	w.ArrivalGroups = r.ArrivalGroups
+	w.DepartureRunways = r.DepartureRunways
+	w.ArrivalRunways = r.ArrivalRunways
+
+	w.DepartureAirports = make(map[string]*Airport)
+	for name := range w.LaunchConfig.DepartureRates {
+		w.DepartureAirports[name] = w.GetAirport(name)
+	}
+	w.ArrivalAirports = make(map[string]*Airport)
+	for _, airportRates := range w.LaunchConfig.ArrivalGroupRates {
+		for name := range airportRates {
+			w.ArrivalAirports[name] = w.GetAirport(name)
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Client side
+
+func (w *World) ReloadScenarioGroup(eventStream *EventStream) {
+	var r ScenarioReload
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.ReloadScenarioGroup(&r),
+			IssueTime: time.Now(),
+			OnSuccess: func(any) {
+				w.applyScenarioReload(r)
+				// Start over with the manual launch aircraft, since the
+				// departures and arrivals may have changed.
+				w.launchControlWindow = nil
+				for _, c := range r.Changes {
+					eventStream.Post(Event{Type: StatusMessageEvent, Message: c})
+				}
+			},
+			OnErr: func(err error) {
+				lg.Errorf("%v", err)
+				ShowErrorDialog("Unable to reload the scenario group:\n%v", err)
+			},
+		})
+}
+
+// drawScenarioReload draws the launch control UI for reloading the
+// scenario group's definition from its file.
+func (lc *LaunchControlWindow) drawScenarioReload(eventStream *EventStream) {
+	if imgui.Button("Reload scenario") {
+		lc.w.ReloadScenarioGroup(eventStream)
+	}
+	if imgui.IsItemHovered() {
+		imgui.SetTooltip("Reload the scenario group's JSON file; changes apply to aircraft spawned from now on")
+	}
+}
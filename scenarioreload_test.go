@@ -0,0 +1,78 @@
+// scenarioreload_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestReloadLaunchRates(t *testing.T) {
+	prev := &Scenario{
+		DepartureRunways: []ScenarioGroupDepartureRunway{
+			{Airport: "KJFK", Runway: "31L", DefaultRate: 30},
+			{Airport: "KJFK", Runway: "31L", Category: "Water", DefaultRate: 5},
+			{Airport: "KLGA", Runway: "13", DefaultRate: 20},
+		},
+		ArrivalGroupDefaultRates: map[string]map[string]int{
+			"CAMRN": {"KJFK": 20},
+			"LENDY": {"KJFK": 10, "KLGA": 10},
+		},
+	}
+	sc := &Scenario{
+		DepartureRunways: []ScenarioGroupDepartureRunway{
+			{Airport: "KJFK", Runway: "31L", DefaultRate: 30},                    // unchanged
+			{Airport: "KJFK", Runway: "31L", Category: "Water", DefaultRate: 10}, // changed
+			{Airport: "KEWR", Runway: "22R", DefaultRate: 25},                    // added
+		},
+		ArrivalGroupDefaultRates: map[string]map[string]int{
+			"CAMRN": {"KJFK": 20},
+			"LENDY": {"KJFK": 15},
+		},
+	}
+
+	// The user has adjusted some of the rates.
+	cur := MakeLaunchConfig(prev.DepartureRunways, map[string]map[string]int{
+		"CAMRN": {"KJFK": 25},
+		"LENDY": {"KJFK": 5, "KLGA": 10},
+	})
+	cur.DepartureRates["KJFK"]["31L"][""] = 40
+	cur.DepartureRates["KJFK"]["31L"]["Water"] = 0
+
+	lc, changes := reloadLaunchRates(cur, prev, sc)
+
+	expectRate := func(what string, got, expected int) {
+		if got != expected {
+			t.Errorf("%s: got rate %d, expected %d", what, got, expected)
+		}
+	}
+	expectRate("KJFK/31L", lc.DepartureRates["KJFK"]["31L"][""], 40)
+	expectRate("KJFK/31L Water", lc.DepartureRates["KJFK"]["31L"]["Water"], 10)
+	expectRate("KEWR/22R", lc.DepartureRates["KEWR"]["22R"][""], 25)
+	expectRate("CAMRN", lc.ArrivalGroupRates["CAMRN"]["KJFK"], 25)
+	expectRate("LENDY", lc.ArrivalGroupRates["LENDY"]["KJFK"], 15)
+	if _, ok := lc.DepartureRates["KLGA"]; ok {
+		t.Errorf("KLGA departures should have been removed")
+	}
+	if _, ok := lc.ArrivalGroupRates["LENDY"]["KLGA"]; ok {
+		t.Errorf("LENDY arrivals to KLGA should have been removed")
+	}
+
+	expected := []string{
+		"KEWR/22R departures: added at 25/hour",
+		"KJFK/31L Water departures: rate 0 -> 10/hour",
+		"KLGA/13 departures: removed",
+		"LENDY arrivals to KJFK: rate 5 -> 15/hour",
+		"LENDY arrivals to KLGA: removed",
+	}
+	if !slices.Equal(changes, expected) {
+		t.Errorf("got changes %q, expected %q", changes, expected)
+	}
+
+	// Without the previous definition, the scenario's rates are used.
+	lc, _ = reloadLaunchRates(cur, nil, sc)
+	expectRate("KJFK/31L", lc.DepartureRates["KJFK"]["31L"][""], 30)
+	expectRate("CAMRN", lc.ArrivalGroupRates["CAMRN"]["KJFK"], 20)
+}
@@ -0,0 +1,211 @@
+// separation.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// 7110.65 5-5-4: 3nm separation may be used within 40nm of the radar
+	// antenna; 5nm is required beyond that.
+	EnrouteLateralMinimum = 5
+	TerminalRadarRange    = 40
+)
+
+// OperationalError records a loss of the required separation between two
+// aircraft where at least one of them was being worked by a human
+// controller.
+type OperationalError struct {
+	Time     time.Time // sim time
+	Lateral  float32   // nm
+	Vertical int       // feet
+	// Required gives the lateral separation in nm that was required and
+	// Standard describes where it came from.
+	Required float32
+	Standard string
+	Aircraft [2]OperationalErrorAircraft
+}
+
+// OperationalErrorAircraft is a snapshot of one of the aircraft involved
+// in an operational error at the time that separation was lost.
+type OperationalErrorAircraft struct {
+	Callsign    string
+	Type        string
+	CWT         string
+	Controller  string
+	Position    Point2LL
+	Altitude    int
+	Heading     int
+	Groundspeed int
+}
+
+func (oe OperationalError) String() string {
+	return fmt.Sprintf("%s/%s: %.1fnm %d' (%.1fnm %s required)", oe.Aircraft[0].Callsign,
+		oe.Aircraft[1].Callsign, oe.Lateral, oe.Vertical, oe.Required, oe.Standard)
+}
+
+func makeOperationalErrorAircraft(ac *Aircraft) OperationalErrorAircraft {
+	return OperationalErrorAircraft{
+		Callsign:    ac.Callsign,
+		Type:        ac.FlightPlan.TypeWithoutSuffix(),
+		CWT:         getCwtCategory(ac),
+		Controller:  ac.ControllingController,
+		Position:    ac.Position(),
+		Altitude:    int(ac.Altitude()),
+		Heading:     int(ac.Heading()),
+		Groundspeed: int(ac.GS()),
+	}
+}
+
+// withinTerminalRadarRange indicates whether the aircraft is close enough
+// to one of the radar sites that terminal separation standards apply.
+func (s *Sim) withinTerminalRadarRange(ac *Aircraft) bool {
+	if len(s.World.RadarSites) == 0 {
+		// Assume that the entire facility is terminal airspace.
+		return true
+	}
+	for _, site := range s.World.RadarSites {
+		if nmdistance2ll(ac.Position(), site.Position) < TerminalRadarRange {
+			return true
+		}
+	}
+	return false
+}
+
+// requiredSeparation returns the lateral separation in nm that is required
+// between the two aircraft when they aren't vertically separated along
+// with a description of the standard that applies.
+func (s *Sim) requiredSeparation(a, b *Aircraft) (float32, string) {
+	lateral, standard := float32(LateralMinimum), "radar"
+	if !s.withinTerminalRadarRange(a) || !s.withinTerminalRadarRange(b) {
+		lateral = EnrouteLateralMinimum
+	}
+
+	if front, back, ok := inTrail(a, b); ok {
+		// Don't apply wake separation if we don't know one of the
+		// aircraft's categories; the table's 10nm for NOWGT is meant for
+		// the scope's ATPA warnings, not this.
+		if fc, bc := cwtClass(front), cwtClass(back); fc < 9 && bc < 9 {
+			if wake := cwtOnApproachLookUp[fc][bc]; wake > lateral {
+				lateral = wake
+				standard = "wake turbulence " + getCwtCategory(front) + "/" + getCwtCategory(back)
+			}
+		}
+	}
+	return lateral, standard
+}
+
+// inTrail returns the leading and trailing aircraft if one is following
+// the other such that wake turbulence separation applies: on a similar
+// course, directly behind, and at the same altitude or below it.
+func inTrail(a, b *Aircraft) (front, back *Aircraft, ok bool) {
+	if headingDifference(a.Heading(), b.Heading()) > 45 {
+		return
+	}
+
+	behind := func(f, bk *Aircraft) bool {
+		hdg := headingp2ll(f.Position(), bk.Position(), f.NmPerLongitude(), f.MagneticVariation())
+		// Allow a little slop for "the same altitude."
+		return headingDifference(hdg, OppositeHeading(f.Heading())) < 45 && bk.Altitude() < f.Altitude()+100
+	}
+	if behind(a, b) {
+		return a, b, true
+	} else if behind(b, a) {
+		return b, a, true
+	}
+	return
+}
+
+// diverging indicates whether two aircraft have passed each other and are
+// on courses that differ by at least 15 degrees, in which case separation
+// is not required (7110.65 5-5-7).
+func diverging(a, b *Aircraft) bool {
+	if headingDifference(a.Heading(), b.Heading()) < 15 {
+		return false
+	}
+
+	nmPerLongitude := a.NmPerLongitude()
+	velocity := func(ac *Aircraft) [2]float32 {
+		hdg := radians(ac.Heading() - ac.MagneticVariation())
+		return scale2f([2]float32{sin(hdg), cos(hdg)}, ac.GS())
+	}
+	// They've passed if the distance between them is increasing.
+	p := sub2f(ll2nm(b.Position(), nmPerLongitude), ll2nm(a.Position(), nmPerLongitude))
+	v := sub2f(velocity(b), velocity(a))
+	return dot(p, v) > 0
+}
+
+// checkSeparation records an operational error for each loss of
+// separation between IFR aircraft where at least one is being worked by a
+// human controller. Each loss is recorded once, for each of the human
+// controllers involved, no matter how long it lasts. Aircraft that are
+// both cleared for approaches are exempt. s.mu must be held.
+func (s *Sim) checkSeparation() {
+	applies := func(ac *Aircraft) bool {
+		return ac.FlightPlan != nil && ac.FlightPlan.Rules == IFR && ac.Nav.IsAirborne()
+	}
+
+	losses := make(map[string]bool)
+	callsigns := SortedMapKeys(s.World.Aircraft)
+	for i, ca := range callsigns {
+		a := s.World.Aircraft[ca]
+		if !applies(a) {
+			continue
+		}
+		for _, cb := range callsigns[i+1:] {
+			b := s.World.Aircraft[cb]
+			if !applies(b) || (a.Nav.Approach.Cleared && b.Nav.Approach.Cleared) {
+				continue
+			}
+
+			vertical := abs(a.Altitude() - b.Altitude())
+			lateral := nmdistance2ll(a.Position(), b.Position())
+			if vertical >= VerticalMinimum || lateral >= 10 /* the most any standard requires */ {
+				continue
+			}
+			required, standard := s.requiredSeparation(a, b)
+			if lateral >= required || diverging(a, b) {
+				continue
+			}
+
+			key := ca + "/" + cb
+			losses[key] = true
+			if s.separationLosses[key] {
+				continue
+			}
+
+			oe := OperationalError{
+				Time:     s.SimTime,
+				Lateral:  lateral,
+				Vertical: int(vertical),
+				Required: required,
+				Standard: standard,
+				Aircraft: [2]OperationalErrorAircraft{makeOperationalErrorAircraft(a), makeOperationalErrorAircraft(b)},
+			}
+			for _, ctrl := range []string{a.ControllingController, b.ControllingController} {
+				if s.controllerIsSignedIn(ctrl) {
+					s.lg.Info("operational error", slog.String("controller", ctrl), slog.String("error", oe.String()))
+
+					ss := s.sessionStats(ctrl)
+					ss.SeparationLosses++
+					ss.OperationalErrors = append(ss.OperationalErrors, oe)
+					s.eventStream.Post(Event{
+						Type:         OperationalErrorEvent,
+						Callsign:     ca,
+						ToController: ctrl,
+						Message:      oe.String(),
+					})
+				}
+				if a.ControllingController == b.ControllingController {
+					break
+				}
+			}
+		}
+	}
+	s.separationLosses = losses
+}
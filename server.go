@@ -17,6 +17,7 @@ import (
 	"os"
 	"os/exec"
 	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -25,7 +26,7 @@ import (
 	"github.com/shirou/gopsutil/cpu"
 )
 
-const ViceRPCVersion = 15
+const ViceRPCVersion = 16
 
 type SimServer struct {
 	*RPCClient
@@ -87,6 +88,31 @@ func (s *SimProxy) GetWorldUpdate(wu *SimWorldUpdate) *rpc.Call {
 	return s.Client.Go("Sim.GetWorldUpdate", s.ControllerToken, wu, nil)
 }
 
+func (s *SimProxy) SetCoachState(cs CoachState) *rpc.Call {
+	return s.Client.Go("Sim.SetCoachState", &SetCoachStateArgs{
+		ControllerToken: s.ControllerToken,
+		State:           cs,
+	}, nil, nil)
+}
+
+func (s *SimProxy) SetCoachTakeover(takeover bool) *rpc.Call {
+	return s.Client.Go("Sim.SetCoachTakeover", &SetCoachTakeoverArgs{
+		ControllerToken: s.ControllerToken,
+		Takeover:        takeover,
+	}, nil, nil)
+}
+
+func (s *SimProxy) SetWeatherPreset(preset string) *rpc.Call {
+	return s.Client.Go("Sim.SetWeatherPreset", &SetWeatherPresetArgs{
+		ControllerToken: s.ControllerToken,
+		Preset:          preset,
+	}, nil, nil)
+}
+
+func (s *SimProxy) GetATIS(atis *map[string]ATIS) *rpc.Call {
+	return s.Client.Go("Sim.GetATIS", s.ControllerToken, atis, nil)
+}
+
 func (s *SimProxy) SetSimRate(r float32) *rpc.Call {
 	return s.Client.Go("Sim.SetSimRate",
 		&SetSimRateArgs{
@@ -131,6 +157,22 @@ func (s *SimProxy) SetSecondaryScratchpad(callsign string, scratchpad string) *r
 	}, nil, nil)
 }
 
+func (s *SimProxy) AnnotateFlightStrip(callsign string, annotations [9]string) *rpc.Call {
+	return s.Client.Go("Sim.AnnotateFlightStrip", &FlightStripArgs{
+		ControllerToken: s.ControllerToken,
+		Callsign:        callsign,
+		Annotations:     annotations,
+	}, nil, nil)
+}
+
+func (s *SimProxy) PushFlightStrip(callsign string, controller string) *rpc.Call {
+	return s.Client.Go("Sim.PushFlightStrip", &FlightStripArgs{
+		ControllerToken: s.ControllerToken,
+		Callsign:        callsign,
+		ToController:    controller,
+	}, nil, nil)
+}
+
 func (s *SimProxy) InitiateTrack(callsign string) *rpc.Call {
 	return s.Client.Go("Sim.InitiateTrack", &InitiateTrackArgs{
 		ControllerToken: s.ControllerToken,
@@ -244,6 +286,14 @@ func (s *SimProxy) SetTemporaryAltitude(callsign string, alt int) *rpc.Call {
 	}, nil, nil)
 }
 
+func (s *SimProxy) AmendRoute(callsign, route string) *rpc.Call {
+	return s.Client.Go("Sim.AmendRoute", &AmendRouteArgs{
+		ControllerToken: s.ControllerToken,
+		Callsign:        callsign,
+		Route:           route,
+	}, nil, nil)
+}
+
 func (s *SimProxy) DeleteAircraft(callsign string) *rpc.Call {
 	return s.Client.Go("Sim.DeleteAircraft", &DeleteAircraftArgs{
 		ControllerToken: s.ControllerToken,
@@ -251,6 +301,55 @@ func (s *SimProxy) DeleteAircraft(callsign string) *rpc.Call {
 	}, nil, nil)
 }
 
+func (s *SimProxy) ReleaseDeparture(callsign string) *rpc.Call {
+	return s.Client.Go("Sim.ReleaseDeparture", &AircraftSpecifier{
+		ControllerToken: s.ControllerToken,
+		Callsign:        callsign,
+	}, nil, nil)
+}
+
+func (s *SimProxy) HoldDeparture(callsign string) *rpc.Call {
+	return s.Client.Go("Sim.HoldDeparture", &AircraftSpecifier{
+		ControllerToken: s.ControllerToken,
+		Callsign:        callsign,
+	}, nil, nil)
+}
+
+func (s *SimProxy) TriggerEmergency(callsign string, kind EmergencyType) *rpc.Call {
+	return s.Client.Go("Sim.TriggerEmergency", &EmergencyArgs{
+		ControllerToken: s.ControllerToken,
+		Callsign:        callsign,
+		Type:            kind,
+	}, nil, nil)
+}
+
+func (s *SimProxy) TriggerRandomEvent(kind RandomEventType) *rpc.Call {
+	return s.Client.Go("Sim.TriggerRandomEvent", &RandomEventArgs{
+		ControllerToken: s.ControllerToken,
+		Type:            kind,
+	}, nil, nil)
+}
+
+func (s *SimProxy) TriggerSystemFailure(f SystemFailure, minutes int) *rpc.Call {
+	return s.Client.Go("Sim.TriggerSystemFailure", &SystemFailureArgs{
+		ControllerToken: s.ControllerToken,
+		Failure:         f,
+		Minutes:         minutes,
+	}, nil, nil)
+}
+
+func (s *SimProxy) TriggerRadarOutage(ro RadarOutage, minutes int) *rpc.Call {
+	return s.Client.Go("Sim.TriggerRadarOutage", &RadarOutageArgs{
+		ControllerToken: s.ControllerToken,
+		Outage:          ro,
+		Minutes:         minutes,
+	}, nil, nil)
+}
+
+func (s *SimProxy) ReloadScenarioGroup(result *ScenarioReload) *rpc.Call {
+	return s.Client.Go("Sim.ReloadScenarioGroup", s.ControllerToken, result, nil)
+}
+
 func (s *SimProxy) RunAircraftCommands(callsign string, cmds string, result *AircraftCommandsResult) *rpc.Call {
 	return s.Client.Go("Sim.RunAircraftCommands", &AircraftCommandsArgs{
 		ControllerToken: s.ControllerToken,
@@ -259,6 +358,23 @@ func (s *SimProxy) RunAircraftCommands(callsign string, cmds string, result *Air
 	}, result, nil)
 }
 
+func (s *SimProxy) UplinkClearance(callsign string, cmds string) *rpc.Call {
+	return s.Client.Go("Sim.UplinkClearance", &AircraftCommandsArgs{
+		ControllerToken: s.ControllerToken,
+		Callsign:        callsign,
+		Commands:        cmds,
+	}, nil, nil)
+}
+
+func (s *SimProxy) SendCoordinationMessage(to, callsign, message string) *rpc.Call {
+	return s.Client.Go("Sim.SendCoordinationMessage", &CoordinationMessageArgs{
+		ControllerToken: s.ControllerToken,
+		ToController:    to,
+		Callsign:        callsign,
+		Message:         message,
+	}, nil, nil)
+}
+
 func (s *SimProxy) LaunchAircraft(ac Aircraft) *rpc.Call {
 	return s.Client.Go("Sim.LaunchAircraft", &LaunchAircraftArgs{
 		ControllerToken: s.ControllerToken,
@@ -266,6 +382,13 @@ func (s *SimProxy) LaunchAircraft(ac Aircraft) *rpc.Call {
 	}, nil, nil)
 }
 
+func (s *SimProxy) ScheduleLaunches(launches []ScheduledLaunch) *rpc.Call {
+	return s.Client.Go("Sim.ScheduleLaunches", &ScheduleLaunchesArgs{
+		ControllerToken: s.ControllerToken,
+		Launches:        launches,
+	}, nil, nil)
+}
+
 ///////////////////////////////////////////////////////////////////////////
 // SimManager
 
@@ -274,6 +397,7 @@ type SimManager struct {
 	configs              map[string]map[string]*SimConfiguration
 	activeSims           map[string]*Sim
 	controllerTokenToSim map[string]*Sim
+	coachTokenToSim      map[string]*Sim // view-only: not accepted for commands
 	mu                   LoggingMutex
 	mapLibrary           *VideoMapLibrary
 	startTime            time.Time
@@ -288,6 +412,7 @@ func NewSimManager(scenarioGroups map[string]map[string]*ScenarioGroup,
 		configs:              simConfigurations,
 		activeSims:           make(map[string]*Sim),
 		controllerTokenToSim: make(map[string]*Sim),
+		coachTokenToSim:      make(map[string]*Sim),
 		mapLibrary:           mapLib,
 		startTime:            time.Now(),
 		lg:                   lg,
@@ -300,7 +425,15 @@ type NewSimResult struct {
 }
 
 func (sm *SimManager) New(config *NewSimConfiguration, result *NewSimResult) error {
-	if config.NewSimType == NewSimCreateLocal || config.NewSimType == NewSimCreateRemote {
+	if config.NewSimType == NewSimImport {
+		sim, err := ImportSim(config.ImportedSession, sm.mapLibrary)
+		if err != nil {
+			return err
+		}
+		sim.Name = config.NewSimName
+		sim.RequirePassword, sim.Password = config.RequirePassword, config.Password
+		return sm.Add(sim, result)
+	} else if config.NewSimType == NewSimCreateLocal || config.NewSimType == NewSimCreateRemote {
 		sim := NewSim(*config, sm.scenarioGroups, config.NewSimType == NewSimCreateLocal, sm.mapLibrary, sm.lg)
 		sim.prespawn()
 		return sm.Add(sim, result)
@@ -312,6 +445,25 @@ func (sm *SimManager) New(config *NewSimConfiguration, result *NewSimResult) err
 		if !ok {
 			return ErrNoNamedSim
 		}
+
+		if config.JoinAsCoach {
+			if sim.RequirePassword && config.RemoteSimPassword != sim.Password {
+				return ErrInvalidPassword
+			}
+
+			world, token, err := sim.SignOnCoach(config.SelectedRemoteSimPosition)
+			if err != nil {
+				return err
+			}
+
+			sm.coachTokenToSim[token] = sim
+
+			*result = NewSimResult{
+				World:           world,
+				ControllerToken: token,
+			}
+			return nil
+		}
 		if _, ok := sim.World.Controllers[config.SelectedRemoteSimPosition]; ok {
 			return ErrNoController
 		}
@@ -377,6 +529,11 @@ func (sm *SimManager) Add(sim *Sim, result *NewSimResult) error {
 				delete(sm.controllerTokenToSim, tok)
 			}
 		}
+		for tok, s := range sm.coachTokenToSim {
+			if s == sim {
+				delete(sm.coachTokenToSim, tok)
+			}
+		}
 		sm.mu.Unlock(sm.lg)
 	}()
 
@@ -423,6 +580,7 @@ func (sm *SimManager) GetRunningSims(_ int, result *map[string]*RemoteSim) error
 			ScenarioName:       s.Scenario,
 			PrimaryController:  s.World.PrimaryController,
 			RequirePassword:    s.RequirePassword,
+			SpectatorDelay:     int(s.SpectatorDelay / time.Minute),
 			AvailablePositions: make(map[string]struct{}),
 			CoveredPositions:   make(map[string]struct{}),
 		}
@@ -490,6 +648,20 @@ func (sm *SimManager) ControllerTokenToSim(token string) (*Sim, bool) {
 	return sim, ok
 }
 
+// ViewerTokenToSim is like ControllerTokenToSim but also accepts the
+// tokens of coaches; it should only be used for operations that don't
+// affect the sim.
+func (sm *SimManager) ViewerTokenToSim(token string) (*Sim, bool) {
+	sm.mu.Lock(lg)
+	defer sm.mu.Unlock(sm.lg)
+
+	if sim, ok := sm.controllerTokenToSim[token]; ok {
+		return sim, ok
+	}
+	sim, ok := sm.coachTokenToSim[token]
+	return sim, ok
+}
+
 type SimStatus struct {
 	Name            string
 	Config          string
@@ -596,21 +768,83 @@ type SimDispatcher struct {
 }
 
 func (sd *SimDispatcher) GetWorldUpdate(token string, update *SimWorldUpdate) error {
-	if sim, ok := sd.sm.ControllerTokenToSim(token); !ok {
+	if sim, ok := sd.sm.ViewerTokenToSim(token); !ok {
 		return ErrNoSimForControllerToken
 	} else {
 		return sim.GetWorldUpdate(token, update)
 	}
 }
 
+func (sd *SimDispatcher) GetATIS(token string, atis *map[string]ATIS) error {
+	if sim, ok := sd.sm.ViewerTokenToSim(token); !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.GetATIS(token, atis)
+	}
+}
+
 func (sd *SimDispatcher) SignOff(token string, _ *struct{}) error {
-	if sim, ok := sd.sm.ControllerTokenToSim(token); !ok {
+	if sim, ok := sd.sm.ViewerTokenToSim(token); !ok {
 		return ErrNoSimForControllerToken
 	} else {
 		return sim.SignOff(token)
 	}
 }
 
+type SetCoachStateArgs struct {
+	ControllerToken string
+	State           CoachState
+}
+
+func (sd *SimDispatcher) SetCoachState(cs *SetCoachStateArgs, _ *struct{}) error {
+	if sim, ok := sd.sm.ViewerTokenToSim(cs.ControllerToken); !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.SetCoachState(cs.ControllerToken, cs.State)
+	}
+}
+
+type SetCoachTakeoverArgs struct {
+	ControllerToken string
+	Takeover        bool
+}
+
+func (sd *SimDispatcher) SetCoachTakeover(ct *SetCoachTakeoverArgs, _ *struct{}) error {
+	sim, ok := sd.sm.ViewerTokenToSim(ct.ControllerToken)
+	if !ok {
+		return ErrNoSimForControllerToken
+	}
+	if err := sim.SetCoachTakeover(ct.ControllerToken, ct.Takeover); err != nil {
+		return err
+	}
+
+	// While they have control, the coach's token is accepted for
+	// commands like any other controller's.
+	sd.sm.mu.Lock(sd.sm.lg)
+	defer sd.sm.mu.Unlock(sd.sm.lg)
+	if ct.Takeover {
+		sd.sm.controllerTokenToSim[ct.ControllerToken] = sim
+	} else {
+		delete(sd.sm.controllerTokenToSim, ct.ControllerToken)
+	}
+	return nil
+}
+
+type SetWeatherPresetArgs struct {
+	ControllerToken string
+	Preset          string
+}
+
+// SetWeatherPreset also accepts coaches' tokens so that an instructor can
+// change the weather.
+func (sd *SimDispatcher) SetWeatherPreset(wp *SetWeatherPresetArgs, _ *struct{}) error {
+	if sim, ok := sd.sm.ViewerTokenToSim(wp.ControllerToken); !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.SetWeatherPreset(wp.ControllerToken, wp.Preset)
+	}
+}
+
 type ChangeControlPositionArgs struct {
 	ControllerToken string
 	Callsign        string
@@ -689,6 +923,29 @@ func (sd *SimDispatcher) SetSecondaryScratchpad(a *SetScratchpadArgs, _ *struct{
 	}
 }
 
+type FlightStripArgs struct {
+	ControllerToken string
+	Callsign        string
+	Annotations     [9]string
+	ToController    string
+}
+
+func (sd *SimDispatcher) AnnotateFlightStrip(a *FlightStripArgs, _ *struct{}) error {
+	if sim, ok := sd.sm.controllerTokenToSim[a.ControllerToken]; !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.AnnotateFlightStrip(a.ControllerToken, a.Callsign, a.Annotations)
+	}
+}
+
+func (sd *SimDispatcher) PushFlightStrip(a *FlightStripArgs, _ *struct{}) error {
+	if sim, ok := sd.sm.controllerTokenToSim[a.ControllerToken]; !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.PushFlightStrip(a.ControllerToken, a.Callsign, a.ToController)
+	}
+}
+
 type SetGlobalLeaderLineArgs struct {
 	ControllerToken string
 	Callsign        string
@@ -865,6 +1122,20 @@ func (sd *SimDispatcher) SetTemporaryAltitude(alt *AssignAltitudeArgs, _ *struct
 	}
 }
 
+type AmendRouteArgs struct {
+	ControllerToken string
+	Callsign        string
+	Route           string
+}
+
+func (sd *SimDispatcher) AmendRoute(ar *AmendRouteArgs, _ *struct{}) error {
+	if sim, ok := sd.sm.controllerTokenToSim[ar.ControllerToken]; !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.AmendRoute(ar.ControllerToken, ar.Callsign, ar.Route)
+	}
+}
+
 type DeleteAircraftArgs AircraftSpecifier
 
 func (sd *SimDispatcher) DeleteAircraft(da *DeleteAircraftArgs, _ *struct{}) error {
@@ -875,6 +1146,88 @@ func (sd *SimDispatcher) DeleteAircraft(da *DeleteAircraftArgs, _ *struct{}) err
 	}
 }
 
+func (sd *SimDispatcher) ReleaseDeparture(a *AircraftSpecifier, _ *struct{}) error {
+	if sim, ok := sd.sm.controllerTokenToSim[a.ControllerToken]; !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.ReleaseDeparture(a.ControllerToken, a.Callsign)
+	}
+}
+
+func (sd *SimDispatcher) HoldDeparture(a *AircraftSpecifier, _ *struct{}) error {
+	if sim, ok := sd.sm.controllerTokenToSim[a.ControllerToken]; !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.HoldDeparture(a.ControllerToken, a.Callsign)
+	}
+}
+
+type EmergencyArgs struct {
+	ControllerToken string
+	Callsign        string
+	Type            EmergencyType
+}
+
+func (sd *SimDispatcher) TriggerEmergency(a *EmergencyArgs, _ *struct{}) error {
+	if sim, ok := sd.sm.controllerTokenToSim[a.ControllerToken]; !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.TriggerEmergency(a.ControllerToken, a.Callsign, a.Type)
+	}
+}
+
+type RandomEventArgs struct {
+	ControllerToken string
+	Type            RandomEventType
+}
+
+func (sd *SimDispatcher) TriggerRandomEvent(a *RandomEventArgs, _ *struct{}) error {
+	if sim, ok := sd.sm.controllerTokenToSim[a.ControllerToken]; !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.TriggerRandomEvent(a.ControllerToken, a.Type)
+	}
+}
+
+type SystemFailureArgs struct {
+	ControllerToken string
+	Failure         SystemFailure
+	Minutes         int
+}
+
+func (sd *SimDispatcher) TriggerSystemFailure(a *SystemFailureArgs, _ *struct{}) error {
+	if sim, ok := sd.sm.controllerTokenToSim[a.ControllerToken]; !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.TriggerSystemFailure(a.ControllerToken, a.Failure, a.Minutes)
+	}
+}
+
+type RadarOutageArgs struct {
+	ControllerToken string
+	Outage          RadarOutage
+	Minutes         int
+}
+
+func (sd *SimDispatcher) TriggerRadarOutage(a *RadarOutageArgs, _ *struct{}) error {
+	if sim, ok := sd.sm.controllerTokenToSim[a.ControllerToken]; !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.TriggerRadarOutage(a.ControllerToken, a.Outage, a.Minutes)
+	}
+}
+
+func (sd *SimDispatcher) ReloadScenarioGroup(token string, result *ScenarioReload) error {
+	if sim, ok := sd.sm.controllerTokenToSim[token]; !ok {
+		return ErrNoSimForControllerToken
+	} else if sg, err := sd.sm.rereadScenarioGroup(sim.World.TRACON, sim.ScenarioGroup); err != nil {
+		return err
+	} else {
+		*result, err = sim.ReloadScenarioGroup(token, sg)
+		return err
+	}
+}
+
 type AircraftCommandsArgs struct {
 	ControllerToken string
 	Callsign        string
@@ -889,15 +1242,76 @@ type AircraftCommandsResult struct {
 }
 
 func (sd *SimDispatcher) RunAircraftCommands(cmds *AircraftCommandsArgs, result *AircraftCommandsResult) error {
-	token, callsign := cmds.ControllerToken, cmds.Callsign
-	sim, ok := sd.sm.controllerTokenToSim[token]
+	sim, ok := sd.sm.controllerTokenToSim[cmds.ControllerToken]
 	if !ok {
 		return ErrNoSimForControllerToken
 	}
 
-	commands := strings.Fields(cmds.Commands)
+	*result = sim.RunAircraftCommands(cmds.ControllerToken, cmds.Callsign, cmds.Commands)
+	return nil
+}
+
+// RunAircraftCommands parses and runs the given space-separated sequence
+// of commands for the aircraft. If a command fails, the returned result
+// has an error message and the commands that were not run.
+func (s *Sim) RunAircraftCommands(token, callsign string, cmds string) AircraftCommandsResult {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	return s.runAircraftCommands(token, callsign, cmds)
+}
+
+// runAircraftCommands implements RunAircraftCommands; s.mu must be held.
+func (s *Sim) runAircraftCommands(token, callsign string, cmds string) (result AircraftCommandsResult) {
+	commands := strings.Fields(cmds)
+
+	if sc, ok := s.controllers[token]; ok {
+		defer func() {
+			ss := s.sessionStats(sc.Callsign)
+			ss.CommandsIssued += len(commands)
+			if result.ErrorMessage != "" {
+				ss.CommandErrors++
+			}
+		}()
+	}
+	ac, ok := s.World.Aircraft[callsign]
+	datalink := s.datalinkExecuting[callsign]
+	// Datalink clearances still get through when the radio has failed.
+	ignored := ok && !ac.Emergency.RespondsToCommands() && !(datalink && ac.Emergency != EmergencyHijack)
+	lc := s.LaunchConfig
+	// Voice transmissions to the aircraft's controlling controller may be
+	// blocked by someone else transmitting at the same time.
+	blocked := ok && !datalink && s.controllers[token] != nil &&
+		s.controllers[token].Callsign == ac.ControllingController && rand.Float32() < lc.FrequencyCongestion
+	if ignored {
+		// The pilot doesn't hear us (or isn't listening); the commands
+		// are silently ignored.
+		s.lg.Info("commands ignored", slog.String("callsign", callsign), slog.String("commands", cmds))
+		ac.UnacknowledgedInstructions = append(ac.UnacknowledgedInstructions, cmds)
+		return
+	}
+	if blocked {
+		s.lg.Info("commands blocked", slog.String("callsign", callsign), slog.String("commands", cmds))
+		PostRadioEvents(callsign, []RadioTransmission{RadioTransmission{
+			Controller: ac.ControllingController,
+			Message:    Sample("say again", "you were blocked", "blocked, say again"),
+			Type:       RadioTransmissionUnexpected,
+		}}, s)
+		return
+	}
 
 	for i, command := range commands {
+		vi, isValue := parseValueInstruction(command)
+		isValue = isValue && !datalink
+		if isValue && rand.Float32() < lc.ReadbackErrorRate {
+			// The pilot mishears the instruction and reads back and
+			// flies the wrong value.
+			vi = vi.misheard()
+			s.lg.Info("misheard", slog.String("callsign", callsign), slog.String("command", command),
+				slog.String("heard", vi.String()))
+			command = vi.String()
+		}
+
 		rewriteError := func(err error) {
 			result.RemainingInput = strings.Join(commands[i:], " ")
 
@@ -913,22 +1327,37 @@ func (sd *SimDispatcher) RunAircraftCommands(cmds *AircraftCommandsArgs, result
 
 		switch command[0] {
 		case 'A', 'C':
-			if command == "CAC" {
+			if command == "CTO" {
+				if err := s.clearedForTakeoff(token, callsign); err != nil {
+					rewriteError(err)
+					return
+				}
+			} else if command == "CT" {
+				if err := s.continueTaxi(token, callsign); err != nil {
+					rewriteError(err)
+					return
+				}
+			} else if command == "CAC" {
 				// Cancel approach clearance
-				if err := sim.CancelApproachClearance(token, callsign); err != nil {
+				if err := s.cancelApproachClearance(token, callsign); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			} else if command == "CVS" {
-				if err := sim.ClimbViaSID(token, callsign); err != nil {
+				if err := s.climbViaSID(token, callsign); err != nil {
+					rewriteError(err)
+					return
+				}
+			} else if command == "CTEC" {
+				if err := s.clearedTECRoute(token, callsign); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			} else if len(command) > 4 && command[:3] == "CSI" && !isAllNumbers(command[3:]) {
 				// Cleared straight in approach.
-				if err := sim.ClearedApproach(token, callsign, command[3:], true); err != nil {
+				if err := s.clearedApproach(token, callsign, command[3:], true); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			} else if command[0] == 'C' && len(command) > 2 && !isAllNumbers(command[1:]) {
 				if components := strings.Split(command, "/"); len(components) > 1 {
@@ -940,14 +1369,14 @@ func (sd *SimDispatcher) RunAircraftCommands(cmds *AircraftCommandsArgs, result
 					for _, cmd := range components[1:] {
 						if len(cmd) == 0 {
 							rewriteError(ErrInvalidCommandSyntax)
-							return nil
+							return
 						}
 
 						var err error
 						if cmd[0] == 'A' && len(cmd) > 1 {
 							if ar, err = ParseAltitudeRestriction(cmd[1:]); err != nil {
 								rewriteError(err)
-								return nil
+								return
 							}
 							// User input here is 100s of feet, while AltitudeRestriction is feet...
 							ar.Range[0] *= 100
@@ -955,35 +1384,35 @@ func (sd *SimDispatcher) RunAircraftCommands(cmds *AircraftCommandsArgs, result
 						} else if cmd[0] == 'S' {
 							if speed, err = strconv.Atoi(cmd[1:]); err != nil {
 								rewriteError(err)
-								return nil
+								return
 							}
 						} else {
 							rewriteError(ErrInvalidCommandSyntax)
-							return nil
+							return
 						}
 					}
 
-					if err := sim.CrossFixAt(token, callsign, fix, ar, speed); err != nil {
+					if err := s.crossFixAt(token, callsign, fix, ar, speed); err != nil {
 						rewriteError(err)
-						return nil
+						return
 					}
-				} else if err := sim.ClearedApproach(token, callsign, command[1:], false); err != nil {
+				} else if err := s.clearedApproach(token, callsign, command[1:], false); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			} else {
 				if command[0] == 'A' {
 					components := strings.Split(command, "/")
 					if len(components) != 2 || len(components[1]) == 0 || components[1][0] != 'C' {
 						rewriteError(ErrInvalidCommandSyntax)
-						return nil
+						return
 					}
 
 					fix := strings.ToUpper(components[0][1:])
 					approach := components[1][1:]
-					if err := sim.AtFixCleared(token, callsign, fix, approach); err != nil {
+					if err := s.atFixCleared(token, callsign, fix, approach); err != nil {
 						rewriteError(err)
-						return nil
+						return
 					} else {
 						continue
 					}
@@ -992,18 +1421,18 @@ func (sd *SimDispatcher) RunAircraftCommands(cmds *AircraftCommandsArgs, result
 				// Otherwise look for an altitude
 				if alt, err := strconv.Atoi(command[1:]); err != nil {
 					rewriteError(err)
-					return nil
-				} else if err := sim.AssignAltitude(token, callsign, 100*alt, false); err != nil {
+					return
+				} else if err := s.assignAltitude(token, callsign, 100*alt, false); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			}
 
 		case 'D':
 			if command == "DVS" {
-				if err := sim.DescendViaSTAR(token, callsign); err != nil {
+				if err := s.descendViaSTAR(token, callsign); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			} else if components := strings.Split(command, "/"); len(components) > 1 && len(components[1]) > 1 {
 				fix := components[0][1:]
@@ -1011,231 +1440,265 @@ func (sd *SimDispatcher) RunAircraftCommands(cmds *AircraftCommandsArgs, result
 				switch components[1][0] {
 				case 'D':
 					// Depart <fix1> direct <fix2>
-					if err := sim.DepartFixDirect(token, callsign, fix, components[1][1:]); err != nil {
+					if err := s.departFixDirect(token, callsign, fix, components[1][1:]); err != nil {
 						rewriteError(err)
-						return nil
+						return
 					}
 				case 'H':
 					// Depart <fix> at heading <hdg>
 					if hdg, err := strconv.Atoi(components[1][1:]); err != nil {
 						rewriteError(err)
-						return nil
-					} else if err := sim.DepartFixHeading(token, callsign, fix, hdg); err != nil {
+						return
+					} else if err := s.departFixHeading(token, callsign, fix, hdg); err != nil {
 						rewriteError(err)
-						return nil
+						return
 					}
 
 				default:
 					rewriteError(ErrInvalidCommandSyntax)
-					return nil
+					return
 				}
 			} else if len(command) > 1 && command[1] >= '0' && command[1] <= '9' {
 				// Looks like an altitude.
 				if alt, err := strconv.Atoi(command[1:]); err != nil {
 					rewriteError(err)
-					return nil
-				} else if err := sim.AssignAltitude(token, callsign, 100*alt, false); err != nil {
+					return
+				} else if err := s.assignAltitude(token, callsign, 100*alt, false); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
-			} else if _, ok := sim.World.Locate(string(command[1:])); ok {
-				if err := sim.DirectFix(token, callsign, command[1:]); err != nil {
+			} else if _, ok := s.World.Locate(string(command[1:])); ok {
+				if err := s.directFix(token, callsign, command[1:]); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			} else {
 				rewriteError(ErrInvalidCommandSyntax)
-				return nil
+				return
 			}
 
 		case 'E':
 			if command == "ED" {
-				if err := sim.ExpediteDescent(token, callsign); err != nil {
+				if err := s.expediteDescent(token, callsign); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			} else if command == "EC" {
-				if err := sim.ExpediteClimb(token, callsign); err != nil {
+				if err := s.expediteClimb(token, callsign); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			} else if len(command) > 1 {
 				// Expect approach.
-				if err := sim.ExpectApproach(token, callsign, command[1:]); err != nil {
+				if err := s.expectApproach(token, callsign, command[1:]); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			} else {
 				rewriteError(ErrInvalidCommandSyntax)
-				return nil
+				return
 			}
 		case 'F':
 			if command == "FC" {
-				if err := sim.HandoffControl(token, callsign); err != nil {
+				if err := s.handoffControl(token, callsign); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			}
 		case 'H':
-			if len(command) == 1 {
-				if err := sim.AssignHeading(&HeadingArgs{
+			if command == "HP" {
+				if err := s.holdPosition(token, callsign); err != nil {
+					rewriteError(err)
+					return
+				}
+			} else if strings.HasPrefix(command, "HOLD/") {
+				if hc, err := parseHoldCommand(command); err != nil {
+					rewriteError(err)
+					return
+				} else if err := s.hold(token, callsign, hc); err != nil {
+					rewriteError(err)
+					return
+				}
+			} else if len(command) == 1 {
+				if err := s.assignHeading(&HeadingArgs{
 					ControllerToken: token,
 					Callsign:        callsign,
 					Present:         true,
 				}); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			} else if hdg, err := strconv.Atoi(command[1:]); err != nil {
 				rewriteError(err)
-				return nil
-			} else if err := sim.AssignHeading(&HeadingArgs{
+				return
+			} else if err := s.assignHeading(&HeadingArgs{
 				ControllerToken: token,
 				Callsign:        callsign,
 				Heading:         hdg,
 				Turn:            TurnClosest,
 			}); err != nil {
 				rewriteError(err)
-				return nil
+				return
 			}
 
 		case 'I':
 			if len(command) == 1 {
-				if err := sim.InterceptLocalizer(token, callsign); err != nil {
+				if err := s.interceptLocalizer(token, callsign); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			} else if command == "ID" {
-				if err := sim.Ident(token, callsign); err != nil {
+				if err := s.ident(token, callsign); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			} else {
 				rewriteError(ErrInvalidCommandSyntax)
-				return nil
+				return
 			}
 
 		case 'L':
-			if l := len(command); l > 2 && command[l-1] == 'D' {
+			if command == "LUAW" {
+				if err := s.lineUpAndWait(token, callsign); err != nil {
+					rewriteError(err)
+					return
+				}
+			} else if l := len(command); l > 2 && command[l-1] == 'D' {
 				// turn left x degrees
 				if deg, err := strconv.Atoi(command[1 : l-1]); err != nil {
 					rewriteError(err)
-					return nil
-				} else if err := sim.AssignHeading(&HeadingArgs{
+					return
+				} else if err := s.assignHeading(&HeadingArgs{
 					ControllerToken: token,
 					Callsign:        callsign,
 					LeftDegrees:     deg,
 				}); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			} else {
 				// turn left heading...
 				if hdg, err := strconv.Atoi(command[1:]); err != nil {
 					rewriteError(err)
-					return nil
-				} else if err := sim.AssignHeading(&HeadingArgs{
+					return
+				} else if err := s.assignHeading(&HeadingArgs{
 					ControllerToken: token,
 					Callsign:        callsign,
 					Heading:         hdg,
 					Turn:            TurnLeft,
 				}); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			}
 
 		case 'R':
-			if l := len(command); l > 2 && command[l-1] == 'D' {
+			if command == "RON" {
+				if err := s.resumeOwnNavigation(token, callsign); err != nil {
+					rewriteError(err)
+					return
+				}
+			} else if l := len(command); l > 2 && command[l-1] == 'D' {
 				// turn right x degrees
 				if deg, err := strconv.Atoi(command[1 : l-1]); err != nil {
 					rewriteError(err)
-					return nil
-				} else if err := sim.AssignHeading(&HeadingArgs{
+					return
+				} else if err := s.assignHeading(&HeadingArgs{
 					ControllerToken: token,
 					Callsign:        callsign,
 					RightDegrees:    deg,
 				}); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			} else {
 				// turn right heading...
 				if hdg, err := strconv.Atoi(command[1:]); err != nil {
 					rewriteError(err)
-					return nil
-				} else if err := sim.AssignHeading(&HeadingArgs{
+					return
+				} else if err := s.assignHeading(&HeadingArgs{
 					ControllerToken: token,
 					Callsign:        callsign,
 					Heading:         hdg,
 					Turn:            TurnRight,
 				}); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			}
 
 		case 'S':
 			if len(command) == 1 {
 				// Cancel speed restrictions
-				if err := sim.AssignSpeed(token, callsign, 0, false); err != nil {
+				if err := s.assignSpeed(token, callsign, 0, false); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			} else if command == "SMIN" {
-				if err := sim.MaintainSlowestPractical(token, callsign); err != nil {
+				if err := s.maintainSlowestPractical(token, callsign); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			} else if command == "SMAX" {
-				if err := sim.MaintainMaximumForward(token, callsign); err != nil {
+				if err := s.maintainMaximumForward(token, callsign); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			} else if command == "SS" {
-				if err := sim.SaySpeed(token, callsign); err != nil {
+				if err := s.saySpeed(token, callsign); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			} else {
 				if kts, err := strconv.Atoi(command[1:]); err != nil {
 					rewriteError(err)
-					return nil
-				} else if err := sim.AssignSpeed(token, callsign, kts, false); err != nil {
+					return
+				} else if err := s.assignSpeed(token, callsign, kts, false); err != nil {
 					rewriteError(err)
-					return nil
+					return
 				}
 			}
 
 		case 'T':
 			if command == "TO" {
-				if err := sim.ContactTower(token, callsign); err != nil {
+				if err := s.contactTower(token, callsign); err != nil {
 					rewriteError(err)
-					return nil
+					return
+				}
+			} else if strings.HasPrefix(command, "TX") {
+				// Taxi [to destination] [via taxiways]
+				components := strings.Split(command[2:], "/")
+				if slices.Contains(components[1:], "") {
+					rewriteError(ErrInvalidCommandSyntax)
+					return
+				}
+				if err := s.taxi(token, callsign, components[0], components[1:]); err != nil {
+					rewriteError(err)
+					return
 				}
 			} else if n := len(command); n > 2 {
 				if deg, err := strconv.Atoi(command[1 : n-1]); err == nil {
 					if command[n-1] == 'L' {
 						// turn x degrees left
-						if err := sim.AssignHeading(&HeadingArgs{
+						if err := s.assignHeading(&HeadingArgs{
 							ControllerToken: token,
 							Callsign:        callsign,
 							LeftDegrees:     deg,
 						}); err != nil {
 							rewriteError(err)
-							return nil
+							return
 						} else {
 							continue
 						}
 					} else if command[n-1] == 'R' {
 						// turn x degrees right
-						if err := sim.AssignHeading(&HeadingArgs{
+						if err := s.assignHeading(&HeadingArgs{
 							ControllerToken: token,
 							Callsign:        callsign,
 							RightDegrees:    deg,
 						}); err != nil {
 							rewriteError(err)
-							return nil
+							return
 						} else {
 							continue
 						}
@@ -1246,34 +1709,71 @@ func (sd *SimDispatcher) RunAircraftCommands(cmds *AircraftCommandsArgs, result
 				case "TS":
 					if kts, err := strconv.Atoi(command[2:]); err != nil {
 						rewriteError(err)
-						return nil
-					} else if err := sim.AssignSpeed(token, callsign, kts, true); err != nil {
+						return
+					} else if err := s.assignSpeed(token, callsign, kts, true); err != nil {
 						rewriteError(err)
-						return nil
+						return
 					}
 
 				case "TA", "TC", "TD":
 					if alt, err := strconv.Atoi(command[2:]); err != nil {
 						rewriteError(err)
-						return nil
-					} else if err := sim.AssignAltitude(token, callsign, 100*alt, true); err != nil {
+						return
+					} else if err := s.assignAltitude(token, callsign, 100*alt, true); err != nil {
 						rewriteError(err)
-						return nil
+						return
 					}
 
 				default:
 					rewriteError(ErrInvalidCommandSyntax)
-					return nil
+					return
 				}
 			}
 
+		case 'X':
+			// Cross runway
+			if len(command) == 1 {
+				rewriteError(ErrInvalidCommandSyntax)
+				return
+			} else if err := s.crossRunway(token, callsign, command[1:]); err != nil {
+				rewriteError(err)
+				return
+			}
+
 		default:
 			rewriteError(ErrInvalidCommandSyntax)
-			return nil
+			return
+		}
+
+		if isValue {
+			s.pilotCompliance(callsign, vi, &lc)
 		}
 	}
 
-	return nil
+	return
+}
+
+func (sd *SimDispatcher) UplinkClearance(cmds *AircraftCommandsArgs, _ *struct{}) error {
+	if sim, ok := sd.sm.controllerTokenToSim[cmds.ControllerToken]; !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.UplinkClearance(cmds.ControllerToken, cmds.Callsign, cmds.Commands)
+	}
+}
+
+type CoordinationMessageArgs struct {
+	ControllerToken string
+	ToController    string
+	Callsign        string
+	Message         string
+}
+
+func (sd *SimDispatcher) SendCoordinationMessage(args *CoordinationMessageArgs, _ *struct{}) error {
+	if sim, ok := sd.sm.controllerTokenToSim[args.ControllerToken]; !ok {
+		return ErrNoSimForControllerToken
+	} else {
+		return sim.SendCoordinationMessage(args.ControllerToken, args.ToController, args.Callsign, args.Message)
+	}
 }
 
 type LaunchAircraftArgs struct {
@@ -1290,6 +1790,20 @@ func (sd *SimDispatcher) LaunchAircraft(ls *LaunchAircraftArgs, _ *struct{}) err
 	return nil
 }
 
+type ScheduleLaunchesArgs struct {
+	ControllerToken string
+	Launches        []ScheduledLaunch
+}
+
+func (sd *SimDispatcher) ScheduleLaunches(ls *ScheduleLaunchesArgs, _ *struct{}) error {
+	sim, ok := sd.sm.controllerTokenToSim[ls.ControllerToken]
+	if !ok {
+		return ErrNoSimForControllerToken
+	}
+	sim.ScheduleLaunches(ls.Launches)
+	return nil
+}
+
 func RunSimServer() {
 	l, err := net.Listen("tcp", fmt.Sprintf(":%d", *serverPort))
 	if err != nil {
@@ -1439,6 +1953,10 @@ func launchHTTPStats(sm *SimManager) {
 		statsHandler(w, r, sm)
 		lg.Infof("%s: served stats request", r.URL.String())
 	})
+	http.HandleFunc("/companion", companionPageHandler)
+	http.HandleFunc("/companion/state", func(w http.ResponseWriter, r *http.Request) {
+		companionStateHandler(w, r, sm)
+	})
 	http.HandleFunc("/vice-logs/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain")
 		if f, err := os.Open("." + r.URL.String()); err == nil {
@@ -0,0 +1,179 @@
+// session.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// SessionExport holds the complete state of a running Sim so that it can
+// be moved to another server, e.g. to move a long-running multi-controller
+// session to a new host. Exports are stored as zstd-compressed JSON.
+type SessionExport struct {
+	ConfigVersion int
+	Name          string
+	Exported      time.Time
+	Sim           json.RawMessage
+}
+
+// Export returns the Sim's state for importing on another server. Only the
+// primary controller may export the Sim.
+func (s *Sim) Export(token string) (*SessionExport, error) {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	ctrl, ok := s.controllers[token]
+	if !ok || ctrl.coach {
+		return nil, ErrInvalidControllerToken
+	}
+	if ctrl.Callsign != s.World.PrimaryController {
+		return nil, ErrNotPrimaryController
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+
+	// As when the Sim is saved in the config file, the video maps are
+	// dropped from the export; the destination server restores them
+	// from its own library. PreSave() modifies the World, so it's done
+	// on a copy.
+	var cp Sim
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil, err
+	}
+	cp.PreSave()
+	if b, err = json.Marshal(&cp); err != nil {
+		return nil, err
+	}
+
+	s.lg.Infof("%s: exported sim (%d bytes)", ctrl.Callsign, len(b))
+
+	return &SessionExport{
+		ConfigVersion: CurrentConfigVersion,
+		Name:          s.Name,
+		Exported:      time.Now(),
+		Sim:           b,
+	}, nil
+}
+
+// ImportSim returns a Sim for the exported state that is ready to be
+// added to the SimManager. The sign-ons from the original server are
+// discarded: the controllers' tokens aren't valid here, so each one gets a
+// new token when they reconnect to the destination server. Their tracks
+// are kept so that they can pick up where they left off.
+func ImportSim(e *SessionExport, ml *VideoMapLibrary) (*Sim, error) {
+	if e == nil || e.ConfigVersion != CurrentConfigVersion {
+		return nil, ErrIncompatibleSessionExport
+	}
+
+	var s Sim
+	if err := json.Unmarshal(e.Sim, &s); err != nil {
+		return nil, err
+	}
+	if s.World == nil {
+		return nil, ErrIncompatibleSessionExport
+	}
+	if err := s.PostLoad(ml); err != nil {
+		return nil, err
+	}
+
+	for callsign := range s.SignOnPositions {
+		delete(s.World.Controllers, callsign)
+	}
+	if s.LaunchConfig.Controller != s.World.PrimaryController {
+		s.LaunchConfig.Controller = ""
+	}
+
+	return &s, nil
+}
+
+func (sm *SimManager) ExportSim(token string, result *SessionExport) error {
+	sim, ok := sm.ControllerTokenToSim(token)
+	if !ok {
+		return ErrNoSimForControllerToken
+	}
+
+	e, err := sim.Export(token)
+	if err != nil {
+		return err
+	}
+	*result = *e
+	return nil
+}
+
+func (s *SimProxy) ExportSim() (*SessionExport, error) {
+	var e SessionExport
+	err := s.Client.CallWithTimeout("SimManager.ExportSim", s.ControllerToken, &e)
+	return &e, err
+}
+
+// ExportSession writes the state of the sim to a file in the given
+// directory, returning the file's name.
+func (w *World) ExportSession(dir string) (string, error) {
+	e, err := w.simProxy.ExportSim()
+	if err != nil {
+		return "", TryDecodeError(err)
+	}
+
+	name := Select(e.Name != "", e.Name, "vice")
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+	fn := path.Join(dir, name+"-"+e.Exported.Format("2006-01-02-150405")+".vicesim")
+
+	f, err := os.Create(fn)
+	if err != nil {
+		return "", err
+	}
+	zw, err := zstd.NewWriter(f)
+	if err != nil {
+		f.Close()
+		return "", err
+	}
+
+	err = json.NewEncoder(zw).Encode(e)
+	if zerr := zw.Close(); err == nil {
+		err = zerr
+	}
+	if ferr := f.Close(); err == nil {
+		err = ferr
+	}
+	return fn, err
+}
+
+// ReadSessionExport reads a file written by ExportSession.
+func ReadSessionExport(filename string) (*SessionExport, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var e SessionExport
+	if err := json.NewDecoder(zr).Decode(&e); err != nil {
+		return nil, err
+	}
+	if e.ConfigVersion != CurrentConfigVersion {
+		return nil, ErrIncompatibleSessionExport
+	}
+	return &e, nil
+}
@@ -5,13 +5,15 @@
 package main
 
 import (
+	"bytes"
 	crand "crypto/rand"
 	"encoding/base64"
+	"encoding/gob"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/rpc"
-	"runtime"
+	"path"
 	"slices"
 	"sort"
 	"strconv"
@@ -34,11 +36,17 @@ type SimScenarioConfiguration struct {
 	SplitConfigurations SplitConfigurationSet
 	PrimaryAirport      string
 
-	Wind         Wind
-	LaunchConfig LaunchConfig
+	Wind           Wind
+	WeatherPresets []string
+	LaunchConfig   LaunchConfig
 
 	DepartureRunways []ScenarioGroupDepartureRunway
 	ArrivalRunways   []ScenarioGroupArrivalRunway
+	HaveRandomEvents bool
+
+	// For assessing the wind on the scenario's runways.
+	MagneticVariation float32
+	TailwindLimits    map[string]int // airport -> knots, if not the default
 }
 
 const ServerSimCallsign = "__SERVER__"
@@ -65,6 +73,30 @@ type LaunchConfig struct {
 	ArrivalPushes               bool
 	ArrivalPushFrequencyMinutes int
 	ArrivalPushLengthMinutes    int
+	// If set, IFR departures wait on the ground until they are released
+	// by the departure controller.
+	RequireReleases bool
+	// Rate per hour at which aircraft randomly declare emergencies.
+	EmergencyRate float32
+	// Rate per hour at which equipment failures are injected at the
+	// human-controlled positions.
+	FailureRate float32
+	// Rate per hour of the scenario's random events.
+	RandomEventRate float32
+	// How much pilots' check-ins and readbacks vary from standard
+	// phraseology.
+	PilotRealism PilotRealism
+	// Probability that a pilot mishears an altitude, heading, or speed
+	// and reads back and flies the wrong one.
+	ReadbackErrorRate float32
+	// Probability that a pilot reads an instruction back correctly but
+	// flies the wrong value.
+	PilotErrorRate float32
+	// Maximum additional delay in seconds before pilots start turning
+	// after being given a heading.
+	ResponseDelay float32
+	// Probability that a transmission to a pilot is blocked.
+	FrequencyCongestion float32
 }
 
 func MakeLaunchConfig(dep []ScenarioGroupDepartureRunway, arr map[string]map[string]int) LaunchConfig {
@@ -134,9 +166,10 @@ func (lc *LaunchConfig) DrawDepartureUI() (changed bool) {
 	imgui.Text(fmt.Sprintf("Overall departure rate: %d / hour", sumRates))
 
 	changed = imgui.SliderFloatV("Sequencing challenge", &lc.DepartureChallenge, 0, 1, "%.02f", 0) || changed
+	changed = imgui.Checkbox("Departures require release", &lc.RequireReleases) || changed
 	flags := imgui.TableFlagsBordersV | imgui.TableFlagsBordersOuterH | imgui.TableFlagsRowBg | imgui.TableFlagsSizingStretchProp
 
-	tableScale := Select(runtime.GOOS == "windows", platform.DPIScale(), float32(1))
+	tableScale := ui.scale
 	if imgui.BeginTableV("departureRunways", 4, flags, imgui.Vec2{tableScale * 500, 0}, 0.) {
 		imgui.TableSetupColumn("Airport")
 		imgui.TableSetupColumn("Runway")
@@ -213,7 +246,7 @@ func (lc *LaunchConfig) DrawArrivalUI() (changed bool) {
 	uiEndDisable(!lc.ArrivalPushes)
 
 	flags := imgui.TableFlagsBordersV | imgui.TableFlagsBordersOuterH | imgui.TableFlagsRowBg | imgui.TableFlagsSizingStretchProp
-	tableScale := Select(runtime.GOOS == "windows", platform.DPIScale(), float32(1))
+	tableScale := ui.scale
 	if imgui.BeginTableV("arrivalgroups", 3, flags, imgui.Vec2{tableScale * 500, 0}, 0.) {
 		imgui.TableSetupColumn("Airport")
 		imgui.TableSetupColumn("Arrival")
@@ -255,16 +288,27 @@ type NewSimConfiguration struct {
 	NewSimName      string // for create remote only
 	RequirePassword bool   // for create remote only
 	Password        string // for create remote only
+	SpectatorDelay  int    // minutes; for create remote only. 0 -> no spectators
 	NewSimType      int
 
 	LiveWeather               bool
+	WeatherPreset             string // "" -> the scenario's (or live) weather
 	SelectedRemoteSim         string
 	SelectedRemoteSimPosition string
-	RemoteSimPassword         string // for join remote only
+	RemoteSimPassword         string         // for join remote only
+	JoinAsCoach               bool           // for join remote only
+	ImportedSession           *SessionExport // for import only
+
+	importFilename string
+	importDialog   *FileSelectDialogBox
 
 	lastRemoteSimsUpdate time.Time
 	updateRemoteSimsCall *PendingCall
 
+	// If set, the new sim is opened in a new tab and the current one
+	// stays connected.
+	openInNewTab bool
+
 	displayError error
 }
 
@@ -273,6 +317,7 @@ type RemoteSim struct {
 	ScenarioName       string
 	PrimaryController  string
 	RequirePassword    bool
+	SpectatorDelay     int // minutes; 0 if spectators aren't allowed
 	AvailablePositions map[string]struct{}
 	CoveredPositions   map[string]struct{}
 }
@@ -281,6 +326,7 @@ const (
 	NewSimCreateLocal = iota
 	NewSimCreateRemote
 	NewSimJoinRemote
+	NewSimImport
 )
 
 func MakeNewSimConfiguration() NewSimConfiguration {
@@ -357,7 +403,14 @@ func (c *NewSimConfiguration) SetScenario(groupName, scenarioName string) {
 }
 
 func (c *NewSimConfiguration) UIButtonText() string {
-	return Select(c.NewSimType == NewSimJoinRemote, "Join", "Next")
+	switch c.NewSimType {
+	case NewSimJoinRemote:
+		return "Join"
+	case NewSimImport:
+		return "Import"
+	default:
+		return "Next"
+	}
 }
 
 func (c *NewSimConfiguration) ShowRatesWindow() bool {
@@ -382,7 +435,7 @@ func (c *NewSimConfiguration) DrawUI() bool {
 		imgui.Separator()
 	}
 
-	tableScale := Select(runtime.GOOS == "windows", platform.DPIScale(), float32(1))
+	tableScale := ui.scale
 	if remoteServer != nil {
 		if imgui.BeginTableV("server", 2, 0, imgui.Vec2{tableScale * 500, 0}, 0.) {
 			imgui.TableNextRow()
@@ -421,6 +474,18 @@ func (c *NewSimConfiguration) DrawUI() bool {
 			}
 			uiEndDisable(len(remoteServer.runningSims) == 0)
 
+			imgui.TableNextRow()
+			imgui.TableNextColumn()
+			imgui.TableNextColumn()
+			if imgui.RadioButtonInt("Import exported session", &c.NewSimType, NewSimImport) &&
+				origType != NewSimImport {
+				c.selectedServer = remoteServer
+				c.displayError = nil
+			}
+			if imgui.IsItemHovered() {
+				imgui.SetTooltip("Continue a session that was exported from another server")
+			}
+
 			imgui.EndTable()
 		}
 	} else {
@@ -435,7 +500,7 @@ func (c *NewSimConfiguration) DrawUI() bool {
 	if c.NewSimType == NewSimCreateLocal || c.NewSimType == NewSimCreateRemote {
 		flags := imgui.TableFlagsBordersV | imgui.TableFlagsBordersOuterH | imgui.TableFlagsRowBg |
 			imgui.TableFlagsSizingStretchProp
-		tableScale := Select(runtime.GOOS == "windows", platform.DPIScale(), float32(1))
+		tableScale := ui.scale
 		if imgui.BeginTableV("SelectScenario", 3, flags, imgui.Vec2{tableScale * 600, tableScale * 300}, 0.) {
 			imgui.TableSetupColumn("ARTCC")
 			imgui.TableSetupColumn("ATCT/TRACON")
@@ -515,33 +580,13 @@ func (c *NewSimConfiguration) DrawUI() bool {
 		}
 
 		if c.NewSimType == NewSimCreateRemote {
-			if imgui.InputTextV("Name", &c.NewSimName, imgui.InputTextFlagsCallbackAlways,
-				func(cb imgui.InputTextCallbackData) int32 {
-					// Prevent excessively-long names...
-					const MaxLength = 32
-					if l := len(cb.Buffer()); l > MaxLength {
-						cb.DeleteBytes(MaxLength-1, l-MaxLength)
-					}
-					return 0
-				}) {
-				c.displayError = nil
-			}
-			if c.NewSimName == "" {
-				imgui.SameLine()
-				imgui.PushStyleColor(imgui.StyleColorText, imgui.Vec4{.7, .1, .1, 1})
-				imgui.Text(FontAwesomeIconExclamationTriangle)
-				imgui.PopStyleColor()
-			}
+			c.drawNameAndPasswordUI()
 
-			imgui.Checkbox("Require Password", &c.RequirePassword)
-			if c.RequirePassword {
-				imgui.InputTextV("Password", &c.Password, 0, nil)
-				if c.Password == "" {
-					imgui.SameLine()
-					imgui.PushStyleColor(imgui.StyleColorText, imgui.Vec4{.7, .1, .1, 1})
-					imgui.Text(FontAwesomeIconExclamationTriangle)
-					imgui.PopStyleColor()
-				}
+			delay := int32(c.SpectatorDelay)
+			imgui.SliderInt("Spectator delay (minutes)", &delay, 0, 30)
+			c.SpectatorDelay = int(delay)
+			if imgui.IsItemHovered() {
+				imgui.SetTooltip("Spectators see the sim this long after the controllers do. 0 disallows spectators.")
 			}
 		}
 
@@ -608,9 +653,61 @@ func (c *NewSimConfiguration) DrawUI() bool {
 				clear(windRequest)
 			}
 			uiEndDisable(!c.LiveWeather)
+
+			configs := c.TRACON[c.GroupName].ScenarioConfigs
+			winds := scenarioRunwayWinds(configs, c.ScenarioName, func(string) Wind { return wind })
+			if cur := winds[c.ScenarioName]; len(cur) > 0 {
+				imgui.TableNextRow()
+				imgui.TableNextColumn()
+				imgui.Text("Runway winds:")
+				imgui.TableNextColumn()
+				for _, rw := range cur {
+					if rw.ExceedsTailwindLimit() {
+						imgui.PushStyleColor(imgui.StyleColorText, imgui.Vec4{1, .5, .5, 1})
+						imgui.Text(rw.String() + fmt.Sprintf(" (limit %d kt)", rw.TailwindLimit))
+						imgui.PopStyleColor()
+					} else {
+						imgui.Text(rw.String())
+					}
+				}
+				if best := suggestRunwayConfig(c.ScenarioName, winds); best != c.ScenarioName {
+					imgui.Text("Suggested for the wind: " + best)
+					imgui.SameLine()
+					if imgui.Button("Select##runwayconfig") {
+						c.SetScenario(c.GroupName, best)
+					}
+				}
+			}
+
+			imgui.TableNextRow()
+			imgui.TableNextColumn()
+			imgui.Text("Weather:")
+			imgui.TableNextColumn()
+			if c.LiveWeather || !slices.Contains(c.Scenario.WeatherPresets, c.WeatherPreset) {
+				c.WeatherPreset = ""
+			}
+			uiStartDisable(c.LiveWeather)
+			weatherPresetCombo("##weather", &c.WeatherPreset, c.Scenario.WeatherPresets)
+			uiEndDisable(c.LiveWeather)
 			imgui.EndTable()
 
 		}
+	} else if c.NewSimType == NewSimImport {
+		imgui.Text("Session: " + Select(c.importFilename != "", path.Base(c.importFilename), "(none selected)"))
+		imgui.SameLine()
+		if imgui.Button("Select...") {
+			c.importDialog = NewFileSelectDialogBox("Select Exported Session...", []string{".vicesim"}, c.importFilename,
+				func(fn string) {
+					c.importFilename = fn
+					c.displayError = nil
+				})
+			c.importDialog.Activate()
+		}
+		if c.importDialog != nil {
+			c.importDialog.Draw()
+		}
+
+		c.drawNameAndPasswordUI()
 	} else {
 		// Join remote
 		runningSims := remoteServer.runningSims
@@ -638,7 +735,7 @@ func (c *NewSimConfiguration) DrawUI() bool {
 
 			for _, simName := range SortedMapKeys(runningSims) {
 				rs := runningSims[simName]
-				if len(rs.AvailablePositions) == 0 {
+				if len(rs.AvailablePositions) == 0 && !(c.JoinAsCoach && len(rs.CoveredPositions) > 0) {
 					// No open positions left; don't even offer it.
 					continue
 				}
@@ -681,12 +778,46 @@ func (c *NewSimConfiguration) DrawUI() bool {
 			imgui.EndTable()
 		}
 
-		// Handle the case of someone else signing in to the position
-		if _, ok := rs.AvailablePositions[c.SelectedRemoteSimPosition]; c.SelectedRemoteSimPosition != "Observer" && !ok {
-			c.SelectedRemoteSimPosition = SortedMapKeys(rs.AvailablePositions)[0]
+		if len(rs.CoveredPositions) > 0 {
+			imgui.Checkbox("Join as coach", &c.JoinAsCoach)
+			if imgui.IsItemHovered() {
+				imgui.SetTooltip("See the scope of a controller who is already signed in and share a cursor " +
+					"and aircraft highlights with them. Coaches can't issue commands.")
+			}
+		} else {
+			c.JoinAsCoach = false
+		}
+
+		if c.JoinAsCoach {
+			// Coaches join a position that someone is already covering.
+			if _, ok := rs.CoveredPositions[c.SelectedRemoteSimPosition]; !ok {
+				c.SelectedRemoteSimPosition = SortedMapKeys(rs.CoveredPositions)[0]
+			}
+
+			if imgui.BeginComboV("Position", c.SelectedRemoteSimPosition, 0) {
+				for _, pos := range SortedMapKeys(rs.CoveredPositions) {
+					if pos[0] == '_' {
+						continue
+					}
+					if imgui.SelectableV(pos, pos == c.SelectedRemoteSimPosition, 0, imgui.Vec2{}) {
+						c.SelectedRemoteSimPosition = pos
+					}
+				}
+				imgui.EndCombo()
+			}
+		} else {
+			// Handle the case of someone else signing in to the position
+			if _, ok := rs.AvailablePositions[c.SelectedRemoteSimPosition]; c.SelectedRemoteSimPosition != "Observer" &&
+				!(c.SelectedRemoteSimPosition == "Spectator" && rs.SpectatorDelay > 0) && !ok {
+				if len(rs.AvailablePositions) > 0 {
+					c.SelectedRemoteSimPosition = SortedMapKeys(rs.AvailablePositions)[0]
+				} else {
+					c.SelectedRemoteSimPosition = "Observer"
+				}
+			}
 		}
 
-		if imgui.BeginComboV("Position", c.SelectedRemoteSimPosition, 0) {
+		if !c.JoinAsCoach && imgui.BeginComboV("Position", c.SelectedRemoteSimPosition, 0) {
 			for _, pos := range SortedMapKeys(rs.AvailablePositions) {
 				if pos[0] == '_' {
 					continue
@@ -699,6 +830,14 @@ func (c *NewSimConfiguration) DrawUI() bool {
 			if imgui.SelectableV("Observer", "Observer" == c.SelectedRemoteSimPosition, 0, imgui.Vec2{}) {
 				c.SelectedRemoteSimPosition = "Observer"
 			}
+			if rs.SpectatorDelay > 0 {
+				if imgui.SelectableV("Spectator", "Spectator" == c.SelectedRemoteSimPosition, 0, imgui.Vec2{}) {
+					c.SelectedRemoteSimPosition = "Spectator"
+				}
+				if imgui.IsItemHovered() {
+					imgui.SetTooltip(fmt.Sprintf("Watch the sim with a %d minute delay", rs.SpectatorDelay))
+				}
+			}
 
 			imgui.EndCombo()
 		}
@@ -707,12 +846,58 @@ func (c *NewSimConfiguration) DrawUI() bool {
 		}
 	}
 
+	if worldTabs.Active() != nil {
+		imgui.Separator()
+		imgui.Checkbox("Keep the current simulation connected in another tab", &c.openInNewTab)
+	}
+
 	return false
 }
 
+// drawNameAndPasswordUI draws the controls for the name and password of a
+// new multi-controller sim.
+func (c *NewSimConfiguration) drawNameAndPasswordUI() {
+	if imgui.InputTextV("Name", &c.NewSimName, imgui.InputTextFlagsCallbackAlways,
+		func(cb imgui.InputTextCallbackData) int32 {
+			// Prevent excessively-long names...
+			const MaxLength = 32
+			if l := len(cb.Buffer()); l > MaxLength {
+				cb.DeleteBytes(MaxLength-1, l-MaxLength)
+			}
+			return 0
+		}) {
+		c.displayError = nil
+	}
+	if c.NewSimName == "" {
+		imgui.SameLine()
+		imgui.PushStyleColor(imgui.StyleColorText, imgui.Vec4{.7, .1, .1, 1})
+		imgui.Text(FontAwesomeIconExclamationTriangle)
+		imgui.PopStyleColor()
+	}
+
+	imgui.Checkbox("Require Password", &c.RequirePassword)
+	if c.RequirePassword {
+		imgui.InputTextV("Password", &c.Password, 0, nil)
+		if c.Password == "" {
+			imgui.SameLine()
+			imgui.PushStyleColor(imgui.StyleColorText, imgui.Vec4{.7, .1, .1, 1})
+			imgui.Text(FontAwesomeIconExclamationTriangle)
+			imgui.PopStyleColor()
+		}
+	}
+}
+
 func (c *NewSimConfiguration) DrawRatesUI() bool {
+	c.Scenario.LaunchConfig.DrawDifficultyUI()
+	imgui.Separator()
 	c.Scenario.LaunchConfig.DrawDepartureUI()
 	c.Scenario.LaunchConfig.DrawArrivalUI()
+	c.Scenario.LaunchConfig.DrawEmergencyUI()
+	c.Scenario.LaunchConfig.DrawFailureUI()
+	if c.Scenario.HaveRandomEvents {
+		c.Scenario.LaunchConfig.DrawRandomEventUI()
+	}
+	c.Scenario.LaunchConfig.DrawPilotRealismUI()
 	return false
 }
 
@@ -759,10 +944,24 @@ func getWind(airport string) (Wind, bool) {
 }
 
 func (c *NewSimConfiguration) OkDisabled() bool {
-	return c.NewSimType == NewSimCreateRemote && (c.NewSimName == "" || (c.RequirePassword && c.Password == ""))
+	if c.NewSimType == NewSimImport && c.importFilename == "" {
+		return true
+	}
+	return (c.NewSimType == NewSimCreateRemote || c.NewSimType == NewSimImport) &&
+		(c.NewSimName == "" || (c.RequirePassword && c.Password == ""))
 }
 
 func (c *NewSimConfiguration) Start() error {
+	if c.NewSimType == NewSimImport {
+		e, err := ReadSessionExport(c.importFilename)
+		if err != nil {
+			return err
+		}
+		c.ImportedSession = e
+		// It's only needed for the RPC call.
+		defer func() { c.ImportedSession = nil }()
+	}
+
 	var result NewSimResult
 	if err := c.selectedServer.CallWithTimeout("SimManager.New", c, &result); err != nil {
 		err = TryDecodeError(err)
@@ -783,7 +982,11 @@ func (c *NewSimConfiguration) Start() error {
 
 	globalConfig.LastTRACON = c.TRACONName
 
-	newWorldChan <- result.World
+	if c.openInNewTab {
+		newWorldTabChan <- result.World
+	} else {
+		newWorldChan <- result.World
+	}
 
 	return nil
 }
@@ -798,6 +1001,9 @@ type Sim struct {
 
 	ScenarioGroup string
 	Scenario      string
+	// The scenario group's definition, which is updated if it's
+	// reloaded. It's not saved, so it's nil for restored sims.
+	scenarioGroup *ScenarioGroup
 
 	World           *World
 	controllers     map[string]*ServerController // from token
@@ -819,6 +1025,15 @@ type Sim struct {
 	// Key is arrival group name
 	NextArrivalSpawn map[string]time.Time
 
+	// One per background traffic route, followed by one for overflights.
+	NextBackgroundSpawn []time.Time
+
+	// Key is satellite airport
+	NextSatelliteSpawn map[string]time.Time
+
+	// Indexed by helicopter route
+	NextHelicopterSpawn []time.Time
+
 	// callsign -> auto accept time
 	Handoffs map[string]time.Time
 	// callsign -> "to" controller
@@ -844,6 +1059,72 @@ type Sim struct {
 
 	NextPushStart time.Time // both w.r.t. sim time
 	PushEnd       time.Time
+
+	ScheduledLaunches []ScheduledLaunch
+
+	// Departures waiting on the ground for release
+	DepartureReleases []DepartureRelease
+
+	// Equipment failures currently in effect
+	SystemFailures []SystemFailure
+	RadarOutages   []RadarOutage
+	PIREPs         []PIREP
+
+	ActiveRandomEvents []RandomEvent
+
+	// Controller callsign -> statistics for the debrief report
+	SessionStats     map[string]*SessionStats
+	arrivalTimings   map[string]arrivalTiming
+	separationLosses map[string]bool // "callsign/callsign" -> currently in conflict
+
+	// Clearances sent via datalink that the pilots haven't acknowledged
+	// yet. They aren't saved with the sim since the controller tokens
+	// won't be valid when it's restored.
+	datalinkUplinks   []DatalinkUplink
+	datalinkExecuting map[string]bool // callsign -> running an uplinked clearance
+
+	// airport -> current ATIS
+	ATIS map[string]ATIS
+
+	// The METARs before any WeatherPreset was applied.
+	BaseMETAR map[string]*METAR
+
+	// If non-zero, a "Spectator" may sign on and will see the state of
+	// the sim as it was this long ago (w.r.t. wallclock time).
+	SpectatorDelay      time.Duration
+	spectatorFrames     []spectatorFrame
+	spectatorEvents     *EventsSubscription
+	spectatorEventQueue []spectatorEvent
+	spectatorEventSeq   int // of the last event added to spectatorEventQueue
+	lastSpectatorRecord time.Time
+}
+
+// maxSpectatorFrames bounds the number of snapshots of the sim's state
+// that are kept for spectators; with long delays, they are recorded less
+// frequently.
+const maxSpectatorFrames = 300
+
+// spectatorFrame is a snapshot of the sim's state that is sent to
+// spectators once the sim's SpectatorDelay has passed. Events are queued
+// separately so that none are lost if a spectator doesn't ask for an
+// update for a while.
+type spectatorFrame struct {
+	WallTime time.Time
+	Update   SimWorldUpdate // without Events
+}
+
+type spectatorEvent struct {
+	WallTime time.Time
+	Seq      int
+	Event    Event
+}
+
+// ScheduledLaunch is an aircraft that is to be launched at a later time;
+// these are used e.g. for traffic imported from ADS-B traces.
+type ScheduledLaunch struct {
+	Delay    time.Duration // w.r.t. when the launch was scheduled
+	Time     time.Time     // sim time of the launch; set by the Sim
+	Aircraft Aircraft
 }
 
 type PointOut struct {
@@ -856,6 +1137,23 @@ type ServerController struct {
 	lastUpdateCall      time.Time
 	warnedNoUpdateCalls bool
 	events              *EventsSubscription
+	spectatorSeq        int // last spectatorEvent sent, for spectators
+
+	// Coaches are signed on to a position that is already covered; they
+	// see what its controller sees but are unable to issue commands.
+	coach      bool
+	coachState CoachState
+}
+
+// CoachState is what a coach shares with the controller they are
+// coaching: the position of their cursor and the aircraft they have
+// highlighted.
+type CoachState struct {
+	Cursor      Point2LL // zero if the cursor isn't over the scope
+	Highlighted []string // callsigns
+	// Takeover is set when the coach has temporarily taken command
+	// authority for the position; it is only changed by the Sim.
+	Takeover bool
 }
 
 func (sc *ServerController) LogValue() slog.Value {
@@ -924,6 +1222,7 @@ func NewSim(ssc NewSimConfiguration, scenarioGroups map[string]map[string]*Scena
 	s := &Sim{
 		ScenarioGroup: ssc.GroupName,
 		Scenario:      ssc.ScenarioName,
+		scenarioGroup: sg,
 		LaunchConfig:  ssc.Scenario.LaunchConfig,
 
 		controllers: make(map[string]*ServerController),
@@ -937,6 +1236,7 @@ func NewSim(ssc NewSimConfiguration, scenarioGroups map[string]map[string]*Scena
 
 		Password:        ssc.Password,
 		RequirePassword: ssc.RequirePassword,
+		SpectatorDelay:  time.Duration(ssc.SpectatorDelay) * time.Minute,
 
 		SimTime:        time.Now(),
 		lastUpdateTime: time.Now(),
@@ -983,6 +1283,17 @@ func NewSim(ssc NewSimConfiguration, scenarioGroups map[string]map[string]*Scena
 
 	s.World = newWorld(ssc, s, sg, sc)
 
+	s.BaseMETAR = make(map[string]*METAR)
+	for icao, m := range s.World.METAR {
+		mc := *m
+		s.BaseMETAR[icao] = &mc
+	}
+	if ssc.WeatherPreset != "" {
+		if err := s.applyWeatherPreset(ssc.WeatherPreset); err != nil {
+			lg.Errorf("%s: %v", ssc.WeatherPreset, err)
+		}
+	}
+
 	s.setInitialSpawnTimes()
 
 	return s
@@ -1003,6 +1314,8 @@ func newWorld(ssc NewSimConfiguration, s *Sim, sg *ScenarioGroup, sc *Scenario)
 	w.Wind = sc.Wind
 	w.Airports = sg.Airports
 	w.Fixes = sg.Fixes
+	w.Holds = sg.Holds
+	w.PreferentialRoutes = sg.PreferentialRoutes
 	w.PrimaryAirport = sg.PrimaryAirport
 	fa := sg.STARSFacilityAdaptation
 	w.RadarSites = fa.RadarSites
@@ -1137,8 +1450,23 @@ func newWorld(ssc NewSimConfiguration, s *Sim, sg *ScenarioGroup, sc *Scenario)
 		for ap := range w.ArrivalAirports {
 			fakeMETAR(ap)
 		}
+		// METARs given in the scenario take precedence over the
+		// generated ones.
+		for _, str := range sc.METAR {
+			if m, err := ParseMETAR(str); err == nil {
+				w.METAR[m.AirportICAO] = m
+			}
+		}
 	}
 
+	w.Weather = MakeWeatherModel(w.METAR, w.PrimaryAirport, sc.WindsAloft, w.Wind)
+	w.WeatherPresets = sc.AllWeatherPresets()
+	w.BackgroundTraffic = sc.BackgroundTraffic
+	w.SatelliteTraffic = sc.SatelliteTraffic
+	w.HelicopterRoutes = sc.HelicopterRoutes
+	w.RandomEvents = sc.RandomEvents
+	w.GroundMovement = sc.GroundMovement
+
 	return w
 }
 
@@ -1173,26 +1501,82 @@ func (s *Sim) LogValue() slog.Value {
 		slog.Any("aircraft", s.World.Aircraft))
 }
 
+func newControllerToken() (string, error) {
+	var buf [16]byte
+	if _, err := crand.Read(buf[:]); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf[:]), nil
+}
+
 func (s *Sim) SignOn(callsign string) (*World, string, error) {
 	if err := s.signOn(callsign); err != nil {
 		return nil, "", err
 	}
 
-	var buf [16]byte
-	if _, err := crand.Read(buf[:]); err != nil {
+	token, err := newControllerToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	sc := &ServerController{
+		Callsign:       callsign,
+		lastUpdateCall: time.Now(),
+		events:         s.eventStream.Subscribe(),
+	}
+	if callsign == "Spectator" {
+		// Spectators start with the events from SpectatorDelay ago.
+		s.mu.Lock(s.lg)
+		sc.spectatorSeq = s.spectatorStartSeq()
+		s.mu.Unlock(s.lg)
+	}
+	s.controllers[token] = sc
+
+	w := NewWorld()
+	w.Assign(s.World)
+	w.Callsign = callsign
+	if callsign == "Spectator" {
+		// Don't leak the current state; the delayed aircraft and
+		// controllers will arrive with the first update.
+		w.Aircraft = make(map[string]*Aircraft)
+		w.Controllers = make(map[string]*Controller)
+	}
+
+	return w, token, nil
+}
+
+// SignOnCoach signs on a coach for the controller at the given position,
+// which must already be signed on.
+func (s *Sim) SignOnCoach(callsign string) (*World, string, error) {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	if !s.controllerIsSignedIn(callsign) {
+		return nil, "", ErrNoController
+	}
+
+	token, err := newControllerToken()
+	if err != nil {
 		return nil, "", err
 	}
-	token := base64.StdEncoding.EncodeToString(buf[:])
 
 	s.controllers[token] = &ServerController{
 		Callsign:       callsign,
 		lastUpdateCall: time.Now(),
 		events:         s.eventStream.Subscribe(),
+		coach:          true,
 	}
 
+	s.eventStream.Post(Event{
+		Type:    StatusMessageEvent,
+		Message: "A coach has joined " + callsign + ".",
+	})
+	s.lg.Infof("%s: coach signed on", callsign)
+
 	w := NewWorld()
 	w.Assign(s.World)
 	w.Callsign = callsign
+	w.IsCoach = true
 
 	return w, token, nil
 }
@@ -1201,7 +1585,11 @@ func (s *Sim) signOn(callsign string) error {
 	s.mu.Lock(s.lg)
 	defer s.mu.Unlock(s.lg)
 
-	if callsign != "Observer" {
+	if callsign == "Spectator" {
+		if s.SpectatorDelay == 0 {
+			return ErrNoController
+		}
+	} else if callsign != "Observer" {
 		if s.controllerIsSignedIn(callsign) {
 			return ErrControllerAlreadySignedIn
 		}
@@ -1235,12 +1623,28 @@ func (s *Sim) SignOff(token string) error {
 
 	if ctrl, ok := s.controllers[token]; !ok {
 		return ErrInvalidControllerToken
+	} else if ctrl.coach {
+		ctrl.events.Unsubscribe()
+		delete(s.controllers, token)
+
+		s.eventStream.Post(Event{
+			Type:    StatusMessageEvent,
+			Message: "The coach for " + ctrl.Callsign + " has signed off.",
+		})
+		s.lg.Infof("%s: coach signing off", ctrl.Callsign)
 	} else {
 		// Drop track on controlled aircraft
 		for _, ac := range s.World.Aircraft {
 			ac.HandleControllerDisconnect(ctrl.Callsign, s.World)
 		}
 
+		// A coach can't keep control of a position nobody is signed in to.
+		for _, c := range s.controllers {
+			if c.coach && c.Callsign == ctrl.Callsign {
+				c.coachState.Takeover = false
+			}
+		}
+
 		if ctrl.Callsign == s.LaunchConfig.Controller {
 			// give up control of launches so someone else can take it.
 			s.LaunchConfig.Controller = ""
@@ -1323,11 +1727,27 @@ type SimWorldUpdate struct {
 
 	LaunchConfig LaunchConfig
 
+	// Departures waiting for release
+	DepartureReleases  []DepartureRelease
+	SystemFailures     []SystemFailure
+	RadarOutages       []RadarOutage
+	PIREPs             []PIREP
+	ActiveRandomEvents []RandomEvent
+	SessionStats       *SessionStats
+
 	SimIsPaused     bool
 	SimRate         float32
 	Events          []Event
 	TotalDepartures int
 	TotalArrivals   int
+	Coach           *CoachState
+
+	// The weather may be changed while the sim is running (via a
+	// WeatherPreset). These are nil in replays recorded before that was
+	// possible.
+	METAR         map[string]*METAR
+	Weather       *WeatherModel
+	WeatherPreset string
 }
 
 func (wu *SimWorldUpdate) UpdateWorld(w *World, eventStream *EventStream) {
@@ -1337,12 +1757,33 @@ func (wu *SimWorldUpdate) UpdateWorld(w *World, eventStream *EventStream) {
 	}
 
 	w.LaunchConfig = wu.LaunchConfig
+	w.DepartureReleases = wu.DepartureReleases
+	w.SystemFailures = wu.SystemFailures
+	w.RadarOutages = wu.RadarOutages
+	w.PIREPs = wu.PIREPs
+	w.ActiveRandomEvents = wu.ActiveRandomEvents
+	if wu.SessionStats != nil {
+		w.SessionStats = wu.SessionStats
+	}
 
+	if wu.Time.Before(w.SimTime) {
+		// Time only goes backward when a replay is rewound; let
+		// CurrentTime() follow along.
+		w.lastReturnedTime = time.Time{}
+	}
 	w.SimTime = wu.Time
 	w.SimIsPaused = wu.SimIsPaused
 	w.SimRate = wu.SimRate
 	w.TotalDepartures = wu.TotalDepartures
 	w.TotalArrivals = wu.TotalArrivals
+	w.CoachState = wu.Coach
+	if wu.METAR != nil {
+		w.METAR = wu.METAR
+	}
+	if wu.Weather != nil {
+		w.Weather = *wu.Weather
+		w.WeatherPreset = wu.WeatherPreset
+	}
 
 	// Important: do this after updating aircraft, controllers, etc.,
 	// so that they reflect any changes the events are flagging.
@@ -1368,6 +1809,12 @@ func (s *Sim) GetWorldUpdate(token string, update *SimWorldUpdate) error {
 			})
 		}
 
+		if ctrl.Callsign == "Spectator" {
+			ctrl.events.Get() // spectators get the delayed events instead
+			*update = s.spectatorUpdate(ctrl)
+			return nil
+		}
+
 		*update = SimWorldUpdate{
 			Aircraft:        s.World.Aircraft,
 			Controllers:     s.World.Controllers,
@@ -1378,12 +1825,395 @@ func (s *Sim) GetWorldUpdate(token string, update *SimWorldUpdate) error {
 			Events:          ctrl.events.Get(),
 			TotalDepartures: s.TotalDepartures,
 			TotalArrivals:   s.TotalArrivals,
+			METAR:           s.World.METAR,
+			Weather:         &s.World.Weather,
+			WeatherPreset:   s.World.WeatherPreset,
+		}
+		update.DepartureReleases = s.DepartureReleases
+		update.SystemFailures = s.SystemFailures
+		update.RadarOutages = s.RadarOutages
+		update.PIREPs = s.PIREPs
+		update.ActiveRandomEvents = s.ActiveRandomEvents
+		if ctrl.Callsign != "Observer" && ctrl.Callsign != "Spectator" && !ctrl.coach {
+			ss := *s.sessionStats(ctrl.Callsign)
+			ss.AircraftHandled = slices.Clone(ss.AircraftHandled)
+			ss.OperationalErrors = slices.Clone(ss.OperationalErrors)
+			ss.End = s.SimTime
+			update.SessionStats = &ss
+		}
+
+		for _, c := range s.controllers {
+			if c.coach && c.Callsign == ctrl.Callsign {
+				cs := c.coachState
+				update.Coach = &cs
+			}
 		}
 
 		return nil
 	}
 }
 
+func (s *Sim) SetCoachState(token string, cs CoachState) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	if ctrl, ok := s.controllers[token]; !ok || !ctrl.coach {
+		return ErrInvalidControllerToken
+	} else {
+		cs.Takeover = ctrl.coachState.Takeover
+		ctrl.coachState = cs
+		return nil
+	}
+}
+
+// SetCoachTakeover gives command authority for the coached position to
+// the coach or returns it to the controller signed in to it.
+func (s *Sim) SetCoachTakeover(token string, takeover bool) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	ctrl, ok := s.controllers[token]
+	if !ok || !ctrl.coach {
+		return ErrInvalidControllerToken
+	}
+	if takeover && !s.controllerIsSignedIn(ctrl.Callsign) {
+		return ErrNoController
+	}
+	if ctrl.coachState.Takeover == takeover {
+		return nil
+	}
+	ctrl.coachState.Takeover = takeover
+
+	msg := Select(takeover, "The coach has taken control of "+ctrl.Callsign+".",
+		"The coach has returned control of "+ctrl.Callsign+".")
+	s.eventStream.Post(Event{
+		Type:    StatusMessageEvent,
+		Message: msg,
+	})
+	s.lg.Info("coach takeover", slog.String("controller", ctrl.Callsign), slog.Bool("takeover", takeover))
+
+	return nil
+}
+
+// hasCommandAuthority indicates whether commands from the given
+// controller should be accepted: while a coach has taken over a position,
+// only the coach's are.
+func (s *Sim) hasCommandAuthority(sc *ServerController) bool {
+	if sc.coach {
+		return sc.coachState.Takeover
+	}
+	for _, c := range s.controllers {
+		if c.coach && c.Callsign == sc.Callsign && c.coachState.Takeover {
+			return false
+		}
+	}
+	return true
+}
+
+// updateSpectators queues the sim's events for spectators and
+// periodically saves a snapshot of its state; frames and events that are
+// no longer needed are discarded. s.mu must be held.
+func (s *Sim) updateSpectators() {
+	now := time.Now()
+	cutoff := now.Add(-s.SpectatorDelay)
+
+	if s.spectatorEvents == nil {
+		s.spectatorEvents = s.eventStream.Subscribe()
+	}
+	for _, e := range s.spectatorEvents.Get() {
+		s.spectatorEventSeq++
+		s.spectatorEventQueue = append(s.spectatorEventQueue,
+			spectatorEvent{WallTime: now, Seq: s.spectatorEventSeq, Event: e})
+	}
+
+	// Events are kept until they have been sent to all of the spectators.
+	sent := s.spectatorEventSeq
+	for _, ctrl := range s.controllers {
+		if ctrl.Callsign == "Spectator" {
+			sent = min(sent, ctrl.spectatorSeq)
+		}
+	}
+	s.spectatorEventQueue = slices.DeleteFunc(s.spectatorEventQueue, func(e spectatorEvent) bool {
+		return e.Seq <= sent && !e.WallTime.After(cutoff)
+	})
+
+	if now.Sub(s.lastSpectatorRecord) >= max(time.Second, s.SpectatorDelay/maxSpectatorFrames) {
+		s.recordSpectatorFrame(now)
+	}
+
+	// Only the most recent frame that is older than the delay is still
+	// of use to spectators.
+	for len(s.spectatorFrames) > 1 && !s.spectatorFrames[1].WallTime.After(cutoff) {
+		s.spectatorFrames = s.spectatorFrames[1:]
+	}
+}
+
+// recordSpectatorFrame saves a snapshot of the current state of the sim
+// for spectators. s.mu must be held.
+func (s *Sim) recordSpectatorFrame(now time.Time) {
+	s.lastSpectatorRecord = now
+
+	// The aircraft will continue to be updated, so they are deep-copied
+	// (via gob) for the snapshot.
+	var buf bytes.Buffer
+	var aircraft map[string]*Aircraft
+	err := gob.NewEncoder(&buf).Encode(s.World.Aircraft)
+	if err == nil {
+		err = gob.NewDecoder(&buf).Decode(&aircraft)
+	}
+	if err != nil {
+		s.lg.Errorf("unable to snapshot aircraft for spectators: %v", err)
+		return
+	}
+	if aircraft == nil { // gob doesn't send empty maps
+		aircraft = make(map[string]*Aircraft)
+	}
+
+	weather := s.World.Weather
+	s.spectatorFrames = append(s.spectatorFrames, spectatorFrame{
+		WallTime: now,
+		Update: SimWorldUpdate{
+			Aircraft:        aircraft,
+			Controllers:     DuplicateMap(s.World.Controllers),
+			Time:            s.SimTime,
+			LaunchConfig:    s.LaunchConfig,
+			SimIsPaused:     s.Paused,
+			SimRate:         s.SimRate,
+			TotalDepartures: s.TotalDepartures,
+			TotalArrivals:   s.TotalArrivals,
+			METAR:           DuplicateMap(s.World.METAR),
+			Weather:         &weather,
+			WeatherPreset:   s.World.WeatherPreset,
+		},
+	})
+}
+
+// spectatorStartSeq returns the sequence number of the last event from
+// before SpectatorDelay ago; a new spectator is sent the events after it.
+// s.mu must be held.
+func (s *Sim) spectatorStartSeq() int {
+	cutoff := time.Now().Add(-s.SpectatorDelay)
+	// All of the events that have been discarded from the queue are from
+	// before the cutoff.
+	seq := s.spectatorEventSeq - len(s.spectatorEventQueue)
+	for _, e := range s.spectatorEventQueue {
+		if e.WallTime.After(cutoff) {
+			break
+		}
+		seq = e.Seq
+	}
+	return seq
+}
+
+// spectatorUpdate returns the delayed world update for the given
+// spectator, including all of the events from SpectatorDelay ago or
+// earlier that it hasn't been sent yet. s.mu must be held.
+func (s *Sim) spectatorUpdate(ctrl *ServerController) SimWorldUpdate {
+	cutoff := time.Now().Add(-s.SpectatorDelay)
+	idx := -1
+	for i, f := range s.spectatorFrames {
+		if f.WallTime.After(cutoff) {
+			break
+		}
+		idx = i
+	}
+
+	var update SimWorldUpdate
+	if idx == -1 {
+		// Nothing to show yet.
+		update = SimWorldUpdate{
+			Aircraft:     make(map[string]*Aircraft),
+			Controllers:  make(map[string]*Controller),
+			Time:         s.SimTime.Add(-s.SpectatorDelay),
+			LaunchConfig: s.LaunchConfig,
+			SimIsPaused:  true,
+			SimRate:      s.SimRate,
+		}
+	} else {
+		update = s.spectatorFrames[idx].Update
+	}
+
+	for _, e := range s.spectatorEventQueue {
+		if e.WallTime.After(cutoff) {
+			break
+		}
+		if e.Seq > ctrl.spectatorSeq {
+			update.Events = append(update.Events, e.Event)
+			ctrl.spectatorSeq = e.Seq
+		}
+	}
+
+	return update
+}
+
+// applyWeatherPreset updates the METARs and weather model for the named
+// WeatherPreset, or restores the sim's original weather if name is
+// empty. The go around rate is updated as well, as are the ATISs. s.mu
+// must be held.
+func (s *Sim) applyWeatherPreset(name string) error {
+	var preset *WeatherPreset
+	if name != "" {
+		if p, ok := s.World.WeatherPresets[name]; !ok {
+			return ErrUnknownWeatherPreset
+		} else {
+			preset = &p
+		}
+	}
+
+	metar := make(map[string]*METAR)
+	for icao, m := range s.BaseMETAR {
+		if preset != nil {
+			metar[icao] = preset.Apply(m)
+		} else {
+			mc := *m
+			metar[icao] = &mc
+		}
+	}
+	s.World.METAR = metar
+
+	// The winds aloft don't depend on the preset.
+	s.World.Weather = MakeWeatherModel(metar, s.World.PrimaryAirport, s.World.Weather.WindsAloft, s.World.Wind)
+	if preset != nil {
+		s.World.Weather.Visibility = preset.Visibility
+		s.World.Weather.Ceiling = preset.Ceiling()
+		s.LaunchConfig.GoAroundRate = preset.GoAroundRate
+	}
+	s.World.WeatherPreset = name
+
+	s.updateATIS()
+
+	s.eventStream.Post(Event{
+		Type:    StatusMessageEvent,
+		Message: "The weather is now " + Select(name == "", "the scenario's default", name) + ".",
+	})
+	s.lg.Info("weather preset", slog.String("preset", name))
+
+	return nil
+}
+
+func (s *Sim) SetWeatherPreset(token, name string) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	if ctrl, ok := s.controllers[token]; !ok {
+		return ErrInvalidControllerToken
+	} else if ctrl.Callsign == "Observer" || ctrl.Callsign == "Spectator" {
+		return ErrInvalidController
+	} else {
+		return s.applyWeatherPreset(name)
+	}
+}
+
+// requestWeatherDeviation occasionally has an aircraft deviate from its
+// course for weather, at the rate given by the current weather preset.
+// s.mu must be held.
+func (s *Sim) requestWeatherDeviation() {
+	preset, ok := s.World.WeatherPresets[s.World.WeatherPreset]
+	if !ok || rand.Float32() >= preset.DeviationRate/3600 {
+		return
+	}
+
+	// Only aircraft that a human is working that are up and away from
+	// the airport.
+	callsigns := FilterSlice(SortedMapKeys(s.World.Aircraft), func(callsign string) bool {
+		ac := s.World.Aircraft[callsign]
+		return s.controllerIsSignedIn(ac.ControllingController) && ac.Nav.IsAirborne() &&
+			ac.Nav.FlightState.Altitude > 5000 && !ac.Nav.Approach.Cleared
+	})
+	if len(callsigns) == 0 {
+		return
+	}
+
+	ac := s.World.Aircraft[callsigns[rand.Intn(len(callsigns))]]
+	deg := 10 * (1 + rand.Intn(3))
+	hdg, turn, dir := ac.Nav.FlightState.Heading-float32(deg), TurnMethod(TurnLeft), "left"
+	if rand.Intn(2) == 0 {
+		hdg, turn, dir = ac.Nav.FlightState.Heading+float32(deg), TurnRight, "right"
+	}
+	hdg = float32(int(NormalizeHeading(hdg) + 0.5)) // heading to the nearest degree
+	if hdg == 0 {
+		hdg = 360
+	}
+	ac.Nav.AssignHeading(hdg, turn)
+
+	PostRadioEvents(ac.Callsign, []RadioTransmission{RadioTransmission{
+		Controller: ac.ControllingController,
+		Message:    fmt.Sprintf("deviating %d degrees %s for weather, heading %03d", deg, dir, int(hdg)),
+		Type:       RadioTransmissionUnexpected,
+	}}, s)
+	s.lg.Info("weather deviation", slog.String("callsign", ac.Callsign), slog.Float64("heading", float64(hdg)))
+}
+
+// updateATIS generates the ATIS for each of the scenario's airports from
+// its METAR and the runways and approaches in use. An airport's ATIS
+// letter advances whenever anything that it reports changes. s.mu must
+// be held.
+func (s *Sim) updateATIS() {
+	if s.ATIS == nil {
+		s.ATIS = make(map[string]ATIS)
+	}
+
+	for name := range s.World.AllAirports() {
+		var departureRunways []string
+		for rwy, categoryRates := range s.LaunchConfig.DepartureRates[name] {
+			for _, rate := range categoryRates {
+				if rate > 0 {
+					departureRunways = append(departureRunways, rwy)
+					break
+				}
+			}
+		}
+		slices.Sort(departureRunways)
+
+		var arrivalRunways, approaches []string
+		for _, rwy := range s.World.ArrivalRunways {
+			if rwy.Airport != name {
+				continue
+			}
+			arrivalRunways = append(arrivalRunways, rwy.Runway)
+			if ap := s.World.Airports[name]; ap != nil {
+				for _, id := range SortedMapKeys(ap.Approaches) {
+					if appr := ap.Approaches[id]; appr.Runway == rwy.Runway &&
+						s.World.Weather.ApproachAvailable(appr.Type) {
+						approaches = append(approaches, appr.FullName)
+					}
+				}
+			}
+		}
+
+		metar := s.World.METAR[name]
+		if cur, ok := s.ATIS[name]; ok &&
+			cur.Contents == MakeATISContents(name, cur.Code, cur.Issued, metar, approaches, arrivalRunways, departureRunways) {
+			continue
+		}
+
+		code := string(rune('A' + rand.Intn(26)))
+		if cur, ok := s.ATIS[name]; ok {
+			code = NextATISCode(cur.Code)
+		}
+		s.ATIS[name] = ATIS{
+			Airport:  name,
+			Code:     code,
+			Issued:   s.SimTime,
+			Contents: MakeATISContents(name, code, s.SimTime, metar, approaches, arrivalRunways, departureRunways),
+		}
+		s.lg.Info("new ATIS", slog.String("airport", name), slog.String("code", code))
+	}
+}
+
+func (s *Sim) GetATIS(token string, atis *map[string]ATIS) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	if _, ok := s.controllers[token]; !ok {
+		return ErrInvalidControllerToken
+	}
+
+	s.updateATIS()
+	*atis = DuplicateMap(s.ATIS)
+	return nil
+}
+
 func (s *Sim) Activate(lg *Logger) {
 	if s.Name == "" {
 		s.lg = lg
@@ -1465,6 +2295,10 @@ func (s *Sim) Update() {
 		}
 	}
 
+	if s.SpectatorDelay > 0 {
+		s.updateSpectators()
+	}
+
 	if s.Paused {
 		return
 	}
@@ -1492,6 +2326,8 @@ func (s *Sim) Update() {
 	s.updateTimeSlop = elapsed - elapsed.Truncate(time.Second)
 	s.World.SimTime = s.SimTime
 
+	s.processDatalinkUplinks()
+
 	s.lastUpdateTime = time.Now()
 
 	// Log the current state of everything once a minute
@@ -1555,8 +2391,22 @@ func (s *Sim) updateState() {
 	// Update the simulation state once a second.
 	if now.Sub(s.lastSimUpdate) >= time.Second {
 		s.lastSimUpdate = now
+		s.updateATIS()
+		s.requestWeatherDeviation()
+		s.triggerRandomEmergency()
+		s.updateSystemFailures()
+		s.updateRadarOutages()
+		s.updatePIREPs()
+		s.updateRandomEvents()
+		s.checkSeparation()
+
 		for callsign, ac := range s.World.Aircraft {
+			goingAround := ac.GoAroundDistance != nil
 			passedWaypoint := ac.Update(s.World, s, s.lg)
+			ac.updateETAs(s.World, now)
+			if goingAround && ac.GoAroundDistance == nil && s.controllerIsSignedIn(ac.ApproachController) {
+				s.sessionStats(ac.ApproachController).GoArounds++
+			}
 			if passedWaypoint != nil && passedWaypoint.Handoff {
 				// Handoff from virtual controller to a human controller.
 				ctrl := s.ResolveController(ac.WaypointHandoffController)
@@ -1571,6 +2421,10 @@ func (s *Sim) updateState() {
 				ac.HandoffTrackController = ctrl
 			}
 
+			if ac.RequestingTransition {
+				s.requestTransition(ac)
+			}
+
 			// Contact the departure controller
 			if ac.IsDeparture() && ac.DepartureContactAltitude != 0 &&
 				ac.Nav.FlightState.Altitude >= ac.DepartureContactAltitude {
@@ -1584,11 +2438,11 @@ func (s *Sim) updateState() {
 				}
 
 				msg := "departing " + airportName + ", " + ac.Nav.DepartureMessage()
-				PostRadioEvents(ac.Callsign, []RadioTransmission{RadioTransmission{
+				PostRadioEvents(ac.Callsign, s.varyTransmissions([]RadioTransmission{RadioTransmission{
 					Controller: ctrl,
 					Message:    msg,
 					Type:       RadioTransmissionContact,
-				}}, s)
+				}}), s)
 
 				// Clear this out so we only send one contact message
 				ac.DepartureContactAltitude = 0
@@ -1601,7 +2455,12 @@ func (s *Sim) updateState() {
 			}
 
 			// Cull far-away departures/arrivals
-			if ac.IsDeparture() {
+			if ac.BackgroundTraffic {
+				if s.World.backgroundTrafficDone(ac) {
+					s.lg.Info("culled background traffic", slog.String("callsign", callsign))
+					delete(s.World.Aircraft, callsign)
+				}
+			} else if ac.IsDeparture() {
 				if ap := s.World.GetAirport(ac.FlightPlan.DepartureAirport); ap != nil &&
 					nmdistance2ll(ac.Position(), ap.Location) > 250 {
 					s.lg.Info("culled far-away departure", slog.String("callsign", callsign))
@@ -1618,6 +2477,22 @@ func (s *Sim) updateState() {
 		}
 	}
 
+	// Scheduled launches happen regardless of the launch mode.
+	s.ScheduledLaunches = FilterSlice(s.ScheduledLaunches, func(l ScheduledLaunch) bool {
+		if now.After(l.Time) {
+			s.launchAircraftNoLock(l.Aircraft)
+			return false
+		}
+		return true
+	})
+
+	// Background traffic, VFRs at satellite airports, and helicopters
+	// aren't the user's to launch, so they're launched even when the
+	// user is launching aircraft manually.
+	s.spawnBackgroundTraffic()
+	s.spawnSatelliteTraffic()
+	s.spawnHelicopters()
+
 	// Don't spawn automatically if someone is spawning manually.
 	if s.LaunchConfig.Mode == LaunchAutomatic {
 		s.spawnAircraft()
@@ -1652,7 +2527,7 @@ func (s *Sim) IdleTime() time.Duration {
 
 func (s *Sim) controllerIsSignedIn(callsign string) bool {
 	for _, ctrl := range s.controllers {
-		if ctrl.Callsign == callsign {
+		if ctrl.Callsign == callsign && !ctrl.coach {
 			return true
 		}
 	}
@@ -1714,6 +2589,10 @@ func (s *Sim) setInitialSpawnTimes() {
 
 		s.NextDepartureSpawn[airport] = randomSpawn(rateSum)
 	}
+
+	s.NextBackgroundSpawn = initialBackgroundSpawnTimes(s.World.BackgroundTraffic, randomSpawn)
+	s.NextSatelliteSpawn = initialSatelliteSpawnTimes(s.World.SatelliteTraffic, randomSpawn)
+	s.NextHelicopterSpawn = initialHelicopterSpawnTimes(s.World.HelicopterRoutes, randomSpawn)
 }
 
 func sampleRateMap(rates map[string]int) (string, int) {
@@ -1819,8 +2698,12 @@ func (s *Sim) spawnAircraft() {
 			s.lg.Errorf("CreateDeparture error: %v", err)
 		} else {
 			s.lastDeparture[airport][runway][category] = dep
-			s.lg.Infof("%s/%s/%s: launch departure", airport, runway, category)
-			s.launchAircraftNoLock(*ac)
+			if s.LaunchConfig.RequireReleases && ac.FlightPlan.Rules == IFR {
+				s.requestRelease(ac, runway)
+			} else {
+				s.lg.Infof("%s/%s/%s: launch departure", airport, runway, category)
+				s.launchAircraftNoLock(*ac)
+			}
 			s.NextDepartureSpawn[airport] = now.Add(randomWait(rateSum, false))
 		}
 	}
@@ -1851,37 +2734,54 @@ func (s *Sim) SetLaunchConfig(token string, lc LaunchConfig) error {
 	} else if ctrl.Callsign != s.LaunchConfig.Controller {
 		return ErrNotLaunchController
 	} else {
-		// Update the next spawn time for any rates that changed.
-		for ap, rwyRates := range lc.DepartureRates {
-			newSum, oldSum := 0, 0
-			for rwy, categoryRates := range rwyRates {
-				for category, rate := range categoryRates {
-					newSum += rate
-					oldSum += s.LaunchConfig.DepartureRates[ap][rwy][category]
-				}
-			}
-			if newSum != oldSum {
-				s.lg.Infof("%s: departure rate changed %d -> %d", ap, oldSum, newSum)
-				s.NextDepartureSpawn[ap] = s.SimTime.Add(randomWait(newSum, false))
-			}
-		}
-		for group, groupRates := range lc.ArrivalGroupRates {
-			newSum, oldSum := 0, 0
-			for ap, rate := range groupRates {
+		s.updateLaunchConfig(lc)
+		return nil
+	}
+}
+
+// updateLaunchConfig switches to the given launch configuration, updating
+// the next spawn time for any rates that changed. s.mu must be held.
+func (s *Sim) updateLaunchConfig(lc LaunchConfig) {
+	for ap, rwyRates := range lc.DepartureRates {
+		newSum, oldSum := 0, 0
+		for rwy, categoryRates := range rwyRates {
+			for category, rate := range categoryRates {
 				newSum += rate
-				oldSum += s.LaunchConfig.ArrivalGroupRates[group][ap]
-			}
-			if newSum != oldSum {
-				pushActive := s.SimTime.Before(s.PushEnd)
-				s.lg.Infof("%s: arrival rate changed %d -> %d", group, oldSum, newSum)
-				s.NextArrivalSpawn[group] = s.SimTime.Add(randomWait(newSum, pushActive))
+				oldSum += s.LaunchConfig.DepartureRates[ap][rwy][category]
 			}
 
+			if s.lastDeparture[ap] == nil {
+				s.lastDeparture[ap] = make(map[string]map[string]*Departure)
+			}
+			if s.lastDeparture[ap][rwy] == nil {
+				s.lastDeparture[ap][rwy] = make(map[string]*Departure)
+			}
+		}
+		if newSum != oldSum {
+			s.lg.Infof("%s: departure rate changed %d -> %d", ap, oldSum, newSum)
+			s.NextDepartureSpawn[ap] = s.SimTime.Add(randomWait(newSum, false))
 		}
+	}
+	for ap := range s.NextDepartureSpawn {
+		if _, ok := lc.DepartureRates[ap]; !ok {
+			delete(s.NextDepartureSpawn, ap)
+		}
+	}
 
-		s.LaunchConfig = lc
-		return nil
+	for group, groupRates := range lc.ArrivalGroupRates {
+		newSum, oldSum := 0, 0
+		for ap, rate := range groupRates {
+			newSum += rate
+			oldSum += s.LaunchConfig.ArrivalGroupRates[group][ap]
+		}
+		if newSum != oldSum {
+			pushActive := s.SimTime.Before(s.PushEnd)
+			s.lg.Infof("%s: arrival rate changed %d -> %d", group, oldSum, newSum)
+			s.NextArrivalSpawn[group] = s.SimTime.Add(randomWait(newSum, pushActive))
+		}
 	}
+
+	s.LaunchConfig = lc
 }
 
 func (s *Sim) TakeOrReturnLaunchControl(token string) error {
@@ -1918,6 +2818,17 @@ func (s *Sim) LaunchAircraft(ac Aircraft) {
 	s.launchAircraftNoLock(ac)
 }
 
+func (s *Sim) ScheduleLaunches(launches []ScheduledLaunch) {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	for _, l := range launches {
+		l.Time = s.SimTime.Add(l.Delay)
+		s.ScheduledLaunches = append(s.ScheduledLaunches, l)
+	}
+	s.lg.Info("scheduled launches", slog.Int("count", len(launches)))
+}
+
 // Assumes the lock is already held (as is the case e.g. for automatic spawning...)
 func (s *Sim) launchAircraftNoLock(ac Aircraft) {
 	if _, ok := s.World.Aircraft[ac.Callsign]; ok {
@@ -1929,15 +2840,30 @@ func (s *Sim) launchAircraftNoLock(ac Aircraft) {
 
 	ac.Nav.Check(s.lg)
 
-	if ac.IsDeparture() {
+	if ac.BackgroundTraffic {
+		s.lg.Info("launched background traffic", slog.String("callsign", ac.Callsign), slog.Any("aircraft", ac))
+	} else if ac.Nav.Perf.Helicopter {
+		s.lg.Info("launched helicopter", slog.String("callsign", ac.Callsign), slog.Any("aircraft", ac))
+	} else if ac.FlightPlan.Rules == VFR {
+		s.lg.Info("launched VFR", slog.String("callsign", ac.Callsign), slog.Any("aircraft", ac))
+	} else if ac.IsDeparture() {
 		s.TotalDepartures++
 		s.lg.Info("launched departure", slog.String("callsign", ac.Callsign), slog.Any("aircraft", ac))
+		if ac.Nav.Taxi == nil && !ac.IsAirborne() {
+			// It starts out on the runway; otherwise the roll starts
+			// once it has taxied there.
+			ac.postDepartureRoll(s)
+		}
 	} else {
 		s.TotalArrivals++
 		s.lg.Info("launched arrival", slog.String("callsign", ac.Callsign), slog.Any("aircraft", ac))
 	}
 }
 
+// dispatchCommand runs cmd for the aircraft if the controller is allowed
+// to issue it. s.mu must be held. The unexported pilot commands below
+// (assignAltitude, directFix, etc.) are run this way by
+// runAircraftCommands, which holds s.mu for the entire command sequence.
 func (s *Sim) dispatchCommand(token string, callsign string,
 	check func(c *Controller, ac *Aircraft) error,
 	cmd func(*Controller, *Aircraft) []RadioTransmission) error {
@@ -1946,9 +2872,12 @@ func (s *Sim) dispatchCommand(token string, callsign string,
 	} else if ac, ok := s.World.Aircraft[callsign]; !ok {
 		return ErrNoAircraftForCallsign
 	} else {
-		if sc.Callsign == "Observer" {
+		if sc.Callsign == "Observer" || sc.Callsign == "Spectator" {
 			return ErrOtherControllerHasTrack
 		}
+		if !s.hasCommandAuthority(sc) {
+			return Select(sc.coach, ErrOtherControllerHasTrack, ErrCoachHasControl)
+		}
 
 		ctrl := s.World.GetControllerByCallsign(sc.Callsign)
 		if ctrl == nil {
@@ -1965,7 +2894,16 @@ func (s *Sim) dispatchCommand(token string, callsign string,
 			s.lg.Info("dispatch_command", slog.String("callsign", ac.Callsign),
 				slog.Any("prepost_aircraft", []Aircraft{preAc, *ac}),
 				slog.Any("radio_transmissions", radioTransmissions))
-			PostRadioEvents(ac.Callsign, radioTransmissions, s)
+			if !s.datalinkExecuting[ac.Callsign] {
+				// Datalink clearances are acknowledged in writing, not
+				// read back over the radio.
+				PostRadioEvents(ac.Callsign, s.varyTransmissions(radioTransmissions), s)
+			}
+			if len(radioTransmissions) > 0 {
+				// The pilot is hearing us again; presumably anything
+				// that was missed has been reissued.
+				ac.UnacknowledgedInstructions = nil
+			}
 			return nil
 		}
 	}
@@ -2002,6 +2940,10 @@ func (s *Sim) GlobalMessage(global GlobalMessageArgs) error {
 	s.mu.Lock(s.lg)
 	defer s.mu.Unlock(s.lg)
 
+	if HasSystemFailure(s.SystemFailures, FailureLandlines, global.FromController) {
+		return ErrLandlinesFailure
+	}
+
 	s.eventStream.Post(Event{
 		Type:           GlobalMessageEvent,
 		Message:        global.Message,
@@ -2033,10 +2975,43 @@ func (s *Sim) SetSecondaryScratchpad(token, callsign, scratchpad string) error {
 		})
 }
 
-func (s *Sim) Ident(token, callsign string) error {
+func (s *Sim) AnnotateFlightStrip(token, callsign string, annotations [9]string) error {
 	s.mu.Lock(s.lg)
 	defer s.mu.Unlock(s.lg)
 
+	return s.dispatchCommand(token, callsign,
+		func(ctrl *Controller, ac *Aircraft) error { return nil },
+		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			ac.Strip.Annotations = annotations
+			return nil
+		})
+}
+
+func (s *Sim) PushFlightStrip(token, callsign, toController string) error {
+	s.mu.Lock(s.lg)
+	defer s.mu.Unlock(s.lg)
+
+	return s.dispatchCommand(token, callsign,
+		func(ctrl *Controller, ac *Aircraft) error {
+			if to := s.World.GetControllerByCallsign(toController); to == nil || !to.IsHuman {
+				return ErrNoController
+			} else if to.Callsign == ctrl.Callsign {
+				return ErrInvalidController
+			}
+			return nil
+		},
+		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			s.eventStream.Post(Event{
+				Type:           PushedFlightStripEvent,
+				Callsign:       ac.Callsign,
+				FromController: ctrl.Callsign,
+				ToController:   toController,
+			})
+			return nil
+		})
+}
+
+func (s *Sim) ident(token, callsign string) error {
 	return s.dispatchCommand(token, callsign,
 		func(c *Controller, ac *Aircraft) error {
 			// Can't ask for ident if they're on someone else's frequency.
@@ -2097,6 +3072,7 @@ func (s *Sim) InitiateTrack(token, callsign string) error {
 		},
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
 			ac.TrackingController = ctrl.Callsign
+			s.startedTracking(ctrl.Callsign, ac)
 			if ac.DepartureContactAltitude == 0 {
 				// If they have already contacted departure, then
 				// initiating track gives control as well; otherwise
@@ -2144,6 +3120,8 @@ func (s *Sim) HandoffTrack(token, callsign, controller string) error {
 			} else if octrl.Callsign == ctrl.Callsign {
 				// Can't handoff to ourself
 				return ErrInvalidController
+			} else if HasSystemFailure(s.SystemFailures, FailureHandoffAutomation, ctrl.Callsign, octrl.Callsign) {
+				return ErrAutomationFailure
 			}
 			return nil
 		},
@@ -2158,6 +3136,7 @@ func (s *Sim) HandoffTrack(token, callsign, controller string) error {
 			})
 
 			ac.HandoffTrackController = octrl.Callsign
+			s.sessionStats(ctrl.Callsign).HandoffsOffered++
 
 			// Add them to the auto-accept map even if the target is
 			// covered; this way, if they sign off in the interim, we still
@@ -2168,10 +3147,7 @@ func (s *Sim) HandoffTrack(token, callsign, controller string) error {
 		})
 }
 
-func (s *Sim) HandoffControl(token, callsign string) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
-
+func (s *Sim) handoffControl(token, callsign string) error {
 	return s.dispatchCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) error {
 			if ac.ControllingController != ctrl.Callsign {
@@ -2234,6 +3210,8 @@ func (s *Sim) AcceptHandoff(token, callsign string) error {
 		func(ctrl *Controller, ac *Aircraft) error {
 			if ac.HandoffTrackController != ctrl.Callsign {
 				return ErrNotBeingHandedOffToMe
+			} else if HasSystemFailure(s.SystemFailures, FailureHandoffAutomation, ctrl.Callsign) {
+				return ErrAutomationFailure
 			}
 			return nil
 		},
@@ -2247,6 +3225,8 @@ func (s *Sim) AcceptHandoff(token, callsign string) error {
 
 			ac.HandoffTrackController = ""
 			ac.TrackingController = ctrl.Callsign
+			s.sessionStats(ctrl.Callsign).HandoffsAccepted++
+			s.startedTracking(ctrl.Callsign, ac)
 			if !s.controllerIsSignedIn(ac.ControllingController) {
 				// Take immediate control on handoffs from virtual
 				ac.ControllingController = ctrl.Callsign
@@ -2367,6 +3347,8 @@ func (s *Sim) PointOut(token, callsign, controller string) error {
 			} else if octrl.Callsign == ctrl.Callsign {
 				// Can't point out to ourself
 				return ErrInvalidController
+			} else if HasSystemFailure(s.SystemFailures, FailureHandoffAutomation, ctrl.Callsign, octrl.Callsign) {
+				return ErrAutomationFailure
 			}
 			return nil
 		},
@@ -2379,6 +3361,8 @@ func (s *Sim) PointOut(token, callsign, controller string) error {
 				Callsign:       ac.Callsign,
 			})
 
+			s.sessionStats(ctrl.Callsign).PointOuts++
+
 			// As with handoffs, always add it to the auto-accept list for now.
 			acceptDelay := 4 + rand.Intn(10)
 			if s.PointOuts[ac.Callsign] == nil {
@@ -2460,12 +3444,13 @@ func (s *Sim) ToggleSPCOverride(token, callsign, spc string) error {
 		})
 }
 
-func (s *Sim) AssignAltitude(token, callsign string, altitude int, afterSpeed bool) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
-
+func (s *Sim) assignAltitude(token, callsign string, altitude int, afterSpeed bool) error {
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			if p := s.unacceptablePIREP(ac, altitude); p != nil {
+				return ac.readbackUnexpected("unable %s, %s reported there",
+					FormatAltitude(float32(altitude)), p.Conditions())
+			}
 			return ac.AssignAltitude(altitude, afterSpeed)
 		})
 }
@@ -2491,10 +3476,7 @@ type HeadingArgs struct {
 	Turn            TurnMethod
 }
 
-func (s *Sim) AssignHeading(hdg *HeadingArgs) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
-
+func (s *Sim) assignHeading(hdg *HeadingArgs) error {
 	return s.dispatchControllingCommand(hdg.ControllerToken, hdg.Callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
 			if hdg.Present {
@@ -2509,174 +3491,178 @@ func (s *Sim) AssignHeading(hdg *HeadingArgs) error {
 		})
 }
 
-func (s *Sim) AssignSpeed(token, callsign string, speed int, afterAltitude bool) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
-
+func (s *Sim) assignSpeed(token, callsign string, speed int, afterAltitude bool) error {
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
 			return ac.AssignSpeed(speed, afterAltitude)
 		})
 }
 
-func (s *Sim) MaintainSlowestPractical(token, callsign string) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
-
+func (s *Sim) maintainSlowestPractical(token, callsign string) error {
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
 			return ac.MaintainSlowestPractical()
 		})
 }
 
-func (s *Sim) MaintainMaximumForward(token, callsign string) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
-
+func (s *Sim) maintainMaximumForward(token, callsign string) error {
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
 			return ac.MaintainMaximumForward()
 		})
 }
 
-func (s *Sim) SaySpeed(token, callsign string) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
-
+func (s *Sim) saySpeed(token, callsign string) error {
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
 			return ac.SaySpeed()
 		})
 }
 
-func (s *Sim) ExpediteDescent(token, callsign string) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
-
+func (s *Sim) expediteDescent(token, callsign string) error {
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
 			return ac.ExpediteDescent()
 		})
 }
 
-func (s *Sim) ExpediteClimb(token, callsign string) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
-
+func (s *Sim) expediteClimb(token, callsign string) error {
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
 			return ac.ExpediteClimb()
 		})
 }
 
-func (s *Sim) DirectFix(token, callsign, fix string) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
-
+func (s *Sim) directFix(token, callsign, fix string) error {
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
 			return ac.DirectFix(fix)
 		})
 }
 
-func (s *Sim) DepartFixDirect(token, callsign, fixa string, fixb string) error {
+func (s *Sim) resumeOwnNavigation(token, callsign string) error {
+	return s.dispatchControllingCommand(token, callsign,
+		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			return ac.ResumeOwnNavigation()
+		})
+}
+
+// AmendRoute clears the aircraft to fly the given route, which is
+// specified as a series of fixes separated by spaces.
+func (s *Sim) AmendRoute(token, callsign, route string) error {
 	s.mu.Lock(s.lg)
 	defer s.mu.Unlock(s.lg)
 
+	var waypoints []Waypoint
+	for _, fix := range strings.Fields(strings.ToUpper(route)) {
+		if loc, ok := s.World.Locate(fix); !ok {
+			return ErrUnknownFix
+		} else {
+			waypoints = append(waypoints, Waypoint{Fix: fix, Location: loc})
+		}
+	}
+	if len(waypoints) == 0 {
+		return ErrInvalidCommandSyntax
+	}
+
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
-			return ac.DepartFixDirect(fixa, fixb)
+			return ac.AmendRoute(waypoints)
 		})
 }
 
-func (s *Sim) DepartFixHeading(token, callsign, fix string, heading int) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
+func (s *Sim) hold(token, callsign string, hc HoldClearance) error {
+	loc, ok := s.World.Locate(hc.Fix)
+	if !ok {
+		return ErrUnknownFix
+	}
 
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
-			return ac.DepartFixHeading(fix, heading)
+			h, published := hc.Resolve(s.World.Holds, loc, ac.Position(), s.World.NmPerLongitude,
+				s.World.MagneticVariation)
+			return ac.Hold(h, published, hc.EFC)
 		})
 }
 
-func (s *Sim) CrossFixAt(token, callsign, fix string, ar *AltitudeRestriction, speed int) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
+func (s *Sim) departFixDirect(token, callsign, fixa string, fixb string) error {
+	return s.dispatchControllingCommand(token, callsign,
+		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			return ac.DepartFixDirect(fixa, fixb)
+		})
+}
 
+func (s *Sim) departFixHeading(token, callsign, fix string, heading int) error {
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
-			return ac.CrossFixAt(fix, ar, speed)
+			return ac.DepartFixHeading(fix, heading)
 		})
 }
 
-func (s *Sim) AtFixCleared(token, callsign, fix, approach string) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
+func (s *Sim) crossFixAt(token, callsign, fix string, ar *AltitudeRestriction, speed int) error {
+	return s.dispatchControllingCommand(token, callsign,
+		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			return ac.CrossFixAt(fix, ar, speed)
+		})
+}
 
+func (s *Sim) atFixCleared(token, callsign, fix, approach string) error {
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
 			return ac.AtFixCleared(fix, approach)
 		})
 }
 
-func (s *Sim) ExpectApproach(token, callsign, approach string) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
-
+func (s *Sim) expectApproach(token, callsign, approach string) error {
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
 			return ac.ExpectApproach(approach, s.World, s.lg)
 		})
 }
 
-func (s *Sim) ClearedApproach(token, callsign, approach string, straightIn bool) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
-
+func (s *Sim) clearedApproach(token, callsign, approach string, straightIn bool) error {
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			var rt []RadioTransmission
 			if straightIn {
-				return ac.ClearedStraightInApproach(approach, s.World)
+				rt = ac.ClearedStraightInApproach(approach, s.World)
 			} else {
-				return ac.ClearedApproach(approach, s.World)
+				rt = ac.ClearedApproach(approach, s.World)
 			}
+			s.checkClosedFinal(ctrl, ac)
+			return rt
 		})
 }
 
-func (s *Sim) InterceptLocalizer(token, callsign string) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
-
+func (s *Sim) interceptLocalizer(token, callsign string) error {
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
 			return ac.InterceptLocalizer(s.World)
 		})
 }
 
-func (s *Sim) CancelApproachClearance(token, callsign string) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
-
+func (s *Sim) cancelApproachClearance(token, callsign string) error {
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
 			return ac.CancelApproachClearance()
 		})
 }
 
-func (s *Sim) ClimbViaSID(token, callsign string) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
-
+func (s *Sim) climbViaSID(token, callsign string) error {
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
 			return ac.ClimbViaSID()
 		})
 }
 
-func (s *Sim) DescendViaSTAR(token, callsign string) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
+func (s *Sim) clearedTECRoute(token, callsign string) error {
+	return s.dispatchControllingCommand(token, callsign,
+		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			return ac.ClearedTECRoute(s.World)
+		})
+}
 
+func (s *Sim) descendViaSTAR(token, callsign string) error {
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
 			return ac.DescendViaSTAR()
@@ -2689,6 +3675,7 @@ func (s *Sim) GoAround(token, callsign string) error {
 
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			s.sessionStats(ctrl.Callsign).GoArounds++
 			resp := ac.GoAround()
 			for i := range resp {
 				// Upgrade to unexpected versus it just being controller initiated.
@@ -2698,13 +3685,56 @@ func (s *Sim) GoAround(token, callsign string) error {
 		})
 }
 
-func (s *Sim) ContactTower(token, callsign string) error {
-	s.mu.Lock(s.lg)
-	defer s.mu.Unlock(s.lg)
+func (s *Sim) contactTower(token, callsign string) error {
+	return s.dispatchControllingCommand(token, callsign,
+		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			rt := ac.ContactTower(s.World)
+			if ac.GotContactTower {
+				s.arrivalHandedToTower(ac)
+			}
+			return rt
+		})
+}
+
+func (s *Sim) taxi(token, callsign, destination string, via []string) error {
+	return s.dispatchControllingCommand(token, callsign,
+		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			return ac.Taxi(s.World, destination, via)
+		})
+}
+
+func (s *Sim) crossRunway(token, callsign, runway string) error {
+	return s.dispatchControllingCommand(token, callsign,
+		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			return ac.CrossRunway(runway)
+		})
+}
+
+func (s *Sim) holdPosition(token, callsign string) error {
+	return s.dispatchControllingCommand(token, callsign,
+		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			return ac.HoldPosition()
+		})
+}
+
+func (s *Sim) continueTaxi(token, callsign string) error {
+	return s.dispatchControllingCommand(token, callsign,
+		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			return ac.ContinueTaxi()
+		})
+}
+
+func (s *Sim) lineUpAndWait(token, callsign string) error {
+	return s.dispatchControllingCommand(token, callsign,
+		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
+			return ac.LineUpAndWait()
+		})
+}
 
+func (s *Sim) clearedForTakeoff(token, callsign string) error {
 	return s.dispatchControllingCommand(token, callsign,
 		func(ctrl *Controller, ac *Aircraft) []RadioTransmission {
-			return ac.ContactTower(s.World)
+			return ac.ClearedForTakeoff()
 		})
 }
 
@@ -0,0 +1,305 @@
+// speech.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+)
+
+var (
+	ErrNoSpeechBackend = errors.New("No text-to-speech program found")
+	ErrInvalidWAV      = errors.New("Invalid or unsupported WAV file")
+)
+
+// Pilot transmissions are synthesized by running the platform's
+// text-to-speech program (say on macOS, espeak-ng or espeak on Linux, and
+// System.Speech via PowerShell on Windows) to generate a WAV file, which
+// is then band-limited to sound like it came over the radio and mixed in
+// with the sound effects by the audio callback.
+
+// speechDuckGain is the gain applied to the sound effects while a
+// transmission is being played so that the pilot can be heard over them.
+const speechDuckGain = 0.25
+
+// SpeechVoice describes how a particular aircraft's pilot sounds.
+type SpeechVoice struct {
+	Female  bool
+	Variant int     // backend-specific voice variant, [0,4)
+	Pitch   float32 // [0,1]
+	Rate    float32 // relative to the backend's default speaking rate
+	// Accent is zero for the backend's default voice and otherwise one
+	// more than an index into its list of accented voices.
+	Accent int
+}
+
+// Accented English voices: the voice names for say (British, Scottish,
+// Australian, Irish, Indian, and South African) and the language variants
+// for espeak (British, Scottish, Lancastrian, received pronunciation, and
+// Caribbean). Windows's voices vary by installation, so accents aren't
+// used with it.
+var (
+	sayAccents    = []string{"Daniel", "Fiona", "Karen", "Moira", "Rishi", "Tessa"}
+	espeakAccents = []string{"en-gb", "en-gb-scotland", "en-gb-x-gbclan", "en-gb-x-rp", "en-029"}
+)
+
+// voiceForCallsign returns the voice used for the given callsign; it is
+// derived from a hash of the callsign so that the same aircraft always
+// sounds the same. Depending on the realism level, some pilots have
+// accents.
+func voiceForCallsign(callsign string, realism PilotRealism) SpeechVoice {
+	h := fnv.New32a()
+	h.Write([]byte(callsign))
+	v := h.Sum32()
+
+	voice := SpeechVoice{
+		Female:  v&0x7 == 0, // 1 in 8
+		Variant: int(v>>3) & 0x3,
+		Pitch:   float32((v>>5)&0xff) / 255,
+		Rate:    1 + 0.25*float32((v>>13)&0xff)/255,
+	}
+	if float32((v>>21)&0xff)/256 < pilotRealismParameters[realism].Accent {
+		voice.Accent = 1 + int(v>>29)
+	}
+	return voice
+}
+
+type speechRequest struct {
+	callsign string
+	text     string
+	realism  PilotRealism
+}
+
+// speechBackend returns the name of the text-to-speech program to use on
+// this system or an empty string if none is available.
+func speechBackend() string {
+	var candidates []string
+	switch runtime.GOOS {
+	case "darwin":
+		candidates = []string{"say"}
+	case "windows":
+		candidates = []string{"powershell"}
+	default:
+		candidates = []string{"espeak-ng", "espeak"}
+	}
+	for _, c := range candidates {
+		if _, err := exec.LookPath(c); err == nil {
+			return c
+		}
+	}
+	return ""
+}
+
+// synthesizeSpeech runs the given text-to-speech program to speak the
+// text with the given voice and returns the resulting audio as 16-bit
+// samples at AudioSampleRate.
+func synthesizeSpeech(backend string, text string, voice SpeechVoice) ([]int16, error) {
+	if backend == "" {
+		return nil, ErrNoSpeechBackend
+	}
+
+	dir, err := os.MkdirTemp("", "vice-speech")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "speech.wav")
+
+	var cmd *exec.Cmd
+	switch backend {
+	case "say":
+		// say's embedded pbas command sets the baseline pitch.
+		pbas := Select(voice.Female, 50, 30) + int(voice.Pitch*15)
+		rate := int(200 * voice.Rate)
+		args := []string{"-r", strconv.Itoa(rate), "-o", fn, "--file-format=WAVE",
+			"--data-format=LEI16@" + strconv.Itoa(AudioSampleRate)}
+		if voice.Accent > 0 {
+			args = append(args, "-v", sayAccents[(voice.Accent-1)%len(sayAccents)])
+		}
+		cmd = exec.Command("say", append(args, fmt.Sprintf("[[pbas %d]] %s", pbas, text))...)
+
+	case "espeak-ng", "espeak":
+		lang := "en-us"
+		if voice.Accent > 0 {
+			lang = espeakAccents[(voice.Accent-1)%len(espeakAccents)]
+		}
+		variant := fmt.Sprintf("%s+%s%d", lang, Select(voice.Female, "f", "m"), 1+voice.Variant)
+		pitch := int(25 + voice.Pitch*40)
+		rate := int(175 * voice.Rate)
+		cmd = exec.Command(backend, "-v", variant, "-p", strconv.Itoa(pitch), "-s", strconv.Itoa(rate),
+			"-w", fn, text)
+
+	case "powershell":
+		// The text and filename are passed via the environment to avoid
+		// having to quote them for PowerShell.
+		gender := Select(voice.Female, "Female", "Male")
+		rate := int(10 * (voice.Rate - 1))
+		script := "Add-Type -AssemblyName System.Speech; " +
+			"$s = New-Object System.Speech.Synthesis.SpeechSynthesizer; " +
+			"$s.SelectVoiceByHints([System.Speech.Synthesis.VoiceGender]::" + gender + "); " +
+			"$s.Rate = " + strconv.Itoa(rate) + "; " +
+			"$s.SetOutputToWaveFile($env:VICE_SPEECH_FILE); " +
+			"$s.Speak($env:VICE_SPEECH_TEXT); $s.Dispose()"
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+		cmd.Env = append(os.Environ(), "VICE_SPEECH_FILE="+fn, "VICE_SPEECH_TEXT="+text)
+
+	default:
+		return nil, fmt.Errorf("%s: unknown text-to-speech program", backend)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%s: %v: %s", backend, err, string(out))
+	}
+
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	pcm, rate, err := decodeWAV(b)
+	if err != nil {
+		return nil, err
+	}
+	return radioFilter(resamplePCM(pcm, rate, AudioSampleRate), AudioSampleRate), nil
+}
+
+// decodeWAV decodes a 16-bit PCM WAV file, returning its samples (mixed
+// down to mono) and its sample rate.
+func decodeWAV(b []byte) ([]int16, int, error) {
+	if len(b) < 12 || string(b[:4]) != "RIFF" || string(b[8:12]) != "WAVE" {
+		return nil, 0, ErrInvalidWAV
+	}
+
+	var channels, rate int
+	for b = b[12:]; len(b) >= 8; {
+		id, sz := string(b[:4]), int(binary.LittleEndian.Uint32(b[4:8]))
+		b = b[8:]
+		if sz > len(b) {
+			// Some programs write a bogus size for the data chunk when
+			// they're writing to a stream; just take what's there.
+			sz = len(b)
+		}
+
+		switch id {
+		case "fmt ":
+			if sz < 16 {
+				return nil, 0, ErrInvalidWAV
+			}
+			format := binary.LittleEndian.Uint16(b[0:2])
+			channels = int(binary.LittleEndian.Uint16(b[2:4]))
+			rate = int(binary.LittleEndian.Uint32(b[4:8]))
+			bits := binary.LittleEndian.Uint16(b[14:16])
+			// 0xfffe is WAVE_FORMAT_EXTENSIBLE, which say uses.
+			if (format != 1 && format != 0xfffe) || bits != 16 || channels < 1 || rate == 0 {
+				return nil, 0, ErrInvalidWAV
+			}
+
+		case "data":
+			if channels == 0 {
+				return nil, 0, ErrInvalidWAV
+			}
+			n := sz / (2 * channels)
+			pcm := make([]int16, n)
+			for i := range pcm {
+				sum := 0
+				for c := 0; c < channels; c++ {
+					off := 2 * (i*channels + c)
+					sum += int(int16(binary.LittleEndian.Uint16(b[off : off+2])))
+				}
+				pcm[i] = int16(sum / channels)
+			}
+			return pcm, rate, nil
+		}
+
+		// Chunks are padded to an even number of bytes.
+		b = b[min(len(b), sz+sz&1):]
+	}
+
+	return nil, 0, ErrInvalidWAV
+}
+
+// resamplePCM converts the given samples from one sample rate to another
+// using linear interpolation.
+func resamplePCM(pcm []int16, from, to int) []int16 {
+	if from == to || len(pcm) == 0 {
+		return pcm
+	}
+
+	n := int(int64(len(pcm)) * int64(to) / int64(from))
+	out := make([]int16, n)
+	for i := range out {
+		t := float32(i) * float32(from) / float32(to)
+		i0 := min(int(t), len(pcm)-1)
+		i1 := min(i0+1, len(pcm)-1)
+		out[i] = int16(lerp(t-float32(i0), float32(pcm[i0]), float32(pcm[i1])))
+	}
+	return out
+}
+
+// radioFilter band-limits the given samples to roughly the 300Hz-3kHz
+// range of VHF voice radio and adds a little bit of clipping.
+func radioFilter(pcm []int16, rate int) []int16 {
+	// One-pole high- and low-pass filters
+	dt := 1 / float32(rate)
+	rcHigh, rcLow := 1/(2*3.14159*float32(300)), 1/(2*3.14159*float32(3000))
+	alphaHigh := rcHigh / (rcHigh + dt)
+	alphaLow := dt / (rcLow + dt)
+
+	out := make([]int16, len(pcm))
+	var prevIn, prevHigh, prevLow float32
+	for i, s := range pcm {
+		x := float32(s)
+		high := alphaHigh * (prevHigh + x - prevIn)
+		low := prevLow + alphaLow*(high-prevLow)
+		prevIn, prevHigh, prevLow = x, high, low
+
+		out[i] = int16(clamp(1.5*low, -24000, 24000))
+	}
+	return out
+}
+
+// speechWorker synthesizes the transmissions that are passed to Speak
+// and queues them for playback. It runs in its own goroutine since
+// running the text-to-speech program takes a while.
+func (a *AudioEngine) speechWorker(backend string) {
+	for req := range a.speechRequests {
+		pcm, err := synthesizeSpeech(backend, req.text, voiceForCallsign(req.callsign, req.realism))
+		if err != nil {
+			lg.Errorf("%s: unable to synthesize speech: %v", req.callsign, err)
+			continue
+		}
+
+		buf := make([]byte, 2*len(pcm))
+		for i, s := range pcm {
+			binary.LittleEndian.PutUint16(buf[2*i:], uint16(s))
+		}
+
+		a.mu.Lock()
+		a.speech = append(a.speech, buf)
+		a.mu.Unlock()
+	}
+}
+
+// Speak queues the given pilot transmission to be spoken. Transmissions
+// are played one at a time, as they would be on a single frequency.
+func (a *AudioEngine) Speak(callsign string, text string, realism PilotRealism) {
+	if !a.AudioEnabled || !a.SpeechEnabled || a.speechRequests == nil {
+		return
+	}
+
+	select {
+	case a.speechRequests <- speechRequest{callsign: callsign, text: text, realism: realism}:
+	default:
+		// Rather than getting further and further behind, drop
+		// transmissions if lots of them are backed up.
+		lg.Warnf("%s: dropping transmission \"%s\"; too many queued", callsign, text)
+	}
+}
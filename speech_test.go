@@ -0,0 +1,91 @@
+// speech_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func makeTestWAV(channels, rate int, samples []int16) []byte {
+	le := binary.LittleEndian
+	var b []byte
+	b = append(b, "RIFF"...)
+	b = le.AppendUint32(b, uint32(36+2*len(samples)))
+	b = append(b, "WAVE"...)
+	// An unrelated chunk with an odd size that should be skipped.
+	b = append(b, "LIST"...)
+	b = le.AppendUint32(b, 3)
+	b = append(b, 'a', 'b', 'c', 0)
+	b = append(b, "fmt "...)
+	b = le.AppendUint32(b, 16)
+	b = le.AppendUint16(b, 1)
+	b = le.AppendUint16(b, uint16(channels))
+	b = le.AppendUint32(b, uint32(rate))
+	b = le.AppendUint32(b, uint32(2*channels*rate))
+	b = le.AppendUint16(b, uint16(2*channels))
+	b = le.AppendUint16(b, 16)
+	b = append(b, "data"...)
+	b = le.AppendUint32(b, uint32(2*len(samples)))
+	for _, s := range samples {
+		b = le.AppendUint16(b, uint16(s))
+	}
+	return b
+}
+
+func TestDecodeWAV(t *testing.T) {
+	pcm, rate, err := decodeWAV(makeTestWAV(1, 22050, []int16{0, 100, -100, 32767}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rate != 22050 || len(pcm) != 4 || pcm[1] != 100 || pcm[2] != -100 || pcm[3] != 32767 {
+		t.Errorf("mono: got rate %d, samples %v", rate, pcm)
+	}
+
+	pcm, _, err = decodeWAV(makeTestWAV(2, 12000, []int16{100, 300, -1000, -2000}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pcm) != 2 || pcm[0] != 200 || pcm[1] != -1500 {
+		t.Errorf("stereo: expected [200 -1500], got %v", pcm)
+	}
+
+	for _, b := range [][]byte{nil, []byte("RIFF0000WAVX"), makeTestWAV(1, 12000, nil)[:40]} {
+		if _, _, err := decodeWAV(b); err == nil {
+			t.Errorf("expected error for invalid WAV")
+		}
+	}
+}
+
+func TestResamplePCM(t *testing.T) {
+	pcm := []int16{0, 100, 200, 300}
+	if r := resamplePCM(pcm, 12000, 12000); len(r) != 4 {
+		t.Errorf("expected samples to be unchanged, got %v", r)
+	}
+	if r := resamplePCM(pcm, 12000, 24000); len(r) != 8 || r[1] != 50 || r[2] != 100 || r[7] != 300 {
+		t.Errorf("upsampling: got %v", r)
+	}
+	if r := resamplePCM(pcm, 24000, 12000); len(r) != 2 || r[0] != 0 || r[1] != 200 {
+		t.Errorf("downsampling: got %v", r)
+	}
+}
+
+func TestVoiceForCallsign(t *testing.T) {
+	if voiceForCallsign("AAL123", PilotRealismTypical) != voiceForCallsign("AAL123", PilotRealismTypical) {
+		t.Errorf("expected the same voice for the same callsign")
+	}
+
+	voices := make(map[SpeechVoice]interface{})
+	for _, cs := range []string{"AAL123", "UAL456", "DAL789", "JBU12", "N123AB", "SWA3456"} {
+		v := voiceForCallsign(cs, PilotRealismStandard)
+		if v.Pitch < 0 || v.Pitch > 1 || v.Rate < 1 || v.Rate > 1.25 || v.Variant < 0 || v.Variant > 3 || v.Accent != 0 {
+			t.Errorf("%s: voice out of range: %+v", cs, v)
+		}
+		voices[v] = nil
+	}
+	if len(voices) < 5 {
+		t.Errorf("expected varied voices, got %d distinct", len(voices))
+	}
+}
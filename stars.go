@@ -9,7 +9,6 @@ package main
 
 import (
 	"fmt"
-	"runtime"
 	"slices"
 	"sort"
 	"strconv"
@@ -24,6 +23,10 @@ import (
 const LateralMinimum = 3
 const VerticalMinimum = 1000
 
+// Conflict alerts are issued for aircraft that are predicted to lose
+// separation within this many seconds.
+const CALookaheadSeconds = 90
+
 // STARS ∆ is character 0x80 in the font
 const STARSTriangleCharacter = string(rune(0x80))
 
@@ -48,6 +51,7 @@ var (
 	STARSInboundPointOutColor   = RGB{1, 1, 0}
 	STARSGhostColor             = RGB{1, 1, 0}
 	STARSSelectedAircraftColor  = RGB{0, 1, 1}
+	STARSCoachColor             = RGB{.8, .3, 1}
 
 	STARSATPAWarningColor = RGB{1, 1, 0}
 	STARSATPAAlertColor   = RGB{1, .215, 0}
@@ -58,6 +62,20 @@ var (
 	STARSDCBTextSelectedColor   = RGB{1, 1, 0}
 	STARSDCBDisabledButtonColor = RGB{.4, .4, .4}
 	STARSDCBDisabledTextColor   = RGB{.8, .8, .8}
+
+	// Colors used for tracks owned by other controllers when ownership
+	// coloring is enabled; these are chosen to be distinct from the
+	// standard tracked, untracked, pointout, and selected colors.
+	STARSOwnershipPalette = []RGB{
+		RGB{1, .6, .2},    // orange
+		RGB{1, .4, 1},     // magenta
+		RGB{.45, .65, 1},  // light blue
+		RGB{1, .6, .7},    // pink
+		RGB{.85, .75, .5}, // tan
+		RGB{.7, .55, 1},   // lavender
+		RGB{1, .45, .4},   // salmon
+		RGB{.6, .9, .6},   // pale green
+	}
 )
 
 const NumSTARSPreferenceSets = 32
@@ -72,6 +90,11 @@ type STARSPane struct {
 
 	weatherRadar WeatherRadar
 
+	// Georeferenced images drawn underneath everything else.
+	Underlays       []*RasterUnderlay
+	freedUnderlays  []*RasterUnderlay // removed in the UI; textures to be freed
+	newUnderlayPath string
+
 	systemFont        [6]*Font
 	systemOutlineFont [6]*Font
 	dcbFont           [3]*Font // 0, 1, 2 only
@@ -89,12 +112,25 @@ type STARSPane struct {
 	// backwards compatibility, since this used to be a
 	// map[string]interface{}.
 	AutoTrackDepartures bool `json:"autotrack_departures"`
+	AutoHandoff         bool `json:"auto_handoff"` // departures leaving our airspace
 	LockDisplay         bool
 	AirspaceAwareness   struct {
 		Interfacility bool
 		Intrafacility bool
 	}
 
+	// CleanScope hides the DCB, lists, and cursor, leaving just the
+	// radar picture (e.g., for streaming).
+	CleanScope bool
+
+	// RadarSweepPeriod, if non-zero, overrides the rotation period of
+	// the radar sites, in seconds; e.g., 12 for en-route radar.
+	RadarSweepPeriod float32
+
+	// DisplayDatalinkEquipage adds a "D" after the aircraft type in full
+	// datablocks for aircraft that can receive CPDLC uplinks.
+	DisplayDatalinkEquipage bool
+
 	// callsign -> controller id
 	InboundPointOuts  map[string]string
 	OutboundPointOuts map[string]string
@@ -102,8 +138,20 @@ type STARSPane struct {
 
 	queryUnassociated *TransientMap[string, interface{}]
 
+	airports []string // the scenario's departure and arrival airports, for the UI
+
 	RangeBearingLines []STARSRangeBearingLine
 	MinSepAircraft    [2]string
+	Tethers           []STARSTether
+
+	// Per-aircraft datablock overrides for managing clutter: suppressed
+	// aircraft are shown with the position symbol only and forced ones
+	// always have their datablock displayed, regardless of filters.
+	SuppressedDatablocks map[string]interface{}
+	ForcedDatablocks     map[string]interface{}
+
+	AltitudeFilterPresets []STARSAltitudeFilterPreset
+	currentFilterPreset   int // index of the last one applied, for cycling
 
 	CAAircraft []CAAircraft
 
@@ -120,6 +168,8 @@ type STARSPane struct {
 
 	commandMode       CommandMode
 	multiFuncPrefix   string
+	keymap            STARSKeymap
+	controlMap        *STARSControlMap
 	previewAreaOutput string
 	previewAreaInput  string
 
@@ -129,11 +179,31 @@ type STARSPane struct {
 	lastHistoryTrackUpdate time.Time
 	discardTracks          bool
 
+	// Automatic centering: an aircraft to keep the scope centered on, if
+	// any, and an in-progress animated transition to a new center and
+	// range.
+	followAircraft    string
+	centerTransition  *STARSCenterTransition
+	lastWeatherCenter Point2LL
+
+	// Extent of the overview inset in window coordinates, if it is
+	// displayed, for handling clicks in it.
+	overviewExtent Extent2D
+
+	// Time at which the most recent departure started its takeoff roll,
+	// indexed by departure airport and runway (e.g., "KJFK 31L").
+	departureRollTimes map[string]time.Time
+
 	drawApproachAirspace  bool
 	drawDepartureAirspace bool
 
 	// The start of a RBL--one click received, waiting for the second.
 	wipRBL *STARSRangeBearingLine
+
+	// When coaching, the aircraft we have highlighted and when we last
+	// sent our state to the server.
+	coachHighlights []string
+	lastCoachUpdate time.Time
 }
 
 type STARSRangeBearingLine struct {
@@ -165,6 +235,59 @@ func (rbl STARSRangeBearingLine) GetPoints(ctx *PaneContext, aircraft []*Aircraf
 	return
 }
 
+// STARSTether links two aircraft so that the distance between them and
+// their closure rate are continuously displayed until it is removed.
+type STARSTether struct {
+	Callsigns [2]string
+}
+
+// Returns the current distance between the two tethered aircraft in nm
+// and their closure rate in knots; the closure rate is positive if they
+// are converging.
+func (t STARSTether) Measure(sp *STARSPane, nmPerLongitude, magneticVariation float32) (dist, closure float32) {
+	s0, s1 := sp.Aircraft[t.Callsigns[0]], sp.Aircraft[t.Callsigns[1]]
+	p0, p1 := ll2nm(s0.TrackPosition(), nmPerLongitude), ll2nm(s1.TrackPosition(), nmPerLongitude)
+	dist = distance2f(p0, p1)
+	if dist == 0 || !s0.HaveHeading() || !s1.HaveHeading() {
+		return
+	}
+
+	// Heading vectors are per minute, so scale to get knots.
+	v0 := ll2nm(s0.HeadingVector(nmPerLongitude, magneticVariation), nmPerLongitude)
+	v1 := ll2nm(s1.HeadingVector(nmPerLongitude, magneticVariation), nmPerLongitude)
+	closure = 60 * RayRayClosureRate(p0, v0, p1, v1)
+	return
+}
+
+// STARSCenterTransition records the state of an animated change to the
+// scope's center and range.
+type STARSCenterTransition struct {
+	FromCenter, ToCenter Point2LL
+	FromRange, ToRange   float32
+	Start                time.Time
+}
+
+const STARSCenterTransitionDuration = 500 * time.Millisecond
+
+// STARSAltitudeFilterPreset is a named bundle of altitude filter limits
+// and displayed video maps that can be applied all at once.
+type STARSAltitudeFilterPreset struct {
+	Name         string
+	Unassociated [2]int // low, high
+	Associated   [2]int
+	VideoMaps    *[NumSTARSMaps]bool // nil -> leave the video maps unchanged
+}
+
+// defaultAltitudeFilterPresets returns the altitude filter bands that
+// are available before the user has saved any of their own.
+func defaultAltitudeFilterPresets() []STARSAltitudeFilterPreset {
+	return []STARSAltitudeFilterPreset{
+		STARSAltitudeFilterPreset{Name: "ALL", Unassociated: [2]int{-9900, 99900}, Associated: [2]int{-9900, 99900}},
+		STARSAltitudeFilterPreset{Name: "SFC-FL180", Unassociated: [2]int{-9900, 18000}, Associated: [2]int{-9900, 18000}},
+		STARSAltitudeFilterPreset{Name: "FL180+", Unassociated: [2]int{18000, 99900}, Associated: [2]int{18000, 99900}},
+	}
+}
+
 type CAAircraft struct {
 	Callsigns    [2]string // sorted alphabetically
 	Acknowledged bool
@@ -175,6 +298,7 @@ type QuickLookPosition struct {
 	Callsign string
 	Id       string
 	Plus     bool
+	Expires  time.Time // for timed quick looks; zero otherwise
 }
 
 func (sp *STARSPane) parseQuickLookPositions(ctx *PaneContext, s string) ([]QuickLookPosition, string, error) {
@@ -416,6 +540,10 @@ type STARSAircraftState struct {
 	historyTracks      [10]RadarTrack
 	historyTracksIndex int
 
+	// Time-based history trail, sampled according to the HistoryTrails
+	// preferences, oldest first.
+	historyTrail []RadarTrack
+
 	DatablockType            DatablockType
 	FullLDB                  time.Time // If the LDB displays the groundspeed. When to stop
 	DisplayRequestedAltitude *bool     // nil if unspecified
@@ -432,13 +560,13 @@ type STARSAircraftState struct {
 	ATPAStatus               ATPAStatus
 	MinimumMIT               float32
 	ATPALeadAircraftCallsign string
-	POFlashingEndTime        time.Time
 
 	// These are only set if a leader line direction was specified for this
 	// aircraft individually:
 	LeaderLineDirection       *CardinalOrdinalDirection
 	GlobalLeaderLineDirection *CardinalOrdinalDirection
 	UseGlobalLeaderLine       bool
+	LeaderLineLength          *int // nil -> use the preference set's
 
 	Ghost struct {
 		PartialDatablock bool
@@ -458,9 +586,14 @@ type STARSAircraftState struct {
 	MSAWAcknowledged bool
 	MSAWSoundEnd     time.Time
 
+	// Set to the fix if the aircraft can't meet a controller-assigned
+	// crossing restriction.
+	UnableCrossingFix string
+
 	FirstSeen           time.Time
 	FirstRadarTrack     time.Time
 	HaveEnteredAirspace bool
+	AutoHandoffOffered  bool // only try once, in case it's rejected
 
 	IdentStart, IdentEnd    time.Time
 	OutboundHandoffAccepted bool
@@ -506,6 +639,16 @@ func (s *STARSAircraftState) TrackDeltaAltitude() int {
 	return s.track.Altitude - s.previousTrack.Altitude
 }
 
+// TrackAltitudeRate returns the aircraft's vertical speed in feet per
+// second, based on its two most recent radar tracks.
+func (s *STARSAircraftState) TrackAltitudeRate() float32 {
+	dt := s.track.Time.Sub(s.previousTrack.Time).Seconds()
+	if s.previousTrack.Position.IsZero() || dt <= 0 {
+		return 0
+	}
+	return float32(s.TrackDeltaAltitude()) / float32(dt)
+}
+
 func (s *STARSAircraftState) TrackPosition() Point2LL {
 	return s.track.Position
 }
@@ -543,10 +686,24 @@ func (s *STARSAircraftState) TrackHeading(nmPerLongitude float32) float32 {
 	return headingp2ll(s.previousTrack.Position, s.track.Position, nmPerLongitude, 0)
 }
 
+// Tracks that haven't been updated for trackCoastTime coast: they stay at
+// their last position with "CST" in their datablock. If there's still no
+// update after trackDropTime, the track is dropped from the display.
+const (
+	trackCoastTime = 15 * time.Second
+	trackDropTime  = 30 * time.Second
+)
+
 func (s *STARSAircraftState) LostTrack(now time.Time) bool {
 	// Only return true if we have at least one valid track from the past
 	// but haven't heard from the aircraft recently.
-	return !s.track.Position.IsZero() && now.Sub(s.track.Time) > 30*time.Second
+	return !s.track.Position.IsZero() && now.Sub(s.track.Time) > trackDropTime
+}
+
+// Coasting indicates whether the track is coasting but hasn't yet been
+// dropped.
+func (s *STARSAircraftState) Coasting(now time.Time) bool {
+	return !s.track.Position.IsZero() && now.Sub(s.track.Time) > trackCoastTime && !s.LostTrack(now)
 }
 
 func (s *STARSAircraftState) Ident() bool {
@@ -592,13 +749,31 @@ type STARSPreferenceSet struct {
 	// keyboard input.
 	RadarTrackHistoryRate float32
 
+	// Alternatively, time-based history trails, where past positions are
+	// sampled at a fixed interval and fade out as they age.
+	HistoryTrails struct {
+		Enabled  bool
+		Interval float32 // seconds between samples
+		Length   float32 // minutes of history
+	}
+
 	DisplayWeatherLevel [6]bool
+	// If set, the weather display loops through the recent radar
+	// images rather than showing the most recent one.
+	AnimateWeather bool
+
+	// Holding patterns that aircraft are flying are always drawn;
+	// published holds are only drawn if this is set.
+	DisplayPublishedHolds bool
 
 	// If empty, then then MULTI or FUSED mode, depending on
 	// FusedRadarMode.  The custom JSON name is so we don't get errors
 	// parsing old configs, which stored this as an array...
 	RadarSiteSelected string `json:"RadarSiteSelectedName"`
 	FusedRadarMode    bool
+	// Only show beacon (secondary) returns; aircraft without an operating
+	// transponder aren't displayed.
+	BeaconOnly bool
 
 	// For tracked by the user
 	LeaderLineDirection CardinalOrdinalDirection
@@ -618,6 +793,8 @@ type STARSPreferenceSet struct {
 	QuickLookAll       bool
 	QuickLookAllIsPlus bool
 	QuickLookPositions []QuickLookPosition
+	// Duration in seconds of quick looks entered with .QL
+	TimedQuickLookDuration int
 
 	CRDA struct {
 		Disabled bool
@@ -653,11 +830,40 @@ type STARSPreferenceSet struct {
 
 	DisplayRequestedAltitude bool
 
+	DatablockTemplate STARSDatablockTemplate
+	EnRouteDatablocks bool // ERAM-style full datablocks
+
 	DwellMode DwellMode
 
 	TopDownMode     bool
 	GroundRangeMode bool
 
+	// Airport diagrams are drawn when the range is at or below this
+	// value; zero disables them.
+	AirportDiagramRange int
+
+	// Inset in the corner of the scope that shows the entire facility
+	// with the current view and all traffic.
+	DisplayOverview bool
+
+	// When enabled, tracks owned by other controllers are drawn using a
+	// color associated with the owning position.
+	OwnershipColors struct {
+		Enabled bool
+		// Indices into STARSOwnershipPalette for controllers that have
+		// been explicitly assigned a color, indexed by controller
+		// callsign. Others are assigned colors automatically.
+		Assigned map[string]int
+		Legend   struct {
+			Position [2]float32
+			Visible  bool
+		}
+	}
+
+	// Per-airport filters and symbology for consolidated positions that
+	// work several airports' traffic on one scope, indexed by ICAO code.
+	AirportFilters map[string]STARSAirportFilter
+
 	Bookmarks [10]struct {
 		Center      Point2LL
 		Range       float32
@@ -699,6 +905,7 @@ type STARSPreferenceSet struct {
 		Visible  bool
 		Filter   struct {
 			All                 bool
+			Wx                  bool
 			Time                bool
 			Altimeter           bool
 			Status              bool
@@ -712,6 +919,7 @@ type STARSPreferenceSet struct {
 			QuickLookPositions  bool
 			DisabledTerminal    bool
 			ActiveCRDAPairs     bool
+			DepartureTimers     bool
 
 			Text struct {
 				Main bool
@@ -759,6 +967,15 @@ type STARSPreferenceSet struct {
 	}
 }
 
+// STARSAirportFilter controls how the traffic departing from or arriving
+// at an airport is displayed.
+type STARSAirportFilter struct {
+	Hidden bool
+	// If set, Symbol is used as the position symbol for the airport's
+	// untracked aircraft and follows the callsign in full datablocks.
+	Symbol string
+}
+
 type VideoMapsGroup int
 
 const (
@@ -832,6 +1049,10 @@ func (sp *STARSPane) MakePreferenceSet(name string, w *World) STARSPreferenceSet
 
 	ps.RadarTrackHistory = 5
 	ps.RadarTrackHistoryRate = 4.5
+	ps.HistoryTrails.Interval = 5
+	ps.HistoryTrails.Length = 2
+
+	ps.TimedQuickLookDuration = 30
 
 	ps.SystemMapVisible = make(map[int]interface{})
 
@@ -840,8 +1061,12 @@ func (sp *STARSPane) MakePreferenceSet(name string, w *World) STARSPreferenceSet
 	ps.LeaderLineDirection = North
 	ps.LeaderLineLength = 1
 
-	ps.AltitudeFilters.Unassociated = [2]int{100, 60000}
-	ps.AltitudeFilters.Associated = [2]int{100, 60000}
+	if w != nil {
+		ps.AltitudeFilters.Unassociated, ps.AltitudeFilters.Associated = w.GetInitialAltitudeFilters()
+	} else {
+		ps.AltitudeFilters.Unassociated = [2]int{100, 60000}
+		ps.AltitudeFilters.Associated = [2]int{100, 60000}
+	}
 
 	//ps.DisplayUncorrelatedTargets = true
 
@@ -850,6 +1075,8 @@ func (sp *STARSPane) MakePreferenceSet(name string, w *World) STARSPreferenceSet
 
 	ps.PTLLength = 1
 
+	ps.AirportDiagramRange = 6
+
 	ps.Brightness.DCB = 60
 	ps.Brightness.BackgroundContrast = 0
 	ps.Brightness.VideoGroupA = 50
@@ -908,6 +1135,9 @@ func (sp *STARSPane) MakePreferenceSet(name string, w *World) STARSPreferenceSet
 
 	ps.CRDAStatusList.Position = [2]float32{.05, .7}
 
+	ps.OwnershipColors.Legend.Position = [2]float32{.8, .5}
+	ps.OwnershipColors.Legend.Visible = true
+
 	ps.TowerLists[0].Position = [2]float32{.05, .5}
 	ps.TowerLists[0].Lines = 5
 	ps.TowerLists[0].Visible = true
@@ -928,6 +1158,8 @@ func (ps *STARSPreferenceSet) Duplicate() STARSPreferenceSet {
 	dupe.SelectedBeaconCodes = DuplicateSlice(ps.SelectedBeaconCodes)
 	dupe.CRDA.RunwayPairState = DuplicateSlice(ps.CRDA.RunwayPairState)
 	dupe.SystemMapVisible = DuplicateMap(ps.SystemMapVisible)
+	dupe.OwnershipColors.Assigned = DuplicateMap(ps.OwnershipColors.Assigned)
+	dupe.AirportFilters = DuplicateMap(ps.AirportFilters)
 	return dupe
 }
 
@@ -943,6 +1175,13 @@ func (ps *STARSPreferenceSet) Activate(w *World, sp *STARSPane) {
 	if ps.RadarTrackHistoryRate == 0 {
 		ps.RadarTrackHistoryRate = 4.5 // upgrade from old
 	}
+	if ps.HistoryTrails.Interval == 0 {
+		ps.HistoryTrails.Interval = 5
+		ps.HistoryTrails.Length = 2
+	}
+	if ps.TimedQuickLookDuration == 0 {
+		ps.TimedQuickLookDuration = 30
+	}
 
 	// Brightness goes in steps of 5 (similarly not enforced previously...)
 	remapBrightness := func(b *STARSBrightness) {
@@ -1061,6 +1300,69 @@ func (sp *STARSPane) flightPlanSTARS(w *World, ac *Aircraft) (string, error) {
 	return result, nil
 }
 
+// fixInfo returns a summary of the given fix, navaid, or airport for
+// display in the preview area: its type, location, frequency (if any),
+// the procedures at the scenario's airports that use it, and the bearing
+// and distance from the center of the scope.
+func (sp *STARSPane) fixInfo(w *World, name string) (string, error) {
+	p, ok := w.Locate(name)
+	if !ok {
+		return "", ErrSTARSIllegalFix
+	}
+
+	var lines []string
+	if nav, ok := database.Navaids[name]; ok {
+		lines = append(lines, nav.Id+" "+nav.Type+" "+nav.Name)
+		if nav.Frequency != 0 {
+			lines = append(lines, "FREQ "+nav.Frequency.String()+Select(nav.Type == "NDB", " KHZ", ""))
+		}
+	} else if ap, ok := database.Airports[name]; ok {
+		lines = append(lines, ap.Id+" AIRPORT "+ap.Name)
+		lines = append(lines, fmt.Sprintf("ELEV %d RWYS %s", ap.Elevation, strings.Join(
+			MapSlice(ap.Runways, func(r Runway) string { return r.Id }), " ")))
+		if wap, ok := w.Airports[name]; ok {
+			// Report the tower frequency, if the scenario has one.
+			for _, appr := range wap.Approaches {
+				if ctrl, ok := w.Controllers[appr.TowerController]; ok {
+					lines = append(lines, "TWR "+ctrl.Frequency.String())
+					break
+				}
+			}
+		}
+	} else {
+		lines = append(lines, name+" FIX")
+	}
+	lines = append(lines, p.DMSString())
+
+	var procs []string
+	for _, icao := range SortedMapKeys(w.Airports) {
+		if fap, ok := database.Airports[icao]; ok {
+			for _, id := range SortedMapKeys(fap.STARs) {
+				if fap.STARs[id].HasWaypoint(name) {
+					procs = append(procs, id)
+				}
+			}
+		}
+		ap := w.Airports[icao]
+		for _, id := range SortedMapKeys(ap.Approaches) {
+			if slices.ContainsFunc(ap.Approaches[id].Waypoints, func(wps WaypointArray) bool {
+				return slices.ContainsFunc(wps, func(wp Waypoint) bool { return wp.Fix == name })
+			}) {
+				procs = append(procs, icao+" "+id)
+			}
+		}
+	}
+	if len(procs) > 0 {
+		lines = append(lines, "PROC "+strings.Join(procs, " "))
+	}
+
+	ps := sp.CurrentPreferenceSet
+	hdg := headingp2ll(ps.CurrentCenter, p, w.NmPerLongitude, w.MagneticVariation)
+	lines = append(lines, fmt.Sprintf("%03d/%.1f FROM CTR", int(hdg+.5), nmdistance2ll(ps.CurrentCenter, p)))
+
+	return strings.ToUpper(strings.Join(lines, "\n")), nil
+}
+
 type STARSCommandStatus struct {
 	clear  bool
 	output string
@@ -1110,9 +1412,23 @@ func (sp *STARSPane) Activate(w *World, r Renderer, eventStream *EventStream) {
 	if sp.RejectedPointOuts == nil {
 		sp.RejectedPointOuts = make(map[string]interface{})
 	}
+	if sp.SuppressedDatablocks == nil {
+		sp.SuppressedDatablocks = make(map[string]interface{})
+	}
+	if sp.ForcedDatablocks == nil {
+		sp.ForcedDatablocks = make(map[string]interface{})
+	}
 	if sp.queryUnassociated == nil {
 		sp.queryUnassociated = NewTransientMap[string, interface{}]()
 	}
+	if sp.AltitudeFilterPresets == nil {
+		sp.AltitudeFilterPresets = defaultAltitudeFilterPresets()
+	}
+	sp.loadKeymap()
+	sp.loadControlMap()
+	if sp.departureRollTimes == nil {
+		sp.departureRollTimes = make(map[string]time.Time)
+	}
 
 	sp.initializeFonts()
 
@@ -1158,6 +1474,7 @@ func (sp *STARSPane) ResetWorld(w *World) {
 	ps.Range = w.GetInitialRange()
 	ps.CurrentCenter = ps.Center
 	ps.RangeRingsCenter = ps.Center
+	ps.AltitudeFilters.Unassociated, ps.AltitudeFilters.Associated = w.GetInitialAltitudeFilters()
 
 	videoMaps, defaultVideoMaps := w.GetVideoMaps()
 	clear(ps.DisplayVideoMap[:])
@@ -1185,10 +1502,9 @@ func (sp *STARSPane) ResetWorld(w *World) {
 		for idx, pair := range ap.ConvergingRunways {
 			sp.ConvergingRunways = append(sp.ConvergingRunways, STARSConvergingRunways{
 				ConvergingRunways: pair,
-				ApproachRegions: [2]*ApproachRegion{ap.ApproachRegions[pair.Runways[0]],
-					ap.ApproachRegions[pair.Runways[1]]},
-				Airport: name[1:], // drop the leading "K"
-				Index:   idx + 1,  // 1-based
+				ApproachRegions:   ap.ConvergingRunwayRegions(pair),
+				Airport:           name[1:], // drop the leading "K"
+				Index:             idx + 1,  // 1-based
 			})
 		}
 	}
@@ -1198,6 +1514,8 @@ func (sp *STARSPane) ResetWorld(w *World) {
 		sp.PreferenceSets[i].ResetCRDAState(sp.ConvergingRunways)
 	}
 
+	clear(sp.departureRollTimes)
+
 	sp.lastTrackUpdate = time.Time{} // force update
 }
 
@@ -1220,10 +1538,9 @@ func (sp *STARSPane) makeSystemMaps(w *World) map[int]*STARSMap {
 		Name:  "ALL MINIMUM VECTORING ALTITUDES",
 	}
 	ld := GetLinesDrawBuilder()
-	for _, mva := range database.MVAs[w.TRACON] {
+	for _, mva := range w.MinimumAltitudeAreas() {
 		ld.AddLineLoop(mva.ExteriorRing)
-		p := Extent2DFromPoints(mva.ExteriorRing).Center()
-		ld.AddNumber(p, 0.005, fmt.Sprintf("%d", mva.MinimumLimit/100))
+		ld.AddNumber(mva.LabelPosition(), 0.005, fmt.Sprintf("%d", mva.MinimumLimit/100))
 	}
 	ld.GenerateCommands(&mvas.CommandBuffer)
 	ReturnLinesDrawBuilder(ld)
@@ -1278,11 +1595,178 @@ func (sp *STARSPane) makeSystemMaps(w *World) map[int]*STARSMap {
 
 func (sp *STARSPane) DrawUI() {
 	imgui.Checkbox("Auto track departures", &sp.AutoTrackDepartures)
+	imgui.Checkbox("Automatically hand off departures leaving our airspace", &sp.AutoHandoff)
 	imgui.Checkbox("Lock display", &sp.LockDisplay)
+	imgui.Checkbox("Clean scope (hide DCB, lists, and cursor)", &sp.CleanScope)
+	imgui.Checkbox("Show datalink (CPDLC) equipage in datablocks", &sp.DisplayDatalinkEquipage)
+	imgui.SliderFloatV("Radar sweep period (seconds, 0 for site default)", &sp.RadarSweepPeriod, 0, 12, "%.1f", 0)
+	imgui.Text(fmt.Sprintf("Function key bindings: %s (%d keys remapped)", starsKeymapPath(), len(sp.keymap)))
+	imgui.SameLine()
+	if imgui.Button("Reload") {
+		sp.loadKeymap()
+	}
+	sp.drawControlMapUI()
+
+	ps := &sp.CurrentPreferenceSet
+	if len(sp.AltitudeFilterPresets) > 0 {
+		// Also available via ".FILTER name" and F12 / shift-F12.
+		current := sp.AltitudeFilterPresets[clamp(sp.currentFilterPreset, 0, len(sp.AltitudeFilterPresets)-1)]
+		if imgui.BeginCombo("Altitude filter preset", current.Name) {
+			for i, preset := range sp.AltitudeFilterPresets {
+				if imgui.SelectableV(preset.Name, i == sp.currentFilterPreset, 0, imgui.Vec2{}) {
+					sp.currentFilterPreset = i
+					sp.applyAltitudeFilterPreset(preset)
+				}
+			}
+			imgui.EndCombo()
+		}
+	}
+	imgui.Checkbox("Beacon-only radar display (hide primary returns)", &ps.BeaconOnly)
+	imgui.Checkbox("Animate weather radar (loop the recent images)", &ps.AnimateWeather)
+	imgui.Checkbox("Show published holding patterns", &ps.DisplayPublishedHolds)
+
+	if imgui.CollapsingHeader("Full datablock format") {
+		imgui.Checkbox("En-route (ERAM-style) full datablocks", &ps.EnRouteDatablocks)
+		uiStartDisable(ps.EnRouteDatablocks)
+		ps.DatablockTemplate.DrawUI()
+		uiEndDisable(ps.EnRouteDatablocks)
+	}
+
+	if imgui.CollapsingHeader("Brightness") {
+		// These are the brightness settings from the DCB's BRITE menu,
+		// with the same limits; the scope already scales each category's
+		// colors by them.
+		slider := func(label string, b *STARSBrightness, min STARSBrightness, allowOff bool) {
+			v := int32(*b)
+			if imgui.SliderIntV(label, &v, 0, 100, "%d%%", 0) {
+				if STARSBrightness(v) < min {
+					v = int32(Select(allowOff, 0, min))
+				}
+				*b = STARSBrightness(v)
+			}
+		}
+		br := &ps.Brightness
+		slider("Maps (group A)", &br.VideoGroupA, 5, false)
+		slider("Maps (group B)", &br.VideoGroupB, 5, false)
+		slider("Weather", &br.Weather, 5, true)
+		slider("Weather contrast", &br.WxContrast, 5, false)
+		slider("Position symbols", &br.Positions, 5, true)
+		slider("Other tracks", &br.OtherTracks, 5, true)
+		slider("Beacon symbols", &br.BeaconSymbols, 5, true)
+		slider("Primary symbols", &br.PrimarySymbols, 5, true)
+		slider("Full datablocks", &br.FullDatablocks, 5, true)
+		slider("Limited datablocks", &br.LimitedDatablocks, 5, true)
+		slider("History trails", &br.History, 5, true)
+		slider("Compass", &br.Compass, 5, true)
+		slider("Range rings", &br.RangeRings, 5, true)
+		slider("Lines", &br.Lines, 5, true)
+		slider("Lists", &br.Lists, 25, false)
+		slider("DCB", &br.DCB, 25, false)
+	}
+
+	trails := &sp.CurrentPreferenceSet.HistoryTrails
+	qlDuration := int32(sp.CurrentPreferenceSet.TimedQuickLookDuration)
+	if imgui.SliderInt("Timed quick look duration (seconds)", &qlDuration, 5, 300) {
+		sp.CurrentPreferenceSet.TimedQuickLookDuration = int(qlDuration)
+	}
+
+	imgui.Checkbox("Fading history trails", &trails.Enabled)
+	uiStartDisable(!trails.Enabled)
+	imgui.SliderFloatV("Trail sample interval (seconds)", &trails.Interval, 1, 30, "%.0f", 0)
+	imgui.SliderFloatV("Trail length (minutes)", &trails.Length, 0.5, 10, "%.1f", 0)
+	uiEndDisable(!trails.Enabled)
+
+	if len(sp.airports) > 1 && imgui.CollapsingHeader("Airports") {
+		// For consolidated positions: hide airports' traffic or give it
+		// a distinct symbol.
+		if ps.AirportFilters == nil {
+			ps.AirportFilters = make(map[string]STARSAirportFilter)
+		}
+		flags := imgui.TableFlagsBordersV | imgui.TableFlagsBordersOuterH | imgui.TableFlagsRowBg | imgui.TableFlagsSizingStretchProp
+		if imgui.BeginTableV("airports", 3, flags, imgui.Vec2{}, 0) {
+			imgui.TableSetupColumn("Airport")
+			imgui.TableSetupColumn("Show")
+			imgui.TableSetupColumn("Symbol")
+			imgui.TableHeadersRow()
+			for _, icao := range sp.airports {
+				f := ps.AirportFilters[icao]
+				imgui.PushID(icao)
+				imgui.TableNextRow()
+				imgui.TableNextColumn()
+				imgui.Text(icao)
+				imgui.TableNextColumn()
+				show := !f.Hidden
+				if imgui.Checkbox("##show", &show) {
+					f.Hidden = !show
+				}
+				imgui.TableNextColumn()
+				imgui.InputTextV("##symbol", &f.Symbol, imgui.InputTextFlagsCharsUppercase|imgui.InputTextFlagsCallbackAlways,
+					func(cb imgui.InputTextCallbackData) int32 {
+						// Symbols are a single character.
+						if l := len(cb.Buffer()); l > 1 {
+							cb.DeleteBytes(1, l-1)
+						}
+						return 0
+					})
+				imgui.PopID()
+
+				if f == (STARSAirportFilter{}) {
+					delete(ps.AirportFilters, icao)
+				} else {
+					ps.AirportFilters[icao] = f
+				}
+			}
+			imgui.EndTable()
+		}
+	}
+
+	if imgui.CollapsingHeader("Underlays") {
+		for i := 0; i < len(sp.Underlays); i++ {
+			u := sp.Underlays[i]
+			imgui.PushID(fmt.Sprintf("underlay%d", i))
+
+			imgui.Checkbox(u.Filename, &u.Visible)
+			imgui.SliderFloatV("Brightness", &u.Brightness, 0, 1, "%.02f", 0)
+			if imgui.InputText("SW corner", &u.SW) {
+				u.Reload()
+			}
+			if imgui.InputText("NE corner", &u.NE) {
+				u.Reload()
+			}
+			if err := u.Error(); err != nil {
+				imgui.PushStyleColor(imgui.StyleColorText, imgui.Vec4{1, .5, .5, 1})
+				imgui.Text(err.Error())
+				imgui.PopStyleColor()
+			}
+			if imgui.Button("Remove") {
+				sp.freedUnderlays = append(sp.freedUnderlays, u)
+				sp.Underlays = append(sp.Underlays[:i], sp.Underlays[i+1:]...)
+				i--
+			}
+
+			imgui.PopID()
+			imgui.Separator()
+		}
+
+		imgui.InputText("Image file", &sp.newUnderlayPath)
+		imgui.SameLine()
+		if imgui.Button("Add") && sp.newUnderlayPath != "" {
+			sp.Underlays = append(sp.Underlays, &RasterUnderlay{
+				Filename:   sp.newUnderlayPath,
+				Visible:    true,
+				Brightness: 0.5,
+			})
+			sp.newUnderlayPath = ""
+		}
+	}
 }
 
 func (sp *STARSPane) CanTakeKeyboardFocus() bool { return true }
 
+// Hidden implements the PaneHider interface; the scope is hidden when the
+// non-radar display is being used in its place.
+func (sp *STARSPane) Hidden() bool { return nonRadarModeEnabled() }
+
 func (sp *STARSPane) processEvents(w *World) {
 	// First handle changes in world.Aircraft
 	for callsign, ac := range w.Aircraft {
@@ -1311,11 +1795,13 @@ func (sp *STARSPane) processEvents(w *World) {
 	for callsign := range sp.Aircraft {
 		if _, ok := w.Aircraft[callsign]; !ok {
 			delete(sp.Aircraft, callsign)
+			delete(sp.SuppressedDatablocks, callsign)
+			delete(sp.ForcedDatablocks, callsign)
 		}
 	}
 
 	// See if there are any MVA issues
-	mvas := database.MVAs[w.TRACON]
+	mvas := w.MinimumAltitudeAreas()
 	for callsign, ac := range w.Aircraft {
 		state := sp.Aircraft[callsign]
 		if !ac.MVAsApply() {
@@ -1339,6 +1825,15 @@ func (sp *STARSPane) processEvents(w *World) {
 		state.MSAW = warn
 	}
 
+	// Check that the crossing restrictions we have assigned can still be met
+	for callsign, ac := range w.Aircraft {
+		state := sp.Aircraft[callsign]
+		state.UnableCrossingFix = ""
+		if ac.TrackingController == w.Callsign {
+			state.UnableCrossingFix, _ = ac.Nav.UnableCrossingRestriction()
+		}
+	}
+
 	// Filter out any removed aircraft from the CA list
 	sp.CAAircraft = FilterSlice(sp.CAAircraft, func(ca CAAircraft) bool {
 		_, a := w.Aircraft[ca.Callsigns[0]]
@@ -1348,6 +1843,10 @@ func (sp *STARSPane) processEvents(w *World) {
 
 	for _, event := range sp.events.Get() {
 		switch event.Type {
+		case DepartureRollEvent:
+			// Restart the departure timer for the runway.
+			sp.departureRollTimes[event.Message] = w.CurrentTime()
+
 		case PointOutEvent:
 			if event.ToController == w.Callsign {
 				if ctrl := w.GetControllerByCallsign(event.FromController); ctrl != nil {
@@ -1356,6 +1855,9 @@ func (sp *STARSPane) processEvents(w *World) {
 					sp.InboundPointOuts[event.Callsign] = ""
 				}
 				sp.Aircraft[event.Callsign].DatablockType = FullDatablock
+				if sp.primaryScope() {
+					globalConfig.Audio.PlayOnce(AudioPointOut)
+				}
 			}
 			if event.FromController == w.Callsign {
 				if ctrl := w.GetControllerByCallsign(event.ToController); ctrl != nil {
@@ -1376,7 +1878,6 @@ func (sp *STARSPane) processEvents(w *World) {
 				if ctrl := w.GetControllerByCallsign(event.ToController); ctrl != nil && ctrl.SectorId == id {
 					delete(sp.InboundPointOuts, event.Callsign)
 					sp.Aircraft[event.Callsign].PointedOut = true
-					sp.Aircraft[event.Callsign].POFlashingEndTime = time.Now().Add(5 * time.Second)
 				}
 			}
 
@@ -1400,7 +1901,7 @@ func (sp *STARSPane) processEvents(w *World) {
 			}
 
 		case OfferedHandoffEvent:
-			if event.ToController == w.Callsign {
+			if event.ToController == w.Callsign && sp.primaryScope() {
 				globalConfig.Audio.PlayOnce(AudioInboundHandoff)
 			}
 
@@ -1409,7 +1910,9 @@ func (sp *STARSPane) processEvents(w *World) {
 				if state, ok := sp.Aircraft[event.Callsign]; !ok {
 					lg.Errorf("%s: have AcceptedHandoffEvent but missing STARS state?", event.Callsign)
 				} else {
-					globalConfig.Audio.PlayOnce(AudioHandoffAccepted)
+					if sp.primaryScope() {
+						globalConfig.Audio.PlayOnce(AudioHandoffAccepted)
+					}
 					state.OutboundHandoffAccepted = true
 					state.OutboundHandoffFlashEnd = time.Now().Add(10 * time.Second)
 				}
@@ -1420,7 +1923,9 @@ func (sp *STARSPane) processEvents(w *World) {
 				if state, ok := sp.Aircraft[event.Callsign]; !ok {
 					lg.Errorf("%s: have AcceptedRedirectedHandoffEvent but missing STARS state?", event.Callsign)
 				} else {
-					globalConfig.Audio.PlayOnce(AudioHandoffAccepted)
+					if sp.primaryScope() {
+						globalConfig.Audio.PlayOnce(AudioHandoffAccepted)
+					}
 					state.OutboundHandoffAccepted = true
 					state.OutboundHandoffFlashEnd = time.Now().Add(10 * time.Second)
 					state.RDIndicatorEnd = time.Now().Add(30 * time.Second)
@@ -1436,6 +1941,19 @@ func (sp *STARSPane) processEvents(w *World) {
 				state.IdentEnd = state.IdentStart.Add(10 * time.Second)
 			}
 
+		case SelectedAircraftEvent:
+			if state, ok := sp.Aircraft[event.Callsign]; ok {
+				state.IsSelected = event.Selected
+			}
+
+		case HighlightedAircraftEvent:
+			sp.coachHighlights = slices.DeleteFunc(sp.coachHighlights, func(cs string) bool { return cs == event.Callsign })
+			if event.Selected {
+				sp.coachHighlights = append(sp.coachHighlights, event.Callsign)
+			}
+			// Send the change with the next update.
+			sp.lastCoachUpdate = time.Time{}
+
 		case SetGlobalLeaderLineEvent:
 			if state, ok := sp.Aircraft[event.Callsign]; !ok {
 				lg.Errorf("%s: have SetGlobalLeaderLineEvent but missing STARS state?", event.Callsign)
@@ -1533,24 +2051,53 @@ func (sp *STARSPane) Upgrade(from, to int) {
 			update(&sp.PreferenceSets[i])
 		}
 	}
+	if from < 24 {
+		// Added airport diagrams
+		sp.CurrentPreferenceSet.AirportDiagramRange = 6
+		for i := range sp.PreferenceSets {
+			sp.PreferenceSets[i].AirportDiagramRange = 6
+		}
+	}
+	if from < 25 {
+		// Added ownership colors
+		update := func(ps *STARSPreferenceSet) {
+			ps.OwnershipColors.Legend.Position = [2]float32{.8, .5}
+			ps.OwnershipColors.Legend.Visible = true
+		}
+		update(&sp.CurrentPreferenceSet)
+		for i := range sp.PreferenceSets {
+			update(&sp.PreferenceSets[i])
+		}
+	}
 }
 
 func (sp *STARSPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	sp.airports = SortedMapKeys(ctx.world.DepartureAirports)
+	for icao := range ctx.world.ArrivalAirports {
+		if _, ok := ctx.world.DepartureAirports[icao]; !ok {
+			sp.airports = append(sp.airports, icao)
+		}
+	}
+	slices.Sort(sp.airports)
 	sp.processEvents(ctx.world)
 	sp.updateRadarTracks(ctx.world)
 
+	sp.updateCenter(ctx)
+	sp.expireQuickLooks()
+
 	ps := sp.CurrentPreferenceSet
 
 	// Clear to background color
 	cb.ClearRGB(ps.Brightness.BackgroundContrast.ScaleRGB(STARSBackgroundColor))
 
 	sp.processKeyboardInput(ctx)
+	sp.processControlMap(ctx)
 
 	transforms := GetScopeTransformations(ctx.paneExtent, ctx.world.MagneticVariation, ctx.world.NmPerLongitude,
 		ps.CurrentCenter, float32(ps.Range), 0)
 
 	paneExtent := ctx.paneExtent
-	if ps.DisplayDCB {
+	if ps.DisplayDCB && !sp.CleanScope {
 		paneExtent = sp.DrawDCB(ctx, transforms, cb)
 
 		// Update scissor for what's left and to protect the DCB (even
@@ -1570,8 +2117,16 @@ func (sp *STARSPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 
 	weatherBrightness := float32(ps.Brightness.Weather) / float32(100)
 	weatherContrast := float32(ps.Brightness.WxContrast) / float32(100)
+	for _, u := range sp.freedUnderlays {
+		u.Free(ctx.renderer)
+	}
+	sp.freedUnderlays = nil
+	for _, u := range sp.Underlays {
+		u.Draw(ctx, transforms, cb)
+	}
+
 	sp.weatherRadar.Draw(ctx, weatherBrightness, weatherContrast, ps.DisplayWeatherLevel,
-		transforms, cb)
+		ps.AnimateWeather, transforms, cb)
 
 	if ps.Brightness.RangeRings > 0 {
 		color := ps.Brightness.RangeRings.ScaleRGB(STARSRangeRingColor)
@@ -1606,11 +2161,17 @@ func (sp *STARSPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 		cb.Call(sp.systemMaps[idx].CommandBuffer)
 	}
 
+	sp.drawAirportDiagrams(ctx, transforms, cb)
+
 	ctx.world.DrawScenarioRoutes(transforms, sp.systemFont[ps.CharSize.Tools],
 		ps.Brightness.Lists.ScaleRGB(STARSListColor), cb)
 
 	sp.drawCRDARegions(ctx, transforms, cb)
 	sp.drawSelectedRoute(ctx, transforms, cb)
+	sp.drawHolds(ctx, transforms, cb)
+	ctx.world.DrawScenarioEditorRoute(transforms, ps.Brightness.Lines.ScaleRGB(STARSJRingConeColor), cb)
+	ctx.world.DrawScenarioEditorRegion(transforms, ps.Brightness.OtherTracks.ScaleRGB(STARSGhostColor), cb)
+	ctx.world.DrawScenarioEditorCanvas(transforms, ps.Brightness.Lines.ScaleRGB(STARSJRingConeColor), cb)
 
 	transforms.LoadWindowViewingMatrices(cb)
 
@@ -1632,16 +2193,20 @@ func (sp *STARSPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 		return aircraft[i].Callsign < aircraft[j].Callsign
 	})
 
-	sp.drawSystemLists(aircraft, ctx, ctx.paneExtent, transforms, cb)
+	if !sp.CleanScope {
+		sp.drawSystemLists(aircraft, ctx, ctx.paneExtent, transforms, cb)
+	}
 
 	// Tools before datablocks
 	sp.drawPTLs(aircraft, ctx, transforms, cb)
 	sp.drawRingsAndCones(aircraft, ctx, transforms, cb)
 	sp.drawRBLs(aircraft, ctx, transforms, cb)
+	sp.drawTethers(aircraft, ctx, transforms, cb)
 	sp.drawMinSep(ctx, transforms, cb)
 	sp.drawAirspace(ctx, transforms, cb)
 
 	DrawHighlighted(ctx, transforms, cb)
+	sp.drawCoach(aircraft, ctx, paneExtent, transforms, cb)
 
 	sp.drawLeaderLines(aircraft, ctx, transforms, cb)
 	sp.drawTracks(aircraft, ctx, transforms, cb)
@@ -1649,30 +2214,22 @@ func (sp *STARSPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 
 	ghosts := sp.getGhostAircraft(aircraft, ctx)
 	sp.drawGhosts(ghosts, ctx, transforms, cb)
+	sp.drawOffscreenIndicators(aircraft, ctx, paneExtent, transforms, cb)
+	if !sp.CleanScope {
+		sp.drawOverview(aircraft, ctx, paneExtent, transforms, cb)
+	}
 	sp.consumeMouseEvents(ctx, ghosts, transforms, cb)
-	sp.drawMouseCursor(ctx, paneExtent, transforms, cb)
-
-	// Play the CA sound if any CAs or MSAWs are unacknowledged
-	now := time.Now()
-	playAlertSound := !ps.DisableCAWarnings && slices.ContainsFunc(sp.CAAircraft,
-		func(ca CAAircraft) bool {
-			return !ca.Acknowledged && !sp.Aircraft[ca.Callsigns[0]].DisableCAWarnings &&
-				!sp.Aircraft[ca.Callsigns[1]].DisableCAWarnings && now.Before(ca.SoundEnd)
-		})
-	if !ps.DisableMSAW {
-		for _, ac := range aircraft {
-			state := sp.Aircraft[ac.Callsign]
-			if state.MSAW && !state.MSAWAcknowledged && !state.InhibitMSAW && !state.DisableMSAW &&
-				now.Before(state.MSAWSoundEnd) {
-				playAlertSound = true
-				break
-			}
-		}
+	if !sp.CleanScope {
+		sp.drawMouseCursor(ctx, paneExtent, transforms, cb)
 	}
-	if playAlertSound {
-		globalConfig.Audio.StartPlayContinuous(AudioConflictAlert)
-	} else {
-		globalConfig.Audio.StopPlayContinuous(AudioConflictAlert)
+	if ctx.world.IsCoach {
+		sp.updateCoach(ctx, paneExtent, transforms)
+	}
+
+	if sp.primaryScope() {
+		// Only the primary scope reports alerts, so that their sounds
+		// play once.
+		ctx.world.AlertManager().Update(sp.collectAlerts(ctx, aircraft), time.Now())
 	}
 
 	// Do this at the end of drawing so that we hold on to the tracks we
@@ -1686,19 +2243,81 @@ func (sp *STARSPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
 	}
 }
 
+// radarSweepStep is how often the antenna sweep is processed; each time,
+// the aircraft in the sector swept since the last update are updated.
+const radarSweepStep = 500 * time.Millisecond
+
+// radarSweepPeriod returns the time the given radar site's antenna takes
+// for a full rotation.
+func (sp *STARSPane) radarSweepPeriod(site *RadarSite) time.Duration {
+	p := sp.RadarSweepPeriod
+	if p == 0 {
+		p = site.RotationPeriod
+	}
+	if p == 0 {
+		p = DefaultRadarRotationPeriod
+	}
+	return time.Duration(p * float32(time.Second))
+}
+
+// trackRadarSite returns the radar site that provides the given
+// aircraft's track in single- and multi-sensor modes. It returns nil if
+// the aircraft isn't seen by any site that is in service.
+func (sp *STARSPane) trackRadarSite(w *World, ac *Aircraft) *RadarSite {
+	if id := sp.CurrentPreferenceSet.RadarSiteSelected; id != "" {
+		if site, ok := w.RadarSites[id]; ok {
+			return Select(w.RadarSiteInService(id), site, nil)
+		}
+	}
+
+	// Multi-sensor: use the closest one that's working.
+	var closest *RadarSite
+	dist := float32(1e30)
+	for id, site := range w.RadarSites {
+		if !w.RadarSiteInService(id) {
+			continue
+		}
+		if d := nmdistance2ll(site.Position, ac.Position()); d < dist {
+			closest, dist = site, d
+		}
+	}
+	return closest
+}
+
 func (sp *STARSPane) updateRadarTracks(w *World) {
-	// FIXME: all aircraft radar tracks are updated at the same time.
 	now := w.CurrentTime()
-	if sp.radarMode(w) == RadarModeFused {
-		if now.Sub(sp.lastTrackUpdate) < 1*time.Second {
-			return
+	if now.Before(sp.lastTrackUpdate) {
+		// Time went backward, which happens when a replay is rewound; the
+		// existing tracks and history no longer apply.
+		sp.lastTrackUpdate, sp.lastHistoryTrackUpdate = time.Time{}, time.Time{}
+		for _, state := range sp.Aircraft {
+			state.track, state.previousTrack = RadarTrack{}, RadarTrack{}
+			clear(state.historyTracks[:])
+			state.historyTracksIndex = 0
+			state.historyTrail = nil
 		}
-	} else {
-		if now.Sub(sp.lastTrackUpdate) < 5*time.Second {
+	}
+	if w.HasSystemFailure(FailureRadarFeed) {
+		// No new radar data; the tracks coast until the feed is
+		// restored.
+		return
+	}
+
+	// In fused mode, all tracks are updated once a second. Otherwise,
+	// each aircraft's track is updated when its radar's antenna sweeps
+	// past it.
+	fused := sp.radarMode(w) == RadarModeFused
+	if fused {
+		if now.Sub(sp.lastTrackUpdate) < 1*time.Second {
 			return
 		}
+	} else if now.Sub(sp.lastTrackUpdate) < radarSweepStep {
+		return
 	}
+	lastUpdate := sp.lastTrackUpdate
 	sp.lastTrackUpdate = now
+	allSitesOut := len(w.RadarSites) > 0 &&
+		!slices.ContainsFunc(SortedMapKeys(w.RadarSites), w.RadarSiteInService)
 
 	for callsign, state := range sp.Aircraft {
 		ac, ok := w.Aircraft[callsign]
@@ -1707,6 +2326,20 @@ func (sp *STARSPane) updateRadarTracks(w *World) {
 			continue
 		}
 
+		if !w.InRadarCoverage(ac.Position(), int(ac.Altitude())) {
+			// The track coasts until the aircraft is back in coverage.
+			continue
+		}
+		if !fused {
+			site := sp.trackRadarSite(w, ac)
+			if site == nil ||
+				!site.SweepPasses(ac.Position(), w.NmPerLongitude, sp.radarSweepPeriod(site), lastUpdate, now) {
+				continue
+			}
+		} else if allSitesOut {
+			continue
+		}
+
 		state.previousTrack = state.track
 		state.track = RadarTrack{
 			Position:    ac.Position(),
@@ -1714,6 +2347,9 @@ func (sp *STARSPane) updateRadarTracks(w *World) {
 			Groundspeed: int(ac.Nav.FlightState.GS),
 			Time:        now,
 		}
+		if p, ok := ac.DownlinkedParameters(); ok {
+			state.track.ModeS = &p
+		}
 	}
 
 	// History tracks are updated after a radar track update, only if
@@ -1728,6 +2364,21 @@ func (sp *STARSPane) updateRadarTracks(w *World) {
 		}
 	}
 
+	// The time-based trails are always maintained so that they're
+	// immediately available if they are enabled.
+	trailStart := now.Add(-time.Duration(ps.HistoryTrails.Length * float32(time.Minute)))
+	for _, state := range sp.Aircraft {
+		trail := state.historyTrail
+		if n := len(trail); n == 0 || now.Sub(trail[n-1].Time).Seconds() >= float64(ps.HistoryTrails.Interval) {
+			trail = append(trail, state.track)
+		}
+		i := slices.IndexFunc(trail, func(t RadarTrack) bool { return t.Time.After(trailStart) })
+		if i == -1 {
+			i = len(trail)
+		}
+		state.historyTrail = slices.Delete(trail, 0, i)
+	}
+
 	aircraft := sp.visibleAircraft(w)
 	sort.Slice(aircraft, func(i, j int) bool {
 		return aircraft[i].Callsign < aircraft[j].Callsign
@@ -1735,6 +2386,50 @@ func (sp *STARSPane) updateRadarTracks(w *World) {
 
 	sp.updateCAAircraft(w, aircraft)
 	sp.updateInTrailDistance(aircraft, w)
+	if sp.AutoHandoff {
+		sp.updateAutoHandoffs(w, aircraft)
+	}
+}
+
+// updateAutoHandoffs initiates handoffs of our tracked departures that are
+// currently in the departure airspace but are predicted to leave it within
+// the next minute. They are only ever handed off to their exit route's
+// handoff controller (WaypointHandoffController; generally, the center
+// controller), and only departures are handled: arrivals leave our
+// airspace by landing and are switched to the tower, and overflights
+// aren't handed off automatically.
+func (sp *STARSPane) updateAutoHandoffs(w *World, aircraft []*Aircraft) {
+	if len(w.DepartureAirspace) == 0 {
+		return
+	}
+
+	for _, ac := range aircraft {
+		state := sp.Aircraft[ac.Callsign]
+		if ac.TrackingController != w.Callsign || ac.HandoffTrackController != "" ||
+			!ac.IsDeparture() || state.AutoHandoffOffered || !state.HaveHeading() {
+			continue
+		}
+
+		to := ac.WaypointHandoffController
+		if _, ok := w.Controllers[to]; !ok || to == w.Callsign {
+			continue
+		}
+
+		alt := float32(state.TrackAltitude())
+		if in, _ := InAirspace(state.TrackPosition(), alt, w.DepartureAirspace); !in {
+			continue
+		}
+
+		// Extrapolate one minute ahead.
+		p := add2ll(state.TrackPosition(), state.HeadingVector(w.NmPerLongitude, w.MagneticVariation))
+		alt += 60 * state.TrackAltitudeRate()
+		if in, _ := InAirspace(p, alt, w.DepartureAirspace); !in {
+			state.AutoHandoffOffered = true
+			w.HandoffTrack(ac.Callsign, to, nil, func(err error) {
+				lg.Warnf("%s: auto handoff to %s: %v", ac.Callsign, to, err)
+			})
+		}
+	}
 }
 
 func (sp *STARSPane) processKeyboardInput(ctx *PaneContext) {
@@ -1781,6 +2476,11 @@ func (sp *STARSPane) processKeyboardInput(ctx *PaneContext) {
 	}
 
 	for key := range ctx.keyboard.Pressed {
+		if b, ok := sp.keymap[key]; ok && !ctx.keyboard.IsPressed(KeyControl) {
+			sp.applyKeyBinding(b)
+			continue
+		}
+
 		switch key {
 		case KeyBackspace:
 			if len(sp.previewAreaInput) > 0 {
@@ -1815,9 +2515,9 @@ func (sp *STARSPane) processKeyboardInput(ctx *PaneContext) {
 
 		case KeyF1:
 			if ctx.keyboard.IsPressed(KeyControl) {
-				// Recenter
-				ps.Center = ctx.world.GetInitialCenter()
-				ps.CurrentCenter = ps.Center
+				// Return to the initial center and range
+				sp.followAircraft = ""
+				sp.transitionCenter(ctx.world.GetInitialCenter(), ctx.world.GetInitialRange())
 			}
 
 		case KeyF2:
@@ -1909,6 +2609,13 @@ func (sp *STARSPane) processKeyboardInput(ctx *PaneContext) {
 				sp.resetInputState()
 				sp.commandMode = CommandModeCollisionAlert
 			}
+
+		case KeyF12:
+			// Quick toggle through the altitude filter presets; shift goes
+			// backwards.
+			sp.resetInputState()
+			sp.previewAreaOutput, _ = sp.cycleAltitudeFilterPreset(
+				Select(ctx.keyboard.IsPressed(KeyShift), -1, 1))
 		}
 	}
 }
@@ -1948,6 +2655,21 @@ func (sp *STARSPane) executeSTARSCommand(cmd string, ctx *PaneContext) (status S
 		return
 	}
 
+	// When a PLACE button is active, a location--a fix, lat-long
+	// coordinates, or fix-radial-distance--may be entered rather than
+	// clicking on the scope.
+	if sp.selectedPlaceButton != "" && cmd != "" {
+		if p, ok := ctx.world.Locate(cmd); ok {
+			ps := sp.CurrentPreferenceSet
+			transforms := GetScopeTransformations(ctx.paneExtent, ctx.world.MagneticVariation, ctx.world.NmPerLongitude,
+				ps.CurrentCenter, float32(ps.Range), 0)
+			status = sp.scopeClickHandler(transforms.WindowFromLatLongP(p), transforms)
+		} else {
+			status.err = ErrSTARSIllegalFix
+		}
+		return
+	}
+
 	lookupAircraft := func(callsign string, abbreviated bool) *Aircraft {
 		if ac := ctx.world.GetAircraft(callsign, abbreviated); ac != nil {
 			return ac
@@ -2076,6 +2798,77 @@ func (sp *STARSPane) executeSTARSCommand(cmd string, ctx *PaneContext) (status S
 			sp.drawRouteAircraft = ""
 			status.clear = true
 			return
+
+		case ".MVA":
+			// Toggle the MVA system map
+			if _, ok := ps.SystemMapVisible[701]; ok {
+				delete(ps.SystemMapVisible, 701)
+			} else {
+				ps.SystemMapVisible[701] = nil
+			}
+			status.clear = true
+			return
+
+		case ".OVERVIEW":
+			ps.DisplayOverview = !ps.DisplayOverview
+			status.clear = true
+			return
+
+		case ".CLEAN":
+			sp.CleanScope = !sp.CleanScope
+			status.clear = true
+			return
+
+		case ".TAKEOVER":
+			// Coaches: take command authority for the position or hand
+			// it back.
+			if !ctx.world.IsCoach {
+				status.err = ErrSTARSIllegalFunction
+				return
+			}
+			ctx.world.SetCoachTakeover(!ctx.world.CoachHasControl(), nil,
+				func(err error) { sp.displayError(err) })
+			status.clear = true
+			return
+
+		case ".FOLLOW":
+			// Stop following an aircraft
+			sp.followAircraft = ""
+			status.clear = true
+			return
+
+		case ".TETHER":
+			sp.Tethers = nil
+			status.clear = true
+			return
+
+		case ".FILTER":
+			// Cycle through the altitude filter presets
+			status.output, status.err = sp.cycleAltitudeFilterPreset(1)
+			status.clear = status.err == nil
+			return
+
+		case ".OWNERS":
+			// Toggle ownership coloring
+			ps.OwnershipColors.Enabled = !ps.OwnershipColors.Enabled
+			status.clear = true
+			return
+
+		case ".SUPPRESS":
+			clear(sp.SuppressedDatablocks)
+			status.clear = true
+			return
+
+		case ".FORCE":
+			clear(sp.ForcedDatablocks)
+			status.clear = true
+			return
+
+		case ".DIAGRAM":
+			// Toggle airport diagrams
+			ps.AirportDiagramRange = Select(ps.AirportDiagramRange == 0, 6, 0)
+			status.clear = true
+			return
 		}
 
 		if len(cmd) > 5 && cmd[:2] == "**" { // Force QL
@@ -2128,10 +2921,25 @@ func (sp *STARSPane) executeSTARSCommand(cmd string, ctx *PaneContext) (status S
 					sp.RangeBearingLines = DeleteSliceElement(sp.RangeBearingLines, idx)
 					status.clear = true
 				} else {
-					status.err = ErrSTARSIllegalParam
+					status.err = ErrSTARSIllegalParam
+				}
+			} else if ac := lookupAircraft(suffix, false); ac != nil {
+				// Aircraft for the first or second point of the RBL; the
+				// line follows it as it moves.
+				if rbl := sp.wipRBL; rbl != nil {
+					rbl.P[1].Callsign = ac.Callsign
+					sp.RangeBearingLines = append(sp.RangeBearingLines, *rbl)
+					sp.wipRBL = nil
+					status.clear = true
+				} else {
+					sp.wipRBL = &STARSRangeBearingLine{}
+					sp.wipRBL.P[0].Callsign = ac.Callsign
+					sp.scopeClickHandler = rblSecondClickHandler(ctx, sp)
+					sp.previewAreaInput = "*T" // set up for the second point
 				}
 			} else if p, ok := ctx.world.Locate(suffix); ok {
-				// Fix name for first or second point of RBL
+				// Fix name (or lat-long or fix-radial-distance) for first
+				// or second point of RBL
 				if rbl := sp.wipRBL; rbl != nil {
 					rbl.P[1].Loc = p
 					sp.RangeBearingLines = append(sp.RangeBearingLines, *rbl)
@@ -2161,6 +2969,111 @@ func (sp *STARSPane) executeSTARSCommand(cmd string, ctx *PaneContext) (status S
 					status.clear = true
 					return
 				}
+			} else if f[0] == ".DIAGRAM" && len(f) == 2 {
+				// Set the range at which airport diagrams are drawn
+				if r, err := strconv.Atoi(f[1]); err != nil || r < 0 || r > 20 {
+					status.err = ErrSTARSIllegalValue
+				} else {
+					ps.AirportDiagramRange = r
+					status.clear = true
+				}
+				return
+			} else if f[0] == ".OWNERS" && len(f) <= 3 {
+				// Assign a palette color to a position, or reset it to
+				// the automatic assignment if no color is given.
+				var ctrl *Controller
+				for _, c := range ctx.world.GetAllControllers() {
+					if c.SectorId == f[1] {
+						ctrl = c
+					}
+				}
+				if ctrl == nil {
+					status.err = ErrSTARSIllegalPosition
+				} else if len(f) == 2 {
+					delete(ps.OwnershipColors.Assigned, ctrl.Callsign)
+					status.clear = true
+				} else if idx, err := strconv.Atoi(f[2]); err != nil || idx < 1 || idx > len(STARSOwnershipPalette) {
+					status.err = ErrSTARSIllegalValue
+				} else {
+					if ps.OwnershipColors.Assigned == nil {
+						ps.OwnershipColors.Assigned = make(map[string]int)
+					}
+					ps.OwnershipColors.Assigned[ctrl.Callsign] = idx - 1
+					status.clear = true
+				}
+				return
+			} else if f[0] == ".FILTER" {
+				status.err = sp.altitudeFilterPresetCommand(f[1:])
+				status.clear = status.err == nil
+				return
+			} else if (f[0] == ".CENTER" || f[0] == ".RR") && len(f) == 2 {
+				// Center the scope or the range rings at a location, which
+				// may be given as a fix-radial-distance.
+				p, ok := ctx.world.Locate(f[1])
+				if !ok {
+					status.err = ErrSTARSIllegalFix
+					return
+				}
+				ps := &sp.CurrentPreferenceSet
+				if f[0] == ".CENTER" {
+					sp.followAircraft = ""
+					sp.transitionCenter(p, ps.Range)
+				} else {
+					ps.RangeRingsCenter = p
+				}
+				status.clear = true
+				return
+			} else if f[0] == ".QL" {
+				// Temporarily quick look the given positions.
+				positions, input, err := sp.parseQuickLookPositions(ctx, strings.Join(f[1:], " "))
+				if err != nil {
+					status.err = err
+					sp.previewAreaInput = ".QL " + input
+					return
+				}
+				ps := &sp.CurrentPreferenceSet
+				expires := time.Now().Add(time.Duration(ps.TimedQuickLookDuration) * time.Second)
+				for _, pos := range positions {
+					if idx := slices.IndexFunc(ps.QuickLookPositions,
+						func(q QuickLookPosition) bool { return q.Id == pos.Id }); idx == -1 {
+						pos.Expires = expires
+						ps.QuickLookPositions = append(ps.QuickLookPositions, pos)
+					} else if !ps.QuickLookPositions[idx].Expires.IsZero() {
+						// Extend an existing timed quick look (but leave
+						// regular ones as they are).
+						ps.QuickLookPositions[idx].Expires = expires
+						ps.QuickLookPositions[idx].Plus = pos.Plus
+					}
+				}
+				sort.Slice(ps.QuickLookPositions,
+					func(i, j int) bool { return ps.QuickLookPositions[i].Id < ps.QuickLookPositions[j].Id })
+				status.clear = true
+				return
+			} else if f[0] == ".FIT" {
+				// Center and zoom the scope to show all of the given
+				// airports and fixes.
+				var pts []Point2LL
+				for _, loc := range f[1:] {
+					if p, ok := ctx.world.Locate(loc); ok {
+						pts = append(pts, p)
+					} else {
+						status.err = ErrSTARSIllegalFix
+						return
+					}
+				}
+				center := Extent2DFromPoints(MapSlice(pts, func(p Point2LL) [2]float32 { return p })).Center()
+				r := float32(0)
+				for _, p := range pts {
+					r = max(r, nmdistance2ll(center, p))
+				}
+				sp.followAircraft = ""
+				sp.transitionCenter(center, clamp(float32(int(1.15*r+2)), 6, 256))
+				status.clear = true
+				return
+			} else if f[0] == ".INFO" && len(f) == 2 {
+				status.output, status.err = sp.fixInfo(ctx.world, f[1])
+				status.clear = status.err == nil
+				return
 			} else if f[0] == ".FIND" {
 				if pos, ok := ctx.world.Locate(f[1]); ok {
 					globalConfig.highlightedLocation = pos
@@ -2706,15 +3619,16 @@ func (sp *STARSPane) executeSTARSCommand(cmd string, ctx *PaneContext) (status S
 								return
 							} else {
 								// Make sure neither of the runways involved is already enabled in
-								// another pair.
+								// another pair at the airport. Multiple pairs may be enabled
+								// as long as they don't share a runway, and an enabled pair's
+								// mode can be changed without disabling it first.
 								for j, pairState := range ps.CRDA.RunwayPairState {
-									if !pairState.Enabled {
+									other := sp.ConvergingRunways[j]
+									if j == i || !pairState.Enabled || other.Airport != pair.Airport {
 										continue
 									}
-									if sp.ConvergingRunways[j].Runways[0] == pair.Runways[0] ||
-										sp.ConvergingRunways[j].Runways[0] == pair.Runways[1] ||
-										sp.ConvergingRunways[j].Runways[1] == pair.Runways[0] ||
-										sp.ConvergingRunways[j].Runways[1] == pair.Runways[1] {
+									if other.Runways[0] == pair.Runways[0] || other.Runways[0] == pair.Runways[1] ||
+										other.Runways[1] == pair.Runways[0] || other.Runways[1] == pair.Runways[1] {
 										status.err = ErrSTARSIllegalRunway
 										return
 									}
@@ -3167,6 +4081,14 @@ func (sp *STARSPane) executeSTARSCommand(cmd string, ctx *PaneContext) (status S
 	return
 }
 
+// expireQuickLooks removes timed quick looks once their time is up.
+func (sp *STARSPane) expireQuickLooks() {
+	ps := &sp.CurrentPreferenceSet
+	now := time.Now()
+	ps.QuickLookPositions = FilterSlice(ps.QuickLookPositions,
+		func(q QuickLookPosition) bool { return q.Expires.IsZero() || now.Before(q.Expires) })
+}
+
 func (sp *STARSPane) updateQL(ctx *PaneContext, input string) (ok bool, previewInput string, err error) {
 	positions, input, err := sp.parseQuickLookPositions(ctx, input)
 	if err != nil {
@@ -3443,9 +4365,31 @@ func (sp *STARSPane) lookupControllerForId(ctx *PaneContext, id, callsign string
 	return nil
 }
 
+// setLeaderLine handles a leader line entry for a single aircraft: a
+// numpad direction sets its direction locally and a doubled one sets it
+// system wide. Either may be followed by "/" and a length from 0-7 to set
+// the aircraft's leader line length; "/" by itself reverts to the length
+// from the preference set.
 func (sp *STARSPane) setLeaderLine(ctx *PaneContext, ac *Aircraft, cmd string) error {
 	state := sp.Aircraft[ac.Callsign]
-	if len(cmd) == 1 {
+	if dir, length, ok := strings.Cut(cmd, "/"); ok {
+		if cmd == "/" {
+			state.LeaderLineLength = nil
+			return nil
+		}
+
+		l, err := strconv.Atoi(length)
+		if err != nil || len(length) != 1 || l > 7 {
+			return ErrSTARSIllegalParam
+		}
+		if dir != "" {
+			if err := sp.setLeaderLine(ctx, ac, dir); err != nil {
+				return err
+			}
+		}
+		state.LeaderLineLength = &l
+		return nil
+	} else if len(cmd) == 1 {
 		if dir, ok := numpadToDirection(cmd[0]); ok {
 			state.LeaderLineDirection = dir
 			if dir != nil {
@@ -3651,8 +4595,10 @@ func (sp *STARSPane) executeSTARSClickedCommand(ctx *PaneContext, cmd string, mo
 				}
 				return
 			} else if (unicode.IsDigit(rune(cmd[0])) && len(cmd) == 1) ||
-				(len(cmd) == 2 && unicode.IsDigit(rune(cmd[1]))) {
-				// 6-81: set locally, 6-101: set system wide
+				(len(cmd) == 2 && unicode.IsDigit(rune(cmd[1]))) ||
+				isLeaderLineLengthEntry(cmd) {
+				// 6-81: set locally, 6-101: set system wide, optionally
+				// with a length for this aircraft's leader line
 				if err := sp.setLeaderLine(ctx, ac, cmd); err != nil {
 					status.err = err
 				} else {
@@ -3811,9 +4757,66 @@ func (sp *STARSPane) executeSTARSClickedCommand(ctx *PaneContext, cmd string, mo
 				}
 				return
 			} else if cmd == ".ROUTE" {
-				sp.drawRouteAircraft = ac.Callsign
+				// Toggle the display of the aircraft's route.
+				sp.drawRouteAircraft = Select(sp.drawRouteAircraft == ac.Callsign, "", ac.Callsign)
+				status.clear = true
+				return
+			} else if strings.HasPrefix(cmd, ".ROUTE ") {
+				// Amend the aircraft's route and show the new one.
+				ctx.world.AmendRoute(ac.Callsign, strings.TrimSpace(cmd[7:]),
+					func(any) { sp.drawRouteAircraft = ac.Callsign },
+					func(err error) { sp.displayError(err) })
+				status.clear = true
+				return
+			} else if cmd == ".SUPPRESS" {
+				// Toggle suppression of the aircraft's datablock
+				if _, ok := sp.SuppressedDatablocks[ac.Callsign]; ok {
+					delete(sp.SuppressedDatablocks, ac.Callsign)
+				} else {
+					sp.SuppressedDatablocks[ac.Callsign] = nil
+					delete(sp.ForcedDatablocks, ac.Callsign)
+				}
+				status.clear = true
+				return
+			} else if cmd == ".FORCE" {
+				// Toggle forced display of the aircraft's datablock
+				if _, ok := sp.ForcedDatablocks[ac.Callsign]; ok {
+					delete(sp.ForcedDatablocks, ac.Callsign)
+				} else {
+					sp.ForcedDatablocks[ac.Callsign] = nil
+					delete(sp.SuppressedDatablocks, ac.Callsign)
+				}
+				status.clear = true
+				return
+			} else if cmd == ".FOLLOW" {
+				sp.followAircraft = ac.Callsign
+				sp.transitionCenter(state.TrackPosition(), ps.Range)
 				status.clear = true
 				return
+			} else if cmd == ".TETHER" {
+				// Clicking on an aircraft that is already tethered removes
+				// its tethers; otherwise wait for the second aircraft.
+				n := len(sp.Tethers)
+				sp.Tethers = FilterSlice(sp.Tethers, func(t STARSTether) bool {
+					return t.Callsigns[0] != ac.Callsign && t.Callsigns[1] != ac.Callsign
+				})
+				if len(sp.Tethers) != n {
+					status.clear = true
+					return
+				}
+
+				sp.scopeClickHandler = func(pw [2]float32, transforms ScopeTransformations) (status STARSCommandStatus) {
+					if ac2, _ := sp.tryGetClosestAircraft(ctx.world, pw, transforms); ac2 == nil {
+						status.err = ErrSTARSNoFlight
+					} else if ac2 == ac {
+						status.err = ErrSTARSIllegalTrack
+					} else {
+						sp.Tethers = append(sp.Tethers, STARSTether{Callsigns: [2]string{ac.Callsign, ac2.Callsign}})
+						status.clear = true
+					}
+					return
+				}
+				return
 			} else if len(cmd) > 2 && cmd[:2] == "*J" {
 				if r, err := strconv.Atoi(cmd[2:]); err == nil {
 					if r < 1 || r > 30 {
@@ -4252,6 +5255,11 @@ func (sp *STARSPane) executeSTARSClickedCommand(ctx *PaneContext, cmd string, mo
 			ps.CRDAStatusList.Visible = true
 			status.clear = true
 			return
+		} else if cmd == "TO" {
+			ps.OwnershipColors.Legend.Position = transforms.NormalizedFromWindowP(mousePosition)
+			ps.OwnershipColors.Legend.Visible = true
+			status.clear = true
+			return
 		} else if len(cmd) == 2 && cmd[0] == 'P' {
 			if idx, err := strconv.Atoi(cmd[1:]); err == nil && idx > 0 && idx <= 3 {
 				ps.TowerLists[idx-1].Position = transforms.NormalizedFromWindowP(mousePosition)
@@ -4271,6 +5279,17 @@ func (sp *STARSPane) executeSTARSClickedCommand(ctx *PaneContext, cmd string, mo
 // Returns the cardinal-ordinal direction associated with the numbpad keys,
 // interpreting 5 as the center; (nil, true) is returned for '5' and
 // (nil, false) is returned for an invalid key.
+// isLeaderLineLengthEntry returns true if cmd is of the form
+// [(#)[(#)]]/(#) or "/", as used to set an aircraft's leader line length.
+func isLeaderLineLengthEntry(cmd string) bool {
+	dir, length, ok := strings.Cut(cmd, "/")
+	isDigits := func(s string) bool {
+		return !strings.ContainsFunc(s, func(ch rune) bool { return !unicode.IsDigit(ch) })
+	}
+	return ok && len(dir) <= 2 && isDigits(dir) && len(length) <= 1 && isDigits(length) &&
+		(length != "" || dir == "")
+}
+
 func numpadToDirection(key byte) (*CardinalOrdinalDirection, bool) {
 	var dir CardinalOrdinalDirection
 	switch key {
@@ -4331,7 +5350,7 @@ func (sp *STARSPane) DrawDCB(ctx *PaneContext, transforms ScopeTransformations,
 	// Find a scale factor so that the buttons all fit in the window, if necessary
 	const NumDCBSlots = 20
 	// Sigh; on windows we want the button size in pixels on high DPI displays
-	ds := Select(runtime.GOOS == "windows", ctx.platform.DPIScale(), float32(1))
+	ds := ctx.platform.WindowScale()
 	var buttonScale float32
 	// Scale based on width or height available depending on DCB position
 	if ps.DCBPosition == DCBPositionTop || ps.DCBPosition == DCBPositionBottom {
@@ -4578,10 +5597,7 @@ func (sp *STARSPane) DrawDCB(ctx *PaneContext, transforms ScopeTransformations,
 				flags = flags | STARSButtonSelected
 			}
 			if STARSSelectButton(ctx, text, flags, buttonScale) {
-				// Make this one current
-				sp.SelectedPreferenceSet = i
-				sp.CurrentPreferenceSet = sp.PreferenceSets[i]
-				sp.weatherRadar.Activate(sp.CurrentPreferenceSet.Center, ctx.renderer)
+				sp.recallPreferenceSet(ctx, i)
 			}
 		}
 		for i := len(sp.PreferenceSets); i < NumSTARSPreferenceSets; i++ {
@@ -4660,7 +5676,7 @@ func (sp *STARSPane) DrawDCB(ctx *PaneContext, transforms ScopeTransformations,
 
 	case DCBMenuSSAFilter:
 		STARSToggleButton(ctx, "ALL", &ps.SSAList.Filter.All, STARSButtonHalfVertical, buttonScale)
-		STARSDisabledButton(ctx, "WX", STARSButtonHalfVertical, buttonScale) // ?? TODO
+		STARSToggleButton(ctx, "WX", &ps.SSAList.Filter.Wx, STARSButtonHalfVertical, buttonScale)
 		STARSToggleButton(ctx, "TIME", &ps.SSAList.Filter.Time, STARSButtonHalfVertical, buttonScale)
 		STARSToggleButton(ctx, "ALTSTG", &ps.SSAList.Filter.Altimeter, STARSButtonHalfVertical, buttonScale)
 		STARSToggleButton(ctx, "STATUS", &ps.SSAList.Filter.Status, STARSButtonHalfVertical, buttonScale)
@@ -4682,7 +5698,7 @@ func (sp *STARSPane) DrawDCB(ctx *PaneContext, transforms ScopeTransformations,
 		STARSDisabledButton(ctx, "CON/CPL", STARSButtonHalfVertical, buttonScale) // ?? TODO
 		STARSDisabledButton(ctx, "OFF IND", STARSButtonHalfVertical, buttonScale) // ?? TODO
 		STARSToggleButton(ctx, "CRDA", &ps.SSAList.Filter.ActiveCRDAPairs, STARSButtonHalfVertical, buttonScale)
-		STARSDisabledButton(ctx, "", STARSButtonHalfVertical, buttonScale)
+		STARSToggleButton(ctx, "DEP TMR", &ps.SSAList.Filter.DepartureTimers, STARSButtonHalfVertical, buttonScale)
 		if STARSSelectButton(ctx, "DONE", STARSButtonFull, buttonScale) {
 			sp.activeDCBMenu = DCBMenuMain
 		}
@@ -4889,6 +5905,28 @@ func (sp *STARSPane) drawSystemLists(aircraft []*Aircraft, ctx *PaneContext, pan
 				pw = td.AddText(sp.radarSiteId(ctx.world), pw, style)
 			}
 			newline()
+
+			if filter.All || filter.Status {
+				// Equipment failures at our position
+				for f := SystemFailureType(0); f < NumSystemFailureTypes; f++ {
+					if ctx.world.HasSystemFailure(f) {
+						pw = td.AddText(f.SSAText(), pw, alertStyle)
+						newline()
+					}
+				}
+				for _, ro := range ctx.world.RadarOutages {
+					pw = td.AddText(Select(ro.Site != "", "RDR "+ro.Site+" OUT", "RDR OUT "+ro.Fix), pw, alertStyle)
+					newline()
+				}
+			}
+		}
+
+		if filter.All || filter.Wx {
+			// Time and age of the weather radar image
+			if txt := sp.weatherRadar.StatusText(time.Now()); txt != "" {
+				pw = td.AddText(txt, pw, style)
+				newline()
+			}
 		}
 
 		if filter.All || filter.Codes {
@@ -5005,6 +6043,23 @@ func (sp *STARSPane) drawSystemLists(aircraft []*Aircraft, ctx *PaneContext, pan
 			}
 		}
 
+		if filter.All || filter.DepartureTimers {
+			// Time since the last departure roll from each runway; they
+			// are no longer shown after 5 minutes.
+			now := ctx.world.CurrentTime()
+			for _, rwy := range SortedMapKeys(sp.departureRollTimes) {
+				elapsed := now.Sub(sp.departureRollTimes[rwy])
+				if elapsed > 5*time.Minute {
+					continue
+				}
+				ap, r, _ := strings.Cut(rwy, " ")
+				secs := int(elapsed.Seconds())
+				text := fmt.Sprintf("DEP %s %s %d:%02d", stripK(ap), r, secs/60, secs%60)
+				pw = td.AddText(text, pw, style)
+				newline()
+			}
+		}
+
 		if (filter.All || filter.ActiveCRDAPairs) && !ps.CRDA.Disabled {
 			for i, crda := range ps.CRDA.RunwayPairState {
 				if !crda.Enabled {
@@ -5092,6 +6147,12 @@ func (sp *STARSPane) drawSystemLists(aircraft []*Aircraft, ctx *PaneContext, pan
 			lists = append(lists, "CA")
 			n += len(sp.CAAircraft)
 		}
+		lists = append(lists, "XR")
+		for _, ac := range aircraft {
+			if sp.Aircraft[ac.Callsign].UnableCrossingFix != "" {
+				n++
+			}
+		}
 
 		if len(lists) > 0 {
 			text := strings.Join(lists, "/") + "\n"
@@ -5124,13 +6185,45 @@ func (sp *STARSPane) drawSystemLists(aircraft []*Aircraft, ctx *PaneContext, pan
 				}
 			}
 
+			// Crossing restrictions
+			for _, ac := range aircraft {
+				if n == 0 {
+					break
+				}
+				if fix := sp.Aircraft[ac.Callsign].UnableCrossingFix; fix != "" {
+					text += fmt.Sprintf("%-8s%-9s XR\n", ac.Callsign, fix)
+					n--
+				}
+			}
+
 			drawList(text, ps.AlertList.Position)
 		}
 	}
 
 	if ps.CoastList.Visible {
-		text := "COAST/SUSPEND"
-		// TODO
+		// Tracked aircraft that have left radar coverage: first they
+		// coast and then the track is dropped.
+		var lines []string
+		now := ctx.world.CurrentTime()
+		for _, callsign := range SortedMapKeys(ctx.world.Aircraft) {
+			ac := ctx.world.Aircraft[callsign]
+			state, ok := sp.Aircraft[callsign]
+			if !ok || ac.TrackingController == "" {
+				continue
+			}
+			if state.Coasting(now) {
+				lines = append(lines, fmt.Sprintf("%-8s %s CST\n", callsign, ac.Squawk))
+			} else if state.LostTrack(now) {
+				lines = append(lines, fmt.Sprintf("%-8s %s DRP\n", callsign, ac.Squawk))
+			}
+		}
+
+		text := "COAST/SUSPEND\n"
+		if len(lines) > ps.CoastList.Lines {
+			text += fmt.Sprintf("MORE: %d/%d\n", ps.CoastList.Lines, len(lines))
+			lines = lines[:ps.CoastList.Lines]
+		}
+		text += strings.Join(lines, "")
 		drawList(text, ps.CoastList.Position)
 	}
 
@@ -5233,26 +6326,44 @@ func (sp *STARSPane) drawSystemLists(aircraft []*Aircraft, ctx *PaneContext, pan
 		ap := towerListAirports[i]
 		loc := ctx.world.ArrivalAirports[ap].Location
 		text := stripK(ap) + " TOWER\n"
-		m := make(map[float32]string)
+		type towerListEntry struct {
+			eta  time.Time
+			dist float32
+			line string
+		}
+		var entries []towerListEntry
 		for _, ac := range aircraft {
 			if ac.FlightPlan != nil && ac.FlightPlan.ArrivalAirport == ap {
 				dist := nmdistance2ll(loc, sp.Aircraft[ac.Callsign].TrackPosition())
 				actype := ac.FlightPlan.TypeWithoutSuffix()
 				actype = strings.TrimPrefix(actype, "H/")
 				actype = strings.TrimPrefix(actype, "S/")
-				// We'll punt on the chance that two aircraft have the
-				// exact same distance to the airport...
-				m[dist] = fmt.Sprintf("%-7s %s", ac.Callsign, actype)
+				entries = append(entries, towerListEntry{
+					eta:  ac.ETAs.RunwayTime,
+					dist: dist,
+					line: fmt.Sprintf("%-7s %s", ac.Callsign, actype),
+				})
 			}
 		}
 
-		k := SortedMapKeys(m)
-		if len(k) > tl.Lines {
-			k = k[:tl.Lines]
+		// List aircraft in the order of their ETAs at the runway so that
+		// the list matches the arrival sequence. Aircraft that don't
+		// have one go at the end, ordered by distance to the airport.
+		sort.SliceStable(entries, func(i, j int) bool {
+			a, b := entries[i], entries[j]
+			if a.eta.IsZero() != b.eta.IsZero() {
+				return b.eta.IsZero()
+			} else if !a.eta.Equal(b.eta) {
+				return a.eta.Before(b.eta)
+			}
+			return a.dist < b.dist
+		})
+		if len(entries) > tl.Lines {
+			entries = entries[:tl.Lines]
 		}
 
-		for _, key := range k {
-			text += m[key] + "\n"
+		for _, e := range entries {
+			text += e.line + "\n"
 		}
 		drawList(text, tl.Position)
 	}
@@ -5284,9 +6395,189 @@ func (sp *STARSPane) drawSystemLists(aircraft []*Aircraft, ctx *PaneContext, pan
 		drawList(text, ps.SignOnList.Position)
 	}
 
+	sp.drawOwnershipLegend(ctx, paneExtent, td)
+
 	td.GenerateCommands(cb)
 }
 
+// transitionCenter starts an animated transition of the scope's center
+// and range to the given values.
+func (sp *STARSPane) transitionCenter(center Point2LL, rangenm float32) {
+	ps := &sp.CurrentPreferenceSet
+	sp.centerTransition = &STARSCenterTransition{
+		FromCenter: ps.CurrentCenter,
+		ToCenter:   center,
+		FromRange:  ps.Range,
+		ToRange:    rangenm,
+		Start:      time.Now(),
+	}
+	ps.Center = center
+}
+
+// updateCenter updates the scope's center and range for the automatic
+// centering modes; it should be called before the scope is drawn.
+func (sp *STARSPane) updateCenter(ctx *PaneContext) {
+	ps := &sp.CurrentPreferenceSet
+
+	if sp.followAircraft != "" {
+		if state, ok := sp.Aircraft[sp.followAircraft]; !ok || state.LostTrack(ctx.world.CurrentTime()) {
+			sp.followAircraft = ""
+		} else if sp.centerTransition != nil {
+			// Keep the transition's endpoint on the aircraft.
+			sp.centerTransition.ToCenter = state.TrackPosition()
+			ps.Center = state.TrackPosition()
+		} else {
+			ps.Center = state.TrackPosition()
+			ps.CurrentCenter = ps.Center
+		}
+	}
+
+	if tr := sp.centerTransition; tr != nil {
+		t := float32(time.Since(tr.Start)) / float32(STARSCenterTransitionDuration)
+		if t >= 1 {
+			ps.CurrentCenter = tr.ToCenter
+			ps.Range = tr.ToRange
+			sp.centerTransition = nil
+		} else {
+			t = t * t * (3 - 2*t) // ease in and out
+			ps.CurrentCenter = lerp2f(t, tr.FromCenter, tr.ToCenter)
+			ps.Range = lerp(t, tr.FromRange, tr.ToRange)
+		}
+	}
+
+	// Recenter the weather radar once the center has moved a fair
+	// amount, rather than continuously when following an aircraft.
+	if sp.centerTransition == nil && nmdistance2ll(ps.Center, sp.lastWeatherCenter) > 10 {
+		sp.lastWeatherCenter = ps.Center
+		sp.weatherRadar.UpdateCenter(ps.Center)
+	}
+}
+
+// altitudeFilterPresetCommand handles the arguments to the .FILTER
+// command: "SAVE name" saves the current altitude filters and video maps
+// as a preset, "DELETE name" removes one, and otherwise the named preset
+// is applied.
+func (sp *STARSPane) altitudeFilterPresetCommand(args []string) error {
+	ps := &sp.CurrentPreferenceSet
+	find := func(name string) int {
+		return slices.IndexFunc(sp.AltitudeFilterPresets,
+			func(p STARSAltitudeFilterPreset) bool { return p.Name == name })
+	}
+
+	switch args[0] {
+	case "SAVE":
+		if len(args) < 2 {
+			return ErrSTARSCommandFormat
+		}
+		maps := ps.DisplayVideoMap
+		preset := STARSAltitudeFilterPreset{
+			Name:         strings.Join(args[1:], " "),
+			Unassociated: ps.AltitudeFilters.Unassociated,
+			Associated:   ps.AltitudeFilters.Associated,
+			VideoMaps:    &maps,
+		}
+		if idx := find(preset.Name); idx != -1 {
+			sp.AltitudeFilterPresets[idx] = preset
+		} else {
+			sp.AltitudeFilterPresets = append(sp.AltitudeFilterPresets, preset)
+		}
+		return nil
+
+	case "DELETE":
+		if len(args) < 2 {
+			return ErrSTARSCommandFormat
+		}
+		idx := find(strings.Join(args[1:], " "))
+		if idx == -1 {
+			return ErrSTARSIllegalParam
+		}
+		sp.AltitudeFilterPresets = DeleteSliceElement(sp.AltitudeFilterPresets, idx)
+		return nil
+
+	default:
+		// Presets may be selected by either name or 1-based index.
+		idx := find(strings.Join(args, " "))
+		if n, err := strconv.Atoi(args[0]); idx == -1 && err == nil && len(args) == 1 &&
+			n >= 1 && n <= len(sp.AltitudeFilterPresets) {
+			idx = n - 1
+		}
+		if idx == -1 {
+			return ErrSTARSIllegalParam
+		}
+		sp.currentFilterPreset = idx
+		sp.applyAltitudeFilterPreset(sp.AltitudeFilterPresets[idx])
+		return nil
+	}
+}
+
+// cycleAltitudeFilterPreset applies the preset delta after the most
+// recently applied one, wrapping around at the ends, and returns its
+// name.
+func (sp *STARSPane) cycleAltitudeFilterPreset(delta int) (string, error) {
+	n := len(sp.AltitudeFilterPresets)
+	if n == 0 {
+		return "", ErrSTARSIllegalFunction
+	}
+	sp.currentFilterPreset = ((sp.currentFilterPreset+delta)%n + n) % n
+	preset := sp.AltitudeFilterPresets[sp.currentFilterPreset]
+	sp.applyAltitudeFilterPreset(preset)
+	return preset.Name, nil
+}
+
+func (sp *STARSPane) applyAltitudeFilterPreset(preset STARSAltitudeFilterPreset) {
+	ps := &sp.CurrentPreferenceSet
+	ps.AltitudeFilters.Unassociated = preset.Unassociated
+	ps.AltitudeFilters.Associated = preset.Associated
+	if preset.VideoMaps != nil {
+		ps.DisplayVideoMap = *preset.VideoMaps
+	}
+}
+
+// ownershipColor returns the color to use for tracks owned by the given
+// controller if ownership coloring is enabled. Controllers that haven't
+// been explicitly assigned a color are assigned one from the palette
+// according to their position in the sorted list of controllers, so that
+// the assignment is consistent across all of the scopes in a session.
+func (sp *STARSPane) ownershipColor(w *World, controller string) (RGB, bool) {
+	oc := sp.CurrentPreferenceSet.OwnershipColors
+	if !oc.Enabled || controller == "" || controller == w.Callsign {
+		return RGB{}, false
+	}
+
+	if idx, ok := oc.Assigned[controller]; ok {
+		return STARSOwnershipPalette[idx%len(STARSOwnershipPalette)], true
+	}
+
+	idx := slices.Index(SortedMapKeys(w.GetAllControllers()), controller)
+	if idx == -1 {
+		return RGB{}, false
+	}
+	return STARSOwnershipPalette[idx%len(STARSOwnershipPalette)], true
+}
+
+func (sp *STARSPane) drawOwnershipLegend(ctx *PaneContext, paneExtent Extent2D, td *TextDrawBuilder) {
+	ps := sp.CurrentPreferenceSet
+	if !ps.OwnershipColors.Enabled || !ps.OwnershipColors.Legend.Visible {
+		return
+	}
+
+	font := sp.systemFont[ps.CharSize.Lists]
+	p := ps.OwnershipColors.Legend.Position
+	pw := [2]float32{p[0] * paneExtent.Width(), p[1] * paneExtent.Height()}
+
+	td.AddText("OWNERSHIP", pw, TextStyle{Font: font, Color: ps.Brightness.Lists.ScaleRGB(STARSListColor)})
+	for _, callsign := range SortedMapKeys(ctx.world.GetAllControllers()) {
+		color, ok := sp.ownershipColor(ctx.world, callsign)
+		if !ok {
+			continue
+		}
+		ctrl := ctx.world.GetControllerByCallsign(callsign)
+		pw[1] -= float32(font.size)
+		td.AddText(fmt.Sprintf("%4s %s", ctrl.SectorId, ctrl.Callsign), pw,
+			TextStyle{Font: font, Color: ps.Brightness.Lists.ScaleRGB(color)})
+	}
+}
+
 func (sp *STARSPane) drawCRDARegions(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
 	transforms.LoadLatLongViewingMatrices(cb)
 
@@ -5320,6 +6611,28 @@ func (sp *STARSPane) drawCRDARegions(ctx *PaneContext, transforms ScopeTransform
 	}
 }
 
+func (sp *STARSPane) drawAirportDiagrams(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
+	ps := sp.CurrentPreferenceSet
+	if ps.AirportDiagramRange == 0 || ps.Range > float32(ps.AirportDiagramRange) {
+		return
+	}
+
+	runwayColor := ps.Brightness.VideoGroupA.ScaleRGB(STARSMapColor)
+	taxiwayColor := ps.Brightness.VideoGroupB.ScaleRGB(STARSMapColor)
+
+	transforms.LoadLatLongViewingMatrices(cb)
+	cb.LineWidth(1)
+	for _, icao := range SortedMapKeys(ctx.world.Airports) {
+		ap := ctx.world.Airports[icao]
+		// Skip airports that are well off the scope.
+		if nmdistance2ll(ap.Location, ps.CurrentCenter) > 2*ps.Range {
+			continue
+		}
+		ap.GenerateDiagramCommands(icao, ctx.world.NmPerLongitude, ctx.world.MagneticVariation,
+			runwayColor, taxiwayColor, cb)
+	}
+}
+
 func (sp *STARSPane) drawSelectedRoute(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
 	if sp.drawRouteAircraft == "" {
 		return
@@ -5332,16 +6645,71 @@ func (sp *STARSPane) drawSelectedRoute(ctx *PaneContext, transforms ScopeTransfo
 
 	ld := GetLinesDrawBuilder()
 	defer ReturnLinesDrawBuilder(ld)
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	ps := sp.CurrentPreferenceSet
+	color := ps.Brightness.Lines.ScaleRGB(STARSJRingConeColor)
+	style := TextStyle{
+		Font:  sp.systemFont[ps.CharSize.Tools],
+		Color: color,
+	}
 
+	// Draw the remaining route from the aircraft's current position,
+	// labeling the fixes along it.
 	prev := ac.Position()
 	for _, wp := range ac.Nav.Waypoints {
 		ld.AddLine(prev, wp.Location)
 		prev = wp.Location
+		if wp.Fix != "" && wp.Fix[0] != '_' {
+			pw := add2f(transforms.WindowFromLatLongP(wp.Location), [2]float32{5, -5})
+			td.AddText(wp.Fix, pw, style)
+		}
 	}
 
-	ps := sp.CurrentPreferenceSet
 	cb.LineWidth(3)
-	cb.SetRGB(ps.Brightness.Lines.ScaleRGB(STARSJRingConeColor))
+	cb.SetRGB(color)
+	transforms.LoadLatLongViewingMatrices(cb)
+	ld.GenerateCommands(cb)
+	transforms.LoadWindowViewingMatrices(cb)
+	td.GenerateCommands(cb)
+}
+
+// drawHolds draws the holding patterns that aircraft have been cleared
+// to fly and, if enabled, the published holds.
+func (sp *STARSPane) drawHolds(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
+	ps := sp.CurrentPreferenceSet
+	w := ctx.world
+
+	ld := GetColoredLinesDrawBuilder()
+	defer ReturnColoredLinesDrawBuilder(ld)
+
+	if ps.DisplayPublishedHolds {
+		color := ps.Brightness.VideoGroupA.ScaleRGB(STARSMapColor)
+		for _, h := range w.Holds {
+			// Drawn for a typical aircraft flying it at 10,000'.
+			ld.AddLineLoop(color, h.Racetrack(10000, maxHoldingSpeed(10000), w.NmPerLongitude,
+				w.MagneticVariation))
+		}
+	}
+
+	color := ps.Brightness.Lines.ScaleRGB(STARSJRingConeColor)
+	for _, callsign := range SortedMapKeys(w.Aircraft) {
+		ac := w.Aircraft[callsign]
+		fh := ac.Nav.Heading.Hold
+		if fh == nil {
+			if dh := ac.Nav.DeferredHeading; dh != nil {
+				fh = dh.Heading.Hold
+			}
+		}
+		if fh == nil || (fh.Published && ps.DisplayPublishedHolds) {
+			continue
+		}
+		gs := max(ac.Nav.FlightState.GS, 100)
+		ld.AddLineLoop(color, fh.Hold.Racetrack(ac.Altitude(), gs, w.NmPerLongitude, w.MagneticVariation))
+	}
+
+	cb.LineWidth(1)
 	transforms.LoadLatLongViewingMatrices(cb)
 	ld.GenerateCommands(cb)
 }
@@ -5355,7 +6723,9 @@ func (sp *STARSPane) datablockType(ctx *PaneContext, ac *Aircraft) DatablockType
 		dt = PartialDatablock
 	}
 
-	if ac.TrackingController == "" {
+	if ac.TrackingController == "" || ac.BackgroundTraffic {
+		// Background traffic is shown with a limited datablock unless it
+		// is quick looked.
 		dt = LimitedDatablock
 	}
 
@@ -5442,6 +6812,8 @@ func (sp *STARSPane) drawTracks(aircraft []*Aircraft, ctx *PaneContext, transfor
 			if ctrl := ctx.world.GetControllerByCallsign(ac.TrackingController); ctrl != nil {
 				trackId = ctrl.Scope
 			}
+		} else if f := sp.airportFilter(ac); f.Symbol != "" {
+			trackId = f.Symbol
 		}
 
 		// "cheat" by using ac.Heading() if we don't yet have two radar tracks to compute the
@@ -5569,7 +6941,7 @@ func (sp *STARSPane) drawGhosts(ghosts []*GhostAircraft, ctx *PaneContext, trans
 		}
 		w, h := datablockFont.BoundText(datablockText, datablockStyle.LineSpacing)
 		datablockOffset := sp.getDatablockOffset([2]float32{float32(w), float32(h)},
-			ghost.LeaderLineDirection)
+			ghost.LeaderLineDirection, ps.LeaderLineLength)
 
 		// Draw datablock
 		pac := transforms.WindowFromLatLongP(ghost.Position)
@@ -5577,7 +6949,7 @@ func (sp *STARSPane) drawGhosts(ghosts []*GhostAircraft, ctx *PaneContext, trans
 		td.AddText(datablockText, pt, datablockStyle)
 
 		// Leader line
-		v := sp.getLeaderLineVector(ghost.LeaderLineDirection)
+		v := sp.getLeaderLineVector(ghost.LeaderLineDirection, ps.LeaderLineLength)
 		ld.AddLine(pac, add2f(pac, v), color)
 	}
 
@@ -5596,7 +6968,7 @@ func (sp *STARSPane) drawRadarTrack(ac *Aircraft, state *STARSAircraftState, hea
 	pos := state.TrackPosition()
 	pw := transforms.WindowFromLatLongP(pos)
 	// On high DPI windows displays we need to scale up the tracks
-	scale := Select(runtime.GOOS == "windows", ctx.platform.DPIScale(), float32(1))
+	scale := ctx.platform.WindowScale()
 
 	primaryTargetBrightness := ps.Brightness.PrimarySymbols
 	if primaryTargetBrightness > 0 {
@@ -5604,6 +6976,7 @@ func (sp *STARSPane) drawRadarTrack(ac *Aircraft, state *STARSAircraftState, hea
 		case RadarModeSingle:
 			site := ctx.world.RadarSites[ps.RadarSiteSelected]
 			primary, secondary, dist := site.CheckVisibility(ctx.world, pos, state.TrackAltitude())
+			primary, secondary = sp.radarReturns(ac, primary, secondary)
 
 			// Orient the box toward the radar
 			h := headingp2ll(site.Position, pos, ctx.world.NmPerLongitude, ctx.world.MagneticVariation)
@@ -5640,6 +7013,7 @@ func (sp *STARSPane) drawRadarTrack(ac *Aircraft, state *STARSAircraftState, hea
 
 		case RadarModeMulti:
 			primary, secondary, _ := sp.radarVisibility(ctx.world, pos, state.TrackAltitude())
+			primary, secondary = sp.radarReturns(ac, primary, secondary)
 			rot := rotator2f(heading)
 
 			// blue box: x +/-9 pixels, y +/-3 pixels
@@ -5706,7 +7080,22 @@ func (sp *STARSPane) drawRadarTrack(ac *Aircraft, state *STARSAircraftState, hea
 		}
 	}
 
-	if ps.Brightness.History > 0 { // Don't draw if brightness == 0.
+	if ps.Brightness.History > 0 && ps.HistoryTrails.Enabled {
+		// Fade from the history color to the background with age, not
+		// including the most recent sample, which is at the current
+		// position.
+		now := ctx.world.CurrentTime()
+		length := 60 * ps.HistoryTrails.Length
+		trailColor := ps.Brightness.History.ScaleRGB(STARSTrackHistoryColors[0])
+		bgColor := ps.Brightness.BackgroundContrast.ScaleRGB(STARSBackgroundColor)
+		for _, t := range state.historyTrail {
+			if age := float32(now.Sub(t.Time).Seconds()); age > 0 && age < length && t.Time.Before(state.track.Time) {
+				const historyTrailDiameter = 6
+				drawTrack(ctx, historyBuilder, transforms.WindowFromLatLongP(t.Position), historyTrailDiameter,
+					lerpRGB(age/length, trailColor, bgColor))
+			}
+		}
+	} else if ps.Brightness.History > 0 { // Don't draw if brightness == 0.
 		// Draw history from new to old
 		for i := range ps.RadarTrackHistory {
 			trackColorNum := min(i, len(STARSTrackHistoryColors)-1)
@@ -5741,7 +7130,7 @@ func drawTrack(ctx *PaneContext, ctd *ColoredTrianglesDrawBuilder, p [2]float32,
 
 	// Scale the points based on the circle radius (and deal with the usual
 	// Windows high-DPI borkage...)
-	scale := Select(runtime.GOOS == "windows", ctx.platform.DPIScale(), float32(1))
+	scale := ctx.platform.WindowScale()
 	radius := scale * float32(int(diameter/2+0.5)) // round to integer
 	pts = MapSlice(pts, func(p [2]float32) [2]float32 { return scale2f(p, radius) })
 
@@ -5781,9 +7170,10 @@ func (sp *STARSPane) getDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDatabl
 	return dbs
 }
 
-func (sp *STARSPane) getDatablockOffset(textBounds [2]float32, leaderDir CardinalOrdinalDirection) [2]float32 {
+func (sp *STARSPane) getDatablockOffset(textBounds [2]float32, leaderDir CardinalOrdinalDirection,
+	leaderLength int) [2]float32 {
 	// To place the datablock, start with the vector for the leader line.
-	drawOffset := sp.getLeaderLineVector(leaderDir)
+	drawOffset := sp.getLeaderLineVector(leaderDir, leaderLength)
 
 	// And now fine-tune so that the leader line connects with the midpoint
 	// of the line that includes the callsign.
@@ -5844,18 +7234,55 @@ func (sp *STARSPane) updateCAAircraft(w *World, aircraft []*Aircraft) {
 		return false
 	}
 
+	// Extrapolate both tracks along their current paths and vertical
+	// speeds to see if they will lose separation during the look-ahead
+	// period.
+	predictedConflict := func(sa, sb *STARSAircraftState) bool {
+		if !sa.HaveHeading() || !sb.HaveHeading() {
+			return false
+		}
+
+		nmPerLongitude, magvar := w.NmPerLongitude, w.MagneticVariation
+		pa, pb := ll2nm(sa.TrackPosition(), nmPerLongitude), ll2nm(sb.TrackPosition(), nmPerLongitude)
+		// nm per minute
+		va := ll2nm(sa.HeadingVector(nmPerLongitude, magvar), nmPerLongitude)
+		vb := ll2nm(sb.HeadingVector(nmPerLongitude, magvar), nmPerLongitude)
+		ra, rb := sa.TrackAltitudeRate(), sb.TrackAltitudeRate()
+
+		for t := float32(5); t <= CALookaheadSeconds; t += 5 {
+			qa, qb := add2f(pa, scale2f(va, t/60)), add2f(pb, scale2f(vb, t/60))
+			alta, altb := float32(sa.TrackAltitude())+ra*t, float32(sb.TrackAltitude())+rb*t
+			if distance2f(qa, qb) <= LateralMinimum && abs(alta-altb) <= VerticalMinimum-5 {
+				return true
+			}
+		}
+		return false
+	}
+
 	conflicting := func(callsigna, callsignb string) bool {
 		sa, sb := sp.Aircraft[callsigna], sp.Aircraft[callsignb]
 		if sa.DisableCAWarnings || sb.DisableCAWarnings {
 			return false
 		}
-		if inCAVolumes(sa) || inCAVolumes(sb) {
+		if inCAVolumes(sa) || inCAVolumes(sb) {
+			return false
+		}
+
+		// Quickly cull pairs that are too far apart to come into
+		// conflict during the look-ahead period.
+		d := nmdistance2ll(sa.TrackPosition(), sb.TrackPosition())
+		closing := float32(sa.TrackGroundspeed()+sb.TrackGroundspeed()) * CALookaheadSeconds / 3600
+		if d > LateralMinimum+closing {
+			return false
+		}
+
+		if sp.diverging(w.Aircraft[callsigna], w.Aircraft[callsignb]) {
 			return false
 		}
-		return nmdistance2ll(sa.TrackPosition(), sb.TrackPosition()) <= LateralMinimum &&
+		return (d <= LateralMinimum &&
 			/*small slop for fp error*/
-			abs(sa.TrackAltitude()-sb.TrackAltitude()) <= VerticalMinimum-5 &&
-			!sp.diverging(w.Aircraft[callsigna], w.Aircraft[callsignb])
+			abs(sa.TrackAltitude()-sb.TrackAltitude()) <= VerticalMinimum-5) ||
+			predictedConflict(sa, sb)
 	}
 
 	// Remove ones that are no longer conflicting
@@ -6055,56 +7482,60 @@ func getCwtCategory(ac *Aircraft) string {
 
 }
 
-func (sp *STARSPane) checkInTrailCwtSeparation(back, front *Aircraft) {
-	cwtClass := func(ac *Aircraft) int {
-		perf, ok := database.AircraftPerformance[ac.FlightPlan.BaseType()]
-		if !ok {
-			lg.Errorf("%s: unable to get performance model for %s", ac.Callsign, ac.FlightPlan.BaseType())
-			return 9
-		}
-		wc := perf.Category.CWT
-		if len(wc) == 0 {
-			lg.Errorf("%s: no CWT category found for %s", ac.Callsign, ac.FlightPlan.BaseType())
-			return 9
-		}
-		switch wc[0] {
-		case 'I':
-			return 0
-		case 'H':
-			return 1
-		case 'G':
-			return 2
-		case 'F':
-			return 3
-		case 'E':
-			return 4
-		case 'D':
-			return 5
-		case 'C':
-			return 6
-		case 'B':
-			return 7
-		case 'A':
-			return 8
-		default:
-			lg.Errorf("%s: unexpected weight class \"%c\"", ac.Callsign, wc[0])
-			return 9
-		}
-	}
-	// 7110.126B TBL 5-5-2
-	// 0 value means minimum radar separation
-	cwtOnApproachLookUp := [10][10]float32{ // [front][back]
-		{0, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind I
-		{0, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind H
-		{0, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind G
-		{4, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind F
-		{4, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind E
-		{6, 6, 5, 5, 5, 4, 4, 3, 0, 10},          // Behind D
-		{6, 5, 3.5, 3.5, 3.5, 0, 0, 0, 0, 10},    // Behind C
-		{6, 5, 5, 5, 5, 4, 4, 3, 0, 10},          // Behind B
-		{8, 8, 7, 7, 7, 6, 6, 5, 0, 10},          // Behind A
-		{10, 10, 10, 10, 10, 10, 10, 10, 10, 10}, // Behind NOWGT (No weight: 7110.762)
+// cwtClass returns the index of the aircraft's consolidated wake
+// turbulence (CWT) category in cwtOnApproachLookUp.
+func cwtClass(ac *Aircraft) int {
+	perf, ok := database.AircraftPerformance[ac.FlightPlan.BaseType()]
+	if !ok {
+		lg.Errorf("%s: unable to get performance model for %s", ac.Callsign, ac.FlightPlan.BaseType())
+		return 9
+	}
+	wc := perf.Category.CWT
+	if len(wc) == 0 {
+		lg.Errorf("%s: no CWT category found for %s", ac.Callsign, ac.FlightPlan.BaseType())
+		return 9
+	}
+	switch wc[0] {
+	case 'I':
+		return 0
+	case 'H':
+		return 1
+	case 'G':
+		return 2
+	case 'F':
+		return 3
+	case 'E':
+		return 4
+	case 'D':
+		return 5
+	case 'C':
+		return 6
+	case 'B':
+		return 7
+	case 'A':
+		return 8
+	default:
+		lg.Errorf("%s: unexpected weight class \"%c\"", ac.Callsign, wc[0])
+		return 9
 	}
+}
+
+// 7110.126B TBL 5-5-2
+// 0 value means minimum radar separation
+var cwtOnApproachLookUp = [10][10]float32{ // [front][back]
+	{0, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind I
+	{0, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind H
+	{0, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind G
+	{4, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind F
+	{4, 0, 0, 0, 0, 0, 0, 0, 0, 10},          // Behind E
+	{6, 6, 5, 5, 5, 4, 4, 3, 0, 10},          // Behind D
+	{6, 5, 3.5, 3.5, 3.5, 0, 0, 0, 0, 10},    // Behind C
+	{6, 5, 5, 5, 5, 4, 4, 3, 0, 10},          // Behind B
+	{8, 8, 7, 7, 7, 6, 6, 5, 0, 10},          // Behind A
+	{10, 10, 10, 10, 10, 10, 10, 10, 10, 10}, // Behind NOWGT (No weight: 7110.762)
+}
+
+func (sp *STARSPane) checkInTrailCwtSeparation(back, front *Aircraft) {
 	cwtSeparation := cwtOnApproachLookUp[cwtClass(front)][cwtClass(back)]
 
 	state := sp.Aircraft[back.Callsign]
@@ -6205,6 +7636,9 @@ func (sp *STARSPane) getWarnings(ctx *PaneContext, ac *Aircraft) []string {
 	for code := range ac.SPCOverrides {
 		warnings[code] = nil
 	}
+	if state.UnableCrossingFix != "" {
+		warnings["XR"] = nil
+	}
 	if !ps.DisableCAWarnings && !state.DisableCAWarnings &&
 		slices.ContainsFunc(sp.CAAircraft,
 			func(ca CAAircraft) bool {
@@ -6340,7 +7774,9 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 
 		field1 := [2]string{}
 		field1[0] = alt
-		if ac.Scratchpad != "" {
+		if ctx.world.STARSFacilityAdaptation.Timeshare.HoldDuringHandoff && ac.HandoffTrackController != "" {
+			field1[1] = alt
+		} else if ac.Scratchpad != "" {
 			field1[1] = sp
 		} else if airport := ctx.world.GetAirport(ac.FlightPlan.ArrivalAirport); airport != nil && !airport.OmitArrivalScratchpad {
 			field1[1] = ap
@@ -6355,7 +7791,7 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 
 	case FullDatablock:
 		// Line 1: fields 1, 2, and 8 (surprisingly). Field 8 may be multiplexed.
-		field1 := ac.Callsign
+		field1 := ac.Callsign + sp.airportFilter(ac).Symbol
 
 		field2 := ""
 		if state.InhibitMSAW || state.DisableMSAW {
@@ -6369,40 +7805,60 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 		}
 
 		field8 := []string{""}
-		if _, ok := sp.InboundPointOuts[ac.Callsign]; ok || state.PointedOut {
+		if id, ok := sp.InboundPointOuts[ac.Callsign]; ok {
+			// Pending until it is accepted (slew) or rejected (UN slew);
+			// the datablock flashes until then.
+			field8 = []string{" PO" + id}
+		} else if state.PointedOut {
+			// Accepted; shown until it is cleared with a slew.
 			field8 = []string{" PO"}
 		} else if id, ok := sp.OutboundPointOuts[ac.Callsign]; ok {
 			field8 = []string{" PO" + id}
 		} else if _, ok := sp.RejectedPointOuts[ac.Callsign]; ok {
 			field8 = []string{"", " UN"}
-		} else if time.Until(state.POFlashingEndTime) > 0*time.Second {
-			field8 = []string{"", " PO"}
 		} else if ac.RedirectedHandoff.ShowRDIndicator(ctx.world.Callsign, state.RDIndicatorEnd) {
 			field8 = []string{" RD"}
 		}
 
+		if sp.CurrentPreferenceSet.EnRouteDatablocks {
+			return sp.formatEnRouteDatablocks(ctx, ac, state, baseDB, field1+field2, field8)
+		}
+
 		// Line 2: fields 3, 4, 5
 		alt := fmt.Sprintf("%03d", (state.TrackAltitude()+50)/100)
-		if state.LostTrack(ctx.world.CurrentTime()) {
+		if state.Coasting(ctx.world.CurrentTime()) {
 			alt = "CST"
 		}
 		// Build up field3 and field4 in tandem because 4 gets a "+" if 3
 		// is displaying the secondary scratchpad.  Leave the empty string
 		// as a placeholder in field 4 otherwise.
-		field3 := []string{alt}
-		field4 := []string{""}
-		if !state.Ident() {
-			// Don't display these if they're identing: then it's just altitude and speed + "ID"
-			if ac.Scratchpad != "" {
-				field3 = append(field3, ac.Scratchpad)
+		//
+		// The items that are timeshared in fields 3 and 5 and their
+		// order are given by the facility adaptation.
+		timeshare := ctx.world.STARSFacilityAdaptation.Timeshare
+		// Don't rotate if they're identing: then it's just altitude and
+		// speed + "ID". Some facilities also hold the fields during
+		// handoffs.
+		hold := state.Ident() || (timeshare.HoldDuringHandoff && ac.HandoffTrackController != "")
+		var field3, field4 []string
+		for _, item := range timeshare.Field3 {
+			switch item {
+			case "altitude":
+				field3 = append(field3, alt)
 				field4 = append(field4, "")
-			}
-			if ac.SecondaryScratchpad != "" {
-				field3 = append(field3, ac.SecondaryScratchpad)
-				field4 = append(field4, "+") // 2-67, "Field 4 Contents"
-			}
-			if len(field3) == 1 {
-				if ap := ctx.world.GetAirport(ac.FlightPlan.ArrivalAirport); ap != nil && !ap.OmitArrivalScratchpad {
+			case "scratchpad":
+				if ac.Scratchpad != "" && !hold {
+					field3 = append(field3, ac.Scratchpad)
+					field4 = append(field4, "")
+				}
+			case "secondary_scratchpad":
+				if ac.SecondaryScratchpad != "" && !hold {
+					field3 = append(field3, ac.SecondaryScratchpad)
+					field4 = append(field4, "+") // 2-67, "Field 4 Contents"
+				}
+			case "destination":
+				if ap := ctx.world.GetAirport(ac.FlightPlan.ArrivalAirport); ac.Scratchpad == "" &&
+					ac.SecondaryScratchpad == "" && !hold && ap != nil && !ap.OmitArrivalScratchpad {
 					ap := ac.FlightPlan.ArrivalAirport
 					if len(ap) == 4 {
 						ap = ap[1:] // drop the leading K
@@ -6412,6 +7868,11 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 				}
 			}
 		}
+		if len(field3) == 0 {
+			// Scenarios from older servers may not have a timeshare
+			// configuration.
+			field3, field4 = []string{alt}, []string{""}
+		}
 
 		// Fill in empty field4 entries.
 		for i := range field4 {
@@ -6456,6 +7917,9 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 			if strings.Index(actype, "/") == 1 {
 				actype = actype[2:]
 			}
+			if sp.DisplayDatalinkEquipage && ac.DatalinkEquipped() {
+				actype += "D"
+			}
 			modifier := ""
 			if ac.FlightPlan.Rules == VFR {
 				modifier += "V"
@@ -6467,12 +7931,23 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 			cat := getCwtCategory(ac)
 			acCategory = modifier + cat
 
-			field5 = append(field5, speed+acCategory)
-
-			field5 = append(field5, actype)
-			if (state.DisplayRequestedAltitude != nil && *state.DisplayRequestedAltitude) ||
-				(state.DisplayRequestedAltitude == nil && sp.CurrentPreferenceSet.DisplayRequestedAltitude) {
-				field5 = append(field5, fmt.Sprintf("R%03d", ac.FlightPlan.Altitude/100))
+			for _, item := range timeshare.Field5 {
+				switch item {
+				case "speed":
+					field5 = append(field5, speed+acCategory)
+				case "type":
+					if !hold {
+						field5 = append(field5, actype)
+					}
+				case "requested_altitude":
+					if !hold && ((state.DisplayRequestedAltitude != nil && *state.DisplayRequestedAltitude) ||
+						(state.DisplayRequestedAltitude == nil && sp.CurrentPreferenceSet.DisplayRequestedAltitude)) {
+						field5 = append(field5, fmt.Sprintf("R%03d", ac.FlightPlan.Altitude/100))
+					}
+				}
+			}
+			if len(field5) == 0 {
+				field5 = []string{speed + acCategory, actype}
 			}
 		}
 		for i := range field5 {
@@ -6502,6 +7977,12 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 				}
 			}
 		}
+		if field6 == "" {
+			// Flag aircraft that haven't filed the preferential route.
+			if pr, ok := ctx.world.RouteConformance(ac); !ok {
+				field6 = pr.Type
+			}
+		}
 		for len(field6) < 5 {
 			field6 += " "
 		}
@@ -6539,6 +8020,22 @@ func (sp *STARSPane) formatDatablocks(ctx *PaneContext, ac *Aircraft) []STARSDat
 			}
 			dbs = append(dbs, db)
 		}
+
+		// User-specified line formats replace the standard ones, which
+		// they may include via {standard}; each of the time-shared
+		// datablocks gets its own. Unknown fields are left as is, which
+		// makes them visible on the scope.
+		if tmpl := sp.CurrentPreferenceSet.DatablockTemplate; tmpl.Enabled {
+			fields := sp.datablockTemplateFieldValues(ctx, ac, state)
+			for i, t := range tmpl.Lines {
+				if t == "" {
+					continue
+				}
+				for j := range dbs {
+					applyDatablockTemplate(&dbs[j].Lines[i+1], t, fields)
+				}
+			}
+		}
 		return dbs
 	}
 
@@ -6616,6 +8113,9 @@ func (sp *STARSPane) datablockColor(ctx *PaneContext, ac *Aircraft) (color RGB,
 		func(q QuickLookPosition) bool { return q.Callsign == ac.TrackingController && q.Plus }) {
 		// individual quicklook plus controller
 		color = STARSTrackedAircraftColor
+	} else if oc, ok := sp.ownershipColor(w, ac.TrackingController); ok {
+		// owned by another controller and ownership coloring is enabled
+		color = oc
 	} else {
 		// green otherwise
 		color = STARSUntrackedAircraftColor
@@ -6643,7 +8143,8 @@ func (sp *STARSPane) drawLeaderLines(aircraft []*Aircraft, ctx *PaneContext, tra
 
 		baseColor, brightness := sp.datablockColor(ctx, ac)
 		pac := transforms.WindowFromLatLongP(state.TrackPosition())
-		v := sp.getLeaderLineVector(sp.getLeaderLineDirection(ac, ctx.world))
+		dir := sp.getDisplayedLeaderLineDirection(ctx, ac, dbs[0], pac)
+		v := sp.getLeaderLineVector(dir, sp.getLeaderLineLength(ac))
 		ld.AddLine(pac, add2f(pac, v), brightness.ScaleRGB(baseColor))
 	}
 
@@ -6661,6 +8162,7 @@ func (sp *STARSPane) drawDatablocks(aircraft []*Aircraft, ctx *PaneContext,
 	realNow := time.Now() // for flashing rate...
 	ps := sp.CurrentPreferenceSet
 	font := sp.systemFont[ps.CharSize.Datablocks]
+	period := ctx.world.STARSFacilityAdaptation.Timeshare.CyclePeriod()
 
 	for _, ac := range aircraft {
 		state := sp.Aircraft[ac.Callsign]
@@ -6681,14 +8183,14 @@ func (sp *STARSPane) drawDatablocks(aircraft []*Aircraft, ctx *PaneContext,
 		// Compute the bounds of the datablock; always use the first one so
 		// things don't jump around when it switches between multiple of
 		// them.
+		pac := transforms.WindowFromLatLongP(state.TrackPosition())
 		w, h := dbs[0].BoundText(font)
 		datablockOffset := sp.getDatablockOffset([2]float32{float32(w), float32(h)},
-			sp.getLeaderLineDirection(ac, ctx.world))
+			sp.getDisplayedLeaderLineDirection(ctx, ac, dbs[0], pac), sp.getLeaderLineLength(ac))
 
 		// Draw characters starting at the upper left.
-		pac := transforms.WindowFromLatLongP(state.TrackPosition())
 		pt := add2f(datablockOffset, pac)
-		idx := (realNow.Second() / 2) % len(dbs) // 2 second cycle
+		idx := int(realNow.UnixMilli()/period.Milliseconds()) % len(dbs)
 		dbs[idx].DrawText(td, pt, font, color, brightness)
 	}
 
@@ -6945,6 +8447,190 @@ func (sp *STARSPane) drawRBLs(aircraft []*Aircraft, ctx *PaneContext, transforms
 	td.GenerateCommands(cb)
 }
 
+// drawOffscreenIndicators draws arrows at the edge of the scope pointing
+// toward aircraft that need attention--unacknowledged conflict alerts and
+// MSAWs, inbound point outs, and handoffs to us--but that are outside of
+// the displayed area. Each is labeled with the callsign and the distance
+// from the scope's center.
+func (sp *STARSPane) drawOffscreenIndicators(aircraft []*Aircraft, ctx *PaneContext, paneExtent Extent2D,
+	transforms ScopeTransformations, cb *CommandBuffer) {
+	ps := sp.CurrentPreferenceSet
+	inCA := func(callsign string) bool {
+		return slices.ContainsFunc(sp.CAAircraft, func(ca CAAircraft) bool {
+			return !ca.Acknowledged && (ca.Callsigns[0] == callsign || ca.Callsigns[1] == callsign)
+		})
+	}
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+	trid := GetColoredTrianglesDrawBuilder()
+	defer ReturnColoredTrianglesDrawBuilder(trid)
+
+	width, height := paneExtent.Width(), paneExtent.Height()
+	center := [2]float32{width / 2, height / 2}
+	const inset = 24 // pixels from the edge of the scope
+
+	for _, ac := range aircraft {
+		state := sp.Aircraft[ac.Callsign]
+		var color RGB
+		if _, ok := sp.InboundPointOuts[ac.Callsign]; ok {
+			color = STARSInboundPointOutColor
+		} else if ac.HandoffTrackController == ctx.world.Callsign {
+			color = STARSTrackedAircraftColor
+		}
+		if (inCA(ac.Callsign) && !ps.DisableCAWarnings) || (state.MSAW && !state.MSAWAcknowledged && !ps.DisableMSAW) {
+			color = STARSTextAlertColor
+		}
+		if color == (RGB{}) {
+			continue
+		}
+		color = ps.Brightness.FullDatablocks.ScaleRGB(color)
+
+		p := transforms.WindowFromLatLongP(state.TrackPosition())
+		if p[0] >= 0 && p[0] < width && p[1] >= 0 && p[1] < height {
+			continue
+		}
+
+		// Find where the line from the center of the scope to the
+		// aircraft crosses the inset boundary.
+		d := sub2f(p, center)
+		t := min(abs((center[0]-inset)/d[0]), abs((center[1]-inset)/d[1]))
+		e := add2f(center, scale2f(d, t))
+
+		v := normalize2f(d)
+		perp := [2]float32{-v[1], v[0]}
+		trid.AddTriangle(add2f(e, scale2f(v, 10)), add2f(e, scale2f(perp, 6)), add2f(e, scale2f(perp, -6)), color)
+
+		dist := nmdistance2ll(ps.CurrentCenter, state.TrackPosition())
+		style := TextStyle{Font: sp.systemFont[ps.CharSize.Tools], Color: color}
+		td.AddTextCentered(fmt.Sprintf("%s\n%d", ac.Callsign, int(dist+0.5)), sub2f(e, scale2f(v, 20)), style)
+	}
+
+	transforms.LoadWindowViewingMatrices(cb)
+	trid.GenerateCommands(cb)
+	td.GenerateCommands(cb)
+}
+
+func (sp *STARSPane) drawTethers(aircraft []*Aircraft, ctx *PaneContext, transforms ScopeTransformations,
+	cb *CommandBuffer) {
+	// Remove tethers where one of the aircraft is no longer visible.
+	sp.Tethers = FilterSlice(sp.Tethers, func(t STARSTether) bool {
+		return slices.ContainsFunc(aircraft, func(ac *Aircraft) bool { return ac.Callsign == t.Callsigns[0] }) &&
+			slices.ContainsFunc(aircraft, func(ac *Aircraft) bool { return ac.Callsign == t.Callsigns[1] })
+	})
+	if len(sp.Tethers) == 0 {
+		return
+	}
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+	ld := GetLinesDrawBuilder()
+	defer ReturnLinesDrawBuilder(ld)
+
+	ps := sp.CurrentPreferenceSet
+	color := ps.Brightness.Lines.ScaleRGB(STARSJRingConeColor)
+	style := TextStyle{
+		Font:           sp.systemFont[ps.CharSize.Tools],
+		Color:          color,
+		DrawBackground: true, // default BackgroundColor is fine
+	}
+
+	for _, t := range sp.Tethers {
+		p0 := sp.Aircraft[t.Callsigns[0]].TrackPosition()
+		p1 := sp.Aircraft[t.Callsigns[1]].TrackPosition()
+		ld.AddLine(p0, p1)
+
+		dist, closure := t.Measure(sp, ctx.world.NmPerLongitude, ctx.world.MagneticVariation)
+		text := fmt.Sprintf("%.2f %+d", dist, int(closure))
+		td.AddTextCentered(text, transforms.WindowFromLatLongP(mid2ll(p0, p1)), style)
+	}
+
+	cb.LineWidth(1)
+	cb.SetRGB(color)
+	transforms.LoadLatLongViewingMatrices(cb)
+	ld.GenerateCommands(cb)
+	transforms.LoadWindowViewingMatrices(cb)
+	td.GenerateCommands(cb)
+}
+
+// updateCoach periodically sends the coach's cursor position and
+// highlighted aircraft to the server.
+func (sp *STARSPane) updateCoach(ctx *PaneContext, paneExtent Extent2D, transforms ScopeTransformations) {
+	if time.Since(sp.lastCoachUpdate) < 200*time.Millisecond {
+		return
+	}
+	m := ctx.mouse
+	inside := m != nil && m.Pos[0] >= 0 && m.Pos[0] < paneExtent.Width() && m.Pos[1] >= 0 && m.Pos[1] < paneExtent.Height()
+	if !inside && !sp.lastCoachUpdate.IsZero() && !sp.primaryScope() {
+		// With multiple scopes, the cursor comes from the one the mouse
+		// is in, or the primary scope if it isn't in any of them.
+		return
+	}
+	sp.lastCoachUpdate = time.Now()
+
+	cs := CoachState{Highlighted: slices.Clone(sp.coachHighlights)}
+	if inside {
+		cs.Cursor = transforms.LatLongFromWindowP(m.Pos)
+	}
+
+	ctx.world.SetCoachState(cs)
+}
+
+// drawCoach draws circles around the aircraft that a coach has
+// highlighted and, for the controller being coached, the coach's cursor.
+func (sp *STARSPane) drawCoach(aircraft []*Aircraft, ctx *PaneContext, paneExtent Extent2D,
+	transforms ScopeTransformations, cb *CommandBuffer) {
+	highlighted := sp.coachHighlights
+	var cursor Point2LL
+	if !ctx.world.IsCoach {
+		if ctx.world.CoachState == nil {
+			return
+		}
+		highlighted, cursor = ctx.world.CoachState.Highlighted, ctx.world.CoachState.Cursor
+	}
+
+	ps := sp.CurrentPreferenceSet
+	color := ps.Brightness.FullDatablocks.ScaleRGB(STARSCoachColor)
+
+	if ctx.world.CoachHasControl() {
+		// Make it clear to both whose commands are being accepted.
+		td := GetTextDrawBuilder()
+		defer ReturnTextDrawBuilder(td)
+		style := TextStyle{
+			Font:           sp.systemFont[ps.CharSize.Tools],
+			Color:          color,
+			DrawBackground: true,
+		}
+		text := Select(ctx.world.IsCoach, "COACH CONTROL", "COACH HAS CONTROL")
+		td.AddTextCentered(text, [2]float32{paneExtent.Width() / 2, paneExtent.Height() - 20}, style)
+		transforms.LoadWindowViewingMatrices(cb)
+		td.GenerateCommands(cb)
+	}
+	ld := GetColoredLinesDrawBuilder()
+	defer ReturnColoredLinesDrawBuilder(ld)
+
+	for _, ac := range aircraft {
+		if slices.Contains(highlighted, ac.Callsign) {
+			if state, ok := sp.Aircraft[ac.Callsign]; ok && !state.LostTrack(ctx.world.CurrentTime()) {
+				p := transforms.WindowFromLatLongP(state.TrackPosition())
+				ld.AddCircle(p, 15, 32, color)
+			}
+		}
+	}
+
+	if !cursor.IsZero() {
+		p := transforms.WindowFromLatLongP(cursor)
+		w := float32(9)
+		ld.AddLine(add2f(p, [2]float32{-w, 0}), add2f(p, [2]float32{w, 0}), color)
+		ld.AddLine(add2f(p, [2]float32{0, -w}), add2f(p, [2]float32{0, w}), color)
+		ld.AddCircle(p, w/2, 16, color)
+	}
+
+	transforms.LoadWindowViewingMatrices(cb)
+	cb.LineWidth(2)
+	ld.GenerateCommands(cb)
+}
+
 // Draw the minimum separation line between two aircraft, if selected.
 func (sp *STARSPane) drawMinSep(ctx *PaneContext, transforms ScopeTransformations, cb *CommandBuffer) {
 	cs0, cs1 := sp.MinSepAircraft[0], sp.MinSepAircraft[1]
@@ -7032,6 +8718,44 @@ func (sp *STARSPane) consumeMouseEvents(ctx *PaneContext, ghosts []*GhostAircraf
 	mouse := ctx.mouse
 	ps := &sp.CurrentPreferenceSet
 
+	if ps.DisplayOverview && mouse.Clicked[MouseButtonPrimary] && sp.overviewExtent.Inside(mouse.Pos) {
+		// Jump the main view to the clicked location in the overview.
+		ot := sp.getOverviewTransformations(ctx)
+		sp.followAircraft = ""
+		sp.transitionCenter(ot.LatLongFromWindowP(sub2f(mouse.Pos, sp.overviewExtent.p0)), ps.Range)
+		wmTakeKeyboardFocus(sp, false)
+		return
+	}
+
+	if ctx.world.scenarioEditor.Capturing() && mouse.Clicked[MouseButtonPrimary] {
+		// Add the clicked fix to the route being built in the scenario
+		// editor (fixes within 10 pixels are considered) or use the point
+		// to define a fix or volume.
+		p := transforms.LatLongFromWindowP(mouse.Pos)
+		tolerance := nmdistance2ll(p, transforms.LatLongFromWindowP(add2f(mouse.Pos, [2]float32{10, 0})))
+		ctx.world.scenarioEditor.ScopeClicked(ctx.world, p, tolerance)
+		return
+	}
+	if ctx.world.scenarioEditor.EditingRegion() && ctx.world.scenarioEditor.RegionMouse(ctx.world, mouse, transforms) {
+		return
+	}
+
+	if ctx.world.IsCoach && !ctx.world.CoachHasControl() && mouse.Clicked[MouseButtonPrimary] {
+		// Coaches can't issue commands; clicking on an aircraft toggles
+		// whether it's highlighted for the controller being coached.
+		if ac, _ := sp.tryGetClosestAircraft(ctx.world, mouse.Pos, transforms); ac != nil {
+			// The change is applied when the event comes back so that all
+			// of the scopes stay in sync.
+			sp.events.PostEvent(Event{
+				Type:     HighlightedAircraftEvent,
+				Callsign: ac.Callsign,
+				Selected: !slices.Contains(sp.coachHighlights, ac.Callsign),
+			})
+		}
+		wmTakeKeyboardFocus(sp, false)
+		return
+	}
+
 	if ctx.mouse.Clicked[MouseButtonPrimary] && !ctx.haveFocus {
 		if ac, _ := sp.tryGetClosestAircraft(ctx.world, ctx.mouse.Pos, transforms); ac != nil {
 			sp.events.PostEvent(Event{Type: TrackClickedEvent, Callsign: ac.Callsign})
@@ -7048,6 +8772,9 @@ func (sp *STARSPane) consumeMouseEvents(ctx *PaneContext, ghosts []*GhostAircraf
 		if mouse.Dragging[MouseButtonSecondary] {
 			delta := mouse.DragDelta
 			if delta[0] != 0 || delta[1] != 0 {
+				// Manual positioning cancels automatic centering.
+				sp.followAircraft = ""
+				sp.centerTransition = nil
 				deltaLL := transforms.LatLongFromWindowV(delta)
 				ps.CurrentCenter = sub2f(ps.CurrentCenter, deltaLL)
 			}
@@ -7055,6 +8782,7 @@ func (sp *STARSPane) consumeMouseEvents(ctx *PaneContext, ghosts []*GhostAircraf
 
 		// Consume mouse wheel
 		if mouse.Wheel[1] != 0 {
+			sp.centerTransition = nil
 			r := ps.Range
 			if _, ok := ctx.keyboard.Pressed[KeyControl]; ok {
 				ps.Range += 3 * mouse.Wheel[1]
@@ -7084,11 +8812,26 @@ func (sp *STARSPane) consumeMouseEvents(ctx *PaneContext, ghosts []*GhostAircraf
 			ctx.platform.GetClipboard().SetText(strings.ReplaceAll(mouseLatLong.DMSString(), " ", ""))
 		}
 
+		if ctx.keyboard != nil && ctx.keyboard.IsPressed(KeyAlt) {
+			// Alt-click -> display the point as a fix-radial-distance from
+			// the closest navaid, or from the fix that has been entered,
+			// and copy it to the clipboard.
+			p := transforms.LatLongFromWindowP(ctx.mouse.Pos)
+			if frd, err := sp.fixRadialDistance(ctx.world, p, strings.TrimSpace(sp.previewAreaInput)); err != nil {
+				sp.displayError(err)
+			} else {
+				ctx.platform.GetClipboard().SetText(frd)
+				sp.resetInputState()
+				sp.previewAreaOutput = frd
+			}
+			return
+		}
+
 		if ctx.keyboard != nil && ctx.keyboard.IsPressed(KeyControl) && !ctx.keyboard.IsPressed(KeyShift) { // There is a conflict between this and initating a track CRC-style,
 			// so making sure that shift isn't being pressed would be a good idea.
 			if ac, _ := sp.tryGetClosestAircraft(ctx.world, ctx.mouse.Pos, transforms); ac != nil {
 				if state := sp.Aircraft[ac.Callsign]; state != nil {
-					state.IsSelected = !state.IsSelected
+					sp.selectAircraft(ac.Callsign, !state.IsSelected)
 					return
 				}
 			}
@@ -7114,7 +8857,7 @@ func (sp *STARSPane) consumeMouseEvents(ctx *PaneContext, ghosts []*GhostAircraf
 	} else if ctx.mouse.Clicked[MouseButtonTertiary] {
 		if ac, _ := sp.tryGetClosestAircraft(ctx.world, ctx.mouse.Pos, transforms); ac != nil {
 			if state := sp.Aircraft[ac.Callsign]; state != nil {
-				state.IsSelected = !state.IsSelected
+				sp.selectAircraft(ac.Callsign, !state.IsSelected)
 			}
 		}
 	} else if !ctx.world.SimIsPaused {
@@ -7177,6 +8920,81 @@ func (sp *STARSPane) consumeMouseEvents(ctx *PaneContext, ghosts []*GhostAircraf
 	}
 }
 
+// getOverviewTransformations returns the transformations for the overview
+// inset; its window coordinates are with respect to the inset's lower-left
+// corner.
+func (sp *STARSPane) getOverviewTransformations(ctx *PaneContext) ScopeTransformations {
+	fa := &ctx.world.STARSFacilityAdaptation
+	extent := Extent2D{p1: [2]float32{sp.overviewExtent.Width(), sp.overviewExtent.Height()}}
+	return GetScopeTransformations(extent, ctx.world.MagneticVariation, ctx.world.NmPerLongitude,
+		fa.Center, fa.Range, 0)
+}
+
+func (sp *STARSPane) drawOverview(aircraft []*Aircraft, ctx *PaneContext, paneExtent Extent2D,
+	transforms ScopeTransformations, cb *CommandBuffer) {
+	ps := sp.CurrentPreferenceSet
+	if !ps.DisplayOverview {
+		return
+	}
+
+	// Square inset in the lower right corner.
+	const margin = 10
+	size := min(paneExtent.Width(), paneExtent.Height()) / 4
+	p1 := [2]float32{paneExtent.Width() - margin, margin + size}
+	sp.overviewExtent = Extent2D{p0: sub2f(p1, [2]float32{size, size}), p1: p1}
+	ot := sp.getOverviewTransformations(ctx)
+	toWindow := func(p Point2LL) [2]float32 {
+		return add2f(ot.WindowFromLatLongP(p), sp.overviewExtent.p0)
+	}
+
+	trid := GetColoredTrianglesDrawBuilder()
+	defer ReturnColoredTrianglesDrawBuilder(trid)
+	ld := GetColoredLinesDrawBuilder()
+	defer ReturnColoredLinesDrawBuilder(ld)
+
+	e := sp.overviewExtent
+	corners := [4][2]float32{e.p0, [2]float32{e.p1[0], e.p0[1]}, e.p1, [2]float32{e.p0[0], e.p1[1]}}
+	trid.AddQuad(corners[0], corners[1], corners[2], corners[3],
+		ps.Brightness.BackgroundContrast.ScaleRGB(STARSBackgroundColor))
+	borderColor := ps.Brightness.Lists.ScaleRGB(STARSListColor)
+
+	// The main view's extent
+	var view [4][2]float32
+	for i, p := range [4][2]float32{{0, 0}, {paneExtent.Width(), 0},
+		{paneExtent.Width(), paneExtent.Height()}, {0, paneExtent.Height()}} {
+		view[i] = toWindow(transforms.LatLongFromWindowP(p))
+	}
+	ld.AddLineLoop(borderColor, view[:])
+
+	// Traffic as dots
+	now := ctx.world.CurrentTime()
+	for _, ac := range aircraft {
+		state := sp.Aircraft[ac.Callsign]
+		if state.LostTrack(now) {
+			continue
+		}
+		color, brightness := sp.datablockColor(ctx, ac)
+		if brightness == 0 {
+			brightness = ps.Brightness.LimitedDatablocks
+		}
+		p := toWindow(state.TrackPosition())
+		trid.AddQuad(add2f(p, [2]float32{-1, -1}), add2f(p, [2]float32{1, -1}),
+			add2f(p, [2]float32{1, 1}), add2f(p, [2]float32{-1, 1}), brightness.ScaleRGB(color))
+	}
+
+	transforms.LoadWindowViewingMatrices(cb)
+	cb.SetScissorBounds(e.Offset(paneExtent.p0))
+	trid.GenerateCommands(cb)
+	cb.LineWidth(1)
+	ld.GenerateCommands(cb)
+	cb.SetScissorBounds(paneExtent)
+
+	// Draw the border last so it isn't clipped.
+	ld.Reset()
+	ld.AddLineLoop(borderColor, corners[:])
+	ld.GenerateCommands(cb)
+}
+
 func (sp *STARSPane) drawMouseCursor(ctx *PaneContext, paneExtent Extent2D, transforms ScopeTransformations,
 	cb *CommandBuffer) {
 	if ctx.mouse == nil {
@@ -7192,7 +9010,7 @@ func (sp *STARSPane) drawMouseCursor(ctx *PaneContext, paneExtent Extent2D, tran
 		ld := GetLinesDrawBuilder()
 		defer ReturnLinesDrawBuilder(ld)
 
-		w := float32(7) * Select(runtime.GOOS == "windows", ctx.platform.DPIScale(), float32(1))
+		w := float32(7) * ctx.platform.WindowScale()
 		ld.AddLine(add2f(ctx.mouse.Pos, [2]float32{-w, 0}), add2f(ctx.mouse.Pos, [2]float32{w, 0}))
 		ld.AddLine(add2f(ctx.mouse.Pos, [2]float32{0, -w}), add2f(ctx.mouse.Pos, [2]float32{0, w}))
 
@@ -7883,6 +9701,35 @@ func STARSDisabledButton(ctx *PaneContext, text string, flags int, buttonScale f
 ///////////////////////////////////////////////////////////////////////////
 // STARSPane utility methods
 
+// fixRadialDistance returns the fix-radial-distance string for the given
+// point. If fix is empty, the closest navaid is used as the reference.
+func (sp *STARSPane) fixRadialDistance(w *World, p Point2LL, fix string) (string, error) {
+	var fixp Point2LL
+	if fix != "" {
+		var ok bool
+		if fixp, ok = w.Locate(fix); !ok {
+			return "", ErrSTARSIllegalFix
+		}
+	} else {
+		dist := float32(1e30)
+		for id, n := range database.Navaids {
+			if d := nmdistance2ll(p, n.Location); d < dist {
+				fix, fixp, dist = id, n.Location, d
+			}
+		}
+		if fix == "" {
+			return "", ErrSTARSIllegalFix
+		}
+	}
+
+	dist := int(nmdistance2ll(fixp, p) + 0.5)
+	if dist > 999 {
+		return "", ErrSTARSRangeLimit
+	}
+	radial := int(headingp2ll(fixp, p, w.NmPerLongitude, w.MagneticVariation)+0.5) % 360
+	return FormatFixRadialDistance(strings.ToUpper(fix), radial, dist), nil
+}
+
 // amendFlightPlan is a useful utility function for changing an entry in
 // the flightplan; the provided callback function should make the update
 // and the rest of the details are handled here.
@@ -7914,6 +9761,13 @@ func (sp *STARSPane) initializeFonts() {
 	sp.dcbFont[2] = GetFont(FontIdentifier{Name: "sddCharFontSetBSize2", Size: 15})
 }
 
+// recallPreferenceSet makes the given preference set current.
+func (sp *STARSPane) recallPreferenceSet(ctx *PaneContext, i int) {
+	sp.SelectedPreferenceSet = i
+	sp.CurrentPreferenceSet = sp.PreferenceSets[i]
+	sp.weatherRadar.Activate(sp.CurrentPreferenceSet.Center, ctx.renderer)
+}
+
 func (sp *STARSPane) resetInputState() {
 	sp.previewAreaInput = ""
 	sp.previewAreaOutput = ""
@@ -7972,10 +9826,21 @@ func (sp *STARSPane) radarVisibility(w *World, pos Point2LL, alt int) (primary,
 	return
 }
 
+// radarReturns filters an aircraft's primary and secondary radar returns:
+// there's no secondary return without an operating transponder and
+// primary returns aren't shown if the scope is set for beacon-only
+// display.
+func (sp *STARSPane) radarReturns(ac *Aircraft, primary, secondary bool) (bool, bool) {
+	secondary = secondary && ac.Mode != Standby
+	if sp.CurrentPreferenceSet.BeaconOnly {
+		primary = false
+	}
+	return primary, secondary
+}
+
 func (sp *STARSPane) visibleAircraft(w *World) []*Aircraft {
 	var aircraft []*Aircraft
 	ps := sp.CurrentPreferenceSet
-	single := sp.radarMode(w) == RadarModeSingle
 	now := w.CurrentTime()
 	for callsign, state := range sp.Aircraft {
 		ac, ok := w.Aircraft[callsign]
@@ -7995,41 +9860,83 @@ func (sp *STARSPane) visibleAircraft(w *World) []*Aircraft {
 			alt := float32(state.TrackAltitude())
 			visible = (ac.IsDeparture() && alt > ac.DepartureAirportElevation()+100) ||
 				(!ac.IsDeparture() && alt > ac.ArrivalAirportElevation()+100)
+			// Without a transponder, there's nothing to show on a
+			// beacon-only display.
+			visible = visible && !(ps.BeaconOnly && ac.Mode == Standby)
 		} else {
 			// Otherwise see if any of the radars can see it
-			for id, site := range w.RadarSites {
-				if single && ps.RadarSiteSelected != id {
-					continue
-				}
-
-				if p, s, _ := site.CheckVisibility(w, state.TrackPosition(), state.TrackAltitude()); p || s {
-					visible = true
-				}
-			}
+			p, s, _ := sp.radarVisibility(w, state.TrackPosition(), state.TrackAltitude())
+			p, s = sp.radarReturns(ac, p, s)
+			visible = p || s
 		}
 
 		if visible {
-			aircraft = append(aircraft, ac)
-
 			// Is this the first we've seen it?
 			if state.FirstRadarTrack.IsZero() {
 				state.FirstRadarTrack = now
 
 				if sp.AutoTrackDepartures && ac.TrackingController == "" &&
-					w.DepartureController(ac) == w.Callsign {
+					w.DepartureController(ac) == w.Callsign && sp.primaryScope() {
 					w.InitiateTrack(callsign, nil, nil) // ignore error...
 				}
 			}
+
+			// Traffic for airports that have been filtered out is still
+			// shown if it's ours or is being handed off to us.
+			if !sp.airportFilter(ac).Hidden || ac.TrackingController == w.Callsign ||
+				ac.ControllingController == w.Callsign || ac.HandoffTrackController == w.Callsign {
+				aircraft = append(aircraft, ac)
+			}
 		}
 	}
 
 	return aircraft
 }
 
+// selectAircraft selects or deselects an aircraft. The change is made via
+// the event stream so that it's applied to all of the scopes.
+func (sp *STARSPane) selectAircraft(callsign string, selected bool) {
+	sp.events.PostEvent(Event{Type: SelectedAircraftEvent, Callsign: callsign, Selected: selected})
+}
+
+// primaryScope returns whether sp is the first STARS scope in the display
+// layout. When there are multiple scopes, only the primary one plays
+// audio alerts and takes automatic actions like tracking departures so
+// that they don't happen more than once.
+func (sp *STARSPane) primaryScope() bool {
+	if globalConfig == nil || globalConfig.DisplayRoot == nil {
+		return true
+	}
+	var first *STARSPane
+	globalConfig.DisplayRoot.VisitPanes(func(p Pane) {
+		if s, ok := p.(*STARSPane); ok && first == nil {
+			first = s
+		}
+	})
+	return first == nil || first == sp
+}
+
+// airportFilter returns the display filter for the airport the aircraft
+// is departing from or arriving at.
+func (sp *STARSPane) airportFilter(ac *Aircraft) STARSAirportFilter {
+	if ac.FlightPlan == nil {
+		return STARSAirportFilter{}
+	}
+	ap := Select(ac.IsDeparture(), ac.FlightPlan.DepartureAirport, ac.FlightPlan.ArrivalAirport)
+	return sp.CurrentPreferenceSet.AirportFilters[ap]
+}
+
 func (sp *STARSPane) datablockVisible(ac *Aircraft, ctx *PaneContext) bool {
 	af := sp.CurrentPreferenceSet.AltitudeFilters
 	alt := sp.Aircraft[ac.Callsign].TrackAltitude()
-	if ac.TrackingController == ctx.world.Callsign {
+	if _, ok := sp.ForcedDatablocks[ac.Callsign]; ok {
+		// Explicitly forced by the controller
+		return true
+	} else if _, ok := sp.SuppressedDatablocks[ac.Callsign]; ok && !sp.datablockAlerting(ac, ctx) {
+		// Explicitly suppressed by the controller; alerts and handoffs to
+		// us always get a datablock, though.
+		return false
+	} else if ac.TrackingController == ctx.world.Callsign {
 		// For owned datablocks
 		return true
 	} else if ac.HandoffTrackController == ctx.world.Callsign {
@@ -8077,6 +9984,22 @@ func (sp *STARSPane) datablockVisible(ac *Aircraft, ctx *PaneContext) bool {
 	}
 }
 
+// datablockAlerting returns true if the aircraft has a condition that
+// requires the controller's attention and thus its datablock should be
+// displayed even if it has been suppressed.
+func (sp *STARSPane) datablockAlerting(ac *Aircraft, ctx *PaneContext) bool {
+	state := sp.Aircraft[ac.Callsign]
+	if state.MSAW || ac.HandoffTrackController == ctx.world.Callsign {
+		return true
+	}
+	if ok, _ := SquawkIsSPC(ac.Squawk); ok {
+		return true
+	}
+	return slices.ContainsFunc(sp.CAAircraft, func(ca CAAircraft) bool {
+		return ca.Callsigns[0] == ac.Callsign || ca.Callsigns[1] == ac.Callsign
+	})
+}
+
 func (sp *STARSPane) getLeaderLineDirection(ac *Aircraft, w *World) CardinalOrdinalDirection {
 	ps := sp.CurrentPreferenceSet
 	state := sp.Aircraft[ac.Callsign]
@@ -8101,11 +10024,65 @@ func (sp *STARSPane) getLeaderLineDirection(ac *Aircraft, w *World) CardinalOrdi
 	}
 }
 
-func (sp *STARSPane) getLeaderLineVector(dir CardinalOrdinalDirection) [2]float32 {
+// getDisplayedLeaderLineDirection returns the direction in which the
+// leader line for the given aircraft should be drawn. This is generally
+// the direction from getLeaderLineDirection, though if the datablock
+// would then extend past the edge of the scope, the direction is flipped
+// horizontally and/or vertically so that the datablock remains fully
+// visible. pac is the aircraft's position in window coordinates.
+func (sp *STARSPane) getDisplayedLeaderLineDirection(ctx *PaneContext, ac *Aircraft, db STARSDatablock,
+	pac [2]float32) CardinalOrdinalDirection {
+	dir := sp.getLeaderLineDirection(ac, ctx.world)
+
+	ps := sp.CurrentPreferenceSet
+	w, h := db.BoundText(sp.systemFont[ps.CharSize.Datablocks])
+	bounds := [2]float32{float32(w), float32(h)}
+
+	// Window coordinates have the origin at the lower-left corner of the
+	// pane.
+	extent := Extent2D{p1: [2]float32{ctx.paneExtent.Width(), ctx.paneExtent.Height()}}
+	datablockExtent := func(dir CardinalOrdinalDirection) Extent2D {
+		// The offset gives the upper-left corner of the text.
+		p := add2f(pac, sp.getDatablockOffset(bounds, dir, sp.getLeaderLineLength(ac)))
+		return Extent2D{p0: [2]float32{p[0], p[1] - bounds[1]}, p1: [2]float32{p[0] + bounds[0], p[1]}}
+	}
+
+	e := datablockExtent(dir)
+	if e.p0[0] < extent.p0[0] || e.p1[0] > extent.p1[0] {
+		flip := dir.FlipHorizontal(e.p0[0] < extent.p0[0])
+		if fe := datablockExtent(flip); fe.p0[0] >= extent.p0[0] && fe.p1[0] <= extent.p1[0] {
+			dir = flip
+			e = datablockExtent(dir)
+		}
+	}
+	if e.p0[1] < extent.p0[1] || e.p1[1] > extent.p1[1] {
+		flip := dir.FlipVertical(e.p0[1] < extent.p0[1])
+		if fe := datablockExtent(flip); fe.p0[1] >= extent.p0[1] && fe.p1[1] <= extent.p1[1] {
+			dir = flip
+		}
+	}
+
+	return dir
+}
+
+// getLeaderLineLength returns the length (0-7) of the given aircraft's
+// leader line.
+func (sp *STARSPane) getLeaderLineLength(ac *Aircraft) int {
+	if l := sp.Aircraft[ac.Callsign].LeaderLineLength; l != nil {
+		return *l
+	}
+	return sp.CurrentPreferenceSet.LeaderLineLength
+}
+
+// getLeaderLineVector returns the leader line vector for the given
+// direction and length in window coordinates. Note that leader line
+// directions are always with respect to the display (i.e., North is up),
+// independent of any rotation applied to the scope by its
+// ScopeTransformations.
+func (sp *STARSPane) getLeaderLineVector(dir CardinalOrdinalDirection, length int) [2]float32 {
 	angle := dir.Heading()
 	v := [2]float32{sin(radians(angle)), cos(radians(angle))}
-	ps := sp.CurrentPreferenceSet
-	return scale2f(v, float32(10+10*ps.LeaderLineLength))
+	return scale2f(v, float32(10+10*length))
 }
 
 func (sp *STARSPane) isOverflight(ctx *PaneContext, ac *Aircraft) bool {
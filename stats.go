@@ -0,0 +1,252 @@
+// stats.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// SessionStats summarizes how a controller worked a session; it's the
+// basis of the debrief report.
+type SessionStats struct {
+	Controller       string
+	Start, End       time.Time // sim time
+	AircraftHandled  []string  // callsigns of the aircraft that we tracked
+	HandoffsOffered  int
+	HandoffsAccepted int
+	PointOuts        int
+	SeparationLosses int
+	GoArounds        int
+	// Approach clearances to runways whose finals were closed for drones
+	// and aircraft that flew into active parachute jumping areas.
+	ClosedFinalApproaches int
+	JumpAreaIncursions    int
+	// The details of each of the separation losses
+	OperationalErrors []OperationalError
+	// Delays are measured for departures as the time spent waiting for
+	// release and for arrivals as the time beyond what a direct flight
+	// to the airport would have taken.
+	DelayedAircraft int
+	TotalDelay      time.Duration
+	CommandsIssued  int
+	CommandErrors   int
+}
+
+// arrivalTiming records when a controller started working an arrival and
+// how long it would take to fly direct to the airport from there.
+type arrivalTiming struct {
+	controller string
+	start      time.Time
+	direct     time.Duration
+}
+
+func (ss *SessionStats) AverageDelay() time.Duration {
+	if ss.DelayedAircraft == 0 {
+		return 0
+	}
+	return ss.TotalDelay / time.Duration(ss.DelayedAircraft)
+}
+
+// Score rates the session out of 100: separation losses cost 20 points,
+// go arounds and approaches to closed finals and jump area incursions 5,
+// and command errors 1. Each minute of average delay over 2 minutes costs
+// another point.
+func (ss *SessionStats) Score() int {
+	score := 100 - 20*ss.SeparationLosses -
+		5*(ss.GoArounds+ss.ClosedFinalApproaches+ss.JumpAreaIncursions) - ss.CommandErrors
+	if over := ss.AverageDelay() - 2*time.Minute; over > 0 {
+		score -= int(over.Minutes())
+	}
+	return max(0, score)
+}
+
+func (ss *SessionStats) addHandled(callsign string) {
+	if !slices.Contains(ss.AircraftHandled, callsign) {
+		ss.AircraftHandled = append(ss.AircraftHandled, callsign)
+	}
+}
+
+func (ss *SessionStats) addDelay(d time.Duration) {
+	ss.DelayedAircraft++
+	ss.TotalDelay += max(d, 0)
+}
+
+// sessionStats returns the statistics for the given controller, creating
+// them if this is the first time they're needed. s.mu must be held.
+func (s *Sim) sessionStats(controller string) *SessionStats {
+	if s.SessionStats == nil {
+		s.SessionStats = make(map[string]*SessionStats)
+	}
+	ss, ok := s.SessionStats[controller]
+	if !ok {
+		ss = &SessionStats{Controller: controller, Start: s.SimTime}
+		s.SessionStats[controller] = ss
+	}
+	return ss
+}
+
+// startedTracking records that the controller has started working the
+// aircraft. s.mu must be held.
+func (s *Sim) startedTracking(controller string, ac *Aircraft) {
+	s.sessionStats(controller).addHandled(ac.Callsign)
+
+	if ac.IsDeparture() || ac.FlightPlan == nil || ac.Nav.FlightState.GS == 0 {
+		return
+	}
+	if ap := s.World.GetAirport(ac.FlightPlan.ArrivalAirport); ap != nil {
+		if s.arrivalTimings == nil {
+			s.arrivalTimings = make(map[string]arrivalTiming)
+		}
+		hours := nmdistance2ll(ac.Position(), ap.Location) / ac.Nav.FlightState.GS
+		s.arrivalTimings[ac.Callsign] = arrivalTiming{
+			controller: controller,
+			start:      s.SimTime,
+			direct:     time.Duration(hours * float32(time.Hour)),
+		}
+	}
+}
+
+// arrivalHandedToTower records the arrival's delay once it has been sent
+// to the tower. s.mu must be held.
+func (s *Sim) arrivalHandedToTower(ac *Aircraft) {
+	if at, ok := s.arrivalTimings[ac.Callsign]; ok {
+		s.sessionStats(at.controller).addDelay(s.SimTime.Sub(at.start) - at.direct)
+		delete(s.arrivalTimings, ac.Callsign)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Debrief report
+
+// debriefRows returns the rows of the debrief report as label/value pairs.
+func (ss *SessionStats) debriefRows() [][2]string {
+	avg := ss.AverageDelay()
+	return [][2]string{
+		{"Controller", ss.Controller},
+		{"Session length", ss.End.Sub(ss.Start).Round(time.Second).String()},
+		{"Aircraft handled", strconv.Itoa(len(ss.AircraftHandled))},
+		{"Handoffs offered", strconv.Itoa(ss.HandoffsOffered)},
+		{"Handoffs accepted", strconv.Itoa(ss.HandoffsAccepted)},
+		{"Point outs", strconv.Itoa(ss.PointOuts)},
+		{"Separation losses", strconv.Itoa(ss.SeparationLosses)},
+		{"Go arounds", strconv.Itoa(ss.GoArounds)},
+		{"Approaches to closed finals", strconv.Itoa(ss.ClosedFinalApproaches)},
+		{"Jump area incursions", strconv.Itoa(ss.JumpAreaIncursions)},
+		{"Average delay", fmt.Sprintf("%d:%02d", int(avg.Minutes()), int(avg.Seconds())%60)},
+		{"Commands issued", strconv.Itoa(ss.CommandsIssued)},
+		{"Command errors", strconv.Itoa(ss.CommandErrors)},
+		{"Score", strconv.Itoa(ss.Score())},
+	}
+}
+
+// ExportDebrief writes the debrief report as both JSON and CSV in the
+// user's configuration directory and returns the path of the JSON file.
+func (ss *SessionStats) ExportDebrief() (string, error) {
+	base := path.Join(path.Dir(configFilePath()),
+		"debrief-"+ss.Controller+"-"+time.Now().Format("20060102-150405"))
+
+	js, err := json.MarshalIndent(ss, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(base+".json", js, 0o644); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	cw := csv.NewWriter(&sb)
+	cw.Write([]string{"Statistic", "Value"})
+	for _, row := range ss.debriefRows() {
+		cw.Write(row[:])
+	}
+	cw.Write([]string{"Callsigns", strings.Join(ss.AircraftHandled, " ")})
+	for _, oe := range ss.OperationalErrors {
+		cw.Write([]string{"Operational error", oe.Time.Format("15:04:05") + " " + oe.String()})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(base+".csv", []byte(sb.String()), 0o644); err != nil {
+		return "", err
+	}
+
+	return base + ".json", nil
+}
+
+func (w *World) ToggleShowDebriefWindow() {
+	w.showDebrief = !w.showDebrief
+}
+
+// DrawDebriefWindow shows the statistics for the user's session so far.
+func (w *World) DrawDebriefWindow(eventStream *EventStream) {
+	if !w.showDebrief {
+		return
+	}
+
+	imgui.BeginV("Debrief", &w.showDebrief, imgui.WindowFlagsAlwaysAutoResize)
+	if ss := w.SessionStats; ss == nil {
+		imgui.Text("No statistics are available yet.")
+	} else {
+		flags := imgui.TableFlagsBordersV | imgui.TableFlagsBordersOuterH | imgui.TableFlagsRowBg
+		if imgui.BeginTableV("debrief", 2, flags, imgui.Vec2{}, 0) {
+			for _, row := range ss.debriefRows() {
+				imgui.TableNextRow()
+				imgui.TableNextColumn()
+				imgui.Text(row[0])
+				imgui.TableNextColumn()
+				imgui.Text(row[1])
+			}
+			imgui.EndTable()
+		}
+
+		if len(ss.OperationalErrors) > 0 {
+			imgui.Text("Operational errors")
+			if imgui.BeginTableV("operrors", 4, flags, imgui.Vec2{}, 0) {
+				imgui.TableSetupColumn("Time")
+				imgui.TableSetupColumn("Aircraft")
+				imgui.TableSetupColumn("Separation")
+				imgui.TableSetupColumn("Required")
+				imgui.TableHeadersRow()
+				for _, oe := range ss.OperationalErrors {
+					imgui.TableNextRow()
+					imgui.TableNextColumn()
+					imgui.Text(oe.Time.Format("15:04:05"))
+					imgui.TableNextColumn()
+					imgui.Text(oe.Aircraft[0].Callsign + "/" + oe.Aircraft[1].Callsign)
+					imgui.TableNextColumn()
+					imgui.Text(fmt.Sprintf("%.1fnm %d'", oe.Lateral, oe.Vertical))
+					imgui.TableNextColumn()
+					imgui.Text(fmt.Sprintf("%.1fnm %s", oe.Required, oe.Standard))
+				}
+				imgui.EndTable()
+			}
+		}
+
+		if imgui.Button("Export JSON and CSV") {
+			if fn, err := ss.ExportDebrief(); err != nil {
+				ShowErrorDialog("Unable to save debrief report: %v", err)
+			} else {
+				eventStream.Post(Event{Type: StatusMessageEvent, Message: "Saved debrief report to " + fn})
+			}
+		}
+	}
+	imgui.Checkbox("Export the report when signing off", &globalConfig.ExportDebriefOnSignOff)
+
+	if imgui.CollapsingHeader("Training Record") {
+		getTrainingRecord().DrawUI(eventStream)
+	}
+	imgui.End()
+}
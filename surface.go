@@ -0,0 +1,392 @@
+// surface.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// SurfacePane is an ASDE-X style surface movement display for tower
+// positions: it draws an airport's runways and taxiways (from the
+// scenario's airport "diagram") and the aircraft on and near the
+// airport, and alerts when a runway is occupied by two aircraft at once.
+type SurfacePane struct {
+	Enabled  bool
+	Airport  string
+	Range    float32 // nm
+	FontSize int
+	font     *Font
+
+	center   Point2LL
+	airports []string               // the scenario's airports, for the UI
+	alerted  map[string]interface{} // RunwayIncursion keys we have played the alert for
+	lastICAO string
+}
+
+const (
+	// Aircraft higher than this above the field aren't shown.
+	surfaceMaxAGL = 2000
+	// Aircraft this close to the field elevation are considered to be on
+	// the ground regardless of their speed.
+	surfaceGroundAGL = 50
+
+	// An airborne aircraft is arriving on a runway when it is within
+	// this distance of its threshold, below the given altitude above the
+	// field, and roughly aligned with it.
+	incursionArrivalDistance = 1    // nm
+	incursionArrivalAGL      = 750  // feet
+	incursionArrivalOffset   = 0.25 // nm from the extended centerline
+	// Distance beyond the edge of a runway that an aircraft is still
+	// considered to be on it.
+	incursionRunwayMargin = 0.02 // nm
+	// Two aircraft on the same runway are only a conflict if one of them
+	// is taking off or landing.
+	incursionRollSpeed = 40 // knots
+)
+
+// SurfaceTarget is an aircraft as seen by the surface radar.
+type SurfaceTarget struct {
+	Callsign  string
+	Position  [2]float32 // nm coordinates
+	Direction [2]float32 // unit vector along the aircraft's track
+	GS        float32
+	AGL       float32
+	OnGround  bool
+}
+
+// RunwayIncursion records an aircraft that is landing on or taking off
+// from a runway that is occupied by another.
+type RunwayIncursion struct {
+	Runway    string
+	Callsigns [2]string
+}
+
+func (ri RunwayIncursion) key() string {
+	return ri.Runway + " " + ri.Callsigns[0] + " " + ri.Callsigns[1]
+}
+
+// contains indicates whether the given point is on the runway.
+func (rg RunwayGeometry) contains(p [2]float32) bool {
+	v := sub2f(rg.Ends[1], rg.Ends[0])
+	length := length2f(v)
+	if length == 0 {
+		return false
+	}
+	v = scale2f(v, 1/length)
+	d := sub2f(p, rg.Ends[0])
+	along, across := dot(d, v), abs(dot(d, [2]float32{-v[1], v[0]}))
+	return along >= -incursionRunwayMargin && along <= length+incursionRunwayMargin &&
+		across <= rg.Width/2*FeetToNauticalMiles+incursionRunwayMargin
+}
+
+// arriving indicates whether the target is on short final to either end
+// of the runway.
+func (rg RunwayGeometry) arriving(t SurfaceTarget) bool {
+	if t.OnGround || t.AGL > incursionArrivalAGL {
+		return false
+	}
+	for i := range 2 {
+		v := sub2f(rg.Ends[1-i], rg.Ends[i])
+		if length2f(v) == 0 {
+			continue
+		}
+		v = normalize2f(v)
+		d := sub2f(t.Position, rg.Ends[i])
+		along, across := dot(d, v), abs(dot(d, [2]float32{-v[1], v[0]}))
+		// Aligned to within 30 degrees.
+		if along <= 0 && along >= -incursionArrivalDistance && across <= incursionArrivalOffset &&
+			dot(t.Direction, v) > 0.866 {
+			return true
+		}
+	}
+	return false
+}
+
+// runwayIncursions returns the runway incursions among the given targets:
+// either an aircraft on short final to a runway that another is on, or
+// two aircraft on the same runway while one of them is taking off or
+// landing.
+func runwayIncursions(runways []RunwayGeometry, targets []SurfaceTarget) []RunwayIncursion {
+	var incursions []RunwayIncursion
+	add := func(rwy string, a, b SurfaceTarget) {
+		cs := [2]string{a.Callsign, b.Callsign}
+		if cs[1] < cs[0] {
+			cs[0], cs[1] = cs[1], cs[0]
+		}
+		incursions = append(incursions, RunwayIncursion{Runway: rwy, Callsigns: cs})
+	}
+
+	for _, rwy := range runways {
+		var on, arriving []SurfaceTarget
+		for _, t := range targets {
+			if t.OnGround && rwy.contains(t.Position) {
+				on = append(on, t)
+			} else if rwy.arriving(t) {
+				arriving = append(arriving, t)
+			}
+		}
+
+		for _, a := range arriving {
+			for _, t := range on {
+				add(rwy.Id, a, t)
+			}
+		}
+		for i, a := range on {
+			for _, b := range on[i+1:] {
+				if a.GS >= incursionRollSpeed || b.GS >= incursionRollSpeed {
+					add(rwy.Id, a, b)
+				}
+			}
+		}
+	}
+	return incursions
+}
+
+func NewSurfacePane() *SurfacePane {
+	return &SurfacePane{Range: 1.5, FontSize: 12}
+}
+
+func (sp *SurfacePane) Name() string { return "Surface" }
+
+func (sp *SurfacePane) Activate(w *World, r Renderer, eventStream *EventStream) {
+	if sp.FontSize == 0 {
+		sp.FontSize = 12
+	}
+	if sp.Range == 0 {
+		sp.Range = 1.5
+	}
+	if sp.font = GetFont(FontIdentifier{Name: "Flight Strip Printer", Size: sp.FontSize}); sp.font == nil {
+		sp.font = GetDefaultFont()
+	}
+	sp.alerted = make(map[string]interface{})
+	sp.lastICAO = ""
+}
+
+func (sp *SurfacePane) Deactivate() {}
+
+func (sp *SurfacePane) ResetWorld(w *World) {
+	sp.alerted = make(map[string]interface{})
+	sp.lastICAO = ""
+}
+
+func (sp *SurfacePane) CanTakeKeyboardFocus() bool { return false }
+
+// Hidden implements the PaneHider interface; the SurfacePane is only
+// shown when it is enabled.
+func (sp *SurfacePane) Hidden() bool { return !sp.Enabled }
+
+func (sp *SurfacePane) DrawUI() {
+	imgui.Checkbox("Show surface display", &sp.Enabled)
+
+	uiStartDisable(!sp.Enabled)
+	if len(sp.airports) > 0 && imgui.BeginComboV("Airport", sp.Airport, imgui.ComboFlagsHeightLarge) {
+		for _, icao := range sp.airports {
+			if imgui.SelectableV(icao, icao == sp.Airport, 0, imgui.Vec2{}) {
+				sp.Airport = icao
+			}
+		}
+		imgui.EndCombo()
+	}
+	imgui.SliderFloatV("Range (nm)", &sp.Range, 0.25, 5, "%.2f", 0)
+	id := FontIdentifier{Name: sp.font.id.Name, Size: sp.FontSize}
+	if newFont, changed := DrawFontSizeSelector(&id); changed {
+		sp.FontSize = newFont.size
+		sp.font = newFont
+	}
+	uiEndDisable(!sp.Enabled)
+}
+
+// airport returns the airport to display: the one selected in the UI if
+// it's in the scenario and otherwise the primary airport.
+func (sp *SurfacePane) airport(w *World) (string, *Airport) {
+	if ap, ok := w.Airports[sp.Airport]; ok {
+		return sp.Airport, ap
+	}
+	sp.Airport = w.PrimaryAirport
+	return sp.Airport, w.Airports[sp.Airport]
+}
+
+// surfaceTargets returns the aircraft near the airport that are low
+// enough to be displayed.
+func surfaceTargets(w *World, center Point2LL, rangenm, elevation float32) []SurfaceTarget {
+	var targets []SurfaceTarget
+	for _, callsign := range SortedMapKeys(w.Aircraft) {
+		ac := w.Aircraft[callsign]
+		agl := ac.Altitude() - elevation
+		if agl > surfaceMaxAGL || nmdistance2ll(ac.Position(), center) > 2*rangenm+incursionArrivalDistance {
+			continue
+		}
+		hdg := radians(ac.Heading() - w.MagneticVariation)
+		targets = append(targets, SurfaceTarget{
+			Callsign:  callsign,
+			Position:  ll2nm(ac.Position(), w.NmPerLongitude),
+			Direction: [2]float32{sin(hdg), cos(hdg)},
+			GS:        ac.GS(),
+			AGL:       agl,
+			OnGround:  agl < surfaceGroundAGL || !ac.IsAirborne(),
+		})
+	}
+	return targets
+}
+
+func (sp *SurfacePane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	cb.ClearRGB(RGB{})
+
+	w := ctx.world
+	sp.airports = SortedMapKeys(w.Airports)
+	icao, ap := sp.airport(w)
+	if ap == nil {
+		return
+	}
+	if icao != sp.lastICAO {
+		sp.center = ap.Location
+		sp.lastICAO = icao
+	}
+
+	transforms := GetScopeTransformations(ctx.paneExtent, w.MagneticVariation, w.NmPerLongitude,
+		sp.center, sp.Range, 0)
+	sp.processMouse(ctx, transforms)
+
+	var elevation float32
+	if fap, ok := database.Airports[icao]; ok {
+		elevation = float32(fap.Elevation)
+	}
+	runways := AirportRunwayGeometry(icao, w.NmPerLongitude, w.MagneticVariation)
+	targets := surfaceTargets(w, sp.center, sp.Range, elevation)
+	incursions := runwayIncursions(runways, targets)
+	sp.updateAlerts(incursions)
+
+	// Airport surface
+	transforms.LoadLatLongViewingMatrices(cb)
+	ap.GenerateDiagramCommands(icao, w.NmPerLongitude, w.MagneticVariation,
+		RGB{0.45, 0.45, 0.45}, RGB{0.25, 0.25, 0.25}, cb)
+
+	// Everything else is drawn in window coordinates.
+	transforms.LoadWindowViewingMatrices(cb)
+
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+	trid := GetTrianglesDrawBuilder()
+	defer ReturnTrianglesDrawBuilder(trid)
+	ld := GetLinesDrawBuilder()
+	defer ReturnLinesDrawBuilder(ld)
+
+	labelStyle := TextStyle{Font: sp.font, Color: RGB{0.6, 0.6, 0.6}}
+	for _, rwy := range runways {
+		ids := strings.Split(rwy.Id, "/")
+		for i, id := range ids {
+			// Label each end just beyond its threshold.
+			v := normalize2f(sub2f(rwy.Ends[i], rwy.Ends[1-i]))
+			p := add2f(rwy.Ends[i], scale2f(v, 0.08))
+			td.AddTextCentered(id, transforms.WindowFromLatLongP(nm2ll(p, w.NmPerLongitude)), labelStyle)
+		}
+	}
+
+	inIncursion := func(callsign string) bool {
+		return slices.ContainsFunc(incursions, func(ri RunwayIncursion) bool {
+			return ri.Callsigns[0] == callsign || ri.Callsigns[1] == callsign
+		})
+	}
+
+	const size = 6 // pixels
+	var alertTris, ownTris, otherTris [][3][2]float32
+	for _, t := range targets {
+		ac := w.Aircraft[t.Callsign]
+		p := transforms.WindowFromLatLongP(nm2ll(t.Position, w.NmPerLongitude))
+		if !ctx.paneExtent.Inside(add2f(p, ctx.paneExtent.p0)) {
+			continue
+		}
+
+		// Find the direction of the aircraft's track on the screen.
+		ahead := transforms.WindowFromLatLongP(nm2ll(add2f(t.Position, scale2f(t.Direction, 0.01)), w.NmPerLongitude))
+		v := normalize2f(sub2f(ahead, p))
+		vperp := [2]float32{-v[1], v[0]}
+		tri := [3][2]float32{add2f(p, scale2f(v, 1.5*size)), add2f(p, scale2f(vperp, size)),
+			sub2f(p, scale2f(vperp, size))}
+
+		color := UITextColor
+		switch {
+		case inIncursion(t.Callsign):
+			alertTris = append(alertTris, tri)
+			color = UIErrorColor
+		case ac.TrackingController == w.Callsign:
+			ownTris = append(ownTris, tri)
+			color = UITextHighlightColor
+		default:
+			otherTris = append(otherTris, tri)
+		}
+
+		// Datablock
+		text := t.Callsign
+		if ac.FlightPlan != nil {
+			text += "\n" + ac.FlightPlan.BaseType()
+			if !t.OnGround {
+				text += fmt.Sprintf(" %03d", int(ac.Altitude()+50)/100)
+			}
+		}
+		dp := add2f(p, [2]float32{2 * size, 2 * size})
+		ld.AddLine(add2f(p, [2]float32{size, size}), dp)
+		td.AddText(text, add2f(dp, [2]float32{2, float32(sp.font.size)}), TextStyle{Font: sp.font, Color: color})
+	}
+
+	for _, group := range []struct {
+		tris  [][3][2]float32
+		color RGB
+	}{{otherTris, UITextColor}, {ownTris, UITextHighlightColor}, {alertTris, UIErrorColor}} {
+		trid.Reset()
+		for _, t := range group.tris {
+			trid.AddTriangle(t[0], t[1], t[2])
+		}
+		cb.SetRGB(group.color)
+		trid.GenerateCommands(cb)
+	}
+
+	// Incursion alerts at the top of the pane.
+	y := ctx.paneExtent.Height() - float32(sp.font.size)/2
+	for _, ri := range incursions {
+		td.AddText(fmt.Sprintf("RWY %s: %s %s", ri.Runway, ri.Callsigns[0], ri.Callsigns[1]),
+			[2]float32{float32(sp.font.size), y}, TextStyle{Font: sp.font, Color: UIErrorColor})
+		y -= float32(sp.font.size + 2)
+	}
+
+	cb.SetRGB(UITextColor)
+	cb.LineWidth(1)
+	ld.GenerateCommands(cb)
+	td.GenerateCommands(cb)
+}
+
+// updateAlerts plays the alert sound for incursions that have just
+// started.
+func (sp *SurfacePane) updateAlerts(incursions []RunwayIncursion) {
+	current := make(map[string]interface{})
+	for _, ri := range incursions {
+		k := ri.key()
+		if _, ok := sp.alerted[k]; !ok {
+			globalConfig.Audio.PlayOnce(AudioConflictAlert)
+		}
+		current[k] = nil
+	}
+	sp.alerted = current
+}
+
+func (sp *SurfacePane) processMouse(ctx *PaneContext, transforms ScopeTransformations) {
+	mouse := ctx.mouse
+	if mouse == nil {
+		return
+	}
+
+	if mouse.Dragging[MouseButtonSecondary] {
+		if delta := mouse.DragDelta; delta[0] != 0 || delta[1] != 0 {
+			sp.center = sub2f(sp.center, transforms.LatLongFromWindowV(delta))
+		}
+	}
+	if mouse.Wheel[1] != 0 {
+		sp.Range = clamp(sp.Range*pow(1.1, -mouse.Wheel[1]), 0.25, 5)
+	}
+}
@@ -0,0 +1,48 @@
+// surface_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRunwayIncursions(t *testing.T) {
+	// A 2nm north-south runway, 150' wide.
+	runways := []RunwayGeometry{{Id: "18/36", Ends: [2][2]float32{{0, 2}, {0, 0}}, Width: 150}}
+	north, south := [2]float32{0, 1}, [2]float32{0, -1}
+
+	holding := SurfaceTarget{Callsign: "HOLD", Position: [2]float32{0, 0.1}, Direction: north, OnGround: true}
+	rolling := SurfaceTarget{Callsign: "ROLL", Position: [2]float32{0.005, 1}, Direction: north, GS: 90, OnGround: true}
+	taxiing := SurfaceTarget{Callsign: "TAXI", Position: [2]float32{0.2, 1}, Direction: north, GS: 15, OnGround: true}
+	final := SurfaceTarget{Callsign: "FINAL", Position: [2]float32{0, -0.6}, Direction: north, GS: 130, AGL: 200}
+	// On final to runway 18, from the north.
+	final18 := SurfaceTarget{Callsign: "FNL18", Position: [2]float32{0.1, 2.5}, Direction: south, GS: 130, AGL: 300}
+	// Passing the threshold but heading the wrong way.
+	departed := SurfaceTarget{Callsign: "DEP", Position: [2]float32{0, -0.6}, Direction: south, GS: 160, AGL: 400}
+	high := SurfaceTarget{Callsign: "HIGH", Position: [2]float32{0, -0.6}, Direction: north, GS: 130, AGL: 1200}
+
+	for _, test := range []struct {
+		targets  []SurfaceTarget
+		expected [][2]string
+	}{
+		{[]SurfaceTarget{holding, taxiing, departed, high}, nil},
+		{[]SurfaceTarget{holding, final}, [][2]string{{"FINAL", "HOLD"}}},
+		{[]SurfaceTarget{final18, holding}, [][2]string{{"FNL18", "HOLD"}}},
+		{[]SurfaceTarget{holding, rolling, taxiing}, [][2]string{{"HOLD", "ROLL"}}},
+		{[]SurfaceTarget{final, taxiing}, nil},
+	} {
+		var got [][2]string
+		for _, ri := range runwayIncursions(runways, test.targets) {
+			if ri.Runway != "18/36" {
+				t.Errorf("got runway %q, expected \"18/36\"", ri.Runway)
+			}
+			got = append(got, ri.Callsigns)
+		}
+		if !slices.Equal(got, test.expected) {
+			t.Errorf("got incursions %v, expected %v", got, test.expected)
+		}
+	}
+}
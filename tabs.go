@@ -0,0 +1,308 @@
+// tabs.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"slices"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// WorldTabs holds all of the sims that the client is connected to, e.g. a
+// local practice sim and a remote one that is being observed. Only the
+// active one is shown; the others continue to receive updates in the
+// background so that their state is current when the user switches back
+// to them.
+type WorldTabs struct {
+	tabs   []*worldTab
+	active int
+
+	// shown is the tab whose panes are globalConfig.DisplayRoot; it is nil
+	// if those panes don't belong to any tab, e.g. after the tab was
+	// closed.
+	shown *worldTab
+}
+
+// worldTab holds a world along with its own panes and its own event
+// stream, so that the panes' state (STARS tracks, pointouts, flight
+// strips, etc.) isn't lost when the user switches between tabs.
+type worldTab struct {
+	world  *World
+	events *EventStream
+	// broadcasts is used to pass along server broadcast messages to the UI.
+	broadcasts *EventsSubscription
+	// root holds the tab's panes while it isn't shown; when it is,
+	// they're in globalConfig.DisplayRoot.
+	root *DisplayNode
+
+	// While the tab is in the background, the world's events are posted
+	// to background; the ones that are worth giving to the panes when
+	// the tab is shown again are held in held.
+	background       *EventStream
+	backgroundEvents *EventsSubscription
+	held             []heldEvent
+}
+
+type heldEvent struct {
+	time  time.Time
+	event Event
+}
+
+// maxHeldEventAge is how long a background tab's events are held for its
+// panes; older ones are stale by the time the tab is shown.
+const maxHeldEventAge = 2 * time.Minute
+
+var worldTabs WorldTabs
+
+func newWorldTab(w *World) *worldTab {
+	es, bg := NewEventStream(), NewEventStream()
+	return &worldTab{
+		world:            w,
+		events:           es,
+		broadcasts:       es.Subscribe(),
+		background:       bg,
+		backgroundEvents: bg.Subscribe(),
+	}
+}
+
+// holdEvents saves the events that the world has posted while in the
+// background so that its panes get them when it is shown again. Ones that
+// only matter when they happen (radio transmissions, status messages,
+// etc.) are discarded, as are the rest once they are older than
+// maxHeldEventAge. Server broadcast messages are passed along to
+// uiEventStream.
+func (tab *worldTab) holdEvents(uiEventStream *EventStream) {
+	now := time.Now()
+	for _, e := range tab.backgroundEvents.Get() {
+		switch e.Type {
+		case ServerBroadcastMessageEvent:
+			uiEventStream.Post(e)
+
+		case RadioTransmissionEvent, StatusMessageEvent, GlobalMessageEvent, TrackClickedEvent,
+			SelectedAircraftEvent, HighlightedAircraftEvent, OperationalErrorEvent:
+			// Drop it.
+
+		default:
+			tab.held = append(tab.held, heldEvent{time: now, event: e})
+		}
+	}
+
+	// The held events are in the order they arrived, so the stale ones
+	// are at the start.
+	stale := slices.IndexFunc(tab.held, func(h heldEvent) bool { return now.Sub(h.time) <= maxHeldEventAge })
+	if stale == -1 {
+		tab.held = nil
+	} else {
+		tab.held = tab.held[stale:]
+	}
+}
+
+// Active returns the world that is currently being displayed, or nil if
+// the client isn't connected to any sims.
+func (wt *WorldTabs) Active() *World {
+	if len(wt.tabs) == 0 {
+		return nil
+	}
+	return wt.tabs[wt.active].world
+}
+
+// Events returns the event stream of the active world, or eventStream if
+// the client isn't connected to any sims.
+func (wt *WorldTabs) Events(eventStream *EventStream) *EventStream {
+	if len(wt.tabs) == 0 {
+		return eventStream
+	}
+	return wt.tabs[wt.active].events
+}
+
+// Add adds the given world in a new tab and makes it the active one.
+func (wt *WorldTabs) Add(w *World) {
+	wt.tabs = append(wt.tabs, newWorldTab(w))
+	wt.active = len(wt.tabs) - 1
+}
+
+// SetPanes records that the panes in globalConfig.DisplayRoot, which
+// must have been activated with the active world and its event stream,
+// belong to the active tab.
+func (wt *WorldTabs) SetPanes() {
+	if len(wt.tabs) > 0 {
+		wt.shown = wt.tabs[wt.active]
+	}
+}
+
+// Show makes globalConfig.DisplayRoot the active tab's panes, saving the
+// panes of the one that was previously shown. A new tab gets a copy of
+// the current layout and pane settings, reset for its world.
+func (wt *WorldTabs) Show(r Renderer) {
+	if len(wt.tabs) == 0 || wt.tabs[wt.active] == wt.shown {
+		return
+	}
+	tab := wt.tabs[wt.active]
+
+	prev := globalConfig.DisplayRoot
+	if wt.shown != nil {
+		wt.shown.root = prev
+		prev = nil
+	}
+
+	if tab.root != nil {
+		globalConfig.DisplayRoot = tab.root
+		tab.root = nil
+	} else {
+		dp, err := MakeDisplayProfile(tab.world.GetWindowTitle(), globalConfig.DisplayRoot)
+		var root *DisplayNode
+		if err == nil {
+			root, err = dp.Root()
+		}
+		if err != nil {
+			// This shouldn't happen; fall back to the default layout.
+			lg.Errorf("unable to copy panes: %v", err)
+			globalConfig.DisplayRoot = nil
+			globalConfig.Activate(tab.world, r, tab.events)
+		} else {
+			globalConfig.DisplayRoot = root
+			root.VisitPanes(func(p Pane) { p.Activate(tab.world, r, tab.events) })
+		}
+		globalConfig.DisplayRoot.VisitPanes(func(p Pane) { p.ResetWorld(tab.world) })
+	}
+
+	// Panes that no longer belong to a tab are done.
+	if prev != nil {
+		prev.VisitPanes(func(p Pane) { p.Deactivate() })
+	}
+	wt.shown = tab
+
+	// Catch the panes up with what happened while the tab was hidden.
+	for _, h := range tab.held {
+		if time.Since(h.time) <= maxHeldEventAge {
+			tab.events.Post(h.event)
+		}
+	}
+	tab.held = nil
+}
+
+// ReplaceActive disconnects the active world and replaces it with the
+// given one in a new tab. If w is nil, the active tab is closed.
+func (wt *WorldTabs) ReplaceActive(w *World) {
+	if len(wt.tabs) == 0 {
+		if w != nil {
+			wt.Add(w)
+		}
+		return
+	}
+
+	wt.tabs[wt.active].world.Disconnect()
+	if w != nil {
+		wt.close(wt.active)
+		wt.tabs[wt.active] = newWorldTab(w)
+	} else {
+		wt.remove(wt.active)
+	}
+}
+
+// Drop removes the active world without disconnecting it; it is used
+// when the connection to its server has been lost.
+func (wt *WorldTabs) Drop() {
+	if len(wt.tabs) > 0 {
+		wt.remove(wt.active)
+	}
+}
+
+func (wt *WorldTabs) remove(i int) {
+	wt.close(i)
+	wt.tabs = slices.Delete(wt.tabs, i, i+1)
+	if wt.active > i || wt.active == len(wt.tabs) {
+		wt.active = max(0, wt.active-1)
+	}
+}
+
+// close releases the tab's panes and event stream. If its panes are
+// being shown, they are kept until another tab's panes replace them.
+func (wt *WorldTabs) close(i int) {
+	tab := wt.tabs[i]
+	if tab == wt.shown {
+		wt.shown = nil
+		// Nothing will update its alerts any more; silence them.
+		tab.world.AlertManager().Update(nil, time.Now())
+	} else if tab.root != nil {
+		tab.root.VisitPanes(func(p Pane) { p.Deactivate() })
+	}
+	tab.broadcasts.Unsubscribe()
+	tab.backgroundEvents.Unsubscribe()
+}
+
+// DisconnectAll disconnects from all of the sims.
+func (wt *WorldTabs) DisconnectAll() {
+	for _, tab := range wt.tabs {
+		tab.world.Disconnect()
+	}
+	wt.tabs = nil
+	wt.active = 0
+	wt.shown = nil
+}
+
+// UpdateBackground fetches updates for all of the worlds that aren't
+// being displayed; some of their events are held for their panes until
+// they are shown again (see holdEvents). Ones whose server can't be
+// reached are dropped. Server broadcast messages from all of the worlds
+// are passed along to uiEventStream, which the UI watches for them.
+func (wt *WorldTabs) UpdateBackground(eventStream, uiEventStream *EventStream) {
+	var active *worldTab
+	if len(wt.tabs) > 0 {
+		active = wt.tabs[wt.active]
+	}
+	for _, tab := range slices.Clone(wt.tabs) {
+		for _, e := range tab.broadcasts.Get() {
+			if e.Type == ServerBroadcastMessageEvent {
+				uiEventStream.Post(e)
+			}
+		}
+
+		if tab == active {
+			continue
+		}
+
+		w := tab.world
+		w.GetUpdates(tab.background, func(err error) {
+			if isRPCServerError(err) {
+				eventStream.Post(Event{
+					Type:    StatusMessageEvent,
+					Message: "Lost connection to " + w.GetWindowTitle(),
+				})
+				if idx := slices.Index(wt.tabs, tab); idx != -1 {
+					wt.remove(idx)
+				}
+			}
+		})
+		if slices.Contains(wt.tabs, tab) {
+			tab.holdEvents(uiEventStream)
+		}
+	}
+}
+
+// DrawMenu draws a menu in the main menu bar for switching between and
+// closing the tabs; it's only shown if there is more than one.
+func (wt *WorldTabs) DrawMenu() {
+	if len(wt.tabs) < 2 {
+		return
+	}
+
+	if imgui.BeginMenu(FontAwesomeIconLayerGroup) {
+		for i, tab := range wt.tabs {
+			if imgui.MenuItemV(tab.world.GetWindowTitle(), "", i == wt.active, true) {
+				wt.active = i
+			}
+		}
+		imgui.Separator()
+		if imgui.MenuItem("Disconnect from current simulation") {
+			wt.ReplaceActive(nil)
+		}
+		imgui.EndMenu()
+	}
+	if imgui.IsItemHovered() {
+		imgui.SetTooltip("Switch between connected simulations")
+	}
+}
@@ -0,0 +1,837 @@
+// taxi.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Ground movement: when a scenario enables "ground_movement", departures
+// from airports with a diagram that has parking spots start out at a
+// spot and wait for a taxi clearance, and arrivals roll out after landing,
+// exit the runway, and taxi to parking. Aircraft that are working with a
+// virtual controller taxi on their own.
+
+const (
+	taxiSpeed       = 20    // knots
+	taxiTurnSpeed   = 10    // knots, for turns of more than 45 degrees
+	taxiAccel       = 2     // knots per second
+	taxiDecel       = 4     // knots per second
+	taxiNodeEpsilon = 0.005 // nm; taxiway points closer than this are the same point
+	taxiSpotSpacing = 0.02  // nm; spots with an aircraft closer than this are occupied
+
+	// Exit speeds for high-speed (angled) and regular exits.
+	highSpeedExitSpeed = 30 // knots
+	exitSpeed          = 12 // knots
+)
+
+// TaxiGraph is an airport's taxiway network, built from its diagram.
+// Taxiways that intersect must share a point in their paths; spots are
+// connected to the nearest taxiway point.
+type TaxiGraph struct {
+	Points  [][2]float32 // nm coordinates
+	Edges   [][]TaxiEdge // for each point
+	Spots   map[string]int
+	Runways []RunwayGeometry
+
+	nmPerLongitude float32
+}
+
+type TaxiEdge struct {
+	To      int
+	Taxiway string // "" for the connections from spots
+	Length  float32
+}
+
+// TaxiPoint is a point along an aircraft's taxi route.
+type TaxiPoint struct {
+	Location Point2LL
+	Taxiway  string
+	// If set, the aircraft must hold short at this point until it is
+	// cleared onto or across this runway.
+	HoldShort string
+}
+
+// NavTaxi is the state of an aircraft that is moving on the ground.
+type NavTaxi struct {
+	Route []TaxiPoint
+
+	// For departures, the runway they're taxiing to; for arrivals, the
+	// runway they landed on.
+	Runway        string
+	RunwayHeading float32
+	ToRunway      bool // the route ends on the departure runway
+	Spot          string
+	ToSpot        bool // the route ends at parking
+
+	Rollout       bool    // landing rollout, until clear of the runway
+	ExitSpeed     float32 // during the rollout
+	ExitTaxiway   string
+	Elevation     float32
+	Holding       bool     // told to hold position
+	HoldingShort  string   // the runway we're holding short of, once reported
+	CrossRunways  []string // runways cleared to cross
+	LineUpAndWait bool
+	Takeoff       bool // cleared for takeoff
+	ReportedReady bool // departures: "ready to taxi"
+	Automatic     bool // taxiing without instructions for a virtual controller
+}
+
+// taxiStatus reports changes in a taxiing aircraft's state that it may
+// need to tell the controller about.
+type taxiStatus int
+
+const (
+	taxiMoving taxiStatus = iota
+	taxiHoldingShort
+	taxiClearOfRunway
+	taxiLinedUp
+	taxiTakeoff
+	taxiParked
+)
+
+// MakeTaxiGraph builds the taxi graph for the airport; runways gives the
+// geometry of its runways in nm coordinates.
+func MakeTaxiGraph(ap *Airport, runways []RunwayGeometry, nmPerLongitude float32) *TaxiGraph {
+	if ap == nil || ap.Diagram == nil || len(ap.Diagram.Taxiways) == 0 {
+		return nil
+	}
+
+	g := &TaxiGraph{
+		Spots:          make(map[string]int),
+		Runways:        runways,
+		nmPerLongitude: nmPerLongitude,
+	}
+	node := func(p [2]float32) int {
+		if i := slices.IndexFunc(g.Points, func(q [2]float32) bool { return distance2f(p, q) < taxiNodeEpsilon }); i != -1 {
+			return i
+		}
+		g.Points = append(g.Points, p)
+		g.Edges = append(g.Edges, nil)
+		return len(g.Points) - 1
+	}
+	connect := func(a, b int, taxiway string) {
+		if a != b {
+			l := distance2f(g.Points[a], g.Points[b])
+			g.Edges[a] = append(g.Edges[a], TaxiEdge{To: b, Taxiway: taxiway, Length: l})
+			g.Edges[b] = append(g.Edges[b], TaxiEdge{To: a, Taxiway: taxiway, Length: l})
+		}
+	}
+
+	for _, twy := range ap.Diagram.Taxiways {
+		prev := -1
+		for _, p := range twy.Path {
+			n := node(ll2nm(p, nmPerLongitude))
+			if prev != -1 {
+				connect(prev, n, twy.Name)
+			}
+			prev = n
+		}
+	}
+
+	ntaxiway := len(g.Points)
+	for _, spot := range ap.Diagram.Spots {
+		p := ll2nm(spot.Location, nmPerLongitude)
+		nearest, dist := -1, float32(0)
+		for i := range ntaxiway {
+			if d := distance2f(p, g.Points[i]); nearest == -1 || d < dist {
+				nearest, dist = i, d
+			}
+		}
+		n := node(p)
+		connect(n, nearest, "")
+		g.Spots[spot.Name] = n
+	}
+
+	return g
+}
+
+// TaxiGraph returns the taxi graph for the given airport, or nil if ground
+// movement isn't being simulated there.
+func (w *World) TaxiGraph(icao string) *TaxiGraph {
+	if !w.GroundMovement {
+		return nil
+	}
+	return MakeTaxiGraph(w.Airports[icao],
+		AirportRunwayGeometry(icao, w.NmPerLongitude, w.MagneticVariation), w.NmPerLongitude)
+}
+
+func (g *TaxiGraph) closest(p [2]float32) int {
+	best, dist := -1, float32(0)
+	for i, q := range g.Points {
+		if d := distance2f(p, q); best == -1 || d < dist {
+			best, dist = i, d
+		}
+	}
+	return best
+}
+
+// route returns the shortest route from the point closest to p to one of
+// the goals. If any taxiways are given, the route must follow them in
+// order, though it may also use the unnamed connections to spots and any
+// taxiway for the final turn onto the runway.
+func (g *TaxiGraph) route(p [2]float32, goals []int, via []string) ([]int, bool) {
+	start := g.closest(p)
+	if start == -1 || len(goals) == 0 {
+		return nil, false
+	}
+
+	// Dijkstra's algorithm over (point, index of the current taxiway in
+	// via) pairs; the taxiway index is offset by one so that zero means
+	// that we haven't yet reached the first one.
+	nv := len(via) + 1
+	state := func(n, k int) int { return n*nv + k }
+	dist := make([]float32, len(g.Points)*nv)
+	prev := make([]int, len(dist))
+	done := make([]bool, len(dist))
+	for i := range dist {
+		dist[i], prev[i] = float32(math.MaxFloat32), -1
+	}
+	dist[state(start, 0)] = 0
+
+	for {
+		cur := -1
+		for i := range dist {
+			if !done[i] && dist[i] != math.MaxFloat32 && (cur == -1 || dist[i] < dist[cur]) {
+				cur = i
+			}
+		}
+		if cur == -1 {
+			return nil, false
+		}
+		done[cur] = true
+
+		n, k := cur/nv, cur%nv
+		if (len(via) == 0 || k == len(via)) && slices.Contains(goals, n) {
+			var nodes []int
+			for s := cur; s != -1; s = prev[s] {
+				nodes = append(nodes, s/nv)
+			}
+			slices.Reverse(nodes)
+			return nodes, true
+		}
+
+		for _, e := range g.Edges[n] {
+			nk := k
+			switch {
+			case len(via) == 0 || e.Taxiway == "":
+			case k > 0 && e.Taxiway == via[k-1]:
+			case k < len(via) && e.Taxiway == via[k]:
+				nk = k + 1
+			case k == len(via) && slices.Contains(goals, e.To):
+			default:
+				continue
+			}
+			if next := state(e.To, nk); !done[next] && dist[cur]+e.Length < dist[next] {
+				dist[next] = dist[cur] + e.Length
+				prev[next] = cur
+			}
+		}
+	}
+}
+
+// runway returns the geometry of the runway with the given id and which
+// of its ends the id corresponds to.
+func (g *TaxiGraph) runway(id string) (RunwayGeometry, int, bool) {
+	for _, rg := range g.Runways {
+		if i := slices.Index(strings.Split(rg.Id, "/"), id); i != -1 {
+			return rg, i, true
+		}
+	}
+	return RunwayGeometry{}, 0, false
+}
+
+// runwayEntries returns the points on the runway closest to the given
+// end where a departure can line up.
+func (g *TaxiGraph) runwayEntries(rg RunwayGeometry, end int) []int {
+	v := normalize2f(sub2f(rg.Ends[1-end], rg.Ends[end]))
+	var entries []int
+	closest, closestAlong := -1, float32(0)
+	for i, p := range g.Points {
+		if !rg.contains(p) {
+			continue
+		}
+		along := dot(sub2f(p, rg.Ends[end]), v)
+		if along < 0.3 {
+			entries = append(entries, i)
+		}
+		if closest == -1 || along < closestAlong {
+			closest, closestAlong = i, along
+		}
+	}
+	if len(entries) == 0 && closest != -1 {
+		entries = []int{closest}
+	}
+	return entries
+}
+
+// exit returns the point where an aircraft landing on the given end of
+// the runway will exit, the point off the runway that it will taxi to,
+// and the exit speed. The first exit at least minRoll nm from the
+// threshold that doesn't require turning back is used if there is one
+// and otherwise the farthest one.
+func (g *TaxiGraph) exit(rg RunwayGeometry, end int, minRoll float32) (int, int, string, float32, bool) {
+	v := normalize2f(sub2f(rg.Ends[1-end], rg.Ends[end]))
+	best, bestNext, bestAlong, bestTaxiway, bestDot := -1, -1, float32(0), "", float32(0)
+	for i, p := range g.Points {
+		if !rg.contains(p) {
+			continue
+		}
+		along := dot(sub2f(p, rg.Ends[end]), v)
+		if along < 0 {
+			continue
+		}
+		for _, e := range g.Edges[i] {
+			q := g.Points[e.To]
+			if rg.contains(q) {
+				continue
+			}
+			d := dot(normalize2f(sub2f(q, p)), v)
+			if d < -0.5 {
+				continue
+			}
+			better := best == -1 ||
+				(along >= minRoll && (bestAlong < minRoll || along < bestAlong)) ||
+				(along < minRoll && bestAlong < minRoll && along > bestAlong)
+			if better {
+				best, bestNext, bestAlong, bestTaxiway, bestDot = i, e.To, along, e.Taxiway, d
+			}
+		}
+	}
+	if best == -1 {
+		return 0, 0, "", 0, false
+	}
+	// Exits angled 45 degrees or less from the runway are high-speed exits.
+	return best, bestNext, bestTaxiway, Select(bestDot > 0.7, float32(highSpeedExitSpeed), exitSpeed), true
+}
+
+// taxiPoints converts a route through the graph to TaxiPoints, adding a
+// hold short point wherever it enters a runway.
+func (g *TaxiGraph) taxiPoints(nodes []int) []TaxiPoint {
+	var pts []TaxiPoint
+	for i, n := range nodes {
+		p := g.Points[n]
+		var taxiway string
+		if i > 0 {
+			prev := g.Points[nodes[i-1]]
+			if idx := slices.IndexFunc(g.Edges[nodes[i-1]], func(e TaxiEdge) bool { return e.To == n }); idx != -1 {
+				taxiway = g.Edges[nodes[i-1]][idx].Taxiway
+			}
+			for _, rg := range g.Runways {
+				if !rg.contains(prev) && rg.contains(p) {
+					// Find where the segment enters the runway's
+					// protected area.
+					outside, inside := prev, p
+					for range 12 {
+						mid := mid2f(outside, inside)
+						if rg.contains(mid) {
+							inside = mid
+						} else {
+							outside = mid
+						}
+					}
+					pts = append(pts, TaxiPoint{
+						Location:  nm2ll(outside, g.nmPerLongitude),
+						Taxiway:   taxiway,
+						HoldShort: rg.Id,
+					})
+				}
+			}
+		}
+		pts = append(pts, TaxiPoint{Location: nm2ll(p, g.nmPerLongitude), Taxiway: taxiway})
+	}
+	return pts
+}
+
+// freeSpots returns the names of the graph's spots that don't have an
+// aircraft at or taxiing to them.
+func (g *TaxiGraph) freeSpots(w *World) []string {
+	return FilterSlice(SortedMapKeys(g.Spots), func(spot string) bool {
+		p := g.Points[g.Spots[spot]]
+		for _, ac := range w.Aircraft {
+			if t := ac.Nav.Taxi; t != nil && t.Spot == spot {
+				return false
+			}
+			if !ac.IsAirborne() && distance2f(p, ll2nm(ac.Position(), g.nmPerLongitude)) < taxiSpotSpacing {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// clearedOnto indicates whether the aircraft may enter the runway with
+// the given id (e.g., "4L/22R").
+func (t *NavTaxi) clearedOnto(runway string) bool {
+	ids := strings.Split(runway, "/")
+	if t.Automatic || slices.ContainsFunc(t.CrossRunways, func(r string) bool { return slices.Contains(ids, r) }) {
+		return true
+	}
+	// Line up and wait and takeoff clearances clear the aircraft onto its
+	// departure runway.
+	return t.ToRunway && slices.Contains(ids, t.Runway) && (t.LineUpAndWait || t.Takeoff)
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Nav
+
+// updateTaxi moves a taxiing aircraft along its route for one second.
+func (nav *Nav) updateTaxi(lg *Logger) taxiStatus {
+	t := nav.Taxi
+	fs := &nav.FlightState
+	fs.Altitude = t.Elevation
+	pos := ll2nm(fs.Position, fs.NmPerLongitude)
+
+	// Figure out how fast we can go given where we next have to stop or
+	// slow down.
+	target := float32(taxiSpeed)
+	if t.Rollout {
+		target = max(t.ExitSpeed, fs.IAS)
+	}
+	if t.Holding {
+		target = 0
+	}
+	d, p := float32(0), pos
+	for i, tp := range t.Route {
+		q := ll2nm(tp.Location, fs.NmPerLongitude)
+		d += distance2f(p, q)
+		stop := i == len(t.Route)-1 || (tp.HoldShort != "" && !t.clearedOnto(tp.HoldShort))
+		// v^2 = v0^2 + 2ad, with d converted to knot-seconds.
+		if stop {
+			target = min(target, sqrt(2*taxiDecel*d*3600))
+			break
+		} else if t.Rollout && i == 0 {
+			target = min(target, sqrt(t.ExitSpeed*t.ExitSpeed+2*taxiDecel*d*3600))
+		} else if r := ll2nm(t.Route[i+1].Location, fs.NmPerLongitude); distance2f(p, q) > 0 && distance2f(q, r) > 0 &&
+			dot(normalize2f(sub2f(q, p)), normalize2f(sub2f(r, q))) < 0.7 {
+			target = min(target, sqrt(taxiTurnSpeed*taxiTurnSpeed+2*taxiDecel*d*3600))
+		}
+		p = q
+	}
+	if fs.IAS < target {
+		fs.IAS = min(target, fs.IAS+taxiAccel)
+	} else {
+		fs.IAS = max(target, fs.IAS-taxiDecel)
+	}
+	fs.GS = fs.IAS
+
+	remaining := fs.IAS / 3600
+	for remaining > 0 && len(t.Route) > 0 {
+		tp := t.Route[0]
+		q := ll2nm(tp.Location, fs.NmPerLongitude)
+		v := sub2f(q, pos)
+		dist := length2f(v)
+		if dist > 1e-5 {
+			fs.Heading = headingp2ll(fs.Position, tp.Location, fs.NmPerLongitude, fs.MagneticVariation)
+		}
+		if dist > remaining {
+			pos = add2f(pos, scale2f(v, remaining/dist))
+			break
+		}
+		pos = q
+		remaining -= dist
+		if tp.HoldShort != "" && !t.clearedOnto(tp.HoldShort) {
+			break
+		}
+		t.Route = t.Route[1:]
+		if t.Rollout && len(t.Route) == 1 {
+			// We've turned off onto the exit.
+			lg.Info("exiting runway", slog.String("taxiway", t.Route[0].Taxiway))
+		}
+	}
+	fs.Position = nm2ll(pos, fs.NmPerLongitude)
+
+	if len(t.Route) > 0 {
+		tp := t.Route[0]
+		if tp.HoldShort != "" && !t.clearedOnto(tp.HoldShort) && distance2f(pos, ll2nm(tp.Location, fs.NmPerLongitude)) < 1e-4 {
+			fs.IAS, fs.GS = 0, 0
+			if t.HoldingShort != tp.HoldShort {
+				t.HoldingShort = tp.HoldShort
+				return taxiHoldingShort
+			}
+		}
+		return taxiMoving
+	}
+
+	// We've reached the end of the route.
+	fs.IAS, fs.GS = 0, 0
+	switch {
+	case t.Rollout:
+		t.Rollout = false
+		return taxiClearOfRunway
+	case t.ToSpot:
+		return taxiParked
+	case t.ToRunway:
+		fs.Heading = t.RunwayHeading
+		if t.Takeoff {
+			return taxiTakeoff
+		} else if t.LineUpAndWait {
+			t.LineUpAndWait = false
+			return taxiLinedUp
+		}
+	}
+	return taxiMoving
+}
+
+// takeoff starts the takeoff roll from the runway the aircraft has lined
+// up on; from here on, the regular departure flight model takes over.
+func (nav *Nav) takeoff() {
+	fs := &nav.FlightState
+	fs.Heading = nav.Taxi.RunwayHeading
+	fs.IAS, fs.GS = 0, 0
+	// Start from the threshold (the first waypoint of the departure
+	// route) if we're lined up near it so that the waypoint's heading
+	// and other directives are followed.
+	if len(nav.Waypoints) > 0 && nmdistance2ll(fs.Position, nav.Waypoints[0].Location) < 0.5 {
+		fs.Position = nav.Waypoints[0].Location
+	}
+	nav.Taxi = nil
+}
+
+///////////////////////////////////////////////////////////////////////////
+// Aircraft
+
+// postDepartureRoll posts an event to let the controllers know that the
+// departure has started its takeoff roll.
+func (ac *Aircraft) postDepartureRoll(ep EventPoster) {
+	if ac.DepartureRunway != "" {
+		ep.PostEvent(Event{
+			Type:     DepartureRollEvent,
+			Callsign: ac.Callsign,
+			Message:  ac.FlightPlan.DepartureAirport + " " + ac.DepartureRunway,
+		})
+	}
+}
+
+// groundAirport returns the airport where the aircraft is on the ground.
+func (ac *Aircraft) groundAirport() string {
+	return Select(ac.IsDeparture(), ac.FlightPlan.DepartureAirport, ac.FlightPlan.ArrivalAirport)
+}
+
+func (ac *Aircraft) taxiingForHuman(w *World) bool {
+	ctrl, ok := w.Controllers[ac.ControllingController]
+	return ok && ctrl.IsHuman
+}
+
+// startAtParkingSpot moves a new departure to a free parking spot at the
+// airport, if ground movement is being simulated there. Departures that
+// will be working with a virtual controller taxi to the runway and depart
+// on their own.
+func (ac *Aircraft) startAtParkingSpot(w *World, airport, runway string) {
+	g := w.TaxiGraph(airport)
+	if g == nil {
+		return
+	}
+	rg, end, ok := g.runway(runway)
+	rwy, rok := LookupRunway(airport, runway)
+	spots := g.freeSpots(w)
+	if !ok || !rok || len(spots) == 0 {
+		return
+	}
+
+	spot := SampleSlice(spots)
+	p := g.Points[g.Spots[spot]]
+	ac.Nav.FlightState.Position = nm2ll(p, g.nmPerLongitude)
+	if e := g.Edges[g.Spots[spot]]; len(e) > 0 {
+		ac.Nav.FlightState.Heading = headingp2ll(ac.Nav.FlightState.Position, nm2ll(g.Points[e[0].To], g.nmPerLongitude),
+			g.nmPerLongitude, w.MagneticVariation)
+	}
+	ac.Nav.Taxi = &NavTaxi{
+		Runway:        runway,
+		RunwayHeading: rwy.Heading,
+		Spot:          spot,
+		Elevation:     ac.Nav.FlightState.DepartureAirportElevation,
+	}
+
+	if ac.DepartureContactController != "" {
+		// A human controller will be working the departure; the pilot
+		// calls for taxi.
+		ac.ControllingController = w.DepartureController(ac)
+	}
+	if !ac.taxiingForHuman(w) {
+		if nodes, ok := g.route(p, g.runwayEntries(rg, end), nil); ok {
+			ac.Nav.Taxi.Route = g.taxiPoints(nodes)
+			ac.Nav.Taxi.ToRunway = true
+			ac.Nav.Taxi.Takeoff = true
+			ac.Nav.Taxi.Automatic = true
+		} else {
+			// No way to get to the runway; start there instead.
+			ac.Nav.Taxi = nil
+			ac.Nav.FlightState.Position = ac.Nav.Waypoints[0].Location
+		}
+	}
+}
+
+// startRollout starts the landing rollout for an arrival that has
+// reached the runway threshold. false is returned if ground movement isn't
+// being simulated at the airport or there's no way off the runway.
+func (ac *Aircraft) startRollout(w *World, lg *Logger) bool {
+	if ac.Nav.Approach.Assigned == nil {
+		return false
+	}
+	airport := ac.FlightPlan.ArrivalAirport
+	g := w.TaxiGraph(airport)
+	if g == nil {
+		return false
+	}
+	runway := ac.Nav.Approach.Assigned.Runway
+	rg, end, ok := g.runway(runway)
+	if !ok {
+		return false
+	}
+
+	// Distance to slow from the landing speed to a high-speed exit.
+	ias := ac.Nav.FlightState.IAS
+	minRoll := max(0, ias*ias-highSpeedExitSpeed*highSpeedExitSpeed) / (2 * taxiDecel * 3600)
+	exit, next, taxiway, speed, ok := g.exit(rg, end, minRoll)
+	if !ok {
+		return false
+	}
+
+	lg.Info("landed", slog.String("runway", runway), slog.String("exit", taxiway))
+	ac.Nav.Taxi = &NavTaxi{
+		Route: []TaxiPoint{
+			TaxiPoint{Location: nm2ll(g.Points[exit], g.nmPerLongitude)},
+			TaxiPoint{Location: nm2ll(g.Points[next], g.nmPerLongitude), Taxiway: taxiway},
+		},
+		Runway:      runway,
+		Rollout:     true,
+		ExitSpeed:   speed,
+		ExitTaxiway: taxiway,
+		Elevation:   ac.Nav.FlightState.ArrivalAirportElevation,
+		Automatic:   !ac.taxiingForHuman(w),
+	}
+	ac.Nav.FlightState.Altitude = ac.Nav.Taxi.Elevation
+	ac.Nav.Approach = NavApproach{}
+	ac.Nav.Heading = NavHeading{}
+	ac.Nav.DeferredHeading = nil
+	return true
+}
+
+// taxiToParking sends an arrival to a free spot; false is returned if
+// there isn't one or there's no route to it.
+func (ac *Aircraft) taxiToParking(g *TaxiGraph, w *World, via []string) bool {
+	spots := g.freeSpots(w)
+	for len(spots) > 0 {
+		i := rand.Intn(len(spots))
+		if ac.taxiTo(g, spots[i], via) {
+			return true
+		}
+		spots = slices.Delete(spots, i, i+1)
+	}
+	return false
+}
+
+// taxiTo sets the aircraft's route to the given runway or spot.
+func (ac *Aircraft) taxiTo(g *TaxiGraph, destination string, via []string) bool {
+	t := ac.Nav.Taxi
+	pos := ll2nm(ac.Position(), g.nmPerLongitude)
+	if rg, end, ok := g.runway(destination); ok {
+		rwy, rok := LookupRunway(ac.groundAirport(), destination)
+		nodes, ok := g.route(pos, g.runwayEntries(rg, end), via)
+		if !rok || !ok {
+			return false
+		}
+		t.Route = g.taxiPoints(nodes)
+		t.Runway, t.RunwayHeading = destination, rwy.Heading
+		t.ToRunway, t.ToSpot = true, false
+	} else if n, ok := g.Spots[destination]; ok {
+		nodes, ok := g.route(pos, []int{n}, via)
+		if !ok {
+			return false
+		}
+		t.Route = g.taxiPoints(nodes)
+		t.Spot = destination
+		t.ToRunway, t.ToSpot = false, true
+	} else {
+		return false
+	}
+
+	// A new taxi clearance replaces any earlier runway crossing
+	// clearances.
+	t.CrossRunways = nil
+	t.Holding = false
+	t.HoldingShort = ""
+	return true
+}
+
+// updateTaxi moves the aircraft along its taxi route and handles the
+// pilot's calls to the controller.
+func (ac *Aircraft) updateTaxi(w *World, ep EventPoster, lg *Logger) {
+	t := ac.Nav.Taxi
+	report := func(msg string) {
+		if !t.Automatic {
+			PostRadioEvents(ac.Callsign, []RadioTransmission{RadioTransmission{
+				Controller: ac.ControllingController,
+				Message:    msg,
+				Type:       RadioTransmissionContact,
+			}}, ep)
+		}
+	}
+
+	if ac.IsDeparture() && !t.ReportedReady && !t.Automatic {
+		t.ReportedReady = true
+		report("at " + t.Spot + ", ready to taxi")
+	}
+
+	switch ac.Nav.updateTaxi(lg) {
+	case taxiHoldingShort:
+		rwy := t.HoldingShort
+		if t.ToRunway && slices.Contains(strings.Split(rwy, "/"), t.Runway) &&
+			!slices.ContainsFunc(t.Route[1:], func(tp TaxiPoint) bool { return tp.HoldShort != "" }) {
+			report("holding short runway " + t.Runway + ", ready for departure")
+		} else {
+			// Refer to the runway by its end that's closer to the
+			// aircraft.
+			dist := float32(0)
+			for _, id := range strings.Split(t.HoldingShort, "/") {
+				if r, ok := LookupRunway(ac.groundAirport(), id); ok {
+					if d := nmdistance2ll(ac.Position(), r.Threshold); dist == 0 || d < dist {
+						rwy, dist = id, d
+					}
+				}
+			}
+			report("holding short runway " + rwy)
+		}
+
+	case taxiClearOfRunway:
+		g := w.TaxiGraph(ac.FlightPlan.ArrivalAirport)
+		lg.Info("clear of runway", slog.String("runway", t.Runway))
+		if g == nil || len(g.Spots) == 0 {
+			lg.Info("deleting aircraft after landing")
+			w.DeleteAircraft(ac, nil)
+		} else if t.Automatic {
+			if !ac.taxiToParking(g, w, nil) {
+				lg.Info("deleting aircraft after landing; no parking")
+				w.DeleteAircraft(ac, nil)
+			}
+		} else {
+			report("clear of runway " + t.Runway + Select(t.ExitTaxiway != "", " at "+t.ExitTaxiway, ""))
+		}
+
+	case taxiLinedUp:
+		// Nothing to say; the pilot waits for the takeoff clearance.
+
+	case taxiTakeoff:
+		lg.Info("starting takeoff roll", slog.String("runway", t.Runway))
+		ac.Nav.takeoff()
+		ac.postDepartureRoll(ep)
+
+	case taxiParked:
+		lg.Info("deleting aircraft after parking", slog.String("spot", t.Spot))
+		w.DeleteAircraft(ac, nil)
+	}
+}
+
+// Taxi clears the aircraft to taxi to the given runway or spot via the
+// given taxiways. With no destination, departures taxi to their runway and
+// arrivals to a free spot.
+func (ac *Aircraft) Taxi(w *World, destination string, via []string) []RadioTransmission {
+	if ac.Nav.Taxi == nil || ac.Nav.Taxi.Rollout {
+		return ac.readbackUnexpected("unable. We're not taxiing.")
+	}
+	g := w.TaxiGraph(ac.groundAirport())
+	if g == nil {
+		return ac.readbackUnexpected("unable.")
+	}
+
+	viaText := ""
+	if len(via) > 0 {
+		viaText = " via " + strings.Join(via, " ")
+	}
+	if destination == "" && !ac.IsDeparture() {
+		if !ac.taxiToParking(g, w, via) {
+			return ac.readbackUnexpected("unable. We don't see a way to parking%s.", viaText)
+		}
+		return ac.readback("taxi to %s%s", ac.Nav.Taxi.Spot, viaText)
+	}
+
+	if destination == "" {
+		destination = ac.Nav.Taxi.Runway
+	}
+	if !ac.taxiTo(g, destination, via) {
+		return ac.readbackUnexpected("unable. We don't see a way to %s%s.", destination, viaText)
+	}
+	if ac.Nav.Taxi.ToRunway {
+		return ac.readback("runway %s, taxi%s", destination, viaText)
+	}
+	return ac.readback("taxi to %s%s", destination, viaText)
+}
+
+func (ac *Aircraft) CrossRunway(runway string) []RadioTransmission {
+	t := ac.Nav.Taxi
+	if t == nil {
+		return ac.readbackUnexpected("unable. We're not taxiing.")
+	}
+	t.CrossRunways = append(t.CrossRunways, runway)
+	if slices.Contains(strings.Split(t.HoldingShort, "/"), runway) {
+		t.HoldingShort = ""
+	}
+	return ac.readback("cross runway %s", runway)
+}
+
+func (ac *Aircraft) HoldPosition() []RadioTransmission {
+	if ac.Nav.Taxi == nil {
+		return ac.readbackUnexpected("unable. We're not taxiing.")
+	}
+	ac.Nav.Taxi.Holding = true
+	return ac.readback("hold position")
+}
+
+func (ac *Aircraft) ContinueTaxi() []RadioTransmission {
+	if ac.Nav.Taxi == nil {
+		return ac.readbackUnexpected("unable. We're not taxiing.")
+	}
+	ac.Nav.Taxi.Holding = false
+	return ac.readback("continue taxi")
+}
+
+func (ac *Aircraft) LineUpAndWait() []RadioTransmission {
+	if t := ac.Nav.Taxi; t == nil || !ac.IsDeparture() {
+		return ac.readbackUnexpected("unable. We're not a departure on the ground.")
+	} else if !t.ToRunway {
+		return ac.readbackUnexpected("unable. We haven't been cleared to taxi to the runway.")
+	} else {
+		t.LineUpAndWait = true
+		t.HoldingShort = ""
+		return ac.readback("runway %s, line up and wait", t.Runway)
+	}
+}
+
+func (ac *Aircraft) ClearedForTakeoff() []RadioTransmission {
+	if t := ac.Nav.Taxi; t == nil || !ac.IsDeparture() {
+		return ac.readbackUnexpected("unable. We're not a departure on the ground.")
+	} else if !t.ToRunway {
+		return ac.readbackUnexpected("unable. We haven't been cleared to taxi to the runway.")
+	} else {
+		t.Takeoff = true
+		t.HoldingShort = ""
+		return ac.readback("runway %s, cleared for takeoff", t.Runway)
+	}
+}
+
+// Summary describes what the aircraft is doing on the ground.
+func (t *NavTaxi) Summary() string {
+	switch {
+	case t.Rollout:
+		return "Landing rollout on runway " + t.Runway
+	case t.Holding:
+		return "Holding position"
+	case t.HoldingShort != "":
+		return "Holding short of runway " + t.HoldingShort
+	case len(t.Route) == 0 && t.ToRunway:
+		return "Lined up on runway " + t.Runway
+	case len(t.Route) == 0:
+		return "Waiting for taxi instructions"
+	case t.ToRunway:
+		return fmt.Sprintf("Taxiing to runway %s", t.Runway)
+	default:
+		return fmt.Sprintf("Taxiing to %s", t.Spot)
+	}
+}
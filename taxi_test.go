@@ -0,0 +1,172 @@
+// taxi_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// makeTestTaxiGraph returns a graph for an east-west runway 9/27 that is 1
+// nm long with a parallel taxiway A 0.1 nm to the south, connectors B1 at
+// the runway 9 threshold and B2 at its midpoint, a high-speed exit C, and a
+// spot S1 south of A. With 60 nm per degree of longitude, lat-long and nm
+// coordinates are the same up to a factor of 60.
+func makeTestTaxiGraph() *TaxiGraph {
+	pt := func(x, y float32) Point2LL { return Point2LL{x / 60, y / 60} }
+	ap := &Airport{
+		Diagram: &AirportDiagram{
+			Taxiways: []AirportTaxiway{
+				{Name: "A", Path: []Point2LL{pt(0, -0.1), pt(0.5, -0.1), pt(0.7, -0.1), pt(1, -0.1)}},
+				{Name: "B1", Path: []Point2LL{pt(0, -0.1), pt(0, 0)}},
+				{Name: "B2", Path: []Point2LL{pt(0.5, -0.1), pt(0.5, 0)}},
+				{Name: "C", Path: []Point2LL{pt(0.6, 0), pt(0.7, -0.1)}},
+			},
+			Spots: []AirportSpot{{Name: "S1", Location: pt(0.5, -0.3)}},
+		},
+	}
+	runways := []RunwayGeometry{{Id: "9/27", Ends: [2][2]float32{{0, 0}, {1, 0}}, Width: 150}}
+	return MakeTaxiGraph(ap, runways, 60)
+}
+
+func TestTaxiGraphRoute(t *testing.T) {
+	g := makeTestTaxiGraph()
+	if len(g.Points) != 8 {
+		t.Fatalf("expected 8 points, got %d: %v", len(g.Points), g.Points)
+	}
+
+	rg, end, ok := g.runway("9")
+	if !ok || end != 0 {
+		t.Fatalf("didn't find runway 9")
+	}
+	entries := g.runwayEntries(rg, end)
+	spot := g.Points[g.Spots["S1"]]
+
+	for _, test := range []struct {
+		via  []string
+		ok   bool
+		last [2]float32
+	}{
+		{via: nil, ok: true, last: [2]float32{0, 0}},
+		{via: []string{"A"}, ok: true, last: [2]float32{0, 0}},
+		{via: []string{"A", "B1"}, ok: true, last: [2]float32{0, 0}},
+		{via: []string{"C"}, ok: false},
+	} {
+		nodes, ok := g.route(spot, entries, test.via)
+		if ok != test.ok {
+			t.Errorf("via %v: got ok %v, expected %v", test.via, ok, test.ok)
+			continue
+		}
+		if ok {
+			if nodes[0] != g.Spots["S1"] {
+				t.Errorf("via %v: route doesn't start at the spot: %v", test.via, nodes)
+			}
+			if p := g.Points[nodes[len(nodes)-1]]; distance2f(p, test.last) > 1e-4 {
+				t.Errorf("via %v: route ends at %v, expected %v", test.via, p, test.last)
+			}
+		}
+	}
+
+	// The route along A enters the runway from B1 and so should hold
+	// short there.
+	nodes, _ := g.route(spot, entries, []string{"A"})
+	pts := g.taxiPoints(nodes)
+	holds := slices.DeleteFunc(slices.Clone(pts), func(tp TaxiPoint) bool { return tp.HoldShort == "" })
+	if len(holds) != 1 || holds[0].HoldShort != "9/27" {
+		t.Fatalf("expected a single hold short point for 9/27, got %+v", pts)
+	}
+	if p := ll2nm(holds[0].Location, 60); !rg.contains(g.Points[nodes[len(nodes)-1]]) || rg.contains(p) ||
+		abs(p[1]+(150./2*FeetToNauticalMiles+incursionRunwayMargin)) > 1e-3 {
+		t.Errorf("hold short point %v isn't at the edge of the runway's protected area", p)
+	}
+}
+
+func TestTaxiGraphExit(t *testing.T) {
+	g := makeTestTaxiGraph()
+	rg, end, _ := g.runway("9")
+
+	for _, test := range []struct {
+		minRoll float32
+		exit    [2]float32
+		taxiway string
+		speed   float32
+	}{
+		{minRoll: 0.45, exit: [2]float32{0.5, 0}, taxiway: "B2", speed: exitSpeed},
+		{minRoll: 0.55, exit: [2]float32{0.6, 0}, taxiway: "C", speed: highSpeedExitSpeed},
+		// Past all of the exits: take the last one.
+		{minRoll: 0.9, exit: [2]float32{0.6, 0}, taxiway: "C", speed: highSpeedExitSpeed},
+	} {
+		exit, next, taxiway, speed, ok := g.exit(rg, end, test.minRoll)
+		if !ok {
+			t.Errorf("%f: no exit found", test.minRoll)
+			continue
+		}
+		if distance2f(g.Points[exit], test.exit) > 1e-4 || taxiway != test.taxiway || speed != test.speed {
+			t.Errorf("%f: got exit %v via %s at %f, expected %v via %s at %f", test.minRoll, g.Points[exit],
+				taxiway, speed, test.exit, test.taxiway, test.speed)
+		}
+		if rg.contains(g.Points[next]) {
+			t.Errorf("%f: exit leads to %v, which is on the runway", test.minRoll, g.Points[next])
+		}
+	}
+
+	// Landing on 27, B2 is the only exit that doesn't require turning
+	// back.
+	rg, end, _ = g.runway("27")
+	if exit, _, taxiway, _, ok := g.exit(rg, end, 0.3); !ok || taxiway != "B2" {
+		t.Errorf("runway 27: got exit %v via %s, expected B2", g.Points[exit], taxiway)
+	}
+}
+
+func TestNavUpdateTaxi(t *testing.T) {
+	g := makeTestTaxiGraph()
+	rg, end, _ := g.runway("9")
+	spot := g.Points[g.Spots["S1"]]
+	nodes, _ := g.route(spot, g.runwayEntries(rg, end), nil)
+
+	nav := &Nav{
+		FlightState: FlightState{
+			NmPerLongitude: 60,
+			Position:       nm2ll(spot, 60),
+		},
+		Taxi: &NavTaxi{
+			Route:         g.taxiPoints(nodes),
+			Runway:        "9",
+			RunwayHeading: 90,
+			ToRunway:      true,
+			Elevation:     20,
+		},
+	}
+
+	run := func(until taxiStatus) bool {
+		for range 1000 {
+			if nav.FlightState.IAS > taxiSpeed {
+				t.Errorf("taxiing too fast: %f", nav.FlightState.IAS)
+			}
+			if nav.updateTaxi(lg) == until {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !run(taxiHoldingShort) {
+		t.Fatalf("never held short of the runway")
+	}
+	if nav.FlightState.IAS != 0 || nav.Taxi.HoldingShort != "9/27" || nav.FlightState.Altitude != 20 {
+		t.Errorf("unexpected state holding short: %+v %+v", nav.FlightState, nav.Taxi)
+	}
+	if rg.contains(ll2nm(nav.FlightState.Position, 60)) {
+		t.Errorf("holding short on the runway at %v", nav.FlightState.Position)
+	}
+
+	nav.Taxi.Takeoff = true
+	if !run(taxiTakeoff) {
+		t.Fatalf("never got to the runway")
+	}
+	if p := ll2nm(nav.FlightState.Position, 60); distance2f(p, [2]float32{0, 0}) > 1e-3 || nav.FlightState.Heading != 90 {
+		t.Errorf("expected to be lined up at the threshold, got %v heading %f", p, nav.FlightState.Heading)
+	}
+}
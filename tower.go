@@ -0,0 +1,412 @@
+// tower.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// TowerPane shows a simple out-the-window view from an airport's tower
+// cab: the runways and taxiways, the final approach courses to the
+// arrival runways, and the aircraft in view, so that local control can be
+// worked visually. The view is rendered by projecting points into the
+// pane on the CPU and then drawing them with the regular 2D drawing
+// builders.
+type TowerPane struct {
+	Enabled   bool
+	Airport   string
+	Heading   float32 // magnetic direction of view
+	Pitch     float32 // degrees; positive is up
+	FOV       float32 // horizontal field of view, in degrees
+	EyeHeight float32 // feet above the field
+	FontSize  int
+	font      *Font
+
+	airports []string // the scenario's airports, for the UI
+	lastICAO string
+}
+
+const (
+	// Points closer than this to the eye along the view direction are
+	// clipped.
+	towerNearDistance = 0.005 // nm
+	// Aircraft are drawn as discs that are this wide, though never
+	// smaller than a few pixels so that they can be seen in the distance.
+	towerAircraftSize = 120 // feet
+	// Length of the glidepaths that are drawn for the arrival runways.
+	towerFinalLength = 10 // nm
+)
+
+var (
+	towerSkyColor       = RGB{0.35, 0.5, 0.7}
+	towerGroundColor    = RGB{0.25, 0.3, 0.2}
+	towerRunwayColor    = RGB{0.2, 0.2, 0.2}
+	towerTaxiwayColor   = RGB{0.3, 0.3, 0.28}
+	towerMarkingColor   = RGB{0.9, 0.9, 0.9}
+	towerFinalColor     = RGB{0.3, 0.9, 0.9}
+	towerAircraftColor  = RGB{0.95, 0.95, 0.95}
+	towerHighlightColor = RGB{1, 0.85, 0.3}
+)
+
+// towerCamera projects points given in nm coordinates with the altitude
+// (also in nm) as the third component into pane window coordinates.
+type towerCamera struct {
+	eye                [3]float32
+	right, up, forward [3]float32
+	focal              float32 // in pixels
+	center             [2]float32
+	pitch              float32 // degrees
+}
+
+func makeTowerCamera(eye [3]float32, trueHeading, pitch, fov, width, height float32) towerCamera {
+	h, p := radians(trueHeading), radians(pitch)
+	return towerCamera{
+		eye:     eye,
+		right:   [3]float32{cos(h), -sin(h), 0},
+		up:      [3]float32{-sin(h) * sin(p), -cos(h) * sin(p), cos(p)},
+		forward: [3]float32{sin(h) * cos(p), cos(h) * cos(p), sin(p)},
+		focal:   width / 2 / tan(radians(fov/2)),
+		center:  [2]float32{width / 2, height / 2},
+		pitch:   pitch,
+	}
+}
+
+// toCamera returns the point in camera space: x is to the right, y up,
+// and z the distance along the view direction.
+func (c *towerCamera) toCamera(p [3]float32) [3]float32 {
+	d := sub3f(p, c.eye)
+	return [3]float32{dot3f(d, c.right), dot3f(d, c.up), dot3f(d, c.forward)}
+}
+
+func (c *towerCamera) projectCamera(pc [3]float32) [2]float32 {
+	return [2]float32{c.center[0] + c.focal*pc[0]/pc[2], c.center[1] + c.focal*pc[1]/pc[2]}
+}
+
+// project returns the window coordinates of the given point; false is
+// returned if it is behind the viewer.
+func (c *towerCamera) project(p [3]float32) ([2]float32, bool) {
+	pc := c.toCamera(p)
+	if pc[2] < towerNearDistance {
+		return [2]float32{}, false
+	}
+	return c.projectCamera(pc), true
+}
+
+// projectLine returns the window coordinates of the endpoints of the
+// portion of the segment p0-p1 that is in front of the viewer.
+func (c *towerCamera) projectLine(p0, p1 [3]float32) ([2]float32, [2]float32, bool) {
+	c0, c1 := c.toCamera(p0), c.toCamera(p1)
+	if c0[2] < towerNearDistance && c1[2] < towerNearDistance {
+		return [2]float32{}, [2]float32{}, false
+	}
+	if c0[2] < towerNearDistance {
+		c0 = lerp3f((towerNearDistance-c0[2])/(c1[2]-c0[2]), c0, c1)
+	} else if c1[2] < towerNearDistance {
+		c1 = lerp3f((towerNearDistance-c1[2])/(c0[2]-c1[2]), c1, c0)
+	}
+	return c.projectCamera(c0), c.projectCamera(c1), true
+}
+
+// projectPolygon clips the given convex polygon to the portion in front
+// of the viewer and returns its vertices in window coordinates.
+func (c *towerCamera) projectPolygon(poly [][3]float32) [][2]float32 {
+	var clipped [][3]float32
+	for i := range poly {
+		a, b := c.toCamera(poly[i]), c.toCamera(poly[(i+1)%len(poly)])
+		ina, inb := a[2] >= towerNearDistance, b[2] >= towerNearDistance
+		if ina {
+			clipped = append(clipped, a)
+		}
+		if ina != inb {
+			clipped = append(clipped, lerp3f((towerNearDistance-a[2])/(b[2]-a[2]), a, b))
+		}
+	}
+	return MapSlice(clipped, c.projectCamera)
+}
+
+// horizonY returns the window y coordinate of the horizon.
+func (c *towerCamera) horizonY() float32 {
+	return c.center[1] - c.focal*tan(radians(c.pitch))
+}
+
+func NewTowerPane() *TowerPane {
+	return &TowerPane{Pitch: -3, FOV: 70, EyeHeight: 150, FontSize: 12}
+}
+
+func (tp *TowerPane) Name() string { return "Tower View" }
+
+func (tp *TowerPane) Activate(w *World, r Renderer, eventStream *EventStream) {
+	if tp.FontSize == 0 {
+		tp.FontSize = 12
+	}
+	if tp.FOV == 0 {
+		tp.FOV = 70
+	}
+	if tp.EyeHeight == 0 {
+		tp.EyeHeight = 150
+	}
+	if tp.font = GetFont(FontIdentifier{Name: "Flight Strip Printer", Size: tp.FontSize}); tp.font == nil {
+		tp.font = GetDefaultFont()
+	}
+	tp.lastICAO = ""
+}
+
+func (tp *TowerPane) Deactivate() {}
+
+func (tp *TowerPane) ResetWorld(w *World) { tp.lastICAO = "" }
+
+func (tp *TowerPane) CanTakeKeyboardFocus() bool { return false }
+
+// Hidden implements the PaneHider interface; the TowerPane is only shown
+// when it is enabled.
+func (tp *TowerPane) Hidden() bool { return !tp.Enabled }
+
+func (tp *TowerPane) DrawUI() {
+	imgui.Checkbox("Show tower view", &tp.Enabled)
+
+	uiStartDisable(!tp.Enabled)
+	if len(tp.airports) > 0 && imgui.BeginComboV("Airport##tower", tp.Airport, imgui.ComboFlagsHeightLarge) {
+		for _, icao := range tp.airports {
+			if imgui.SelectableV(icao, icao == tp.Airport, 0, imgui.Vec2{}) {
+				tp.Airport = icao
+			}
+		}
+		imgui.EndCombo()
+	}
+	imgui.SliderFloatV("Field of view (degrees)", &tp.FOV, 10, 120, "%.0f", 0)
+	imgui.SliderFloatV("Eye height (feet)", &tp.EyeHeight, 20, 400, "%.0f", 0)
+	id := FontIdentifier{Name: tp.font.id.Name, Size: tp.FontSize}
+	if newFont, changed := DrawFontSizeSelector(&id); changed {
+		tp.FontSize = newFont.size
+		tp.font = newFont
+	}
+	uiEndDisable(!tp.Enabled)
+}
+
+// airport returns the airport to display: the one selected in the UI if
+// it's in the scenario and otherwise the primary airport.
+func (tp *TowerPane) airport(w *World) (string, *Airport) {
+	if ap, ok := w.Airports[tp.Airport]; ok {
+		return tp.Airport, ap
+	}
+	tp.Airport = w.PrimaryAirport
+	return tp.Airport, w.Airports[tp.Airport]
+}
+
+// resetView points the view down the final approach course of the
+// airport's first arrival runway, if there is one.
+func (tp *TowerPane) resetView(w *World, icao string) {
+	for _, ar := range w.ArrivalRunways {
+		if ar.Airport == icao {
+			if rwy, ok := LookupRunway(icao, ar.Runway); ok {
+				tp.Heading = NormalizeHeading(rwy.Heading + 180)
+				return
+			}
+		}
+	}
+}
+
+func (tp *TowerPane) Draw(ctx *PaneContext, cb *CommandBuffer) {
+	cb.ClearRGB(towerSkyColor)
+
+	w := ctx.world
+	tp.airports = SortedMapKeys(w.Airports)
+	icao, ap := tp.airport(w)
+	if ap == nil {
+		return
+	}
+	if icao != tp.lastICAO {
+		tp.resetView(w, icao)
+		tp.lastICAO = icao
+	}
+	tp.processMouse(ctx)
+
+	var elevation float32
+	if fap, ok := database.Airports[icao]; ok {
+		elevation = float32(fap.Elevation)
+	}
+	loc := ap.Location
+	if !ap.TowerLocation.IsZero() {
+		loc = ap.TowerLocation
+	}
+	groundZ := elevation * FeetToNauticalMiles
+	eye := ll2nm(loc, w.NmPerLongitude)
+	width, height := ctx.paneExtent.Width(), ctx.paneExtent.Height()
+	cam := makeTowerCamera([3]float32{eye[0], eye[1], (elevation + tp.EyeHeight) * FeetToNauticalMiles},
+		tp.Heading-w.MagneticVariation, tp.Pitch, tp.FOV, width, height)
+
+	ctx.SetWindowCoordinateMatrices(cb)
+
+	trid := GetTrianglesDrawBuilder()
+	defer ReturnTrianglesDrawBuilder(trid)
+	ld := GetLinesDrawBuilder()
+	defer ReturnLinesDrawBuilder(ld)
+	td := GetTextDrawBuilder()
+	defer ReturnTextDrawBuilder(td)
+
+	// Ground
+	if hy := clamp(cam.horizonY(), 0, height); hy > 0 {
+		trid.AddQuad([2]float32{0, 0}, [2]float32{width, 0}, [2]float32{width, hy}, [2]float32{0, hy})
+		cb.SetRGB(towerGroundColor)
+		trid.GenerateCommands(cb)
+	}
+
+	// Adds the quad along the segment p0-p1 (in nm) on the ground.
+	addStrip := func(p0, p1 [2]float32, widthFeet float32) {
+		v := normalize2f(sub2f(p1, p0))
+		vperp := scale2f([2]float32{-v[1], v[0]}, widthFeet/2*FeetToNauticalMiles)
+		ground := func(p [2]float32) [3]float32 { return [3]float32{p[0], p[1], groundZ} }
+		poly := cam.projectPolygon([][3]float32{ground(add2f(p0, vperp)), ground(add2f(p1, vperp)),
+			ground(sub2f(p1, vperp)), ground(sub2f(p0, vperp))})
+		for i := 1; i+1 < len(poly); i++ {
+			trid.AddTriangle(poly[0], poly[i], poly[i+1])
+		}
+	}
+
+	// Taxiways and then runways on top of them.
+	trid.Reset()
+	if ap.Diagram != nil {
+		for _, twy := range ap.Diagram.Taxiways {
+			for i := 0; i+1 < len(twy.Path); i++ {
+				addStrip(ll2nm(twy.Path[i], w.NmPerLongitude), ll2nm(twy.Path[i+1], w.NmPerLongitude), twy.Width)
+			}
+		}
+	}
+	cb.SetRGB(towerTaxiwayColor)
+	trid.GenerateCommands(cb)
+
+	trid.Reset()
+	runways := AirportRunwayGeometry(icao, w.NmPerLongitude, w.MagneticVariation)
+	for _, rwy := range runways {
+		addStrip(rwy.Ends[0], rwy.Ends[1], rwy.Width)
+	}
+	cb.SetRGB(towerRunwayColor)
+	trid.GenerateCommands(cb)
+
+	// Runway centerlines and numbers.
+	ld.Reset()
+	labelStyle := TextStyle{Font: tp.font, Color: towerMarkingColor}
+	for _, rwy := range runways {
+		p0 := [3]float32{rwy.Ends[0][0], rwy.Ends[0][1], groundZ}
+		p1 := [3]float32{rwy.Ends[1][0], rwy.Ends[1][1], groundZ}
+		if a, b, ok := cam.projectLine(p0, p1); ok {
+			ld.AddLine(a, b)
+		}
+		for i, id := range strings.Split(rwy.Id, "/") {
+			if p, ok := cam.project([3]float32{rwy.Ends[i][0], rwy.Ends[i][1], groundZ}); ok {
+				td.AddTextCentered(id, p, labelStyle)
+			}
+		}
+	}
+	cb.SetRGB(towerMarkingColor)
+	cb.LineWidth(1)
+	ld.GenerateCommands(cb)
+
+	// Glidepaths to the arrival runways.
+	ld.Reset()
+	for _, ar := range w.ArrivalRunways {
+		if ar.Airport != icao {
+			continue
+		}
+		if rwy, ok := LookupRunway(icao, ar.Runway); ok {
+			th := ll2nm(rwy.Threshold, w.NmPerLongitude)
+			hdg := radians(rwy.Heading - w.MagneticVariation)
+			back := [2]float32{-sin(hdg), -cos(hdg)}
+			tch := (float32(rwy.Elevation) + 50) * FeetToNauticalMiles
+			p0 := [3]float32{th[0], th[1], tch}
+			p1 := add3f(p0, [3]float32{towerFinalLength * back[0], towerFinalLength * back[1],
+				towerFinalLength * tan(radians(3))})
+			if a, b, ok := cam.projectLine(p0, p1); ok {
+				ld.AddLine(a, b)
+			}
+		}
+	}
+	cb.SetRGB(towerFinalColor)
+	ld.GenerateCommands(cb)
+
+	// Heading marks along the horizon.
+	ld.Reset()
+	hy := cam.horizonY()
+	for hdg := 10; hdg <= 360; hdg += 10 {
+		h := radians(float32(hdg) - w.MagneticVariation)
+		p, ok := cam.project(add3f(cam.eye, [3]float32{sin(h), cos(h), 0}))
+		if !ok {
+			continue
+		}
+		p[1] = hy
+		ld.AddLine(p, add2f(p, [2]float32{0, Select(hdg%30 == 0, float32(8), 4)}))
+		if hdg%30 == 0 {
+			td.AddTextCentered(fmt.Sprintf("%03d", hdg), add2f(p, [2]float32{0, 10 + float32(tp.font.size)}),
+				labelStyle)
+		}
+	}
+	cb.SetRGB(towerMarkingColor)
+	ld.GenerateCommands(cb)
+
+	// Aircraft, farthest first so that closer ones are drawn on top.
+	type visible struct {
+		ac   *Aircraft
+		p    [2]float32
+		dist float32
+	}
+	var aircraft []visible
+	for _, ac := range w.Aircraft {
+		pos := ll2nm(ac.Position(), w.NmPerLongitude)
+		pc := cam.toCamera([3]float32{pos[0], pos[1], ac.Altitude() * FeetToNauticalMiles})
+		if pc[2] < towerNearDistance {
+			continue
+		}
+		p := cam.projectCamera(pc)
+		if p[0] < 0 || p[0] > width || p[1] < 0 || p[1] > height {
+			continue
+		}
+		aircraft = append(aircraft, visible{ac: ac, p: p, dist: pc[2]})
+	}
+	slices.SortFunc(aircraft, func(a, b visible) int { return cmp.Compare(b.dist, a.dist) })
+
+	for _, v := range aircraft {
+		r := max(2, min(40, cam.focal*towerAircraftSize*FeetToNauticalMiles/v.dist/2))
+		color := Select(v.ac.TrackingController == w.Callsign || v.ac.ControllingController == w.Callsign,
+			towerHighlightColor, towerAircraftColor)
+
+		trid.Reset()
+		trid.AddCircle(v.p, r, 12)
+		cb.SetRGB(color)
+		trid.GenerateCommands(cb)
+
+		label := v.ac.Callsign
+		if v.ac.FlightPlan != nil {
+			label += " " + v.ac.FlightPlan.BaseType()
+		}
+		td.AddTextCentered(label, add2f(v.p, [2]float32{0, r + float32(tp.font.size)}),
+			TextStyle{Font: tp.font, Color: color})
+	}
+
+	td.GenerateCommands(cb)
+}
+
+// processMouse handles dragging with the right button to look around and
+// the scroll wheel to zoom.
+func (tp *TowerPane) processMouse(ctx *PaneContext) {
+	mouse := ctx.mouse
+	if mouse == nil {
+		return
+	}
+
+	if mouse.Dragging[MouseButtonSecondary] {
+		delta := mouse.DragDelta
+		degreesPerPixel := tp.FOV / ctx.paneExtent.Width()
+		tp.Heading = NormalizeHeading(tp.Heading - delta[0]*degreesPerPixel)
+		tp.Pitch = clamp(tp.Pitch-delta[1]*degreesPerPixel, -45, 30)
+	}
+	if mouse.Wheel[1] != 0 {
+		tp.FOV = clamp(tp.FOV*pow(1.1, -mouse.Wheel[1]), 10, 120)
+	}
+}
@@ -0,0 +1,76 @@
+// tower_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+)
+
+func TestTowerCameraProject(t *testing.T) {
+	// Looking north, level, with a 90 degree field of view in a 200x100
+	// pane: the focal length is 100 pixels.
+	cam := makeTowerCamera([3]float32{0, 0, 0}, 0, 0, 90, 200, 100)
+
+	for _, test := range []struct {
+		p        [3]float32
+		expected [2]float32
+		ok       bool
+	}{
+		{p: [3]float32{0, 1, 0}, expected: [2]float32{100, 50}, ok: true},
+		{p: [3]float32{1, 1, 0}, expected: [2]float32{200, 50}, ok: true},
+		{p: [3]float32{-0.5, 2, 1}, expected: [2]float32{75, 100}, ok: true},
+		{p: [3]float32{0, -1, 0}, ok: false},
+	} {
+		p, ok := cam.project(test.p)
+		if ok != test.ok {
+			t.Errorf("%v: got ok %v, expected %v", test.p, ok, test.ok)
+		} else if ok && (abs(p[0]-test.expected[0]) > 1e-3 || abs(p[1]-test.expected[1]) > 1e-3) {
+			t.Errorf("%v: got %v, expected %v", test.p, p, test.expected)
+		}
+	}
+
+	// Looking east, a point to the east is in the center.
+	cam = makeTowerCamera([3]float32{0, 0, 0}, 90, 0, 90, 200, 100)
+	if p, ok := cam.project([3]float32{3, 0, 0}); !ok || abs(p[0]-100) > 1e-3 || abs(p[1]-50) > 1e-3 {
+		t.Errorf("looking east: got %v %v, expected [100 50] true", p, ok)
+	}
+
+	// Looking down, the horizon moves up.
+	cam = makeTowerCamera([3]float32{0, 0, 0}, 0, -45, 90, 200, 100)
+	if y := cam.horizonY(); abs(y-150) > 1e-3 {
+		t.Errorf("horizon: got %f, expected 150", y)
+	}
+}
+
+func TestTowerCameraClip(t *testing.T) {
+	cam := makeTowerCamera([3]float32{0, 0, 0}, 0, 0, 90, 200, 100)
+
+	// A line that passes behind the viewer is clipped at the near plane.
+	a, b, ok := cam.projectLine([3]float32{0, -1, -0.1}, [3]float32{0, 1, -0.1})
+	if !ok {
+		t.Fatalf("expected line to be visible")
+	}
+	if abs(b[1]-(50-10)) > 1e-3 {
+		t.Errorf("unclipped endpoint: got %v, expected y 40", b)
+	}
+	if a[1] > -1000 {
+		t.Errorf("clipped endpoint should be far below the pane: got %v", a)
+	}
+
+	if _, _, ok := cam.projectLine([3]float32{0, -1, 0}, [3]float32{1, -2, 0}); ok {
+		t.Errorf("expected line behind the viewer to be culled")
+	}
+
+	// A ground quad that straddles the viewer keeps the two vertices in
+	// front and gains two on the near plane.
+	poly := cam.projectPolygon([][3]float32{{-1, -1, -0.1}, {1, -1, -0.1}, {1, 1, -0.1}, {-1, 1, -0.1}})
+	if len(poly) != 4 {
+		t.Errorf("expected 4 vertices after clipping, got %d: %v", len(poly), poly)
+	}
+
+	if poly := cam.projectPolygon([][3]float32{{-1, -1, 0}, {1, -1, 0}, {1, -2, 0}}); len(poly) != 0 {
+		t.Errorf("expected polygon behind the viewer to be culled, got %v", poly)
+	}
+}
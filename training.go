@@ -0,0 +1,277 @@
+// training.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+// TrainingRecord is the history of the user's sessions, kept in its own
+// file next to the config file so that training programs can track
+// progress across sessions. It is only stored locally.
+type TrainingRecord struct {
+	Trainee string
+	// A position is current if it has been worked within this many days.
+	CurrencyDays int
+	Sessions     []TrainingSession
+
+	// Lessons completed in the current session; they are recorded with
+	// it when the user signs off.
+	pendingLessons []string
+	newLesson      string
+}
+
+// TrainingSession summarizes a single session that the user worked.
+type TrainingSession struct {
+	Date             time.Time // when the user signed off
+	TRACON           string
+	Scenario         string
+	Controller       string
+	Duration         time.Duration // sim time
+	AircraftHandled  int
+	SeparationLosses int
+	GoArounds        int
+	CommandErrors    int
+	AverageDelay     time.Duration
+	Score            int
+	Lessons          []string
+}
+
+// PositionCurrency summarizes the sessions worked at a position.
+type PositionCurrency struct {
+	TRACON     string
+	Controller string
+	Sessions   int
+	Total      time.Duration
+	Last       time.Time
+	Current    bool
+}
+
+const defaultCurrencyDays = 30
+
+// trainingRecord is loaded the first time it is needed.
+var trainingRecord *TrainingRecord
+
+func trainingRecordPath() string {
+	return path.Join(path.Dir(configFilePath()), "training.json")
+}
+
+// getTrainingRecord returns the user's training record, loading it if
+// necessary. A new record is returned if there isn't one yet.
+func getTrainingRecord() *TrainingRecord {
+	if trainingRecord == nil {
+		trainingRecord = &TrainingRecord{CurrencyDays: defaultCurrencyDays}
+		if b, err := os.ReadFile(trainingRecordPath()); err == nil {
+			if err := json.Unmarshal(b, trainingRecord); err != nil {
+				lg.Errorf("%s: %v", trainingRecordPath(), err)
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			lg.Errorf("%s: %v", trainingRecordPath(), err)
+		}
+	}
+	return trainingRecord
+}
+
+func (tr *TrainingRecord) Save() error {
+	b, err := json.MarshalIndent(tr, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(trainingRecordPath(), b, 0o644)
+}
+
+// AddSession records a session from its statistics along with any
+// lessons that were completed during it. Sessions where no aircraft were
+// worked aren't recorded.
+func (tr *TrainingRecord) AddSession(ss *SessionStats, tracon, scenario string, now time.Time) {
+	if len(ss.AircraftHandled) == 0 && len(tr.pendingLessons) == 0 {
+		return
+	}
+
+	tr.Sessions = append(tr.Sessions, TrainingSession{
+		Date:             now,
+		TRACON:           tracon,
+		Scenario:         scenario,
+		Controller:       ss.Controller,
+		Duration:         ss.End.Sub(ss.Start),
+		AircraftHandled:  len(ss.AircraftHandled),
+		SeparationLosses: ss.SeparationLosses,
+		GoArounds:        ss.GoArounds,
+		CommandErrors:    ss.CommandErrors,
+		AverageDelay:     ss.AverageDelay(),
+		Score:            ss.Score(),
+		Lessons:          tr.pendingLessons,
+	})
+	tr.pendingLessons = nil
+}
+
+// Currency returns a summary of the sessions worked at each position,
+// sorted by TRACON and position.
+func (tr *TrainingRecord) Currency(now time.Time) []PositionCurrency {
+	days := tr.CurrencyDays
+	if days <= 0 {
+		days = defaultCurrencyDays
+	}
+
+	var pc []PositionCurrency
+	for _, s := range tr.Sessions {
+		idx := slices.IndexFunc(pc, func(p PositionCurrency) bool {
+			return p.TRACON == s.TRACON && p.Controller == s.Controller
+		})
+		if idx == -1 {
+			pc = append(pc, PositionCurrency{TRACON: s.TRACON, Controller: s.Controller})
+			idx = len(pc) - 1
+		}
+		p := &pc[idx]
+		p.Sessions++
+		p.Total += s.Duration
+		if s.Date.After(p.Last) {
+			p.Last = s.Date
+		}
+	}
+
+	for i := range pc {
+		pc[i].Current = now.Sub(pc[i].Last) <= time.Duration(days)*24*time.Hour
+	}
+	slices.SortFunc(pc, func(a, b PositionCurrency) int {
+		if a.TRACON != b.TRACON {
+			return strings.Compare(a.TRACON, b.TRACON)
+		}
+		return strings.Compare(a.Controller, b.Controller)
+	})
+	return pc
+}
+
+// csvRows returns the sessions as rows of a CSV file, with a header.
+func (tr *TrainingRecord) csvRows() [][]string {
+	rows := [][]string{{"Trainee", "Date", "TRACON", "Scenario", "Controller", "Duration", "Aircraft handled",
+		"Separation losses", "Go arounds", "Command errors", "Average delay", "Score", "Lessons"}}
+	for _, s := range tr.Sessions {
+		rows = append(rows, []string{
+			tr.Trainee,
+			s.Date.Format(time.RFC3339),
+			s.TRACON,
+			s.Scenario,
+			s.Controller,
+			s.Duration.Round(time.Second).String(),
+			strconv.Itoa(s.AircraftHandled),
+			strconv.Itoa(s.SeparationLosses),
+			strconv.Itoa(s.GoArounds),
+			strconv.Itoa(s.CommandErrors),
+			s.AverageDelay.Round(time.Second).String(),
+			strconv.Itoa(s.Score),
+			strings.Join(s.Lessons, "; "),
+		})
+	}
+	return rows
+}
+
+// Export writes the training record as both JSON and CSV in the user's
+// configuration directory and returns the path of the JSON file.
+func (tr *TrainingRecord) Export() (string, error) {
+	base := path.Join(path.Dir(configFilePath()), "training-record-"+time.Now().Format("20060102-150405"))
+
+	js, err := json.MarshalIndent(tr, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(base+".json", js, 0o644); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	cw := csv.NewWriter(&sb)
+	cw.WriteAll(tr.csvRows())
+	if err := cw.Error(); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(base+".csv", []byte(sb.String()), 0o644); err != nil {
+		return "", err
+	}
+
+	return base + ".json", nil
+}
+
+// recordTrainingSession adds the World's session to the training record
+// and saves it; it's called when the user signs off.
+func (w *World) recordTrainingSession() {
+	if w.SessionStats == nil || w.IsReplay() {
+		return
+	}
+	tr := getTrainingRecord()
+	tr.AddSession(w.SessionStats, w.TRACON, w.SimDescription, time.Now())
+	if err := tr.Save(); err != nil {
+		lg.Errorf("Error saving training record: %v", err)
+	}
+}
+
+// DrawUI draws the training record section of the debrief window.
+func (tr *TrainingRecord) DrawUI(eventStream *EventStream) {
+	imgui.InputText("Trainee", &tr.Trainee)
+	days := int32(tr.CurrencyDays)
+	if imgui.InputIntV("Currency period (days)", &days, 1, 10, 0) {
+		tr.CurrencyDays = max(1, int(days))
+	}
+
+	imgui.InputText("##lesson", &tr.newLesson)
+	imgui.SameLine()
+	uiStartDisable(strings.TrimSpace(tr.newLesson) == "")
+	if imgui.Button("Mark lesson completed") {
+		tr.pendingLessons = append(tr.pendingLessons, strings.TrimSpace(tr.newLesson))
+		tr.newLesson = ""
+	}
+	uiEndDisable(strings.TrimSpace(tr.newLesson) == "")
+	if len(tr.pendingLessons) > 0 {
+		imgui.Text("Completed this session: " + strings.Join(tr.pendingLessons, ", "))
+	}
+
+	flags := imgui.TableFlagsBordersV | imgui.TableFlagsBordersOuterH | imgui.TableFlagsRowBg
+	if currency := tr.Currency(time.Now()); len(currency) == 0 {
+		imgui.Text("No sessions have been recorded yet.")
+	} else if imgui.BeginTableV("currency", 5, flags, imgui.Vec2{}, 0) {
+		imgui.TableSetupColumn("Position")
+		imgui.TableSetupColumn("Sessions")
+		imgui.TableSetupColumn("Total time")
+		imgui.TableSetupColumn("Last worked")
+		imgui.TableSetupColumn("Current")
+		imgui.TableHeadersRow()
+		for _, pc := range currency {
+			imgui.TableNextRow()
+			imgui.TableNextColumn()
+			imgui.Text(pc.TRACON + " " + pc.Controller)
+			imgui.TableNextColumn()
+			imgui.Text(strconv.Itoa(pc.Sessions))
+			imgui.TableNextColumn()
+			imgui.Text(pc.Total.Round(time.Minute).String())
+			imgui.TableNextColumn()
+			imgui.Text(pc.Last.Format("2006-01-02"))
+			imgui.TableNextColumn()
+			imgui.Text(Select(pc.Current, "Yes", "No"))
+		}
+		imgui.EndTable()
+	}
+
+	if imgui.Button("Export training record") {
+		if err := tr.Save(); err != nil {
+			ShowErrorDialog("Unable to save training record: %v", err)
+		} else if fn, err := tr.Export(); err != nil {
+			ShowErrorDialog("Unable to export training record: %v", err)
+		} else {
+			eventStream.Post(Event{Type: StatusMessageEvent, Message: fmt.Sprintf("Saved training record to %s", fn)})
+		}
+	}
+}
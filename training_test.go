@@ -0,0 +1,85 @@
+// training_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionScore(t *testing.T) {
+	ss := &SessionStats{}
+	if s := ss.Score(); s != 100 {
+		t.Errorf("got score %d for a clean session, expected 100", s)
+	}
+
+	ss = &SessionStats{SeparationLosses: 1, GoArounds: 2, CommandErrors: 3,
+		DelayedAircraft: 1, TotalDelay: 5 * time.Minute}
+	// 100 - 20 - 10 - 3 - 3 minutes of excess delay
+	if s := ss.Score(); s != 64 {
+		t.Errorf("got score %d, expected 64", s)
+	}
+
+	ss = &SessionStats{SeparationLosses: 10}
+	if s := ss.Score(); s != 0 {
+		t.Errorf("got score %d, expected it to be clamped to 0", s)
+	}
+}
+
+func TestTrainingRecord(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := start.Add(40 * 24 * time.Hour)
+	tr := &TrainingRecord{Trainee: "J. Smith", CurrencyDays: 30}
+
+	session := func(ctrl string, handled []string, d time.Duration) *SessionStats {
+		return &SessionStats{Controller: ctrl, Start: start, End: start.Add(d), AircraftHandled: handled}
+	}
+
+	// Sessions where nothing was done aren't recorded.
+	tr.AddSession(session("JFK_APP", nil, time.Hour), "N90", "JFK Arrivals", start)
+	if len(tr.Sessions) != 0 {
+		t.Errorf("recorded an empty session")
+	}
+
+	tr.pendingLessons = []string{"Lesson 1"}
+	tr.AddSession(session("JFK_APP", []string{"AAL1"}, time.Hour), "N90", "JFK Arrivals", start)
+	tr.AddSession(session("JFK_APP", []string{"AAL2", "UAL3"}, 30*time.Minute), "N90", "JFK Arrivals",
+		now.Add(-24*time.Hour))
+	tr.AddSession(session("PHL_APP", []string{"DAL4"}, 45*time.Minute), "PHL", "PHL Departures", start)
+
+	if len(tr.Sessions) != 3 {
+		t.Fatalf("got %d sessions, expected 3", len(tr.Sessions))
+	}
+	if l := tr.Sessions[0].Lessons; len(l) != 1 || l[0] != "Lesson 1" {
+		t.Errorf("got lessons %v for the first session, expected [Lesson 1]", l)
+	}
+	if len(tr.Sessions[1].Lessons) != 0 || len(tr.pendingLessons) != 0 {
+		t.Errorf("lessons weren't cleared after being recorded")
+	}
+
+	pc := tr.Currency(now)
+	if len(pc) != 2 {
+		t.Fatalf("got %d positions, expected 2", len(pc))
+	}
+	if pc[0].TRACON != "N90" || pc[0].Sessions != 2 || pc[0].Total != 90*time.Minute || !pc[0].Current {
+		t.Errorf("unexpected N90 currency %+v", pc[0])
+	}
+	if pc[1].TRACON != "PHL" || pc[1].Sessions != 1 || pc[1].Current {
+		t.Errorf("unexpected PHL currency %+v", pc[1])
+	}
+
+	rows := tr.csvRows()
+	if len(rows) != 4 {
+		t.Fatalf("got %d CSV rows, expected 4", len(rows))
+	}
+	for i, row := range rows {
+		if len(row) != len(rows[0]) {
+			t.Errorf("row %d has %d columns, expected %d", i, len(row), len(rows[0]))
+		}
+	}
+	if rows[1][0] != "J. Smith" || rows[1][5] != "1h0m0s" || rows[1][12] != "Lesson 1" {
+		t.Errorf("unexpected CSV row %v", rows[1])
+	}
+}
@@ -14,7 +14,6 @@ import (
 	"net/http"
 	"os"
 	"path"
-	"runtime"
 	"runtime/debug"
 	"sort"
 	"strconv"
@@ -34,6 +33,9 @@ var (
 		eventsSubscription *EventsSubscription
 
 		menuBarHeight float32
+		// The factor that the imgui style's sizes are currently scaled by
+		// to account for the display's DPI and the user's UI scale.
+		scale float32
 
 		showAboutDialog bool
 
@@ -43,6 +45,15 @@ var (
 		activeModalDialogs []*ModalDialogBox
 
 		newReleaseDialogChan chan *NewReleaseModalClient
+
+		replayFileDialog *FileSelectDialogBox
+		adsbDialog       *FileSelectDialogBox
+		profileDialog    *FileSelectDialogBox
+		sessionDialog    *FileSelectDialogBox
+
+		profileName string // name for a new display profile
+
+		voiceInput VoiceCommandInput
 	}
 
 	//go:embed icons/tower-256x256.png
@@ -193,6 +204,96 @@ var (
 		`Added I90 scenario (Jace Martin)`,
 		`Added full-screen mode`,
 		`Updated command entry so keyboard focus returns to STARS after issuing a control command`,
+		`STARS: runways and airport diagrams are drawn at short ranges (".DIAGRAM" to toggle)`,
+		`STARS: ".SUPPRESS" and ".FORCE" hide or always show an individual aircraft's datablock`,
+		`STARS: ".OWNERS" colors tracks by their owning controller, with a legend`,
+		`STARS: altitude filter presets (".FILTER SAVE name", ".FILTER name", the STARS settings, and F12 / shift-F12 to cycle)`,
+		`STARS: the SSA shows the time since the last departure roll from each runway`,
+		`STARS: an "XR" alert is shown when an aircraft can't meet an assigned crossing restriction`,
+		`STARS: conflict alerts are now issued for aircraft predicted to lose separation in the next 90 seconds`,
+		`STARS: ".FOLLOW" keeps the scope centered on an aircraft, ".FIT" shows a set of airports/fixes, and control-F1 returns home`,
+		`STARS: ".OVERVIEW" shows an inset with the whole facility and all traffic; click in it to move the view`,
+		`STARS: georeferenced images like VFR sectionals or OpenStreetMap tiles can be drawn under the scope (Settings > Underlays)`,
+		"Sessions can be recorded and played back later for debriefing; use the record and film buttons in the menu bar.",
+		`STARS: ".CENTER loc" and ".RR loc" center the scope or range rings; fix-radial-distance like JFK180010 can be used for locations, including after PLACE CNTR/PLACE RR`,
+		`STARS: optional time-based history trails that fade with age (Settings window)`,
+		`STARS: alt-click shows and copies the clicked point as a fix-radial-distance from the closest navaid (or from a fix entered first)`,
+		`STARS: ".QL pos..." quick looks other positions temporarily; the duration is set in the Settings window`,
+		`STARS: "*T" range-bearing lines can be anchored to aircraft by callsign and to fix-radial-distance locations`,
+		`STARS: ".MVA" toggles the MVA map; altitude labels are now always placed inside their areas`,
+		`ADS-B traces (readsb/adsbexchange JSON) can be imported to replay real-world arrivals as scheduled traffic`,
+		`STARS: leader line lengths can be set for individual aircraft, e.g. "6/3" [SLEW] for a length of 3 to the East`,
+		`Remote sims can allow spectators who see the sim with a delay; ".CLEAN" hides the DCB, lists, and cursor for streaming`,
+		`The current ATIS for each airport is available from the menu bar; its letter advances when the weather or runways change`,
+		`Instructors can join a remote sim as a coach for a signed-in controller; their cursor and highlighted aircraft appear on the controller's scope`,
+		`Weather: surface winds now come from each airport's METAR and winds aloft vary with altitude; scenarios may specify METARs and winds aloft, shown in the ATIS window`,
+		`Coaches can enter ".TAKEOVER" to temporarily take command authority for the position they are coaching and again to hand it back`,
+		`Weather presets (VFR day, marginal, low IFR, convective, or scenario-defined) can be chosen when creating a sim or from the ATIS window; they affect approach availability, go arounds, and weather deviations`,
+		`Pilot transmissions can be spoken using the system's text-to-speech (say, espeak-ng, or Windows speech); enable "Speak Pilot Transmissions" in the Audio settings`,
+		`Departures file the SID transition for their runway ("transition" in "departure_routes"); airports can give per-runway "initial_altitudes"`,
+		`Voice commands: with whisper.cpp installed and "Voice Commands" enabled in the Audio settings, hold the push-to-talk key (Insert by default) and speak clearances like "Delta four twenty-one, turn left heading two one zero"`,
+		`Scenarios can now include background traffic in neighboring facilities' airspace, shown with limited datablocks and never handed off`,
+		`Scenarios can specify VFR pattern traffic at satellite airports, including departures that request transitions through the TRACON's airspace`,
+		`Helicopters: scenarios can now include helicopter routes between heliports, with helicopters that hover-taxi, fly slowly and direct, and land vertically`,
+		`STARS: in single- and multi-sensor modes, tracks update as the radar antenna sweeps past them; the rotation period can be set per radar site or overridden in the scope's settings`,
+		`Arrivals may be trainers that request practice approaches; after each low approach, tower hands them back for another or vectors to depart the area`,
+		`STARS: departures can be handed off automatically when they are about to leave the scenario's departure airspace; enable it in the scope's settings`,
+		`Airports can specify TEC routes to nearby airports that short-haul props file automatically; "CTEC" clears an aircraft via the TEC route`,
+		`A non-radar display for procedural control training can be used in place of the STARS scope, with position report strips and time-at-fix separation checks using the Mach number technique (Settings window, Non-Radar section)`,
+		`STARS: arrows at the edge of the scope point toward off-screen aircraft with incoming handoffs, point outs, or conflict alerts`,
+		`Flight strips are colored by departure, arrival, or overflight, can be annotated, and can be pushed to the receiving controller by right-clicking them`,
+		`Clearances can be uplinked to jets via CPDLC by entering "UL" after the callsign, e.g. "AAL123 UL C280 DMERIT"; pilots acknowledge with WILCO after a short delay`,
+		`Scenario "controller_configs" can now specify initial altitude filters, so signing into a position sets up its scope center, range, maps, and filters`,
+		`STARS: function keys can be remapped with a stars-keymap.json file in the configuration directory; see the STARS documentation for details`,
+		`vice can now be connected to multiple simulations at once: check "Keep the current simulation connected" when starting a new one, then switch between them from the menu bar`,
+		`A CPDLC message composer (envelope icon in the menu bar) sends altitude, direct-to, speed, and frequency change uplinks; datalink equipage can optionally be shown in STARS datablocks`,
+		`Departure releases: if enabled in the departure settings, IFR departures wait on the ground until they're released from the Departure Releases window`,
+		`Aircraft can declare emergencies (engine or hydraulic failure, medical, NORDO, radio failure, or hijack), either randomly at a rate set in the launch settings or on demand from the Launch Control window`,
+		`The messages pane can flag aircraft whose pilots haven't read back the instructions they were given; enable it in the pane's settings`,
+		`Equipment failures (handoff automation, landlines, or a scope's radar feed) can be injected randomly or from the Launch Control window to practice manual coordination`,
+		`Instructors can take a radar site, or the radar coverage around a fix, out of service from the Launch Control window; affected tracks coast and drop until service is restored`,
+		`Session statistics (aircraft handled, handoffs, separation losses, go arounds, delays, and commands) are shown in the Debrief window (bar chart icon) and can be exported to JSON and CSV`,
+		`STARS: the rotation period and items of the timeshared datablock fields can be configured per facility with "timeshare" in "stars_config"`,
+		`The user interface can be scaled with the "UI scale" setting; vice now also adapts when its window is moved to a monitor with a different DPI and supports high-DPI displays on Linux`,
+		`Losses of separation are now checked against the 3nm/5nm radar, 1,000', and wake turbulence standards, are reported in the messages pane as operational errors, and are detailed in the debrief`,
+		`STARS: hardware controls on game controllers and control surfaces can be bound to the range, brightness, sim rate, and preference sets with a stars-controls.json file; see the STARS documentation for details`,
+		`Scenario authors can reload the scenario group's JSON file from the Launch Control window; changes to routes and spawn rates apply to aircraft spawned from then on without restarting the sim`,
+		`Scenario editor (pencil icon): edit controller positions, routes, runway configurations, and spawn rates and build routes by clicking fixes on the scope`,
+		`A companion web page with flight strips, arrival and departure lists, and timers can be shown on a tablet next to the scope; use "Copy companion page link" in the Settings window to open it`,
+		`Backups of the configuration and of scenario files saved by the scenario editor are kept automatically and can be restored from the Settings window and the scenario editor`,
+		`STARS: CRDA runway pairs that don't share a runway can be enabled at once and switched between stagger and tie mode while enabled; converging runway pairs can have their own qualification regions`,
+		`Scenario editor: CRDA qualification regions can be adjusted by dragging them on the scope and customized for each converging runway pair`,
+		`Scenarios can enable random events—bird activity, drones on final, laser strikes, and parachute jumping—that happen at a rate set in the launch control window or can be triggered on demand`,
+		`An ASDE-X style surface display with runway incursion alerts can be enabled in the settings window for tower positions`,
+		`The new "Pilot realism" setting varies pilots' check-ins and readbacks with hesitation, non-standard phraseology, missing information, and accented voices`,
+		`Difficulty presets (Basic, Realistic, and Expert) set pilot realism, readback and pilot errors, response delays, frequency congestion, and event rates with a single selection when creating a sim`,
+		`A 3D tower view showing runways, final approach courses, and aircraft from the tower cab can be enabled in the settings window for local control`,
+		`Scenarios can simulate ground movement: departures taxi from parking spots to the runway and arrivals exit the runway and taxi to parking, with new ground control commands`,
+		`Arrival ETAs at the meter fix, final approach fix, and runway are computed along each aircraft's route with the winds and are used to order the companion arrival list and the tower lists`,
+		`Per-airport display filters and symbols in the STARS settings help consolidated positions tell the traffic for different airports apart`,
+		`Multiple STARS scopes can be shown side by side (e.g., a finals scope next to a wide-area one) with aircraft selection kept in sync between them`,
+		`Mouse and keyboard input can be recorded with the ` + FontAwesomeIconBug + ` button and played back against the same simulation state to help reproduce UI bugs`,
+		`Named display profiles save the entire window layout and scope settings so that each position can have its own, and can be exported to and imported from JSON files`,
+		`The primary controller can export a running sim from the settings window and it can then be imported on another server to continue the session there`,
+		`Scenarios can define radar coverage gaps where tracks coast and are then dropped, and STARS scopes can be set for beacon-only display`,
+		`The new "Mode S" full datablock format shows the heading, selected altitude, and IAS that aircraft downlink`,
+		`The scenario editor can define fixes and airspace volumes by clicking on the scope, writing their coordinates into the scenario file`,
+		`A new chart pane shows the approach plates and SID and STAR charts that scenarios list, following the selected aircraft's procedure`,
+		`The lines of full datablocks can be given as templates with fields like {callsign} and {exit}, with a live preview in the STARS settings`,
+		`The wind components for the active runways are shown in the ATIS window, with a suggested runway configuration and an alert when a runway's tailwind limit is exceeded`,
+		`STARS: full datablocks can be shown in an en-route (ERAM-style) format with the assigned or interim altitude, groundspeed, and rotating exit fix, destination, and type`,
+		`STARS: point outs flash at the receiving position until they are accepted or rejected, and accepted point outs stay marked until they are cleared`,
+		`Distinct, configurable sounds play when arrivals, departures, and satellite airport VFRs check in`,
+		`Sessions are kept in a training record with position currency, lesson completions, and CSV/JSON export`,
+		`Coordination window for sending APREQs, ride requests, and other messages between controllers`,
+		`Some piston aircraft lack RNAV and can't go direct to distant fixes; "RON" has aircraft resume own navigation`,
+		`Alert sounds can be loaded from .wav and .ogg files, with per-alert volume and repeat interval; new point out and frequency message alerts`,
+		`New alert list that collects conflict alerts, MSAWs, emergency squawks, handoff timeouts, altitude deviations, and equipment failures by severity`,
+		`Weather radar uses the six NWS intensity levels and can loop through recent images; the SSA WX filter shows the image's time and age`,
+		`Pilots make PIREPs of turbulence and icing, shown in a new PIREPs window, and ask for or refuse altitudes to avoid them`,
+		`Aircraft can be cleared to hold with "HOLD/fix", flying published or ad-hoc holds with the correct entries; holds are drawn on the scope`,
+		`Amend an aircraft's route with ".ROUTE fixes" and a slew; ".ROUTE" and a slew toggles drawing its route with fixes labeled`,
+		`Routes that don't follow the scenario's preferential departure and arrival routes are flagged in datablocks and on flight strips`,
 	}
 )
 
@@ -218,9 +319,8 @@ func imguiInit() *imgui.Context {
 }
 
 func uiInit(r Renderer, p Platform, es *EventStream) {
-	if runtime.GOOS == "windows" {
-		imgui.CurrentStyle().ScaleAllSizes(p.DPIScale())
-	}
+	ui.scale = p.WindowScale() * globalConfig.UIScale
+	imgui.CurrentStyle().ScaleAllSizes(ui.scale)
 
 	ui.font = GetFont(FontIdentifier{Name: "Roboto Regular", Size: globalConfig.UIFontSize})
 	ui.aboutFont = GetFont(FontIdentifier{Name: "Roboto Regular", Size: 18})
@@ -292,6 +392,41 @@ func uiEndDisable(b bool) {
 	}
 }
 
+// weatherPresetCombo draws a combo box for selecting one of the given
+// weather presets, where "" is the scenario's default weather. It returns
+// true if the selection was changed.
+func weatherPresetCombo(label string, preset *string, presets []string) bool {
+	changed := false
+	if imgui.BeginComboV(label, Select(*preset == "", "Scenario default", *preset), 0) {
+		if imgui.SelectableV("Scenario default", *preset == "", 0, imgui.Vec2{}) && *preset != "" {
+			*preset, changed = "", true
+		}
+		for _, name := range presets {
+			if imgui.SelectableV(name, name == *preset, 0, imgui.Vec2{}) && name != *preset {
+				*preset, changed = name, true
+			}
+		}
+		imgui.EndCombo()
+	}
+	return changed
+}
+
+// uiUpdateScale keeps the imgui style and fonts in sync with the user's UI
+// scale and the DPI of the monitor that the window is on, which changes if
+// it is moved to a monitor with a different DPI.
+func uiUpdateScale(p Platform) {
+	// ScaleAllSizes rounds the sizes to integers, so wait until the user
+	// is done dragging the UI scale slider so that the error doesn't
+	// accumulate.
+	if scale := p.WindowScale() * globalConfig.UIScale; scale != ui.scale && !imgui.IsAnyItemActive() {
+		imgui.CurrentStyle().ScaleAllSizes(scale / ui.scale)
+		ui.scale = scale
+	}
+	// The fonts were rasterized for the DPI at startup and are resampled
+	// for anything else.
+	imgui.CurrentIO().SetFontGlobalScale(fontScale(p.WindowScale()) / fontsScale * globalConfig.UIScale)
+}
+
 func drawUI(p Platform, r Renderer, w *World, eventStream *EventStream, stats *Stats) {
 	if ui.newReleaseDialogChan != nil {
 		select {
@@ -307,6 +442,9 @@ func drawUI(p Platform, r Renderer, w *World, eventStream *EventStream, stats *S
 		}
 	}
 
+	ui.voiceInput.Update(w, eventStream)
+	uiUpdateScale(p)
+
 	imgui.PushFont(ui.font.ifont)
 	if imgui.BeginMainMenuBar() {
 		imgui.PushStyleColor(imgui.StyleColorButton, imgui.CurrentStyle().Color(imgui.StyleColorMenuBarBg))
@@ -336,6 +474,8 @@ func drawUI(p Platform, r Renderer, w *World, eventStream *EventStream, stats *S
 			imgui.SetTooltip("Start new simulation")
 		}
 
+		worldTabs.DrawMenu()
+
 		if w != nil && w.Connected() {
 			if imgui.Button(FontAwesomeIconCog) {
 				w.ToggleActivateSettingsWindow()
@@ -350,6 +490,136 @@ func drawUI(p Platform, r Renderer, w *World, eventStream *EventStream, stats *S
 			if imgui.IsItemHovered() {
 				imgui.SetTooltip("Show available departures, arrivals, and approaches")
 			}
+
+			if !w.IsReplay() {
+				if imgui.Button(FontAwesomeIconBroadcastTower) {
+					w.ToggleShowATISWindow()
+				}
+				if imgui.IsItemHovered() {
+					imgui.SetTooltip("Show the current ATIS")
+				}
+
+				if imgui.Button(FontAwesomeIconTrafficLight) {
+					w.ToggleShowReleasesWindow()
+				}
+				if imgui.IsItemHovered() {
+					imgui.SetTooltip("Show departures awaiting release")
+				}
+
+				if imgui.Button(FontAwesomeIconChartBar) {
+					w.ToggleShowDebriefWindow()
+				}
+				if imgui.IsItemHovered() {
+					imgui.SetTooltip("Show session statistics")
+				}
+
+				if imgui.Button(FontAwesomeIconEnvelope) {
+					w.ToggleShowDatalinkWindow()
+				}
+				if imgui.IsItemHovered() {
+					imgui.SetTooltip("Send CPDLC datalink clearances")
+				}
+
+				if imgui.Button(FontAwesomeIconComments) {
+					w.ToggleShowCoordinationWindow()
+				}
+				if imgui.IsItemHovered() {
+					imgui.SetTooltip("Send coordination messages to other controllers")
+				}
+
+				if imgui.Button(FontAwesomeIconCloud) {
+					w.ToggleShowPIREPWindow()
+				}
+				if imgui.IsItemHovered() {
+					imgui.SetTooltip("Show pilot reports (PIREPs)")
+				}
+
+				am := w.AlertManager()
+				n := am.Unacknowledged(AlertCaution, time.Now())
+				if n > 0 {
+					c := Select(am.Unacknowledged(AlertWarning, time.Now()) > 0, AlertWarning, AlertCaution).Color()
+					imgui.PushStyleColor(imgui.StyleColorText, imgui.Vec4{c.R, c.G, c.B, 1})
+				}
+				if imgui.Button(FontAwesomeIconExclamationTriangle) {
+					am.ToggleShow()
+				}
+				if n > 0 {
+					imgui.PopStyleColor()
+				}
+				if imgui.IsItemHovered() {
+					imgui.SetTooltip(Select(n > 0, fmt.Sprintf("Show alerts (%d unacknowledged)", n), "Show alerts"))
+				}
+
+				if imgui.Button(FontAwesomeIconEdit) {
+					w.ToggleShowScenarioEditor()
+				}
+				if imgui.IsItemHovered() {
+					imgui.SetTooltip("Edit scenario group files")
+				}
+			}
+		}
+
+		if w != nil && w.Connected() && !w.IsReplay() {
+			if w.IsRecording() {
+				if imgui.Button(FontAwesomeIconStopCircle) {
+					w.StopRecording()
+				}
+				if imgui.IsItemHovered() {
+					imgui.SetTooltip("Stop recording")
+				}
+			} else {
+				if imgui.Button(FontAwesomeIconDotCircle) {
+					if fn, err := w.StartRecording(); err != nil {
+						uiShowModalDialog(NewModalDialogBox(&ErrorModalClient{message: "Unable to start recording: " + err.Error()}), true)
+					} else {
+						eventStream.Post(Event{Type: StatusMessageEvent, Message: "Recording to " + fn})
+					}
+				}
+				if imgui.IsItemHovered() {
+					imgui.SetTooltip("Record this session for later playback")
+				}
+			}
+
+			if wm.inputRecorder != nil {
+				if imgui.Button(FontAwesomeIconBug) {
+					uiStopInputRecording(eventStream)
+				}
+				if imgui.IsItemHovered() {
+					imgui.SetTooltip("Stop recording input")
+				}
+			} else if wm.inputPlayer == nil {
+				if imgui.Button(FontAwesomeIconBug) {
+					if ir, err := NewInputRecorder(w, p.DisplaySize()); err != nil {
+						uiShowModalDialog(NewModalDialogBox(&ErrorModalClient{message: "Unable to record input: " + err.Error()}), true)
+					} else {
+						wm.inputRecorder = ir
+						eventStream.Post(Event{Type: StatusMessageEvent, Message: "Recording input for a bug report"})
+					}
+				}
+				if imgui.IsItemHovered() {
+					imgui.SetTooltip("Record mouse and keyboard input to reproduce a bug")
+				}
+			}
+
+			if imgui.Button(FontAwesomeIconFileImport) {
+				ui.adsbDialog = NewDirectorySelectDialogBox("Import ADS-B Traces...", "",
+					func(dir string) { uiImportADSBTraces(w, eventStream, dir) })
+				ui.adsbDialog.Activate()
+			}
+			if imgui.IsItemHovered() {
+				imgui.SetTooltip("Import real-world arrivals from a directory of ADS-B traces")
+			}
+		}
+
+		if imgui.Button(FontAwesomeIconFilm) {
+			if ui.replayFileDialog == nil {
+				ui.replayFileDialog = NewFileSelectDialogBox("Open Replay...", []string{".replay"}, "", uiOpenReplay)
+				ui.replayFileDialog.directory = replayDirectory()
+			}
+			ui.replayFileDialog.Activate()
+		}
+		if imgui.IsItemHovered() {
+			imgui.SetTooltip("Play back a recorded session")
 		}
 
 		if imgui.Button(FontAwesomeIconKeyboard) {
@@ -382,6 +652,15 @@ func drawUI(p Platform, r Renderer, w *World, eventStream *EventStream, stats *S
 			imgui.SetTooltip("Display online vice documentation")
 		}
 
+		if ui.voiceInput.Recording() {
+			imgui.PushStyleColor(imgui.StyleColorText, imgui.Vec4{1, .2, .2, 1})
+			imgui.Text(FontAwesomeIconMicrophone)
+			imgui.PopStyleColor()
+			if imgui.IsItemHovered() {
+				imgui.SetTooltip("Recording voice command")
+			}
+		}
+
 		width, _ := ui.font.BoundText(FontAwesomeIconInfoCircle, 0)
 		imgui.SetCursorPos(imgui.Vec2{p.DisplaySize()[0] - float32(6*width+15), 0})
 		if imgui.Button(FontAwesomeIconInfoCircle) {
@@ -409,12 +688,36 @@ func drawUI(p Platform, r Renderer, w *World, eventStream *EventStream, stats *S
 	ui.menuBarHeight = imgui.CursorPos().Y - 1
 
 	if w != nil {
-		w.DrawSettingsWindow()
+		w.DrawSettingsWindow(r, eventStream)
 
 		w.DrawScenarioInfoWindow()
 
+		w.DrawATISWindow()
+
+		w.DrawDatalinkWindow(eventStream)
+
+		if !w.IsReplay() {
+			w.DrawCoordinationWindow(eventStream)
+		}
+
+		w.AlertManager().DrawWindow(eventStream)
+
+		w.DrawPIREPWindow()
+
+		w.DrawReleasesWindow(eventStream)
+
+		w.DrawDebriefWindow(eventStream)
+
+		w.DrawScenarioEditor()
+
 		w.DrawMissingPrimaryDialog()
 
+		w.CheckRunwayWinds()
+
+		if w.replay != nil {
+			w.replay.DrawUI(w)
+		}
+
 		if w.LaunchConfig.Controller == w.Callsign {
 			if w.launchControlWindow == nil {
 				w.launchControlWindow = MakeLaunchControlWindow(w)
@@ -431,6 +734,19 @@ func drawUI(p Platform, r Renderer, w *World, eventStream *EventStream, stats *S
 
 	drawActiveDialogBoxes()
 
+	if ui.replayFileDialog != nil {
+		ui.replayFileDialog.Draw()
+	}
+	if ui.adsbDialog != nil {
+		ui.adsbDialog.Draw()
+	}
+	if ui.profileDialog != nil {
+		ui.profileDialog.Draw()
+	}
+	if ui.sessionDialog != nil {
+		ui.sessionDialog.Draw()
+	}
+
 	wmDrawUI(p)
 
 	uiDrawKeyboardWindow(w)
@@ -445,6 +761,42 @@ func drawUI(p Platform, r Renderer, w *World, eventStream *EventStream, stats *S
 	stats.renderUI = r.RenderCommandBuffer(cb)
 }
 
+func uiOpenReplay(filename string) {
+	rp, err := LoadReplay(filename)
+	if err != nil {
+		uiShowModalDialog(NewModalDialogBox(&ErrorModalClient{message: "Unable to load replay: " + err.Error()}), true)
+		return
+	}
+	w, err := rp.Connect()
+	if err != nil {
+		uiShowModalDialog(NewModalDialogBox(&ErrorModalClient{message: "Unable to play replay: " + err.Error()}), true)
+		return
+	}
+	newWorldChan <- w
+}
+
+func uiStopInputRecording(eventStream *EventStream) {
+	ir := wm.inputRecorder
+	wm.inputRecorder = nil
+	if err := ir.Close(); err != nil {
+		uiShowModalDialog(NewModalDialogBox(&ErrorModalClient{message: "Unable to save input recording: " + err.Error()}), true)
+		return
+	}
+	eventStream.Post(Event{Type: StatusMessageEvent, Message: "Saved input recording to " + ir.Filename})
+}
+
+func uiImportADSBTraces(w *World, eventStream *EventStream, dir string) {
+	n, err := w.ImportADSBTraces(dir)
+	if err != nil {
+		uiShowModalDialog(NewModalDialogBox(&ErrorModalClient{message: "Unable to import ADS-B traces: " + err.Error()}), true)
+		return
+	}
+	eventStream.Post(Event{
+		Type:    StatusMessageEvent,
+		Message: fmt.Sprintf("Scheduled %d arrivals from ADS-B traces", n),
+	})
+}
+
 func drawActiveDialogBoxes() {
 	for len(ui.activeModalDialogs) > 0 {
 		d := ui.activeModalDialogs[0]
@@ -522,7 +874,7 @@ func DrawComboBox(state *ComboBoxState, config ComboBoxDisplayConfig,
 		sz.Y = float32((1 + config.MaxDisplayed) * (6 + ui.font.size))
 	}
 
-	sz.X *= Select(runtime.GOOS == "windows", platform.DPIScale(), float32(1))
+	sz.X *= ui.scale
 	if imgui.BeginTableV("##"+id, len(config.ColumnHeaders), flags, sz, 0.0) {
 		for _, name := range config.ColumnHeaders {
 			imgui.TableSetupColumn(name)
@@ -692,12 +1044,19 @@ func (m *ModalDialogBox) Draw() {
 type ConnectModalClient struct {
 	config      NewSimConfiguration
 	allowCancel bool
+
+	// If set, the scenario that is initially selected.
+	tracon, group, scenario string
 }
 
 func (c *ConnectModalClient) Title() string { return "New Simulation" }
 
 func (c *ConnectModalClient) Opening() {
 	c.config = MakeNewSimConfiguration()
+	if _, ok := c.config.selectedServer.configs[c.tracon]; ok && c.scenario != "" {
+		c.config.SetTRACON(c.tracon)
+		c.config.SetScenario(c.group, c.scenario)
+	}
 }
 
 func (c *ConnectModalClient) Buttons() []ModalDialogButton {
@@ -1256,7 +1615,7 @@ func (fs *FileSelectDialogBox) Draw() {
 		fileSelected := false
 		// unique per-directory id maintains the scroll position in each
 		// directory (and starts newly visited ones at the top!)
-		tableScale := Select(runtime.GOOS == "windows", platform.DPIScale(), float32(1))
+		tableScale := ui.scale
 		if imgui.BeginTableV("Files##"+fs.directory, 1, flags,
 			imgui.Vec2{tableScale * 500, float32(platform.WindowSize()[1] * 3 / 4)}, 0) {
 			imgui.TableSetupColumn("Filename")
@@ -1596,6 +1955,17 @@ type LaunchControlWindow struct {
 	w          *World
 	departures []*LaunchDeparture
 	arrivals   []*LaunchArrival
+
+	emergencyCallsign string
+	emergencyType     EmergencyType
+
+	failure        SystemFailure
+	failureMinutes int32
+
+	radarOutage        RadarOutage
+	radarOutageMinutes int32
+
+	randomEventType RandomEventType
 }
 
 type LaunchDeparture struct {
@@ -1630,7 +2000,12 @@ func (la *LaunchArrival) Reset() {
 }
 
 func MakeLaunchControlWindow(w *World) *LaunchControlWindow {
-	lc := &LaunchControlWindow{w: w}
+	lc := &LaunchControlWindow{
+		w:                  w,
+		failureMinutes:     5,
+		radarOutage:        RadarOutage{Radius: 20},
+		radarOutageMinutes: 10,
+	}
 
 	config := &w.LaunchConfig
 	for _, airport := range SortedMapKeys(config.DepartureRates) {
@@ -1768,7 +2143,7 @@ func (lc *LaunchControlWindow) Draw(w *World, eventStream *EventStream) {
 
 		flags := imgui.TableFlagsBordersH | imgui.TableFlagsBordersOuterV | imgui.TableFlagsRowBg |
 			imgui.TableFlagsSizingStretchProp
-		tableScale := Select(runtime.GOOS == "windows", platform.DPIScale(), float32(1))
+		tableScale := ui.scale
 		if imgui.BeginTableV("dep", 9, flags, imgui.Vec2{tableScale * 600, 0}, 0.0) {
 			imgui.TableSetupColumn("Airport")
 			imgui.TableSetupColumn("Launches")
@@ -1885,19 +2260,32 @@ func (lc *LaunchControlWindow) Draw(w *World, eventStream *EventStream) {
 		}
 	} else {
 		// Slightly messy, but DrawActiveDepartureRunways expects a table context...
-		tableScale := Select(runtime.GOOS == "windows", platform.DPIScale(), float32(1))
+		tableScale := ui.scale
 		if imgui.BeginTableV("runways", 2, 0, imgui.Vec2{tableScale * 500, 0}, 0.) {
 			lc.w.LaunchConfig.DrawActiveDepartureRunways()
 			imgui.EndTable()
 		}
 		changed := lc.w.LaunchConfig.DrawDepartureUI()
 		changed = lc.w.LaunchConfig.DrawArrivalUI() || changed
+		changed = lc.w.LaunchConfig.DrawEmergencyUI() || changed
+		changed = lc.w.LaunchConfig.DrawFailureUI() || changed
+		if len(lc.w.RandomEvents.Types()) > 0 {
+			changed = lc.w.LaunchConfig.DrawRandomEventUI() || changed
+		}
+		changed = lc.w.LaunchConfig.DrawPilotRealismUI() || changed
 
 		if changed {
 			lc.w.SetLaunchConfig(lc.w.LaunchConfig)
 		}
 	}
 
+	imgui.Separator()
+	lc.drawEmergencyTrigger(eventStream)
+	lc.drawFailureTrigger(eventStream)
+	lc.drawRadarOutageTrigger(eventStream)
+	lc.drawRandomEventTrigger(eventStream)
+	lc.drawScenarioReload(eventStream)
+
 	imgui.End()
 
 	if !showLaunchControls {
@@ -1954,6 +2342,13 @@ Either one or both of *A* and *S* may be specified.`, "*CCAMRN/A110+*"},
 	[3]string{"*ID*", `"Ident."`, "*ID*"},
 	[3]string{"*CVS*", `"Climb via the SID"`, "*CVS*"},
 	[3]string{"*DVS*", `"Descend via the STAR"`, "*CVS*"},
+	[3]string{"*TX_dest*/_twy_", `"Taxi to _dest_ via _twy_", where _dest_ is a runway or a parking spot.
+Taxiways are optional.`, "*TX4/B/A*"},
+	[3]string{"*X_rwy", `"Cross runway _rwy_".`, "*X13L*"},
+	[3]string{"*HP*", `"Hold position".`, "*HP*"},
+	[3]string{"*CT*", `"Continue taxi".`, "*CT*"},
+	[3]string{"*LUAW*", `"Line up and wait".`, "*LUAW*"},
+	[3]string{"*CTO*", `"Cleared for takeoff".`, "*CTO*"},
 }
 
 var starsCommands = [][2]string{
@@ -192,6 +192,59 @@ func (co CardinalOrdinalDirection) Heading() float32 {
 	return float32(co) * 45
 }
 
+// FlipHorizontal returns the direction mirrored so that it points east
+// (if east is true) or west; directions that already point that way are
+// returned unchanged. Due north and south are turned to the requested
+// side.
+func (co CardinalOrdinalDirection) FlipHorizontal(east bool) CardinalOrdinalDirection {
+	if east {
+		switch co {
+		case NorthWest, North:
+			return NorthEast
+		case West:
+			return East
+		case SouthWest, South:
+			return SouthEast
+		}
+	} else {
+		switch co {
+		case NorthEast, North:
+			return NorthWest
+		case East:
+			return West
+		case SouthEast, South:
+			return SouthWest
+		}
+	}
+	return co
+}
+
+// FlipVertical returns the direction mirrored so that it points north
+// (if north is true) or south; directions that already point that way are
+// returned unchanged. Due east and west are turned to the requested side.
+func (co CardinalOrdinalDirection) FlipVertical(north bool) CardinalOrdinalDirection {
+	if north {
+		switch co {
+		case SouthWest, West:
+			return NorthWest
+		case South:
+			return North
+		case SouthEast, East:
+			return NorthEast
+		}
+	} else {
+		switch co {
+		case NorthWest, West:
+			return SouthWest
+		case North:
+			return South
+		case NorthEast, East:
+			return SouthEast
+		}
+	}
+	return co
+}
+
 func (co CardinalOrdinalDirection) ShortString() string {
 	switch co {
 	case North:
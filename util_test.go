@@ -202,3 +202,34 @@ func TestReduceMap(t *testing.T) {
 		t.Errorf("Expected %d from ReduceMap; got %d", 5+5+6+6+1, length)
 	}
 }
+
+func TestCardinalOrdinalDirectionFlip(t *testing.T) {
+	for _, test := range []struct {
+		dir        CardinalOrdinalDirection
+		east, west CardinalOrdinalDirection
+		north      CardinalOrdinalDirection
+		south      CardinalOrdinalDirection
+	}{
+		{North, NorthEast, NorthWest, North, South},
+		{NorthEast, NorthEast, NorthWest, NorthEast, SouthEast},
+		{East, East, West, NorthEast, SouthEast},
+		{SouthEast, SouthEast, SouthWest, NorthEast, SouthEast},
+		{South, SouthEast, SouthWest, North, South},
+		{SouthWest, SouthEast, SouthWest, NorthWest, SouthWest},
+		{West, East, West, NorthWest, SouthWest},
+		{NorthWest, NorthEast, NorthWest, NorthWest, SouthWest},
+	} {
+		if d := test.dir.FlipHorizontal(true); d != test.east {
+			t.Errorf("%s flipped east gave %s; expected %s", test.dir.ShortString(), d.ShortString(), test.east.ShortString())
+		}
+		if d := test.dir.FlipHorizontal(false); d != test.west {
+			t.Errorf("%s flipped west gave %s; expected %s", test.dir.ShortString(), d.ShortString(), test.west.ShortString())
+		}
+		if d := test.dir.FlipVertical(true); d != test.north {
+			t.Errorf("%s flipped north gave %s; expected %s", test.dir.ShortString(), d.ShortString(), test.north.ShortString())
+		}
+		if d := test.dir.FlipVertical(false); d != test.south {
+			t.Errorf("%s flipped south gave %s; expected %s", test.dir.ShortString(), d.ShortString(), test.south.ShortString())
+		}
+	}
+}
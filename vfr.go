@@ -0,0 +1,309 @@
+// vfr.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SatelliteAirportTraffic specifies the VFR activity at a satellite
+// airport under the TRACON's airspace. Aircraft there either fly a few
+// laps in the pattern doing touch-and-goes or depart straight out; some
+// of the departures call up to request a transition through the
+// TRACON's airspace.
+type SatelliteAirportTraffic struct {
+	Rate            int     `json:"rate"` // aircraft per hour
+	Runway          string  `json:"runway"`
+	RightTraffic    bool    `json:"right_traffic"`
+	PatternAltitude int     `json:"pattern_altitude"` // AGL
+	TouchAndGoes    int     `json:"touch_and_goes"`   // maximum number of laps in the pattern
+	TransitionRate  float32 `json:"transition_rate"`  // fraction of departures that request a transition
+}
+
+// GA aircraft types used for VFR traffic; ones that aren't in the
+// performance database are ignored.
+var vfrAircraftTypes = []string{"C152", "C172", "C182", "P28A", "PA28", "SR22", "BE36", "DA40", "M20P"}
+
+func (st *SatelliteAirportTraffic) PostDeserialize(icao string, sg *ScenarioGroup, e *ErrorLogger) {
+	e.Push("Airport " + icao)
+	defer e.Pop()
+
+	ap, ok := database.Airports[icao]
+	if !ok {
+		e.ErrorString("airport not found in database")
+		return
+	}
+
+	if st.Rate < 0 {
+		e.ErrorString("\"rate\" must be positive")
+	}
+	if _, ok := LookupRunway(icao, st.Runway); !ok {
+		e.ErrorString("runway \"%s\" is unknown. Options: %s", st.Runway, ap.ValidRunways())
+	} else if _, ok := LookupOppositeRunway(icao, st.Runway); !ok {
+		e.ErrorString("runway \"%s\": unable to find opposite end", st.Runway)
+	}
+
+	if st.PatternAltitude == 0 {
+		st.PatternAltitude = 1000
+	} else if st.PatternAltitude < 500 || st.PatternAltitude > 3000 {
+		e.ErrorString("\"pattern_altitude\" %d should be between 500 and 3000", st.PatternAltitude)
+	}
+	if st.TouchAndGoes == 0 {
+		st.TouchAndGoes = 3
+	} else if st.TouchAndGoes < 0 {
+		e.ErrorString("\"touch_and_goes\" must be positive")
+	}
+	if st.TransitionRate < 0 || st.TransitionRate > 1 {
+		e.ErrorString("\"transition_rate\" must be between 0 and 1")
+	}
+}
+
+// trafficPattern returns the waypoints for the given number of laps in
+// the pattern for the given runway, ending with a full stop landing.
+// Positions are given in nm with the threshold of the runway at the
+// origin and v, a unit vector, pointing along it.
+func trafficPattern(v [2]float32, length float32, right bool, laps int) [][2]float32 {
+	// Left or right perpendicular, pointing toward the downwind leg.
+	side := Select(right, [2]float32{v[1], -v[0]}, [2]float32{-v[1], v[0]})
+	pt := func(along, across float32) [2]float32 {
+		return add2f(scale2f(v, along), scale2f(side, across))
+	}
+
+	var p [][2]float32
+	for i := 0; i < laps; i++ {
+		p = append(p,
+			pt(length+0.8, 0), // crosswind turn
+			pt(length+0.8, 1), // downwind
+			pt(-1.5, 1),       // base
+			pt(-1.5, 0),       // final
+			pt(0, 0))          // touchdown
+	}
+	return p
+}
+
+func patternWaypoints(icao string, st *SatelliteAirportTraffic, nmPerLongitude float32, laps int) []Waypoint {
+	rwy, _ := LookupRunway(icao, st.Runway)
+	opp, _ := LookupOppositeRunway(icao, st.Runway)
+	t, o := ll2nm(rwy.Threshold, nmPerLongitude), ll2nm(opp.Threshold, nmPerLongitude)
+	v := sub2f(o, t)
+	length := length2f(v)
+	v = normalize2f(v)
+
+	elev := float32(rwy.Elevation)
+	patternAlt := elev + float32(st.PatternAltitude)
+	at := func(alt float32) *AltitudeRestriction {
+		return &AltitudeRestriction{Range: [2]float32{alt, alt}}
+	}
+
+	var wps []Waypoint
+	for i, p := range trafficPattern(v, length, st.RightTraffic, laps) {
+		wp := Waypoint{
+			Fix:      fmt.Sprintf("_%s_PATTERN_%d", icao, i),
+			Location: nm2ll(add2f(t, p), nmPerLongitude),
+		}
+		switch i % 5 {
+		case 1, 2:
+			wp.AltitudeRestriction = at(patternAlt)
+		case 3:
+			wp.AltitudeRestriction = at(elev + 500)
+		case 4:
+			wp.AltitudeRestriction = at(elev)
+			wp.FlyOver = true
+		}
+		wps = append(wps, wp)
+	}
+	wps[len(wps)-1].Delete = true
+
+	return wps
+}
+
+// sampleGAAircraft returns an aircraft of one of the given types with a
+// random N-number callsign that is squawking VFR.
+func (w *World) sampleGAAircraft(types []string) (*Aircraft, string, error) {
+	acType := SampleSlice(FilterSlice(types, func(t string) bool {
+		_, ok := database.AircraftPerformance[t]
+		return ok
+	}))
+	if acType == "" {
+		return nil, "", fmt.Errorf("none of the aircraft types %s found in performance database",
+			strings.Join(types, ", "))
+	}
+
+	for {
+		callsign := "N" + strconv.Itoa(1+rand.Intn(9))
+		for i := rand.Intn(3); i >= 0; i-- {
+			callsign += strconv.Itoa(rand.Intn(10))
+		}
+		for i := rand.Intn(3); i > 0; i-- {
+			callsign += string(rune('A' + rand.Intn(26)))
+		}
+		if _, ok := w.Aircraft[callsign]; !ok {
+			return &Aircraft{
+				Callsign:       callsign,
+				AssignedSquawk: Squawk(0o1200),
+				Squawk:         Squawk(0o1200),
+				Mode:           Charlie,
+			}, acType, nil
+		}
+	}
+}
+
+// CreateSatelliteVFR returns a VFR aircraft that has just taken off from
+// the given satellite airport.
+func (w *World) CreateSatelliteVFR(icao string, st *SatelliteAirportTraffic) (*Aircraft, error) {
+	ac, acType, err := w.sampleGAAircraft(vfrAircraftTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	ap := database.Airports[icao]
+	perf := database.AircraftPerformance[acType]
+	rwy, _ := LookupRunway(icao, st.Runway)
+	opp, _ := LookupOppositeRunway(icao, st.Runway)
+	elev := float32(rwy.Elevation)
+
+	// Start just after liftoff, halfway down the runway.
+	start := Waypoint{
+		Fix:      "_" + icao + "_LIFTOFF",
+		Location: Point2LL(lerp2f(0.5, [2]float32(rwy.Threshold), [2]float32(opp.Threshold))),
+	}
+	wps := []Waypoint{start}
+
+	destination := icao
+	pattern := rand.Intn(2) == 0
+	if pattern {
+		// Touch-and-goes, finishing with a full stop.
+		wps = append(wps, patternWaypoints(icao, st, w.NmPerLongitude, 1+rand.Intn(st.TouchAndGoes))...)
+		ac.FlightPlan = NewFlightPlan(VFR, acType, icao, destination)
+		ac.FlightPlan.Altitude = int(elev) + st.PatternAltitude
+	} else {
+		// Straight-out departure, possibly headed across the TRACON's
+		// airspace.
+		p := ll2nm(ap.Location, w.NmPerLongitude)
+		var dir [2]float32
+		transition := rand.Float32() < st.TransitionRate
+		if transition {
+			// Head for the other side of the scope, passing near its center.
+			c := ll2nm(w.Center, w.NmPerLongitude)
+			dir = normalize2f(sub2f(c, p))
+			dir = add2f(dir, scale2f([2]float32{-dir[1], dir[0]}, (rand.Float32()-0.5)*0.3))
+			ac.RequestingTransition = true
+		} else {
+			// Depart roughly in the direction of the runway.
+			dir = normalize2f(sub2f(ll2nm(opp.Threshold, w.NmPerLongitude), ll2nm(rwy.Threshold, w.NmPerLongitude)))
+			dir = add2f(dir, scale2f([2]float32{-dir[1], dir[0]}, (rand.Float32()-0.5)*0.6))
+		}
+		dist := Select(transition, 2*w.Range, float32(20))
+		exit := Waypoint{
+			Fix:      "_" + icao + "_VFR_DEPARTURE",
+			Location: nm2ll(add2f(p, scale2f(normalize2f(dir), dist)), w.NmPerLongitude),
+			Delete:   true,
+		}
+		wps = append(wps, exit)
+
+		if transition {
+			// The destination is whichever airport is closest to where it's headed.
+			best := float32(1e30)
+			for name, a := range database.Airports {
+				if d := nmdistance2ll(a.Location, exit.Location); d < best && name != icao && len(name) == 4 {
+					best, destination = d, name
+				}
+			}
+		}
+
+		// VFR cruising altitudes: odd thousands plus 500 eastbound, even
+		// thousands plus 500 westbound.
+		hdg := headingp2ll(start.Location, exit.Location, w.NmPerLongitude, w.MagneticVariation)
+		alt := 2500 + 2000*rand.Intn(2)
+		if hdg >= 180 {
+			alt += 1000
+		}
+		for float32(alt) < elev+1500 {
+			alt += 2000
+		}
+
+		ac.FlightPlan = NewFlightPlan(VFR, acType, icao, destination)
+		ac.FlightPlan.Altitude = alt
+	}
+	ac.FlightPlan.Route = "DCT"
+
+	nav := makeNav(w, *ac.FlightPlan, perf, wps)
+	if nav == nil {
+		return nil, fmt.Errorf("error initializing Nav")
+	}
+	nav.FlightState.Altitude = elev
+	nav.FlightState.IAS = 1.1 * nav.v2()
+	nav.FlightState.GS = nav.FlightState.IAS
+	if pattern {
+		// Fly the pattern at a modest speed; the waypoints' altitude
+		// restrictions take care of the climbs and descents.
+		spd := min(1.4*perf.Speed.Landing, TASToIAS(perf.Speed.CruiseTAS, elev))
+		nav.Speed.Assigned = &spd
+	} else {
+		alt := float32(ac.FlightPlan.Altitude)
+		nav.Altitude.Assigned = &alt
+	}
+	ac.Nav = *nav
+
+	return ac, nil
+}
+
+// spawnSatelliteTraffic launches VFR traffic at satellite airports
+// according to their rates. s.mu must be held.
+func (s *Sim) spawnSatelliteTraffic() {
+	now := s.SimTime
+	for icao, st := range s.World.SatelliteTraffic {
+		if !now.After(s.NextSatelliteSpawn[icao]) {
+			continue
+		}
+		if ac, err := s.World.CreateSatelliteVFR(icao, st); err != nil {
+			s.lg.Errorf("%s: CreateSatelliteVFR error: %v", icao, err)
+		} else {
+			s.launchAircraftNoLock(*ac)
+		}
+		s.NextSatelliteSpawn[icao] = now.Add(randomWait(st.Rate, false))
+	}
+}
+
+// requestTransition has the aircraft call up the TRACON to request a
+// transition once it has climbed out. s.mu must be held.
+func (s *Sim) requestTransition(ac *Aircraft) {
+	if ac.Nav.FlightState.Altitude < ac.Nav.FlightState.DepartureAirportElevation+1000 {
+		return
+	}
+
+	ctrl := s.ResolveController(s.World.PrimaryController)
+	s.lg.Info("requesting VFR transition", slog.String("callsign", ac.Callsign), slog.String("controller", ctrl))
+
+	airportName := ac.FlightPlan.DepartureAirport
+	if ap, ok := database.Airports[airportName]; ok && ap.Name != "" {
+		airportName = ap.Name
+	}
+	msg := fmt.Sprintf("%s, departed %s, %s, request transition through your airspace to %s",
+		ac.FlightPlan.AircraftType, airportName, FormatAltitude(ac.Nav.FlightState.Altitude),
+		ac.FlightPlan.ArrivalAirport)
+	PostRadioEvents(ac.Callsign, []RadioTransmission{RadioTransmission{
+		Controller: ctrl,
+		Message:    msg,
+		Type:       RadioTransmissionContact,
+	}}, s)
+
+	ac.RequestingTransition = false
+	ac.ControllingController = ctrl
+}
+
+// initialSatelliteSpawnTimes returns the initial spawn times for VFR
+// traffic at each of the satellite airports.
+func initialSatelliteSpawnTimes(st map[string]*SatelliteAirportTraffic, randomSpawn func(rate int) time.Time) map[string]time.Time {
+	t := make(map[string]time.Time)
+	for icao, s := range st {
+		t[icao] = randomSpawn(s.Rate)
+	}
+	return t
+}
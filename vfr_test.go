@@ -0,0 +1,37 @@
+// vfr_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+)
+
+func TestTrafficPattern(t *testing.T) {
+	// Runway 36, 1nm long, starting at the origin.
+	v := [2]float32{0, 1}
+	for _, right := range []bool{false, true} {
+		p := trafficPattern(v, 1, right, 2)
+		if len(p) != 10 {
+			t.Fatalf("expected 10 points for 2 laps, got %d", len(p))
+		}
+
+		// The downwind leg should be on the left for left traffic and
+		// on the right for right traffic.
+		downwind := p[2][0]
+		if (right && downwind <= 0) || (!right && downwind >= 0) {
+			t.Errorf("right traffic %v: downwind leg on the wrong side: %v", right, p[2])
+		}
+
+		for i := 0; i < 2; i++ {
+			if p[5*i+4] != ([2]float32{0, 0}) {
+				t.Errorf("lap %d: expected touchdown at the threshold, got %v", i, p[5*i+4])
+			}
+			if p[5*i][1] <= 1 || p[5*i+3][1] >= 0 {
+				t.Errorf("lap %d: crosswind %v should be past the end and final %v before the threshold",
+					i, p[5*i], p[5*i+3])
+			}
+		}
+	}
+}
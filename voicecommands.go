@@ -0,0 +1,568 @@
+// voicecommands.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mmp/imgui-go/v4"
+)
+
+var (
+	ErrNoSpeechRecognizer    = errors.New("No speech recognition program found; install whisper.cpp")
+	ErrNoWhisperModel        = errors.New("No whisper model specified in the audio settings")
+	ErrSpokenCallsignUnknown = errors.New("Unable to match the callsign")
+	ErrNoSpokenCommands      = errors.New("No commands recognized")
+)
+
+// Voice commands are recorded while the push-to-talk key is held down,
+// transcribed by running whisper.cpp, and then converted into the same
+// command syntax that is typed into the messages pane.
+
+// VoiceRecordingSampleRate is the sample rate of the recorded audio;
+// whisper requires 16kHz input.
+const VoiceRecordingSampleRate = 16000
+
+// PushToTalkKeys gives the keys that may be used for push-to-talk and
+// their GLFW key codes, which is what imgui uses for key indices.
+var PushToTalkKeys = map[string]int{
+	"Insert":        260,
+	"Right Shift":   344,
+	"Right Control": 345,
+	"Right Alt":     346,
+}
+
+type voiceTranscription struct {
+	text string
+	err  error
+}
+
+// VoiceCommandInput manages push-to-talk voice input: it starts and
+// stops recording as the push-to-talk key is pressed and released and
+// issues the recognized commands once the transcription is available.
+type VoiceCommandInput struct {
+	recording      bool
+	recordingStart time.Time
+	transcriptions chan voiceTranscription
+}
+
+func (vc *VoiceCommandInput) Recording() bool {
+	return vc.recording
+}
+
+func (vc *VoiceCommandInput) Update(w *World, eventStream *EventStream) {
+	a := &globalConfig.Audio
+	if vc.transcriptions == nil {
+		vc.transcriptions = make(chan voiceTranscription, 4)
+	}
+
+	pressed := a.VoiceCommandsEnabled && w != nil && w.Connected() && !w.IsReplay() &&
+		imgui.IsKeyDown(a.PushToTalkKey)
+	if pressed && !vc.recording {
+		if err := a.StartRecording(); err != nil {
+			lg.Errorf("Unable to start recording: %v", err)
+			eventStream.Post(Event{Type: StatusMessageEvent, Message: "Unable to record: " + err.Error()})
+			a.VoiceCommandsEnabled = false
+		} else {
+			vc.recording = true
+			vc.recordingStart = time.Now()
+		}
+	} else if !pressed && vc.recording {
+		vc.recording = false
+		pcm := a.StopRecording()
+		if time.Since(vc.recordingStart) < 300*time.Millisecond {
+			// Most likely an accidental key press.
+			return
+		}
+
+		model, prompt := a.WhisperModel, whisperPrompt(w)
+		go func() {
+			text, err := transcribeSpeech(pcm, model, prompt)
+			vc.transcriptions <- voiceTranscription{text: text, err: err}
+		}()
+	}
+
+	select {
+	case t := <-vc.transcriptions:
+		if t.err != nil {
+			lg.Errorf("Speech recognition: %v", t.err)
+			eventStream.Post(Event{Type: StatusMessageEvent, Message: "Speech recognition failed: " + t.err.Error()})
+		} else if w != nil && w.Connected() {
+			vc.runCommands(w, t.text, eventStream)
+		}
+	default:
+	}
+}
+
+func (vc *VoiceCommandInput) runCommands(w *World, text string, eventStream *EventStream) {
+	callsign, cmds, err := makeSpokenCommandParser(w).Parse(text)
+	lg.Infof("Voice command: \"%s\" -> %s %s (%v)", text, callsign, cmds, err)
+	if err != nil {
+		eventStream.Post(Event{Type: StatusMessageEvent, Message: "\"" + text + "\": " + err.Error()})
+		return
+	}
+
+	eventStream.Post(Event{Type: StatusMessageEvent, Message: "> " + callsign + " " + cmds})
+	w.RunAircraftCommands(callsign, cmds, func(errorString string, remainingCommands string) {
+		if errorString != "" {
+			eventStream.Post(Event{Type: StatusMessageEvent, Message: callsign + ": " + errorString})
+		}
+	})
+}
+
+// whisperPrompt returns text that is passed to whisper to prime it with
+// the vocabulary that is likely to be used: the callsigns of the user's
+// aircraft and standard phraseology.
+func whisperPrompt(w *World) string {
+	var callsigns []string
+	for _, ac := range w.GetAllAircraft() {
+		if ac.ControllingController == w.Callsign {
+			callsigns = append(callsigns, spokenCallsign(ac.Callsign))
+		}
+	}
+	slices.Sort(callsigns)
+	return strings.Join(callsigns, ", ") + ". Turn left heading 210, descend and maintain 4,000, " +
+		"reduce speed to 180, proceed direct, expect the ILS runway 22L approach, cleared approach."
+}
+
+// speechRecognizer returns the name of the whisper.cpp command line
+// program or an empty string if it isn't available.
+func speechRecognizer() string {
+	for _, c := range []string{"whisper-cli", "whisper-cpp"} {
+		if _, err := exec.LookPath(c); err == nil {
+			return c
+		}
+	}
+	return ""
+}
+
+// transcribeSpeech runs whisper.cpp to transcribe the given 16kHz
+// samples.
+func transcribeSpeech(pcm []int16, model string, prompt string) (string, error) {
+	recognizer := speechRecognizer()
+	if recognizer == "" {
+		return "", ErrNoSpeechRecognizer
+	} else if model == "" {
+		return "", ErrNoWhisperModel
+	}
+
+	dir, err := os.MkdirTemp("", "vice-voice")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "command.wav")
+
+	if err := os.WriteFile(fn, encodeWAV(pcm, VoiceRecordingSampleRate), 0o600); err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command(recognizer, "-m", model, "-f", fn, "-l", "en", "-nt", "-np", "--prompt", prompt)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.Join(strings.Fields(string(out)), " ")), nil
+}
+
+// encodeWAV returns a 16-bit mono WAV file with the given samples.
+func encodeWAV(pcm []int16, rate int) []byte {
+	le := binary.LittleEndian
+	b := make([]byte, 0, 44+2*len(pcm))
+	b = append(b, "RIFF"...)
+	b = le.AppendUint32(b, uint32(36+2*len(pcm)))
+	b = append(b, "WAVEfmt "...)
+	b = le.AppendUint32(b, 16)
+	b = le.AppendUint16(b, 1) // PCM
+	b = le.AppendUint16(b, 1) // mono
+	b = le.AppendUint32(b, uint32(rate))
+	b = le.AppendUint32(b, uint32(2*rate))
+	b = le.AppendUint16(b, 2)
+	b = le.AppendUint16(b, 16)
+	b = append(b, "data"...)
+	b = le.AppendUint32(b, uint32(2*len(pcm)))
+	for _, s := range pcm {
+		b = le.AppendUint16(b, uint16(s))
+	}
+	return b
+}
+
+///////////////////////////////////////////////////////////////////////////
+// spokenCommandParser
+
+var phoneticAlphabet = [26]string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf",
+	"hotel", "india", "juliet", "kilo", "lima", "mike", "november", "oscar", "papa", "quebec",
+	"romeo", "sierra", "tango", "uniform", "victor", "whiskey", "xray", "yankee", "zulu"}
+
+var spokenDigits = map[string]string{
+	"zero": "0", "one": "1", "two": "2", "three": "3", "tree": "3", "four": "4", "fower": "4",
+	"five": "5", "fife": "5", "six": "6", "seven": "7", "eight": "8", "nine": "9", "niner": "9",
+}
+
+var spokenTeens = map[string]string{
+	"ten": "10", "eleven": "11", "twelve": "12", "thirteen": "13", "fourteen": "14",
+	"fifteen": "15", "sixteen": "16", "seventeen": "17", "eighteen": "18", "nineteen": "19",
+}
+
+var spokenTens = map[string]string{
+	"twenty": "2", "thirty": "3", "forty": "4", "fifty": "5", "sixty": "6", "seventy": "7",
+	"eighty": "8", "ninety": "9",
+}
+
+// spokenCallsign returns the callsign as it would be spoken, e.g. "delta
+// 421" for DAL421 or "november 123 alpha bravo" for N123AB.
+func spokenCallsign(callsign string) string {
+	var words []string
+	rest := callsign
+	if idx := strings.IndexAny(callsign, "0123456789"); idx > 0 {
+		if telephony, ok := database.Callsigns[callsign[:idx]]; ok && telephony != "" {
+			words = append(words, strings.ToLower(telephony))
+			rest = callsign[idx:]
+		}
+	}
+
+	digits := ""
+	for _, ch := range strings.ToUpper(rest) {
+		if ch >= '0' && ch <= '9' {
+			digits += string(ch)
+			continue
+		}
+		if digits != "" {
+			words = append(words, digits)
+			digits = ""
+		}
+		if ch >= 'A' && ch <= 'Z' {
+			words = append(words, phoneticAlphabet[ch-'A'])
+		}
+	}
+	if digits != "" {
+		words = append(words, digits)
+	}
+	return strings.Join(words, " ")
+}
+
+// spokenTokens splits transcribed text into lower-case words, converting
+// spoken numbers into digits: "four twenty-one" and "four two one" both
+// become "421" and "one zero thousand" becomes "10000". Runway
+// designators are combined into a single token, e.g. "22l".
+func spokenTokens(text string) []string {
+	text = strings.Map(func(r rune) rune {
+		switch r {
+		case '.', ',', '!', '?', ';', ':', '"':
+			return -1
+		case '-':
+			return ' '
+		}
+		return unicode.ToLower(r)
+	}, text)
+
+	// Split words like "delta421" and "fl240".
+	var words []string
+	for _, w := range strings.Fields(text) {
+		for i := 1; i < len(w); i++ {
+			if unicode.IsDigit(rune(w[i])) && unicode.IsLetter(rune(w[i-1])) {
+				words = append(words, w[:i])
+				w = w[i:]
+				break
+			}
+		}
+		words = append(words, w)
+	}
+
+	var tokens []string
+	for i := 0; i < len(words); {
+		// Consume a run of number words and digits.
+		digits, total, haveMultiplier := "", 0, false
+		j := i
+		for ; j < len(words); j++ {
+			w := words[j]
+			if d, ok := spokenDigits[w]; ok {
+				digits += d
+			} else if t, ok := spokenTeens[w]; ok {
+				digits += t
+			} else if t, ok := spokenTens[w]; ok {
+				if j+1 < len(words) && spokenDigits[words[j+1]] != "" && words[j+1] != "zero" {
+					digits += t + spokenDigits[words[j+1]]
+					j++
+				} else {
+					digits += t + "0"
+				}
+			} else if isAllNumbers(w) {
+				digits += w
+			} else if (w == "thousand" || w == "hundred") && j > i {
+				n, _ := strconv.Atoi(digits)
+				total += n * Select(w == "thousand", 1000, 100)
+				digits, haveMultiplier = "", true
+			} else {
+				break
+			}
+		}
+
+		if j == i {
+			tokens = append(tokens, words[i])
+			i++
+			continue
+		}
+
+		if haveMultiplier {
+			n, _ := strconv.Atoi(digits)
+			digits = strconv.Itoa(total + n)
+		}
+		// Runway designators
+		if len(tokens) > 0 && tokens[len(tokens)-1] == "runway" && j < len(words) {
+			if rl, ok := map[string]string{"left": "l", "right": "r", "center": "c"}[words[j]]; ok {
+				digits += rl
+				j++
+			}
+		}
+		tokens = append(tokens, digits)
+		i = j
+	}
+	return tokens
+}
+
+// spokenCommandParser converts transcribed controller transmissions into
+// vice's aircraft command syntax.
+type spokenCommandParser struct {
+	// For each callsign, the tokens for how it is spoken.
+	callsigns map[string][]string
+	// For each callsign, the approaches at its arrival airport: approach
+	// id to the tokens of the approach's full name.
+	approaches map[string]map[string][]string
+}
+
+func makeSpokenCommandParser(w *World) *spokenCommandParser {
+	p := &spokenCommandParser{
+		callsigns:  make(map[string][]string),
+		approaches: make(map[string]map[string][]string),
+	}
+	for _, ac := range w.GetAllAircraft() {
+		if ac.ControllingController != w.Callsign {
+			continue
+		}
+		p.callsigns[ac.Callsign] = spokenTokens(spokenCallsign(ac.Callsign))
+
+		if ac.FlightPlan == nil {
+			continue
+		}
+		if ap := w.GetAirport(ac.FlightPlan.ArrivalAirport); ap != nil {
+			appr := make(map[string][]string)
+			for id, a := range ap.Approaches {
+				appr[id] = approachTokens(a.FullName)
+			}
+			p.approaches[ac.Callsign] = appr
+		}
+	}
+	return p
+}
+
+// approachTokens returns the tokens to match for an approach's full
+// name, e.g. "rnav", "zulu", "22l" for "RNAV Z Runway 22L".
+func approachTokens(name string) []string {
+	var tokens []string
+	for _, t := range spokenTokens(name) {
+		if t == "runway" || t == "approach" || t == "rwy" {
+			continue
+		}
+		if len(t) == 1 && t[0] >= 'a' && t[0] <= 'z' {
+			t = phoneticAlphabet[t[0]-'a']
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens
+}
+
+// Parse returns the callsign and the commands corresponding to the given
+// transcription.
+func (p *spokenCommandParser) Parse(text string) (string, string, error) {
+	tokens := spokenTokens(text)
+
+	// Find the aircraft whose spoken callsign best matches the start of
+	// the transmission.
+	callsign, n := "", 0
+	for cs, ct := range p.callsigns {
+		if len(ct) > n && len(ct) <= len(tokens) && slices.Equal(ct, tokens[:len(ct)]) {
+			callsign, n = cs, len(ct)
+		}
+	}
+	if callsign == "" {
+		return "", "", ErrSpokenCallsignUnknown
+	}
+	tokens = tokens[n:]
+	if len(tokens) > 0 && (tokens[0] == "heavy" || tokens[0] == "super") {
+		tokens = tokens[1:]
+	}
+
+	var cmds []string
+	next := func(i int) string {
+		if i < len(tokens) {
+			return tokens[i]
+		}
+		return ""
+	}
+	// number returns the index of the first number within a few tokens
+	// after i, or -1 if there isn't one.
+	number := func(i int) int {
+		for j := i; j < min(i+5, len(tokens)); j++ {
+			if isAllNumbers(tokens[j]) {
+				return j
+			}
+		}
+		return -1
+	}
+	// altitude returns the altitude, in 100s of feet, that follows i.
+	altitude := func(i int) (string, int) {
+		j := number(i)
+		if j == -1 {
+			return "", i
+		}
+		alt, _ := strconv.Atoi(tokens[j])
+		if !slices.Contains(tokens[i:j], "level") && !slices.Contains(tokens[i:j], "fl") {
+			alt /= 100
+		}
+		return strconv.Itoa(alt), j + 1
+	}
+	approach := func(i int) (string, int) {
+		// Take everything up to the next command.
+		end := i
+		var spoken []string
+		for ; end < len(tokens) && !slices.Contains([]string{"turn", "heading", "climb", "descend", "maintain",
+			"speed", "reduce", "increase", "direct", "cleared", "contact", "intercept"}, tokens[end]); end++ {
+			spoken = append(spoken, approachTokens(tokens[end])...)
+		}
+		id, best := "", 0
+		for aid, at := range p.approaches[callsign] {
+			if len(at) > best && !slices.ContainsFunc(at, func(t string) bool { return !slices.Contains(spoken, t) }) {
+				id, best = aid, len(at)
+			}
+		}
+		return id, end
+	}
+
+	for i := 0; i < len(tokens); {
+		t := tokens[i]
+		i++
+
+		switch t {
+		case "turn":
+			dir := next(i)
+			if dir != "left" && dir != "right" {
+				continue
+			}
+			if j := number(i); j != -1 {
+				cmd := Select(dir == "left", "L", "R") + tokens[j]
+				if next(j+1) == "degrees" {
+					cmd += "D"
+				}
+				cmds = append(cmds, cmd)
+				i = j + 1
+			}
+
+		case "heading":
+			if j := number(i); j != -1 {
+				cmds = append(cmds, "H"+tokens[j])
+				i = j + 1
+			}
+
+		case "present":
+			if next(i) == "heading" {
+				cmds = append(cmds, "H")
+				i++
+			}
+
+		case "climb", "descend":
+			if next(i) == "via" {
+				cmds = append(cmds, Select(t == "climb", "CVS", "DVS"))
+				i++
+			} else if alt, j := altitude(i); alt != "" {
+				cmds = append(cmds, Select(t == "climb", "C", "D")+alt)
+				i = j
+			}
+
+		case "maintain":
+			if next(i) == "slowest" {
+				cmds = append(cmds, "SMIN")
+			} else if next(i) == "maximum" {
+				cmds = append(cmds, "SMAX")
+			} else if j := number(i); j != -1 && next(j+1) == "knots" {
+				cmds = append(cmds, "S"+tokens[j])
+				i = j + 2
+			} else if alt, j := altitude(i); alt != "" {
+				cmds = append(cmds, "C"+alt)
+				i = j
+			}
+
+		case "speed", "reduce", "increase":
+			if t == "speed" && i >= 2 && tokens[i-2] == "normal" {
+				cmds = append(cmds, "S")
+			} else if t == "speed" && i >= 2 && tokens[i-2] == "say" {
+				cmds = append(cmds, "SS")
+			} else if j := number(i); j != -1 {
+				cmds = append(cmds, "S"+tokens[j])
+				i = j + 1
+			}
+
+		case "expedite":
+			if next(i) == "descent" || next(i) == "climb" {
+				cmds = append(cmds, Select(next(i) == "descent", "ED", "EC"))
+				i++
+			}
+
+		case "direct":
+			if fix := next(i); fix != "" && !isAllNumbers(fix) {
+				cmds = append(cmds, "D"+strings.ToUpper(fix))
+				i++
+			}
+
+		case "expect":
+			if id, j := approach(i); id != "" {
+				cmds = append(cmds, "E"+id)
+				i = j
+			}
+
+		case "cleared":
+			if next(i) == "direct" {
+				continue
+			}
+			straightIn := next(i) == "straight"
+			if id, j := approach(i); id != "" {
+				cmds = append(cmds, Select(straightIn, "CSI", "C")+id)
+				i = j
+			}
+
+		case "cancel":
+			if next(i) == "approach" {
+				cmds = append(cmds, "CAC")
+				i++
+			}
+
+		case "intercept":
+			cmds = append(cmds, "I")
+
+		case "contact":
+			if next(i) == "tower" {
+				cmds = append(cmds, "TO")
+				i++
+			}
+
+		case "ident":
+			cmds = append(cmds, "ID")
+		}
+	}
+
+	if len(cmds) == 0 {
+		return callsign, "", ErrNoSpokenCommands
+	}
+	return callsign, strings.Join(cmds, " "), nil
+}
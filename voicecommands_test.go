@@ -0,0 +1,95 @@
+// voicecommands_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSpokenTokens(t *testing.T) {
+	for _, test := range []struct {
+		text     string
+		expected []string
+	}{
+		{"Delta four twenty-one, turn left heading two one zero.",
+			[]string{"delta", "421", "turn", "left", "heading", "210"}},
+		{"Delta421 descend and maintain 8,000", []string{"delta", "421", "descend", "and", "maintain", "8000"}},
+		{"one zero thousand", []string{"10000"}},
+		{"eight thousand five hundred", []string{"8500"}},
+		{"flight level two four zero", []string{"flight", "level", "240"}},
+		{"FL240", []string{"fl", "240"}},
+		{"heading zero niner zero", []string{"heading", "090"}},
+		{"twenty zero", []string{"200"}},
+		{"ILS runway two two left", []string{"ils", "runway", "22l"}},
+		{"RNAV Z Runway 22L", []string{"rnav", "z", "runway", "22l"}},
+		{"thousand", []string{"thousand"}},
+	} {
+		if tokens := spokenTokens(test.text); !slices.Equal(tokens, test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.text, test.expected, tokens)
+		}
+	}
+}
+
+func TestSpokenCommandParser(t *testing.T) {
+	p := &spokenCommandParser{
+		callsigns: map[string][]string{
+			"DAL421": {"delta", "421"},
+			"DAL42":  {"delta", "42"},
+			"N123AB": {"november", "123", "alpha", "bravo"},
+		},
+		approaches: map[string]map[string][]string{
+			"DAL421": {
+				"I2L": approachTokens("ILS Runway 22L"),
+				"R2L": approachTokens("RNAV Z Runway 22L"),
+				"I4R": approachTokens("ILS Runway 4R"),
+			},
+		},
+	}
+
+	for _, test := range []struct {
+		text     string
+		callsign string
+		cmds     string
+		err      error
+	}{
+		{text: "Delta four twenty-one, turn left heading two one zero", callsign: "DAL421", cmds: "L210"},
+		{text: "Delta 42 heavy, fly heading 090, climb and maintain flight level two four zero",
+			callsign: "DAL42", cmds: "H090 C240"},
+		{text: "Delta 421, descend and maintain one zero thousand, reduce speed to 180",
+			callsign: "DAL421", cmds: "D100 S180"},
+		{text: "November one two three alpha bravo turn right 20 degrees, maintain 3000",
+			callsign: "N123AB", cmds: "R20D C30"},
+		{text: "Delta 421, proceed direct Merit, descend via the STAR", callsign: "DAL421", cmds: "DMERIT DVS"},
+		{text: "Delta 421 expect the ILS runway two two left approach", callsign: "DAL421", cmds: "EI2L"},
+		{text: "Delta 421, cleared RNAV Zulu runway 22 left approach", callsign: "DAL421", cmds: "CR2L"},
+		{text: "Delta 421, cleared straight in ILS runway 4 right approach", callsign: "DAL421", cmds: "CSII4R"},
+		{text: "Delta 421 maintain 210 knots", callsign: "DAL421", cmds: "S210"},
+		{text: "Delta 421 maintain slowest practical speed", callsign: "DAL421", cmds: "SMIN"},
+		{text: "Delta 421 resume normal speed, contact tower", callsign: "DAL421", cmds: "S TO"},
+		{text: "Delta 421 expedite descent, cancel approach clearance", callsign: "DAL421", cmds: "ED CAC"},
+		{text: "Delta 421 fly present heading, intercept the localizer", callsign: "DAL421", cmds: "H I"},
+		{text: "United 12 turn left heading 210", err: ErrSpokenCallsignUnknown},
+		{text: "Delta 421 say again", callsign: "DAL421", err: ErrNoSpokenCommands},
+	} {
+		callsign, cmds, err := p.Parse(test.text)
+		if err != test.err {
+			t.Errorf("%q: expected error %v, got %v", test.text, test.err, err)
+		} else if callsign != test.callsign || cmds != test.cmds {
+			t.Errorf("%q: expected %q %q, got %q %q", test.text, test.callsign, test.cmds, callsign, cmds)
+		}
+	}
+}
+
+func TestEncodeWAV(t *testing.T) {
+	pcm := []int16{0, 1000, -1000, 32767, -32768}
+	dec, rate, err := decodeWAV(encodeWAV(pcm, VoiceRecordingSampleRate))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rate != VoiceRecordingSampleRate || !slices.Equal(dec, pcm) {
+		t.Errorf("round trip: got rate %d samples %v", rate, dec)
+	}
+}
@@ -0,0 +1,403 @@
+// weather.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WindLayer specifies the wind at a given altitude (MSL).
+type WindLayer struct {
+	Altitude  int32 `json:"altitude"`
+	Direction int32 `json:"direction"`
+	Speed     int32 `json:"speed"`
+}
+
+// WeatherStation holds the surface weather reported at an airport.
+type WeatherStation struct {
+	Airport   string
+	Location  Point2LL
+	Elevation float32
+	Wind      Wind    // Direction is -1 for variable winds
+	Altimeter float32 // inches of mercury; 0 if not reported
+}
+
+// WeatherModel describes the weather throughout the sim: surface
+// conditions come from the METAR of the closest airport and winds aloft
+// are given by a profile that is the same everywhere. Winds between the
+// surface and the lowest layer of the profile (and between successive
+// layers) are interpolated.
+type WeatherModel struct {
+	Stations   []WeatherStation
+	WindsAloft []WindLayer // sorted by altitude
+
+	// Prevailing conditions from the current WeatherPreset; Visibility
+	// is 0 if there is no preset, in which case all approaches are
+	// available. Ceiling is 0 if there's no ceiling.
+	Visibility float32 // statute miles
+	Ceiling    int     // feet AGL
+}
+
+// WeatherPreset describes general weather conditions that can be
+// selected when a sim is created or switched to while it is running.
+// The preset's conditions are applied to the airports' METARs, keeping
+// the reported wind and altimeter.
+type WeatherPreset struct {
+	Visibility    float32 `json:"visibility"`               // statute miles
+	Sky           string  `json:"sky"`                      // METAR sky condition, e.g. "BKN012 OVC020"
+	Weather       string  `json:"weather,omitempty"`        // METAR present weather, e.g. "-RA BR"
+	Gust          int32   `json:"gust,omitempty"`           // gusts are this much above the wind speed
+	GoAroundRate  float32 `json:"go_around_rate"`           // replaces the sim's go around probability
+	DeviationRate float32 `json:"deviation_rate,omitempty"` // weather deviations per hour across all aircraft
+	PIREPRate     float32 `json:"pirep_rate,omitempty"`     // PIREPs per hour across all aircraft
+}
+
+// DefaultWeatherPresets are available in all scenarios; scenarios may
+// override them or add more.
+var DefaultWeatherPresets = map[string]WeatherPreset{
+	"VFR day":    {Visibility: 10, Sky: "FEW250", GoAroundRate: 0.02, PIREPRate: 2},
+	"Marginal":   {Visibility: 4, Sky: "BKN020 OVC035", Weather: "BR", GoAroundRate: 0.05, PIREPRate: 4},
+	"Low IFR":    {Visibility: 0.75, Sky: "OVC004", Weather: "-RA BR", GoAroundRate: 0.1, PIREPRate: 4},
+	"Convective": {Visibility: 3, Sky: "BKN030CB OVC080", Weather: "TSRA", Gust: 15, GoAroundRate: 0.08, DeviationRate: 12, PIREPRate: 10},
+}
+
+// approachMinimums gives the lowest ceiling and visibility at which each
+// type of approach is offered. (Actual minimums vary by approach and
+// airport; these are typical.)
+var approachMinimums = map[ApproachType]struct {
+	Ceiling    int
+	Visibility float32
+}{
+	ILSApproach:           {200, 0.5},
+	RNAVApproach:          {400, 1},
+	ChartedVisualApproach: {3000, 5},
+}
+
+// windsAloftAltitudes are the altitudes at which the winds aloft are
+// given when they aren't specified by the scenario.
+var windsAloftAltitudes = []int32{3000, 6000, 9000, 12000, 18000, 24000, 30000, 34000, 39000}
+
+// MakeWeatherModel returns a WeatherModel for the given METARs. If
+// windsAloft is empty, a plausible profile is synthesized from the
+// surface wind at the primary airport--veering and strengthening with
+// altitude--or from the provided fallback wind if the primary airport's
+// METAR doesn't give a usable wind.
+func MakeWeatherModel(metar map[string]*METAR, primary string, windsAloft []WindLayer, fallback Wind) WeatherModel {
+	var wm WeatherModel
+
+	surface := fallback
+	for _, icao := range SortedMapKeys(metar) {
+		ap, ok := database.Airports[icao]
+		if !ok {
+			continue
+		}
+
+		m := metar[icao]
+		st := WeatherStation{Airport: icao, Location: ap.Location, Elevation: float32(ap.Elevation)}
+		if wind, err := ParseMETARWind(m.Wind); err != nil {
+			lg.Warnf("%s: %v", icao, err)
+			st.Wind = fallback
+		} else {
+			st.Wind = wind
+			if icao == primary && wind.Direction != -1 {
+				surface = wind
+			}
+		}
+		if alt, err := ParseAltimeter(m.Altimeter); err == nil {
+			st.Altimeter = alt
+		}
+
+		wm.Stations = append(wm.Stations, st)
+	}
+
+	if len(windsAloft) > 0 {
+		wm.WindsAloft = DuplicateSlice(windsAloft)
+		sort.Slice(wm.WindsAloft, func(i, j int) bool { return wm.WindsAloft[i].Altitude < wm.WindsAloft[j].Altitude })
+	} else {
+		wm.WindsAloft = synthesizeWindsAloft(surface)
+	}
+
+	return wm
+}
+
+func synthesizeWindsAloft(surface Wind) []WindLayer {
+	dir, spd := float32(surface.Direction), float32(max(surface.Speed, 5))
+	if surface.Direction == -1 {
+		// Prevailing westerlies
+		dir = 270
+	}
+
+	var layers []WindLayer
+	for _, alt := range windsAloftAltitudes {
+		// Winds veer by about 30 degrees through the friction layer and
+		// then strengthen steadily with altitude.
+		veer := min(30, float32(alt)/100)
+		s := 1.5*spd + 2*float32(alt-windsAloftAltitudes[0])/1000
+		layers = append(layers, WindLayer{
+			Altitude:  alt,
+			Direction: int32(NormalizeHeading(dir+veer) + 0.5),
+			Speed:     int32(min(s, 150) + 0.5),
+		})
+	}
+	return layers
+}
+
+// ParseMETARWind parses the wind from a METAR, e.g., "27015G25KT" or
+// "VRB04KT". The returned Wind's Direction is -1 for variable winds.
+func ParseMETARWind(s string) (Wind, error) {
+	var mps bool
+	if str, ok := strings.CutSuffix(s, "KT"); ok {
+		s = str
+	} else if str, ok := strings.CutSuffix(s, "MPS"); ok {
+		s, mps = str, true
+	} else {
+		return Wind{}, fmt.Errorf("%s: wind must be reported in KT or MPS", s)
+	}
+	if len(s) < 5 {
+		return Wind{}, fmt.Errorf("%s: invalid wind", s)
+	}
+
+	var w Wind
+	if s[:3] == "VRB" {
+		w.Direction = -1
+	} else if dir, err := strconv.Atoi(s[:3]); err != nil || dir > 360 {
+		return Wind{}, fmt.Errorf("%s: invalid wind direction", s[:3])
+	} else {
+		w.Direction = int32(dir)
+	}
+
+	spd, gst, gusting := strings.Cut(s[3:], "G")
+	if v, err := strconv.Atoi(spd); err != nil {
+		return Wind{}, fmt.Errorf("%s: invalid wind speed", spd)
+	} else {
+		w.Speed = int32(v)
+	}
+	if gusting {
+		if v, err := strconv.Atoi(gst); err != nil {
+			return Wind{}, fmt.Errorf("%s: invalid gust speed", gst)
+		} else {
+			w.Gust = int32(v)
+		}
+	}
+
+	if mps {
+		w.Speed = int32(float32(w.Speed)*1.944 + 0.5)
+		w.Gust = int32(float32(w.Gust)*1.944 + 0.5)
+	}
+
+	return w, nil
+}
+
+// ParseAltimeter returns the altimeter setting in inches of mercury
+// given a METAR altimeter, e.g., "A2992" or "Q1013".
+func ParseAltimeter(s string) (float32, error) {
+	if len(s) != 5 {
+		return 0, fmt.Errorf("%s: invalid altimeter", s)
+	}
+	v, err := strconv.Atoi(s[1:])
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid altimeter", s)
+	}
+
+	switch s[0] {
+	case 'A':
+		return float32(v) / 100, nil
+	case 'Q':
+		// hectopascals
+		return float32(v) * 0.02953, nil
+	default:
+		return 0, fmt.Errorf("%s: invalid altimeter", s)
+	}
+}
+
+// windVector returns the vector in knots corresponding to the wind coming
+// from the given direction; variable winds give a zero vector.
+func windVector(dir int32, speed float32) [2]float32 {
+	if dir == -1 {
+		return [2]float32{}
+	}
+	d := OppositeHeading(float32(dir))
+	return scale2f([2]float32{sin(radians(d)), cos(radians(d))}, speed)
+}
+
+func (wm *WeatherModel) closestStation(p Point2LL) *WeatherStation {
+	var closest *WeatherStation
+	var dist float32
+	for i, st := range wm.Stations {
+		if d := nmdistance2ll(p, st.Location); closest == nil || d < dist {
+			closest, dist = &wm.Stations[i], d
+		}
+	}
+	return closest
+}
+
+// WindVector returns the wind at the given point and altitude as a vector
+// in knots in the direction the wind is blowing. gust, in [0,1], gives
+// how far the surface wind is between its base speed and its gust speed;
+// it's up to the caller to vary it over time.
+func (wm *WeatherModel) WindVector(p Point2LL, alt float32, gust float32) [2]float32 {
+	var vs [2]float32
+	var elevation float32
+	if st := wm.closestStation(p); st != nil {
+		spd := lerp(gust, float32(st.Wind.Speed), float32(max(st.Wind.Gust, st.Wind.Speed)))
+		vs, elevation = windVector(st.Wind.Direction, spd), st.Elevation
+	}
+
+	layers := wm.WindsAloft
+	if len(layers) == 0 || alt <= elevation {
+		return vs
+	}
+	if l0 := layers[0]; alt < float32(l0.Altitude) {
+		x := (alt - elevation) / (float32(l0.Altitude) - elevation)
+		return lerp2f(x, vs, windVector(l0.Direction, float32(l0.Speed)))
+	}
+	for i := 1; i < len(layers); i++ {
+		if l0, l1 := layers[i-1], layers[i]; alt < float32(l1.Altitude) {
+			x := (alt - float32(l0.Altitude)) / float32(l1.Altitude-l0.Altitude)
+			return lerp2f(x, windVector(l0.Direction, float32(l0.Speed)),
+				windVector(l1.Direction, float32(l1.Speed)))
+		}
+	}
+	l := layers[len(layers)-1]
+	return windVector(l.Direction, float32(l.Speed))
+}
+
+// Wind returns the wind at the given point and altitude, not including
+// gusts.
+func (wm *WeatherModel) Wind(p Point2LL, alt float32) Wind {
+	v := wm.WindVector(p, alt, 0)
+	spd := length2f(v)
+	if spd < 0.5 {
+		return Wind{}
+	}
+	// The vector is where the wind is going; report where it's from.
+	dir := OppositeHeading(degrees(atan2(v[0], v[1])))
+	if dir < 0.5 {
+		dir = 360
+	}
+	return Wind{Direction: int32(dir + 0.5), Speed: int32(spd + 0.5)}
+}
+
+// Altimeter returns the altimeter setting at the given airport.
+func (wm *WeatherModel) Altimeter(airport string) (float32, bool) {
+	for _, st := range wm.Stations {
+		if st.Airport == airport {
+			return st.Altimeter, st.Altimeter != 0
+		}
+	}
+	return 0, false
+}
+
+// ApproachAvailable indicates whether the current weather allows the
+// given type of approach to be flown.
+func (wm *WeatherModel) ApproachAvailable(t ApproachType) bool {
+	if wm.Visibility == 0 {
+		return true
+	}
+	mins, ok := approachMinimums[t]
+	return !ok || (wm.Visibility >= mins.Visibility && (wm.Ceiling == 0 || wm.Ceiling >= mins.Ceiling))
+}
+
+// parseSkyLayer splits a METAR sky condition layer, e.g. "BKN030CB", into
+// its coverage ("BKN") and its height in feet AGL.
+func parseSkyLayer(layer string) (cover string, height int, ok bool) {
+	if strings.HasPrefix(layer, "VV") {
+		cover, layer = "VV", layer[2:]
+	} else if len(layer) >= 3 {
+		cover, layer = layer[:3], layer[3:]
+	}
+	if !slices.Contains([]string{"FEW", "SCT", "BKN", "OVC", "VV"}, cover) || len(layer) < 3 {
+		return "", 0, false
+	}
+	h, err := strconv.Atoi(layer[:3])
+	return cover, 100 * h, err == nil
+}
+
+// Ceiling returns the height in feet AGL of the lowest broken or overcast
+// layer (or vertical visibility) in the preset's sky condition. 0 is
+// returned if there is no ceiling.
+func (p WeatherPreset) Ceiling() int {
+	ceiling := 0
+	for _, layer := range strings.Fields(p.Sky) {
+		if cover, h, ok := parseSkyLayer(layer); ok && (cover == "BKN" || cover == "OVC" || cover == "VV") {
+			h = max(h, 1) // VV000 is still a ceiling
+			if ceiling == 0 || h < ceiling {
+				ceiling = h
+			}
+		}
+	}
+	return ceiling
+}
+
+// Check reports any problems with the preset's values.
+func (p WeatherPreset) Check(e *ErrorLogger) {
+	if p.Visibility <= 0 {
+		e.ErrorString("\"visibility\" must be greater than zero")
+	}
+	for _, layer := range strings.Fields(p.Sky) {
+		if _, _, ok := parseSkyLayer(layer); !ok {
+			e.ErrorString("%s: invalid sky condition", layer)
+		}
+	}
+	if p.GoAroundRate < 0 || p.GoAroundRate > 1 {
+		e.ErrorString("\"go_around_rate\" must be between 0 and 1")
+	}
+	if p.DeviationRate < 0 {
+		e.ErrorString("\"deviation_rate\" can't be negative")
+	}
+	if p.PIREPRate < 0 {
+		e.ErrorString("\"pirep_rate\" can't be negative")
+	}
+}
+
+// Apply returns a copy of the given METAR updated for the preset's
+// conditions.
+func (p WeatherPreset) Apply(m *METAR) *METAR {
+	pm := *m
+	pm.Weather = strings.Join(FilterSlice([]string{metarVisibility(p.Visibility), p.Weather, p.Sky},
+		func(s string) bool { return s != "" }), " ")
+	if wind, err := ParseMETARWind(m.Wind); err == nil && p.Gust > 0 && wind.Speed > 0 {
+		wind.Gust = wind.Speed + p.Gust
+		pm.Wind = FormatMETARWind(wind)
+	}
+	return &pm
+}
+
+// metarVisibility returns the visibility in statute miles formatted as in
+// a METAR, e.g. "10SM" or "1 1/2SM".
+func metarVisibility(v float32) string {
+	whole, frac := int(v), v-float32(int(v))
+	var fs string
+	if frac >= 0.625 {
+		fs = "3/4"
+	} else if frac >= 0.375 {
+		fs = "1/2"
+	} else if frac >= 0.125 {
+		fs = "1/4"
+	}
+
+	if fs == "" {
+		return fmt.Sprintf("%dSM", whole)
+	} else if whole == 0 {
+		return fs + "SM"
+	}
+	return fmt.Sprintf("%d %sSM", whole, fs)
+}
+
+// FormatMETARWind returns the METAR representation of the given wind.
+func FormatMETARWind(w Wind) string {
+	if w.Speed <= 0 {
+		return "00000KT"
+	}
+	s := Select(w.Direction == -1, "VRB", fmt.Sprintf("%03d", w.Direction)) + fmt.Sprintf("%02d", w.Speed)
+	if w.Gust > w.Speed {
+		s += fmt.Sprintf("G%02d", w.Gust)
+	}
+	return s + "KT"
+}
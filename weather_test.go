@@ -0,0 +1,181 @@
+// weather_test.go
+// Copyright(c) 2022 Matt Pharr, licensed under the GNU Public License, Version 3.
+// SPDX: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+)
+
+func TestParseMETARWind(t *testing.T) {
+	for _, test := range []struct {
+		s        string
+		expected Wind
+		err      bool
+	}{
+		{s: "27015KT", expected: Wind{Direction: 270, Speed: 15}},
+		{s: "31012G20KT", expected: Wind{Direction: 310, Speed: 12, Gust: 20}},
+		{s: "VRB04KT", expected: Wind{Direction: -1, Speed: 4}},
+		{s: "00000KT", expected: Wind{}},
+		{s: "18005MPS", expected: Wind{Direction: 180, Speed: 10}},
+		{s: "27015", err: true},
+		{s: "XYZ15KT", err: true},
+		{s: "27015GKT", err: true},
+	} {
+		w, err := ParseMETARWind(test.s)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected error", test.s)
+			}
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.s, err)
+		} else if w != test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.s, test.expected, w)
+		}
+	}
+}
+
+func TestParseAltimeter(t *testing.T) {
+	if a, err := ParseAltimeter("A2992"); err != nil || a != 29.92 {
+		t.Errorf("A2992: got %f, %v", a, err)
+	}
+	if a, err := ParseAltimeter("Q1013"); err != nil || abs(a-29.91) > 0.01 {
+		t.Errorf("Q1013: got %f, %v", a, err)
+	}
+	for _, s := range []string{"", "A299", "X2992", "A29X2"} {
+		if _, err := ParseAltimeter(s); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
+
+func TestWeatherModelWind(t *testing.T) {
+	wm := WeatherModel{
+		Stations: []WeatherStation{
+			{Airport: "KAAA", Location: Point2LL{-73, 40}, Elevation: 0, Wind: Wind{Direction: 360, Speed: 10, Gust: 20}},
+			{Airport: "KBBB", Location: Point2LL{-70, 40}, Elevation: 1000, Wind: Wind{Direction: 90, Speed: 10},
+				Altimeter: 30.01},
+		},
+		WindsAloft: []WindLayer{
+			{Altitude: 3000, Direction: 270, Speed: 20},
+			{Altitude: 6000, Direction: 270, Speed: 40},
+		},
+	}
+
+	for _, test := range []struct {
+		p        Point2LL
+		alt      float32
+		expected Wind
+	}{
+		{p: Point2LL{-73, 40}, alt: 0, expected: Wind{Direction: 360, Speed: 10}},
+		{p: Point2LL{-72.9, 40.1}, alt: 0, expected: Wind{Direction: 360, Speed: 10}},
+		{p: Point2LL{-70.1, 40}, alt: 500, expected: Wind{Direction: 90, Speed: 10}},
+		{p: Point2LL{-73, 40}, alt: 3000, expected: Wind{Direction: 270, Speed: 20}},
+		{p: Point2LL{-73, 40}, alt: 4500, expected: Wind{Direction: 270, Speed: 30}},
+		{p: Point2LL{-73, 40}, alt: 10000, expected: Wind{Direction: 270, Speed: 40}},
+		// Halfway between 090@10 at the surface (1000' MSL) and 270@20 at 3000'
+		{p: Point2LL{-70, 40}, alt: 2000, expected: Wind{Direction: 270, Speed: 5}},
+	} {
+		if w := wm.Wind(test.p, test.alt); w != test.expected {
+			t.Errorf("%v at %.0f: expected %+v, got %+v", test.p, test.alt, test.expected, w)
+		}
+	}
+
+	// Gusts only affect the surface wind.
+	if v := wm.WindVector(Point2LL{-73, 40}, 0, 1); abs(v[1]+20) > 0.01 || abs(v[0]) > 0.01 {
+		t.Errorf("expected 20 knot gust from the north, got %v", v)
+	}
+	if v0, v1 := wm.WindVector(Point2LL{-73, 40}, 3000, 0), wm.WindVector(Point2LL{-73, 40}, 3000, 1); v0 != v1 {
+		t.Errorf("gust changed wind aloft: %v vs %v", v0, v1)
+	}
+
+	if a, ok := wm.Altimeter("KBBB"); !ok || a != 30.01 {
+		t.Errorf("KBBB altimeter: got %f, %v", a, ok)
+	}
+	if _, ok := wm.Altimeter("KAAA"); ok {
+		t.Errorf("KAAA: expected no altimeter")
+	}
+}
+
+func TestSynthesizeWindsAloft(t *testing.T) {
+	layers := synthesizeWindsAloft(Wind{Direction: 350, Speed: 10})
+	if len(layers) != len(windsAloftAltitudes) {
+		t.Fatalf("expected %d layers, got %d", len(windsAloftAltitudes), len(layers))
+	}
+	for i, l := range layers {
+		if l.Altitude != windsAloftAltitudes[i] {
+			t.Errorf("layer %d: expected altitude %d, got %d", i, windsAloftAltitudes[i], l.Altitude)
+		}
+		if l.Direction != 20 {
+			t.Errorf("layer %d: expected winds veered to 020, got %03d", i, l.Direction)
+		}
+		if i > 0 && l.Speed <= layers[i-1].Speed {
+			t.Errorf("layer %d: expected wind speed to increase with altitude", i)
+		}
+	}
+}
+
+func TestWeatherPreset(t *testing.T) {
+	for _, test := range []struct {
+		sky     string
+		ceiling int
+	}{
+		{"FEW250", 0},
+		{"SCT010 BKN025 OVC040", 2500},
+		{"BKN030CB OVC080", 3000},
+		{"FEW005 OVC004", 400},
+		{"VV002", 200},
+		{"VV000", 1},
+		{"", 0},
+	} {
+		if c := (WeatherPreset{Sky: test.sky}).Ceiling(); c != test.ceiling {
+			t.Errorf("%q: expected ceiling %d, got %d", test.sky, test.ceiling, c)
+		}
+	}
+
+	for v, expected := range map[float32]string{10: "10SM", 0.75: "3/4SM", 1.5: "1 1/2SM", 0.25: "1/4SM", 2.9: "2 3/4SM"} {
+		if s := metarVisibility(v); s != expected {
+			t.Errorf("%f: expected visibility %q, got %q", v, expected, s)
+		}
+	}
+
+	for _, w := range []Wind{{Direction: 310, Speed: 12, Gust: 20}, {Direction: 90, Speed: 5}, {Direction: -1, Speed: 3}} {
+		if pw, err := ParseMETARWind(FormatMETARWind(w)); err != nil || pw != w {
+			t.Errorf("%+v: round trip through %q gave %+v, %v", w, FormatMETARWind(w), pw, err)
+		}
+	}
+	if s := FormatMETARWind(Wind{Direction: 270}); s != "00000KT" {
+		t.Errorf("expected calm wind, got %q", s)
+	}
+
+	m := &METAR{AirportICAO: "KJFK", Wind: "31012KT", Weather: "10SM FEW250", Altimeter: "A2992"}
+	p := WeatherPreset{Visibility: 0.75, Sky: "OVC004", Weather: "-RA BR", Gust: 10}
+	pm := p.Apply(m)
+	if pm.Weather != "3/4SM -RA BR OVC004" || pm.Wind != "31012G22KT" || pm.Altimeter != "A2992" {
+		t.Errorf("unexpected METAR after applying preset: %+v", *pm)
+	}
+	if m.Weather != "10SM FEW250" || m.Wind != "31012KT" {
+		t.Errorf("original METAR was modified: %+v", *m)
+	}
+
+	for _, test := range []struct {
+		preset                   WeatherPreset
+		ils, rnav, chartedVisual bool
+	}{
+		{DefaultWeatherPresets["VFR day"], true, true, true},
+		{DefaultWeatherPresets["Marginal"], true, true, false},
+		{DefaultWeatherPresets["Low IFR"], true, false, false},
+		{WeatherPreset{Visibility: 0.25, Sky: "VV001"}, false, false, false},
+	} {
+		wm := WeatherModel{Visibility: test.preset.Visibility, Ceiling: test.preset.Ceiling()}
+		if wm.ApproachAvailable(ILSApproach) != test.ils ||
+			wm.ApproachAvailable(RNAVApproach) != test.rnav ||
+			wm.ApproachAvailable(ChartedVisualApproach) != test.chartedVisual {
+			t.Errorf("%+v: unexpected approach availability", test.preset)
+		}
+	}
+	if wm := (WeatherModel{}); !wm.ApproachAvailable(ChartedVisualApproach) {
+		t.Errorf("expected all approaches to be available without a preset")
+	}
+}
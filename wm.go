@@ -40,6 +40,12 @@ var (
 		// back to the previous one (e.g., the CLIPane.)
 		keyboardFocusStack []Pane
 
+		// Mouse and keyboard input to the Panes is recorded if
+		// inputRecorder is set, while inputPlayer, if set, supplies it in
+		// place of the user's.
+		inputRecorder *InputRecorder
+		inputPlayer   *InputPlayer
+
 		lastAircraftResponse string
 	}
 )
@@ -159,6 +165,18 @@ func (d *DisplayNode) ParentNodeForPane(pane Pane) (*DisplayNode, int) {
 	return d.Children[1].ParentNodeForPane(pane)
 }
 
+// RemovePane removes the specified Pane from the hierarchy; its sibling
+// takes the place of their parent node. false is returned if the Pane
+// isn't found or is the only one.
+func (d *DisplayNode) RemovePane(pane Pane) bool {
+	parent, idx := d.ParentNodeForPane(pane)
+	if parent == nil {
+		return false
+	}
+	*parent = *parent.Children[1-idx]
+	return true
+}
+
 // TypedDisplayNodePane helps with marshaling to and unmarshaling from
 // JSON, which is how the configuration and settings are saved between
 // sessions. Most of this works out pretty much for free thanks to go's
@@ -231,11 +249,37 @@ func (d *DisplayNode) VisitPanes(visit func(Pane)) {
 	}
 }
 
+// hidden indicates whether the node is a leaf node with a Pane that is
+// currently hidden.
+func (d *DisplayNode) hidden() bool {
+	h, ok := d.Pane.(PaneHider)
+	return ok && h.Hidden()
+}
+
+// visibleChild returns the child of an interior node that takes the
+// node's entire extent because the other one is hidden; it returns nil if
+// both children are shown.
+func (d *DisplayNode) visibleChild() *DisplayNode {
+	if d.SplitLine.Axis == SplitAxisNone {
+		return nil
+	} else if d.Children[0].hidden() {
+		return d.Children[1]
+	} else if d.Children[1].hidden() {
+		return d.Children[0]
+	}
+	return nil
+}
+
 // VisitPanesWithBounds visits all of the panes in a DisplayNode hierarchy,
 // giving each one both its own bounding box in window coordinates as well
 // the bounding box of its parent node in the DisplayNodeTree.
 func (d *DisplayNode) VisitPanesWithBounds(displayExtent Extent2D, parentDisplayExtent Extent2D,
 	visit func(Extent2D, Extent2D, Pane)) {
+	if c := d.visibleChild(); c != nil {
+		c.VisitPanesWithBounds(displayExtent, parentDisplayExtent, visit)
+		return
+	}
+
 	switch d.SplitLine.Axis {
 	case SplitAxisNone:
 		visit(displayExtent, parentDisplayExtent, d.Pane)
@@ -313,6 +357,9 @@ func (d *DisplayNode) FindPaneForMouse(displayExtent Extent2D, p [2]float32) Pan
 		// We've reached a leaf node and found the pane.
 		return d.Pane
 	}
+	if c := d.visibleChild(); c != nil {
+		return c.FindPaneForMouse(displayExtent, p)
+	}
 
 	// Compute the extents of the two nodes and the split line.
 	var d0, ds, d1 Extent2D
@@ -434,7 +481,7 @@ func wmPaneIsPresent(pane Pane, root *DisplayNode) bool {
 // hierarchy, making sure they don't inadvertently draw over other panes,
 // and providing mouse and keyboard events only to the Pane that should
 // respectively be receiving them.
-func wmDrawPanes(p Platform, r Renderer, w *World, stats *Stats) {
+func wmDrawPanes(p Platform, r Renderer, w *World, eventStream *EventStream, stats *Stats) {
 	var filter func(d *DisplayNode) *DisplayNode
 	filter = func(d *DisplayNode) *DisplayNode {
 		if fsp, ok := d.Children[0].Pane.(*FlightStripPane); ok && fsp.HideFlightStrips {
@@ -455,6 +502,9 @@ func wmDrawPanes(p Platform, r Renderer, w *World, stats *Stats) {
 		// Take any one that can take keyboard events.
 		if wm.keyboardFocusPane == nil {
 			root.VisitPanes(func(pane Pane) {
+				if h, ok := pane.(PaneHider); ok && h.Hidden() {
+					return
+				}
 				if pane.CanTakeKeyboardFocus() {
 					wm.keyboardFocusPane = pane
 				}
@@ -512,8 +562,25 @@ func wmDrawPanes(p Platform, r Renderer, w *World, stats *Stats) {
 	if !imgui.CurrentIO().WantCaptureKeyboard() {
 		keyboard = NewKeyboardState(p)
 	}
+
+	var playback *InputFrame
+	if wm.inputPlayer != nil {
+		if f, done := wm.inputPlayer.Next(); done {
+			lg.Infof("%s: finished playing back input", wm.inputPlayer.Filename)
+			eventStream.Post(Event{Type: StatusMessageEvent, Message: "Finished playing back recorded input"})
+			wm.inputPlayer = nil
+		} else {
+			playback = &f
+		}
+	}
+	record := InputFrame{MousePane: -1, FocusPane: -1, Keyboard: keyboard}
+
+	paneIndex := 0
 	root.VisitPanesWithBounds(paneDisplayExtent, paneDisplayExtent,
 		func(paneExtent Extent2D, parentExtent Extent2D, pane Pane) {
+			idx := paneIndex
+			paneIndex++
+
 			haveFocus := pane == wm.keyboardFocusPane && !imgui.CurrentIO().WantCaptureKeyboard()
 			ctx := PaneContext{
 				paneExtent:       paneExtent,
@@ -536,6 +603,25 @@ func wmDrawPanes(p Platform, r Renderer, w *World, stats *Stats) {
 				ctx.InitializeMouse(displayTrueFull)
 			}
 
+			if playback != nil {
+				// Replace the user's input with the recorded input.
+				ctx.haveFocus = idx == playback.FocusPane
+				ctx.keyboard = playback.Keyboard
+				ctx.mouse = nil
+				if idx == playback.MousePane {
+					mouse := playback.Mouse
+					ctx.mouse = &mouse
+				}
+			} else if wm.inputRecorder != nil {
+				if ctx.haveFocus {
+					record.FocusPane = idx
+				}
+				if ctx.mouse != nil {
+					record.MousePane = idx
+					record.Mouse = *ctx.mouse
+				}
+			}
+
 			// Specify the scissor rectangle and viewport that
 			// correspond to the pixels that the Pane covers. In this
 			// way, not only can the Pane be implemented in terms of
@@ -552,6 +638,10 @@ func wmDrawPanes(p Platform, r Renderer, w *World, stats *Stats) {
 			commandBuffer.ResetState()
 		})
 
+	if wm.inputRecorder != nil {
+		wm.inputRecorder.Record(record)
+	}
+
 	// Clear mouseConsumerOverride if the user has stopped dragging;
 	// only do this after visiting the Panes so that the override Pane
 	// still sees the mouse button release event.
@@ -39,12 +39,50 @@ type World struct {
 	showSettings      bool
 	showScenarioInfo  bool
 
+	// Set when a coach is coaching our position (or when we are the
+	// coach); updated from the Sim.
+	CoachState *CoachState
+
+	DepartureReleases []DepartureRelease
+	showReleases      bool
+	lastReleaseCount  int
+
+	SystemFailures     []SystemFailure
+	RadarOutages       []RadarOutage
+	ActiveRandomEvents []RandomEvent
+
+	PIREPs               []PIREP
+	showPIREPs           bool
+	lastUrgentPIREPCount int
+
+	SessionStats   *SessionStats
+	showDebrief    bool
+	scenarioEditor *ScenarioEditor
+
+	showATIS        bool
+	atis            map[string]ATIS
+	lastATISRequest time.Time
+
 	launchControlWindow *LaunchControlWindow
+	datalinkWindow      *DatalinkWindow
+	coordinationWindow  *CoordinationWindow
+	alertManager        *AlertManager
 
 	pendingCalls []*PendingCall
 
 	missingPrimaryDialog *ModalDialogBox
 
+	// The runways whose tailwind limit was most recently alerted.
+	tailwindAlert string
+
+	// The MSAW areas, which are computed the first time they're needed.
+	minimumAltitudeAreas []MVA
+
+	// Set when the session is being recorded or when this is a World for
+	// playing back a replay, respectively.
+	recorder *ReplayRecorder
+	replay   *ReplayPlayer
+
 	sameGateDepartures int
 	sameDepartureCap   int
 
@@ -70,12 +108,18 @@ type World struct {
 	NmPerLongitude           float32
 	Airports                 map[string]*Airport
 	Fixes                    map[string]Point2LL
+	Holds                    []Hold
+	PreferentialRoutes       []PreferentialRoute
 	PrimaryAirport           string
 	RadarSites               map[string]*RadarSite
 	Center                   Point2LL
 	Range                    float32
 	Wind                     Wind
+	Weather                  WeatherModel
+	WeatherPreset            string // "" if none
+	WeatherPresets           map[string]WeatherPreset
 	Callsign                 string
+	IsCoach                  bool // view-only, coaching the controller at Callsign
 	ScenarioDefaultVideoMaps []string
 	ApproachAirspace         []ControllerAirspaceVolume
 	DepartureAirspace        []ControllerAirspaceVolume
@@ -83,6 +127,11 @@ type World struct {
 	ArrivalRunways           []ScenarioGroupArrivalRunway
 	Scratchpads              map[string]string
 	ArrivalGroups            map[string][]Arrival
+	BackgroundTraffic        BackgroundTraffic
+	SatelliteTraffic         map[string]*SatelliteAirportTraffic
+	HelicopterRoutes         []HelicopterRoute
+	RandomEvents             ScenarioRandomEvents
+	GroundMovement           bool
 	TotalDepartures          int
 	TotalArrivals            int
 	STARSFacilityAdaptation  STARSFacilityAdaptation
@@ -104,26 +153,22 @@ func (w *World) Assign(other *World) {
 	w.MultiControllers = DuplicateMap(other.MultiControllers)
 }
 
+// GetWindVector returns the wind at the given point and altitude in nm
+// per second.
 func (w *World) GetWindVector(p Point2LL, alt float32) Point2LL {
 	// Sinusoidal wind speed variation from the base speed up to base +
 	// gust and then back...
 	base := time.UnixMicro(0)
 	sec := w.SimTime.Sub(base).Seconds()
-	windSpeed := float32(w.Wind.Speed) +
-		float32(w.Wind.Gust-w.Wind.Speed)*float32(1+math.Cos(sec/4))/2
+	gust := float32(1+math.Cos(sec/4)) / 2
 
-	// Wind.Direction is where it's coming from, so +180 to get the vector
-	// that affects the aircraft's course.
-	d := OppositeHeading(float32(w.Wind.Direction))
-	vWind := [2]float32{sin(radians(d)), cos(radians(d))}
-	vWind = scale2f(vWind, windSpeed/3600)
-	return vWind
+	return scale2f(w.Weather.WindVector(p, alt, gust), 1./3600)
 }
 
-func (w *World) AverageWindVector() [2]float32 {
-	d := OppositeHeading(float32(w.Wind.Direction))
-	v := [2]float32{sin(radians(d)), cos(radians(d))}
-	return scale2f(v, float32(w.Wind.Speed))
+// AverageWindVector returns the wind at the given point and altitude in
+// knots, not including gusts.
+func (w *World) AverageWindVector(p Point2LL, alt float32) [2]float32 {
+	return w.Weather.WindVector(p, alt, 0)
 }
 
 func (w *World) GetAirport(icao string) *Airport {
@@ -145,6 +190,14 @@ func (w *World) Locate(s string) (Point2LL, bool) {
 		return f.Location, ok
 	} else if p, err := ParseLatLong([]byte(s)); err == nil {
 		return p, true
+	} else if fix, radial, dist, err := ParseFixRadialDistance(s); err == nil {
+		// Fix-radial-distance, e.g. JFK180010; radials are magnetic.
+		if p, ok := w.Locate(fix); ok {
+			h := radians(float32(radial) - w.MagneticVariation)
+			v := scale2f([2]float32{sin(h), cos(h)}, float32(dist))
+			return nm2ll(add2f(ll2nm(p, w.NmPerLongitude), v), w.NmPerLongitude), true
+		}
+		return Point2LL{}, false
 	} else {
 		return Point2LL{}, false
 	}
@@ -188,6 +241,46 @@ func (w *World) LaunchAircraft(ac Aircraft) {
 		})
 }
 
+func (w *World) SetCoachState(cs CoachState) {
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.SetCoachState(cs),
+			IssueTime: time.Now(),
+		})
+}
+
+func (w *World) SetCoachTakeover(takeover bool, success func(any), err func(error)) {
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.SetCoachTakeover(takeover),
+			IssueTime: time.Now(),
+			OnSuccess: success,
+			OnErr:     err,
+		})
+}
+
+// CoachHasControl indicates whether a coach has taken command authority
+// for the position.
+func (w *World) CoachHasControl() bool {
+	return w.CoachState != nil && w.CoachState.Takeover
+}
+
+func (w *World) SetWeatherPreset(preset string) {
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.SetWeatherPreset(preset),
+			IssueTime: time.Now(),
+		})
+}
+
+func (w *World) ScheduleLaunches(launches []ScheduledLaunch) {
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.ScheduleLaunches(launches),
+			IssueTime: time.Now(),
+		})
+}
+
 func (w *World) SendGlobalMessage(global GlobalMessage) {
 	w.pendingCalls = append(w.pendingCalls,
 		&PendingCall{
@@ -224,6 +317,30 @@ func (w *World) SetSecondaryScratchpad(callsign string, scratchpad string, succe
 		})
 }
 
+func (w *World) AnnotateFlightStrip(callsign string, annotations [9]string, success func(any), err func(error)) {
+	if ac := w.Aircraft[callsign]; ac != nil {
+		ac.Strip.Annotations = annotations
+	}
+
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.AnnotateFlightStrip(callsign, annotations),
+			IssueTime: time.Now(),
+			OnSuccess: success,
+			OnErr:     err,
+		})
+}
+
+func (w *World) PushFlightStrip(callsign string, controller string, success func(any), err func(error)) {
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.PushFlightStrip(callsign, controller),
+			IssueTime: time.Now(),
+			OnSuccess: success,
+			OnErr:     err,
+		})
+}
+
 func (w *World) SetTemporaryAltitude(callsign string, alt int, success func(any), err func(error)) {
 	if ac := w.Aircraft[callsign]; ac != nil && ac.TrackingController == w.Callsign {
 		ac.TempAltitude = alt
@@ -238,6 +355,16 @@ func (w *World) SetTemporaryAltitude(callsign string, alt int, success func(any)
 		})
 }
 
+func (w *World) AmendRoute(callsign, route string, success func(any), err func(error)) {
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.AmendRoute(callsign, route),
+			IssueTime: time.Now(),
+			OnSuccess: success,
+			OnErr:     err,
+		})
+}
+
 func (w *World) AmendFlightPlan(callsign string, fp FlightPlan) error {
 	return nil // UNIMPLEMENTED
 }
@@ -416,6 +543,27 @@ func (w *World) ChangeControlPosition(callsign string, keepTracks bool) error {
 }
 
 func (w *World) Disconnect() {
+	w.StopRecording()
+	if globalConfig.ExportDebriefOnSignOff && w.SessionStats != nil {
+		if fn, err := w.SessionStats.ExportDebrief(); err != nil {
+			lg.Errorf("Error saving debrief report: %v", err)
+		} else {
+			lg.Infof("Saved debrief report to %s", fn)
+		}
+	}
+	w.recordTrainingSession()
+	if dw := w.datalinkWindow; dw != nil && dw.events != nil {
+		dw.events.Unsubscribe()
+		dw.events = nil
+	}
+	if cw := w.coordinationWindow; cw != nil && cw.events != nil {
+		cw.events.Unsubscribe()
+		cw.events = nil
+	}
+	if am := w.alertManager; am != nil {
+		// Clear the alerts so that their sounds stop.
+		am.Update(nil, time.Now())
+	}
 	if err := w.simProxy.SignOff(nil, nil); err != nil {
 		lg.Errorf("Error signing off from sim: %v", err)
 	}
@@ -533,6 +681,13 @@ func (w *World) GetUpdates(eventStream *EventStream, onErr func(error)) {
 					lg.Debugf("World update response time %s", d)
 				}
 				wu.UpdateWorld(w, eventStream)
+
+				if w.recorder != nil {
+					if err := w.recorder.Record(wu); err != nil {
+						lg.Errorf("%s: error recording replay: %v", w.recorder.Filename, err)
+						w.StopRecording()
+					}
+				}
 			},
 			OnErr: onErr,
 		}
@@ -548,6 +703,39 @@ func (w *World) Connected() bool {
 	return w.simProxy != nil
 }
 
+// StartRecording starts recording all of the updates from the Sim to a
+// replay file, returning the file's name.
+func (w *World) StartRecording() (string, error) {
+	if w.recorder != nil {
+		return w.recorder.Filename, nil
+	}
+
+	rr, err := NewReplayRecorder(w)
+	if err != nil {
+		return "", err
+	}
+	w.recorder = rr
+	return rr.Filename, nil
+}
+
+func (w *World) StopRecording() {
+	if w.recorder != nil {
+		if err := w.recorder.Close(); err != nil {
+			lg.Errorf("%s: %v", w.recorder.Filename, err)
+		}
+		w.recorder = nil
+	}
+}
+
+func (w *World) IsRecording() bool {
+	return w.recorder != nil
+}
+
+// IsReplay returns true if the World is playing back a recorded session.
+func (w *World) IsReplay() bool {
+	return w.replay != nil
+}
+
 func (w *World) GetSerializeSim() (*Sim, error) {
 	return w.simProxy.GetSerializeSim()
 }
@@ -658,6 +846,34 @@ func (w *World) GetInitialCenter() Point2LL {
 	return w.Center
 }
 
+// GetInitialAltitudeFilters returns the unassociated and associated
+// altitude filters for the current controller's position.
+func (w *World) GetInitialAltitudeFilters() (unassociated [2]int, associated [2]int) {
+	unassociated, associated = [2]int{100, 60000}, [2]int{100, 60000}
+	if config, ok := w.STARSFacilityAdaptation.ControllerConfigs[w.Callsign]; ok {
+		if config.UnassociatedAltitudeFilter[1] != 0 {
+			unassociated = config.UnassociatedAltitudeFilter
+		}
+		if config.AssociatedAltitudeFilter[1] != 0 {
+			associated = config.AssociatedAltitudeFilter
+		}
+	}
+	return
+}
+
+// MinimumAltitudeAreas returns the areas to use for MSAW: the FAA MVAs
+// for the TRACON along with any that were specified in the scenario.
+func (w *World) MinimumAltitudeAreas() []MVA {
+	if w.minimumAltitudeAreas == nil {
+		w.minimumAltitudeAreas = database.MVAs[w.TRACON]
+		if len(w.STARSFacilityAdaptation.MSAWVolumes) > 0 {
+			w.minimumAltitudeAreas = append(slices.Clip(w.minimumAltitudeAreas),
+				w.STARSFacilityAdaptation.MSAWVolumes...)
+		}
+	}
+	return w.minimumAltitudeAreas
+}
+
 func (w *World) InhibitCAVolumes() []AirspaceVolume {
 	return w.STARSFacilityAdaptation.InhibitCAVolumes
 }
@@ -700,6 +916,18 @@ func (w *World) RunAircraftCommands(callsign string, cmds string, handleResult f
 		})
 }
 
+// UplinkClearance sends the given commands to the aircraft via datalink;
+// the pilot's response is posted as a DatalinkMessageEvent.
+func (w *World) UplinkClearance(callsign string, cmds string, success func(any), err func(error)) {
+	w.pendingCalls = append(w.pendingCalls,
+		&PendingCall{
+			Call:      w.simProxy.UplinkClearance(callsign, cmds),
+			IssueTime: time.Now(),
+			OnSuccess: success,
+			OnErr:     err,
+		})
+}
+
 var badCallsigns map[string]interface{} = map[string]interface{}{
 	// 9/11
 	"AAL11":  nil,
@@ -967,6 +1195,76 @@ func (w *World) ToggleShowScenarioInfoWindow() {
 	w.showScenarioInfo = !w.showScenarioInfo
 }
 
+func (w *World) ToggleShowATISWindow() {
+	w.showATIS = !w.showATIS
+}
+
+// DrawATISWindow shows the current ATIS for each of the airports in the
+// scenario; it is periodically refreshed from the Sim while the window
+// is open.
+func (w *World) DrawATISWindow() {
+	if !w.showATIS {
+		return
+	}
+
+	if time.Since(w.lastATISRequest) > 5*time.Second {
+		w.lastATISRequest = time.Now()
+		var atis map[string]ATIS
+		w.pendingCalls = append(w.pendingCalls,
+			&PendingCall{
+				Call:      w.simProxy.GetATIS(&atis),
+				IssueTime: time.Now(),
+				OnSuccess: func(any) { w.atis = atis },
+			})
+	}
+
+	imgui.SetNextWindowSizeConstraints(imgui.Vec2{400, 100}, imgui.Vec2{800, 100000})
+	imgui.BeginV("ATIS", &w.showATIS, imgui.WindowFlagsAlwaysAutoResize)
+	if w.replay == nil && len(w.WeatherPresets) > 0 && w.Callsign != "Observer" && w.Callsign != "Spectator" {
+		if preset := w.WeatherPreset; weatherPresetCombo("Weather", &preset, SortedMapKeys(w.WeatherPresets)) {
+			w.SetWeatherPreset(preset)
+		}
+	}
+	if len(w.atis) == 0 {
+		imgui.Text("No ATIS available.")
+	}
+	imgui.PushTextWrapPosV(400)
+	for _, name := range SortedMapKeys(w.atis) {
+		atis := w.atis[name]
+		if imgui.CollapsingHeaderV(name+" ATIS "+atis.Code, imgui.TreeNodeFlagsDefaultOpen) {
+			imgui.Text(atis.Contents)
+		}
+	}
+	imgui.PopTextWrapPos()
+
+	if winds := w.RunwayWinds(); len(winds) > 0 && imgui.CollapsingHeader("Runway Winds") {
+		drawRunwayWinds("runwaywinds", winds)
+		if _, name, suggested := w.SuggestRunwayConfig(); name != w.SimDescription {
+			imgui.Text("The \"" + name + "\" configuration suits the wind better:")
+			drawRunwayWinds("suggestedwinds", suggested)
+		}
+	}
+
+	if layers := w.Weather.WindsAloft; len(layers) > 0 && imgui.CollapsingHeader("Winds Aloft") {
+		flags := imgui.TableFlagsBordersV | imgui.TableFlagsBordersOuterH | imgui.TableFlagsRowBg |
+			imgui.TableFlagsSizingStretchProp
+		if imgui.BeginTableV("windsaloft", 2, flags, imgui.Vec2{}, 0) {
+			imgui.TableSetupColumn("Altitude")
+			imgui.TableSetupColumn("Wind")
+			imgui.TableHeadersRow()
+			for _, l := range layers {
+				imgui.TableNextRow()
+				imgui.TableNextColumn()
+				imgui.Text(FormatAltitude(float32(l.Altitude)))
+				imgui.TableNextColumn()
+				imgui.Text(fmt.Sprintf("%03d at %d", l.Direction, l.Speed))
+			}
+			imgui.EndTable()
+		}
+	}
+	imgui.End()
+}
+
 type MissingPrimaryModalClient struct {
 	world *World
 }
@@ -1647,7 +1945,7 @@ func (w *World) drawWaypoints(waypoints []Waypoint, drawnWaypoints map[string]in
 	}
 }
 
-func (w *World) DrawSettingsWindow() {
+func (w *World) DrawSettingsWindow(r Renderer, eventStream *EventStream) {
 	if !w.showSettings {
 		return
 	}
@@ -1677,22 +1975,79 @@ func (w *World) DrawSettingsWindow() {
 		}
 		imgui.EndCombo()
 	}
+	imgui.SliderFloatV("UI scale", &globalConfig.UIScale, 0.5, 2, "%.2f", 0)
+
+	if w.simProxy != nil && !w.IsReplay() {
+		if imgui.Button("Copy companion page link") {
+			platform.GetClipboard().SetText(w.CompanionURL())
+		}
+		if imgui.IsItemHovered() {
+			imgui.SetTooltip("Copy the link to the companion web page for your position.\n" +
+				"It includes your access key for the simulation, so don't share it.")
+		}
+	}
 
 	var fsp *FlightStripPane
 	var messages *MessagesPane
-	var stars *STARSPane
+	var scopes []*STARSPane
+	var nonRadar *NonRadarPane
+	var surface *SurfacePane
+	var tower *TowerPane
+	var charts *ChartPane
 	globalConfig.DisplayRoot.VisitPanes(func(p Pane) {
 		switch pane := p.(type) {
+		case *NonRadarPane:
+			nonRadar = pane
+		case *SurfacePane:
+			surface = pane
+		case *TowerPane:
+			tower = pane
+		case *ChartPane:
+			charts = pane
 		case *FlightStripPane:
 			fsp = pane
 		case *STARSPane:
-			stars = pane
+			scopes = append(scopes, pane)
 		case *MessagesPane:
 			messages = pane
 		}
 	})
 
-	stars.DrawUI()
+	if len(scopes) == 1 {
+		scopes[0].DrawUI()
+	} else {
+		for i, sp := range scopes {
+			imgui.PushID(fmt.Sprintf("scope%d", i))
+			if imgui.CollapsingHeader(fmt.Sprintf("Scope %d", i+1)) {
+				sp.DrawUI()
+				if i > 0 && imgui.Button("Remove scope") {
+					sp.Deactivate()
+					globalConfig.DisplayRoot.RemovePane(sp)
+				}
+			}
+			imgui.PopID()
+		}
+	}
+	if imgui.Button("Add scope") {
+		// Start out with the same settings as the last scope; the new
+		// one can then be zoomed to show finals, for example.
+		last := scopes[len(scopes)-1]
+		sp := NewSTARSPane(w)
+		sp.CurrentPreferenceSet = last.CurrentPreferenceSet.Duplicate()
+		sp.Activate(w, r, eventStream)
+
+		node := globalConfig.DisplayRoot.NodeForPane(last)
+		*node = DisplayNode{
+			SplitLine: SplitLine{
+				Pos:  0.5,
+				Axis: SplitAxisX,
+			},
+			Children: [2]*DisplayNode{
+				&DisplayNode{Pane: last},
+				&DisplayNode{Pane: sp},
+			},
+		}
+	}
 
 	imgui.Separator()
 
@@ -1701,6 +2056,7 @@ func (w *World) DrawSettingsWindow() {
 	}
 	if imgui.CollapsingHeader("Display") {
 		imgui.Checkbox("Start in full-screen", &globalConfig.StartInFullScreen)
+		imgui.Checkbox("Export debrief report when signing off", &globalConfig.ExportDebriefOnSignOff)
 		monitorNames := platform.GetAllMonitorNames()
 		if imgui.BeginComboV("Monitor", monitorNames[globalConfig.FullScreenMonitor], imgui.ComboFlagsHeightLarge) {
 			for index, monitor := range monitorNames {
@@ -1720,6 +2076,47 @@ func (w *World) DrawSettingsWindow() {
 	if messages != nil && imgui.CollapsingHeader("Messages") {
 		messages.DrawUI()
 	}
+	if nonRadar != nil && imgui.CollapsingHeader("Non-Radar") {
+		nonRadar.DrawUI()
+	}
+	if surface != nil && imgui.CollapsingHeader("Surface Display") {
+		surface.DrawUI()
+	}
+	if tower != nil && imgui.CollapsingHeader("Tower View") {
+		tower.DrawUI()
+	}
+	if charts != nil && imgui.CollapsingHeader("Charts") {
+		charts.DrawUI()
+	}
+	if !w.IsReplay() && w.Callsign == w.PrimaryController && imgui.CollapsingHeader("Session") {
+		imgui.Text("Save the complete state of this sim so that it can be continued on another server.")
+		if imgui.Button("Export session...") {
+			ui.sessionDialog = NewDirectorySelectDialogBox("Export Session...", "", func(dir string) {
+				if fn, err := w.ExportSession(dir); err != nil {
+					ShowErrorDialog("Unable to export session: %v", err)
+				} else {
+					eventStream.Post(Event{Type: StatusMessageEvent, Message: "Exported session to " + fn})
+				}
+			})
+			ui.sessionDialog.Activate()
+		}
+	}
+	if imgui.CollapsingHeader("Display Profiles") {
+		globalConfig.DrawDisplayProfilesUI(w, r, eventStream)
+	}
+	if imgui.CollapsingHeader("Backups") {
+		if globalConfig.restoredBackup {
+			imgui.Text("A backup has been restored; restart vice to use it.")
+		} else {
+			drawBackupsUI(configFilePath(), func(b Backup) {
+				if err := RestoreBackup(b, configFilePath()); err != nil {
+					ShowErrorDialog("Unable to restore backup: %v", err)
+				} else {
+					globalConfig.restoredBackup = true
+				}
+			})
+		}
+	}
 
 	imgui.End()
 }